@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	veleroctrl "github.com/openshift/managed-velero-operator/pkg/controller/velero"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// configCheckCommand is the subcommand which validates a Velero instance read
+// from a file, and prints the bucket configuration it resolves to.
+const configCheckCommand = "config-check"
+
+// runConfigCheck parses the Velero instance of the file given by the --file
+// flag, validates it like the validating webhook does, and writes the bucket
+// configuration the operator would apply to out. Nothing is read from the
+// cluster, so its region and infrastructure name are taken from the flags.
+func runConfigCheck(args []string, out io.Writer) error {
+	fs := pflag.NewFlagSet(configCheckCommand, pflag.ContinueOnError)
+	file := fs.String("file", "", "The Velero instance to check, as YAML or JSON")
+	region := fs.String("region", "", "The cluster's region, which the bucket lives in unless the spec sets one")
+	infraName := fs.String("infrastructure-name", "", "The cluster's infrastructure name, which the bucket is tagged with")
+	fs.AddFlagSet(veleroctrl.FlagSet())
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("the --file flag is required")
+	}
+
+	data, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	instance := &veleroCR.Velero{}
+	if err := yaml.Unmarshal(data, instance); err != nil {
+		return fmt.Errorf("unable to parse %v: %v", *file, err)
+	}
+	if err := veleroctrl.ValidateSpec(instance); err != nil {
+		return fmt.Errorf("invalid Velero spec in %v: %v", *file, err)
+	}
+
+	location := instance.Spec.DefaultStorageLocation()
+	bucketRegion := *region
+	if location.Region != "" {
+		bucketRegion = location.Region
+	}
+	plan := veleroctrl.BucketPlan(instance, bucketRegion, *infraName)
+	if plan.Name == "" {
+		plan.Name = location.BucketName
+	}
+	doc, err := plan.MarshalYAML()
+	if err != nil {
+		return err
+	}
+	if plan.Name == "" {
+		if _, err := fmt.Fprintln(out, "# the bucket name is generated when the bucket is created"); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(out, "---\n%s", doc)
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConfigCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		args      []string
+		want      []string
+		wantError string
+	}{
+		{
+			name: "valid spec",
+			spec: `{"apiVersion": "managed.openshift.io/v1alpha1", "kind": "Velero",
+				"spec": {"backupStorageLocation": {"bucketName": "my-backups", "region": "eu-west-1",
+				"lifecycleDays": 30, "encryption": {"type": "aws:kms", "kmsKeyID": "alias/backups"}}}}`,
+			want: []string{"my-backups", "eu-west-1", "aws:kms", "alias/backups", "30"},
+		},
+		{
+			name: "region given by the flag",
+			spec: `{"spec": {"backupStorageLocation": {"bucketName": "my-backups"}}}`,
+			args: []string{"--region", "us-east-2"},
+			want: []string{"my-backups", "us-east-2", "AES256"},
+		},
+		{
+			name:      "invalid spec",
+			spec:      `{"spec": {"backupStorageLocation": {"slaClass": "platinum"}}}`,
+			wantError: "invalid slaClass",
+		},
+		{
+			name:      "unparsable spec",
+			spec:      `{"spec": `,
+			wantError: "unable to parse",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "config-check")
+			if err != nil {
+				t.Fatalf("TempDir() error = %v", err)
+			}
+			defer os.RemoveAll(dir)
+			file := filepath.Join(dir, "velero.yaml")
+			if err = ioutil.WriteFile(file, []byte(tt.spec), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			err = runConfigCheck(append([]string{"--file", file}, tt.args...), &out)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("runConfigCheck() error = %v, want an error about %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runConfigCheck() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("runConfigCheck() printed %q, want it to hold %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConfigCheckWithoutFile(t *testing.T) {
+	if err := runConfigCheck(nil, ioutil.Discard); err == nil {
+		t.Errorf("runConfigCheck() succeeded without --file, want an error")
+	}
+}
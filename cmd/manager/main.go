@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/openshift/managed-velero-operator/pkg/apis"
 	"github.com/openshift/managed-velero-operator/pkg/controller"
+	veleroctrl "github.com/openshift/managed-velero-operator/pkg/controller/velero"
 	"github.com/openshift/managed-velero-operator/pkg/util/platform"
 	"github.com/openshift/managed-velero-operator/pkg/velero"
 	"github.com/openshift/managed-velero-operator/version"
@@ -33,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	velerov1 "github.com/heptio/velero/pkg/apis/velero/v1"
 	configv1 "github.com/openshift/api/config/v1"
 	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
@@ -44,6 +47,9 @@ var (
 	metricsHost               = "0.0.0.0"
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
+	healthProbePort     int32 = 8081
+	// webhookPort is the port of the validating webhook, when enabled
+	webhookPort = 9443
 )
 
 var log = logf.Log.WithName(version.OperatorName)
@@ -51,7 +57,7 @@ var log = logf.Log.WithName(version.OperatorName)
 const ManagedVeleroOperatorNamespace = "openshift-velero"
 
 // supportedPlatforms is the list of platform supported by the operator
-var supportedPlatforms = []configv1.PlatformType{configv1.AWSPlatformType}
+var supportedPlatforms = []configv1.PlatformType{configv1.AWSPlatformType, configv1.GCPPlatformType, configv1.AzurePlatformType}
 
 func printVersion() {
 	log.Info(fmt.Sprintf("Operator Version: %s", version.Version))
@@ -61,10 +67,23 @@ func printVersion() {
 }
 
 func main() {
+	// Check a Velero instance read from a file instead of running the
+	// operator. The subcommand parses its own flags.
+	if len(os.Args) > 1 && os.Args[1] == configCheckCommand {
+		if err := runConfigCheck(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Add the zap logger flag set to the CLI. The flag set must
 	// be added before calling pflag.Parse().
 	pflag.CommandLine.AddFlagSet(zap.FlagSet())
 
+	// Add the flags used to configure the velero controller.
+	pflag.CommandLine.AddFlagSet(veleroctrl.FlagSet())
+
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -81,6 +100,15 @@ func main() {
 	// uniform and structured logs.
 	logf.SetLogger(zap.Logger())
 
+	// Print the intended bucket configuration instead of running the operator
+	if pflag.Arg(0) == planCommand {
+		if err := runPlan(os.Stdout); err != nil {
+			log.Error(err, "Failed to render bucket plan")
+			os.Exit(1)
+		}
+		return
+	}
+
 	printVersion()
 
 	namespace, err := k8sutil.GetOperatorNamespace()
@@ -116,6 +144,7 @@ func main() {
 		Namespace:          namespace,
 		MapperProvider:     restmapper.NewDynamicRESTMapper,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -154,6 +183,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Add Prometheus Operator apis to scheme
+	if err := monitoringv1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Create k8s client to perform startup tasks
 	startupClient, err := crclient.New(cfg, crclient.Options{Scheme: mgr.GetScheme()})
 	if err != nil {
@@ -215,6 +250,9 @@ func main() {
 		}
 	}
 
+	// Serve the liveness and readiness probes of the operator pod
+	go serveHealthProbes()
+
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
@@ -224,6 +262,19 @@ func main() {
 	}
 }
 
+// serveHealthProbes serves the liveness probe on "http://metricsHost:healthProbePort/healthz",
+// and the readiness probe, which fails while S3 can't be reached, on "/readyz".
+func serveHealthProbes() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/readyz", veleroctrl.ReadinessProbe())
+	if err := http.ListenAndServe(fmt.Sprintf("%s:%d", metricsHost, healthProbePort), mux); err != nil {
+		log.Error(err, "Health probe server exited")
+	}
+}
+
 // serveCRMetrics gets the Operator/CustomResource GVKs and generates metrics based on those types.
 // It serves those metrics on "http://metricsHost:operatorMetricsPort".
 func serveCRMetrics(cfg *rest.Config) error {
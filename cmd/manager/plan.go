@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/openshift/managed-velero-operator/pkg/apis"
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	veleroctrl "github.com/openshift/managed-velero-operator/pkg/controller/velero"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+	"github.com/openshift/managed-velero-operator/pkg/util/platform"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// planCommand is the subcommand which prints the intended bucket configuration.
+const planCommand = "plan"
+
+// runPlan writes the intended bucket configuration for every Velero instance
+// to out, followed by the changes to any provisioned bucket, without changing
+// anything.
+func runPlan(out io.Writer) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := configv1.Install(scheme); err != nil {
+		return err
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	kubeClient, err := crclient.New(cfg, crclient.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	infraStatus, err := platform.GetInfrastructureStatus(kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve infrastructure status: %v", err)
+	}
+	var region string
+	if infraStatus.PlatformStatus != nil && infraStatus.PlatformStatus.AWS != nil {
+		region = infraStatus.PlatformStatus.AWS.Region
+	}
+
+	instances := &veleroCR.VeleroList{}
+	if err := kubeClient.List(context.TODO(), instances, crclient.InNamespace(ManagedVeleroOperatorNamespace)); err != nil {
+		return err
+	}
+
+	for i := range instances.Items {
+		plan := veleroctrl.BucketPlan(&instances.Items[i], region, infraStatus.InfrastructureName)
+		doc, err := plan.MarshalYAML()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", doc); err != nil {
+			return err
+		}
+		if !instances.Items[i].Status.S3Bucket.Provisioned {
+			continue
+		}
+
+		// Report how the existing bucket differs from the plan
+		s3Client, err := s3.NewS3Client(kubeClient, region, veleroctrl.S3Credentials(ManagedVeleroOperatorNamespace))
+		if err != nil {
+			return err
+		}
+		current, err := s3.ReadBucketState(context.TODO(), s3Client, plan.Name)
+		if err != nil {
+			return err
+		}
+		doc, err = s3.DiffBucketState(plan, current).MarshalYAML()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "# changes to bucket %s\n%s", plan.Name, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,13 +3,19 @@ module github.com/openshift/managed-velero-operator
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.23.3
+	cloud.google.com/go/storage v1.0.0
+	github.com/Azure/azure-storage-blob-go v0.8.0
+	github.com/aws/aws-sdk-go v1.42.23
+	github.com/coreos/prometheus-operator v0.29.0
 	github.com/go-logr/logr v0.1.0
 	github.com/go-openapi/spec v0.19.0
 	github.com/google/uuid v1.1.1
 	github.com/heptio/velero v1.1.0
 	github.com/openshift/api v3.9.1-0.20190927182313-d4a64ec2cbd8+incompatible
 	github.com/openshift/cloud-credential-operator v0.0.0-20191009163822-b905f49fd022
+	github.com/prometheus/client_golang v1.1.0
+	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	google.golang.org/api v0.9.0
 	sigs.k8s.io/yaml v1.1.0
 )
 
@@ -24,6 +30,12 @@ require (
 	sigs.k8s.io/controller-runtime v0.3.0
 )
 
+// The original hosts of these modules are gone, use their GitHub mirrors
+replace (
+	bitbucket.org/ww/goautoneg => github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822
+	vbom.ml/util => github.com/fvbommel/util v0.0.0-20160121211510-db5cfe13f5cc
+)
+
 // Pinned to kubernetes-1.15.4
 replace (
 	k8s.io/api => k8s.io/api v0.0.0-20190918195907-bd6ac527cfd2
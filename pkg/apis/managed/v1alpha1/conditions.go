@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of the given type, or nil if it isn't set.
+func (s *VeleroStatus) GetCondition(conditionType VeleroConditionType) *VeleroCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the given type, and reports
+// whether anything changed. The LastTransitionTime only moves when the status
+// of the condition changes.
+func (s *VeleroStatus) SetCondition(conditionType VeleroConditionType, status corev1.ConditionStatus, reason, message string) bool {
+	condition := s.GetCondition(conditionType)
+	if condition == nil {
+		s.Conditions = append(s.Conditions, VeleroCondition{Type: conditionType})
+		condition = &s.Conditions[len(s.Conditions)-1]
+	}
+	if condition.Status == status && condition.Reason == reason && condition.Message == message {
+		return false
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+	return true
+}
@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	status := &VeleroStatus{}
+	if status.GetCondition(BucketDrifted) != nil {
+		t.Fatalf("GetCondition() returned a condition which was never set")
+	}
+
+	if !status.SetCondition(BucketDrifted, corev1.ConditionTrue, "Drifted", "encryption") {
+		t.Errorf("SetCondition() = false for a new condition")
+	}
+	if status.SetCondition(BucketDrifted, corev1.ConditionTrue, "Drifted", "encryption") {
+		t.Errorf("SetCondition() = true for an unchanged condition")
+	}
+	condition := status.GetCondition(BucketDrifted)
+	if condition == nil {
+		t.Fatalf("GetCondition() = nil after SetCondition()")
+	}
+	if condition.Status != corev1.ConditionTrue || condition.Reason != "Drifted" || condition.Message != "encryption" {
+		t.Errorf("GetCondition() = %+v, want the values set", condition)
+	}
+
+	// The transition time only moves when the status changes
+	transitioned := metav1.NewTime(time.Now().Add(-time.Hour))
+	condition.LastTransitionTime = transitioned
+	status.SetCondition(BucketDrifted, corev1.ConditionTrue, "Drifted", "encryption, tags")
+	if got := status.GetCondition(BucketDrifted); !got.LastTransitionTime.Equal(&transitioned) || got.Message != "encryption, tags" {
+		t.Errorf("SetCondition() with the same status = %+v, want only the message updated", got)
+	}
+
+	status.SetCondition(BucketDrifted, corev1.ConditionFalse, "InSync", "")
+	if got := status.GetCondition(BucketDrifted); got.LastTransitionTime.Equal(&transitioned) {
+		t.Errorf("SetCondition() with a new status did not move LastTransitionTime")
+	}
+	if len(status.Conditions) != 1 {
+		t.Errorf("VeleroStatus has %d conditions, want 1", len(status.Conditions))
+	}
+}
@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	"reflect"
+)
+
+// DefaultStorageLocationName is the name of the first backup storage location,
+// which is reconciled as the default Velero BackupStorageLocation.
+const DefaultStorageLocationName = "default"
+
+// StorageLocations returns the backup storage locations, treating the legacy
+// singular BackupStorageLocation as a one-element list.
+func (s *VeleroSpec) StorageLocations() []BackupStorageLocationSpec {
+	if s.legacyStorageLocationSet() {
+		return []BackupStorageLocationSpec{s.BackupStorageLocation}
+	}
+	return s.BackupStorageLocations
+}
+
+// DefaultStorageLocation returns the backup storage location reconciled as the
+// default Velero BackupStorageLocation.
+func (s *VeleroSpec) DefaultStorageLocation() BackupStorageLocationSpec {
+	locations := s.StorageLocations()
+	if len(locations) == 0 {
+		return BackupStorageLocationSpec{}
+	}
+	return locations[0]
+}
+
+// StorageLocationName returns the name of the backup storage location: the
+// first location is always the default, and the others are named in the spec.
+func StorageLocationName(index int, location BackupStorageLocationSpec) string {
+	if index == 0 {
+		return DefaultStorageLocationName
+	}
+	return location.Name
+}
+
+// MigrateStorageLocation moves the legacy singular BackupStorageLocation into
+// BackupStorageLocations, and returns whether the spec changed. A singular
+// field which is set again, such as by re-applying an old manifest, replaces
+// the list.
+func (s *VeleroSpec) MigrateStorageLocation() bool {
+	if !s.legacyStorageLocationSet() {
+		return false
+	}
+	s.BackupStorageLocations = []BackupStorageLocationSpec{s.BackupStorageLocation}
+	s.BackupStorageLocation = BackupStorageLocationSpec{}
+	return true
+}
+
+// legacyStorageLocationSet checks whether the singular BackupStorageLocation is in use.
+func (s *VeleroSpec) legacyStorageLocationSet() bool {
+	return !reflect.DeepEqual(s.BackupStorageLocation, BackupStorageLocationSpec{})
+}
@@ -0,0 +1,62 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrateStorageLocation(t *testing.T) {
+	gold := BackupStorageLocationSpec{SLAClass: SLAClassGold}
+	bronze := BackupStorageLocationSpec{SLAClass: SLAClassBronze}
+
+	var testcases = []struct {
+		testName    string
+		spec        VeleroSpec
+		wantChanged bool
+		want        []BackupStorageLocationSpec
+	}{
+		{
+			testName:    "no location",
+			spec:        VeleroSpec{},
+			wantChanged: false,
+			want:        nil,
+		},
+		{
+			testName:    "singular location",
+			spec:        VeleroSpec{BackupStorageLocation: gold},
+			wantChanged: true,
+			want:        []BackupStorageLocationSpec{gold},
+		},
+		{
+			testName:    "list location",
+			spec:        VeleroSpec{BackupStorageLocations: []BackupStorageLocationSpec{gold}},
+			wantChanged: false,
+			want:        []BackupStorageLocationSpec{gold},
+		},
+		{
+			testName: "singular location set again",
+			spec: VeleroSpec{
+				BackupStorageLocation:  bronze,
+				BackupStorageLocations: []BackupStorageLocationSpec{gold},
+			},
+			wantChanged: true,
+			want:        []BackupStorageLocationSpec{bronze},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			before := tc.spec.StorageLocations()
+			if changed := tc.spec.MigrateStorageLocation(); changed != tc.wantChanged {
+				t.Errorf("MigrateStorageLocation() = %v, want %v", changed, tc.wantChanged)
+			}
+			if !reflect.DeepEqual(tc.spec.BackupStorageLocations, tc.want) {
+				t.Errorf("BackupStorageLocations = %v, want %v", tc.spec.BackupStorageLocations, tc.want)
+			}
+			// Migrating doesn't change the locations which are reconciled
+			if after := tc.spec.StorageLocations(); !reflect.DeepEqual(after, before) {
+				t.Errorf("StorageLocations() = %v after migrating, want %v", after, before)
+			}
+		})
+	}
+}
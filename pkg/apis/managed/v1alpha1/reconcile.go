@@ -5,17 +5,46 @@ import (
 )
 
 func (i *Velero) S3BucketReconcileRequired(reconcilePeriod time.Duration) bool {
+	if i.Status.S3Bucket.ReconcileRequired(reconcilePeriod) {
+		return true
+	}
+
+	// The bucket of every further backup storage location is reconciled alongside
+	locations := i.Spec.StorageLocations()
+	for index := 1; index < len(locations); index++ {
+		bucket := i.Status.LocationS3Bucket(StorageLocationName(index, locations[index]))
+		if bucket == nil || bucket.ReconcileRequired(reconcilePeriod) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReconcileRequired checks whether the S3 bucket has to be reconciled.
+func (b *S3Bucket) ReconcileRequired(reconcilePeriod time.Duration) bool {
 	// If any of the following are true, reconcile the S3 bucket:
 	// - Name is empty
 	// - Provisioned is false
 	// - The LastSyncTimestamp is unset
 	// - It's been longer than 1 hour since last sync
-	if i.Status.S3Bucket.Name == "" ||
-		!i.Status.S3Bucket.Provisioned ||
-		i.Status.S3Bucket.LastSyncTimestamp.IsZero() ||
-		time.Since(i.Status.S3Bucket.LastSyncTimestamp.Time) > reconcilePeriod {
+	if b.Name == "" ||
+		!b.Provisioned ||
+		b.LastSyncTimestamp.IsZero() ||
+		time.Since(b.LastSyncTimestamp.Time) > reconcilePeriod {
 		return true
 	}
 
 	return false
 }
+
+// LocationS3Bucket returns the status of the S3 bucket of the backup storage
+// location after the first, or nil if it isn't recorded yet.
+func (s *VeleroStatus) LocationS3Bucket(location string) *S3Bucket {
+	for i := range s.AdditionalS3Buckets {
+		if s.AdditionalS3Buckets[i].Location == location {
+			return &s.AdditionalS3Buckets[i].S3Bucket
+		}
+	}
+	return nil
+}
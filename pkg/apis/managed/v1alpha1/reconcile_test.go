@@ -85,3 +85,53 @@ func TestS3BucketReconcileRequired(t *testing.T) {
 		}
 	}
 }
+
+func TestS3BucketReconcileRequiredAdditionalLocations(t *testing.T) {
+	synced := S3Bucket{
+		Name:              "test-bucket",
+		Provisioned:       true,
+		LastSyncTimestamp: &metav1.Time{Time: time.Now()},
+	}
+	var testcases = []struct {
+		testName        string
+		buckets         []LocationS3Bucket
+		shouldReconcile bool
+	}{
+		{
+			testName:        "additional bucket synced",
+			buckets:         []LocationS3Bucket{{Location: "secondary", S3Bucket: synced}},
+			shouldReconcile: false,
+		},
+		{
+			testName:        "additional bucket not recorded",
+			shouldReconcile: true,
+		},
+		{
+			testName:        "additional bucket not provisioned",
+			buckets:         []LocationS3Bucket{{Location: "secondary", S3Bucket: S3Bucket{Name: "test-bucket"}}},
+			shouldReconcile: true,
+		},
+		{
+			testName:        "bucket of another location synced",
+			buckets:         []LocationS3Bucket{{Location: "tertiary", S3Bucket: synced}},
+			shouldReconcile: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			instance := &Velero{
+				Spec: VeleroSpec{
+					BackupStorageLocations: []BackupStorageLocationSpec{{}, {Name: "secondary"}},
+				},
+				Status: VeleroStatus{
+					S3Bucket:            synced,
+					AdditionalS3Buckets: tc.buckets,
+				},
+			}
+			if got := instance.S3BucketReconcileRequired(time.Hour); got != tc.shouldReconcile {
+				t.Errorf("S3BucketReconcileRequired() = %v, want %v", got, tc.shouldReconcile)
+			}
+		})
+	}
+}
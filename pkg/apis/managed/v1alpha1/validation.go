@@ -0,0 +1,417 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxTagKeyLength and maxTagValueLength are the S3 limits of the bucket tags
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+	// maxCORSRules is the S3 limit of the CORS rules of a bucket
+	maxCORSRules = 100
+)
+
+var (
+	// bucketARNPattern and roleARNPattern match the ARNs of an S3 bucket and an IAM role
+	bucketARNPattern = regexp.MustCompile(`^arn:[a-z-]+:s3:::[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+	roleARNPattern   = regexp.MustCompile(`^arn:[a-z-]+:iam::[0-9]{12}:role/.+$`)
+	topicARNPattern  = regexp.MustCompile(`^arn:[a-z-]+:sns:[a-z0-9-]+:[0-9]{12}:[A-Za-z0-9_.-]+$`)
+	queueARNPattern  = regexp.MustCompile(`^arn:[a-z-]+:sqs:[a-z0-9-]+:[0-9]{12}:[A-Za-z0-9_.-]+$`)
+	// s3EventPattern matches the S3 event types, such as s3:ObjectCreated:*
+	s3EventPattern = regexp.MustCompile(`^s3:[A-Za-z]+(:([A-Za-z]+|\*))?$`)
+
+	// bucketNamePattern matches the characters an S3 bucket name may hold, and those it may begin and end with
+	bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+
+	// outpostIDPattern and vpcIDPattern match the IDs of an AWS Outpost and a VPC
+	outpostIDPattern = regexp.MustCompile(`^op-[0-9a-f]{17}$`)
+	vpcIDPattern     = regexp.MustCompile(`^vpc-([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+	// kmsKeyPattern matches the ID, ARN, alias name and alias ARN of a KMS key
+	kmsKeyPattern = regexp.MustCompile(`^([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|mrk-[0-9a-f]{32}|alias/[a-zA-Z0-9/_-]+|arn:[a-z-]+:kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/.+)$`)
+)
+
+// Validate checks that the VeleroSpec only contains values that can be reconciled.
+func (s *VeleroSpec) Validate() error {
+	locations := s.StorageLocations()
+	names := make(map[string]bool)
+	for i := range locations {
+		if err := locations[i].Validate(); err != nil {
+			return err
+		}
+		if i == 0 {
+			if name := locations[i].Name; name != "" && name != DefaultStorageLocationName {
+				return fmt.Errorf("the first backup storage location is named %v, not %q", DefaultStorageLocationName, name)
+			}
+			names[DefaultStorageLocationName] = true
+			continue
+		}
+
+		name := locations[i].Name
+		switch {
+		case name == "":
+			return fmt.Errorf("backup storage location %d must be named", i)
+		case names[name]:
+			return fmt.Errorf("backup storage location name %v is used more than once", name)
+		case locations[i].Endpoint != "":
+			// Every bucket is kept at the endpoint of the first location
+			return fmt.Errorf("backup storage location %v can't set an endpoint, which only the first location may set", name)
+		}
+		names[name] = true
+	}
+
+	if s.ReconcileDeadline != nil && s.ReconcileDeadline.Duration <= 0 {
+		return fmt.Errorf("reconcileDeadline %v must be positive", s.ReconcileDeadline.Duration)
+	}
+
+	for _, legacyInfraName := range s.LegacyInfraNames {
+		if legacyInfraName == "" {
+			return fmt.Errorf("legacy infrastructure name must not be empty")
+		}
+	}
+
+	return s.Velero.Validate()
+}
+
+// Validate checks that the VeleroServerSpec only contains values that can be reconciled.
+func (s *VeleroServerSpec) Validate() error {
+	plugins := make(map[string]bool)
+	for _, plugin := range s.Plugins {
+		if plugin == "" {
+			return fmt.Errorf("plugin image must not be empty")
+		}
+		if plugins[plugin] {
+			return fmt.Errorf("plugin image %v is listed more than once", plugin)
+		}
+		plugins[plugin] = true
+	}
+
+	if s.BackupSyncPeriod != nil && s.BackupSyncPeriod.Duration <= 0 {
+		return fmt.Errorf("backupSyncPeriod %v must be positive", s.BackupSyncPeriod.Duration)
+	}
+
+	if s.DefaultBackupTTL != nil && s.DefaultBackupTTL.Duration <= 0 {
+		return fmt.Errorf("defaultBackupTTL %v must be positive", s.DefaultBackupTTL.Duration)
+	}
+
+	return nil
+}
+
+// Validate checks that the BackupStorageLocationSpec only contains values that can be reconciled.
+func (s *BackupStorageLocationSpec) Validate() error {
+	switch s.SLAClass {
+	case "", SLAClassGold, SLAClassSilver, SLAClassBronze:
+	default:
+		return fmt.Errorf("invalid slaClass %q: must be one of %v, %v or %v", s.SLAClass, SLAClassGold, SLAClassSilver, SLAClassBronze)
+	}
+
+	if strings.HasPrefix(s.VerifyWritablePrefix, "/") {
+		return fmt.Errorf("verifyWritablePrefix %q must not start with /", s.VerifyWritablePrefix)
+	}
+
+	if s.LifecycleDays < 0 {
+		return fmt.Errorf("lifecycleDays %d must not be negative", s.LifecycleDays)
+	}
+
+	if err := s.Lifecycle.Validate(); err != nil {
+		return err
+	}
+	// The transitions have to happen before the backups expire, as set either way
+	if s.Lifecycle.ExpirationDays == 0 && s.LifecycleDays > 0 {
+		for _, transition := range s.Lifecycle.Transitions {
+			if transition.Days >= s.LifecycleDays {
+				return fmt.Errorf("lifecycle transition to %v after %d days must happen before the backups expire after lifecycleDays %d",
+					transition.StorageClass, transition.Days, s.LifecycleDays)
+			}
+		}
+	}
+
+	if s.Endpoint != "" {
+		endpoint, err := url.Parse(s.Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %q: %v", s.Endpoint, err)
+		}
+		if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("invalid endpoint %q: must be an http or https URL", s.Endpoint)
+		}
+	} else if s.S3ForcePathStyle {
+		return fmt.Errorf("s3ForcePathStyle requires an endpoint")
+	}
+
+	for key, value := range s.AdditionalTags {
+		if len(key) < 1 || len(key) > maxTagKeyLength {
+			return fmt.Errorf("additionalTags key %q must be between 1 and %d characters long", key, maxTagKeyLength)
+		}
+		if strings.HasPrefix(key, "aws:") {
+			return fmt.Errorf("additionalTags key %q must not use the reserved aws: prefix", key)
+		}
+		if len(value) > maxTagValueLength {
+			return fmt.Errorf("additionalTags value of %q must be at most %d characters long", key, maxTagValueLength)
+		}
+	}
+
+	for _, roleARN := range s.AllowedRoleARNs {
+		if !roleARNPattern.MatchString(roleARN) {
+			return fmt.Errorf("invalid allowedRoleArns entry %q: must be the ARN of an IAM role", roleARN)
+		}
+	}
+
+	if s.Logging.TargetPrefix != "" && s.Logging.TargetBucket == "" {
+		return fmt.Errorf("logging.targetPrefix requires a logging.targetBucket")
+	}
+	switch s.Logging.DeliveryPermission {
+	case "":
+	case LogDeliveryPermissionBucketPolicy, LogDeliveryPermissionACL:
+		if s.Logging.TargetBucket == "" {
+			return fmt.Errorf("logging.deliveryPermission requires a logging.targetBucket")
+		}
+	default:
+		return fmt.Errorf("invalid logging.deliveryPermission %q: must be one of %v or %v",
+			s.Logging.DeliveryPermission, LogDeliveryPermissionBucketPolicy, LogDeliveryPermissionACL)
+	}
+
+	if err := s.Replication.Validate(); err != nil {
+		return err
+	}
+
+	if len(s.CORSRules) > maxCORSRules {
+		return fmt.Errorf("corsRules holds %d rules, but S3 allows at most %d", len(s.CORSRules), maxCORSRules)
+	}
+	for i := range s.CORSRules {
+		if err := s.CORSRules[i].Validate(); err != nil {
+			return fmt.Errorf("invalid corsRules[%d]: %v", i, err)
+		}
+	}
+
+	if err := s.Notifications.Validate(); err != nil {
+		return err
+	}
+
+	if s.BucketName != "" {
+		if err := validateBucketName(s.BucketName); err != nil {
+			return err
+		}
+		if s.RecreateOnImmutableChange {
+			return fmt.Errorf("recreateOnImmutableChange can't be set with bucketName, as the named bucket is never created")
+		}
+		if s.BucketNamePrefix != "" {
+			return fmt.Errorf("bucketNamePrefix can't be set with bucketName, as the named bucket is used instead of a generated one")
+		}
+	}
+
+	switch s.AccessMode {
+	case "", AccessModeReadWrite, AccessModeReadOnly:
+	default:
+		return fmt.Errorf("invalid accessMode %q: must be one of %v or %v", s.AccessMode, AccessModeReadWrite, AccessModeReadOnly)
+	}
+
+	if s.ManageEncryption != nil && !*s.ManageEncryption && s.Encryption != (EncryptionSpec{}) {
+		return fmt.Errorf("encryption can't be set with manageEncryption false, as the encryption of the bucket is left unchanged")
+	}
+
+	if s.ManageTags != nil && !*s.ManageTags && len(s.AdditionalTags) > 0 {
+		return fmt.Errorf("additionalTags can't be set with manageTags false, as the tags of the bucket are left unchanged")
+	}
+
+	if s.OutpostID != "" {
+		if err := s.validateOutpost(); err != nil {
+			return err
+		}
+	} else if s.OutpostVPCID != "" {
+		return fmt.Errorf("outpostVpcId requires an outpostId")
+	}
+
+	return s.Encryption.Validate()
+}
+
+// validateOutpost checks that a bucket on an Outpost only sets the features
+// the operator manages on S3 on Outposts: the tags and lifecycle of the bucket.
+func (s *BackupStorageLocationSpec) validateOutpost() error {
+	if !outpostIDPattern.MatchString(s.OutpostID) {
+		return fmt.Errorf("invalid outpostId %q: must be the ID of an AWS Outpost, such as op-01ac5d28a6a232904", s.OutpostID)
+	}
+	if !vpcIDPattern.MatchString(s.OutpostVPCID) {
+		return fmt.Errorf("invalid outpostVpcId %q: must be the ID of the VPC the access point of the bucket accepts requests from", s.OutpostVPCID)
+	}
+
+	unsupported := []struct {
+		set   bool
+		field string
+	}{
+		{s.Endpoint != "", "endpoint"},
+		{s.BucketName != "", "bucketName"},
+		{s.Encryption.Type == EncryptionTypeKMS, "encryption type " + string(EncryptionTypeKMS)},
+		{s.Versioning, "versioning"},
+		{s.Logging.TargetBucket != "", "logging"},
+		{s.Replication.DestinationBucketARN != "", "replication"},
+		{len(s.CORSRules) > 0, "corsRules"},
+		{len(s.Notifications.Topics) > 0 || len(s.Notifications.Queues) > 0 || s.Notifications.EventBridge, "notifications"},
+		{s.DenySSEC, "denySSEC"},
+		{len(s.AllowedRoleARNs) > 0, "allowedRoleArns"},
+		{s.AccessMode == AccessModeReadOnly, "accessMode " + string(AccessModeReadOnly)},
+		{s.RecreateOnImmutableChange, "recreateOnImmutableChange"},
+		{s.DeleteBucketOnUninstall, "deleteBucketOnUninstall"},
+		{len(s.Lifecycle.Transitions) > 0, "lifecycle transitions"},
+	}
+	for _, feature := range unsupported {
+		if feature.set {
+			return fmt.Errorf("%v can't be set with outpostId, as only the tags and lifecycle of a bucket on an Outpost are managed", feature.field)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the LifecycleSpec only contains values that can be reconciled.
+// Zero days leave the default expiration.
+func (s *LifecycleSpec) Validate() error {
+	if s.ExpirationDays < 0 {
+		return fmt.Errorf("lifecycle.expirationDays %d must be positive", s.ExpirationDays)
+	}
+	if s.NoncurrentVersionExpirationDays < 0 {
+		return fmt.Errorf("lifecycle.noncurrentVersionExpirationDays %d must be positive", s.NoncurrentVersionExpirationDays)
+	}
+	if strings.HasPrefix(s.Prefix, "/") {
+		return fmt.Errorf("lifecycle.prefix %q must not start with /", s.Prefix)
+	}
+	if len(s.Prefix) > 1024 || !utf8.ValidString(s.Prefix) {
+		return fmt.Errorf("lifecycle.prefix %q must be a valid S3 key prefix of at most 1024 bytes", s.Prefix)
+	}
+	for _, transition := range s.Transitions {
+		switch transition.StorageClass {
+		case StorageClassStandardIA, StorageClassOneZoneIA, StorageClassIntelligentTiering,
+			StorageClassGlacierIR, StorageClassGlacier, StorageClassDeepArchive:
+		default:
+			return fmt.Errorf("invalid lifecycle transition storageClass %q", transition.StorageClass)
+		}
+		if transition.Days < 0 {
+			return fmt.Errorf("lifecycle transition days %d must not be negative", transition.Days)
+		}
+		if s.ExpirationDays > 0 && transition.Days >= s.ExpirationDays {
+			return fmt.Errorf("lifecycle transition to %v after %d days must happen before the backups expire after lifecycle.expirationDays %d",
+				transition.StorageClass, transition.Days, s.ExpirationDays)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the BucketReplicationSpec only contains values that can be reconciled.
+func (s *BucketReplicationSpec) Validate() error {
+	if s.DestinationBucketARN == "" && s.RoleARN == "" {
+		return nil
+	}
+	if !bucketARNPattern.MatchString(s.DestinationBucketARN) {
+		return fmt.Errorf("invalid replication.destinationBucketArn %q: must be the ARN of an S3 bucket", s.DestinationBucketARN)
+	}
+	if !roleARNPattern.MatchString(s.RoleARN) {
+		return fmt.Errorf("invalid replication.roleArn %q: must be the ARN of an IAM role", s.RoleARN)
+	}
+	return nil
+}
+
+// Validate checks that the BucketCORSRule only contains values that can be reconciled.
+func (r *BucketCORSRule) Validate() error {
+	if len(r.AllowedOrigins) == 0 {
+		return fmt.Errorf("allowedOrigins must not be empty")
+	}
+	if len(r.AllowedMethods) == 0 {
+		return fmt.Errorf("allowedMethods must not be empty")
+	}
+	for _, method := range r.AllowedMethods {
+		switch method {
+		case "GET", "PUT", "POST", "DELETE", "HEAD":
+		default:
+			return fmt.Errorf("invalid allowedMethods entry %q: must be one of GET, PUT, POST, DELETE or HEAD", method)
+		}
+	}
+	for _, origin := range r.AllowedOrigins {
+		if strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("invalid allowedOrigins entry %q: must hold at most one * wildcard", origin)
+		}
+	}
+	for _, header := range r.AllowedHeaders {
+		if strings.Count(header, "*") > 1 {
+			return fmt.Errorf("invalid allowedHeaders entry %q: must hold at most one * wildcard", header)
+		}
+	}
+	if r.MaxAgeSeconds < 0 {
+		return fmt.Errorf("maxAgeSeconds %d must not be negative", r.MaxAgeSeconds)
+	}
+	return nil
+}
+
+// Validate checks that the BucketNotificationSpec only contains values that can be reconciled.
+func (s *BucketNotificationSpec) Validate() error {
+	for i := range s.Topics {
+		if !topicARNPattern.MatchString(s.Topics[i].ARN) {
+			return fmt.Errorf("invalid notifications.topics[%d].arn %q: must be the ARN of an SNS topic", i, s.Topics[i].ARN)
+		}
+		if err := s.Topics[i].Validate(); err != nil {
+			return fmt.Errorf("invalid notifications.topics[%d]: %v", i, err)
+		}
+	}
+	for i := range s.Queues {
+		if !queueARNPattern.MatchString(s.Queues[i].ARN) {
+			return fmt.Errorf("invalid notifications.queues[%d].arn %q: must be the ARN of an SQS queue", i, s.Queues[i].ARN)
+		}
+		if err := s.Queues[i].Validate(); err != nil {
+			return fmt.Errorf("invalid notifications.queues[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the BucketNotificationTarget only contains events that can be reconciled.
+func (t *BucketNotificationTarget) Validate() error {
+	if len(t.Events) == 0 {
+		return fmt.Errorf("events must not be empty")
+	}
+	for _, event := range t.Events {
+		if !s3EventPattern.MatchString(event) {
+			return fmt.Errorf("invalid events entry %q: must be an S3 event type, such as s3:ObjectCreated:*", event)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the EncryptionSpec only contains values that can be reconciled.
+func (s *EncryptionSpec) Validate() error {
+	switch s.Type {
+	case "", EncryptionTypeAES256:
+		if s.KMSKeyID != "" || s.CreateKey {
+			return fmt.Errorf("encryption type %v does not use a KMS key", EncryptionTypeAES256)
+		}
+	case EncryptionTypeKMS:
+		if s.KMSKeyID != "" && s.CreateKey {
+			return fmt.Errorf("encryption kmsKeyId and createKey are mutually exclusive")
+		}
+		if s.KMSKeyID != "" && !kmsKeyPattern.MatchString(s.KMSKeyID) {
+			return fmt.Errorf("invalid encryption kmsKeyId %q: must be the ID, ARN, alias name or alias ARN of a KMS key", s.KMSKeyID)
+		}
+	default:
+		return fmt.Errorf("invalid encryption type %q: must be one of %v or %v", s.Type, EncryptionTypeAES256, EncryptionTypeKMS)
+	}
+
+	return nil
+}
+
+// validateBucketName checks the bucket name against the S3 bucket naming rules.
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html
+func validateBucketName(bucketName string) error {
+	switch {
+	case len(bucketName) < 3 || len(bucketName) > 63:
+		return fmt.Errorf("invalid bucketName %q: must be between 3 and 63 characters long", bucketName)
+	case !bucketNamePattern.MatchString(bucketName):
+		return fmt.Errorf("invalid bucketName %q: must only hold lowercase letters, digits, dots and hyphens, and begin and end with a letter or digit", bucketName)
+	case strings.Contains(bucketName, ".."):
+		return fmt.Errorf("invalid bucketName %q: must not hold consecutive dots", bucketName)
+	case net.ParseIP(bucketName) != nil:
+		return fmt.Errorf("invalid bucketName %q: must not be formatted as an IP address", bucketName)
+	}
+	return nil
+}
@@ -0,0 +1,841 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVeleroSpecValidate(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		slaClass SLAClass
+		wantErr  bool
+	}{
+		{
+			testName: "no sla class",
+			slaClass: "",
+			wantErr:  false,
+		},
+		{
+			testName: "gold sla class",
+			slaClass: SLAClassGold,
+			wantErr:  false,
+		},
+		{
+			testName: "silver sla class",
+			slaClass: SLAClassSilver,
+			wantErr:  false,
+		},
+		{
+			testName: "bronze sla class",
+			slaClass: SLAClassBronze,
+			wantErr:  false,
+		},
+		{
+			testName: "unknown sla class",
+			slaClass: "platinum",
+			wantErr:  true,
+		},
+		{
+			testName: "sla class with wrong case",
+			slaClass: "Gold",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &VeleroSpec{
+				BackupStorageLocation: BackupStorageLocationSpec{
+					SLAClass: tc.slaClass,
+				},
+			}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVeleroSpecValidateStorageLocations(t *testing.T) {
+	var testcases = []struct {
+		testName  string
+		locations []BackupStorageLocationSpec
+		wantErr   bool
+	}{
+		{
+			testName:  "single location",
+			locations: []BackupStorageLocationSpec{{SLAClass: SLAClassGold}},
+			wantErr:   false,
+		},
+		{
+			testName:  "invalid location",
+			locations: []BackupStorageLocationSpec{{SLAClass: "platinum"}},
+			wantErr:   true,
+		},
+		{
+			testName:  "multiple locations",
+			locations: []BackupStorageLocationSpec{{SLAClass: SLAClassGold}, {Name: "secondary", SLAClass: SLAClassBronze}},
+			wantErr:   false,
+		},
+		{
+			testName:  "unnamed additional location",
+			locations: []BackupStorageLocationSpec{{SLAClass: SLAClassGold}, {SLAClass: SLAClassBronze}},
+			wantErr:   true,
+		},
+		{
+			testName:  "duplicate location names",
+			locations: []BackupStorageLocationSpec{{}, {Name: "secondary"}, {Name: "secondary"}},
+			wantErr:   true,
+		},
+		{
+			testName:  "additional location named default",
+			locations: []BackupStorageLocationSpec{{}, {Name: "default"}},
+			wantErr:   true,
+		},
+		{
+			testName:  "first location renamed",
+			locations: []BackupStorageLocationSpec{{Name: "primary"}},
+			wantErr:   true,
+		},
+		{
+			testName:  "additional location with an endpoint",
+			locations: []BackupStorageLocationSpec{{}, {Name: "secondary", Endpoint: "https://minio.example.com"}},
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &VeleroSpec{BackupStorageLocations: tc.locations}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateAdditionalTags(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		tags     map[string]string
+		wantErr  bool
+	}{
+		{
+			testName: "no tags",
+			tags:     nil,
+			wantErr:  false,
+		},
+		{
+			testName: "valid tags",
+			tags:     map[string]string{"cost-center": "1234", "team": ""},
+			wantErr:  false,
+		},
+		{
+			testName: "empty key",
+			tags:     map[string]string{"": "1234"},
+			wantErr:  true,
+		},
+		{
+			testName: "reserved aws prefix",
+			tags:     map[string]string{"aws:createdBy": "velero"},
+			wantErr:  true,
+		},
+		{
+			testName: "key too long",
+			tags:     map[string]string{strings.Repeat("k", maxTagKeyLength+1): "1234"},
+			wantErr:  true,
+		},
+		{
+			testName: "value too long",
+			tags:     map[string]string{"cost-center": strings.Repeat("v", maxTagValueLength+1)},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{AdditionalTags: tc.tags}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateEndpoint(t *testing.T) {
+	var testcases = []struct {
+		testName         string
+		endpoint         string
+		s3ForcePathStyle bool
+		wantErr          bool
+	}{
+		{
+			testName: "aws",
+			wantErr:  false,
+		},
+		{
+			testName:         "minio with path-style addressing",
+			endpoint:         "https://minio.example.com:9000",
+			s3ForcePathStyle: true,
+			wantErr:          false,
+		},
+		{
+			testName: "plain http endpoint",
+			endpoint: "http://rgw.example.com",
+			wantErr:  false,
+		},
+		{
+			testName: "endpoint without a scheme",
+			endpoint: "minio.example.com:9000",
+			wantErr:  true,
+		},
+		{
+			testName: "endpoint with another scheme",
+			endpoint: "ftp://minio.example.com",
+			wantErr:  true,
+		},
+		{
+			testName:         "path-style addressing without an endpoint",
+			s3ForcePathStyle: true,
+			wantErr:          true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{Endpoint: tc.endpoint, S3ForcePathStyle: tc.s3ForcePathStyle}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLifecycleSpecValidate(t *testing.T) {
+	var testcases = []struct {
+		testName      string
+		lifecycleDays int64
+		lifecycle     LifecycleSpec
+		wantErr       bool
+	}{
+		{
+			testName:  "default expiration",
+			lifecycle: LifecycleSpec{},
+			wantErr:   false,
+		},
+		{
+			testName:  "positive days",
+			lifecycle: LifecycleSpec{ExpirationDays: 30, NoncurrentVersionExpirationDays: 7},
+			wantErr:   false,
+		},
+		{
+			testName:  "negative expiration days",
+			lifecycle: LifecycleSpec{ExpirationDays: -1},
+			wantErr:   true,
+		},
+		{
+			testName:  "negative noncurrent version expiration days",
+			lifecycle: LifecycleSpec{NoncurrentVersionExpirationDays: -1},
+			wantErr:   true,
+		},
+		{
+			testName: "transition before expiration",
+			lifecycle: LifecycleSpec{ExpirationDays: 90, Transitions: []LifecycleTransition{
+				{Days: 30, StorageClass: StorageClassStandardIA},
+			}},
+			wantErr: false,
+		},
+		{
+			testName: "transition at expiration",
+			lifecycle: LifecycleSpec{ExpirationDays: 30, Transitions: []LifecycleTransition{
+				{Days: 30, StorageClass: StorageClassGlacier},
+			}},
+			wantErr: true,
+		},
+		{
+			testName:      "transition after lifecycleDays",
+			lifecycleDays: 30,
+			lifecycle: LifecycleSpec{Transitions: []LifecycleTransition{
+				{Days: 60, StorageClass: StorageClassGlacier},
+			}},
+			wantErr: true,
+		},
+		{
+			testName:  "prefix",
+			lifecycle: LifecycleSpec{Prefix: "velero/backups/"},
+			wantErr:   false,
+		},
+		{
+			testName:  "prefix starting with /",
+			lifecycle: LifecycleSpec{Prefix: "/backups/"},
+			wantErr:   true,
+		},
+		{
+			testName:  "prefix too long",
+			lifecycle: LifecycleSpec{Prefix: strings.Repeat("a", 1025)},
+			wantErr:   true,
+		},
+		{
+			testName: "transition to an unknown storage class",
+			lifecycle: LifecycleSpec{Transitions: []LifecycleTransition{
+				{Days: 30, StorageClass: "REDUCED_REDUNDANCY"},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{LifecycleDays: tc.lifecycleDays, Lifecycle: tc.lifecycle}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptionSpecValidate(t *testing.T) {
+	var testcases = []struct {
+		testName   string
+		encryption EncryptionSpec
+		wantErr    bool
+	}{
+		{
+			testName:   "default encryption",
+			encryption: EncryptionSpec{},
+			wantErr:    false,
+		},
+		{
+			testName:   "kms with a key",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, KMSKeyID: "alias/velero"},
+			wantErr:    false,
+		},
+		{
+			testName:   "kms with a created key",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, CreateKey: true},
+			wantErr:    false,
+		},
+		{
+			testName:   "kms with both a key and a created key",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, KMSKeyID: "alias/velero", CreateKey: true},
+			wantErr:    true,
+		},
+		{
+			testName:   "kms with a key ID",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, KMSKeyID: "1234abcd-12ab-34cd-56ef-1234567890ab"},
+			wantErr:    false,
+		},
+		{
+			testName:   "kms with a key ARN",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"},
+			wantErr:    false,
+		},
+		{
+			testName:   "kms with a malformed key",
+			encryption: EncryptionSpec{Type: EncryptionTypeKMS, KMSKeyID: "velero"},
+			wantErr:    true,
+		},
+		{
+			testName:   "created key without kms",
+			encryption: EncryptionSpec{Type: EncryptionTypeAES256, CreateKey: true},
+			wantErr:    true,
+		},
+		{
+			testName:   "unknown encryption type",
+			encryption: EncryptionSpec{Type: "aws:kms:dsse"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if err := tc.encryption.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVeleroServerSpecValidate(t *testing.T) {
+	var testcases = []struct {
+		testName         string
+		plugins          []string
+		backupSyncPeriod *metav1.Duration
+		defaultBackupTTL *metav1.Duration
+		wantErr          bool
+	}{
+		{
+			testName: "no plugins",
+			plugins:  nil,
+			wantErr:  false,
+		},
+		{
+			testName: "plugins",
+			plugins:  []string{"velero/velero-plugin-for-aws:v1.0.0", "velero/velero-plugin-for-csi:v0.1.0"},
+			wantErr:  false,
+		},
+		{
+			testName: "empty plugin",
+			plugins:  []string{""},
+			wantErr:  true,
+		},
+		{
+			testName: "duplicate plugin",
+			plugins:  []string{"velero/velero-plugin-for-aws:v1.0.0", "velero/velero-plugin-for-aws:v1.0.0"},
+			wantErr:  true,
+		},
+		{
+			testName:         "backup sync period",
+			backupSyncPeriod: &metav1.Duration{Duration: time.Minute},
+			wantErr:          false,
+		},
+		{
+			testName:         "zero backup sync period",
+			backupSyncPeriod: &metav1.Duration{},
+			wantErr:          true,
+		},
+		{
+			testName:         "default backup ttl",
+			defaultBackupTTL: &metav1.Duration{Duration: 720 * time.Hour},
+			wantErr:          false,
+		},
+		{
+			testName:         "negative default backup ttl",
+			defaultBackupTTL: &metav1.Duration{Duration: -time.Hour},
+			wantErr:          true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &VeleroServerSpec{
+				Plugins:          tc.plugins,
+				BackupSyncPeriod: tc.backupSyncPeriod,
+				DefaultBackupTTL: tc.defaultBackupTTL,
+			}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateBucketName(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		spec     BackupStorageLocationSpec
+		wantErr  bool
+	}{
+		{
+			testName: "named bucket",
+			spec:     BackupStorageLocationSpec{BucketName: "reviewed-backups"},
+			wantErr:  false,
+		},
+		{
+			testName: "recreate created bucket",
+			spec:     BackupStorageLocationSpec{RecreateOnImmutableChange: true},
+			wantErr:  false,
+		},
+		{
+			testName: "recreate named bucket",
+			spec:     BackupStorageLocationSpec{BucketName: "reviewed-backups", RecreateOnImmutableChange: true},
+			wantErr:  true,
+		},
+		{
+			testName: "uppercase bucket name",
+			spec:     BackupStorageLocationSpec{BucketName: "Reviewed-Backups"},
+			wantErr:  true,
+		},
+		{
+			testName: "bucket name formatted as an IP address",
+			spec:     BackupStorageLocationSpec{BucketName: "192.168.5.4"},
+			wantErr:  true,
+		},
+		{
+			testName: "named bucket with a name prefix",
+			spec:     BackupStorageLocationSpec{BucketName: "reviewed-backups", BucketNamePrefix: "backups"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if err := tc.spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateOutpost(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		spec     BackupStorageLocationSpec
+		wantErr  bool
+	}{
+		{
+			testName: "bucket on an outpost",
+			spec:     BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904", OutpostVPCID: "vpc-0123456789abcdef0"},
+			wantErr:  false,
+		},
+		{
+			testName: "bucket on an outpost with tags and lifecycle",
+			spec: BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904", OutpostVPCID: "vpc-0123456789abcdef0",
+				AdditionalTags: map[string]string{"cost-center": "1234"}, Lifecycle: LifecycleSpec{ExpirationDays: 30}},
+			wantErr: false,
+		},
+		{
+			testName: "invalid outpost ID",
+			spec:     BackupStorageLocationSpec{OutpostID: "outpost-1", OutpostVPCID: "vpc-0123456789abcdef0"},
+			wantErr:  true,
+		},
+		{
+			testName: "outpost without a VPC",
+			spec:     BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904"},
+			wantErr:  true,
+		},
+		{
+			testName: "VPC without an outpost",
+			spec:     BackupStorageLocationSpec{OutpostVPCID: "vpc-0123456789abcdef0"},
+			wantErr:  true,
+		},
+		{
+			testName: "outpost with an endpoint",
+			spec: BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904", OutpostVPCID: "vpc-0123456789abcdef0",
+				Endpoint: "https://minio.example.com"},
+			wantErr: true,
+		},
+		{
+			testName: "outpost with a KMS key",
+			spec: BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904", OutpostVPCID: "vpc-0123456789abcdef0",
+				Encryption: EncryptionSpec{Type: EncryptionTypeKMS, CreateKey: true}},
+			wantErr: true,
+		},
+		{
+			testName: "outpost with a lifecycle transition",
+			spec: BackupStorageLocationSpec{OutpostID: "op-01ac5d28a6a232904", OutpostVPCID: "vpc-0123456789abcdef0",
+				Lifecycle: LifecycleSpec{Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassGlacier}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if err := tc.spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateReplication(t *testing.T) {
+	var testcases = []struct {
+		testName    string
+		replication BucketReplicationSpec
+		wantErr     bool
+	}{
+		{
+			testName: "replication unset",
+			wantErr:  false,
+		},
+		{
+			testName: "destination bucket and role",
+			replication: BucketReplicationSpec{
+				DestinationBucketARN: "arn:aws:s3:::managed-velero-backups-dr",
+				RoleARN:              "arn:aws:iam::123456789012:role/velero-replication",
+			},
+			wantErr: false,
+		},
+		{
+			testName:    "destination bucket without role",
+			replication: BucketReplicationSpec{DestinationBucketARN: "arn:aws:s3:::managed-velero-backups-dr"},
+			wantErr:     true,
+		},
+		{
+			testName: "destination bucket name instead of ARN",
+			replication: BucketReplicationSpec{
+				DestinationBucketARN: "managed-velero-backups-dr",
+				RoleARN:              "arn:aws:iam::123456789012:role/velero-replication",
+			},
+			wantErr: true,
+		},
+		{
+			testName: "role of another service",
+			replication: BucketReplicationSpec{
+				DestinationBucketARN: "arn:aws:s3:::managed-velero-backups-dr",
+				RoleARN:              "arn:aws:sts::123456789012:assumed-role/velero-replication/session",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{Replication: tc.replication}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateCORSRules(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		rules    []BucketCORSRule
+		wantErr  bool
+	}{
+		{
+			testName: "CORS rules unset",
+			wantErr:  false,
+		},
+		{
+			testName: "origins and methods",
+			rules: []BucketCORSRule{{
+				AllowedOrigins: []string{"https://*.example.com"},
+				AllowedMethods: []string{"GET", "HEAD"},
+				AllowedHeaders: []string{"*"},
+				MaxAgeSeconds:  3000,
+			}},
+			wantErr: false,
+		},
+		{
+			testName: "no origins",
+			rules:    []BucketCORSRule{{AllowedMethods: []string{"GET"}}},
+			wantErr:  true,
+		},
+		{
+			testName: "no methods",
+			rules:    []BucketCORSRule{{AllowedOrigins: []string{"*"}}},
+			wantErr:  true,
+		},
+		{
+			testName: "unsupported method",
+			rules:    []BucketCORSRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PATCH"}}},
+			wantErr:  true,
+		},
+		{
+			testName: "origin with two wildcards",
+			rules:    []BucketCORSRule{{AllowedOrigins: []string{"https://*.*.example.com"}, AllowedMethods: []string{"GET"}}},
+			wantErr:  true,
+		},
+		{
+			testName: "negative max age",
+			rules:    []BucketCORSRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, MaxAgeSeconds: -1}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{CORSRules: tc.rules}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateNotifications(t *testing.T) {
+	var testcases = []struct {
+		testName      string
+		notifications BucketNotificationSpec
+		wantErr       bool
+	}{
+		{
+			testName: "notifications unset",
+			wantErr:  false,
+		},
+		{
+			testName: "topic and queue",
+			notifications: BucketNotificationSpec{
+				Topics: []BucketNotificationTarget{{
+					ARN:    "arn:aws:sns:us-east-1:123456789012:velero-backups",
+					Events: []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:Delete"},
+					Suffix: "velero-backup.json",
+				}},
+				Queues: []BucketNotificationTarget{{
+					ARN:    "arn:aws:sqs:us-east-1:123456789012:velero-backups",
+					Events: []string{"s3:ObjectCreated:Put"},
+					Prefix: "backups/",
+				}},
+				EventBridge: true,
+			},
+			wantErr: false,
+		},
+		{
+			testName: "queue ARN as topic",
+			notifications: BucketNotificationSpec{
+				Topics: []BucketNotificationTarget{{ARN: "arn:aws:sqs:us-east-1:123456789012:velero-backups", Events: []string{"s3:ObjectCreated:*"}}},
+			},
+			wantErr: true,
+		},
+		{
+			testName: "no events",
+			notifications: BucketNotificationSpec{
+				Queues: []BucketNotificationTarget{{ARN: "arn:aws:sqs:us-east-1:123456789012:velero-backups"}},
+			},
+			wantErr: true,
+		},
+		{
+			testName: "invalid event",
+			notifications: BucketNotificationSpec{
+				Queues: []BucketNotificationTarget{{ARN: "arn:aws:sqs:us-east-1:123456789012:velero-backups", Events: []string{"ObjectCreated"}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{Notifications: tc.notifications}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateManageEncryption(t *testing.T) {
+	unmanaged, managed := false, true
+	var testcases = []struct {
+		testName string
+		spec     BackupStorageLocationSpec
+		wantErr  bool
+	}{
+		{
+			testName: "encryption unmanaged",
+			spec:     BackupStorageLocationSpec{ManageEncryption: &unmanaged},
+			wantErr:  false,
+		},
+		{
+			testName: "encryption managed with KMS",
+			spec:     BackupStorageLocationSpec{ManageEncryption: &managed, Encryption: EncryptionSpec{Type: EncryptionTypeKMS, CreateKey: true}},
+			wantErr:  false,
+		},
+		{
+			testName: "encryption unmanaged with an encryption type",
+			spec:     BackupStorageLocationSpec{ManageEncryption: &unmanaged, Encryption: EncryptionSpec{Type: EncryptionTypeAES256}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if err := tc.spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateManageTags(t *testing.T) {
+	unmanaged := false
+	var testcases = []struct {
+		testName string
+		spec     BackupStorageLocationSpec
+		wantErr  bool
+	}{
+		{
+			testName: "tags unmanaged",
+			spec:     BackupStorageLocationSpec{ManageTags: &unmanaged, SLAClass: SLAClassGold},
+			wantErr:  false,
+		},
+		{
+			testName: "tags unmanaged with additional tags",
+			spec:     BackupStorageLocationSpec{ManageTags: &unmanaged, AdditionalTags: map[string]string{"team": "backup"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if err := tc.spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateLogging(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		logging  BucketLoggingSpec
+		wantErr  bool
+	}{
+		{
+			testName: "logging unset",
+			wantErr:  false,
+		},
+		{
+			testName: "target bucket and prefix",
+			logging:  BucketLoggingSpec{TargetBucket: "audit-logs", TargetPrefix: "velero/"},
+			wantErr:  false,
+		},
+		{
+			testName: "prefix without target bucket",
+			logging:  BucketLoggingSpec{TargetPrefix: "velero/"},
+			wantErr:  true,
+		},
+		{
+			testName: "delivery granted by bucket policy",
+			logging:  BucketLoggingSpec{TargetBucket: "audit-logs", DeliveryPermission: LogDeliveryPermissionBucketPolicy},
+			wantErr:  false,
+		},
+		{
+			testName: "delivery permission without target bucket",
+			logging:  BucketLoggingSpec{DeliveryPermission: LogDeliveryPermissionACL},
+			wantErr:  true,
+		},
+		{
+			testName: "unsupported delivery permission",
+			logging:  BucketLoggingSpec{TargetBucket: "audit-logs", DeliveryPermission: "Grant"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{Logging: tc.logging}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupStorageLocationSpecValidateAllowedRoleARNs(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		roleARNs []string
+		wantErr  bool
+	}{
+		{
+			testName: "allowed roles unset",
+			wantErr:  false,
+		},
+		{
+			testName: "Velero and operator roles",
+			roleARNs: []string{"arn:aws:iam::123456789012:role/velero", "arn:aws:iam::123456789012:role/openshift/managed-velero-operator"},
+			wantErr:  false,
+		},
+		{
+			testName: "user ARN",
+			roleARNs: []string{"arn:aws:iam::123456789012:user/velero"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			spec := &BackupStorageLocationSpec{AllowedRoleARNs: tc.roleARNs}
+			if err := spec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -1,21 +1,531 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // VeleroSpec defines the desired state of Velero
 // +k8s:openapi-gen=true
-type VeleroSpec struct{}
+type VeleroSpec struct {
+	// BackupStorageLocation configures the storage used for Velero backups
+	// Deprecated: use BackupStorageLocations, which this is migrated into
+	// +optional
+	BackupStorageLocation BackupStorageLocationSpec `json:"backupStorageLocation,omitempty"`
+
+	// BackupStorageLocations configures the storage used for Velero backups. Every location gets a bucket of its own, and is
+	// installed as a Velero BackupStorageLocation of its own, the first location being the default one. The conditions of
+	// the buckets report a failure of any of the locations
+	// +optional
+	BackupStorageLocations []BackupStorageLocationSpec `json:"backupStorageLocations,omitempty"`
+
+	// Velero configures the Velero server
+	// +optional
+	Velero VeleroServerSpec `json:"velero,omitempty"`
+
+	// NodeAgent configures the scheduling of the Velero node agent, which is only deployed when this is set
+	// +optional
+	NodeAgent *NodeAgentSpec `json:"nodeAgent,omitempty"`
+
+	// ReconcileDeadline is how long reconciling may fail continuously before the operator gives up until the spec changes
+	// +optional
+	ReconcileDeadline *metav1.Duration `json:"reconcileDeadline,omitempty"`
+
+	// LegacyInfraNames are the former infrastructure names of the cluster, such as from before it was renamed during
+	// an upgrade. A bucket tagged with one of them is recovered when none is tagged with the current name, and is
+	// tagged again with the current name
+	// +optional
+	LegacyInfraNames []string `json:"legacyInfraNames,omitempty"`
+}
+
+// VeleroServerSpec defines the desired state of the Velero server
+// +k8s:openapi-gen=true
+type VeleroServerSpec struct {
+	// Plugins is the list of plugin images installed into the Velero server
+	// +optional
+	Plugins []string `json:"plugins,omitempty"`
+
+	// BackupSyncPeriod is how often Velero syncs backups from the backup storage location
+	// +optional
+	BackupSyncPeriod *metav1.Duration `json:"backupSyncPeriod,omitempty"`
+
+	// DefaultBackupTTL is how long backups are kept when they don't set their own TTL
+	// +optional
+	DefaultBackupTTL *metav1.Duration `json:"defaultBackupTTL,omitempty"`
+
+	// Monitoring configures the scraping of the Velero server metrics
+	// +optional
+	Monitoring MonitoringSpec `json:"monitoring,omitempty"`
+}
+
+// MonitoringSpec defines how the Velero server metrics are scraped
+// +k8s:openapi-gen=true
+type MonitoringSpec struct {
+	// Enabled has the operator create a Prometheus Operator ServiceMonitor scraping the Velero server metrics
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// NodeAgentSpec defines the desired state of the Velero node agent DaemonSet
+// +k8s:openapi-gen=true
+type NodeAgentSpec struct {
+	// Tolerations are applied to the node agent pods
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// NodeSelector restricts the nodes the node agent pods are scheduled on
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// BackupStorageLocationSpec defines the desired state of the backup storage location
+// +k8s:openapi-gen=true
+type BackupStorageLocationSpec struct {
+	// Name is the name of the backup storage location, which its bucket is tagged with. The first location is always named default,
+	// and every further location needs a unique name
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// SLAClass is the backup SLA class applied to the bucket and the Velero BackupStorageLocation
+	// +optional
+	SLAClass SLAClass `json:"slaClass,omitempty"`
+
+	// Encryption configures the server-side encryption of the bucket
+	// +optional
+	Encryption EncryptionSpec `json:"encryption,omitempty"`
+
+	// VerifyWritablePrefix is the key prefix the probe object verifying the bucket is writable is written under, defaulting to .managed-velero-operator/
+	// +optional
+	VerifyWritablePrefix string `json:"verifyWritablePrefix,omitempty"`
+
+	// RecreateOnImmutableChange has the operator recreate the bucket when its encryption can only be set at creation, which requires the bucket to be empty
+	// +optional
+	RecreateOnImmutableChange bool `json:"recreateOnImmutableChange,omitempty"`
+
+	// ForceRecreate allows RecreateOnImmutableChange to delete the contents of a bucket which isn't empty
+	// +optional
+	ForceRecreate bool `json:"forceRecreate,omitempty"`
+
+	// DeleteBucketOnUninstall has the operator empty and delete the bucket, including every object version, when the Velero
+	// instance is deleted. All backups are lost, so it defaults to false, which leaves the bucket in place
+	// +optional
+	DeleteBucketOnUninstall bool `json:"deleteBucketOnUninstall,omitempty"`
+
+	// DenySSEC adds a bucket policy statement rejecting uploads encrypted with customer-provided keys (SSE-C)
+	// +optional
+	DenySSEC bool `json:"denySSEC,omitempty"`
+
+	// AllowedRoleARNs adds a bucket policy statement denying access to the bucket to every principal other than these IAM
+	// roles, which have to include the roles of Velero and of the operator
+	// +optional
+	AllowedRoleARNs []string `json:"allowedRoleArns,omitempty"`
+
+	// LifecycleDays is how many days backups are kept in the bucket before they expire, defaulting to 90. Lifecycle.ExpirationDays takes precedence when set
+	// +optional
+	LifecycleDays int64 `json:"lifecycleDays,omitempty"`
+
+	// Lifecycle configures the lifecycle rule expiring the backups in the bucket
+	// +optional
+	Lifecycle LifecycleSpec `json:"lifecycle,omitempty"`
+
+	// ManageLifecycle set to false leaves the lifecycle rules of the bucket to the user, such as an external governance tool, so the
+	// operator neither configures them nor checks them for drift. Defaults to true
+	// +optional
+	ManageLifecycle *bool `json:"manageLifecycle,omitempty"`
+
+	// ManagePublicAccessBlock set to false leaves the public access block of the bucket unchanged, for S3-compatible backends which
+	// don't implement it, such as MinIO. Backends answering NotImplemented are skipped even when it is true. Defaults to true
+	// +optional
+	ManagePublicAccessBlock *bool `json:"managePublicAccessBlock,omitempty"`
+
+	// ManageEncryption set to false leaves the default encryption of the bucket unchanged, for gateways which forbid S3 server-side
+	// encryption, such as those encrypting through an external KMS proxy. The encryption isn't checked for drift either, and Encryption
+	// can't be set. Defaults to true
+	// +optional
+	ManageEncryption *bool `json:"manageEncryption,omitempty"`
+
+	// ManageTags set to false leaves the tags of the bucket unchanged, for organizations which forbid the operator to tag
+	// buckets. Existing buckets are still recovered from their tags, and a bucket lacking the operator's tags is identified
+	// by its name recorded in the status once recovered or created. AdditionalTags can't be set. Defaults to true
+	// +optional
+	ManageTags *bool `json:"manageTags,omitempty"`
+
+	// Versioning enables object versioning on the bucket, which protects backups against accidental deletion. The noncurrent versions
+	// expire after Lifecycle.NoncurrentVersionExpirationDays, defaulting to the backup expiration, so they don't grow the bucket unbounded.
+	// Disabling it leaves versioning on the bucket unchanged
+	// +optional
+	Versioning bool `json:"versioning,omitempty"`
+
+	// Logging delivers the S3 server access logs of the bucket to another bucket. The logging of the bucket is left unchanged when unset
+	// +optional
+	Logging BucketLoggingSpec `json:"logging,omitempty"`
+
+	// Replication replicates the backups in the bucket to a destination bucket, such as one in another region for disaster recovery.
+	// Replication requires versioning to be enabled on the bucket. The replication of the bucket is left unchanged when unset
+	// +optional
+	Replication BucketReplicationSpec `json:"replication,omitempty"`
+
+	// CORSRules are the cross-origin resource sharing rules of the bucket, such as for serving assets from it to a browser. The rules
+	// of the bucket are replaced by these, and left unchanged when unset
+	// +optional
+	CORSRules []BucketCORSRule `json:"corsRules,omitempty"`
+
+	// Notifications sends the events of the bucket, such as a backup being written, to SNS topics, SQS queues or Amazon
+	// EventBridge. The notification configuration of the bucket is replaced by this, and left unchanged when unset
+	// +optional
+	Notifications BucketNotificationSpec `json:"notifications,omitempty"`
+
+	// BucketNamePrefix replaces the prefix of the S3 bucket names the operator generates, which become <prefix>-<infrastructure name>-<random>.
+	// The random suffix is shortened to keep the name within 63 characters, unless its length is set by the operator's
+	// --bucket-name-suffix-length, when a name which doesn't fit is rejected
+	// +optional
+	BucketNamePrefix string `json:"bucketNamePrefix,omitempty"`
+
+	// BucketName is an existing bucket the backups are stored in, which the operator never creates. It is tagged, encrypted and configured
+	// like a bucket the operator created, and has to be created beforehand in the region of the storage location
+	// +optional
+	BucketName string `json:"bucketName,omitempty"`
+
+	// Region is the AWS region the bucket is created in, defaulting to the cluster's region for the first location, and to the region
+	// of the first location otherwise. It doesn't move an existing bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Endpoint is the URL of an S3-compatible object store, such as MinIO or Ceph RGW, addressed instead of AWS S3. It takes precedence over the operator's --s3-endpoint flag
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// S3ForcePathStyle addresses the bucket in the path of the Endpoint URL rather than as a subdomain of its host, which MinIO requires
+	// +optional
+	S3ForcePathStyle bool `json:"s3ForcePathStyle,omitempty"`
+
+	// OutpostID is the ID of the AWS Outpost the bucket is created on, in its S3 on Outposts resource, rather than in the region. Only
+	// the tags and lifecycle of a bucket on an Outpost are managed, and Velero addresses the bucket through an access point
+	// +optional
+	OutpostID string `json:"outpostId,omitempty"`
+
+	// OutpostVPCID is the ID of the VPC the access point of the bucket on the Outpost accepts requests from, which is required with
+	// OutpostID
+	// +optional
+	OutpostVPCID string `json:"outpostVpcId,omitempty"`
+
+	// AdditionalTags are applied to the bucket alongside the operator's tags, such as cost allocation tags. The operator's tags win on conflicting keys
+	// +optional
+	AdditionalTags map[string]string `json:"additionalTags,omitempty"`
+
+	// AccessMode set to ReadOnly makes the Velero BackupStorageLocation read-only, and denies writes to the bucket, defaulting to ReadWrite
+	// +optional
+	AccessMode AccessMode `json:"accessMode,omitempty"`
+}
+
+// AccessMode is the access Velero has to the backup storage location
+// +kubebuilder:validation:Enum=ReadWrite;ReadOnly
+type AccessMode string
+
+const (
+	// AccessModeReadWrite lets Velero write backups to the backup storage location
+	AccessModeReadWrite AccessMode = "ReadWrite"
+	// AccessModeReadOnly only lets Velero restore from the backup storage location
+	AccessModeReadOnly AccessMode = "ReadOnly"
+)
+
+// LifecycleSpec defines the lifecycle rule expiring the backups in the bucket
+// +k8s:openapi-gen=true
+type LifecycleSpec struct {
+	// ExpirationDays is how many days backups are kept in the bucket before they expire, defaulting to LifecycleDays
+	// +optional
+	ExpirationDays int64 `json:"expirationDays,omitempty"`
+
+	// NoncurrentVersionExpirationDays is how many days the noncurrent versions of backups are kept in a versioned bucket, defaulting to the expiration of the backups
+	// +optional
+	NoncurrentVersionExpirationDays int64 `json:"noncurrentVersionExpirationDays,omitempty"`
+
+	// Transitions move the backups to cheaper storage classes as they age, each before the backups expire
+	// +optional
+	Transitions []LifecycleTransition `json:"transitions,omitempty"`
+
+	// Prefix is the key prefix of the objects the lifecycle rule expires, defaulting to backups/, so that other data in
+	// the bucket is kept
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// LifecycleTransition defines when the backups in the bucket move to another storage class
+// +k8s:openapi-gen=true
+type LifecycleTransition struct {
+	// Days is how many days after their creation the backups move to the storage class
+	// +kubebuilder:validation:Minimum=0
+	Days int64 `json:"days"`
+
+	// StorageClass is the S3 storage class the backups move to
+	StorageClass StorageClass `json:"storageClass"`
+}
+
+// StorageClass is an S3 storage class the backups can be transitioned to
+// +kubebuilder:validation:Enum=STANDARD_IA;ONEZONE_IA;INTELLIGENT_TIERING;GLACIER_IR;GLACIER;DEEP_ARCHIVE
+type StorageClass string
+
+const (
+	// StorageClassStandardIA stores the backups for infrequent access
+	StorageClassStandardIA StorageClass = "STANDARD_IA"
+	// StorageClassOneZoneIA stores the backups for infrequent access in a single availability zone
+	StorageClassOneZoneIA StorageClass = "ONEZONE_IA"
+	// StorageClassIntelligentTiering moves the backups between access tiers as they are accessed
+	StorageClassIntelligentTiering StorageClass = "INTELLIGENT_TIERING"
+	// StorageClassGlacierIR archives the backups with instant retrieval
+	StorageClassGlacierIR StorageClass = "GLACIER_IR"
+	// StorageClassGlacier archives the backups, which have to be restored from the archive before Velero can read them
+	StorageClassGlacier StorageClass = "GLACIER"
+	// StorageClassDeepArchive archives the backups at the lowest cost and slowest retrieval
+	StorageClassDeepArchive StorageClass = "DEEP_ARCHIVE"
+)
+
+// BucketLoggingSpec defines where the S3 server access logs of the bucket are delivered
+// +k8s:openapi-gen=true
+type BucketLoggingSpec struct {
+	// TargetBucket is the existing bucket the access logs are delivered to, in the region of the bucket, which the S3 log
+	// delivery is allowed to write to as selected by DeliveryPermission
+	// +optional
+	TargetBucket string `json:"targetBucket,omitempty"`
+
+	// TargetPrefix is the key prefix the access logs are written under in the target bucket
+	// +optional
+	TargetPrefix string `json:"targetPrefix,omitempty"`
+
+	// DeliveryPermission selects how the S3 log delivery is allowed to write to the target bucket. By default, the bucket
+	// policy of the target bucket grants it when the ACLs of the target bucket are disabled by the BucketOwnerEnforced
+	// object ownership, and it is left to the ACL of the target bucket otherwise
+	// +optional
+	DeliveryPermission LogDeliveryPermission `json:"deliveryPermission,omitempty"`
+}
+
+// LogDeliveryPermission is how the S3 log delivery is allowed to write the access logs to the target bucket
+// +kubebuilder:validation:Enum=BucketPolicy;ACL
+type LogDeliveryPermission string
+
+const (
+	// LogDeliveryPermissionBucketPolicy grants the S3 logging service principal the writes in the bucket policy of the
+	// target bucket
+	LogDeliveryPermissionBucketPolicy LogDeliveryPermission = "BucketPolicy"
+	// LogDeliveryPermissionACL leaves the writes to the ACL of the target bucket, which has to grant the S3 log delivery
+	// group write access
+	LogDeliveryPermissionACL LogDeliveryPermission = "ACL"
+)
+
+// BucketReplicationSpec defines where the objects of the bucket are replicated to
+// +k8s:openapi-gen=true
+type BucketReplicationSpec struct {
+	// DestinationBucketARN is the ARN of the existing, versioned bucket the objects are replicated to
+	// +optional
+	DestinationBucketARN string `json:"destinationBucketArn,omitempty"`
+
+	// RoleARN is the ARN of the IAM role S3 assumes to replicate the objects, which has to be allowed to read the bucket and write to the destination bucket
+	// +optional
+	RoleARN string `json:"roleArn,omitempty"`
+}
+
+// BucketCORSRule defines which cross-origin requests are allowed to the bucket
+// +k8s:openapi-gen=true
+type BucketCORSRule struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, which may hold a single * wildcard
+	AllowedOrigins []string `json:"allowedOrigins"`
+
+	// AllowedMethods are the HTTP methods the origins may use: GET, PUT, POST, DELETE or HEAD
+	AllowedMethods []string `json:"allowedMethods"`
+
+	// AllowedHeaders are the headers allowed in the preflight requests, which may hold a single * wildcard
+	// +optional
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+
+	// ExposeHeaders are the response headers the browser may expose to the requesting application
+	// +optional
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+
+	// MaxAgeSeconds is how long the browser may cache the response to a preflight request
+	// +optional
+	MaxAgeSeconds int64 `json:"maxAgeSeconds,omitempty"`
+}
+
+// BucketNotificationSpec defines where the events of the bucket are sent
+// +k8s:openapi-gen=true
+type BucketNotificationSpec struct {
+	// Topics are the SNS topics notified of the events of the bucket
+	// +optional
+	Topics []BucketNotificationTarget `json:"topics,omitempty"`
+
+	// Queues are the SQS queues notified of the events of the bucket
+	// +optional
+	Queues []BucketNotificationTarget `json:"queues,omitempty"`
+
+	// EventBridge sends every event of the bucket to Amazon EventBridge
+	// +optional
+	EventBridge bool `json:"eventBridge,omitempty"`
+}
+
+// BucketNotificationTarget defines which events of the bucket are sent to an SNS topic or SQS queue
+// +k8s:openapi-gen=true
+type BucketNotificationTarget struct {
+	// ARN is the ARN of the SNS topic or SQS queue, whose policy has to allow S3 to send messages to it
+	ARN string `json:"arn"`
+
+	// Events are the S3 event types sent, such as s3:ObjectCreated:*
+	Events []string `json:"events"`
+
+	// Prefix restricts the events to the objects whose keys start with it, such as backups/
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix restricts the events to the objects whose keys end with it, such as velero-backup.json
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// EncryptionSpec defines the server-side encryption of the bucket
+// +k8s:openapi-gen=true
+type EncryptionSpec struct {
+	// Type is the server-side encryption algorithm used for the bucket, defaulting to AES256
+	// +optional
+	Type EncryptionType `json:"type,omitempty"`
+
+	// KMSKeyID is the KMS key used to encrypt the bucket when Type is aws:kms.
+	// An alias, such as alias/velero, is resolved on every reconcile, so that
+	// the key behind the alias can be rotated without encrypting the bucket again
+	// +optional
+	KMSKeyID string `json:"kmsKeyId,omitempty"`
+
+	// CreateKey has the operator create a dedicated KMS key for the bucket when Type is aws:kms and no KMSKeyID is given
+	// +optional
+	CreateKey bool `json:"createKey,omitempty"`
+}
+
+// EncryptionType is a server-side encryption algorithm for the bucket
+// +kubebuilder:validation:Enum=AES256;aws:kms
+type EncryptionType string
+
+const (
+	// EncryptionTypeAES256 encrypts the bucket with S3 managed keys
+	EncryptionTypeAES256 EncryptionType = "AES256"
+	// EncryptionTypeKMS encrypts the bucket with a KMS key
+	EncryptionTypeKMS EncryptionType = "aws:kms"
+)
+
+// SLAClass is the service level class of the backups stored in a bucket
+// +kubebuilder:validation:Enum=gold;silver;bronze
+type SLAClass string
+
+const (
+	// SLAClassGold is the highest backup service level
+	SLAClassGold SLAClass = "gold"
+	// SLAClassSilver is the intermediate backup service level
+	SLAClassSilver SLAClass = "silver"
+	// SLAClassBronze is the lowest backup service level
+	SLAClassBronze SLAClass = "bronze"
+)
 
 // VeleroStatus defines the observed state of Velero
 // +k8s:openapi-gen=true
 type VeleroStatus struct {
-	// S3Bucket contains details of the S3 storage bucket for backups
+	// S3Bucket contains details of the storage bucket for backups: the S3 bucket on AWS, the GCS
+	// bucket on GCP, or the Blob storage container on Azure
 	// +optional
 	S3Bucket S3Bucket `json:"s3Bucket,omitempty"`
+
+	// AdditionalS3Buckets contains details of the S3 buckets of the backup storage locations after the first
+	// +optional
+	AdditionalS3Buckets []LocationS3Bucket `json:"additionalS3Buckets,omitempty"`
+
+	// Conditions are the latest observations of the state of the Velero installation
+	// +optional
+	Conditions []VeleroCondition `json:"conditions,omitempty"`
+
+	// FailingSince is when reconciling started failing continuously
+	// +optional
+	FailingSince *metav1.Time `json:"failingSince,omitempty"`
+
+	// FailingGeneration is the generation of the spec which is failing to reconcile
+	// +optional
+	FailingGeneration int64 `json:"failingGeneration,omitempty"`
+
+	// ConsecutiveFailures is how many times in a row reconciling the spec failed
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the Velero installation last reached the desired state for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// VeleroCondition describes the state of the Velero installation at a certain point
+// +k8s:openapi-gen=true
+type VeleroCondition struct {
+	// Type is the type of the condition
+	Type VeleroConditionType `json:"type"`
+
+	// Status is the status of the condition, one of True, False or Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the last time the condition changed status
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief machine readable explanation for the condition's last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the details of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// VeleroConditionType is a valid value for VeleroCondition.Type
+type VeleroConditionType string
+
+const (
+	// BucketDrifted is True when the bucket configuration differs from the configuration the operator enforces
+	BucketDrifted VeleroConditionType = "BucketDrifted"
+	// BucketWritable is True when the operator could write to the bucket
+	BucketWritable VeleroConditionType = "BucketWritable"
+	// BackupStorageLocationAvailable is True when Velero reports the BackupStorageLocation as available
+	BackupStorageLocationAvailable VeleroConditionType = "BackupStorageLocationAvailable"
+	// TagPolicyViolation is True when the bucket tags don't comply with the configured tag policy
+	TagPolicyViolation VeleroConditionType = "TagPolicyViolation"
+	// ReconcileFailed is True when reconciling failed for longer than the reconcile deadline, and was stopped
+	ReconcileFailed VeleroConditionType = "ReconcileFailed"
+	// Degraded is True when reconciling failed more times in a row than the operator allows, and is retried less often
+	Degraded VeleroConditionType = "Degraded"
+	// LifecycleRetentionClamped is True when the lifecycle retention was reduced to the operator's maximum
+	LifecycleRetentionClamped VeleroConditionType = "LifecycleRetentionClamped"
+	// LifecycleRetentionRejected is True when the lifecycle retention exceeds the operator's maximum, and wasn't applied
+	LifecycleRetentionRejected VeleroConditionType = "LifecycleRetentionRejected"
+	// InvalidRegion is True when the cluster's region isn't a known region of its AWS partition
+	InvalidRegion VeleroConditionType = "InvalidRegion"
+	// InvalidBucketName is True when the bucket name breaks the S3 bucket naming rules, and the bucket can't be created
+	InvalidBucketName VeleroConditionType = "InvalidBucketName"
+	// CredentialsValid is False when the credentials are denied any of the S3 permissions checked before reconciling the bucket
+	CredentialsValid VeleroConditionType = "CredentialsValid"
+	// BucketReady is True when the bucket exists and was fully configured by the last sync
+	BucketReady VeleroConditionType = "BucketReady"
+	// EncryptionConfigured is True when the bucket encryption was enforced
+	EncryptionConfigured VeleroConditionType = "EncryptionConfigured"
+	// PublicAccessBlocked is True when public access to the bucket was blocked
+	PublicAccessBlocked VeleroConditionType = "PublicAccessBlocked"
+	// BucketUnavailable is True when the bucket named in the spec doesn't exist, or belongs to another account
+	BucketUnavailable VeleroConditionType = "BucketUnavailable"
+	// LifecycleObjectLockConflict is True when object lock is enabled on the bucket, so the lifecycle expiration doesn't delete locked backups
+	LifecycleObjectLockConflict VeleroConditionType = "LifecycleObjectLockConflict"
+	// ReplicationConfigured is True when the replication of the bucket to the destination bucket was enforced
+	ReplicationConfigured VeleroConditionType = "ReplicationConfigured"
+	// KMSKeyRotated is True when the bucket is encrypted with another KMS key than the configured key ID it was last encrypted
+	// with, which is left in place until the key ID is changed
+	KMSKeyRotated VeleroConditionType = "KMSKeyRotated"
+	// KMSKeyUsable is False when the credentials can't use the KMS key to encrypt the backups, which is checked before the bucket is encrypted with it
+	KMSKeyUsable VeleroConditionType = "KMSKeyUsable"
+	// VeleroInstalled is True when Velero is installed and reconciled, and False on platforms where only the storage
+	// for backups is managed, such as GCP and Azure
+	VeleroInstalled VeleroConditionType = "VeleroInstalled"
+)
+
 // S3Bucket defines the observed state of Velero
 // +k8s:openapi-gen=true
 type S3Bucket struct {
@@ -26,8 +536,95 @@ type S3Bucket struct {
 	// Provisioned is true once the bucket has been initially provisioned.
 	Provisioned bool `json:"provisioned"`
 
+	// Created is true when the operator created the bucket, rather than adopting an existing one.
+	// +optional
+	Created bool `json:"created,omitempty"`
+
+	// CreatedAt is the creation date of the bucket, as listed by the account of the credentials.
+	// +optional
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+
+	// OwnerAccount is the ID of the AWS account owning the bucket, which is the account of the credentials the
+	// bucket was listed with.
+	// +optional
+	OwnerAccount string `json:"ownerAccount,omitempty"`
+
+	// KMSKeyARN is the ARN of the KMS key created by the operator to encrypt the bucket.
+	KMSKeyARN string `json:"kmsKeyArn,omitempty"`
+
+	// AppliedKMSKeyID is the configured KMS key ID the bucket was last encrypted with.
+	// +optional
+	AppliedKMSKeyID string `json:"appliedKmsKeyId,omitempty"`
+
+	// ClusterID is the ID of the cluster the bucket is tagged with.
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// ClusterVersion is the version of the cluster the bucket is tagged with.
+	ClusterVersion string `json:"clusterVersion,omitempty"`
+
+	// Region is the region the bucket was found to live in, when it differs from the region of its backup storage
+	// location, such as for a recovered bucket. The bucket is then addressed in this region.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// InfrastructureName is the infrastructure name of the cluster the bucket is tagged with, before the characters
+	// disallowed in tag values were replaced in its tag
+	InfrastructureName string `json:"infrastructureName,omitempty"`
+
+	// MigratedFromInfrastructureName is the legacy infrastructure name the bucket was tagged with when it was
+	// recovered, before it was tagged with the current infrastructure name
+	// +optional
+	MigratedFromInfrastructureName string `json:"migratedFromInfrastructureName,omitempty"`
+
+	// Versioned is true when versioning is enabled on the bucket.
+	Versioned bool `json:"versioned,omitempty"`
+
+	// ReadOnly is true when the bucket policy denies writes to the bucket.
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// OutpostAccessPointARN is the ARN of the access point Velero addresses the bucket on an Outpost through.
+	OutpostAccessPointARN string `json:"outpostAccessPointArn,omitempty"`
+
+	// ExpirationDays is the backup expiration the lifecycle rules were last configured for, or 0 while they are left to the user.
+	ExpirationDays int64 `json:"expirationDays,omitempty"`
+
+	// NoncurrentExpirationDays is the noncurrent version expiration the lifecycle rules were last configured for.
+	NoncurrentExpirationDays int64 `json:"noncurrentExpirationDays,omitempty"`
+
+	// Transitions are the storage class transitions the lifecycle rules were last configured for.
+	Transitions []LifecycleTransition `json:"transitions,omitempty"`
+
+	// LifecyclePrefix is the key prefix the lifecycle rules were last configured for, or empty for the default prefix.
+	LifecyclePrefix string `json:"lifecyclePrefix,omitempty"`
+
 	// LastSyncTimestamp is the time that the bucket policy was last synced.
 	LastSyncTimestamp *metav1.Time `json:"lastSyncTimestamp,omitempty"`
+
+	// ObjectCount is the number of objects in the bucket when it was last inventoried, which excludes noncurrent versions
+	// +optional
+	ObjectCount int64 `json:"objectCount,omitempty"`
+
+	// TotalBytes is the total size of the objects in the bucket when it was last inventoried, which excludes noncurrent versions
+	// +optional
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+
+	// InventoryTimestamp is the time the objects in the bucket were last inventoried, when the operator's --inventory-interval is set
+	// +optional
+	InventoryTimestamp *metav1.Time `json:"inventoryTimestamp,omitempty"`
+
+	// PlannedActions are the S3 calls changing the bucket which the last sync skipped, as the operator runs in dry-run mode
+	// +optional
+	PlannedActions []string `json:"plannedActions,omitempty"`
+}
+
+// LocationS3Bucket defines the observed state of the S3 bucket of a backup storage location
+// +k8s:openapi-gen=true
+type LocationS3Bucket struct {
+	// Location is the name of the backup storage location
+	Location string `json:"location"`
+
+	// S3Bucket contains details of the S3 bucket of the backup storage location
+	S3Bucket S3Bucket `json:"s3Bucket"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
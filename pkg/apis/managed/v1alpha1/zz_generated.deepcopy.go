@@ -5,16 +5,331 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorageLocationSpec) DeepCopyInto(out *BackupStorageLocationSpec) {
+	*out = *in
+	out.Encryption = in.Encryption
+	if in.AllowedRoleARNs != nil {
+		in, out := &in.AllowedRoleARNs, &out.AllowedRoleARNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Lifecycle.DeepCopyInto(&out.Lifecycle)
+	if in.ManageLifecycle != nil {
+		in, out := &in.ManageLifecycle, &out.ManageLifecycle
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ManagePublicAccessBlock != nil {
+		in, out := &in.ManagePublicAccessBlock, &out.ManagePublicAccessBlock
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ManageEncryption != nil {
+		in, out := &in.ManageEncryption, &out.ManageEncryption
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ManageTags != nil {
+		in, out := &in.ManageTags, &out.ManageTags
+		*out = new(bool)
+		**out = **in
+	}
+	out.Logging = in.Logging
+	out.Replication = in.Replication
+	if in.CORSRules != nil {
+		in, out := &in.CORSRules, &out.CORSRules
+		*out = make([]BucketCORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Notifications.DeepCopyInto(&out.Notifications)
+	if in.AdditionalTags != nil {
+		in, out := &in.AdditionalTags, &out.AdditionalTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageLocationSpec.
+func (in *BackupStorageLocationSpec) DeepCopy() *BackupStorageLocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorageLocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketCORSRule) DeepCopyInto(out *BucketCORSRule) {
+	*out = *in
+	if in.AllowedOrigins != nil {
+		in, out := &in.AllowedOrigins, &out.AllowedOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedMethods != nil {
+		in, out := &in.AllowedMethods, &out.AllowedMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedHeaders != nil {
+		in, out := &in.AllowedHeaders, &out.AllowedHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposeHeaders != nil {
+		in, out := &in.ExposeHeaders, &out.ExposeHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketCORSRule.
+func (in *BucketCORSRule) DeepCopy() *BucketCORSRule {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketCORSRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLoggingSpec) DeepCopyInto(out *BucketLoggingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLoggingSpec.
+func (in *BucketLoggingSpec) DeepCopy() *BucketLoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketNotificationSpec) DeepCopyInto(out *BucketNotificationSpec) {
+	*out = *in
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]BucketNotificationTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Queues != nil {
+		in, out := &in.Queues, &out.Queues
+		*out = make([]BucketNotificationTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketNotificationSpec.
+func (in *BucketNotificationSpec) DeepCopy() *BucketNotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketNotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketNotificationTarget) DeepCopyInto(out *BucketNotificationTarget) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketNotificationTarget.
+func (in *BucketNotificationTarget) DeepCopy() *BucketNotificationTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketNotificationTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationSpec) DeepCopyInto(out *BucketReplicationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationSpec.
+func (in *BucketReplicationSpec) DeepCopy() *BucketReplicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EncryptionSpec) DeepCopyInto(out *EncryptionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EncryptionSpec.
+func (in *EncryptionSpec) DeepCopy() *EncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleSpec) DeepCopyInto(out *LifecycleSpec) {
+	*out = *in
+	if in.Transitions != nil {
+		in, out := &in.Transitions, &out.Transitions
+		*out = make([]LifecycleTransition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleSpec.
+func (in *LifecycleSpec) DeepCopy() *LifecycleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleTransition) DeepCopyInto(out *LifecycleTransition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleTransition.
+func (in *LifecycleTransition) DeepCopy() *LifecycleTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocationS3Bucket) DeepCopyInto(out *LocationS3Bucket) {
+	*out = *in
+	in.S3Bucket.DeepCopyInto(&out.S3Bucket)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocationS3Bucket.
+func (in *LocationS3Bucket) DeepCopy() *LocationS3Bucket {
+	if in == nil {
+		return nil
+	}
+	out := new(LocationS3Bucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringSpec.
+func (in *MonitoringSpec) DeepCopy() *MonitoringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAgentSpec) DeepCopyInto(out *NodeAgentSpec) {
+	*out = *in
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAgentSpec.
+func (in *NodeAgentSpec) DeepCopy() *NodeAgentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAgentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3Bucket) DeepCopyInto(out *S3Bucket) {
 	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Transitions != nil {
+		in, out := &in.Transitions, &out.Transitions
+		*out = make([]LifecycleTransition, len(*in))
+		copy(*out, *in)
+	}
 	if in.LastSyncTimestamp != nil {
 		in, out := &in.LastSyncTimestamp, &out.LastSyncTimestamp
 		*out = (*in).DeepCopy()
 	}
+	if in.InventoryTimestamp != nil {
+		in, out := &in.InventoryTimestamp, &out.InventoryTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.PlannedActions != nil {
+		in, out := &in.PlannedActions, &out.PlannedActions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -33,7 +348,7 @@ func (in *Velero) DeepCopyInto(out *Velero) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -56,6 +371,23 @@ func (in *Velero) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VeleroCondition) DeepCopyInto(out *VeleroCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VeleroCondition.
+func (in *VeleroCondition) DeepCopy() *VeleroCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(VeleroCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VeleroList) DeepCopyInto(out *VeleroList) {
 	*out = *in
@@ -89,9 +421,65 @@ func (in *VeleroList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VeleroServerSpec) DeepCopyInto(out *VeleroServerSpec) {
+	*out = *in
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BackupSyncPeriod != nil {
+		in, out := &in.BackupSyncPeriod, &out.BackupSyncPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DefaultBackupTTL != nil {
+		in, out := &in.DefaultBackupTTL, &out.DefaultBackupTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	out.Monitoring = in.Monitoring
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VeleroServerSpec.
+func (in *VeleroServerSpec) DeepCopy() *VeleroServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VeleroServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VeleroSpec) DeepCopyInto(out *VeleroSpec) {
 	*out = *in
+	in.BackupStorageLocation.DeepCopyInto(&out.BackupStorageLocation)
+	if in.BackupStorageLocations != nil {
+		in, out := &in.BackupStorageLocations, &out.BackupStorageLocations
+		*out = make([]BackupStorageLocationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Velero.DeepCopyInto(&out.Velero)
+	if in.NodeAgent != nil {
+		in, out := &in.NodeAgent, &out.NodeAgent
+		*out = new(NodeAgentSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReconcileDeadline != nil {
+		in, out := &in.ReconcileDeadline, &out.ReconcileDeadline
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.LegacyInfraNames != nil {
+		in, out := &in.LegacyInfraNames, &out.LegacyInfraNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -109,6 +497,24 @@ func (in *VeleroSpec) DeepCopy() *VeleroSpec {
 func (in *VeleroStatus) DeepCopyInto(out *VeleroStatus) {
 	*out = *in
 	in.S3Bucket.DeepCopyInto(&out.S3Bucket)
+	if in.AdditionalS3Buckets != nil {
+		in, out := &in.AdditionalS3Buckets, &out.AdditionalS3Buckets
+		*out = make([]LocationS3Bucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]VeleroCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailingSince != nil {
+		in, out := &in.FailingSince, &out.FailingSince
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -11,10 +11,696 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
-		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket":     schema_pkg_apis_managed_v1alpha1_S3Bucket(ref),
-		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.Velero":       schema_pkg_apis_managed_v1alpha1_Velero(ref),
-		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroSpec":   schema_pkg_apis_managed_v1alpha1_VeleroSpec(ref),
-		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroStatus": schema_pkg_apis_managed_v1alpha1_VeleroStatus(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BackupStorageLocationSpec": schema_pkg_apis_managed_v1alpha1_BackupStorageLocationSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketCORSRule":            schema_pkg_apis_managed_v1alpha1_BucketCORSRule(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketLoggingSpec":         schema_pkg_apis_managed_v1alpha1_BucketLoggingSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationSpec":    schema_pkg_apis_managed_v1alpha1_BucketNotificationSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationTarget":  schema_pkg_apis_managed_v1alpha1_BucketNotificationTarget(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketReplicationSpec":     schema_pkg_apis_managed_v1alpha1_BucketReplicationSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.EncryptionSpec":            schema_pkg_apis_managed_v1alpha1_EncryptionSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleSpec":             schema_pkg_apis_managed_v1alpha1_LifecycleSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleTransition":       schema_pkg_apis_managed_v1alpha1_LifecycleTransition(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LocationS3Bucket":          schema_pkg_apis_managed_v1alpha1_LocationS3Bucket(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.MonitoringSpec":            schema_pkg_apis_managed_v1alpha1_MonitoringSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.NodeAgentSpec":             schema_pkg_apis_managed_v1alpha1_NodeAgentSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket":                  schema_pkg_apis_managed_v1alpha1_S3Bucket(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.Velero":                    schema_pkg_apis_managed_v1alpha1_Velero(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroCondition":           schema_pkg_apis_managed_v1alpha1_VeleroCondition(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroServerSpec":          schema_pkg_apis_managed_v1alpha1_VeleroServerSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroSpec":                schema_pkg_apis_managed_v1alpha1_VeleroSpec(ref),
+		"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroStatus":              schema_pkg_apis_managed_v1alpha1_VeleroStatus(ref),
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BackupStorageLocationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BackupStorageLocationSpec defines the desired state of the backup storage location",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the backup storage location, which its bucket is tagged with. The first location is always named default, and every further location needs a unique name",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"slaClass": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SLAClass is the backup SLA class applied to the bucket and the Velero BackupStorageLocation",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"encryption": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Encryption configures the server-side encryption of the bucket",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.EncryptionSpec"),
+						},
+					},
+					"verifyWritablePrefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VerifyWritablePrefix is the key prefix the probe object verifying the bucket is writable is written under, defaulting to .managed-velero-operator/",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"recreateOnImmutableChange": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RecreateOnImmutableChange has the operator recreate the bucket when its encryption can only be set at creation, which requires the bucket to be empty",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"forceRecreate": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ForceRecreate allows RecreateOnImmutableChange to delete the contents of a bucket which isn't empty",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"deleteBucketOnUninstall": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeleteBucketOnUninstall has the operator empty and delete the bucket, including every object version, when the Velero instance is deleted. All backups are lost, so it defaults to false, which leaves the bucket in place",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"denySSEC": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DenySSEC adds a bucket policy statement rejecting uploads encrypted with customer-provided keys (SSE-C)",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"allowedRoleArns": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowedRoleARNs adds a bucket policy statement denying access to the bucket to every principal other than these IAM roles, which have to include the roles of Velero and of the operator",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"lifecycleDays": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LifecycleDays is how many days backups are kept in the bucket before they expire, defaulting to 90. Lifecycle.ExpirationDays takes precedence when set",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"lifecycle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Lifecycle configures the lifecycle rule expiring the backups in the bucket",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleSpec"),
+						},
+					},
+					"manageLifecycle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ManageLifecycle set to false leaves the lifecycle rules of the bucket to the user, such as an external governance tool, so the operator neither configures them nor checks them for drift. Defaults to true",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"managePublicAccessBlock": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ManagePublicAccessBlock set to false leaves the public access block of the bucket unchanged, for S3-compatible backends which don't implement it, such as MinIO. Backends answering NotImplemented are skipped even when it is true. Defaults to true",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"manageEncryption": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ManageEncryption set to false leaves the default encryption of the bucket unchanged, for gateways which forbid S3 server-side encryption, such as those encrypting through an external KMS proxy. The encryption isn't checked for drift either, and Encryption can't be set. Defaults to true",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"manageTags": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ManageTags set to false leaves the tags of the bucket unchanged, for organizations which forbid the operator to tag buckets. Existing buckets are still recovered from their tags, and a bucket lacking the operator's tags is identified by its name recorded in the status once recovered or created. AdditionalTags can't be set. Defaults to true",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"versioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Versioning enables object versioning on the bucket, which protects backups against accidental deletion. The noncurrent versions expire after Lifecycle.NoncurrentVersionExpirationDays, defaulting to the backup expiration, so they don't grow the bucket unbounded. Disabling it leaves versioning on the bucket unchanged",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"logging": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Logging delivers the S3 server access logs of the bucket to another bucket. The logging of the bucket is left unchanged when unset",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketLoggingSpec"),
+						},
+					},
+					"replication": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Replication replicates the backups in the bucket to a destination bucket, such as one in another region for disaster recovery. Replication requires versioning to be enabled on the bucket. The replication of the bucket is left unchanged when unset",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketReplicationSpec"),
+						},
+					},
+					"corsRules": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CORSRules are the cross-origin resource sharing rules of the bucket, such as for serving assets from it to a browser. The rules of the bucket are replaced by these, and left unchanged when unset",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketCORSRule"),
+									},
+								},
+							},
+						},
+					},
+					"notifications": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notifications sends the events of the bucket, such as a backup being written, to SNS topics, SQS queues or Amazon EventBridge. The notification configuration of the bucket is replaced by this, and left unchanged when unset",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationSpec"),
+						},
+					},
+					"bucketNamePrefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BucketNamePrefix replaces the prefix of the S3 bucket names the operator generates, which become <prefix>-<infrastructure name>-<random>. The random suffix is shortened to keep the name within 63 characters, unless its length is set by the operator's --bucket-name-suffix-length, when a name which doesn't fit is rejected",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"bucketName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BucketName is an existing bucket the backups are stored in, which the operator never creates. It is tagged, encrypted and configured like a bucket the operator created, and has to be created beforehand in the region of the storage location",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"region": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Region is the AWS region the bucket is created in, defaulting to the cluster's region for the first location, and to the region of the first location otherwise. It doesn't move an existing bucket",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"endpoint": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Endpoint is the URL of an S3-compatible object store, such as MinIO or Ceph RGW, addressed instead of AWS S3. It takes precedence over the operator's --s3-endpoint flag",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"s3ForcePathStyle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "S3ForcePathStyle addresses the bucket in the path of the Endpoint URL rather than as a subdomain of its host, which MinIO requires",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"outpostId": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OutpostID is the ID of the AWS Outpost the bucket is created on, in its S3 on Outposts resource, rather than in the region. Only the tags and lifecycle of a bucket on an Outpost are managed, and Velero addresses the bucket through an access point",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"outpostVpcId": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OutpostVPCID is the ID of the VPC the access point of the bucket on the Outpost accepts requests from, which is required with OutpostID",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"additionalTags": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AdditionalTags are applied to the bucket alongside the operator's tags, such as cost allocation tags. The operator's tags win on conflicting keys",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"accessMode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AccessMode set to ReadOnly makes the Velero BackupStorageLocation read-only, and denies writes to the bucket, defaulting to ReadWrite",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketCORSRule", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketLoggingSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketReplicationSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.EncryptionSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleSpec"},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BucketCORSRule(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BucketCORSRule defines which cross-origin requests are allowed to the bucket",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"allowedOrigins": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowedOrigins are the origins allowed to make cross-origin requests, which may hold a single * wildcard",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"allowedMethods": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowedMethods are the HTTP methods the origins may use: GET, PUT, POST, DELETE or HEAD",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"allowedHeaders": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowedHeaders are the headers allowed in the preflight requests, which may hold a single * wildcard",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"exposeHeaders": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExposeHeaders are the response headers the browser may expose to the requesting application",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"maxAgeSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxAgeSeconds is how long the browser may cache the response to a preflight request",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"allowedOrigins", "allowedMethods"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BucketLoggingSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BucketLoggingSpec defines where the S3 server access logs of the bucket are delivered",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"targetBucket": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TargetBucket is the existing bucket the access logs are delivered to, in the region of the bucket, which the S3 log delivery is allowed to write to as selected by DeliveryPermission",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"targetPrefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TargetPrefix is the key prefix the access logs are written under in the target bucket",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"deliveryPermission": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeliveryPermission selects how the S3 log delivery is allowed to write to the target bucket. By default, the bucket policy of the target bucket grants it when the ACLs of the target bucket are disabled by the BucketOwnerEnforced object ownership, and it is left to the ACL of the target bucket otherwise",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BucketNotificationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BucketNotificationSpec defines where the events of the bucket are sent",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"topics": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Topics are the SNS topics notified of the events of the bucket",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationTarget"),
+									},
+								},
+							},
+						},
+					},
+					"queues": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Queues are the SQS queues notified of the events of the bucket",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationTarget"),
+									},
+								},
+							},
+						},
+					},
+					"eventBridge": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EventBridge sends every event of the bucket to Amazon EventBridge",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BucketNotificationTarget"},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BucketNotificationTarget(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BucketNotificationTarget defines which events of the bucket are sent to an SNS topic or SQS queue",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"arn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ARN is the ARN of the SNS topic or SQS queue, whose policy has to allow S3 to send messages to it",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"events": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Events are the S3 event types sent, such as s3:ObjectCreated:*",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"prefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Prefix restricts the events to the objects whose keys start with it, such as backups/",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"suffix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Suffix restricts the events to the objects whose keys end with it, such as velero-backup.json",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"arn", "events"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_BucketReplicationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BucketReplicationSpec defines where the objects of the bucket are replicated to",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"destinationBucketArn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DestinationBucketARN is the ARN of the existing, versioned bucket the objects are replicated to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"roleArn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RoleARN is the ARN of the IAM role S3 assumes to replicate the objects, which has to be allowed to read the bucket and write to the destination bucket",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_EncryptionSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "EncryptionSpec defines the server-side encryption of the bucket",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type is the server-side encryption algorithm used for the bucket, defaulting to AES256",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"kmsKeyId": {
+						SchemaProps: spec.SchemaProps{
+							Description: "KMSKeyID is the KMS key used to encrypt the bucket when Type is aws:kms. An alias, such as alias/velero, is resolved on every reconcile, so that the key behind the alias can be rotated without encrypting the bucket again",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"createKey": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CreateKey has the operator create a dedicated KMS key for the bucket when Type is aws:kms and no KMSKeyID is given",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_LifecycleSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "LifecycleSpec defines the lifecycle rule expiring the backups in the bucket",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"expirationDays": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExpirationDays is how many days backups are kept in the bucket before they expire, defaulting to LifecycleDays",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"noncurrentVersionExpirationDays": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NoncurrentVersionExpirationDays is how many days the noncurrent versions of backups are kept in a versioned bucket, defaulting to the expiration of the backups",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"transitions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Transitions move the backups to cheaper storage classes as they age, each before the backups expire",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleTransition"),
+									},
+								},
+							},
+						},
+					},
+					"prefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Prefix is the key prefix of the objects the lifecycle rule expires, defaulting to backups/, so that other data in the bucket is kept",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleTransition"},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_LifecycleTransition(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "LifecycleTransition defines when the backups in the bucket move to another storage class",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"days": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Days is how many days after their creation the backups move to the storage class",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"storageClass": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageClass is the S3 storage class the backups move to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"days", "storageClass"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_LocationS3Bucket(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "LocationS3Bucket defines the observed state of the S3 bucket of a backup storage location",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"location": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Location is the name of the backup storage location",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"s3Bucket": {
+						SchemaProps: spec.SchemaProps{
+							Description: "S3Bucket contains details of the S3 bucket of the backup storage location",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket"),
+						},
+					},
+				},
+				Required: []string{"location", "s3Bucket"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket"},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_MonitoringSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "MonitoringSpec defines how the Velero server metrics are scraped",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"enabled": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Enabled has the operator create a Prometheus Operator ServiceMonitor scraping the Velero server metrics",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_NodeAgentSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodeAgentSpec defines the desired state of the Velero node agent DaemonSet",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"tolerations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tolerations are applied to the node agent pods",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.Toleration"),
+									},
+								},
+							},
+						},
+					},
+					"nodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeSelector restricts the nodes the node agent pods are scheduled on",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.Toleration"},
 	}
 }
 
@@ -39,18 +725,176 @@ func schema_pkg_apis_managed_v1alpha1_S3Bucket(ref common.ReferenceCallback) com
 							Format:      "",
 						},
 					},
+					"created": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Created is true when the operator created the bucket, rather than adopting an existing one.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"createdAt": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CreatedAt is the creation date of the bucket, as listed by the account of the credentials.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"ownerAccount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OwnerAccount is the ID of the AWS account owning the bucket, which is the account of the credentials the bucket was listed with.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"kmsKeyArn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "KMSKeyARN is the ARN of the KMS key created by the operator to encrypt the bucket.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"appliedKmsKeyId": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AppliedKMSKeyID is the configured KMS key ID the bucket was last encrypted with.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"clusterID": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterID is the ID of the cluster the bucket is tagged with.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"clusterVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterVersion is the version of the cluster the bucket is tagged with.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"region": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Region is the region the bucket was found to live in, when it differs from the region of its backup storage location, such as for a recovered bucket. The bucket is then addressed in this region.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"infrastructureName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InfrastructureName is the infrastructure name of the cluster the bucket is tagged with, before the characters disallowed in tag values were replaced in its tag",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"migratedFromInfrastructureName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MigratedFromInfrastructureName is the legacy infrastructure name the bucket was tagged with when it was recovered, before it was tagged with the current infrastructure name",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"versioned": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Versioned is true when versioning is enabled on the bucket.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"readOnly": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReadOnly is true when the bucket policy denies writes to the bucket.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"outpostAccessPointArn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OutpostAccessPointARN is the ARN of the access point Velero addresses the bucket on an Outpost through.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"expirationDays": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExpirationDays is the backup expiration the lifecycle rules were last configured for, or 0 while they are left to the user.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"noncurrentExpirationDays": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NoncurrentExpirationDays is the noncurrent version expiration the lifecycle rules were last configured for.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"transitions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Transitions are the storage class transitions the lifecycle rules were last configured for.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleTransition"),
+									},
+								},
+							},
+						},
+					},
+					"lifecyclePrefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LifecyclePrefix is the key prefix the lifecycle rules were last configured for, or empty for the default prefix.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 					"lastSyncTimestamp": {
 						SchemaProps: spec.SchemaProps{
 							Description: "LastSyncTimestamp is the time that the bucket policy was last synced.",
 							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
 						},
 					},
+					"objectCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObjectCount is the number of objects in the bucket when it was last inventoried, which excludes noncurrent versions",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"totalBytes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TotalBytes is the total size of the objects in the bucket when it was last inventoried, which excludes noncurrent versions",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"inventoryTimestamp": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InventoryTimestamp is the time the objects in the bucket were last inventoried, when the operator's --inventory-interval is set",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"plannedActions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PlannedActions are the S3 calls changing the bucket which the last sync skipped, as the operator runs in dry-run mode",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
 				},
 				Required: []string{"provisioned"},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LifecycleTransition", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
 	}
 }
 
@@ -98,14 +942,166 @@ func schema_pkg_apis_managed_v1alpha1_Velero(ref common.ReferenceCallback) commo
 	}
 }
 
+func schema_pkg_apis_managed_v1alpha1_VeleroCondition(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "VeleroCondition describes the state of the Velero installation at a certain point",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type is the type of the condition",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Status is the status of the condition, one of True, False or Unknown",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastTransitionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastTransitionTime is the last time the condition changed status",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"reason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reason is a brief machine readable explanation for the condition's last transition",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message is a human readable description of the details of the last transition",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"type", "status"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_managed_v1alpha1_VeleroServerSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "VeleroServerSpec defines the desired state of the Velero server",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"plugins": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Plugins is the list of plugin images installed into the Velero server",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"backupSyncPeriod": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackupSyncPeriod is how often Velero syncs backups from the backup storage location",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"defaultBackupTTL": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DefaultBackupTTL is how long backups are kept when they don't set their own TTL",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"monitoring": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Monitoring configures the scraping of the Velero server metrics",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.MonitoringSpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.MonitoringSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
+	}
+}
+
 func schema_pkg_apis_managed_v1alpha1_VeleroSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Description: "VeleroSpec defines the desired state of Velero",
 				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"backupStorageLocation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackupStorageLocation configures the storage used for Velero backups Deprecated: use BackupStorageLocations, which this is migrated into",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BackupStorageLocationSpec"),
+						},
+					},
+					"backupStorageLocations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackupStorageLocations configures the storage used for Velero backups. Every location gets a bucket of its own, and is installed as a Velero BackupStorageLocation of its own, the first location being the default one. The conditions of the buckets report a failure of any of the locations",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BackupStorageLocationSpec"),
+									},
+								},
+							},
+						},
+					},
+					"velero": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Velero configures the Velero server",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroServerSpec"),
+						},
+					},
+					"nodeAgent": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeAgent configures the scheduling of the Velero node agent, which is only deployed when this is set",
+							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.NodeAgentSpec"),
+						},
+					},
+					"reconcileDeadline": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReconcileDeadline is how long reconciling may fail continuously before the operator gives up until the spec changes",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"legacyInfraNames": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LegacyInfraNames are the former infrastructure names of the cluster, such as from before it was renamed during an upgrade. A bucket tagged with one of them is recovered when none is tagged with the current name, and is tagged again with the current name",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
+		Dependencies: []string{
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.BackupStorageLocationSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.NodeAgentSpec", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroServerSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
 	}
 }
 
@@ -118,14 +1114,67 @@ func schema_pkg_apis_managed_v1alpha1_VeleroStatus(ref common.ReferenceCallback)
 				Properties: map[string]spec.Schema{
 					"s3Bucket": {
 						SchemaProps: spec.SchemaProps{
-							Description: "S3Bucket contains details of the S3 storage bucket for backups",
+							Description: "S3Bucket contains details of the storage bucket for backups: the S3 bucket on AWS, the GCS bucket on GCP, or the Blob storage container on Azure",
 							Ref:         ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket"),
 						},
 					},
+					"additionalS3Buckets": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AdditionalS3Buckets contains details of the S3 buckets of the backup storage locations after the first",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LocationS3Bucket"),
+									},
+								},
+							},
+						},
+					},
+					"conditions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions are the latest observations of the state of the Velero installation",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroCondition"),
+									},
+								},
+							},
+						},
+					},
+					"failingSince": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FailingSince is when reconciling started failing continuously",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"failingGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FailingGeneration is the generation of the spec which is failing to reconcile",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"consecutiveFailures": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConsecutiveFailures is how many times in a row reconciling the spec failed",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"observedGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObservedGeneration is the generation of the spec the Velero installation last reached the desired state for",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket"},
+			"github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.LocationS3Bucket", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.S3Bucket", "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1.VeleroCondition", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
 	}
 }
@@ -0,0 +1,151 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/openshift/managed-velero-operator/version"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const (
+	azureCredsSecretAccountNameKey = "azure_storage_account_name"
+	azureCredsSecretAccountKeyKey  = "azure_storage_account_key" // #nosec G101
+)
+
+var (
+	azureCredsSecretName = version.OperatorName + "-iam-credentials"
+
+	// ErrContainerNotFound is returned when the container doesn't exist in the storage account.
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrContainerExists is returned when creating a container that already exists in the storage account.
+	ErrContainerExists = errors.New("container already exists")
+)
+
+// containerClient implements the ContainerClient interface.
+type containerClient struct {
+	serviceURL  azblob.ServiceURL
+	accountName string
+}
+
+// ContainerClient is a wrapper object for the actual Azure storage SDK client to allow for easier testing.
+type ContainerClient interface {
+	CreateContainer(containerName string, metadata map[string]string) error
+	GetContainerMetadata(containerName string) (map[string]string, error)
+	GetStorageAccountName() string
+	ListContainers() (map[string]map[string]string, error)
+	SetContainerMetadata(containerName string, metadata map[string]string) error
+}
+
+// When all of the above ContainerClient methods are implemented for containerClient, containerClient becomes a kind of ContainerClient.
+
+// CreateContainer creates the container in the storage account, without public access.
+func (c *containerClient) CreateContainer(containerName string, metadata map[string]string) error {
+	_, err := c.serviceURL.NewContainerURL(containerName).Create(context.TODO(), metadata, azblob.PublicAccessNone)
+	return translateError(err)
+}
+
+// GetContainerMetadata returns the metadata of the container.
+func (c *containerClient) GetContainerMetadata(containerName string) (map[string]string, error) {
+	props, err := c.serviceURL.NewContainerURL(containerName).GetProperties(context.TODO(), azblob.LeaseAccessConditions{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return props.NewMetadata(), nil
+}
+
+// GetStorageAccountName returns the storage account the containerClient manages containers in.
+func (c *containerClient) GetStorageAccountName() string {
+	return c.accountName
+}
+
+// ListContainers returns the metadata of every container in the storage account, by container name.
+func (c *containerClient) ListContainers() (map[string]map[string]string, error) {
+	containers := make(map[string]map[string]string)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := c.serviceURL.ListContainersSegment(context.TODO(), marker, azblob.ListContainersSegmentOptions{
+			Detail: azblob.ListContainersDetail{Metadata: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range resp.ContainerItems {
+			containers[container.Name] = container.Metadata
+		}
+		marker = resp.NextMarker
+	}
+	return containers, nil
+}
+
+// SetContainerMetadata replaces the metadata of the container.
+func (c *containerClient) SetContainerMetadata(containerName string, metadata map[string]string) error {
+	_, err := c.serviceURL.NewContainerURL(containerName).SetMetadata(context.TODO(), metadata, azblob.ContainerAccessConditions{})
+	return translateError(err)
+}
+
+// translateError maps the storage errors the operator acts upon to the errors
+// of this package, so that they don't depend on the SDK.
+func translateError(err error) error {
+	if stgErr, ok := err.(azblob.StorageError); ok {
+		switch stgErr.ServiceCode() {
+		case azblob.ServiceCodeContainerNotFound:
+			return ErrContainerNotFound
+		case azblob.ServiceCodeContainerAlreadyExists:
+			return ErrContainerExists
+		}
+	}
+	return err
+}
+
+// NewContainerClient reads the storage account name and key from the operator's
+// credentials secret, and returns a client managing containers in the account.
+func NewContainerClient(kubeClient client.Client) (ContainerClient, error) {
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operator namespace: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	err = kubeClient.Get(context.TODO(),
+		types.NamespacedName{
+			Name:      azureCredsSecretName,
+			Namespace: namespace,
+		},
+		secret)
+	if err != nil {
+		return nil, err
+	}
+	accountName, ok := secret.Data[azureCredsSecretAccountNameKey]
+	if !ok {
+		return nil, fmt.Errorf("Azure credentials secret %v did not contain key %v",
+			azureCredsSecretName, azureCredsSecretAccountNameKey)
+	}
+	accountKey, ok := secret.Data[azureCredsSecretAccountKeyKey]
+	if !ok {
+		return nil, fmt.Errorf("Azure credentials secret %v did not contain key %v",
+			azureCredsSecretName, azureCredsSecretAccountKeyKey)
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(string(accountName), string(accountKey))
+	if err != nil {
+		return nil, err
+	}
+	serviceURL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/", accountName))
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the actual Azure storage client into the containerClient interface.
+	return &containerClient{
+		serviceURL:  azblob.NewServiceURL(*serviceURL, azblob.NewPipeline(credential, azblob.PipelineOptions{})),
+		accountName: string(accountName),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package azure
+
+import (
+	"fmt"
+)
+
+const (
+	// Azure metadata keys must be valid C# identifiers, so they can't hold
+	// the / and . of the S3 tag keys
+	containerTagBackupLocation = "velero_backup_location"
+	containerTagInfraName      = "velero_infrastructure_name"
+)
+
+// EnsureContainer creates the container, unless it already exists in the
+// storage account. Containers are scoped to the storage account, so an
+// existing container is always ours.
+func EnsureContainer(containerClient ContainerClient, containerName string) error {
+	err := containerClient.CreateContainer(containerName, nil)
+	if err != nil && err != ErrContainerExists {
+		return fmt.Errorf("unable to create %v container: %w", containerName, err)
+	}
+	return nil
+}
+
+// DoesContainerExist checks that the container exists in the storage account.
+func DoesContainerExist(containerClient ContainerClient, containerName string) (bool, error) {
+	_, err := containerClient.GetContainerMetadata(containerName)
+	if err == ErrContainerNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read %v container: %w", containerName, err)
+	}
+	return true, nil
+}
+
+// ListContainerTags returns the tags of every container in the storage account, by container name.
+func ListContainerTags(containerClient ContainerClient) (map[string]map[string]string, error) {
+	containers, err := containerClient.ListContainers()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %w", err)
+	}
+	return containers, nil
+}
+
+// TagContainer adds the tags identifying the container as the backup location
+// of the cluster. The tags are stored as container metadata, and other
+// metadata on the container is kept.
+func TagContainer(containerClient ContainerClient, containerName string, backUpLocation string, infraName string) error {
+	metadata, err := containerClient.GetContainerMetadata(containerName)
+	if err != nil {
+		return fmt.Errorf("unable to read %v container: %w", containerName, err)
+	}
+	merged := make(map[string]string)
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	for key, value := range containerTags(backUpLocation, infraName) {
+		merged[key] = value
+	}
+	if err = containerClient.SetContainerMetadata(containerName, merged); err != nil {
+		return fmt.Errorf("unable to tag %v container: %w", containerName, err)
+	}
+	return nil
+}
+
+// containerTags returns the tags identifying the container.
+func containerTags(backUpLocation string, infraName string) map[string]string {
+	return map[string]string{
+		containerTagBackupLocation: backUpLocation,
+		containerTagInfraName:      infraName,
+	}
+}
+
+// FindMatchingTags looks through the tags of all containers and determines
+// if any of the containers are tagged as the backup location of the cluster.
+// If a matching container is found, the container name is returned.
+func FindMatchingTags(containers map[string]map[string]string, infraName string) string {
+	for container, tags := range containers {
+		if _, ok := tags[containerTagBackupLocation]; !ok {
+			continue
+		}
+		if tags[containerTagInfraName] == infraName {
+			return container
+		}
+	}
+
+	// No matching containers found.
+	return ""
+}
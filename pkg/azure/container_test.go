@@ -0,0 +1,140 @@
+package azure
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mockContainerClient is a ContainerClient holding the containers of a single storage account in memory.
+type mockContainerClient struct {
+	// containers holds the metadata of every container, by container name.
+	containers map[string]map[string]string
+}
+
+// CreateContainer implements the CreateContainer method for mockContainerClient.
+func (c *mockContainerClient) CreateContainer(containerName string, metadata map[string]string) error {
+	if _, ok := c.containers[containerName]; ok {
+		return ErrContainerExists
+	}
+	c.containers[containerName] = metadata
+	return nil
+}
+
+// GetContainerMetadata implements the GetContainerMetadata method for mockContainerClient.
+func (c *mockContainerClient) GetContainerMetadata(containerName string) (map[string]string, error) {
+	metadata, ok := c.containers[containerName]
+	if !ok {
+		return nil, ErrContainerNotFound
+	}
+	return metadata, nil
+}
+
+// GetStorageAccountName implements the GetStorageAccountName method for mockContainerClient.
+func (c *mockContainerClient) GetStorageAccountName() string {
+	return "testaccount"
+}
+
+// ListContainers implements the ListContainers method for mockContainerClient.
+func (c *mockContainerClient) ListContainers() (map[string]map[string]string, error) {
+	return c.containers, nil
+}
+
+// SetContainerMetadata implements the SetContainerMetadata method for mockContainerClient.
+func (c *mockContainerClient) SetContainerMetadata(containerName string, metadata map[string]string) error {
+	if _, ok := c.containers[containerName]; !ok {
+		return ErrContainerNotFound
+	}
+	c.containers[containerName] = metadata
+	return nil
+}
+
+func TestEnsureContainer(t *testing.T) {
+	client := &mockContainerClient{containers: map[string]map[string]string{}}
+	for i := 0; i < 2; i++ {
+		if err := EnsureContainer(client, "testcontainer"); err != nil {
+			t.Fatalf("EnsureContainer() error = %v", err)
+		}
+	}
+	if _, ok := client.containers["testcontainer"]; !ok || len(client.containers) != 1 {
+		t.Errorf("EnsureContainer() left containers %v, want only testcontainer", client.containers)
+	}
+}
+
+func TestDoesContainerExist(t *testing.T) {
+	client := &mockContainerClient{containers: map[string]map[string]string{
+		"testcontainer": nil,
+	}}
+	for container, want := range map[string]bool{"testcontainer": true, "othercontainer": false} {
+		got, err := DoesContainerExist(client, container)
+		if err != nil {
+			t.Fatalf("DoesContainerExist() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("DoesContainerExist(%v) = %v, want %v", container, got, want)
+		}
+	}
+}
+
+func TestTagContainer(t *testing.T) {
+	client := &mockContainerClient{containers: map[string]map[string]string{
+		"testcontainer": {"team": "sre"},
+	}}
+	if err := TagContainer(client, "testcontainer", "default", "cluster-abc12"); err != nil {
+		t.Fatalf("TagContainer() error = %v", err)
+	}
+	want := map[string]string{
+		"team":                     "sre",
+		containerTagBackupLocation: "default",
+		containerTagInfraName:      "cluster-abc12",
+	}
+	if got := client.containers["testcontainer"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("container metadata = %v, want %v", got, want)
+	}
+
+	if err := TagContainer(client, "othercontainer", "default", "cluster-abc12"); err == nil {
+		t.Errorf("TagContainer() on a missing container succeeded, want an error")
+	}
+}
+
+func TestFindMatchingTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers map[string]map[string]string
+		want       string
+	}{
+		{
+			name: "matching container",
+			containers: map[string]map[string]string{
+				"other":  {"team": "sre"},
+				"backup": containerTags("default", "cluster-abc12"),
+			},
+			want: "backup",
+		},
+		{
+			name: "other cluster",
+			containers: map[string]map[string]string{
+				"backup": containerTags("default", "cluster-xyz89"),
+			},
+		},
+		{
+			name: "tags split across containers",
+			containers: map[string]map[string]string{
+				"location": {containerTagBackupLocation: "default"},
+				"cluster":  {containerTagInfraName: "cluster-abc12"},
+			},
+		},
+		{
+			name: "untagged containers",
+			containers: map[string]map[string]string{
+				"backup": nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindMatchingTags(tt.containers, "cluster-abc12"); got != tt.want {
+				t.Errorf("FindMatchingTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package velero
+
+import (
+	"fmt"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/azure"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileAzure manages the Azure Blob storage container of a cluster
+// installed on Azure. The container is recorded in the same status as an S3
+// bucket. Velero itself is only installed on AWS so far: no
+// BackupStorageLocation, credentials or plugin are reconciled on Azure, which
+// the VeleroInstalled condition reports.
+func (r *ReconcileVelero) reconcileAzure(reqLogger logr.Logger, instance *veleroCR.Velero, infraStatus *configv1.InfrastructureStatus) (reconcile.Result, error) {
+	// Report that Velero isn't installed, rather than the container sync passing for it
+	if setStorageOnlyCondition(instance, configv1.AzurePlatformType) {
+		if err := r.statusUpdate(reqLogger, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// A frozen container is left unchanged
+	if bucketFrozen(instance) || !instance.S3BucketReconcileRequired(r.s3ReconcilePeriod()) {
+		return reconcile.Result{}, nil
+	}
+
+	containerClient, err := azure.NewContainerClient(r.client)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return r.provisionAzure(reqLogger, containerClient, instance, infraStatus.InfrastructureName)
+}
+
+func (r *ReconcileVelero) provisionAzure(reqLogger logr.Logger, containerClient azure.ContainerClient, instance *veleroCR.Velero, infraName string) (reconcile.Result, error) {
+	var err error
+	containerLog := reqLogger.WithValues("Container.Name", instance.Status.S3Bucket.Name, "Container.StorageAccount", containerClient.GetStorageAccountName())
+
+	// This switch handles the provisioning steps/checks
+	switch {
+	// We don't yet have a container name selected
+	case instance.Status.S3Bucket.Name == "":
+
+		// Use an existing container, if it exists.
+		log.Info("No container defined. Searching for existing container to use")
+		containers, err := azure.ListContainerTags(containerClient)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		existingContainer := azure.FindMatchingTags(containers, infraName)
+		if existingContainer != "" {
+			log.Info(fmt.Sprintf("Recovered existing container: %s", existingContainer))
+			instance.Status.S3Bucket.Name = existingContainer
+			instance.Status.S3Bucket.Provisioned = true
+			instance.Status.S3Bucket.Created = false
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+
+		// Prepare to create a new container, if none exist.
+		proposedName := generateBucketName(bucketPrefix)
+		proposedContainerExists, err := azure.DoesContainerExist(containerClient, proposedName)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if proposedContainerExists {
+			return reconcile.Result{}, fmt.Errorf("proposed container %s already exists, retrying", proposedName)
+		}
+
+		log.Info("Setting proposed container name", "Container.Name", proposedName)
+		instance.Status.S3Bucket.Name = proposedName
+		instance.Status.S3Bucket.Provisioned = false
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+
+	// We have a container name, but haven't kicked off provisioning of the container yet
+	case instance.Status.S3Bucket.Name != "" && !instance.Status.S3Bucket.Provisioned:
+		containerLog.Info("Container defined, but not provisioned")
+
+		// Create the container
+		containerLog.Info("Creating container")
+		err = azure.EnsureContainer(containerClient, instance.Status.S3Bucket.Name)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("error occurred when creating container %v: %v", instance.Status.S3Bucket.Name, err.Error())
+		}
+		// The proposed name is unique, so an existing container was created by an earlier attempt
+		instance.Status.S3Bucket.Created = true
+	}
+
+	// Verify the container exists
+	containerLog.Info("Verifing container exists")
+	exists, err := azure.DoesContainerExist(containerClient, instance.Status.S3Bucket.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error occurred when verifying container %v: %v", instance.Status.S3Bucket.Name, err.Error())
+	}
+	if !exists {
+		containerLog.Error(nil, "Container doesn't appear to exist")
+		instance.Status.S3Bucket.Provisioned = false
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
+
+	// Make sure that tags are applied to containers
+	containerLog.Info("Enforcing container tags on container")
+	err = azure.TagContainer(containerClient, instance.Status.S3Bucket.Name, defaultBackupStorageLocation, infraName)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error occurred when tagging container %v: %v", instance.Status.S3Bucket.Name, err.Error())
+	}
+
+	instance.Status.S3Bucket.Provisioned = true
+	instance.Status.S3Bucket.LastSyncTimestamp = &metav1.Time{
+		Time: time.Now(),
+	}
+	return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+}
@@ -0,0 +1,153 @@
+package velero
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/azure"
+
+	velerov1 "github.com/heptio/velero/pkg/apis/velero/v1"
+	configv1 "github.com/openshift/api/config/v1"
+	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// mockContainerClient is an azure.ContainerClient holding the containers of a single storage account in memory.
+type mockContainerClient struct {
+	// containers holds the metadata of every container, by container name.
+	containers map[string]map[string]string
+}
+
+// CreateContainer implements the CreateContainer method for mockContainerClient.
+func (c *mockContainerClient) CreateContainer(containerName string, metadata map[string]string) error {
+	if _, ok := c.containers[containerName]; ok {
+		return azure.ErrContainerExists
+	}
+	c.containers[containerName] = metadata
+	return nil
+}
+
+// GetContainerMetadata implements the GetContainerMetadata method for mockContainerClient.
+func (c *mockContainerClient) GetContainerMetadata(containerName string) (map[string]string, error) {
+	metadata, ok := c.containers[containerName]
+	if !ok {
+		return nil, azure.ErrContainerNotFound
+	}
+	return metadata, nil
+}
+
+// GetStorageAccountName implements the GetStorageAccountName method for mockContainerClient.
+func (c *mockContainerClient) GetStorageAccountName() string {
+	return "testaccount"
+}
+
+// ListContainers implements the ListContainers method for mockContainerClient.
+func (c *mockContainerClient) ListContainers() (map[string]map[string]string, error) {
+	return c.containers, nil
+}
+
+// SetContainerMetadata implements the SetContainerMetadata method for mockContainerClient.
+func (c *mockContainerClient) SetContainerMetadata(containerName string, metadata map[string]string) error {
+	if _, ok := c.containers[containerName]; !ok {
+		return azure.ErrContainerNotFound
+	}
+	c.containers[containerName] = metadata
+	return nil
+}
+
+func TestProvisionAzureCreatesContainer(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	containerClient := &mockContainerClient{containers: map[string]map[string]string{}}
+
+	// The first pass proposes a name, and the second one creates the container
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionAzure(log, containerClient, instance, testInfraName); err != nil {
+			t.Fatalf("provisionAzure() error = %v", err)
+		}
+	}
+	status := getTestInstance(t, r).Status.S3Bucket
+	if !status.Provisioned || !status.Created || !strings.HasPrefix(status.Name, bucketPrefix) {
+		t.Fatalf("container status = %+v, want a created container", status)
+	}
+	metadata := containerClient.containers[status.Name]
+	if metadata["velero_infrastructure_name"] != testInfraName || metadata["velero_backup_location"] != defaultBackupStorageLocation {
+		t.Errorf("container metadata = %v, want the tags identifying the cluster", metadata)
+	}
+}
+
+func TestReconcileAzureOnlyManagesContainer(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	r := newTestReconciler(t, instance)
+	infraStatus := &configv1.InfrastructureStatus{
+		InfrastructureName: testInfraName,
+		PlatformStatus:     &configv1.PlatformStatus{Type: configv1.AzurePlatformType},
+	}
+
+	// The condition is reported before the container is synced, even while it's frozen
+	if _, err := r.reconcileAzure(log, instance, infraStatus); err != nil {
+		t.Fatalf("reconcileAzure() error = %v", err)
+	}
+	instance = getTestInstance(t, r)
+	instance.Annotations = nil
+	containerClient := &mockContainerClient{containers: map[string]map[string]string{}}
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionAzure(log, containerClient, instance, testInfraName); err != nil {
+			t.Fatalf("provisionAzure() error = %v", err)
+		}
+	}
+
+	stored := getTestInstance(t, r)
+	if status := stored.Status.S3Bucket; !status.Provisioned || len(containerClient.containers) != 1 {
+		t.Errorf("container status = %+v, want a created container", status)
+	}
+	condition := stored.Status.GetCondition(veleroCR.VeleroInstalled)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != "PlatformUnsupported" {
+		t.Errorf("VeleroInstalled condition = %+v, want status %v with reason PlatformUnsupported", condition, corev1.ConditionFalse)
+	}
+
+	// Nothing of Velero itself is installed
+	bsls := &velerov1.BackupStorageLocationList{}
+	credentialsRequests := &minterv1.CredentialsRequestList{}
+	deployments := &appsv1.DeploymentList{}
+	for _, list := range []runtime.Object{bsls, credentialsRequests, deployments} {
+		if err := r.client.List(context.TODO(), list); err != nil {
+			t.Fatalf("unable to list %T: %v", list, err)
+		}
+	}
+	if len(bsls.Items) != 0 || len(credentialsRequests.Items) != 0 || len(deployments.Items) != 0 {
+		t.Errorf("reconcile on Azure created %d BackupStorageLocations, %d CredentialsRequests and %d Deployments, want none",
+			len(bsls.Items), len(credentialsRequests.Items), len(deployments.Items))
+	}
+}
+
+func TestProvisionAzureRecoversContainer(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	containerClient := &mockContainerClient{containers: map[string]map[string]string{
+		"unrelated": nil,
+		testBucketName: {
+			"velero_backup_location":     defaultBackupStorageLocation,
+			"velero_infrastructure_name": testInfraName,
+		},
+	}}
+
+	if _, err := r.provisionAzure(log, containerClient, instance, testInfraName); err != nil {
+		t.Fatalf("provisionAzure() error = %v", err)
+	}
+	status := getTestInstance(t, r).Status.S3Bucket
+	if status.Name != testBucketName || !status.Provisioned || status.Created {
+		t.Errorf("container status = %+v, want the recovered container %v", status, testBucketName)
+	}
+	if len(containerClient.containers) != 2 {
+		t.Errorf("provisionAzure() created a container, but an existing one was recovered")
+	}
+}
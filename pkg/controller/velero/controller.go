@@ -3,19 +3,27 @@ package velero
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 
 	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+	"github.com/openshift/managed-velero-operator/pkg/metrics"
 	"github.com/openshift/managed-velero-operator/pkg/s3"
 	"github.com/openshift/managed-velero-operator/pkg/util/platform"
 
 	velerov1 "github.com/heptio/velero/pkg/apis/velero/v1"
+	configv1 "github.com/openshift/api/config/v1"
 	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -28,23 +36,62 @@ import (
 var (
 	log               = logf.Log.WithName("controller_velero")
 	s3ReconcilePeriod = 60 * time.Minute
+	// readiness checks that the S3 client of every Velero instance reaches S3
+	readiness = s3.NewReadinessProbe()
 )
 
+// ReadinessProbe returns the handler of the readiness probe, which fails
+// while the controller can't reach S3 for any of the Velero instances.
+func ReadinessProbe() http.Handler {
+	return readiness
+}
+
 // Add creates a new Velero Controller and adds it to the Manager. The Manager will set fields on the Controller
-// and Start it when the Manager is Started.
+// and Start it when the Manager is Started. The validating webhook of the Velero instances is registered
+// with the webhook server of the Manager when enabled by the --validating-webhook flag, and the buckets are
+// inventoried periodically when enabled by the --inventory-interval flag.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	if flagOptions.validatingWebhook {
+		mgr.GetWebhookServer().Register(validatingWebhookPath, newValidatingWebhook(flagOptions))
+	}
+	r := newReconciler(mgr)
+	if flagOptions.inventoryInterval > 0 {
+		if err := mgr.Add(&inventoryReporter{r: r, interval: flagOptions.inventoryInterval}); err != nil {
+			return err
+		}
+	}
+	return add(mgr, r)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileVelero{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager) *ReconcileVelero {
+	return &ReconcileVelero{
+		client:    mgr.GetClient(),
+		scheme:    mgr.GetScheme(),
+		recorder:  mgr.GetEventRecorderFor("velero-controller"),
+		options:   flagOptions,
+		readiness: readiness,
+		discovery: s3.NewDiscoveryCache(flagOptions.discoveryCacheTTL),
+		breaker: s3.NewCircuitBreaker(flagOptions.s3CircuitBreakerThreshold,
+			flagOptions.s3CircuitBreakerWindow, flagOptions.s3CircuitBreakerOpenDuration),
+	}
+}
+
+// controllerOptions returns the options of the Controller reconciling with r.
+// The S3 discovery cache, circuit breaker and readiness probe shared by the
+// reconciles are safe for concurrent use, so several Velero instances may be reconciled at
+// once.
+func controllerOptions(r *ReconcileVelero) controller.Options {
+	return controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: r.options.maxConcurrentReconciles,
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileVelero) error {
 	// Create a new controller
-	c, err := controller.New("velero-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("velero-controller", mgr, controllerOptions(r))
 	if err != nil {
 		return err
 	}
@@ -91,6 +138,33 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to DaemonSets
+	err = c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &veleroCR.Velero{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Services
+	err = c.Watch(&source.Kind{Type: &corev1.Service{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &veleroCR.Velero{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to ConfigMaps
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &veleroCR.Velero{},
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -101,8 +175,31 @@ var _ reconcile.Reconciler = &ReconcileVelero{}
 type ReconcileVelero struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client  client.Client
+	scheme  *runtime.Scheme
+	options options
+	// recorder records the events about the bucket on the Velero instance
+	recorder record.EventRecorder
+	// now returns the current time, and defaults to time.Now
+	now func() time.Time
+	// readiness checks the S3 client of the last reconcile of each Velero
+	// instance, unless nil
+	readiness *s3.ReadinessProbe
+	// newKMSClient returns the KMS client for the AWS config, and defaults to
+	// kms.NewKMSClient
+	newKMSClient func(*aws.Config) (kms.Client, error)
+	// newOutpostsClient returns the S3 on Outposts client for the AWS config,
+	// and defaults to s3.NewOutpostsClient
+	newOutpostsClient func(*aws.Config) (s3.OutpostsClient, error)
+	// discovery caches the buckets listed to find an existing bucket across
+	// reconciles, unless nil
+	discovery *s3.DiscoveryCache
+	// breaker stops the S3 calls of every reconcile after repeated failures,
+	// unless nil
+	breaker *s3.CircuitBreaker
+	// random is the entropy source of the generated bucket names, and
+	// defaults to crypto/rand.Reader
+	random io.Reader
 }
 
 // Reconcile reads that state of the cluster for a Velero object and makes changes based on the state read
@@ -112,6 +209,10 @@ func (r *ReconcileVelero) Reconcile(request reconcile.Request) (reconcile.Result
 	reqLogger.Info("Reconciling Velero Installation")
 	var err error
 
+	// The S3 calls are cancelled when the reconcile takes too long
+	ctx, cancel := r.reconcileContext()
+	defer cancel()
+
 	// Fetch the Velero instance
 	instance := &veleroCR.Velero{}
 	err = r.client.Get(context.TODO(), request.NamespacedName, instance)
@@ -119,13 +220,157 @@ func (r *ReconcileVelero) Reconcile(request reconcile.Request) (reconcile.Result
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Its metrics are dropped so that no stale series is left behind.
 			// Return and don't requeue
+			metrics.ForgetVelero(request.Name, request.Namespace)
+			r.forgetReadiness(request)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	// A deleted instance only has its bucket cleaned up, if requested
+	if instance.DeletionTimestamp != nil {
+		r.forgetReadiness(request)
+		result, err := r.finalizeVelero(ctx, reqLogger, instance)
+		return r.requeueOnOpenCircuit(reqLogger, result, err)
+	}
+
+	// Make sure the spec is valid before acting on it
+	if err = instance.Spec.Validate(); err != nil {
+		// Don't requeue, as this won't succeed until the spec is changed
+		reqLogger.Error(err, "Velero spec is invalid")
+		return reconcile.Result{}, nil
+	}
+
+	// Move a legacy singular backupStorageLocation into the list form. The
+	// update triggers another reconcile of the migrated spec.
+	if instance.Spec.MigrateStorageLocation() {
+		reqLogger.Info("Migrating backupStorageLocation to backupStorageLocations")
+		return reconcile.Result{}, r.client.Update(context.TODO(), instance)
+	}
+
+	// Add or remove the finalizer deleting the bucket on uninstall. The
+	// update triggers another reconcile.
+	if updated, err := r.reconcileBucketFinalizer(reqLogger, instance); updated || err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// A spec which failed for longer than its reconcile deadline is left
+	// alone until it changes
+	if reconcileStopped(instance) {
+		reqLogger.Info("Reconcile deadline exceeded, waiting for the spec to change")
+		return reconcile.Result{}, nil
+	}
+
+	// An unchanged spec is left alone until the safety resync when
+	// reconciling on changes only, without calling S3
+	if r.reconcileSettled(instance) {
+		reqLogger.Info("Spec unchanged since it was reconciled, waiting for the safety resync", "RequeueAfter", r.options.safetyResyncInterval)
+		return reconcile.Result{RequeueAfter: r.options.safetyResyncInterval}, nil
+	}
+
+	result, err := r.reconcileVelero(ctx, reqLogger, request, instance)
+	result, err = r.trackReconcileFailure(reqLogger, instance, result, err)
+	return r.requeueOnOpenCircuit(reqLogger, result, err)
+}
+
+// reconcileSettled checks whether the Velero instance is left alone when
+// reconciling on changes only: its spec didn't change since the installation
+// last reached the desired state, its bucket is ready, nothing is failing,
+// and neither the bucket sync nor a read-only flip is due.
+func (r *ReconcileVelero) reconcileSettled(instance *veleroCR.Velero) bool {
+	if !r.options.reconcileOnChangeOnly || instance.Status.ObservedGeneration != instance.Generation {
+		return false
+	}
+	bucketReady := instance.Status.GetCondition(veleroCR.BucketReady)
+	return bucketReady != nil && bucketReady.Status == corev1.ConditionTrue &&
+		instance.Status.FailingSince == nil &&
+		!instance.S3BucketReconcileRequired(r.s3ReconcilePeriod()) &&
+		!lifecycleChanged(instance) && !readOnlyChanged(instance)
+}
+
+// s3ReconcilePeriod returns how long after they were last synced the buckets
+// are synced again, which is the safety resync interval when reconciling on
+// changes only.
+func (r *ReconcileVelero) s3ReconcilePeriod() time.Duration {
+	if r.options.reconcileOnChangeOnly {
+		return r.options.safetyResyncInterval
+	}
+	return s3ReconcilePeriod
+}
+
+// requeueInterval returns how long after reaching the desired state a Velero
+// instance is reconciled again.
+func (r *ReconcileVelero) requeueInterval() time.Duration {
+	if r.options.reconcileOnChangeOnly {
+		return r.options.safetyResyncInterval
+	}
+	return r.options.reconcileInterval
+}
+
+// targetReadiness has the readiness probe check the bucket of the default
+// backup storage location of the Velero instance with its S3 client, keyed by
+// the instance so that the instances reconciled at once don't replace each
+// other's target.
+func (r *ReconcileVelero) targetReadiness(request reconcile.Request, instance *veleroCR.Velero, s3Client s3.Client) {
+	if r.readiness == nil {
+		return
+	}
+	// The S3 API can't reach a bucket on an Outpost by its name
+	bucket := instance.Status.S3Bucket.Name
+	if onOutpost(instance.Spec.DefaultStorageLocation()) {
+		bucket = ""
+	}
+	r.readiness.SetTarget(request.NamespacedName.String(), s3Client, bucket)
+}
+
+// forgetReadiness stops the readiness probe from checking the S3 client of a
+// Velero instance which is deleted.
+func (r *ReconcileVelero) forgetReadiness(request reconcile.Request) {
+	if r.readiness != nil {
+		r.readiness.RemoveTarget(request.NamespacedName.String())
+	}
+}
+
+// reconcileContext returns the context the S3 calls of a reconcile are made
+// with, which is done once the reconcile timeout passes, unless it is 0. The
+// calls are retried and timed out as configured by the command line flags,
+// and recorded in the operator's metrics.
+func (r *ReconcileVelero) reconcileContext() (context.Context, context.CancelFunc) {
+	ctx := s3.WithRequestObserver(context.Background(), metrics.S3Observer{})
+	policy := s3.DefaultRetryPolicy
+	if r.options.s3MaxAttempts > 0 {
+		policy.MaxAttempts = r.options.s3MaxAttempts
+		policy.BaseDelay = r.options.s3RetryBaseDelay
+	}
+	policy.OperationTimeout = r.options.s3OperationTimeout
+	ctx = s3.WithRetryPolicy(ctx, policy)
+	if r.options.reconcileTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.options.reconcileTimeout)
+}
+
+// requeueOnOpenCircuit requeues a failed reconcile once the S3 circuit breaker
+// lets the calls through again, rather than retrying it with the backoff of
+// the controller while every S3 call fails right away.
+func (r *ReconcileVelero) requeueOnOpenCircuit(reqLogger logr.Logger, result reconcile.Result, err error) (reconcile.Result, error) {
+	if err == nil {
+		return result, nil
+	}
+	wait := r.breaker.RetryAfter()
+	if wait <= 0 {
+		return result, err
+	}
+	reqLogger.Error(err, "S3 calls are failing, requeueing once the circuit breaker probes S3 again", "RequeueAfter", wait)
+	return reconcile.Result{RequeueAfter: wait}, nil
+}
+
+// reconcileVelero provisions the S3 bucket and Velero for a valid Velero instance.
+func (r *ReconcileVelero) reconcileVelero(ctx context.Context, reqLogger logr.Logger, request reconcile.Request, instance *veleroCR.Velero) (reconcile.Result, error) {
+
 	// Grab infrastructureStatus to determine where OpenShift is installed.
 	infrastructureStatusClient, err := platform.GetInfrastructureClient()
 	if err != nil {
@@ -136,22 +381,82 @@ func (r *ReconcileVelero) Reconcile(request reconcile.Request) (reconcile.Result
 		return reconcile.Result{}, err
 	}
 
+	// Only the bucket is managed on GCP
+	if infraStatus.PlatformStatus.Type == configv1.GCPPlatformType {
+		return r.reconcileGCP(reqLogger, instance, infraStatus)
+	}
+
+	// Only the container is managed on Azure
+	if infraStatus.PlatformStatus.Type == configv1.AzurePlatformType {
+		return r.reconcileAzure(reqLogger, instance, infraStatus)
+	}
+
 	// Verify that we have received an AWS region from the platform
 	if infraStatus.PlatformStatus.AWS == nil || len(infraStatus.PlatformStatus.AWS.Region) < 1 {
 		return reconcile.Result{}, fmt.Errorf("unable to determine AWS region")
 	}
 
-	// Create an S3 client based on the region we received
-	s3Client, err := s3.NewS3Client(r.client, infraStatus.PlatformStatus.AWS.Region)
+	// The bucket may be kept in another region than the cluster
+	region := bucketRegion(instance, infraStatus.PlatformStatus.AWS.Region)
+
+	// Fail fast when the region is unknown, rather than on resolving its endpoint
+	if err = r.checkRegion(reqLogger, instance, region); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Create an S3 client based on the bucket's region
+	s3Client, err := s3.NewS3ClientForEndpoint(r.client, region, r.s3Endpoint(instance), r.s3Credentials())
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	r.targetReadiness(request, instance, s3Client)
+	s3Client = r.discovery.InvalidatingClient(s3.NewLoggingClient(r.breaker.Client(s3Client), reqLogger))
+	var dryRunClient *s3.DryRunClient
+	if r.options.dryRun {
+		dryRunClient = s3.NewDryRunClient(s3Client)
+		s3Client = dryRunClient
+	}
+
+	// Fail fast when the credentials are missing permissions, rather than halfway through provisioning
+	if err = r.checkCredentials(ctx, reqLogger, s3Client, instance); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Tag the bucket with the current cluster version
+	if err = r.syncClusterVersion(reqLogger, infrastructureStatusClient, instance); err != nil {
+		return reconcile.Result{}, err
+	}
 
 	// Check if bucket needs to be reconciled
-	if instance.S3BucketReconcileRequired(s3ReconcilePeriod) {
+	if bucketFrozen(instance) {
+		// A frozen bucket is only checked for drift, and never changed
+		if err = r.checkFrozenBucket(ctx, reqLogger, s3Client, instance, infraStatus.InfrastructureName); err != nil {
+			return reconcile.Result{}, err
+		}
+		if !instance.Status.S3Bucket.Provisioned {
+			return reconcile.Result{}, nil
+		}
+	} else if instance.S3BucketReconcileRequired(r.s3ReconcilePeriod()) || lifecycleChanged(instance) {
 		// Always directly return from this, as we will either update the
-		// timestamp when complete, or return an error.
-		return r.provisionS3(reqLogger, s3Client, instance, infraStatus.InfrastructureName)
+		// timestamp when complete, or return an error. A changed retention
+		// is applied right away, rather than on the next sync.
+		instance.Status.S3Bucket.PlannedActions = nil
+		result, err := r.provisionS3(ctx, reqLogger, s3Client, instance, infraStatus.InfrastructureName)
+		if dryRunClient != nil {
+			return result, r.recordPlannedActions(reqLogger, instance, dryRunClient, err)
+		}
+		return result, err
+	} else if readOnlyChanged(instance) {
+		// Flip the read-only policy right away, rather than on the next sync
+		if err = r.flipReadOnlyPolicies(ctx, reqLogger, s3Client, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		if dryRunClient != nil {
+			instance.Status.S3Bucket.PlannedActions = dryRunClient.Actions()
+		}
+		if err = r.statusUpdate(reqLogger, instance); err != nil {
+			return reconcile.Result{}, err
+		}
 	}
 
 	// Now go provision Velero
@@ -0,0 +1,237 @@
+package velero
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/metrics"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// unavailableS3Client is a mockS3Client whose HeadBucket calls fail as
+// during an S3 outage.
+type unavailableS3Client struct {
+	*mockS3Client
+	headBucketCalls int
+}
+
+// HeadBucket implements the HeadBucket method for unavailableS3Client.
+func (c *unavailableS3Client) HeadBucket(ctx context.Context, input *awss3.HeadBucketInput) (*awss3.HeadBucketOutput, error) {
+	c.headBucketCalls++
+	return nil, awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "Service Unavailable", nil), 503, "")
+}
+
+func TestRequeueOnOpenCircuit(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	r.breaker = s3.NewCircuitBreaker(2, time.Minute, time.Hour)
+	client := &unavailableS3Client{mockS3Client: newMockS3Client(testBucketName)}
+	s3Client := r.breaker.Client(client)
+	ctx := s3.WithRetryPolicy(context.TODO(), s3.RetryPolicy{MaxAttempts: 1})
+
+	// The first failure is retried with the backoff of the controller
+	_, err := r.provisionS3(ctx, log, s3Client, instance, testInfraName)
+	if result, err := r.requeueOnOpenCircuit(log, reconcile.Result{}, err); err == nil || result.RequeueAfter != 0 {
+		t.Fatalf("requeueOnOpenCircuit() = %+v, %v with the circuit closed, want the error", result, err)
+	}
+
+	// The circuit opens on the second, and the reconciles are requeued until it half-opens
+	for i := 0; i < 2; i++ {
+		_, err = r.provisionS3(ctx, log, s3Client, instance, testInfraName)
+		result, err := r.requeueOnOpenCircuit(log, reconcile.Result{}, err)
+		if err != nil || result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+			t.Errorf("requeueOnOpenCircuit() = %+v, %v with the circuit open, want a requeue within the open duration", result, err)
+		}
+	}
+	if client.headBucketCalls != 2 {
+		t.Errorf("HeadBucket called %d times, want no call with the circuit open", client.headBucketCalls)
+	}
+}
+
+func TestLastSuccessTimestampMetric(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	now := time.Date(2019, time.November, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	defer metrics.ForgetVelero(instance.Name, instance.Namespace)
+
+	if _, err := r.trackReconcileFailure(log, instance, reconcile.Result{}, nil); err != nil {
+		t.Fatalf("trackReconcileFailure() error = %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.LastSuccessTimestamp.WithLabelValues(instance.Name, instance.Namespace)); got != float64(now.Unix()) {
+		t.Errorf("last success timestamp = %v after a successful reconcile, want %v", got, now.Unix())
+	}
+
+	// A failure leaves the time of the last success
+	now = now.Add(time.Hour)
+	if _, err := r.trackReconcileFailure(log, getTestInstance(t, r), reconcile.Result{}, errors.New("unable to create bucket")); err == nil {
+		t.Fatalf("trackReconcileFailure() error = nil, want the reconcile error")
+	}
+	if got := testutil.ToFloat64(metrics.LastSuccessTimestamp.WithLabelValues(instance.Name, instance.Namespace)); got != float64(now.Add(-time.Hour).Unix()) {
+		t.Errorf("last success timestamp = %v after a failed reconcile, want %v", got, now.Add(-time.Hour).Unix())
+	}
+
+	// The series goes away with the instance
+	if err := r.client.Delete(context.TODO(), getTestInstance(t, r)); err != nil {
+		t.Fatalf("unable to delete the instance: %v", err)
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}}
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatalf("Reconcile() error = %v for a deleted instance", err)
+	}
+	if metrics.LastSuccessTimestamp.DeleteLabelValues(instance.Name, instance.Namespace) {
+		t.Errorf("last success timestamp still registered after the instance was deleted, want no series")
+	}
+}
+
+func TestControllerOptionsMaxConcurrentReconciles(t *testing.T) {
+	defer func(saved options) { flagOptions = saved }(flagOptions)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{
+			name: "default",
+			want: 1,
+		},
+		{
+			name: "configured",
+			args: []string{"--max-concurrent-reconciles=4"},
+			want: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := FlagSet().Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			r := newTestReconciler(t, newTestInstance(veleroCR.VeleroSpec{}))
+			r.options = flagOptions
+
+			opts := controllerOptions(r)
+			if opts.MaxConcurrentReconciles != tt.want {
+				t.Errorf("MaxConcurrentReconciles = %d, want %d", opts.MaxConcurrentReconciles, tt.want)
+			}
+			if opts.Reconciler != r {
+				t.Errorf("Reconciler = %v, want the ReconcileVelero", opts.Reconciler)
+			}
+		})
+	}
+}
+
+func TestReconcileOnChangeOnly(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Generation = 1
+	r := newTestReconciler(t, instance)
+	r.options.reconcileOnChangeOnly = true
+	r.options.safetyResyncInterval = 24 * time.Hour
+	s3Client := newMockS3Client(testBucketName)
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	// The first pass also reports the health of the BackupStorageLocation
+	var result reconcile.Result
+	var err error
+	for i := 0; i < 2; i++ {
+		if result, err = r.provisionVelero(log, testNamespace, testPlatformStatus, getTestInstance(t, r)); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+	}
+	if result.RequeueAfter != r.options.safetyResyncInterval {
+		t.Errorf("provisionVelero() RequeueAfter = %v, want the safety resync interval %v", result.RequeueAfter, r.options.safetyResyncInterval)
+	}
+	if got := getTestInstance(t, r).Status.ObservedGeneration; got != 1 {
+		t.Fatalf("ObservedGeneration = %v after reaching the desired state, want 1", got)
+	}
+
+	// A no-op reconcile of the unchanged, ready instance doesn't touch S3,
+	// nor the instance
+	mutations := len(s3Client.mutations)
+	resourceVersion := getTestInstance(t, r).ResourceVersion
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}}
+	result, err = r.Reconcile(request)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v for an unchanged instance", err)
+	}
+	if result.RequeueAfter != r.options.safetyResyncInterval {
+		t.Errorf("Reconcile() RequeueAfter = %v, want the safety resync interval %v", result.RequeueAfter, r.options.safetyResyncInterval)
+	}
+	if updated := getTestInstance(t, r); updated.ResourceVersion != resourceVersion || updated.S3BucketReconcileRequired(r.s3ReconcilePeriod()) {
+		t.Errorf("instance updated to resource version %v by reconciling it unchanged, want %v with no bucket sync due", updated.ResourceVersion, resourceVersion)
+	}
+	if len(s3Client.mutations) != mutations {
+		t.Errorf("S3 mutations %v after reconciling an unchanged instance, want none", s3Client.mutations[mutations:])
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*veleroCR.Velero)
+	}{
+		{
+			name:   "spec changed",
+			modify: func(instance *veleroCR.Velero) { instance.Generation++ },
+		},
+		{
+			name:   "periodic reconciles",
+			modify: func(*veleroCR.Velero) { r.options.reconcileOnChangeOnly = false },
+		},
+		{
+			name: "bucket not ready",
+			modify: func(instance *veleroCR.Velero) {
+				instance.Status.SetCondition(veleroCR.BucketReady, corev1.ConditionFalse, "BucketSyncFailed", "")
+			},
+		},
+		{
+			name: "safety resync due",
+			modify: func(instance *veleroCR.Velero) {
+				instance.Status.S3Bucket.LastSyncTimestamp = &metav1.Time{Time: time.Now().Add(-25 * time.Hour)}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := r.options
+			defer func() { r.options = saved }()
+			instance := getTestInstance(t, r)
+			tt.modify(instance)
+			if r.reconcileSettled(instance) {
+				t.Errorf("reconcileSettled() = true, want the instance reconciled")
+			}
+		})
+	}
+}
+
+func TestReadinessPerInstance(t *testing.T) {
+	r := newTestReconciler(t)
+	r.readiness = s3.NewReadinessProbe()
+	reachable := newTestInstance(veleroCR.VeleroSpec{})
+	unavailable := newTestInstance(veleroCR.VeleroSpec{})
+	unavailable.Name = "unavailable"
+	reachableRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: reachable.Name}}
+	unavailableRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: unavailable.Name}}
+
+	// The instance reconciled last doesn't hide the other one failing
+	r.targetReadiness(unavailableRequest, unavailable, &unavailableS3Client{mockS3Client: newMockS3Client(testBucketName)})
+	r.targetReadiness(reachableRequest, reachable, newMockS3Client(testBucketName))
+	if err := r.readiness.Check(context.TODO()); err == nil {
+		t.Errorf("Check() = nil, want the error of %v", unavailableRequest)
+	}
+
+	r.forgetReadiness(unavailableRequest)
+	if err := r.readiness.Check(context.TODO()); err != nil {
+		t.Errorf("Check() = %v once %v is deleted, want nil", err, unavailableRequest)
+	}
+}
@@ -0,0 +1,90 @@
+package velero
+
+import (
+	"fmt"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// currentTime returns the current time of the reconciler's clock.
+func (r *ReconcileVelero) currentTime() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+	return r.now()
+}
+
+// reconcileStopped checks whether reconciling the current spec was given up
+// after exceeding its reconcile deadline.
+func reconcileStopped(instance *veleroCR.Velero) bool {
+	condition := instance.Status.GetCondition(veleroCR.ReconcileFailed)
+	return condition != nil && condition.Status == corev1.ConditionTrue &&
+		instance.Status.FailingGeneration == instance.Generation
+}
+
+// trackReconcileFailure records since when, and how many times in a row,
+// reconciling the spec has been failing. Once that exceeds the reconcile
+// deadline, the ReconcileFailed condition is set and the error is dropped, so
+// that the request isn't requeued until the spec changes. Once the failures
+// reach the operator's maximum, the Degraded condition is set and the request
+// is only requeued after the degraded requeue interval, rather than with the
+// backoff of the controller. A success is also recorded in the operator's
+// metrics.
+func (r *ReconcileVelero) trackReconcileFailure(reqLogger logr.Logger, instance *veleroCR.Velero,
+	result reconcile.Result, reconcileErr error) (reconcile.Result, error) {
+	if reconcileErr == nil {
+		metrics.ObserveReconcileSuccess(instance.Name, instance.Namespace, r.currentTime())
+		if instance.Status.FailingSince == nil && instance.Status.ConsecutiveFailures == 0 {
+			return result, nil
+		}
+		instance.Status.FailingSince = nil
+		instance.Status.FailingGeneration = 0
+		instance.Status.ConsecutiveFailures = 0
+		if instance.Status.GetCondition(veleroCR.ReconcileFailed) != nil {
+			instance.Status.SetCondition(veleroCR.ReconcileFailed, corev1.ConditionFalse, "Reconciled", "")
+		}
+		if instance.Status.GetCondition(veleroCR.Degraded) != nil {
+			instance.Status.SetCondition(veleroCR.Degraded, corev1.ConditionFalse, "Reconciled", "")
+		}
+		return result, r.statusUpdate(reqLogger, instance)
+	}
+
+	now := r.currentTime()
+	if instance.Status.FailingSince == nil || instance.Status.FailingGeneration != instance.Generation {
+		// The failure window and count restart whenever the spec changes
+		instance.Status.FailingSince = &metav1.Time{Time: now}
+		instance.Status.FailingGeneration = instance.Generation
+		instance.Status.ConsecutiveFailures = 0
+	}
+	instance.Status.ConsecutiveFailures++
+
+	if deadline := instance.Spec.ReconcileDeadline; deadline != nil && now.Sub(instance.Status.FailingSince.Time) >= deadline.Duration {
+		reqLogger.Error(reconcileErr, "Reconcile deadline exceeded, giving up until the spec changes", "ReconcileDeadline", deadline.Duration)
+		instance.Status.SetCondition(veleroCR.ReconcileFailed, corev1.ConditionTrue, "DeadlineExceeded", reconcileErr.Error())
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
+
+	if maxFailures := r.options.maxReconcileFailures; maxFailures > 0 && int(instance.Status.ConsecutiveFailures) >= maxFailures {
+		interval := r.options.degradedRequeueInterval
+		reqLogger.Error(reconcileErr, "Reconcile failed too many times in a row, retrying less often",
+			"ConsecutiveFailures", instance.Status.ConsecutiveFailures, "RequeueAfter", interval)
+		instance.Status.SetCondition(veleroCR.Degraded, corev1.ConditionTrue, "MaxFailuresExceeded",
+			fmt.Sprintf("Reconciling failed %d times in a row: %v", instance.Status.ConsecutiveFailures, reconcileErr))
+		if err := r.statusUpdate(reqLogger, instance); err != nil {
+			return result, err
+		}
+		return reconcile.Result{RequeueAfter: interval}, nil
+	}
+
+	if err := r.statusUpdate(reqLogger, instance); err != nil {
+		return result, err
+	}
+	return result, reconcileErr
+}
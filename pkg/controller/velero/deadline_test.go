@@ -0,0 +1,135 @@
+package velero
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTrackReconcileFailureDeadline(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		ReconcileDeadline: &metav1.Duration{Duration: 24 * time.Hour},
+	})
+	instance.Generation = 1
+	r := newTestReconciler(t, instance)
+	now := time.Date(2019, time.November, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	reconcileErr := errors.New("unable to create bucket")
+
+	fail := func() error {
+		t.Helper()
+		instance := getTestInstance(t, r)
+		if reconcileStopped(instance) {
+			t.Fatalf("reconcileStopped() = true before the deadline")
+		}
+		_, err := r.trackReconcileFailure(log, instance, reconcile.Result{}, reconcileErr)
+		return err
+	}
+
+	// Failures within the deadline are retried
+	if err := fail(); err != reconcileErr {
+		t.Fatalf("trackReconcileFailure() error = %v, want %v", err, reconcileErr)
+	}
+	now = now.Add(23 * time.Hour)
+	if err := fail(); err != reconcileErr {
+		t.Fatalf("trackReconcileFailure() error = %v, want %v", err, reconcileErr)
+	}
+
+	// Past the deadline the request stops being requeued
+	now = now.Add(2 * time.Hour)
+	if err := fail(); err != nil {
+		t.Fatalf("trackReconcileFailure() error = %v, want nil", err)
+	}
+	stored := getTestInstance(t, r)
+	if !reconcileStopped(stored) {
+		t.Errorf("reconcileStopped() = false after the deadline")
+	}
+	condition := stored.Status.GetCondition(veleroCR.ReconcileFailed)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Message != reconcileErr.Error() {
+		t.Errorf("ReconcileFailed condition = %+v, want status %v with message %q", condition, corev1.ConditionTrue, reconcileErr)
+	}
+
+	// Changing the spec resumes reconciling, with a new deadline
+	stored.Generation = 2
+	if reconcileStopped(stored) {
+		t.Errorf("reconcileStopped() = true after the spec changed")
+	}
+	if _, err := r.trackReconcileFailure(log, stored, reconcile.Result{}, reconcileErr); err != reconcileErr {
+		t.Errorf("trackReconcileFailure() error = %v, want %v", err, reconcileErr)
+	}
+}
+
+func TestTrackReconcileFailureRecovers(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		ReconcileDeadline: &metav1.Duration{Duration: time.Hour},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.trackReconcileFailure(log, instance, reconcile.Result{}, errors.New("unable to create bucket")); err == nil {
+		t.Fatalf("trackReconcileFailure() error = nil, want the reconcile error")
+	}
+	if _, err := r.trackReconcileFailure(log, getTestInstance(t, r), reconcile.Result{}, nil); err != nil {
+		t.Fatalf("trackReconcileFailure() error = %v", err)
+	}
+	if stored := getTestInstance(t, r); stored.Status.FailingSince != nil {
+		t.Errorf("FailingSince = %v after a successful reconcile, want nil", stored.Status.FailingSince)
+	}
+}
+
+func TestTrackReconcileFailureMaxFailures(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	r.options.maxReconcileFailures = 3
+	r.options.degradedRequeueInterval = time.Hour
+	reconcileErr := errors.New("unable to configure bucket")
+
+	// Failures below the maximum are retried with the controller's backoff
+	for i := 1; i < 3; i++ {
+		result, err := r.trackReconcileFailure(log, getTestInstance(t, r), reconcile.Result{}, reconcileErr)
+		if err != reconcileErr || result.RequeueAfter != 0 {
+			t.Fatalf("trackReconcileFailure() = %+v, %v after %d failures, want %v", result, err, i, reconcileErr)
+		}
+		if failures := getTestInstance(t, r).Status.ConsecutiveFailures; failures != int32(i) {
+			t.Errorf("ConsecutiveFailures = %d, want %d", failures, i)
+		}
+	}
+
+	// Reaching the maximum marks the instance Degraded, and slows the retries down
+	for i := 0; i < 2; i++ {
+		result, err := r.trackReconcileFailure(log, getTestInstance(t, r), reconcile.Result{}, reconcileErr)
+		if err != nil || result.RequeueAfter != time.Hour {
+			t.Fatalf("trackReconcileFailure() = %+v, %v at the maximum, want a requeue after %v", result, err, time.Hour)
+		}
+	}
+	stored := getTestInstance(t, r)
+	if stored.Status.ConsecutiveFailures != 4 {
+		t.Errorf("ConsecutiveFailures = %d, want 4", stored.Status.ConsecutiveFailures)
+	}
+	condition := stored.Status.GetCondition(veleroCR.Degraded)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "MaxFailuresExceeded" {
+		t.Errorf("Degraded condition = %+v, want status %v with reason MaxFailuresExceeded", condition, corev1.ConditionTrue)
+	}
+	if reconcileStopped(stored) {
+		t.Errorf("reconcileStopped() = true for a Degraded instance, want it retried")
+	}
+
+	// A successful reconcile resets the count
+	if _, err := r.trackReconcileFailure(log, stored, reconcile.Result{}, nil); err != nil {
+		t.Fatalf("trackReconcileFailure() error = %v", err)
+	}
+	stored = getTestInstance(t, r)
+	if stored.Status.ConsecutiveFailures != 0 {
+		t.Errorf("ConsecutiveFailures = %d after a successful reconcile, want 0", stored.Status.ConsecutiveFailures)
+	}
+	if condition := stored.Status.GetCondition(veleroCR.Degraded); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("Degraded condition = %+v after a successful reconcile, want status %v", condition, corev1.ConditionFalse)
+	}
+	if _, err := r.trackReconcileFailure(log, stored, reconcile.Result{}, reconcileErr); err != reconcileErr {
+		t.Errorf("trackReconcileFailure() error = %v after recovering, want %v", err, reconcileErr)
+	}
+}
@@ -0,0 +1,27 @@
+package velero
+
+import (
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/go-logr/logr"
+)
+
+// recordPlannedActions logs the S3 calls the dry-run sync of the bucket
+// skipped, and records them in the status. The error of the sync is returned,
+// unless the status can't be updated.
+func (r *ReconcileVelero) recordPlannedActions(reqLogger logr.Logger, instance *veleroCR.Velero, dryRunClient *s3.DryRunClient, syncErr error) error {
+	logPlannedActions(reqLogger, dryRunClient)
+	instance.Status.S3Bucket.PlannedActions = dryRunClient.Actions()
+	if err := r.statusUpdate(reqLogger, instance); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// logPlannedActions logs every S3 call the dry-run client skipped.
+func logPlannedActions(reqLogger logr.Logger, dryRunClient *s3.DryRunClient) {
+	for _, action := range dryRunClient.Actions() {
+		reqLogger.Info("Dry run, skipped changing S3", "Action", action)
+	}
+}
@@ -0,0 +1,57 @@
+package velero
+
+import (
+	"context"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+)
+
+func TestProvisionS3DryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		bucketName  string
+		provisioned bool
+		want        []string
+	}{
+		{
+			name: "new bucket",
+			want: []string{"CreateBucket", "PutBucketOwnershipControls", "PutBucketEncryption", "PutPublicAccessBlock", "PutBucketLifecycleConfiguration", "PutBucketTagging"},
+		},
+		{
+			name:        "existing bucket",
+			bucketName:  testBucketName,
+			provisioned: true,
+			want:        []string{"PutBucketEncryption", "PutPublicAccessBlock", "PutBucketLifecycleConfiguration", "PutBucketTagging"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			instance.Status.S3Bucket.Provisioned = tt.provisioned
+			r := newTestReconciler(t, instance)
+			r.options.dryRun = true
+			s3Client := newMockS3Client(tt.bucketName)
+			dryRunClient := s3.NewDryRunClient(s3Client)
+
+			_, err := r.provisionS3(context.TODO(), log, dryRunClient, instance, testInfraName)
+			if err = r.recordPlannedActions(log, instance, dryRunClient, err); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			if len(s3Client.mutations) > 0 {
+				t.Errorf("dry run issued %v, want no calls changing the bucket", s3Client.mutations)
+			}
+
+			planned := make(map[string]bool)
+			for _, action := range getTestInstance(t, r).Status.S3Bucket.PlannedActions {
+				planned[action] = true
+			}
+			for _, operation := range tt.want {
+				if action := operation + " " + testBucketName; !planned[action] {
+					t.Errorf("planned actions %v, want %q", getTestInstance(t, r).Status.S3Bucket.PlannedActions, action)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package velero
+
+import (
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The reasons of the events recorded on the Velero instance about its bucket
+const (
+	eventBucketCreated        = "BucketCreated"
+	eventEncryptionEnabled    = "EncryptionEnabled"
+	eventTaggingApplied       = "TaggingApplied"
+	eventCreateBucketFailed   = "CreateBucketFailed"
+	eventAccessDenied         = "AccessDenied"
+	eventDuplicateBuckets     = "DuplicateBuckets"
+	eventInfraNameMigrated    = "InfrastructureNameMigrated"
+	eventKMSKeyRotated        = "KMSKeyRotated"
+	eventKMSKeyUnusable       = "KMSKeyUnusable"
+	eventBucketRegionMismatch = "BucketRegionMismatch"
+)
+
+// recordEvent records an event on the Velero instance, unless the reconciler
+// has no event recorder.
+func (r *ReconcileVelero) recordEvent(instance *veleroCR.Velero, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.recorder == nil {
+		return
+	}
+	// Nothing really changed in dry-run mode
+	if r.options.dryRun {
+		messageFmt = "Dry run: " + messageFmt
+	}
+	r.recorder.Eventf(instance, eventType, reason, messageFmt, args...)
+}
+
+// recordBucketFailure records a Warning event for the failed operation on the
+// bucket, which is an AccessDenied event when the credentials were denied.
+func (r *ReconcileVelero) recordBucketFailure(instance *veleroCR.Velero, reason string, bucketName string, err error) {
+	if s3.IsAccessDenied(err) {
+		reason = eventAccessDenied
+	}
+	r.recordEvent(instance, corev1.EventTypeWarning, reason, "Bucket %v: %v", bucketName, err)
+}
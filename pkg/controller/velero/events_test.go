@@ -0,0 +1,118 @@
+package velero
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"k8s.io/client-go/tools/record"
+)
+
+// createDeniedS3Client is a mockS3Client whose credentials may not create buckets.
+type createDeniedS3Client struct {
+	*mockS3Client
+}
+
+// CreateBucket implements the CreateBucket method for createDeniedS3Client.
+func (c *createDeniedS3Client) CreateBucket(ctx context.Context, input *awss3.CreateBucketInput) (*awss3.CreateBucketOutput, error) {
+	return nil, awserr.New("AccessDenied", "Access Denied", nil)
+}
+
+// clonedBucketS3Client is a mockS3Client whose bucket was cloned, so that
+// another bucket carries the same tags.
+type clonedBucketS3Client struct {
+	*mockS3Client
+}
+
+// ListBuckets implements the ListBuckets method for clonedBucketS3Client.
+func (c *clonedBucketS3Client) ListBuckets(ctx context.Context, input *awss3.ListBucketsInput) (*awss3.ListBucketsOutput, error) {
+	return &awss3.ListBucketsOutput{Buckets: []*awss3.Bucket{
+		{Name: aws.String(c.bucketName + "-clone")},
+		{Name: aws.String(c.bucketName)},
+	}}, nil
+}
+
+func TestProvisionS3DuplicateBuckets(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket.Name = ""
+	instance.Status.S3Bucket.Provisioned = false
+	r := newTestReconciler(t, instance)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.tags = []*awss3.Tag{
+		{Key: aws.String(s3.TagKey("", s3.DefaultTagKeyPrefix+"backup-location")), Value: aws.String(defaultBackupStorageLocation)},
+		{Key: aws.String(s3.TagKey("", s3.DefaultTagKeyPrefix+"infrastructureName")), Value: aws.String(testInfraName)},
+	}
+
+	if _, err := r.provisionS3(context.TODO(), log, &clonedBucketS3Client{s3Client}, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if got := getTestInstance(t, r).Status.S3Bucket.Name; got != testBucketName {
+		t.Errorf("recovered bucket = %v, want the first bucket in sorted order %v", got, testBucketName)
+	}
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+eventDuplicateBuckets+" ") ||
+		!strings.Contains(events[0], testBucketName+", "+testBucketName+"-clone") {
+		t.Errorf("recorded events %q, want a %v warning about both buckets", events, eventDuplicateBuckets)
+	}
+}
+
+func TestProvisionS3Events(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  s3.Client
+		wantErr bool
+		want    []string
+	}{
+		{
+			name:   "create and configure",
+			client: newMockS3Client(""),
+			want: []string{
+				"Normal " + eventBucketCreated,
+				"Normal " + eventTaggingApplied,
+				"Normal " + eventEncryptionEnabled,
+			},
+		},
+		{
+			name:    "create denied",
+			client:  &createDeniedS3Client{newMockS3Client("")},
+			wantErr: true,
+			want:    []string{"Warning " + eventAccessDenied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			instance.Status.S3Bucket.Provisioned = false
+			r := newTestReconciler(t, instance)
+			recorder := record.NewFakeRecorder(10)
+			r.recorder = recorder
+
+			if _, err := r.provisionS3(context.TODO(), log, tt.client, instance, testInfraName); (err != nil) != tt.wantErr {
+				t.Fatalf("provisionS3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("recorded events %q, want %q", events, tt.want)
+			}
+			for i, event := range events {
+				if !strings.HasPrefix(event, tt.want[i]+" ") || !strings.Contains(event, testBucketName) {
+					t.Errorf("event %d = %q, want %v about bucket %v", i, event, tt.want[i], testBucketName)
+				}
+			}
+		})
+	}
+}
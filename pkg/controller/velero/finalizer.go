@@ -0,0 +1,179 @@
+package velero
+
+import (
+	"context"
+	"fmt"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+	"github.com/openshift/managed-velero-operator/pkg/util/platform"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// bucketFinalizer holds back the deletion of a Velero instance until its
+// bucket has been deleted.
+const bucketFinalizer = "managed.openshift.io/delete-bucket"
+
+// hasBucketFinalizer checks whether the Velero instance holds the bucket finalizer.
+func hasBucketFinalizer(instance *veleroCR.Velero) bool {
+	for _, finalizer := range instance.Finalizers {
+		if finalizer == bucketFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBucketFinalizer removes the bucket finalizer from the Velero instance.
+func removeBucketFinalizer(instance *veleroCR.Velero) {
+	var finalizers []string
+	for _, finalizer := range instance.Finalizers {
+		if finalizer != bucketFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	instance.Finalizers = finalizers
+}
+
+// deletesBucketOnUninstall checks whether deleteBucketOnUninstall is set for
+// any backup storage location of the Velero instance.
+func deletesBucketOnUninstall(instance *veleroCR.Velero) bool {
+	for _, location := range instance.Spec.StorageLocations() {
+		if location.DeleteBucketOnUninstall {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileBucketFinalizer adds the bucket finalizer to the Velero instance
+// when deleteBucketOnUninstall is set for any location, and removes it
+// otherwise. It reports whether the instance was updated, which triggers
+// another reconcile.
+func (r *ReconcileVelero) reconcileBucketFinalizer(reqLogger logr.Logger, instance *veleroCR.Velero) (bool, error) {
+	deleteBucket := deletesBucketOnUninstall(instance)
+	if deleteBucket == hasBucketFinalizer(instance) {
+		return false, nil
+	}
+	if deleteBucket {
+		reqLogger.Info("Adding finalizer deleting the bucket on uninstall")
+		instance.Finalizers = append(instance.Finalizers, bucketFinalizer)
+	} else {
+		reqLogger.Info("Removing finalizer deleting the bucket on uninstall")
+		removeBucketFinalizer(instance)
+	}
+	return true, r.client.Update(context.TODO(), instance)
+}
+
+// finalizeVelero deletes the buckets of a Velero instance being deleted, and
+// then removes the bucket finalizer so that the instance can go away.
+func (r *ReconcileVelero) finalizeVelero(ctx context.Context, reqLogger logr.Logger, instance *veleroCR.Velero) (reconcile.Result, error) {
+	if !hasBucketFinalizer(instance) {
+		return reconcile.Result{}, nil
+	}
+
+	s3Client, err := r.uninstallS3Client(reqLogger, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if s3Client != nil {
+		if r.options.dryRun {
+			dryRunClient := s3.NewDryRunClient(s3Client)
+			defer logPlannedActions(reqLogger, dryRunClient)
+			s3Client = dryRunClient
+		}
+		if err = r.deleteBucket(ctx, reqLogger, s3Client, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	removeBucketFinalizer(instance)
+	return reconcile.Result{}, r.client.Update(context.TODO(), instance)
+}
+
+// uninstallS3Client returns a client for the S3 bucket of a Velero instance
+// being deleted, or nil when there is no S3 bucket to delete.
+func (r *ReconcileVelero) uninstallS3Client(reqLogger logr.Logger, instance *veleroCR.Velero) (s3.Client, error) {
+	s3Client, platformType, err := r.bucketS3Client(reqLogger, instance)
+	if err != nil {
+		return nil, err
+	}
+	if s3Client == nil {
+		reqLogger.Info("Only S3 buckets are deleted on uninstall, leaving the bucket", "Platform", platformType)
+		return nil, nil
+	}
+	// The deleted bucket must no longer be found by the cached discoveries
+	return r.discovery.InvalidatingClient(s3Client), nil
+}
+
+// bucketS3Client returns a client for the region of the S3 bucket of a Velero
+// instance, or nil with the type of the platform when the cluster isn't on AWS.
+func (r *ReconcileVelero) bucketS3Client(reqLogger logr.Logger, instance *veleroCR.Velero) (s3.Client, configv1.PlatformType, error) {
+	infrastructureStatusClient, err := platform.GetInfrastructureClient()
+	if err != nil {
+		return nil, "", err
+	}
+	infraStatus, err := platform.GetInfrastructureStatus(infrastructureStatusClient)
+	if err != nil {
+		return nil, "", err
+	}
+	if infraStatus.PlatformStatus.AWS == nil || len(infraStatus.PlatformStatus.AWS.Region) < 1 {
+		return nil, infraStatus.PlatformStatus.Type, nil
+	}
+	region := bucketRegion(instance, infraStatus.PlatformStatus.AWS.Region)
+	s3Client, err := s3.NewS3ClientForEndpoint(r.client, region, r.s3Endpoint(instance), r.s3Credentials())
+	if err != nil {
+		return nil, "", err
+	}
+	return s3.NewLoggingClient(r.breaker.Client(s3Client), reqLogger), infraStatus.PlatformStatus.Type, nil
+}
+
+// deleteBucket empties and deletes the buckets of a Velero instance being
+// deleted, for every backup storage location with deleteBucketOnUninstall
+// set. Frozen buckets are left unchanged.
+func (r *ReconcileVelero) deleteBucket(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero) error {
+	if bucketFrozen(instance) {
+		reqLogger.Info("S3 buckets are frozen, leaving them on uninstall")
+		return nil
+	}
+	for _, location := range storageLocations(instance) {
+		if !location.spec.DeleteBucketOnUninstall || location.bucket.Name == "" {
+			continue
+		}
+		locationClient, err := r.locationS3Client(reqLogger, s3Client, instance, location)
+		if err != nil {
+			return err
+		}
+		if err = deleteLocationBucket(ctx, reqLogger, locationClient, location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteLocationBucket empties and deletes the bucket of the backup storage
+// location, including every object version and delete marker, after lifting
+// the read-only bucket policy. A bucket which is already gone is ignored.
+func deleteLocationBucket(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, location storageLocation) error {
+	bucketName := location.bucket.Name
+	bucketLog := reqLogger.WithValues("Location", location.name, "S3Bucket.Name", bucketName)
+
+	// The read-only bucket policy would deny emptying the bucket
+	if location.bucket.ReadOnly {
+		err := s3.SetBucketReadOnlyPolicy(ctx, s3Client, bucketName, false)
+		if err != nil && !s3.IsNoSuchBucket(err) {
+			return fmt.Errorf("error occurred when allowing writes to bucket %v: %v", bucketName, err)
+		}
+	}
+
+	bucketLog.Info("Velero instance deleted, deleting S3 bucket and its contents")
+	err := s3.DeleteBucket(ctx, s3Client, bucketName, true)
+	if err != nil && !s3.IsNoSuchBucket(err) {
+		return fmt.Errorf("error occurred when deleting bucket %v: %v", bucketName, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package velero
+
+import (
+	"context"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+)
+
+func TestReconcileBucketFinalizer(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{DeleteBucketOnUninstall: true},
+	})
+	instance.Finalizers = []string{"example.com/other"}
+	r := newTestReconciler(t, instance)
+
+	assertFinalizer := func(want bool) {
+		t.Helper()
+		stored := getTestInstance(t, r)
+		if got := hasBucketFinalizer(stored); got != want {
+			t.Errorf("bucket finalizer set = %v, want %v", got, want)
+		}
+		if len(stored.Finalizers) == 0 || stored.Finalizers[0] != "example.com/other" {
+			t.Errorf("finalizers = %v, want the other finalizer kept", stored.Finalizers)
+		}
+	}
+
+	// Opting in adds the finalizer once
+	for _, wantUpdated := range []bool{true, false} {
+		instance = getTestInstance(t, r)
+		updated, err := r.reconcileBucketFinalizer(log, instance)
+		if err != nil {
+			t.Fatalf("reconcileBucketFinalizer() error = %v", err)
+		}
+		if updated != wantUpdated {
+			t.Errorf("reconcileBucketFinalizer() = %v, want %v", updated, wantUpdated)
+		}
+		assertFinalizer(true)
+	}
+
+	// Opting out removes it again
+	instance.Spec.BackupStorageLocation.DeleteBucketOnUninstall = false
+	if _, err := r.reconcileBucketFinalizer(log, instance); err != nil {
+		t.Fatalf("reconcileBucketFinalizer() error = %v", err)
+	}
+	assertFinalizer(false)
+}
+
+func TestDeleteBucketOnUninstall(t *testing.T) {
+	tests := []struct {
+		name        string
+		objects     []string
+		annotations map[string]string
+		readOnly    bool
+		wantDeleted bool
+	}{
+		{
+			name:        "empty bucket",
+			wantDeleted: true,
+		},
+		{
+			name:        "non-empty bucket",
+			objects:     []string{"backups/a/velero-backup.json", "backups/b/velero-backup.json"},
+			wantDeleted: true,
+		},
+		{
+			name:        "read-only bucket",
+			objects:     []string{"backups/a/velero-backup.json"},
+			readOnly:    true,
+			wantDeleted: true,
+		},
+		{
+			name:        "frozen bucket",
+			objects:     []string{"backups/a/velero-backup.json"},
+			annotations: map[string]string{bucketFrozenAnnotation: "true"},
+			wantDeleted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{DeleteBucketOnUninstall: true},
+			})
+			instance.Annotations = tt.annotations
+			instance.Status.S3Bucket.ReadOnly = tt.readOnly
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			for _, key := range tt.objects {
+				s3Client.objects[key] = true
+			}
+			if tt.readOnly {
+				if err := s3.SetBucketReadOnlyPolicy(context.TODO(), s3Client, testBucketName, true); err != nil {
+					t.Fatalf("SetBucketReadOnlyPolicy() error = %v", err)
+				}
+				s3Client.mutations = nil
+			}
+
+			if err := r.deleteBucket(context.TODO(), log, s3Client, instance); err != nil {
+				t.Fatalf("deleteBucket() error = %v", err)
+			}
+			if deleted := s3Client.bucketName == ""; deleted != tt.wantDeleted {
+				t.Errorf("bucket deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantDeleted && len(s3Client.objects) > 0 {
+				t.Errorf("bucket objects = %v, want none after deleting the bucket", s3Client.objects)
+			}
+			if tt.readOnly && (len(s3Client.mutations) == 0 || s3Client.mutations[0] != "DeleteBucketPolicy") {
+				t.Errorf("deleteBucket() mutations = %v, want the read-only policy lifted first", s3Client.mutations)
+			}
+			if !tt.wantDeleted && len(s3Client.mutations) > 0 {
+				t.Errorf("deleteBucket() changed the bucket with %v", s3Client.mutations)
+			}
+		})
+	}
+}
+
+func TestDeleteBucketOnUninstallLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary", Region: "eu-west-1", DeleteBucketOnUninstall: true},
+		},
+	})
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket", Provisioned: true}},
+	}
+	r := newTestReconciler(t, instance)
+	defaultClient := newMockS3Client(testBucketName)
+	secondaryClient := newMockS3Client("secondary-bucket")
+	s3Client := &regionalMockS3Client{
+		mockS3Client: defaultClient,
+		regions:      map[string]*mockS3Client{"eu-west-1": secondaryClient},
+	}
+
+	if !deletesBucketOnUninstall(instance) {
+		t.Errorf("deletesBucketOnUninstall() = false with deleteBucketOnUninstall set for the secondary location")
+	}
+	if err := r.deleteBucket(context.TODO(), log, s3Client, instance); err != nil {
+		t.Fatalf("deleteBucket() error = %v", err)
+	}
+	if secondaryClient.bucketName != "" {
+		t.Errorf("bucket of the secondary location wasn't deleted")
+	}
+	if defaultClient.bucketName != testBucketName {
+		t.Errorf("bucket of the default location was deleted without deleteBucketOnUninstall")
+	}
+}
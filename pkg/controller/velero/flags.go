@@ -0,0 +1,195 @@
+package velero
+
+import (
+	"time"
+
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/spf13/pflag"
+)
+
+// options holds the operator-level configuration of the controller.
+type options struct {
+	// scanRegions lists the additional regions searched for an existing bucket
+	// to adopt.
+	scanRegions []string
+
+	// scanExclude lists the bucket name globs which are skipped when searching
+	// for an existing bucket to adopt.
+	scanExclude []string
+
+	// scanConcurrency bounds how many buckets have their tags read at once
+	// when searching for an existing bucket to adopt.
+	scanConcurrency int
+
+	// discoveryCacheTTL is how long the buckets and their tags listed when
+	// searching for an existing bucket to adopt are reused, unless 0.
+	discoveryCacheTTL time.Duration
+
+	// maxBucketRestarts bounds how often provisioning restarts when the bucket
+	// disappears during configuration.
+	maxBucketRestarts int
+
+	// tagPolicyRequiredKeys and tagPolicyAllowedValues describe the tag policy
+	// the bucket tags must comply with.
+	tagPolicyRequiredKeys  []string
+	tagPolicyAllowedValues []string
+
+	// maxLifecycleDays caps the lifecycle retention of the bucket, unless 0.
+	// lifecycleCapPolicy selects whether a longer retention is clamped to the
+	// cap or rejected.
+	maxLifecycleDays   int64
+	lifecycleCapPolicy string
+
+	// s3Endpoint is a custom S3 endpoint used instead of the AWS endpoint of
+	// the cluster's region, such as an S3-compatible object store.
+	s3Endpoint string
+
+	// s3UseAccelerate and s3UseDualStack address the S3 Transfer
+	// Acceleration and the dual-stack endpoints of AWS, rather than the
+	// regional endpoint.
+	s3UseAccelerate bool
+	s3UseDualStack  bool
+
+	// s3MaxAttempts and s3RetryBaseDelay configure how the calls to the S3
+	// API are retried on transient errors, such as throttling.
+	s3MaxAttempts    int
+	s3RetryBaseDelay time.Duration
+	// s3OperationTimeout bounds each attempt of a call to the S3 API, unless 0.
+	s3OperationTimeout time.Duration
+
+	// s3CircuitBreakerThreshold, s3CircuitBreakerWindow and
+	// s3CircuitBreakerOpenDuration configure when the calls to the S3 API
+	// are stopped after repeated failures, and for how long.
+	s3CircuitBreakerThreshold    int
+	s3CircuitBreakerWindow       time.Duration
+	s3CircuitBreakerOpenDuration time.Duration
+
+	// bucketNameSuffixLength is the length of the random suffix of the
+	// generated bucket names, unless 0, when the suffix is a UUID.
+	bucketNameSuffixLength int
+
+	// tagKeyPrefix prefixes the keys of the operator's bucket tags.
+	tagKeyPrefix string
+
+	// awsCredentialsMode selects whether the S3 clients use the static keys
+	// of the credentials secret, or assume a role with a web identity token.
+	awsCredentialsMode string
+
+	// reconcileTimeout bounds how long a reconcile may take before its S3
+	// calls are cancelled, unless 0.
+	reconcileTimeout time.Duration
+
+	// reconcileInterval is how long after reaching the desired state a Velero
+	// instance is reconciled again, unless 0.
+	reconcileInterval time.Duration
+
+	// reconcileOnChangeOnly stops the periodic reconciles and bucket syncs, so
+	// that an unchanged Velero instance whose bucket is ready is only
+	// reconciled again after the safetyResyncInterval. Changes to the objects
+	// the operator installs are then only corrected by the safety resync.
+	reconcileOnChangeOnly bool
+	safetyResyncInterval  time.Duration
+
+	// maxReconcileFailures is how many times in a row reconciling a Velero
+	// instance may fail before it is marked Degraded, unless 0.
+	// degradedRequeueInterval is how long after failing a Degraded instance
+	// is reconciled again.
+	maxReconcileFailures    int
+	degradedRequeueInterval time.Duration
+
+	// dryRun has the calls changing S3 recorded in the status of the Velero
+	// instance, rather than made.
+	dryRun bool
+
+	// validatingWebhook serves the validating admission webhook of the Velero
+	// instances.
+	validatingWebhook bool
+
+	// inventoryInterval is how often the objects in the buckets are counted
+	// and reported in the status of the Velero instances, unless 0.
+	inventoryInterval time.Duration
+
+	// maxConcurrentReconciles bounds how many Velero instances are reconciled
+	// at once.
+	maxConcurrentReconciles int
+}
+
+const (
+	// lifecycleCapClamp reduces a retention exceeding the cap to the cap.
+	lifecycleCapClamp = "clamp"
+	// lifecycleCapReject leaves the lifecycle rules unchanged when the retention exceeds the cap.
+	lifecycleCapReject = "reject"
+)
+
+// flagOptions is populated from the command line flags.
+var flagOptions options
+
+// FlagSet returns the command line flags used to configure the controller.
+// The flag set must be added before calling pflag.Parse().
+func FlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("velero", pflag.ExitOnError)
+	fs.StringSliceVar(&flagOptions.scanRegions, "scan-regions", nil,
+		"Additional AWS regions to search for an existing bucket to adopt")
+	fs.StringSliceVar(&flagOptions.scanExclude, "scan-exclude", nil,
+		"Bucket name globs to skip when searching for an existing bucket to adopt")
+	fs.IntVar(&flagOptions.scanConcurrency, "scan-concurrency", s3.DefaultScanConcurrency,
+		"How many buckets to read the tags of at once when searching for an existing bucket to adopt")
+	fs.DurationVar(&flagOptions.discoveryCacheTTL, "discovery-cache-ttl", s3.DefaultDiscoveryCacheTTL,
+		"How long to reuse the buckets and their tags listed when searching for an existing bucket to adopt, or 0 to list them on every search")
+	fs.IntVar(&flagOptions.maxBucketRestarts, "max-bucket-restarts", 2,
+		"How often to restart provisioning when the bucket disappears while being configured")
+	fs.StringSliceVar(&flagOptions.tagPolicyRequiredKeys, "tag-policy-required-keys", nil,
+		"Tag keys the bucket tags must include")
+	fs.StringSliceVar(&flagOptions.tagPolicyAllowedValues, "tag-policy-allowed-values", nil,
+		"key=pattern pairs restricting the values of the bucket tags")
+	fs.Int64Var(&flagOptions.maxLifecycleDays, "max-lifecycle-days", 0,
+		"Maximum days backups may be retained in the bucket, or 0 for no maximum")
+	fs.StringVar(&flagOptions.lifecycleCapPolicy, "lifecycle-cap-policy", lifecycleCapClamp,
+		"Whether a retention exceeding --max-lifecycle-days is clamped to it or rejected, one of clamp or reject")
+	fs.StringVar(&flagOptions.s3Endpoint, "s3-endpoint", "",
+		"Custom S3 endpoint URL to use instead of the AWS endpoint of the cluster's region")
+	fs.BoolVar(&flagOptions.s3UseAccelerate, "s3-use-accelerate", false,
+		"Address the buckets at the S3 Transfer Acceleration endpoint, enabling acceleration on them")
+	fs.BoolVar(&flagOptions.s3UseDualStack, "s3-use-dualstack", false,
+		"Address the dual-stack S3 endpoint of the region, which is reachable over IPv6")
+	fs.IntVar(&flagOptions.s3MaxAttempts, "s3-max-attempts", s3.DefaultRetryPolicy.MaxAttempts,
+		"How often a call to the S3 API is attempted when it fails with a transient error, such as throttling")
+	fs.DurationVar(&flagOptions.s3RetryBaseDelay, "s3-retry-base-delay", s3.DefaultRetryPolicy.BaseDelay,
+		"Delay before retrying a call to the S3 API, doubling with every further retry")
+	fs.DurationVar(&flagOptions.s3OperationTimeout, "s3-operation-timeout", s3.DefaultRetryPolicy.OperationTimeout,
+		"How long an attempt of a call to the S3 API may take before it is retried, or 0 for no timeout")
+	fs.IntVar(&flagOptions.s3CircuitBreakerThreshold, "s3-circuit-breaker-threshold", s3.DefaultCircuitBreakerThreshold,
+		"How many consecutive calls to the S3 API failing with a transient error stop the calls, or 0 to never stop them")
+	fs.DurationVar(&flagOptions.s3CircuitBreakerWindow, "s3-circuit-breaker-window", s3.DefaultCircuitBreakerWindow,
+		"How close together the failed calls to the S3 API must be to stop the calls")
+	fs.DurationVar(&flagOptions.s3CircuitBreakerOpenDuration, "s3-circuit-breaker-open-duration", s3.DefaultCircuitBreakerOpenDuration,
+		"How long the calls to the S3 API are stopped, and the Velero instances requeued for, before a call probes S3 again")
+	fs.IntVar(&flagOptions.bucketNameSuffixLength, "bucket-name-suffix-length", 0,
+		"Length of the lowercase alphanumeric random suffix of the generated S3 bucket names, or 0 for a UUID suffix")
+	fs.StringVar(&flagOptions.tagKeyPrefix, "tag-key-prefix", s3.DefaultTagKeyPrefix,
+		"Prefix of the keys of the operator's bucket tags, replacing the velero.io/ of velero.io/backup-location")
+	fs.StringVar(&flagOptions.awsCredentialsMode, "aws-credentials-mode", s3.CredentialsModeSecret,
+		"How the S3 clients are authenticated, one of secret, reading the credentials secret, or webIdentity, assuming AWS_ROLE_ARN with AWS_WEB_IDENTITY_TOKEN_FILE")
+	fs.DurationVar(&flagOptions.reconcileTimeout, "reconcile-timeout", 10*time.Minute,
+		"How long a reconcile may take before its S3 calls are cancelled, or 0 for no timeout")
+	fs.DurationVar(&flagOptions.reconcileInterval, "reconcile-interval", 10*time.Minute,
+		"How long after reaching the desired state a Velero instance is reconciled again, or 0 to only reconcile on changes")
+	fs.BoolVar(&flagOptions.reconcileOnChangeOnly, "reconcile-on-change-only", false,
+		"Only reconcile the Velero instances whose spec changed, or whose bucket isn't ready, rather than periodically, and sync the buckets after --safety-resync-interval")
+	fs.DurationVar(&flagOptions.safetyResyncInterval, "safety-resync-interval", 24*time.Hour,
+		"How long after reaching the desired state a Velero instance is reconciled again with --reconcile-on-change-only")
+	fs.IntVar(&flagOptions.maxReconcileFailures, "max-reconcile-failures", 0,
+		"How many times in a row reconciling a Velero instance may fail before it is marked Degraded, or 0 to retry with the controller's backoff forever")
+	fs.DurationVar(&flagOptions.degradedRequeueInterval, "degraded-requeue-interval", time.Hour,
+		"How long after failing a Degraded Velero instance is reconciled again, or 0 to wait for it to change")
+	fs.BoolVar(&flagOptions.dryRun, "dry-run", false,
+		"Record the S3 calls changing the buckets in the status of the Velero instances, rather than making them")
+	fs.BoolVar(&flagOptions.validatingWebhook, "validating-webhook", false,
+		"Serve the admission webhook rejecting invalid Velero instances, which requires a serving certificate in the webhook server's certificate directory")
+	fs.DurationVar(&flagOptions.inventoryInterval, "inventory-interval", 0,
+		"How often to count the objects in the buckets and report their number and size in the status of the Velero instances, or 0 to not count them")
+	fs.IntVar(&flagOptions.maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"How many Velero instances to reconcile at once")
+	return fs
+}
@@ -0,0 +1,133 @@
+package velero
+
+import (
+	"fmt"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/gcs"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileGCP manages the GCS bucket of a cluster installed on GCP. The
+// bucket is recorded in the same status as an S3 bucket. Velero itself is
+// only installed on AWS so far: no BackupStorageLocation, credentials or
+// plugin are reconciled on GCP, which the VeleroInstalled condition reports.
+func (r *ReconcileVelero) reconcileGCP(reqLogger logr.Logger, instance *veleroCR.Velero, infraStatus *configv1.InfrastructureStatus) (reconcile.Result, error) {
+	// Verify that we have received a GCP project and region from the platform
+	gcp := infraStatus.PlatformStatus.GCP
+	if gcp == nil || len(gcp.ProjectID) < 1 || len(gcp.Region) < 1 {
+		return reconcile.Result{}, fmt.Errorf("unable to determine GCP project and region")
+	}
+
+	// Report that Velero isn't installed, rather than the bucket sync passing for it
+	if setStorageOnlyCondition(instance, configv1.GCPPlatformType) {
+		if err := r.statusUpdate(reqLogger, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// A frozen bucket is left unchanged
+	if bucketFrozen(instance) || !instance.S3BucketReconcileRequired(r.s3ReconcilePeriod()) {
+		return reconcile.Result{}, nil
+	}
+
+	gcsClient, err := gcs.NewGCSClient(r.client, gcp.ProjectID)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return r.provisionGCS(reqLogger, gcsClient, instance, infraStatus.InfrastructureName, gcp.Region)
+}
+
+func (r *ReconcileVelero) provisionGCS(reqLogger logr.Logger, gcsClient gcs.Client, instance *veleroCR.Velero, infraName string, location string) (reconcile.Result, error) {
+	var err error
+	bucketLog := reqLogger.WithValues("GCSBucket.Name", instance.Status.S3Bucket.Name, "GCSBucket.Location", location)
+
+	// This switch handles the provisioning steps/checks
+	switch {
+	// We don't yet have a bucket name selected
+	case instance.Status.S3Bucket.Name == "":
+
+		// Use an existing bucket, if it exists.
+		log.Info("No GCS bucket defined. Searching for existing bucket to use")
+		buckets, err := gcs.ListBucketLabels(gcsClient)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		existingBucket := gcs.FindMatchingLabels(buckets, infraName)
+		if existingBucket != "" {
+			log.Info(fmt.Sprintf("Recovered existing bucket: %s", existingBucket))
+			instance.Status.S3Bucket.Name = existingBucket
+			instance.Status.S3Bucket.Provisioned = true
+			instance.Status.S3Bucket.Created = false
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+
+		// Prepare to create a new bucket, if none exist.
+		proposedName := generateBucketName(bucketPrefix)
+		proposedBucketExists, err := gcs.DoesBucketExist(gcsClient, proposedName)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if proposedBucketExists {
+			return reconcile.Result{}, fmt.Errorf("proposed bucket %s already exists, retrying", proposedName)
+		}
+
+		log.Info("Setting proposed bucket name", "GCSBucket.Name", proposedName)
+		instance.Status.S3Bucket.Name = proposedName
+		instance.Status.S3Bucket.Provisioned = false
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+
+	// We have a bucket name, but haven't kicked off provisioning of the bucket yet
+	case instance.Status.S3Bucket.Name != "" && !instance.Status.S3Bucket.Provisioned:
+		bucketLog.Info("GCS bucket defined, but not provisioned")
+
+		// Create GCS bucket
+		bucketLog.Info("Creating GCS Bucket")
+		err = gcs.CreateBucket(gcsClient, instance.Status.S3Bucket.Name, location)
+		if err != nil {
+			if !gcs.IsBucketConflict(err) {
+				return reconcile.Result{}, fmt.Errorf("error occurred when creating bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+			}
+			// The name is taken, and the bucket is ours when it can be read
+			if owned, err := gcs.DoesBucketExist(gcsClient, instance.Status.S3Bucket.Name); err != nil || !owned {
+				bucketLog.Info("Bucket exists, but is not owned by current project; retrying")
+				instance.Status.S3Bucket.Name = ""
+				return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+			}
+			bucketLog.Info("Bucket exists, and is owned by current project; continue")
+		}
+		// The proposed name is unique, so a bucket owned by us was created by an earlier attempt
+		instance.Status.S3Bucket.Created = true
+	}
+
+	// Verify GCS bucket exists
+	bucketLog.Info("Verifing GCS Bucket exists")
+	exists, err := gcs.DoesBucketExist(gcsClient, instance.Status.S3Bucket.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+	}
+	if !exists {
+		bucketLog.Error(nil, "GCS bucket doesn't appear to exist")
+		instance.Status.S3Bucket.Provisioned = false
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
+
+	// Make sure that labels are applied to buckets
+	bucketLog.Info("Enforcing GCS Bucket labels on GCS Bucket")
+	err = gcs.LabelBucket(gcsClient, instance.Status.S3Bucket.Name, defaultBackupStorageLocation, infraName)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error occurred when labeling bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+	}
+
+	instance.Status.S3Bucket.Provisioned = true
+	instance.Status.S3Bucket.LastSyncTimestamp = &metav1.Time{
+		Time: time.Now(),
+	}
+	return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+}
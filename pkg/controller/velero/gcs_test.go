@@ -0,0 +1,142 @@
+package velero
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	"cloud.google.com/go/storage"
+	configv1 "github.com/openshift/api/config/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// mockGCSClient is a gcs.Client holding the buckets of a single project in memory.
+type mockGCSClient struct {
+	// buckets holds the attributes of every bucket, by bucket name.
+	buckets map[string]*storage.BucketAttrs
+}
+
+// CreateBucket implements the CreateBucket method for mockGCSClient.
+func (c *mockGCSClient) CreateBucket(bucketName string, attrs *storage.BucketAttrs) error {
+	if _, ok := c.buckets[bucketName]; ok {
+		return &googleapi.Error{Code: http.StatusConflict, Message: "You already own this bucket."}
+	}
+	created := *attrs
+	created.Name = bucketName
+	c.buckets[bucketName] = &created
+	return nil
+}
+
+// GetBucketAttrs implements the GetBucketAttrs method for mockGCSClient.
+func (c *mockGCSClient) GetBucketAttrs(bucketName string) (*storage.BucketAttrs, error) {
+	attrs, ok := c.buckets[bucketName]
+	if !ok {
+		return nil, storage.ErrBucketNotExist
+	}
+	return attrs, nil
+}
+
+// GetProjectID implements the GetProjectID method for mockGCSClient.
+func (c *mockGCSClient) GetProjectID() string {
+	return "test-project"
+}
+
+// ListBuckets implements the ListBuckets method for mockGCSClient.
+func (c *mockGCSClient) ListBuckets() ([]*storage.BucketAttrs, error) {
+	var buckets []*storage.BucketAttrs
+	for _, attrs := range c.buckets {
+		buckets = append(buckets, attrs)
+	}
+	return buckets, nil
+}
+
+// SetBucketLabels implements the SetBucketLabels method for mockGCSClient.
+func (c *mockGCSClient) SetBucketLabels(bucketName string, labels map[string]string) error {
+	attrs, ok := c.buckets[bucketName]
+	if !ok {
+		return storage.ErrBucketNotExist
+	}
+	if attrs.Labels == nil {
+		attrs.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		attrs.Labels[key] = value
+	}
+	return nil
+}
+
+func TestProvisionGCSCreatesBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	gcsClient := &mockGCSClient{buckets: map[string]*storage.BucketAttrs{}}
+
+	// The first pass proposes a name, and the second one creates the bucket
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionGCS(log, gcsClient, instance, testInfraName, "us-central1"); err != nil {
+			t.Fatalf("provisionGCS() error = %v", err)
+		}
+	}
+	status := getTestInstance(t, r).Status.S3Bucket
+	if !status.Provisioned || !status.Created || !strings.HasPrefix(status.Name, bucketPrefix) {
+		t.Fatalf("bucket status = %+v, want a created bucket", status)
+	}
+	attrs := gcsClient.buckets[status.Name]
+	if attrs == nil || attrs.Location != "us-central1" {
+		t.Fatalf("bucket = %+v, want it in us-central1", attrs)
+	}
+	if attrs.Labels["velero-infrastructure-name"] != strings.ToLower(testInfraName) || attrs.Labels["velero-backup-location"] != defaultBackupStorageLocation {
+		t.Errorf("bucket labels = %v, want the labels identifying the cluster", attrs.Labels)
+	}
+}
+
+func TestReconcileGCPReportsVeleroNotInstalled(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	r := newTestReconciler(t, instance)
+	infraStatus := &configv1.InfrastructureStatus{
+		InfrastructureName: testInfraName,
+		PlatformStatus: &configv1.PlatformStatus{
+			Type: configv1.GCPPlatformType,
+			GCP:  &configv1.GCPPlatformStatus{ProjectID: "test-project", Region: "us-central1"},
+		},
+	}
+
+	if _, err := r.reconcileGCP(log, instance, infraStatus); err != nil {
+		t.Fatalf("reconcileGCP() error = %v", err)
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.VeleroInstalled)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != "PlatformUnsupported" {
+		t.Errorf("VeleroInstalled condition = %+v, want status %v with reason PlatformUnsupported", condition, corev1.ConditionFalse)
+	}
+}
+
+func TestProvisionGCSRecoversBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	gcsClient := &mockGCSClient{buckets: map[string]*storage.BucketAttrs{
+		"unrelated": {Name: "unrelated"},
+		testBucketName: {
+			Name: testBucketName,
+			Labels: map[string]string{
+				"velero-backup-location":     defaultBackupStorageLocation,
+				"velero-infrastructure-name": strings.ToLower(testInfraName),
+			},
+		},
+	}}
+
+	if _, err := r.provisionGCS(log, gcsClient, instance, testInfraName, "us-central1"); err != nil {
+		t.Fatalf("provisionGCS() error = %v", err)
+	}
+	status := getTestInstance(t, r).Status.S3Bucket
+	if status.Name != testBucketName || !status.Provisioned || status.Created {
+		t.Errorf("bucket status = %+v, want the recovered bucket %v", status, testBucketName)
+	}
+	if len(gcsClient.buckets) != 2 {
+		t.Errorf("provisionGCS() created a bucket, but an existing one was recovered")
+	}
+}
@@ -0,0 +1,285 @@
+package velero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	iamPolicyConfigMapName = "managed-velero-operator-iam-policy"
+	iamPolicyKey           = "policy.json"
+	iamPolicyVersion       = "2012-10-17"
+)
+
+// iamPolicyDocument is an IAM policy granting the operator access to AWS.
+type iamPolicyDocument struct {
+	Version   string               `json:"Version"`
+	Statement []iamPolicyStatement `json:"Statement"`
+}
+
+// iamPolicyStatement is a statement of an iamPolicyDocument.
+type iamPolicyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource string   `json:"Resource"`
+}
+
+// operatorIAMPolicy returns the least-privilege IAM policy the operator needs
+// to manage the buckets of every backup storage location with the features
+// enabled in the Velero instance. The buckets are only read while frozen, and
+// are only deleted when they may be recreated. Transfer acceleration is
+// enabled as selected by the options.
+func operatorIAMPolicy(partitionID string, instance *veleroCR.Velero, opts options) iamPolicyDocument {
+	frozen := bucketFrozen(instance)
+
+	// Existing buckets are recovered from their tags, which are read for every bucket
+	statements := []iamPolicyStatement{
+		{
+			Sid:    "DiscoverBuckets",
+			Effect: "Allow",
+			Action: []string{
+				"s3:GetBucketTagging",
+				"s3:ListAllMyBuckets",
+			},
+			Resource: "*",
+		},
+	}
+
+	for index, location := range storageLocations(instance) {
+		statements = append(statements, locationIAMStatements(partitionID, instance, location, index, opts)...)
+	}
+
+	// The key is created, and tagged, before its ARN is known
+	createKey, checkKey := false, false
+	for _, location := range instance.Spec.StorageLocations() {
+		encryption := location.Encryption
+		if encryption.Type == veleroCR.EncryptionTypeKMS {
+			createKey = createKey || encryption.CreateKey
+			checkKey = checkKey || encryption.CreateKey || encryption.KMSKeyID != ""
+		}
+	}
+	if !frozen && createKey {
+		statements = append(statements, iamPolicyStatement{
+			Sid:    "CreateKMSKey",
+			Effect: "Allow",
+			Action: []string{
+				"kms:CreateKey",
+				"kms:TagResource",
+			},
+			Resource: "*",
+		})
+	}
+
+	// The key is checked to be usable, and an alias resolved to the key it
+	// points to, on every reconcile
+	if !frozen && checkKey {
+		statements = append(statements, iamPolicyStatement{
+			Sid:    "CheckKMSKey",
+			Effect: "Allow",
+			Action: []string{
+				"kms:DescribeKey",
+				"kms:GenerateDataKey",
+			},
+			Resource: "*",
+		})
+	}
+
+	return iamPolicyDocument{Version: iamPolicyVersion, Statement: statements}
+}
+
+// locationIAMStatements returns the statements of the IAM policy granting the
+// operator the bucket of the backup storage location at the index.
+func locationIAMStatements(partitionID string, instance *veleroCR.Velero, location storageLocation, index int, opts options) []iamPolicyStatement {
+	if onOutpost(location.spec) {
+		return outpostIAMStatements(partitionID, instance, location, index)
+	}
+	frozen := bucketFrozen(instance)
+
+	// A bucket which isn't selected yet is created with the bucket prefix
+	bucketName := location.bucket.Name
+	if bucketName == "" {
+		bucketName = bucketPrefix + "*"
+	}
+	bucketARN := fmt.Sprintf("arn:%s:s3:::%s", partitionID, bucketName)
+
+	bucketActions := []string{
+		"s3:GetBucketLocation",
+		"s3:GetBucketObjectLockConfiguration",
+		"s3:GetBucketPublicAccessBlock",
+		"s3:GetBucketTagging",
+		"s3:GetEncryptionConfiguration",
+		"s3:GetLifecycleConfiguration",
+		"s3:ListBucket",
+	}
+	var objectActions []string
+	if !frozen {
+		bucketActions = append(bucketActions,
+			"s3:CreateBucket",
+			"s3:DeleteBucketPolicy",
+			"s3:GetBucketOwnershipControls",
+			"s3:GetBucketPolicy",
+			"s3:GetBucketVersioning",
+			"s3:PutBucketAcl",
+			"s3:PutBucketOwnershipControls",
+			"s3:PutBucketPolicy",
+		)
+		if tagsManaged(location.spec) {
+			bucketActions = append(bucketActions, "s3:PutBucketTagging")
+		}
+		if encryptionManaged(location.spec) {
+			bucketActions = append(bucketActions, "s3:PutEncryptionConfiguration")
+		}
+		if publicAccessBlockManaged(location.spec) {
+			bucketActions = append(bucketActions, "s3:PutBucketPublicAccessBlock")
+		}
+		if lifecycleManaged(location.spec) {
+			bucketActions = append(bucketActions, "s3:PutLifecycleConfiguration")
+		}
+		// The probe object verifying the bucket is writable
+		if !bslReadOnly(instance, location.spec) {
+			objectActions = append(objectActions, "s3:DeleteObject", "s3:PutObject")
+		}
+		if location.spec.Versioning {
+			bucketActions = append(bucketActions, "s3:PutBucketVersioning")
+		}
+		if bucketEndpoint(instance, opts).Accelerate {
+			bucketActions = append(bucketActions, "s3:PutAccelerateConfiguration")
+		}
+		if location.spec.Logging.TargetBucket != "" {
+			bucketActions = append(bucketActions, "s3:GetBucketLogging", "s3:PutBucketLogging")
+		}
+		if location.spec.Replication.DestinationBucketARN != "" {
+			bucketActions = append(bucketActions, "s3:GetReplicationConfiguration", "s3:PutReplicationConfiguration")
+		}
+		if len(location.spec.CORSRules) > 0 {
+			bucketActions = append(bucketActions, "s3:GetBucketCORS", "s3:PutBucketCORS")
+		}
+		if notificationsManaged(location.spec) {
+			bucketActions = append(bucketActions, "s3:GetBucketNotification", "s3:PutBucketNotification")
+		}
+		if location.spec.RecreateOnImmutableChange || location.spec.DeleteBucketOnUninstall {
+			bucketActions = append(bucketActions, "s3:DeleteBucket", "s3:ListBucketVersions")
+		}
+		// Emptying the bucket deletes every object version
+		if (location.spec.RecreateOnImmutableChange && location.spec.ForceRecreate) || location.spec.DeleteBucketOnUninstall {
+			objectActions = append(objectActions, "s3:DeleteObjectVersion")
+		}
+	}
+	statements := []iamPolicyStatement{{
+		Sid:      locationSid("ManageBucket", index),
+		Effect:   "Allow",
+		Action:   bucketActions,
+		Resource: bucketARN,
+	}}
+	if len(objectActions) > 0 {
+		statements = append(statements, iamPolicyStatement{
+			Sid:      locationSid("ManageObjects", index),
+			Effect:   "Allow",
+			Action:   objectActions,
+			Resource: bucketARN + "/*",
+		})
+	}
+
+	// The log delivery may be granted in the bucket policy of the log target
+	// bucket, depending on its object ownership
+	if logging := location.spec.Logging; !frozen && logging.TargetBucket != "" && logging.DeliveryPermission != veleroCR.LogDeliveryPermissionACL {
+		statements = append(statements, iamPolicyStatement{
+			Sid:    locationSid("GrantLogDelivery", index),
+			Effect: "Allow",
+			Action: []string{
+				"s3:GetBucketOwnershipControls",
+				"s3:GetBucketPolicy",
+				"s3:PutBucketPolicy",
+			},
+			Resource: fmt.Sprintf("arn:%s:s3:::%s", partitionID, logging.TargetBucket),
+		})
+	}
+
+	// S3 assumes the replication role, which the operator has to pass to it
+	if replication := location.spec.Replication; !frozen && replication.RoleARN != "" {
+		statements = append(statements, iamPolicyStatement{
+			Sid:      locationSid("PassReplicationRole", index),
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: replication.RoleARN,
+		})
+	}
+
+	return statements
+}
+
+// locationSid returns the statement ID of the location at the index: the ID
+// itself for the default location, and suffixed with the index otherwise, as
+// the IDs must be unique within the policy.
+func locationSid(sid string, index int) string {
+	if index == 0 {
+		return sid
+	}
+	return fmt.Sprintf("%s%d", sid, index)
+}
+
+// reconcileIAMPolicy publishes the IAM policy the operator needs in a
+// ConfigMap, so that platform teams can grant the operator no more than that.
+func (r *ReconcileVelero) reconcileIAMPolicy(reqLogger logr.Logger, namespace, partitionID string, instance *veleroCR.Velero) (reconcile.Result, error) {
+	foundConfigMap := &corev1.ConfigMap{}
+	configMap, err := iamPolicyConfigMap(namespace, operatorIAMPolicy(partitionID, instance, r.options))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: iamPolicyConfigMapName}, foundConfigMap); err != nil {
+		if errors.IsNotFound(err) {
+			// Didn't find ConfigMap
+			reqLogger.Info("Creating IAM policy ConfigMap")
+			if err := controllerutil.SetControllerReference(instance, configMap, r.scheme); err != nil {
+				return reconcile.Result{}, err
+			}
+			if err = r.client.Create(context.TODO(), configMap); err != nil {
+				return reconcile.Result{}, err
+			}
+		} else {
+			return reconcile.Result{}, err
+		}
+	} else if !reflect.DeepEqual(foundConfigMap.Data, configMap.Data) {
+		// Data isn't equal, update and fix.
+		reqLogger.Info("Updating IAM policy ConfigMap")
+		foundConfigMap.Data = configMap.Data
+		if err = r.client.Update(context.TODO(), foundConfigMap); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func iamPolicyConfigMap(namespace string, policy iamPolicyDocument) (*corev1.ConfigMap, error) {
+	document, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode IAM policy: %v", err)
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      iamPolicyConfigMapName,
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			iamPolicyKey: string(document),
+		},
+	}, nil
+}
@@ -0,0 +1,304 @@
+package velero
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	"github.com/aws/aws-sdk-go/aws"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// policyActions returns the actions granted by the policy, and the resources they are granted on.
+func policyActions(policy iamPolicyDocument) map[string][]string {
+	actions := make(map[string][]string)
+	for _, statement := range policy.Statement {
+		for _, action := range statement.Action {
+			actions[action] = append(actions[action], statement.Resource)
+		}
+	}
+	return actions
+}
+
+func TestOperatorIAMPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        veleroCR.VeleroSpec
+		annotations map[string]string
+		opts        options
+		include     []string
+		exclude     []string
+	}{
+		{
+			name: "defaults",
+			include: []string{
+				"s3:CreateBucket", "s3:PutEncryptionConfiguration", "s3:PutLifecycleConfiguration",
+				"s3:PutBucketTagging", "s3:PutObject", "s3:DeleteObject",
+			},
+			exclude: []string{"s3:DeleteBucket", "s3:DeleteObjectVersion", "s3:PutBucketVersioning", "s3:PutBucketLogging", "kms:CreateKey"},
+		},
+		{
+			name: "versioning",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{Versioning: true},
+			},
+			include: []string{"s3:GetBucketVersioning", "s3:PutBucketVersioning"},
+		},
+		{
+			name: "unmanaged encryption",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManageEncryption: aws.Bool(false)},
+			},
+			include: []string{"s3:GetEncryptionConfiguration"},
+			exclude: []string{"s3:PutEncryptionConfiguration"},
+		},
+		{
+			name: "unmanaged tags",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManageTags: aws.Bool(false)},
+			},
+			include: []string{"s3:GetBucketTagging"},
+			exclude: []string{"s3:PutBucketTagging"},
+		},
+		{
+			name: "unmanaged lifecycle",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManageLifecycle: aws.Bool(false)},
+			},
+			include: []string{"s3:GetLifecycleConfiguration"},
+			exclude: []string{"s3:PutLifecycleConfiguration"},
+		},
+		{
+			name: "access logging",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Logging: veleroCR.BucketLoggingSpec{TargetBucket: "audit-logs"},
+				},
+			},
+			include: []string{"s3:GetBucketLogging", "s3:PutBucketLogging"},
+		},
+		{
+			name: "replication",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Versioning: true,
+					Replication: veleroCR.BucketReplicationSpec{
+						DestinationBucketARN: "arn:aws:s3:::velero-dr",
+						RoleARN:              "arn:aws:iam::123456789012:role/velero-replication",
+					},
+				},
+			},
+			include: []string{"s3:GetReplicationConfiguration", "s3:PutReplicationConfiguration", "iam:PassRole"},
+		},
+		{
+			name: "CORS rules",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					CORSRules: []veleroCR.BucketCORSRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}},
+				},
+			},
+			include: []string{"s3:GetBucketCORS", "s3:PutBucketCORS"},
+		},
+		{
+			name: "access logging",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Logging: veleroCR.BucketLoggingSpec{TargetBucket: "logs"},
+				},
+			},
+			include: []string{"s3:GetBucketLogging", "s3:PutBucketLogging", "s3:GetBucketOwnershipControls", "s3:PutBucketPolicy"},
+		},
+		{
+			name: "notifications",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Notifications: veleroCR.BucketNotificationSpec{EventBridge: true},
+				},
+			},
+			include: []string{"s3:GetBucketNotification", "s3:PutBucketNotification"},
+		},
+		{
+			name: "created KMS key",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, CreateKey: true},
+				},
+			},
+			include: []string{"kms:CreateKey", "kms:TagResource", "kms:GenerateDataKey"},
+		},
+		{
+			name: "existing KMS key",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: "alias/velero"},
+				},
+			},
+			include: []string{"s3:PutEncryptionConfiguration", "kms:DescribeKey"},
+			exclude: []string{"kms:CreateKey", "kms:TagResource"},
+		},
+		{
+			name: "existing KMS key ARN",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/existing"},
+				},
+			},
+			include: []string{"s3:PutEncryptionConfiguration", "kms:DescribeKey", "kms:GenerateDataKey"},
+			exclude: []string{"kms:CreateKey"},
+		},
+		{
+			name: "recreate empty bucket",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{RecreateOnImmutableChange: true},
+			},
+			include: []string{"s3:DeleteBucket", "s3:ListBucketVersions"},
+			exclude: []string{"s3:DeleteObjectVersion"},
+		},
+		{
+			name: "force recreate bucket",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{RecreateOnImmutableChange: true, ForceRecreate: true},
+			},
+			include: []string{"s3:DeleteBucket", "s3:ListBucketVersions", "s3:DeleteObjectVersion"},
+		},
+		{
+			name: "delete bucket on uninstall",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{DeleteBucketOnUninstall: true},
+			},
+			include: []string{"s3:DeleteBucket", "s3:ListBucketVersions", "s3:DeleteObjectVersion"},
+		},
+		{
+			name:    "transfer acceleration",
+			opts:    options{s3UseAccelerate: true},
+			include: []string{"s3:PutAccelerateConfiguration"},
+		},
+		{
+			name:    "transfer acceleration with a custom endpoint",
+			opts:    options{s3UseAccelerate: true, s3Endpoint: "https://minio.example.com:9000"},
+			exclude: []string{"s3:PutAccelerateConfiguration"},
+		},
+		{
+			name:        "read-only",
+			annotations: map[string]string{bslReadOnlyAnnotation: "true"},
+			include:     []string{"s3:PutBucketPolicy"},
+			exclude:     []string{"s3:PutObject", "s3:DeleteObject"},
+		},
+		{
+			name: "frozen",
+			spec: veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					RecreateOnImmutableChange: true,
+					Encryption:                veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, CreateKey: true},
+				},
+			},
+			annotations: map[string]string{bucketFrozenAnnotation: "true"},
+			include:     []string{"s3:GetEncryptionConfiguration", "s3:GetLifecycleConfiguration", "s3:GetBucketTagging"},
+			exclude: []string{
+				"s3:CreateBucket", "s3:DeleteBucket", "s3:PutEncryptionConfiguration", "s3:PutLifecycleConfiguration",
+				"s3:PutBucketTagging", "s3:PutObject", "kms:CreateKey",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(tt.spec)
+			instance.Annotations = tt.annotations
+			actions := policyActions(operatorIAMPolicy("aws", instance, tt.opts))
+			for _, action := range tt.include {
+				if _, ok := actions[action]; !ok {
+					t.Errorf("policy doesn't grant %v", action)
+				}
+			}
+			for _, action := range tt.exclude {
+				if _, ok := actions[action]; ok {
+					t.Errorf("policy grants %v", action)
+				}
+			}
+		})
+	}
+}
+
+func TestOperatorIAMPolicyResources(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	actions := policyActions(operatorIAMPolicy("aws-cn", instance, options{}))
+	if got, want := actions["s3:PutLifecycleConfiguration"], "arn:aws-cn:s3:::"+testBucketName; len(got) != 1 || got[0] != want {
+		t.Errorf("s3:PutLifecycleConfiguration resources = %v, want %v", got, want)
+	}
+	if got, want := actions["s3:PutObject"], "arn:aws-cn:s3:::"+testBucketName+"/*"; len(got) != 1 || got[0] != want {
+		t.Errorf("s3:PutObject resources = %v, want %v", got, want)
+	}
+
+	// Before the bucket is selected, the bucket prefix is granted
+	instance.Status.S3Bucket.Name = ""
+	actions = policyActions(operatorIAMPolicy("aws", instance, options{}))
+	if got, want := actions["s3:CreateBucket"], "arn:aws:s3:::"+bucketPrefix+"*"; len(got) != 1 || got[0] != want {
+		t.Errorf("s3:CreateBucket resources = %v, want %v", got, want)
+	}
+}
+
+func TestOperatorIAMPolicyLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary", Region: "eu-west-1"},
+		},
+	})
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket"}},
+	}
+	policy := operatorIAMPolicy("aws", instance, options{})
+
+	actions := policyActions(policy)
+	want := []string{"arn:aws:s3:::" + testBucketName, "arn:aws:s3:::secondary-bucket"}
+	if got := actions["s3:PutBucketPolicy"]; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("s3:PutBucketPolicy resources = %v, want %v", got, want)
+	}
+	sids := make(map[string]bool)
+	for _, statement := range policy.Statement {
+		if sids[statement.Sid] {
+			t.Errorf("statement ID %v isn't unique", statement.Sid)
+		}
+		sids[statement.Sid] = true
+	}
+}
+
+func TestReconcileIAMPolicy(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+
+	assertPolicy := func(assert func(actions map[string][]string)) {
+		t.Helper()
+		if _, err := r.reconcileIAMPolicy(log, testNamespace, "aws", instance); err != nil {
+			t.Fatalf("reconcileIAMPolicy() error = %v", err)
+		}
+		configMap := &corev1.ConfigMap{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: iamPolicyConfigMapName}, configMap); err != nil {
+			t.Fatalf("unable to get ConfigMap: %v", err)
+		}
+		var policy iamPolicyDocument
+		if err := json.Unmarshal([]byte(configMap.Data[iamPolicyKey]), &policy); err != nil {
+			t.Fatalf("unable to parse IAM policy: %v", err)
+		}
+		if policy.Version != iamPolicyVersion {
+			t.Errorf("IAM policy version = %v, want %v", policy.Version, iamPolicyVersion)
+		}
+		assert(policyActions(policy))
+	}
+	assertPolicy(func(actions map[string][]string) {
+		if _, ok := actions["s3:DeleteBucket"]; ok {
+			t.Errorf("policy grants s3:DeleteBucket, but the bucket is never deleted")
+		}
+	})
+
+	// Enabling bucket recreation updates the published policy
+	instance.Spec.BackupStorageLocation.RecreateOnImmutableChange = true
+	assertPolicy(func(actions map[string][]string) {
+		if _, ok := actions["s3:DeleteBucket"]; !ok {
+			t.Errorf("policy doesn't grant s3:DeleteBucket")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package velero
+
+import (
+	"context"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// inventoryReporter periodically counts the objects in the bucket of every
+// provisioned Velero instance, and reports their number and total size in its
+// status. Counting lists every object in the bucket, so it runs apart from the
+// reconciles rather than slowing them down.
+type inventoryReporter struct {
+	r        *ReconcileVelero
+	interval time.Duration
+}
+
+// Start implements manager.Runnable for inventoryReporter, inventorying the
+// buckets every interval until stop is closed.
+func (i *inventoryReporter) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			i.reportAll()
+		}
+	}
+}
+
+// reportAll inventories the bucket of every provisioned Velero instance. A
+// failure is logged, and the bucket is inventoried again on the next tick.
+func (i *inventoryReporter) reportAll() {
+	instances := &veleroCR.VeleroList{}
+	if err := i.r.client.List(context.TODO(), instances); err != nil {
+		log.Error(err, "Unable to list the Velero instances to inventory")
+		return
+	}
+	for idx := range instances.Items {
+		instance := &instances.Items[idx]
+		if instance.DeletionTimestamp != nil || !instance.Status.S3Bucket.Provisioned ||
+			onOutpost(instance.Spec.DefaultStorageLocation()) {
+			continue
+		}
+		reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+		s3Client, _, err := i.r.bucketS3Client(reqLogger, instance)
+		if err != nil {
+			reqLogger.Error(err, "Unable to create an S3 client to inventory the bucket")
+			continue
+		}
+		if s3Client == nil {
+			continue
+		}
+		ctx, cancel := i.r.reconcileContext()
+		if err = i.r.reportInventory(ctx, reqLogger, s3Client, instance); err != nil {
+			reqLogger.Error(err, "Unable to inventory the S3 bucket", "S3Bucket.Name", instance.Status.S3Bucket.Name)
+		}
+		cancel()
+	}
+}
+
+// reportInventory counts the objects in the bucket of the Velero instance,
+// and updates its status with their number and total size.
+func (r *ReconcileVelero) reportInventory(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero) error {
+	inventory, err := s3.InventoryBucket(ctx, s3Client, instance.Status.S3Bucket.Name)
+	if err != nil {
+		return err
+	}
+	now := metav1.Now()
+	instance.Status.S3Bucket.ObjectCount = inventory.ObjectCount
+	instance.Status.S3Bucket.TotalBytes = inventory.TotalBytes
+	instance.Status.S3Bucket.InventoryTimestamp = &now
+	return r.statusUpdate(reqLogger, instance)
+}
@@ -0,0 +1,31 @@
+package velero
+
+import (
+	"context"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+)
+
+func TestReportInventory(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.objects["backups/a/velero-backup.json"] = true
+	s3Client.objects["backups/b/velero-backup.json"] = true
+	s3Client.objects["restores/c/restore-c-logs.gz"] = true
+
+	if err := r.reportInventory(context.TODO(), log, s3Client, getTestInstance(t, r)); err != nil {
+		t.Fatalf("reportInventory() error = %v", err)
+	}
+	bucket := getTestInstance(t, r).Status.S3Bucket
+	if bucket.ObjectCount != 3 || bucket.TotalBytes != 3*1024 {
+		t.Errorf("inventory = %d objects of %d bytes, want 3 objects of %d bytes", bucket.ObjectCount, bucket.TotalBytes, 3*1024)
+	}
+	if bucket.InventoryTimestamp == nil {
+		t.Errorf("InventoryTimestamp not set")
+	}
+	if len(s3Client.mutations) != 0 {
+		t.Errorf("reportInventory() changed the bucket with %v, want no changes", s3Client.mutations)
+	}
+}
@@ -0,0 +1,75 @@
+package velero
+
+import (
+	"errors"
+	"strings"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ensureKMSKey returns the ARN of the KMS key the operator manages for the
+// bucket. The key is only created once, and its ARN is recorded in the status
+// so that it is reused by every following reconcile.
+func (r *ReconcileVelero) ensureKMSKey(reqLogger logr.Logger, kmsClient kms.Client, instance *veleroCR.Velero, location storageLocation, infraName string) (string, error) {
+	if location.bucket.KMSKeyARN != "" {
+		return location.bucket.KMSKeyARN, nil
+	}
+
+	reqLogger.Info("Creating KMS key for S3 Bucket encryption")
+	keyARN, err := kms.CreateKey(kmsClient, location.name, infraName)
+	if err != nil {
+		return "", err
+	}
+
+	// Record the key straight away, so that a failure in a later step
+	// doesn't result in another key being created.
+	reqLogger.Info("Created KMS key", "KMSKey.ARN", keyARN)
+	location.bucket.KMSKeyARN = keyARN
+	return keyARN, r.statusUpdate(reqLogger, instance)
+}
+
+// checkKMSKey verifies that the credentials can use the KMS key to encrypt
+// the backups, and records the result in the KMSKeyUsable condition. S3
+// accepts a bucket encryption with a key which can't be used, and only fails
+// the backups as Velero writes them.
+func (r *ReconcileVelero) checkKMSKey(reqLogger logr.Logger, kmsClient kms.Client, instance *veleroCR.Velero, location storageLocation, keyID string) error {
+	err := kms.PreflightKeyCheck(kmsClient, keyID)
+	var keyErr *kms.KeyUnusableError
+	if errors.As(err, &keyErr) {
+		r.recordEvent(instance, corev1.EventTypeWarning, eventKMSKeyUnusable, "Bucket %v: %v", location.bucket.Name, err)
+		return r.failCondition(reqLogger, instance, location, veleroCR.KMSKeyUsable, "KeyUnusable", err)
+	}
+	if err != nil {
+		return err
+	}
+	location.setCondition(instance, veleroCR.KMSKeyUsable, corev1.ConditionTrue, "KeyUsable", "")
+	return nil
+}
+
+// kmsClient returns the KMS client for the AWS config.
+func (r *ReconcileVelero) kmsClient(config *aws.Config) (kms.Client, error) {
+	if r.newKMSClient == nil {
+		return kms.NewKMSClient(config)
+	}
+	return r.newKMSClient(config)
+}
+
+// locationKMSKeyARN returns the ARN of the KMS key used to encrypt the bucket
+// of the backup storage location, if it is known.
+func locationKMSKeyARN(location storageLocation) string {
+	encryption := location.spec.Encryption
+	switch {
+	case encryption.Type != veleroCR.EncryptionTypeKMS:
+		return ""
+	case encryption.CreateKey:
+		return location.bucket.KMSKeyARN
+	case strings.HasPrefix(encryption.KMSKeyID, "arn:"):
+		return encryption.KMSKeyID
+	}
+	return ""
+}
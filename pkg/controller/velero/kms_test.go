@@ -0,0 +1,416 @@
+package velero
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awskms "github.com/aws/aws-sdk-go/service/kms"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testInfraName = "fakeCluster"
+
+// mockKMSClient implements the kms.Client interface.
+type mockKMSClient struct {
+	// createKeyInputs records every CreateKey request.
+	createKeyInputs []*awskms.CreateKeyInput
+	// aliases maps the aliases to the ARN of the key they point to.
+	aliases map[string]string
+	// generateDataKeyErr is returned by GenerateDataKey when set.
+	generateDataKeyErr error
+}
+
+// CreateKey implements the CreateKey method for mockKMSClient.
+// Every created key gets a new ARN.
+func (c *mockKMSClient) CreateKey(input *awskms.CreateKeyInput) (*awskms.CreateKeyOutput, error) {
+	c.createKeyInputs = append(c.createKeyInputs, input)
+	return &awskms.CreateKeyOutput{
+		KeyMetadata: &awskms.KeyMetadata{
+			Arn: aws.String(fmt.Sprintf("arn:aws:kms:%s:123456789012:key/%d", testRegion, len(c.createKeyInputs))),
+		},
+	}, nil
+}
+
+// DescribeKey implements the DescribeKey method for mockKMSClient.
+// Only the aliases, and the keys named by their ARN, are known.
+func (c *mockKMSClient) DescribeKey(input *awskms.DescribeKeyInput) (*awskms.DescribeKeyOutput, error) {
+	keyARN, ok := c.aliases[aws.StringValue(input.KeyId)]
+	if !ok && strings.HasPrefix(aws.StringValue(input.KeyId), "arn:") && !kms.IsAlias(aws.StringValue(input.KeyId)) {
+		keyARN, ok = aws.StringValue(input.KeyId), true
+	}
+	if !ok {
+		return nil, awserr.New(awskms.ErrCodeNotFoundException, "Alias is not found.", nil)
+	}
+	return &awskms.DescribeKeyOutput{KeyMetadata: &awskms.KeyMetadata{
+		Arn:      aws.String(keyARN),
+		KeyState: aws.String(awskms.KeyStateEnabled),
+		KeyUsage: aws.String(awskms.KeyUsageTypeEncryptDecrypt),
+	}}, nil
+}
+
+// GenerateDataKey implements the GenerateDataKey method for mockKMSClient.
+func (c *mockKMSClient) GenerateDataKey(input *awskms.GenerateDataKeyInput) (*awskms.GenerateDataKeyOutput, error) {
+	if c.generateDataKeyErr != nil {
+		return nil, c.generateDataKeyErr
+	}
+	return &awskms.GenerateDataKeyOutput{KeyId: input.KeyId, Plaintext: make([]byte, 32)}, nil
+}
+
+// GetAWSClientConfig returns a copy of the AWS Client Config for the mockKMSClient.
+func (c *mockKMSClient) GetAWSClientConfig() *aws.Config {
+	return &aws.Config{Region: aws.String(testRegion)}
+}
+
+// GetCallerIdentity implements the GetCallerIdentity method for mockKMSClient.
+func (c *mockKMSClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/managed-velero-operator"),
+	}, nil
+}
+
+func newKMSTestInstance() *veleroCR.Velero {
+	return newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Encryption: veleroCR.EncryptionSpec{
+				Type:      veleroCR.EncryptionTypeKMS,
+				CreateKey: true,
+			},
+		},
+	})
+}
+
+func TestEnsureKMSKeyCreatesOnce(t *testing.T) {
+	instance := newKMSTestInstance()
+	r := newTestReconciler(t, instance)
+	kmsClient := &mockKMSClient{}
+
+	first, err := r.ensureKMSKey(log, kmsClient, instance, defaultLocation(instance), testInfraName)
+	if err != nil {
+		t.Fatalf("ensureKMSKey() error = %v", err)
+	}
+	if first == "" {
+		t.Fatalf("ensureKMSKey() returned no key")
+	}
+
+	// The created key must be recorded in the stored status
+	stored := &veleroCR.Velero{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, stored); err != nil {
+		t.Fatalf("unable to get Velero instance: %v", err)
+	}
+	if stored.Status.S3Bucket.KMSKeyARN != first {
+		t.Errorf("status KMSKeyARN = %q, want %q", stored.Status.S3Bucket.KMSKeyARN, first)
+	}
+
+	// A later reconcile starts from the stored instance, and must reuse the key
+	second, err := r.ensureKMSKey(log, kmsClient, stored, defaultLocation(stored), testInfraName)
+	if err != nil {
+		t.Fatalf("ensureKMSKey() error = %v", err)
+	}
+	if second != first {
+		t.Errorf("ensureKMSKey() = %q on second reconcile, want %q", second, first)
+	}
+	if len(kmsClient.createKeyInputs) != 1 {
+		t.Errorf("ensureKMSKey() issued %d CreateKey calls, want 1", len(kmsClient.createKeyInputs))
+	}
+}
+
+func TestEnsureKMSKeyReusesRecordedKey(t *testing.T) {
+	instance := newKMSTestInstance()
+	instance.Status.S3Bucket.KMSKeyARN = "arn:aws:kms:us-east-1:123456789012:key/existing"
+	r := newTestReconciler(t, instance)
+	kmsClient := &mockKMSClient{}
+
+	got, err := r.ensureKMSKey(log, kmsClient, instance, defaultLocation(instance), testInfraName)
+	if err != nil {
+		t.Fatalf("ensureKMSKey() error = %v", err)
+	}
+	if got != instance.Status.S3Bucket.KMSKeyARN {
+		t.Errorf("ensureKMSKey() = %q, want %q", got, instance.Status.S3Bucket.KMSKeyARN)
+	}
+	if len(kmsClient.createKeyInputs) != 0 {
+		t.Errorf("ensureKMSKey() issued %d CreateKey calls, want 0", len(kmsClient.createKeyInputs))
+	}
+}
+
+// kmsEncryption returns the encryption configuration of a bucket encrypted with
+// the KMS key.
+func kmsEncryption(keyID string) *awss3.ServerSideEncryptionConfiguration {
+	return &awss3.ServerSideEncryptionConfiguration{
+		Rules: []*awss3.ServerSideEncryptionRule{{
+			ApplyServerSideEncryptionByDefault: &awss3.ServerSideEncryptionByDefault{
+				SSEAlgorithm:   aws.String(awss3.ServerSideEncryptionAwsKms),
+				KMSMasterKeyID: aws.String(keyID),
+			},
+		}},
+	}
+}
+
+func TestProvisionS3KMSKeyAlias(t *testing.T) {
+	const (
+		rotatedKey  = "arn:aws:kms:us-east-1:123456789012:key/rotated"
+		previousKey = "arn:aws:kms:us-east-1:123456789012:key/previous"
+	)
+	tests := []struct {
+		name      string
+		bucketKey string
+		wantPut   bool
+	}{
+		{
+			name:      "bucket encrypted with the key behind the alias",
+			bucketKey: rotatedKey,
+			wantPut:   false,
+		},
+		{
+			name:      "alias moved to another key",
+			bucketKey: previousKey,
+			wantPut:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{
+						Type:     veleroCR.EncryptionTypeKMS,
+						KMSKeyID: "alias/velero",
+					},
+				},
+			})
+			r := newTestReconciler(t, instance)
+			kmsClient := &mockKMSClient{aliases: map[string]string{"alias/velero": rotatedKey}}
+			r.newKMSClient = func(*aws.Config) (kms.Client, error) { return kmsClient, nil }
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.encryption = kmsEncryption(tt.bucketKey)
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketEncryption"
+			}
+			if put != tt.wantPut {
+				t.Errorf("provisionS3() put the bucket encryption = %v, want %v", put, tt.wantPut)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.KMSKeyRotated)
+			if condition == nil || condition.Status != corev1.ConditionFalse {
+				t.Errorf("KMSKeyRotated condition = %+v, want status False", condition)
+			}
+		})
+	}
+}
+
+func TestProvisionS3KMSKeyAliasUnresolved(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Encryption: veleroCR.EncryptionSpec{
+				Type:     veleroCR.EncryptionTypeKMS,
+				KMSKeyID: "alias/missing",
+			},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+	s3Client := newMockS3Client(testBucketName)
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err == nil {
+		t.Fatalf("provisionS3() error = nil, want the alias resolution error")
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.EncryptionConfigured)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != "KeyResolutionFailed" {
+		t.Errorf("EncryptionConfigured condition = %+v, want status False with reason KeyResolutionFailed", condition)
+	}
+}
+
+func TestProvisionS3KMSKeyRotated(t *testing.T) {
+	const (
+		configuredKey = "arn:aws:kms:us-east-1:123456789012:key/configured"
+		rotatedKey    = "arn:aws:kms:us-east-1:123456789012:key/rotated"
+	)
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Encryption: veleroCR.EncryptionSpec{
+				Type:     veleroCR.EncryptionTypeKMS,
+				KMSKeyID: configuredKey,
+			},
+		},
+	})
+	instance.Status.S3Bucket.AppliedKMSKeyID = configuredKey
+	r := newTestReconciler(t, instance)
+	r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.encryption = kmsEncryption(rotatedKey)
+
+	// The key rotated since it was applied is reported on every reconcile, and never replaced
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+	}
+	for _, mutation := range s3Client.mutations {
+		if mutation == "PutBucketEncryption" {
+			t.Errorf("provisionS3() put the bucket encryption, want the rotated key left in place")
+		}
+	}
+	if got := aws.StringValue(s3Client.encryption.Rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID); got != rotatedKey {
+		t.Errorf("bucket KMS key = %v, want %v", got, rotatedKey)
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.KMSKeyRotated)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "KeyRotated" {
+		t.Errorf("KMSKeyRotated condition = %+v, want status True with reason KeyRotated", condition)
+	}
+}
+
+func TestProvisionS3KMSKeyChanged(t *testing.T) {
+	const (
+		keyA = "arn:aws:kms:us-east-1:123456789012:key/a"
+		keyB = "arn:aws:kms:us-east-1:123456789012:key/b"
+	)
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Encryption: veleroCR.EncryptionSpec{
+				Type:     veleroCR.EncryptionTypeKMS,
+				KMSKeyID: keyA,
+			},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+	s3Client := newMockS3Client(testBucketName)
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if got := getTestInstance(t, r).Status.S3Bucket.AppliedKMSKeyID; got != keyA {
+		t.Fatalf("S3Bucket.AppliedKMSKeyID = %v, want %v", got, keyA)
+	}
+
+	// Changing the configured key encrypts the bucket with the new key
+	changed := getTestInstance(t, r)
+	changed.Spec.BackupStorageLocation.Encryption.KMSKeyID = keyB
+	s3Client.mutations = nil
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, changed, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	put := false
+	for _, mutation := range s3Client.mutations {
+		put = put || mutation == "PutBucketEncryption"
+	}
+	if got := aws.StringValue(s3Client.encryption.Rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID); !put || got != keyB {
+		t.Errorf("bucket KMS key = %v after PutBucketEncryption = %v, want %v put", got, put, keyB)
+	}
+	stored := getTestInstance(t, r)
+	if condition := stored.Status.GetCondition(veleroCR.KMSKeyRotated); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("KMSKeyRotated condition = %+v, want status False", condition)
+	}
+	if stored.Status.S3Bucket.AppliedKMSKeyID != keyB {
+		t.Errorf("S3Bucket.AppliedKMSKeyID = %v, want %v", stored.Status.S3Bucket.AppliedKMSKeyID, keyB)
+	}
+}
+
+func TestProvisionS3KMSKeyUsable(t *testing.T) {
+	const keyARN = "arn:aws:kms:us-east-1:123456789012:key/configured"
+	tests := []struct {
+		name        string
+		generateErr error
+		wantErr     bool
+		wantStatus  corev1.ConditionStatus
+	}{
+		{
+			name:       "key permitted",
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:        "key access denied",
+			generateErr: awserr.NewRequestFailure(awserr.New("AccessDeniedException", "User is not authorized to perform: kms:GenerateDataKey", nil), 400, ""),
+			wantErr:     true,
+			wantStatus:  corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{
+						Type:     veleroCR.EncryptionTypeKMS,
+						KMSKeyID: keyARN,
+					},
+				},
+			})
+			r := newTestReconciler(t, instance)
+			r.newKMSClient = func(*aws.Config) (kms.Client, error) {
+				return &mockKMSClient{generateDataKeyErr: tt.generateErr}, nil
+			}
+			s3Client := newMockS3Client(testBucketName)
+
+			_, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("provisionS3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.KMSKeyUsable)
+			if condition == nil || condition.Status != tt.wantStatus {
+				t.Errorf("KMSKeyUsable condition = %+v, want status %v", condition, tt.wantStatus)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketEncryption"
+			}
+			if put == tt.wantErr {
+				t.Errorf("provisionS3() put the bucket encryption = %v with an unusable key %v", put, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentialsRequestKMSKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		kmsKeyARN string
+		wantKMS   bool
+	}{
+		{
+			name:    "no KMS key",
+			wantKMS: false,
+		},
+		{
+			name:      "KMS key",
+			kmsKeyARN: "arn:aws:kms:us-east-1:123456789012:key/existing",
+			wantKMS:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kmsKeyARNs []string
+			if tt.kmsKeyARN != "" {
+				kmsKeyARNs = append(kmsKeyARNs, tt.kmsKeyARN)
+			}
+			cr := credentialsRequest(testNamespace, credentialsRequestName, "aws", []string{testBucketName}, nil, kmsKeyARNs)
+			codec, _ := minterv1.NewCodec()
+			spec := &minterv1.AWSProviderSpec{}
+			if err := codec.DecodeProviderSpec(cr.Spec.ProviderSpec, spec); err != nil {
+				t.Fatalf("unable to decode provider spec: %v", err)
+			}
+			gotKMS := false
+			for _, statement := range spec.StatementEntries {
+				if statement.Resource == tt.kmsKeyARN {
+					gotKMS = true
+				}
+			}
+			if gotKMS != tt.wantKMS {
+				t.Errorf("credentialsRequest() grants KMS key = %v, want %v", gotKMS, tt.wantKMS)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package velero
+
+import (
+	"fmt"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/go-logr/logr"
+)
+
+// failingConditionStatus is the status of each condition set for a backup
+// storage location which reports that the location failed.
+var failingConditionStatus = map[veleroCR.VeleroConditionType]corev1.ConditionStatus{
+	veleroCR.BucketDrifted:               corev1.ConditionTrue,
+	veleroCR.BucketReady:                 corev1.ConditionFalse,
+	veleroCR.BucketUnavailable:           corev1.ConditionTrue,
+	veleroCR.BucketWritable:              corev1.ConditionFalse,
+	veleroCR.EncryptionConfigured:        corev1.ConditionFalse,
+	veleroCR.InvalidBucketName:           corev1.ConditionTrue,
+	veleroCR.KMSKeyRotated:               corev1.ConditionTrue,
+	veleroCR.KMSKeyUsable:                corev1.ConditionFalse,
+	veleroCR.LifecycleObjectLockConflict: corev1.ConditionTrue,
+	veleroCR.LifecycleRetentionClamped:   corev1.ConditionTrue,
+	veleroCR.LifecycleRetentionRejected:  corev1.ConditionTrue,
+	veleroCR.PublicAccessBlocked:         corev1.ConditionFalse,
+	veleroCR.ReplicationConfigured:       corev1.ConditionFalse,
+	veleroCR.TagPolicyViolation:          corev1.ConditionTrue,
+}
+
+// storageLocation is a backup storage location of a Velero instance, along
+// with the status of its bucket.
+type storageLocation struct {
+	// name is the name of the Velero BackupStorageLocation, which the bucket
+	// is tagged with.
+	name string
+	spec veleroCR.BackupStorageLocationSpec
+	// bucket points into the status of the Velero instance.
+	bucket *veleroCR.S3Bucket
+	// failedConditions holds the name of the location each condition failed
+	// for, and is shared by the locations of a reconcile, unless nil.
+	failedConditions map[veleroCR.VeleroConditionType]string
+}
+
+// setCondition sets the condition of the Velero instance for the backup
+// storage location, and reports whether anything changed. A condition which
+// failed for another location of the reconcile is left as is, so that the
+// conditions of the locations provisioned later don't hide the failure.
+func (l storageLocation) setCondition(
+	instance *veleroCR.Velero, conditionType veleroCR.VeleroConditionType, status corev1.ConditionStatus, reason, message string) bool {
+	if l.failedConditions != nil {
+		if failedFor, ok := l.failedConditions[conditionType]; ok && failedFor != l.name {
+			return false
+		}
+		if failing, ok := failingConditionStatus[conditionType]; ok && status == failing {
+			l.failedConditions[conditionType] = l.name
+		}
+	}
+	return instance.Status.SetCondition(conditionType, status, reason, message)
+}
+
+// defaultLocation returns the default backup storage location of the Velero instance.
+func defaultLocation(instance *veleroCR.Velero) storageLocation {
+	return storageLocation{
+		name:   defaultBackupStorageLocation,
+		spec:   instance.Spec.DefaultStorageLocation(),
+		bucket: &instance.Status.S3Bucket,
+	}
+}
+
+// storageLocations returns every backup storage location of the Velero
+// instance, the default location first. The status is brought in line with
+// the spec: the buckets of new locations are added, and those of removed
+// locations are dropped. The locations keep track of the conditions failing
+// for any of them together.
+func storageLocations(instance *veleroCR.Velero) []storageLocation {
+	specs := instance.Spec.StorageLocations()
+	var buckets []veleroCR.LocationS3Bucket
+	for index := 1; index < len(specs); index++ {
+		name := veleroCR.StorageLocationName(index, specs[index])
+		bucket := veleroCR.LocationS3Bucket{Location: name}
+		if existing := instance.Status.LocationS3Bucket(name); existing != nil {
+			bucket.S3Bucket = *existing
+		}
+		buckets = append(buckets, bucket)
+	}
+	instance.Status.AdditionalS3Buckets = buckets
+
+	failedConditions := make(map[veleroCR.VeleroConditionType]string)
+	first := defaultLocation(instance)
+	first.failedConditions = failedConditions
+	locations := []storageLocation{first}
+	for i := range instance.Status.AdditionalS3Buckets {
+		locations = append(locations, storageLocation{
+			name:             instance.Status.AdditionalS3Buckets[i].Location,
+			spec:             specs[i+1],
+			bucket:           &instance.Status.AdditionalS3Buckets[i].S3Bucket,
+			failedConditions: failedConditions,
+		})
+	}
+	return locations
+}
+
+// locationRegion returns the region the bucket of the backup storage location
+// is kept in: the region it was found to live in, or else the region of the
+// location, or else the region of the default location's bucket.
+func locationRegion(location storageLocation, defaultRegion string) string {
+	if location.bucket.Region != "" {
+		return location.bucket.Region
+	}
+	if location.spec.Region != "" {
+		return location.spec.Region
+	}
+	return defaultRegion
+}
+
+// locationS3Client returns a client for the region of the bucket of the
+// backup storage location, based on the client of the default location.
+func (r *ReconcileVelero) locationS3Client(reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) (s3.Client, error) {
+	defaultRegion := *s3Client.GetAWSClientConfig().Region
+	region := locationRegion(location, defaultRegion)
+	if region == defaultRegion {
+		return s3Client, nil
+	}
+	if err := r.checkRegion(reqLogger, instance, region); err != nil {
+		return nil, err
+	}
+	regionalClient, err := s3Client.ForRegion(region)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create S3 client for region %v: %v", region, err)
+	}
+	return regionalClient, nil
+}
@@ -0,0 +1,167 @@
+package velero
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// regionalMockS3Client is a mockS3Client holding the bucket of its own region,
+// alongside the mock clients of the other regions.
+type regionalMockS3Client struct {
+	*mockS3Client
+
+	// regions holds the client of every other region, by region.
+	regions map[string]*mockS3Client
+}
+
+// ForRegion implements the ForRegion method for regionalMockS3Client.
+func (c *regionalMockS3Client) ForRegion(region string) (s3.Client, error) {
+	if regionalClient, ok := c.regions[region]; ok {
+		return regionalClient, nil
+	}
+	return c.mockS3Client, nil
+}
+
+func TestProvisionS3MultipleLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary", Region: "eu-west-1"},
+		},
+	})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+
+	// The bucket of the default location already exists
+	defaultClient := newMockS3Client(testBucketName)
+	defaultClient.tags = []*awss3.Tag{
+		{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+		{Key: aws.String("velero.io/infrastructureName"), Value: aws.String(testInfraName)},
+	}
+	// The bucket in the region of the secondary location belongs to the
+	// default location, so the secondary location needs a bucket of its own
+	secondaryClient := newMockS3Client("managed-velero-backups-other")
+	secondaryClient.tags = defaultClient.tags
+	s3Client := &regionalMockS3Client{
+		mockS3Client: defaultClient,
+		regions:      map[string]*mockS3Client{"eu-west-1": secondaryClient},
+	}
+
+	// Adopting the default bucket, and proposing and creating the secondary
+	// bucket take a pass each
+	for i := 0; i < 3; i++ {
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+	}
+
+	status := getTestInstance(t, r).Status
+	if status.S3Bucket.Name != testBucketName || status.S3Bucket.Created || !status.S3Bucket.Provisioned {
+		t.Errorf("S3Bucket = %+v, want %v adopted", status.S3Bucket, testBucketName)
+	}
+	for _, mutation := range defaultClient.mutations {
+		if mutation == "CreateBucket" {
+			t.Errorf("provisionS3() created a bucket for the default location, calls = %v", defaultClient.mutations)
+		}
+	}
+
+	secondary := status.LocationS3Bucket("secondary")
+	if secondary == nil {
+		t.Fatalf("AdditionalS3Buckets = %+v, want the bucket of the secondary location", status.AdditionalS3Buckets)
+	}
+	if !strings.HasPrefix(secondary.Name, bucketPrefix) || !secondary.Created || !secondary.Provisioned {
+		t.Errorf("secondary S3Bucket = %+v, want a created bucket", *secondary)
+	}
+	if secondaryClient.bucketName != secondary.Name {
+		t.Errorf("bucket in the secondary region = %v, want %v", secondaryClient.bucketName, secondary.Name)
+	}
+	if value, ok := secondaryClient.tagValue("velero.io/backup-location"); !ok || value != "secondary" {
+		t.Errorf("bucket tag velero.io/backup-location = %q, want %q", value, "secondary")
+	}
+
+	if getTestInstance(t, r).S3BucketReconcileRequired(s3ReconcilePeriod) {
+		t.Errorf("S3BucketReconcileRequired() = true after syncing both buckets")
+	}
+}
+
+func TestProvisionS3LocationConditions(t *testing.T) {
+	const (
+		configuredKey = "arn:aws:kms:us-east-1:123456789012:key/configured"
+		rotatedKey    = "arn:aws:kms:us-east-1:123456789012:key/rotated"
+		secondaryKey  = "arn:aws:kms:eu-west-1:123456789012:key/secondary"
+	)
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: configuredKey}},
+			{Name: "secondary", Region: "eu-west-1", Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: secondaryKey}},
+		},
+	})
+	instance.Status.S3Bucket.AppliedKMSKeyID = configuredKey
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket", Provisioned: true, AppliedKMSKeyID: secondaryKey}},
+	}
+	r := newTestReconciler(t, instance)
+	r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+
+	// Only the key of the default location rotated
+	defaultClient := newMockS3Client(testBucketName)
+	defaultClient.encryption = kmsEncryption(rotatedKey)
+	secondaryClient := newMockS3Client("secondary-bucket")
+	secondaryClient.encryption = kmsEncryption(secondaryKey)
+	s3Client := &regionalMockS3Client{
+		mockS3Client: defaultClient,
+		regions:      map[string]*mockS3Client{"eu-west-1": secondaryClient},
+	}
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	status := getTestInstance(t, r).Status
+	if secondary := status.LocationS3Bucket("secondary"); secondary == nil || secondary.LastSyncTimestamp == nil {
+		t.Fatalf("AdditionalS3Buckets = %+v, want the secondary bucket synced", status.AdditionalS3Buckets)
+	}
+	condition := status.GetCondition(veleroCR.KMSKeyRotated)
+	if condition == nil || condition.Status != corev1.ConditionTrue || !strings.Contains(condition.Message, rotatedKey) {
+		t.Errorf("KMSKeyRotated condition = %+v, want status True for the default location", condition)
+	}
+}
+
+func TestStorageLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary"},
+		},
+	})
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "removed", S3Bucket: veleroCR.S3Bucket{Name: "removed-bucket"}},
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket"}},
+	}
+
+	locations := storageLocations(instance)
+	if len(locations) != 2 || locations[0].name != defaultBackupStorageLocation || locations[1].name != "secondary" {
+		t.Fatalf("storageLocations() = %+v, want the default and secondary locations", locations)
+	}
+	if locations[1].bucket.Name != "secondary-bucket" {
+		t.Errorf("secondary bucket = %q, want %q", locations[1].bucket.Name, "secondary-bucket")
+	}
+	if len(instance.Status.AdditionalS3Buckets) != 1 {
+		t.Errorf("AdditionalS3Buckets = %+v, want the bucket of the removed location dropped", instance.Status.AdditionalS3Buckets)
+	}
+
+	// The locations point into the status
+	locations[1].bucket.Provisioned = true
+	if !instance.Status.LocationS3Bucket("secondary").Provisioned {
+		t.Errorf("status of the secondary bucket wasn't updated through its location")
+	}
+}
@@ -0,0 +1,173 @@
+package velero
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	veleroMetricsName     = "velero-metrics"
+	veleroMetricsPortName = "metrics"
+	veleroMetricsPort     = 8085
+	veleroMetricsPath     = "/metrics"
+)
+
+// reconcileMonitoring keeps the Service exposing the Velero server metrics,
+// and the ServiceMonitor scraping it, in place while monitoring is enabled,
+// and removes them otherwise.
+func (r *ReconcileVelero) reconcileMonitoring(reqLogger logr.Logger, namespace string, instance *veleroCR.Velero) (reconcile.Result, error) {
+	var err error
+	enabled := instance.Spec.Velero.Monitoring.Enabled
+
+	// Install metrics Service
+	foundService := &corev1.Service{}
+	service := veleroMetricsService(namespace)
+	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: veleroMetricsName}, foundService); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		if enabled {
+			// Didn't find Service
+			reqLogger.Info("Creating metrics Service")
+			if err := controllerutil.SetControllerReference(instance, service, r.scheme); err != nil {
+				return reconcile.Result{}, err
+			}
+			if err = r.client.Create(context.TODO(), service); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	} else if !enabled {
+		reqLogger.Info("Deleting metrics Service")
+		if err = r.client.Delete(context.TODO(), foundService); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+	} else if !reflect.DeepEqual(foundService.Spec.Ports, service.Spec.Ports) || !reflect.DeepEqual(foundService.Spec.Selector, service.Spec.Selector) {
+		// The cluster IP is allocated by the API server, so only the ports
+		// and selector are reconciled.
+		reqLogger.Info("Updating metrics Service")
+		foundService.Spec.Ports = service.Spec.Ports
+		foundService.Spec.Selector = service.Spec.Selector
+		if err = r.client.Update(context.TODO(), foundService); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Install ServiceMonitor
+	foundServiceMonitor := &monitoringv1.ServiceMonitor{}
+	serviceMonitor := veleroServiceMonitor(namespace)
+	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: veleroMetricsName}, foundServiceMonitor); err != nil {
+		if meta.IsNoMatchError(err) {
+			if !enabled {
+				// Without Prometheus Operator there is nothing to remove
+				return reconcile.Result{}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("unable to create ServiceMonitor, Prometheus Operator may not be installed: %v", err)
+		}
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		if enabled {
+			// Didn't find ServiceMonitor
+			reqLogger.Info("Creating ServiceMonitor")
+			if err := controllerutil.SetControllerReference(instance, serviceMonitor, r.scheme); err != nil {
+				return reconcile.Result{}, err
+			}
+			if err = r.client.Create(context.TODO(), serviceMonitor); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	} else if !enabled {
+		reqLogger.Info("Deleting ServiceMonitor")
+		if err = r.client.Delete(context.TODO(), foundServiceMonitor); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+	} else if !reflect.DeepEqual(foundServiceMonitor.Spec, serviceMonitor.Spec) {
+		// Specs aren't equal, update and fix.
+		reqLogger.Info("Updating ServiceMonitor", "foundServiceMonitor.Spec", foundServiceMonitor.Spec, "serviceMonitor.Spec", serviceMonitor.Spec)
+		foundServiceMonitor.Spec = *serviceMonitor.Spec.DeepCopy()
+		if err = r.client.Update(context.TODO(), foundServiceMonitor); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// veleroMetricsLabels identify the Service exposing the Velero server metrics.
+func veleroMetricsLabels() map[string]string {
+	return map[string]string{
+		"component": "velero",
+		"name":      veleroMetricsName,
+	}
+}
+
+func veleroMetricsService(namespace string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      veleroMetricsName,
+			Namespace: namespace,
+			Labels:    veleroMetricsLabels(),
+		},
+		Spec: corev1.ServiceSpec{
+			// Matches the pods of the velero Deployment
+			Selector: map[string]string{
+				"deploy":    "velero",
+				"component": "velero",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       veleroMetricsPortName,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       veleroMetricsPort,
+					TargetPort: intstr.FromString(veleroMetricsPortName),
+				},
+			},
+		},
+	}
+}
+
+func veleroServiceMonitor(namespace string) *monitoringv1.ServiceMonitor {
+	return &monitoringv1.ServiceMonitor{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       monitoringv1.ServiceMonitorsKind,
+			APIVersion: monitoringv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      veleroMetricsName,
+			Namespace: namespace,
+			Labels:    veleroMetricsLabels(),
+		},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Endpoints: []monitoringv1.Endpoint{
+				{
+					Port: veleroMetricsPortName,
+					Path: veleroMetricsPath,
+				},
+			},
+			Selector: metav1.LabelSelector{
+				MatchLabels: veleroMetricsLabels(),
+			},
+			NamespaceSelector: monitoringv1.NamespaceSelector{
+				MatchNames: []string{namespace},
+			},
+		},
+	}
+}
@@ -0,0 +1,186 @@
+package velero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// onOutpost checks whether the bucket of the backup storage location is kept
+// on an S3 on Outposts resource, which the S3 API doesn't address by name.
+func onOutpost(spec veleroCR.BackupStorageLocationSpec) bool {
+	return spec.OutpostID != ""
+}
+
+// outpostsClient returns the S3 on Outposts client for the AWS config.
+func (r *ReconcileVelero) outpostsClient(config *aws.Config) (s3.OutpostsClient, error) {
+	if r.newOutpostsClient == nil {
+		return s3.NewOutpostsClient(config)
+	}
+	return r.newOutpostsClient(config)
+}
+
+// outpostResourceARN returns the ARN matching the buckets, access points and
+// objects on the Outpost, in any region and account.
+func outpostResourceARN(partitionID string, outpostID string) string {
+	return fmt.Sprintf("arn:%s:s3-outposts:*:*:outpost/%s/*", partitionID, outpostID)
+}
+
+// provisionOutpostBucket provisions the bucket of the backup storage location
+// on its Outpost through the S3 Control API, which addresses the bucket by
+// its Outpost ARN. Velero reaches the objects through the access point of the
+// bucket, which is recorded in the status. Only the tags and the lifecycle
+// rules are configured, as S3 on Outposts encrypts every object, blocks
+// public access, and has no other bucket configuration.
+func (r *ReconcileVelero) provisionOutpostBucket(
+	ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation, infraName string) (reconcile.Result, error) {
+	config := s3Client.GetAWSClientConfig()
+	outpostID := location.spec.OutpostID
+	outpostsClient, err := r.outpostsClient(config)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	kmsClient, err := r.kmsClient(config)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	account, err := kms.CallerAccount(kmsClient)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Bucket names are only unique within the Outpost, which isn't listed, so
+	// the name is proposed without searching for an existing bucket
+	if location.bucket.Name == "" {
+		proposedName, err := r.proposedBucketName(location, infraName)
+		if errors.Is(err, s3.ErrInvalidBucketName) {
+			reason := "InvalidBucketNamePrefix"
+			if location.spec.BucketNamePrefix == "" {
+				reason = "InvalidBucketNameSuffixLength"
+			}
+			reqLogger.Error(err, "Invalid generated bucket name, not retrying")
+			location.setCondition(instance, veleroCR.InvalidBucketName, corev1.ConditionTrue, reason, err.Error())
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("Setting proposed bucket name", "Location", location.name, "S3Bucket.Name", proposedName, "OutpostID", outpostID)
+		location.bucket.Name = proposedName
+		location.bucket.Provisioned = false
+		location.bucket.OutpostAccessPointARN = ""
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
+	bucketLog := reqLogger.WithValues("Location", location.name, "S3Bucket.Name", location.bucket.Name,
+		"S3Bucket.Region", *config.Region, "OutpostID", outpostID)
+
+	if !location.bucket.Provisioned {
+		bucketLog.Info("Creating S3 Bucket on the Outpost")
+		err = s3.CreateOutpostBucket(ctx, outpostsClient, outpostID, location.bucket.Name)
+		if errors.Is(err, s3.ErrBucketNameTaken) {
+			bucketLog.Info("Bucket exists on the Outpost, but is not owned by current user; retrying")
+			location.bucket.Name = ""
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+		if err != nil {
+			r.recordBucketFailure(instance, eventCreateBucketFailed, location.bucket.Name, err)
+			return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.BucketReady, "CreateFailed",
+				fmt.Errorf("error occurred when creating bucket %v on outpost %v: %v", location.bucket.Name, outpostID, err.Error()))
+		}
+		if !location.bucket.Created {
+			r.recordEvent(instance, corev1.EventTypeNormal, eventBucketCreated, "Created bucket %v on outpost %v", location.bucket.Name, outpostID)
+		}
+		location.bucket.Created = true
+		location.bucket.OwnerAccount = account
+	}
+
+	// Velero can only reach the objects through an access point
+	bucketLog.Info("Enforcing S3 Bucket access point")
+	accessPointARN, err := s3.EnsureOutpostAccessPoint(ctx, outpostsClient, account, outpostID, location.bucket.Name, location.spec.OutpostVPCID)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error occurred when creating the access point of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	location.bucket.OutpostAccessPointARN = accessPointARN
+
+	// Configure lifecycle rules on S3 bucket, unless they are left to the user
+	location.bucket.ExpirationDays = 0
+	location.bucket.NoncurrentExpirationDays = 0
+	location.bucket.Transitions = nil
+	location.bucket.LifecyclePrefix = ""
+	if lifecycleManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket lifecycle rules on S3 Bucket")
+		location.bucket.ExpirationDays = requestedLifecycleDays(location)
+		location.bucket.NoncurrentExpirationDays = location.spec.Lifecycle.NoncurrentVersionExpirationDays
+		location.bucket.LifecyclePrefix = location.spec.Lifecycle.Prefix
+		expirationDays, noncurrentDays, err := r.checkLifecycleRetention(reqLogger, instance, location)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		err = s3.SetOutpostBucketLifecycle(ctx, outpostsClient, account, outpostID, location.bucket.Name, backupExpiryRule(location, expirationDays, noncurrentDays))
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("error occurred when configuring lifecycle rules on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	} else {
+		bucketLog.Info("Leaving S3 Bucket lifecycle rules to the user")
+	}
+
+	// Make sure that tags are applied to buckets, unless they are left to the user
+	if tagsManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket tags on S3 Bucket")
+		if err = r.checkTagPolicy(reqLogger, instance, location, infraName); err != nil {
+			return reconcile.Result{}, err
+		}
+		err = s3.TagOutpostBucket(ctx, outpostsClient, account, outpostID, location.bucket.Name,
+			r.options.tagKeyPrefix, location.name, infraName, bucketTags(instance, location, r.options.tagKeyPrefix))
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("error occurred when tagging bucket %v: %v", location.bucket.Name, err.Error())
+		}
+		recordInfrastructureName(bucketLog, location, infraName)
+	} else {
+		bucketLog.Info("Leaving S3 Bucket tags to the user")
+	}
+
+	location.bucket.Provisioned = true
+	location.setCondition(instance, veleroCR.BucketReady, corev1.ConditionTrue, "BucketSynced", "")
+	location.bucket.LastSyncTimestamp = &metav1.Time{
+		Time: time.Now(),
+	}
+	return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+}
+
+// outpostIAMStatements returns the statements of the IAM policy granting the
+// operator the bucket of the backup storage location at the index, on its
+// Outpost. The bucket is only read while frozen.
+func outpostIAMStatements(partitionID string, instance *veleroCR.Velero, location storageLocation, index int) []iamPolicyStatement {
+	actions := []string{"s3-outposts:GetAccessPoint"}
+	if !bucketFrozen(instance) {
+		actions = append(actions,
+			"s3-outposts:CreateAccessPoint",
+			"s3-outposts:CreateBucket",
+		)
+		if tagsManaged(location.spec) {
+			actions = append(actions, "s3-outposts:PutBucketTagging")
+		}
+		if lifecycleManaged(location.spec) {
+			actions = append(actions, "s3-outposts:PutLifecycleConfiguration")
+		}
+	}
+	return []iamPolicyStatement{{
+		Sid:      locationSid("ManageOutpostBucket", index),
+		Effect:   "Allow",
+		Action:   actions,
+		Resource: outpostResourceARN(partitionID, location.spec.OutpostID),
+	}}
+}
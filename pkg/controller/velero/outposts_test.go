@@ -0,0 +1,175 @@
+package velero
+
+import (
+	"context"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3control"
+	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
+)
+
+const (
+	testOutpostID    = "op-01ac5d28a6a232904"
+	testOutpostVPCID = "vpc-0123456789abcdef0"
+	testAccountID    = "123456789012"
+)
+
+// mockOutpostsClient implements the s3.OutpostsClient interface, and records
+// the requests made to the Outpost.
+type mockOutpostsClient struct {
+	createBucketInputs                    []*s3control.CreateBucketInput
+	createAccessPointInputs               []*s3control.CreateAccessPointInput
+	putBucketTaggingInputs                []*s3control.PutBucketTaggingInput
+	putBucketLifecycleConfigurationInputs []*s3control.PutBucketLifecycleConfigurationInput
+	// accessPoints holds the ARNs of the created access points.
+	accessPoints map[string]bool
+}
+
+// CreateAccessPoint implements the CreateAccessPoint method for mockOutpostsClient.
+func (c *mockOutpostsClient) CreateAccessPoint(ctx context.Context, input *s3control.CreateAccessPointInput) (*s3control.CreateAccessPointOutput, error) {
+	c.createAccessPointInputs = append(c.createAccessPointInputs, input)
+	if c.accessPoints == nil {
+		c.accessPoints = make(map[string]bool)
+	}
+	c.accessPoints[s3.OutpostAccessPointARN(testRegion, aws.StringValue(input.AccountId), testOutpostID, aws.StringValue(input.Name))] = true
+	return &s3control.CreateAccessPointOutput{}, nil
+}
+
+// CreateBucket implements the CreateBucket method for mockOutpostsClient.
+func (c *mockOutpostsClient) CreateBucket(ctx context.Context, input *s3control.CreateBucketInput) (*s3control.CreateBucketOutput, error) {
+	c.createBucketInputs = append(c.createBucketInputs, input)
+	return &s3control.CreateBucketOutput{}, nil
+}
+
+// GetAccessPoint implements the GetAccessPoint method for mockOutpostsClient.
+func (c *mockOutpostsClient) GetAccessPoint(ctx context.Context, input *s3control.GetAccessPointInput) (*s3control.GetAccessPointOutput, error) {
+	if !c.accessPoints[aws.StringValue(input.Name)] {
+		return nil, awserr.New("NoSuchAccessPoint", "The specified accesspoint does not exist", nil)
+	}
+	return &s3control.GetAccessPointOutput{}, nil
+}
+
+// GetAWSClientConfig implements the GetAWSClientConfig method for mockOutpostsClient.
+func (c *mockOutpostsClient) GetAWSClientConfig() *aws.Config {
+	return &aws.Config{Region: aws.String(testRegion)}
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for mockOutpostsClient.
+func (c *mockOutpostsClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3control.PutBucketLifecycleConfigurationInput) (*s3control.PutBucketLifecycleConfigurationOutput, error) {
+	c.putBucketLifecycleConfigurationInputs = append(c.putBucketLifecycleConfigurationInputs, input)
+	return &s3control.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+// PutBucketTagging implements the PutBucketTagging method for mockOutpostsClient.
+func (c *mockOutpostsClient) PutBucketTagging(ctx context.Context, input *s3control.PutBucketTaggingInput) (*s3control.PutBucketTaggingOutput, error) {
+	c.putBucketTaggingInputs = append(c.putBucketTaggingInputs, input)
+	return &s3control.PutBucketTaggingOutput{}, nil
+}
+
+func newOutpostTestInstance() *veleroCR.Velero {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			OutpostID:     testOutpostID,
+			OutpostVPCID:  testOutpostVPCID,
+			LifecycleDays: 30,
+		},
+	})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	return instance
+}
+
+func TestProvisionS3OnOutpost(t *testing.T) {
+	r := newTestReconciler(t, newOutpostTestInstance())
+	r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+	outpostsClient := &mockOutpostsClient{}
+	r.newOutpostsClient = func(*aws.Config) (s3.OutpostsClient, error) { return outpostsClient, nil }
+	s3Client := newMockS3Client("")
+
+	// The first pass proposes the bucket name, and the others provision it
+	for i := 0; i < 3; i++ {
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+	}
+	stored := getTestInstance(t, r)
+	status := stored.Status.S3Bucket
+	if status.Name == "" || !status.Provisioned || !status.Created {
+		t.Fatalf("S3Bucket = %+v, want a provisioned bucket", status)
+	}
+	if len(s3Client.mutations) > 0 {
+		t.Errorf("S3 API calls = %v, want the bucket on the Outpost left to the S3 Control API", s3Client.mutations)
+	}
+
+	if len(outpostsClient.createBucketInputs) != 1 || aws.StringValue(outpostsClient.createBucketInputs[0].OutpostId) != testOutpostID {
+		t.Errorf("CreateBucket() requests = %v, want the bucket created once on %v", outpostsClient.createBucketInputs, testOutpostID)
+	}
+	bucketARN := s3.OutpostBucketARN(testRegion, testAccountID, testOutpostID, status.Name)
+	if len(outpostsClient.createAccessPointInputs) != 1 {
+		t.Fatalf("CreateAccessPoint() calls = %d, want the access point created once", len(outpostsClient.createAccessPointInputs))
+	}
+	accessPoint := outpostsClient.createAccessPointInputs[0]
+	if aws.StringValue(accessPoint.Bucket) != bucketARN || aws.StringValue(accessPoint.VpcConfiguration.VpcId) != testOutpostVPCID {
+		t.Errorf("CreateAccessPoint() = %v, want the access point of %v in %v", accessPoint, bucketARN, testOutpostVPCID)
+	}
+	wantAccessPointARN := s3.OutpostAccessPointARN(testRegion, testAccountID, testOutpostID, s3.OutpostAccessPointName(status.Name))
+	if status.OutpostAccessPointARN != wantAccessPointARN {
+		t.Errorf("S3Bucket.OutpostAccessPointARN = %v, want %v", status.OutpostAccessPointARN, wantAccessPointARN)
+	}
+
+	tagging := outpostsClient.putBucketTaggingInputs
+	if len(tagging) == 0 || aws.StringValue(tagging[0].Bucket) != bucketARN {
+		t.Errorf("PutBucketTagging() requests = %v, want the tags put on %v", tagging, bucketARN)
+	}
+	lifecycle := outpostsClient.putBucketLifecycleConfigurationInputs
+	if len(lifecycle) == 0 || aws.StringValue(lifecycle[0].Bucket) != bucketARN {
+		t.Fatalf("PutBucketLifecycleConfiguration() requests = %v, want the rules put on %v", lifecycle, bucketARN)
+	}
+	if rules := lifecycle[0].LifecycleConfiguration.Rules; len(rules) != 1 || aws.Int64Value(rules[0].Expiration.Days) != 30 {
+		t.Errorf("PutBucketLifecycleConfiguration() rules = %v, want the backups expiring after 30 days", rules)
+	}
+	if lifecycleChanged(stored) {
+		t.Errorf("lifecycleChanged() = true once the lifecycle rules are configured")
+	}
+
+	bsl := backupStorageLocation(testNamespace, "aws", stored, defaultLocation(stored), testRegion, s3.Endpoint{})
+	if bsl.Spec.ObjectStorage.Bucket != wantAccessPointARN {
+		t.Errorf("BackupStorageLocation bucket = %v, want the access point %v", bsl.Spec.ObjectStorage.Bucket, wantAccessPointARN)
+	}
+}
+
+func TestOutpostGrants(t *testing.T) {
+	outpostARN := "arn:aws:s3-outposts:*:*:outpost/" + testOutpostID + "/*"
+
+	cr := credentialsRequest(testNamespace, credentialsRequestName, "aws", nil, []string{testOutpostID, testOutpostID}, nil)
+	codec, _ := minterv1.NewCodec()
+	spec := &minterv1.AWSProviderSpec{}
+	if err := codec.DecodeProviderSpec(cr.Spec.ProviderSpec, spec); err != nil {
+		t.Fatalf("unable to decode provider spec: %v", err)
+	}
+	granted := 0
+	for _, statement := range spec.StatementEntries {
+		if statement.Resource == outpostARN {
+			granted++
+		}
+	}
+	if granted != 1 {
+		t.Errorf("credentialsRequest() grants the Outpost %d times, want once", granted)
+	}
+
+	actions := policyActions(operatorIAMPolicy("aws", newOutpostTestInstance(), options{}))
+	for _, action := range []string{"s3-outposts:CreateBucket", "s3-outposts:CreateAccessPoint", "s3-outposts:PutBucketTagging", "s3-outposts:PutLifecycleConfiguration"} {
+		if got := actions[action]; len(got) != 1 || got[0] != outpostARN {
+			t.Errorf("%v resources = %v, want %v", action, got, outpostARN)
+		}
+	}
+	if got := actions["s3:CreateBucket"]; len(got) > 0 {
+		t.Errorf("s3:CreateBucket resources = %v, want none for a bucket on an Outpost", got)
+	}
+}
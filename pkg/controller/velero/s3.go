@@ -1,58 +1,200 @@
 package velero
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
 	"github.com/openshift/managed-velero-operator/pkg/s3"
+	"github.com/openshift/managed-velero-operator/pkg/util/platform"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	awss3 "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/google/uuid"
 )
 
 const (
 	bucketPrefix = "managed-velero-backups-"
+
+	// maxBucketNameLength is the longest bucket name S3 accepts
+	maxBucketNameLength = 63
+	// minBucketNameRandomLength is the shortest random suffix a prefixed
+	// bucket name is truncated to, which keeps the names unique
+	minBucketNameRandomLength = 8
+	// bucketNameSuffixAlphabet holds the characters of the random suffixes
+	// of the configured length
+	bucketNameSuffixAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	// reasonPublicAccessBlockNotImplemented is the reason of the
+	// PublicAccessBlocked condition when the S3 backend doesn't implement
+	// the public access block
+	reasonPublicAccessBlockNotImplemented = "PublicAccessBlockNotImplemented"
 )
 
-func (r *ReconcileVelero) provisionS3(reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, infraName string) (reconcile.Result, error) {
+// errBucketMissing is returned when a configuration step finds that the bucket no longer exists.
+var errBucketMissing = errors.New("bucket no longer exists")
+
+// provisionS3 provisions the bucket of every backup storage location, the
+// default location first. It returns as soon as a bucket isn't synced yet, so
+// that the next reconcile carries on with its provisioning.
+func (r *ReconcileVelero) provisionS3(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, infraName string) (reconcile.Result, error) {
+	for _, location := range storageLocations(instance) {
+		locationClient, err := r.locationS3Client(reqLogger, s3Client, instance, location)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		result, err := r.provisionS3Location(ctx, reqLogger, locationClient, instance, location, infraName)
+		if err != nil || result.Requeue || location.bucket.ReconcileRequired(r.s3ReconcilePeriod()) {
+			return result, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// provisionS3Location provisions the bucket of the backup storage location,
+// starting over when the bucket disappears during its configuration.
+func (r *ReconcileVelero) provisionS3Location(
+	ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation, infraName string) (reconcile.Result, error) {
+	if onOutpost(location.spec) {
+		return r.provisionOutpostBucket(ctx, reqLogger, s3Client, instance, location, infraName)
+	}
+	for restarts := 0; ; restarts++ {
+		result, err := r.provisionS3Bucket(ctx, reqLogger, s3Client, instance, location, infraName)
+		if err != errBucketMissing {
+			return result, err
+		}
+		if restarts >= r.options.maxBucketRestarts {
+			return result, fmt.Errorf("bucket %v disappeared during configuration %d times", location.bucket.Name, restarts+1)
+		}
+
+		// The bucket was removed out-of-band, so provision it again
+		reqLogger.Info("S3 bucket disappeared during configuration, restarting provisioning", "S3Bucket.Name", location.bucket.Name)
+		location.bucket.Provisioned = false
+	}
+}
+
+func (r *ReconcileVelero) provisionS3Bucket(
+	ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation, infraName string) (reconcile.Result, error) {
 	var err error
 	config := s3Client.GetAWSClientConfig()
-	bucketLog := reqLogger.WithValues("S3Bucket.Name", instance.Status.S3Bucket.Name, "S3Bucket.Region", *config.Region)
+
+	// Use the bucket named in the spec, rather than one the operator creates
+	userBucket := location.spec.BucketName
+	if userBucket != "" && location.bucket.Name != userBucket {
+		reqLogger.Info("Using the S3 bucket named in the spec", "S3Bucket.Name", userBucket)
+		location.bucket.Name = userBucket
+		location.bucket.Provisioned = false
+		location.bucket.Created = false
+		location.bucket.CreatedAt = nil
+		location.bucket.OwnerAccount = ""
+		location.bucket.MigratedFromInfrastructureName = ""
+	}
+	bucketLog := reqLogger.WithValues("Location", location.name, "S3Bucket.Name", location.bucket.Name, "S3Bucket.Region", *config.Region)
 
 	// This switch handles the provisioning steps/checks
 	switch {
+	// The bucket named in the spec is only verified, and never created
+	case userBucket != "" && !location.bucket.Provisioned:
+		available, err := r.checkUserBucket(ctx, bucketLog, s3Client, instance, location)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !available {
+			// Retrying can't create the bucket, so wait for the spec or the bucket to be corrected
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+
 	// We don't yet have a bucket name selected
-	case instance.Status.S3Bucket.Name == "":
+	case location.bucket.Name == "":
 
 		// Use an existing bucket, if it exists.
 		log.Info("No S3 bucket defined. Searching for existing bucket to use")
-		bucketlist, err := s3.ListBuckets(s3Client)
+		endpoint := r.s3Endpoint(instance)
+		regionalClients, err := r.regionalS3Clients(reqLogger, endpoint, *config.Region)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
-
-		bucketinfo, err := s3.ListBucketTags(s3Client, bucketlist)
+		// The discovery is shared with the reconciles of other instances in
+		// the same region, until it expires or a bucket is created or tagged
+		discovery, err := r.discovery.Discover(ctx, *config.Region+" "+endpoint.URL, s3Client, s3.ScanOptions{
+			RegionalClients: regionalClients,
+			Exclude:         r.options.scanExclude,
+			Concurrency:     r.options.scanConcurrency,
+		})
 		if err != nil {
 			return reconcile.Result{}, err
 		}
+		bucketinfo := discovery.Tags
 
-		existingBucket := s3.FindMatchingTags(bucketinfo, infraName)
-		if existingBucket != "" {
+		// Several buckets tagged for the location are reported rather than
+		// silently choosing one, and the first in sorted order is recovered
+		matchingBuckets := s3.FindAllMatchingTags(bucketinfo, r.options.tagKeyPrefix, location.name, infraName)
+		if len(matchingBuckets) > 1 {
+			log.Info("Found several S3 buckets tagged for the backup storage location", "Buckets", matchingBuckets)
+			r.recordEvent(instance, corev1.EventTypeWarning, eventDuplicateBuckets,
+				"Buckets %v are all tagged for backup storage location %v, recovering %v", strings.Join(matchingBuckets, ", "), location.name, matchingBuckets[0])
+		}
+		// A bucket tagged before the cluster was renamed is recovered too,
+		// and the sync tags it with the current infrastructure name
+		legacyInfraName := ""
+		if len(matchingBuckets) == 0 {
+			var legacyBucket string
+			legacyBucket, legacyInfraName = s3.FindMatchingLegacyTags(bucketinfo, r.options.tagKeyPrefix, location.name, instance.Spec.LegacyInfraNames)
+			if legacyBucket != "" {
+				log.Info("Found S3 bucket tagged with a legacy infrastructure name", "S3Bucket.Name", legacyBucket, "InfrastructureName", legacyInfraName)
+				r.recordEvent(instance, corev1.EventTypeNormal, eventInfraNameMigrated,
+					"Bucket %v is tagged with legacy infrastructure name %v, tagging it with %v", legacyBucket, legacyInfraName, infraName)
+				matchingBuckets = []string{legacyBucket}
+			}
+		}
+		if len(matchingBuckets) > 0 {
+			existingBucket := matchingBuckets[0]
 			log.Info(fmt.Sprintf("Recovered existing bucket: %s", existingBucket))
-			instance.Status.S3Bucket.Name = existingBucket
-			instance.Status.S3Bucket.Provisioned = true
+			location.bucket.Name = existingBucket
+			location.bucket.Region = ""
+			location.bucket.Provisioned = true
+			location.bucket.Created = false
+			location.bucket.CreatedAt = nil
+			location.bucket.OwnerAccount = ""
+			location.bucket.MigratedFromInfrastructureName = legacyInfraName
+			r.recordBucketOrigin(reqLogger, instance, location, config, s3.CreationDate(discovery.Buckets, existingBucket))
 			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
 		}
 
 		// Prepare to create a new bucket, if none exist.
-		proposedName := generateBucketName(bucketPrefix)
-		proposedBucketExists, err := s3.DoesBucketExist(s3Client, proposedName)
+		proposedName, err := r.proposedBucketName(location, infraName)
+		if errors.Is(err, s3.ErrInvalidBucketName) {
+			// Retrying can't fix the prefix or the suffix length, so wait for
+			// the spec or the operator's flags to be corrected
+			reason := "InvalidBucketNamePrefix"
+			if location.spec.BucketNamePrefix == "" {
+				reason = "InvalidBucketNameSuffixLength"
+			}
+			log.Error(err, "Invalid generated bucket name, not retrying")
+			location.setCondition(instance, veleroCR.InvalidBucketName, corev1.ConditionTrue, reason, err.Error())
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		proposedBucketExists, err := s3.DoesBucketExist(ctx, s3Client, proposedName)
+		if errors.Is(err, s3.ErrBucketForbidden) {
+			// Another account owns the name, so creating it would fail; another
+			// name is proposed on retry
+			return reconcile.Result{}, fmt.Errorf("proposed bucket %s is owned by another account, retrying", proposedName)
+		}
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -61,99 +203,1245 @@ func (r *ReconcileVelero) provisionS3(reqLogger logr.Logger, s3Client s3.Client,
 		}
 
 		log.Info("Setting proposed bucket name", "S3Bucket.Name", proposedName)
-		instance.Status.S3Bucket.Name = proposedName
-		instance.Status.S3Bucket.Provisioned = false
+		location.bucket.Name = proposedName
+		location.bucket.Region = ""
+		location.bucket.Provisioned = false
+		location.bucket.CreatedAt = nil
+		location.bucket.OwnerAccount = ""
+		location.bucket.MigratedFromInfrastructureName = ""
 		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
 
 	// We have a bucket name, but haven't kicked off provisioning of the bucket yet
-	case instance.Status.S3Bucket.Name != "" && !instance.Status.S3Bucket.Provisioned:
+	case location.bucket.Name != "" && !location.bucket.Provisioned:
 		bucketLog.Info("S3 bucket defined, but not provisioned")
 
 		// Create S3 bucket
 		bucketLog.Info("Creating S3 Bucket")
-		err = s3.CreateBucket(s3Client, instance.Status.S3Bucket.Name)
+		err = s3.CreateBucket(ctx, s3Client, location.bucket.Name)
+		if errors.Is(err, s3.ErrInvalidBucketName) {
+			// Retrying can't fix the name, so wait for the status to be corrected
+			bucketLog.Error(err, "Invalid bucket name, not retrying")
+			location.setCondition(instance, veleroCR.InvalidBucketName, corev1.ConditionTrue, "InvalidBucketName", err.Error())
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
+		if errors.Is(err, s3.ErrBucketNameTaken) {
+			bucketLog.Info("Bucket exists, but is not owned by current user; retrying")
+			location.bucket.Name = ""
+			return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+		}
 		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case awss3.ErrCodeBucketAlreadyExists:
-					bucketLog.Info("Bucket exists, but is not owned by current user; retrying")
-					instance.Status.S3Bucket.Name = ""
-					return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
-				case awss3.ErrCodeBucketAlreadyOwnedByYou:
-					bucketLog.Info("Bucket exists, and is owned by current user; continue")
-				default:
-					return reconcile.Result{}, fmt.Errorf("error occurred when creating bucket %v: %v", instance.Status.S3Bucket.Name, aerr.Error())
-				}
-			} else {
-				return reconcile.Result{}, fmt.Errorf("error occurred when creating bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
-			}
+			r.recordBucketFailure(instance, eventCreateBucketFailed, location.bucket.Name, err)
+			return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.BucketReady, "CreateFailed",
+				fmt.Errorf("error occurred when creating bucket %v: %v", location.bucket.Name, err.Error()))
+		}
+		// The proposed name is unique, so a bucket owned by us was created by an earlier attempt
+		location.bucket.Created = true
+		r.recordEvent(instance, corev1.EventTypeNormal, eventBucketCreated, "Created bucket %v", location.bucket.Name)
+		createdAt, err := s3.BucketCreationDate(ctx, s3Client, location.bucket.Name)
+		if err != nil {
+			bucketLog.Error(err, "Unable to read the creation date of the S3 bucket")
+		}
+		r.recordBucketOrigin(bucketLog, instance, location, config, createdAt)
+		if instance.Status.GetCondition(veleroCR.InvalidBucketName) != nil {
+			location.setCondition(instance, veleroCR.InvalidBucketName, corev1.ConditionFalse, "ValidBucketName", "")
 		}
-		err = s3.TagBucket(s3Client, instance.Status.S3Bucket.Name, defaultBackupStorageLocation, infraName)
+		// ACLs are disabled before anything else is set on the bucket
+		err = s3.EnsureOwnershipControls(ctx, s3Client, location.bucket.Name)
 		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("error occurred when tagging bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+			return reconcile.Result{}, fmt.Errorf("error occurred when disabling ACLs of bucket %v: %v", location.bucket.Name, err.Error())
+		}
+		// Without tags, the bucket is only found again through its name in the status
+		if tagsManaged(location.spec) {
+			if err = r.checkTagPolicy(reqLogger, instance, location, infraName); err != nil {
+				return reconcile.Result{}, err
+			}
+			err = s3.TagBucket(ctx, s3Client, location.bucket.Name, r.options.tagKeyPrefix, location.name, infraName, bucketTags(instance, location, r.options.tagKeyPrefix))
+			if err != nil {
+				return reconcile.Result{}, fmt.Errorf("error occurred when tagging bucket %v: %v", location.bucket.Name, err.Error())
+			}
+			recordInfrastructureName(bucketLog, location, infraName)
+			r.recordEvent(instance, corev1.EventTypeNormal, eventTaggingApplied, "Tagged bucket %v", location.bucket.Name)
 		}
 	}
 
 	// Verify S3 bucket exists
 	bucketLog.Info("Verifing S3 Bucket exists")
-	exists, err := s3.DoesBucketExist(s3Client, instance.Status.S3Bucket.Name)
+	exists, err := s3.DoesBucketExist(ctx, s3Client, location.bucket.Name)
+	if errors.Is(err, s3.ErrBucketForbidden) {
+		// Creating the bucket again is bound to fail, so wait for the access
+		// to be granted back rather than provisioning it again
+		bucketLog.Error(err, "S3 bucket can't be accessed, not creating it again")
+		r.recordBucketFailure(instance, eventAccessDenied, location.bucket.Name, err)
+		location.setCondition(instance, veleroCR.BucketReady, corev1.ConditionFalse, "BucketForbidden",
+			fmt.Sprintf("Access to bucket %v is denied: it belongs to another account, or its policy denies the credentials", location.bucket.Name))
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
-			return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v: %v", instance.Status.S3Bucket.Name, aerr.Error())
+			return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v: %v", location.bucket.Name, aerr.Error())
 		}
-		return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+		return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v: %v", location.bucket.Name, err.Error())
 	}
 	if !exists {
 		bucketLog.Error(nil, "S3 bucket doesn't appear to exist")
-		instance.Status.S3Bucket.Provisioned = false
+		location.bucket.Provisioned = false
+		location.setCondition(instance, veleroCR.BucketReady, corev1.ConditionFalse, "BucketMissing", "The bucket doesn't exist")
 		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
 	}
 
-	// Encrypt S3 bucket
-	bucketLog.Info("Enforcing S3 Bucket encryption")
-	err = s3.EncryptBucket(s3Client, instance.Status.S3Bucket.Name)
+	// A recovered bucket may live in another region than the location's,
+	// which would redirect every request of the sync
+	s3Client, err = r.bucketRegionClient(ctx, bucketLog, s3Client, instance, location)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			return reconcile.Result{}, fmt.Errorf("error occurred when encrypting bucket %v: %v", instance.Status.S3Bucket.Name, aerr.Error())
+		if s3.IsNoSuchBucket(err) {
+			return reconcile.Result{}, errBucketMissing
 		}
-		return reconcile.Result{}, fmt.Errorf("error occurred when encrypting bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+		return reconcile.Result{}, err
 	}
 
-	// Block public access to S3 bucket
-	bucketLog.Info("Enforcing S3 Bucket public access policy")
-	err = s3.BlockBucketPublicAccess(s3Client, instance.Status.S3Bucket.Name)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			return reconcile.Result{}, fmt.Errorf("error occurred when blocking public access to bucket %v: %v", instance.Status.S3Bucket.Name, aerr.Error())
+	// Encrypt S3 bucket, unless its encryption is left to the user
+	if encryptionManaged(location.spec) {
+		// Create the KMS key to encrypt the S3 bucket with, if requested, or
+		// resolve the alias to the key it currently points to
+		encryption := location.spec.Encryption
+		kmsKey := s3.KMSKey{ID: encryption.KMSKeyID, AppliedID: location.bucket.AppliedKMSKeyID}
+		if encryption.Type == veleroCR.EncryptionTypeKMS && (encryption.CreateKey || encryption.KMSKeyID != "") {
+			kmsClient, err := r.kmsClient(config)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if encryption.CreateKey {
+				kmsKey.ID, err = r.ensureKMSKey(bucketLog, kmsClient, instance, location, infraName)
+				if err != nil {
+					return reconcile.Result{}, fmt.Errorf("error occurred when creating KMS key for bucket %v: %v", location.bucket.Name, err.Error())
+				}
+			} else if kms.IsAlias(kmsKey.ID) {
+				kmsKey.ResolvedARN, err = kms.ResolveKeyARN(kmsClient, kmsKey.ID)
+				if err != nil {
+					err = fmt.Errorf("error occurred when resolving KMS key %v for bucket %v: %v", kmsKey.ID, location.bucket.Name, err.Error())
+					return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.EncryptionConfigured, "KeyResolutionFailed", err)
+				}
+				bucketLog.Info("Resolved KMS key alias", "KMSKey.ID", kmsKey.ID, "KMSKey.ARN", kmsKey.ResolvedARN)
+			}
+
+			// Make sure the key can be used before the backups are encrypted with it
+			keyID := kmsKey.ID
+			if kmsKey.ResolvedARN != "" {
+				keyID = kmsKey.ResolvedARN
+			}
+			bucketLog.Info("Checking KMS key usability", "KMSKey.ID", keyID)
+			if err = r.checkKMSKey(reqLogger, kmsClient, instance, location, keyID); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+
+		// Encrypt S3 bucket
+		bucketLog.Info("Enforcing S3 Bucket encryption")
+		if encryption.Type == veleroCR.EncryptionTypeKMS && kmsKey.ID != "" {
+			err = s3.EnsureBucketKMSEncryption(ctx, s3Client, location.bucket.Name, kmsKey)
+			if errors.Is(err, s3.ErrKMSKeyRotated) {
+				// Encrypting the bucket again on every reconcile would only fight
+				// whoever rotated the key, so this is left to the user
+				bucketLog.Info("S3 Bucket is encrypted with another KMS key, leaving it in place", "KMSKey.ID", kmsKey.ID)
+				if location.setCondition(instance, veleroCR.KMSKeyRotated, corev1.ConditionTrue, "KeyRotated", err.Error()) {
+					r.recordEvent(instance, corev1.EventTypeWarning, eventKMSKeyRotated, "%v", err)
+				}
+				err = nil
+			} else if err == nil {
+				location.bucket.AppliedKMSKeyID = kmsKey.ID
+				location.setCondition(instance, veleroCR.KMSKeyRotated, corev1.ConditionFalse, "KeyMatches", "")
+			}
+		} else {
+			err = s3.EnsureBucketEncryption(ctx, s3Client, location.bucket.Name, string(encryption.Type), kmsKey.ID)
+			location.bucket.AppliedKMSKeyID = ""
+		}
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			if errors.Is(err, s3.ErrEncryptionImmutable) && location.spec.RecreateOnImmutableChange {
+				return r.recreateBucket(ctx, bucketLog, s3Client, instance, location)
+			}
+			if aerr, ok := err.(awserr.Error); ok {
+				err = fmt.Errorf("error occurred when encrypting bucket %v: %v", location.bucket.Name, aerr.Error())
+			} else {
+				err = fmt.Errorf("error occurred when encrypting bucket %v: %v", location.bucket.Name, err.Error())
+			}
+			return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.EncryptionConfigured, "EncryptionFailed", err)
+		}
+		if location.setCondition(instance, veleroCR.EncryptionConfigured, corev1.ConditionTrue, "EncryptionEnforced", "") {
+			r.recordEvent(instance, corev1.EventTypeNormal, eventEncryptionEnabled, "Enabled encryption on bucket %v", location.bucket.Name)
 		}
-		return reconcile.Result{}, fmt.Errorf("error occurred when blocking public access to bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+	} else {
+		bucketLog.Info("Leaving S3 Bucket encryption to the user")
+		location.setCondition(instance, veleroCR.EncryptionConfigured, corev1.ConditionFalse, "EncryptionUnmanaged",
+			"The encryption of the bucket is left to the user")
 	}
 
-	// Configure lifecycle rules on S3 bucket
-	bucketLog.Info("Enforcing S3 Bucket lifecycle rules on S3 Bucket")
-	err = s3.SetBucketLifecycle(s3Client, instance.Status.S3Bucket.Name)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			return reconcile.Result{}, fmt.Errorf("error occurred when configuring lifecycle rules on bucket %v: %v", instance.Status.S3Bucket.Name, aerr.Error())
+	// Block public access to S3 bucket, unless left to the user or not
+	// implemented by an S3 compatible backend
+	if publicAccessBlockManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket public access policy")
+		err = s3.EnsurePublicAccessBlock(ctx, s3Client, location.bucket.Name)
+		switch {
+		case s3.IsNoSuchBucket(err):
+			return reconcile.Result{}, errBucketMissing
+		case s3.IsNotImplemented(err):
+			bucketLog.Info("S3 backend does not implement the public access block, public access to the bucket is not blocked")
+			location.setCondition(instance, veleroCR.PublicAccessBlocked, corev1.ConditionFalse, reasonPublicAccessBlockNotImplemented,
+				fmt.Sprintf("The S3 backend does not implement the public access block, public access to bucket %v is not blocked", location.bucket.Name))
+		case err != nil:
+			if aerr, ok := err.(awserr.Error); ok {
+				err = fmt.Errorf("error occurred when blocking public access to bucket %v: %v", location.bucket.Name, aerr.Error())
+			} else {
+				err = fmt.Errorf("error occurred when blocking public access to bucket %v: %v", location.bucket.Name, err.Error())
+			}
+			return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.PublicAccessBlocked, "PublicAccessBlockFailed", err)
+		default:
+			location.setCondition(instance, veleroCR.PublicAccessBlocked, corev1.ConditionTrue, "PublicAccessBlocked", "")
+		}
+	} else {
+		bucketLog.Info("Leaving S3 Bucket public access block to the user")
+		location.setCondition(instance, veleroCR.PublicAccessBlocked, corev1.ConditionFalse, "PublicAccessBlockUnmanaged",
+			"The public access block of the bucket is left to the user")
+	}
+
+	// Enable versioning on S3 bucket, if requested
+	if location.spec.Versioning {
+		bucketLog.Info("Enforcing S3 Bucket versioning")
+		err = s3.EnsureBucketVersioning(ctx, s3Client, location.bucket.Name)
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when enabling versioning on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	}
+
+	// Enable Transfer Acceleration on S3 bucket, which the client addresses
+	if r.s3Endpoint(instance).Accelerate {
+		bucketLog.Info("Enforcing S3 Bucket transfer acceleration")
+		err = s3.EnableBucketAcceleration(ctx, s3Client, location.bucket.Name)
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when enabling transfer acceleration on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	}
+
+	// Replicate the S3 bucket to the destination bucket, if requested
+	if replication := location.spec.Replication; replication.DestinationBucketARN != "" {
+		bucketLog.Info("Enforcing S3 Bucket replication", "Replication.DestinationBucketARN", replication.DestinationBucketARN)
+		err = s3.EnsureReplication(ctx, s3Client, location.bucket.Name, s3.ReplicationPlan{
+			DestinationBucketARN: replication.DestinationBucketARN,
+			RoleARN:              replication.RoleARN,
+		})
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			reason := "ReplicationFailed"
+			if errors.Is(err, s3.ErrVersioningRequired) {
+				reason = "VersioningRequired"
+			}
+			return reconcile.Result{}, r.failCondition(reqLogger, instance, location, veleroCR.ReplicationConfigured, reason,
+				fmt.Errorf("error occurred when configuring replication of bucket %v: %v", location.bucket.Name, err.Error()))
+		}
+		location.setCondition(instance, veleroCR.ReplicationConfigured, corev1.ConditionTrue, "ReplicationEnabled", "")
+	}
+
+	// Deliver the server access logs of the S3 bucket, if requested
+	if logging := location.spec.Logging; logging.TargetBucket != "" {
+		bucketLog.Info("Enforcing S3 Bucket access logging", "Logging.TargetBucket", logging.TargetBucket)
+		err = s3.EnsureBucketLogging(ctx, s3Client, location.bucket.Name, logging.TargetBucket, logging.TargetPrefix, logDelivery(logging))
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when configuring access logging on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	}
+
+	// Set the CORS rules of the S3 bucket, if requested
+	if rules := location.spec.CORSRules; len(rules) > 0 {
+		bucketLog.Info("Enforcing S3 Bucket CORS rules", "CORSRules", len(rules))
+		err = s3.EnsureBucketCors(ctx, s3Client, location.bucket.Name, corsRulePlans(rules))
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when configuring CORS rules on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	}
+
+	// Send the events of the S3 bucket to its notification targets, if requested
+	if notificationsManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket notifications")
+		err = s3.EnsureBucketNotification(ctx, s3Client, location.bucket.Name, notificationPlan(location.spec.Notifications))
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when configuring notifications on bucket %v: %v", location.bucket.Name, err.Error())
 		}
-		return reconcile.Result{}, fmt.Errorf("error occurred when configuring lifecycle rules on bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
 	}
 
-	// Make sure that tags are applied to buckets
-	bucketLog.Info("Enforcing S3 Bucket tags on S3 Bucket")
-	err = s3.TagBucket(s3Client, instance.Status.S3Bucket.Name, defaultBackupStorageLocation, infraName)
+	// Configure lifecycle rules on S3 bucket, unless they are left to the user.
+	// The retention of unmanaged rules is recorded as 0, so that managing
+	// them again re-asserts them right away.
+	var expirationDays, noncurrentDays int64
+	location.bucket.ExpirationDays = 0
+	location.bucket.NoncurrentExpirationDays = 0
+	location.bucket.Transitions = nil
+	location.bucket.LifecyclePrefix = ""
+	if lifecycleManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket lifecycle rules on S3 Bucket")
+		location.bucket.ExpirationDays = requestedLifecycleDays(location)
+		location.bucket.NoncurrentExpirationDays = location.spec.Lifecycle.NoncurrentVersionExpirationDays
+		location.bucket.Transitions = append([]veleroCR.LifecycleTransition(nil), location.spec.Lifecycle.Transitions...)
+		location.bucket.LifecyclePrefix = location.spec.Lifecycle.Prefix
+		expirationDays, noncurrentDays, err = r.checkLifecycleRetention(reqLogger, instance, location)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	versioned, err := s3.IsBucketVersioned(ctx, s3Client, location.bucket.Name)
 	if err != nil {
-		return reconcile.Result{}, fmt.Errorf("error occurred when tagging bucket %v: %v", instance.Status.S3Bucket.Name, err.Error())
+		if s3.IsNoSuchBucket(err) {
+			return reconcile.Result{}, errBucketMissing
+		}
+		return reconcile.Result{}, fmt.Errorf("error occurred when reading versioning of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	location.bucket.Versioned = versioned
+	if err = r.checkObjectLock(ctx, s3Client, instance, location); err != nil {
+		if s3.IsNoSuchBucket(err) {
+			return reconcile.Result{}, errBucketMissing
+		}
+		return reconcile.Result{}, fmt.Errorf("error occurred when reading object lock of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	if lifecycleManaged(location.spec) {
+		err = s3.EnsureBucketLifecycle(ctx, s3Client, location.bucket.Name, []s3.LifecycleRulePlan{backupExpiryRule(location, expirationDays, noncurrentDays)})
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			if aerr, ok := err.(awserr.Error); ok {
+				return reconcile.Result{}, fmt.Errorf("error occurred when configuring lifecycle rules on bucket %v: %v", location.bucket.Name, aerr.Error())
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when configuring lifecycle rules on bucket %v: %v", location.bucket.Name, err.Error())
+		}
+	} else {
+		bucketLog.Info("Leaving S3 Bucket lifecycle rules to the user")
+	}
+
+	// Configure the bucket policy to reject SSE-C uploads, if requested
+	bucketLog.Info("Enforcing S3 Bucket SSE-C policy")
+	if err = setSSECPolicy(ctx, bucketLog, s3Client, location); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Deny access to the bucket to the principals other than the allowed roles, if requested
+	bucketLog.Info("Enforcing S3 Bucket role restriction policy")
+	if err = setRoleRestrictionPolicy(ctx, bucketLog, s3Client, location); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Deny writes to the bucket while the backup storage location is read-only
+	bucketLog.Info("Enforcing S3 Bucket read-only policy")
+	if err = setReadOnlyPolicy(ctx, bucketLog, s3Client, instance, location); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Make sure that tags are applied to buckets, unless they are left to the user
+	if tagsManaged(location.spec) {
+		bucketLog.Info("Enforcing S3 Bucket tags on S3 Bucket")
+		if err = r.checkTagPolicy(reqLogger, instance, location, infraName); err != nil {
+			return reconcile.Result{}, err
+		}
+		err = s3.TagBucket(ctx, s3Client, location.bucket.Name, r.options.tagKeyPrefix, location.name, infraName, bucketTags(instance, location, r.options.tagKeyPrefix))
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when tagging bucket %v: %v", location.bucket.Name, err.Error())
+		}
+		recordInfrastructureName(bucketLog, location, infraName)
+	} else {
+		bucketLog.Info("Leaving S3 Bucket tags to the user")
+	}
+
+	// Make sure that Velero will be able to write to the bucket, unless
+	// writes are meant to be denied
+	if bslReadOnly(instance, location.spec) {
+		location.setCondition(instance, veleroCR.BucketWritable, corev1.ConditionFalse, "ReadOnly", "The backup storage location is read-only")
+	} else {
+		bucketLog.Info("Verifying S3 Bucket is writable")
+		prefix := location.spec.VerifyWritablePrefix
+		if prefix == "" {
+			prefix = s3.DefaultWritableProbePrefix
+		}
+		err = s3.VerifyBucketWritable(ctx, s3Client, location.bucket.Name, prefix)
+		if err != nil {
+			if s3.IsNoSuchBucket(err) {
+				return reconcile.Result{}, errBucketMissing
+			}
+			location.setCondition(instance, veleroCR.BucketWritable, corev1.ConditionFalse, "ProbeFailed", err.Error())
+			if updateErr := r.statusUpdate(reqLogger, instance); updateErr != nil {
+				return reconcile.Result{}, updateErr
+			}
+			return reconcile.Result{}, fmt.Errorf("error occurred when verifying bucket %v is writable: %v", location.bucket.Name, err.Error())
+		}
+		location.setCondition(instance, veleroCR.BucketWritable, corev1.ConditionTrue, "ProbeSucceeded", "")
 	}
 
-	instance.Status.S3Bucket.Provisioned = true
-	instance.Status.S3Bucket.LastSyncTimestamp = &metav1.Time{
+	location.bucket.Provisioned = true
+	location.setCondition(instance, veleroCR.BucketDrifted, corev1.ConditionFalse, "BucketSynced", "")
+	location.setCondition(instance, veleroCR.BucketReady, corev1.ConditionTrue, "BucketSynced", "")
+	location.bucket.LastSyncTimestamp = &metav1.Time{
 		Time: time.Now(),
 	}
 	return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
 }
 
+// recordBucketOrigin records the creation date of the bucket, and the account
+// of the credentials as its owner, as the account only lists the buckets it
+// owns. Nothing is recorded until the bucket is listed, such as for a bucket
+// of another account named in the spec. The owner account is left unset at a
+// custom S3 endpoint, or when the caller identity can't be read, as it is
+// informational only and mustn't hold up the provisioning.
+func (r *ReconcileVelero) recordBucketOrigin(
+	reqLogger logr.Logger, instance *veleroCR.Velero, location storageLocation, config *aws.Config, createdAt *time.Time) {
+	if createdAt == nil {
+		return
+	}
+	location.bucket.CreatedAt = &metav1.Time{Time: *createdAt}
+	if r.s3Endpoint(instance).URL != "" {
+		return
+	}
+	kmsClient, err := r.kmsClient(config)
+	if err == nil {
+		var account string
+		account, err = kms.CallerAccount(kmsClient)
+		location.bucket.OwnerAccount = account
+	}
+	if err != nil {
+		reqLogger.Error(err, "Unable to read the account owning the S3 bucket")
+	}
+}
+
+// checkUserBucket verifies that the bucket named in the spec exists and
+// belongs to the account of the credentials, and records the result in the
+// BucketUnavailable condition.
+func (r *ReconcileVelero) checkUserBucket(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) (bool, error) {
+	bucketName := location.bucket.Name
+	reqLogger.Info("Verifying S3 Bucket named in the spec exists")
+	exists, err := s3.DoesBucketExist(ctx, s3Client, bucketName)
+	switch {
+	case errors.Is(err, s3.ErrBucketForbidden):
+		reqLogger.Error(err, "S3 bucket named in the spec can't be accessed, not creating it")
+		r.recordBucketFailure(instance, eventAccessDenied, bucketName, err)
+		location.setCondition(instance, veleroCR.BucketUnavailable, corev1.ConditionTrue, "BucketForbidden",
+			fmt.Sprintf("Access to bucket %v is denied: it belongs to another account, or its policy denies the credentials", bucketName))
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("error occurred when verifying bucket %v: %v", bucketName, err.Error())
+	case !exists:
+		reqLogger.Error(nil, "S3 bucket named in the spec doesn't exist, not creating it")
+		location.setCondition(instance, veleroCR.BucketUnavailable, corev1.ConditionTrue, "BucketNotFound",
+			fmt.Sprintf("Bucket %v doesn't exist, and has to be created beforehand", bucketName))
+		return false, nil
+	}
+	if instance.Status.GetCondition(veleroCR.BucketUnavailable) != nil {
+		location.setCondition(instance, veleroCR.BucketUnavailable, corev1.ConditionFalse, "BucketFound", "")
+	}
+	return true, nil
+}
+
+// failCondition records the error as the reason the condition of the backup
+// storage location is False, and returns it. The status is updated right away,
+// as the error aborts the sync.
+func (r *ReconcileVelero) failCondition(
+	reqLogger logr.Logger, instance *veleroCR.Velero, location storageLocation, conditionType veleroCR.VeleroConditionType, reason string, err error) error {
+	location.setCondition(instance, conditionType, corev1.ConditionFalse, reason, err.Error())
+	if updateErr := r.statusUpdate(reqLogger, instance); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// setReadOnlyPolicy adds the bucket policy statement denying writes and deletes
+// of backups while the backup storage location is read-only, and removes it
+// otherwise. S3 compatible backends without bucket policies only get a
+// read-only BackupStorageLocation.
+func setReadOnlyPolicy(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) error {
+	readOnly := bslReadOnly(instance, location.spec)
+	err := s3.SetBucketReadOnlyPolicy(ctx, s3Client, location.bucket.Name, readOnly)
+	if err != nil {
+		if s3.IsNotImplemented(err) {
+			reqLogger.Info("S3 backend does not support bucket policies, writes to the bucket are not denied")
+			return nil
+		}
+		if s3.IsNoSuchBucket(err) {
+			return errBucketMissing
+		}
+		return fmt.Errorf("error occurred when configuring the read-only policy of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	location.bucket.ReadOnly = readOnly
+	return nil
+}
+
+// setSSECPolicy adds the bucket policy statement denying SSE-C uploads when
+// requested, and removes it otherwise. S3 compatible backends without bucket
+// policies have no statement to remove, so they only fail when the statement
+// is requested.
+func setSSECPolicy(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, location storageLocation) error {
+	err := s3.SetBucketSSECPolicy(ctx, s3Client, location.bucket.Name, location.spec.DenySSEC)
+	if err != nil {
+		if s3.IsNotImplemented(err) && !location.spec.DenySSEC {
+			reqLogger.Info("S3 backend does not support bucket policies, leaving SSE-C uploads allowed")
+			return nil
+		}
+		if s3.IsNoSuchBucket(err) {
+			return errBucketMissing
+		}
+		return fmt.Errorf("error occurred when configuring the policy of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	return nil
+}
+
+// setRoleRestrictionPolicy adds the bucket policy statement denying access to
+// the principals other than the allowed roles when any is allowed, and removes
+// it otherwise. Like with the SSE-C statement, S3 compatible backends without
+// bucket policies only fail when roles are allowed.
+func setRoleRestrictionPolicy(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, location storageLocation) error {
+	err := s3.EnsureBucketPolicy(ctx, s3Client, location.bucket.Name, location.spec.AllowedRoleARNs)
+	if err != nil {
+		if s3.IsNotImplemented(err) && len(location.spec.AllowedRoleARNs) == 0 {
+			reqLogger.Info("S3 backend does not support bucket policies, leaving access to the bucket unrestricted")
+			return nil
+		}
+		if s3.IsNoSuchBucket(err) {
+			return errBucketMissing
+		}
+		return fmt.Errorf("error occurred when configuring the policy of bucket %v: %v", location.bucket.Name, err.Error())
+	}
+	return nil
+}
+
+// readOnlyChanged checks whether the read-only policy of the bucket of any
+// backup storage location differs from the requested access.
+func readOnlyChanged(instance *veleroCR.Velero) bool {
+	for _, location := range storageLocations(instance) {
+		// Velero alone keeps the location on an Outpost read-only
+		if onOutpost(location.spec) {
+			continue
+		}
+		if location.bucket.ReadOnly != bslReadOnly(instance, location.spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// flipReadOnlyPolicies sets the read-only policy of the provisioned buckets
+// whose requested access changed, without syncing them otherwise.
+func (r *ReconcileVelero) flipReadOnlyPolicies(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero) error {
+	for _, location := range storageLocations(instance) {
+		if onOutpost(location.spec) || location.bucket.ReadOnly == bslReadOnly(instance, location.spec) {
+			continue
+		}
+		locationClient, err := r.locationS3Client(reqLogger, s3Client, instance, location)
+		if err != nil {
+			return err
+		}
+		if err = setReadOnlyPolicy(ctx, reqLogger, locationClient, instance, location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recreateBucket deletes a bucket whose encryption can only be set when it is
+// created, so that it is created again with the requested encryption. Unless
+// ForceRecreate is set, the bucket must be empty.
+func (r *ReconcileVelero) recreateBucket(
+	ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) (reconcile.Result, error) {
+	force := location.spec.ForceRecreate
+	if !force {
+		empty, err := s3.IsBucketEmpty(ctx, s3Client, location.bucket.Name)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !empty {
+			return reconcile.Result{}, fmt.Errorf("bucket %v must be recreated to change its encryption, but is not empty; set forceRecreate to delete its contents",
+				location.bucket.Name)
+		}
+	}
+
+	reqLogger.Info("S3 bucket encryption can't be changed, recreating S3 bucket", "Force", force)
+	if err := s3.DeleteBucket(ctx, s3Client, location.bucket.Name, force); err != nil {
+		return reconcile.Result{}, err
+	}
+	location.bucket.Provisioned = false
+	return reconcile.Result{Requeue: true}, r.statusUpdate(reqLogger, instance)
+}
+
+// syncClusterVersion records the ID and version of the cluster the bucket is
+// tagged with. When either changes, such as after an upgrade, the bucket is
+// synced again to refresh its tags.
+func (r *ReconcileVelero) syncClusterVersion(reqLogger logr.Logger, kubeClient client.Client, instance *veleroCR.Velero) error {
+	clusterVersion, err := platform.GetClusterVersion(kubeClient)
+	if err != nil {
+		return fmt.Errorf("unable to get cluster version: %v", err)
+	}
+	clusterID := string(clusterVersion.Spec.ClusterID)
+	version := clusterVersion.Status.Desired.Version
+	if instance.Status.S3Bucket.ClusterID == clusterID && instance.Status.S3Bucket.ClusterVersion == version {
+		return nil
+	}
+
+	reqLogger.Info("Cluster version changed, refreshing S3 bucket tags", "ClusterID", clusterID, "ClusterVersion", version)
+	instance.Status.S3Bucket.ClusterID = clusterID
+	instance.Status.S3Bucket.ClusterVersion = version
+	instance.Status.S3Bucket.LastSyncTimestamp = nil
+	// The buckets of the other locations are tagged with the same cluster
+	for i := range instance.Status.AdditionalS3Buckets {
+		instance.Status.AdditionalS3Buckets[i].S3Bucket.LastSyncTimestamp = nil
+	}
+	return r.statusUpdate(reqLogger, instance)
+}
+
+// bucketFrozen checks whether the operator must leave the bucket unchanged.
+func bucketFrozen(instance *veleroCR.Velero) bool {
+	return instance.Annotations[bucketFrozenAnnotation] == "true"
+}
+
+// checkFrozenBucket reports how the frozen buckets of every backup storage
+// location differ from the configuration the operator would otherwise
+// enforce, without changing them.
+func (r *ReconcileVelero) checkFrozenBucket(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, infraName string) error {
+	unprovisioned := false
+	var drifted []string
+	for _, location := range storageLocations(instance) {
+		bucketLog := reqLogger.WithValues("Location", location.name, "S3Bucket.Name", location.bucket.Name)
+
+		// The bucket is not synced while frozen, so that it is synced as soon
+		// as it is thawed.
+		location.bucket.LastSyncTimestamp = nil
+
+		if location.bucket.Name == "" || !location.bucket.Provisioned {
+			bucketLog.Info("S3 bucket is frozen before being provisioned")
+			unprovisioned = true
+			continue
+		}
+		if onOutpost(location.spec) {
+			bucketLog.Info("S3 bucket is frozen on an Outpost, which isn't checked for drift")
+			continue
+		}
+
+		locationClient, err := r.locationS3Client(reqLogger, s3Client, instance, location)
+		if err != nil {
+			return err
+		}
+		region := *locationClient.GetAWSClientConfig().Region
+		bucketLog = bucketLog.WithValues("S3Bucket.Region", region)
+		bucketLog.Info("S3 bucket is frozen, checking for drift")
+		current, err := s3.ReadBucketState(ctx, locationClient, location.bucket.Name)
+		if err != nil {
+			return fmt.Errorf("error occurred when checking bucket %v for drift: %v", location.bucket.Name, err)
+		}
+		changes := s3.DiffBucketState(locationBucketPlan(instance, location, region, infraName, r.options), current)
+		if !changes.Empty() {
+			bucketLog.Info("S3 bucket has drifted", "drift", changes.Aspects(),
+				"TagsAdded", changes.TagsAdded, "TagsRemoved", changes.TagsRemoved)
+			aspects := strings.Join(changes.Aspects(), ", ")
+			if location.name != defaultBackupStorageLocation {
+				aspects = fmt.Sprintf("%s of location %s", aspects, location.name)
+			}
+			drifted = append(drifted, aspects)
+		}
+	}
+
+	switch {
+	case len(drifted) > 0:
+		instance.Status.SetCondition(veleroCR.BucketDrifted, corev1.ConditionTrue, "BucketFrozen",
+			fmt.Sprintf("bucket is frozen, and differs in: %s", strings.Join(drifted, "; ")))
+	case unprovisioned:
+		instance.Status.SetCondition(veleroCR.BucketDrifted, corev1.ConditionUnknown, "BucketFrozen",
+			"bucket is frozen before being provisioned")
+	default:
+		instance.Status.SetCondition(veleroCR.BucketDrifted, corev1.ConditionFalse, "BucketFrozen",
+			"bucket is frozen, and matches the enforced configuration")
+	}
+	return r.statusUpdate(reqLogger, instance)
+}
+
+// checkTagPolicy verifies that the bucket tags comply with the configured tag
+// policy, and records the result in the TagPolicyViolation condition. An error
+// is returned when the tags must not be applied.
+func (r *ReconcileVelero) checkTagPolicy(reqLogger logr.Logger, instance *veleroCR.Velero, location storageLocation, infraName string) error {
+	if len(r.options.tagPolicyRequiredKeys) == 0 && len(r.options.tagPolicyAllowedValues) == 0 {
+		return nil
+	}
+	policy, err := s3.ParseTagPolicy(r.options.tagPolicyRequiredKeys, r.options.tagPolicyAllowedValues)
+	if err != nil {
+		return fmt.Errorf("invalid tag policy: %v", err)
+	}
+
+	violations := policy.BucketTagViolations(r.options.tagKeyPrefix, location.name, infraName, bucketTags(instance, location, r.options.tagKeyPrefix))
+	if len(violations) == 0 {
+		location.setCondition(instance, veleroCR.TagPolicyViolation, corev1.ConditionFalse, "TagsCompliant", "")
+		return nil
+	}
+
+	message := strings.Join(violations, "; ")
+	location.setCondition(instance, veleroCR.TagPolicyViolation, corev1.ConditionTrue, "TagsNotCompliant", message)
+	if err := r.statusUpdate(reqLogger, instance); err != nil {
+		return err
+	}
+	return fmt.Errorf("refusing to tag bucket %v: %v", location.bucket.Name, message)
+}
+
+// checkObjectLock records in the LifecycleObjectLockConflict condition whether
+// object lock is enabled on the bucket. The lifecycle rules are still applied,
+// as locked backups expire once their retention ends.
+func (r *ReconcileVelero) checkObjectLock(ctx context.Context, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) error {
+	locked, err := s3.IsBucketObjectLocked(ctx, s3Client, location.bucket.Name)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		location.setCondition(instance, veleroCR.LifecycleObjectLockConflict, corev1.ConditionFalse, "ObjectLockDisabled", "")
+		return nil
+	}
+	location.setCondition(instance, veleroCR.LifecycleObjectLockConflict, corev1.ConditionTrue, "ObjectLockEnabled",
+		"Object lock is enabled on the bucket, so the lifecycle expiration doesn't delete backups until their retention ends")
+	return nil
+}
+
+// checkLifecycleRetention enforces the maximum lifecycle retention, and records
+// the outcome in the LifecycleRetentionClamped and LifecycleRetentionRejected
+// conditions. It returns the days after which backups and their noncurrent
+// versions expire, or an error when the lifecycle rules must not be applied.
+func (r *ReconcileVelero) checkLifecycleRetention(reqLogger logr.Logger, instance *veleroCR.Velero, location storageLocation) (int64, int64, error) {
+	days := requestedLifecycleDays(location)
+	noncurrentDays := location.spec.Lifecycle.NoncurrentVersionExpirationDays
+	if r.options.maxLifecycleDays == 0 {
+		return days, noncurrentDays, nil
+	}
+	retention := days
+	if noncurrentDays > retention {
+		retention = noncurrentDays
+	}
+	if retention <= r.options.maxLifecycleDays {
+		location.setCondition(instance, veleroCR.LifecycleRetentionClamped, corev1.ConditionFalse, "WithinRetentionCap", "")
+		location.setCondition(instance, veleroCR.LifecycleRetentionRejected, corev1.ConditionFalse, "WithinRetentionCap", "")
+		return days, noncurrentDays, nil
+	}
+
+	message := fmt.Sprintf("lifecycle retention of %d days exceeds the maximum of %d days", retention, r.options.maxLifecycleDays)
+	switch r.options.lifecycleCapPolicy {
+	case lifecycleCapClamp:
+		location.setCondition(instance, veleroCR.LifecycleRetentionClamped, corev1.ConditionTrue, "RetentionClamped", message)
+		location.setCondition(instance, veleroCR.LifecycleRetentionRejected, corev1.ConditionFalse, "RetentionClamped", "")
+		return capDays(days, r.options.maxLifecycleDays), capDays(noncurrentDays, r.options.maxLifecycleDays), nil
+	case lifecycleCapReject:
+		location.setCondition(instance, veleroCR.LifecycleRetentionClamped, corev1.ConditionFalse, "RetentionRejected", "")
+		location.setCondition(instance, veleroCR.LifecycleRetentionRejected, corev1.ConditionTrue, "RetentionRejected", message)
+		if err := r.statusUpdate(reqLogger, instance); err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, fmt.Errorf("refusing to configure lifecycle rules on bucket %v: %v", location.bucket.Name, message)
+	default:
+		return 0, 0, fmt.Errorf("invalid lifecycle cap policy %q: must be one of %v or %v",
+			r.options.lifecycleCapPolicy, lifecycleCapClamp, lifecycleCapReject)
+	}
+}
+
+// backupExpiryRule returns the lifecycle rule expiring the backups in the
+// bucket after the given days, and their noncurrent versions after the given
+// noncurrent days, unless 0. Unless a retention is set explicitly, the current
+// versions in a versioned bucket don't expire, and are left to Velero to delete.
+// The backups move to the storage classes of the requested transitions as they age.
+// The rule only applies to the requested prefix, if any.
+func backupExpiryRule(location storageLocation, expirationDays int64, noncurrentDays int64) s3.LifecycleRulePlan {
+	expireCurrent := !location.bucket.Versioned || location.spec.LifecycleDays > 0 || location.spec.Lifecycle.ExpirationDays > 0
+	rule := s3.BackupExpiryRule(expirationDays, expireCurrent)
+	if noncurrentDays > 0 {
+		rule.NoncurrentExpirationDays = noncurrentDays
+	}
+	if prefix := location.spec.Lifecycle.Prefix; prefix != "" {
+		rule.Prefix = prefix
+	}
+	for _, transition := range location.spec.Lifecycle.Transitions {
+		rule.Transitions = append(rule.Transitions, s3.TransitionPlan{
+			Days:         transition.Days,
+			StorageClass: string(transition.StorageClass),
+		})
+	}
+	return rule
+}
+
+// logDelivery returns how the S3 log delivery is allowed to write to the
+// target bucket of the access logs.
+func logDelivery(logging veleroCR.BucketLoggingSpec) s3.LogDelivery {
+	switch logging.DeliveryPermission {
+	case veleroCR.LogDeliveryPermissionBucketPolicy:
+		return s3.LogDeliveryBucketPolicy
+	case veleroCR.LogDeliveryPermissionACL:
+		return s3.LogDeliveryACL
+	}
+	return s3.LogDeliveryAuto
+}
+
+// corsRulePlans returns the planned CORS rules of the bucket.
+func corsRulePlans(rules []veleroCR.BucketCORSRule) []s3.CORSRulePlan {
+	plans := make([]s3.CORSRulePlan, 0, len(rules))
+	for _, rule := range rules {
+		plans = append(plans, s3.CORSRulePlan{
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAgeSeconds:  rule.MaxAgeSeconds,
+		})
+	}
+	return plans
+}
+
+// notificationsManaged checks whether the notification configuration of the
+// bucket is managed, which is once any notification is requested.
+func notificationsManaged(spec veleroCR.BackupStorageLocationSpec) bool {
+	notifications := spec.Notifications
+	return len(notifications.Topics) > 0 || len(notifications.Queues) > 0 || notifications.EventBridge
+}
+
+// notificationPlan returns the planned notification configuration of the bucket.
+func notificationPlan(spec veleroCR.BucketNotificationSpec) s3.NotificationPlan {
+	targets := func(targets []veleroCR.BucketNotificationTarget) []s3.NotificationTargetPlan {
+		var plans []s3.NotificationTargetPlan
+		for _, target := range targets {
+			plans = append(plans, s3.NotificationTargetPlan{
+				ARN:    target.ARN,
+				Events: target.Events,
+				Prefix: target.Prefix,
+				Suffix: target.Suffix,
+			})
+		}
+		return plans
+	}
+	return s3.NotificationPlan{
+		Topics:      targets(spec.Topics),
+		Queues:      targets(spec.Queues),
+		EventBridge: spec.EventBridge,
+	}
+}
+
+// requestedLifecycleDays returns the days after which the backup storage
+// location asks for backups to expire.
+func requestedLifecycleDays(location storageLocation) int64 {
+	if days := location.spec.Lifecycle.ExpirationDays; days > 0 {
+		return days
+	}
+	if days := location.spec.LifecycleDays; days > 0 {
+		return days
+	}
+	return s3.DefaultBackupExpiryDays
+}
+
+// lifecycleManaged checks whether the operator configures the lifecycle rules
+// of the bucket, rather than leaving them to the user.
+func lifecycleManaged(spec veleroCR.BackupStorageLocationSpec) bool {
+	return spec.ManageLifecycle == nil || *spec.ManageLifecycle
+}
+
+// encryptionManaged checks whether the operator sets the default encryption of
+// the bucket, rather than leaving it to the user.
+func encryptionManaged(spec veleroCR.BackupStorageLocationSpec) bool {
+	return spec.ManageEncryption == nil || *spec.ManageEncryption
+}
+
+// tagsManaged checks whether the operator tags the bucket, rather than leaving
+// its tags to the user.
+func tagsManaged(spec veleroCR.BackupStorageLocationSpec) bool {
+	return spec.ManageTags == nil || *spec.ManageTags
+}
+
+// publicAccessBlockManaged checks whether the operator blocks public access to
+// the bucket, rather than leaving its public access block to the user.
+func publicAccessBlockManaged(spec veleroCR.BackupStorageLocationSpec) bool {
+	return spec.ManagePublicAccessBlock == nil || *spec.ManagePublicAccessBlock
+}
+
+// publicAccessBlockImplemented checks whether the S3 backend of the bucket
+// implements the public access block, as far as the last sync found out.
+func publicAccessBlockImplemented(instance *veleroCR.Velero) bool {
+	condition := instance.Status.GetCondition(veleroCR.PublicAccessBlocked)
+	return condition == nil || condition.Reason != reasonPublicAccessBlockNotImplemented
+}
+
+// lifecycleChanged checks whether the lifecycle retention requested by any
+// backup storage location changed since its lifecycle rules were last configured.
+func lifecycleChanged(instance *veleroCR.Velero) bool {
+	for _, location := range storageLocations(instance) {
+		if !lifecycleManaged(location.spec) {
+			continue
+		}
+		if location.bucket.ExpirationDays != requestedLifecycleDays(location) ||
+			location.bucket.NoncurrentExpirationDays != location.spec.Lifecycle.NoncurrentVersionExpirationDays ||
+			location.bucket.LifecyclePrefix != location.spec.Lifecycle.Prefix ||
+			transitionsChanged(location.bucket.Transitions, location.spec.Lifecycle.Transitions) {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionsChanged checks whether the requested transitions differ from the
+// ones the lifecycle rules were last configured for.
+func transitionsChanged(configured []veleroCR.LifecycleTransition, requested []veleroCR.LifecycleTransition) bool {
+	if len(configured) != len(requested) {
+		return true
+	}
+	for i := range requested {
+		if configured[i] != requested[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// capDays returns the days reduced to the maximum, unless 0.
+func capDays(days int64, max int64) int64 {
+	if max > 0 && days > max {
+		return max
+	}
+	return days
+}
+
+// checkRegion verifies that the region is known to the AWS SDK, and records the
+// result in the InvalidRegion condition. The region isn't checked when a
+// custom S3 endpoint is used, as it needn't be an AWS region.
+func (r *ReconcileVelero) checkRegion(reqLogger logr.Logger, instance *veleroCR.Velero, region string) error {
+	if r.s3Endpoint(instance).URL != "" {
+		return nil
+	}
+	if err := s3.ValidateRegion(region); err != nil {
+		instance.Status.SetCondition(veleroCR.InvalidRegion, corev1.ConditionTrue, "UnknownRegion", err.Error())
+		if updateErr := r.statusUpdate(reqLogger, instance); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	instance.Status.SetCondition(veleroCR.InvalidRegion, corev1.ConditionFalse, "KnownRegion", "")
+	return nil
+}
+
+// checkCredentials verifies that the credentials are granted the S3
+// permissions the buckets of every backup storage location are read with, and
+// records the result in the CredentialsValid condition, listing any missing
+// permissions.
+func (r *ReconcileVelero) checkCredentials(ctx context.Context, reqLogger logr.Logger, s3Client s3.Client, instance *veleroCR.Velero) error {
+	for _, location := range storageLocations(instance) {
+		// The buckets of the other locations are checked once they are selected
+		if location.name != defaultBackupStorageLocation && location.bucket.Name == "" {
+			continue
+		}
+		locationClient, err := r.locationS3Client(reqLogger, s3Client, instance, location)
+		if err != nil {
+			return err
+		}
+		// The bucket on an Outpost isn't reachable by name, so only the
+		// permissions to list the buckets are checked
+		bucketName := location.bucket.Name
+		if onOutpost(location.spec) {
+			bucketName = ""
+		}
+		err = s3.PreflightPermissionCheck(ctx, locationClient, bucketName)
+		var permErr *s3.PermissionError
+		if errors.As(err, &permErr) {
+			r.recordEvent(instance, corev1.EventTypeWarning, eventAccessDenied, "Bucket %v: %v", location.bucket.Name, err)
+			instance.Status.SetCondition(veleroCR.CredentialsValid, corev1.ConditionFalse, "MissingPermissions", err.Error())
+			if updateErr := r.statusUpdate(reqLogger, instance); updateErr != nil {
+				return updateErr
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+	instance.Status.SetCondition(veleroCR.CredentialsValid, corev1.ConditionTrue, "PermissionsGranted", "")
+	return nil
+}
+
+// bucketRegionClient returns a client for the region the bucket of the
+// location lives in, recording the region in the status when it differs from
+// the region of s3Client. Buckets at a custom endpoint are addressed with
+// s3Client, as S3-compatible stores don't report their regions reliably.
+func (r *ReconcileVelero) bucketRegionClient(
+	ctx context.Context, bucketLog logr.Logger, s3Client s3.Client, instance *veleroCR.Velero, location storageLocation) (s3.Client, error) {
+	if r.s3Endpoint(instance).URL != "" {
+		return s3Client, nil
+	}
+	region, err := s3.BucketRegion(ctx, s3Client, location.bucket.Name)
+	if err != nil {
+		return nil, err
+	}
+	if region == aws.StringValue(s3Client.GetAWSClientConfig().Region) {
+		return s3Client, nil
+	}
+	if location.bucket.Region != region {
+		bucketLog.Info("S3 bucket lives in another region, addressing it there", "S3Bucket.Region", region)
+		r.recordEvent(instance, corev1.EventTypeWarning, eventBucketRegionMismatch,
+			"Bucket %v lives in region %v rather than %v", location.bucket.Name, region, aws.StringValue(s3Client.GetAWSClientConfig().Region))
+		location.bucket.Region = region
+	}
+	regionalClient, err := s3Client.ForRegion(region)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create S3 client for region %v: %v", region, err)
+	}
+	return regionalClient, nil
+}
+
+// bucketRegion returns the region the bucket of the default backup storage
+// location is kept in: the region it was found to live in, or else the region
+// of the location, or else the cluster's region. The buckets of the other
+// locations are kept in their locationRegion, which falls back to this region.
+func bucketRegion(instance *veleroCR.Velero, clusterRegion string) string {
+	return locationRegion(defaultLocation(instance), clusterRegion)
+}
+
+// s3Endpoint returns the custom S3 endpoint the buckets are kept at: the
+// endpoint of the default backup storage location, or else the one configured
+// by the command line flags, which always addresses buckets in the path. AWS
+// is used when neither is set, at the accelerate or dual-stack endpoints when
+// enabled by the command line flags. Only the default location may set an
+// endpoint, so the buckets of every location are kept at the same one.
+func (r *ReconcileVelero) s3Endpoint(instance *veleroCR.Velero) s3.Endpoint {
+	return bucketEndpoint(instance, r.options)
+}
+
+// bucketEndpoint returns the S3 endpoint the bucket of the Velero instance is
+// kept at with the options, as described by ReconcileVelero.s3Endpoint.
+func bucketEndpoint(instance *veleroCR.Velero, opts options) s3.Endpoint {
+	if location := instance.Spec.DefaultStorageLocation(); location.Endpoint != "" {
+		return s3.Endpoint{URL: location.Endpoint, ForcePathStyle: location.S3ForcePathStyle}
+	}
+	if opts.s3Endpoint != "" {
+		return s3.Endpoint{URL: opts.s3Endpoint, ForcePathStyle: true}
+	}
+	return s3.Endpoint{Accelerate: opts.s3UseAccelerate, DualStack: opts.s3UseDualStack}
+}
+
+// s3Credentials returns how the S3 clients are authenticated, as selected by
+// the command line flags. The credentials secret is read in the namespace of
+// the operator.
+func (r *ReconcileVelero) s3Credentials() s3.CredentialsOptions {
+	return s3.CredentialsOptions{Mode: r.options.awsCredentialsMode}
+}
+
+// S3Credentials returns how the S3 clients are authenticated, as configured by
+// the command line flags, with the credentials secret read in the namespace.
+func S3Credentials(namespace string) s3.CredentialsOptions {
+	return s3.CredentialsOptions{Mode: flagOptions.awsCredentialsMode, Namespace: namespace}
+}
+
+// regionalS3Clients returns a client for each of the configured scan regions,
+// other than the given region, at the endpoint.
+func (r *ReconcileVelero) regionalS3Clients(reqLogger logr.Logger, endpoint s3.Endpoint, region string) ([]s3.Client, error) {
+	var clients []s3.Client
+	for _, scanRegion := range r.options.scanRegions {
+		if scanRegion == region {
+			continue
+		}
+		s3Client, err := s3.NewS3ClientForEndpoint(r.client, scanRegion, endpoint, r.s3Credentials())
+		if err != nil {
+			return nil, fmt.Errorf("unable to create S3 client for region %v: %v", scanRegion, err)
+		}
+		clients = append(clients, s3.NewLoggingClient(s3Client, reqLogger))
+	}
+	return clients, nil
+}
+
 func generateBucketName(prefix string) string {
 	id := uuid.New().String()
 	return prefix + id
 }
+
+// recordInfrastructureName records the infrastructure name the bucket was
+// tagged with in its status, as the tag holds it sanitized, which may no
+// longer tell which cluster it is.
+func recordInfrastructureName(bucketLog logr.Logger, location storageLocation, infraName string) {
+	if location.bucket.InfrastructureName == infraName {
+		return
+	}
+	if sanitized := s3.SanitizeTagValue(infraName); sanitized != infraName {
+		bucketLog.Info("Infrastructure name sanitized in the S3 bucket tags", "InfrastructureName", infraName, "TagValue", sanitized)
+	}
+	location.bucket.InfrastructureName = infraName
+}
+
+// randomSource returns the entropy source of the generated bucket names.
+func (r *ReconcileVelero) randomSource() io.Reader {
+	if r.random == nil {
+		return rand.Reader
+	}
+	return r.random
+}
+
+// randomUUID returns a version 4 UUID read from the entropy source.
+func randomUUID(random io.Reader) (uuid.UUID, error) {
+	var id uuid.UUID
+	if _, err := io.ReadFull(random, id[:]); err != nil {
+		return uuid.Nil, err
+	}
+	id[6] = (id[6] & 0x0f) | 0x40 // Version 4
+	id[8] = (id[8] & 0x3f) | 0x80 // Variant is 10
+	return id, nil
+}
+
+// proposedBucketName generates the name of a new bucket, with the prefix of
+// the backup storage location when set. The random suffix is a UUID, unless
+// the operator's --bucket-name-suffix-length is set, when it is that many
+// lowercase alphanumeric characters, and ErrInvalidBucketName is returned
+// when they don't fit in the name.
+func (r *ReconcileVelero) proposedBucketName(location storageLocation, infraName string) (string, error) {
+	prefix := location.spec.BucketNamePrefix
+	length := r.options.bucketNameSuffixLength
+	if length <= 0 {
+		id, err := randomUUID(r.randomSource())
+		if err != nil {
+			return "", fmt.Errorf("unable to generate bucket name: %v", err)
+		}
+		if prefix == "" {
+			return bucketPrefix + id.String(), nil
+		}
+		return generatePrefixedBucketName(prefix, infraName, strings.ReplaceAll(id.String(), "-", ""), minBucketNameRandomLength)
+	}
+
+	suffix, err := randomBucketNameSuffix(r.randomSource(), length)
+	if err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		if len(bucketPrefix)+length > maxBucketNameLength {
+			return "", fmt.Errorf("%w suffix length %d: the name %q and its suffix must be at most %d characters",
+				s3.ErrInvalidBucketName, length, bucketPrefix, maxBucketNameLength)
+		}
+		return bucketPrefix + suffix, nil
+	}
+	return generatePrefixedBucketName(prefix, infraName, suffix, length)
+}
+
+// randomBucketNameSuffix reads a random suffix of the length, made of the
+// characters of bucketNameSuffixAlphabet, from the entropy source. The bytes
+// past the largest multiple of the alphabet size are skipped, so that every
+// character is as likely.
+func randomBucketNameSuffix(random io.Reader, length int) (string, error) {
+	limit := 256 - 256%len(bucketNameSuffixAlphabet)
+	suffix := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(suffix) < length {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return "", fmt.Errorf("unable to generate bucket name suffix: %v", err)
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(suffix) < length {
+				suffix = append(suffix, bucketNameSuffixAlphabet[int(b)%len(bucketNameSuffixAlphabet)])
+			}
+		}
+	}
+	return string(suffix), nil
+}
+
+// generatePrefixedBucketName returns the bucket name <prefix>-<infraName>-<random>,
+// with the random suffix truncated to keep the name within the S3 length
+// limit, though to no fewer than minRandom characters. The prefix is never
+// truncated, so ErrInvalidBucketName is returned when it leaves too little
+// room for the suffix, or makes the name invalid.
+func generatePrefixedBucketName(prefix string, infraName string, random string, minRandom int) (string, error) {
+	name := prefix + "-" + infraName + "-"
+	room := maxBucketNameLength - len(name)
+	if room < minRandom {
+		return "", fmt.Errorf("%w prefix %q: must leave room for a %d character suffix after the infrastructure name %q",
+			s3.ErrInvalidBucketName, prefix, minRandom, infraName)
+	}
+	if len(random) > room {
+		random = random[:room]
+	}
+	name += random
+	if err := s3.ValidateBucketName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// bucketTags returns the tags to apply to the bucket alongside the tags used
+// to identify it, keyed under the tag key prefix.
+func bucketTags(instance *veleroCR.Velero, location storageLocation, tagKeyPrefix string) map[string]string {
+	tags := make(map[string]string)
+	// The operator's tags below replace the conflicting additional tags
+	for key, value := range location.spec.AdditionalTags {
+		tags[key] = value
+	}
+	if slaClass := location.spec.SLAClass; slaClass != "" {
+		tags[s3.TagKey(tagKeyPrefix, slaClassKey)] = string(slaClass)
+	}
+	// Distinguish the buckets the operator created from the adopted ones
+	if location.bucket.Created {
+		tags[s3.TagKey(tagKeyPrefix, provisionedByOperatorKey)] = "true"
+	}
+	// The cluster identity is for inventory only, and isn't used to find the bucket.
+	// It is recorded with the bucket of the default location.
+	if instance.Status.S3Bucket.ClusterID != "" {
+		tags[s3.TagKey(tagKeyPrefix, clusterIDKey)] = instance.Status.S3Bucket.ClusterID
+	}
+	if instance.Status.S3Bucket.ClusterVersion != "" {
+		tags[s3.TagKey(tagKeyPrefix, clusterVersionKey)] = instance.Status.S3Bucket.ClusterVersion
+	}
+	return tags
+}
+
+// BucketPlan returns the intended configuration of the bucket managed for the
+// Velero instance, with the lifecycle retention capped as configured by the
+// command line flags.
+func BucketPlan(instance *veleroCR.Velero, region string, infraName string) s3.BucketPlan {
+	return bucketPlan(instance, region, infraName, flagOptions)
+}
+
+func bucketPlan(instance *veleroCR.Velero, region string, infraName string, opts options) s3.BucketPlan {
+	return locationBucketPlan(instance, defaultLocation(instance), region, infraName, opts)
+}
+
+// locationBucketPlan returns the intended configuration of the bucket of the
+// backup storage location in the region, with the options.
+func locationBucketPlan(instance *veleroCR.Velero, location storageLocation, region string, infraName string, opts options) s3.BucketPlan {
+	expirationDays := capDays(requestedLifecycleDays(location), opts.maxLifecycleDays)
+	noncurrentDays := capDays(location.spec.Lifecycle.NoncurrentVersionExpirationDays, opts.maxLifecycleDays)
+	encryption := location.spec.Encryption
+	kmsKeyID := encryption.KMSKeyID
+	if encryption.CreateKey {
+		// The key is only known once it has been created
+		kmsKeyID = location.bucket.KMSKeyARN
+	}
+	plan := s3.NewBucketPlan(location.bucket.Name, region, string(encryption.Type), kmsKeyID, opts.tagKeyPrefix,
+		location.name, infraName, bucketTags(instance, location, opts.tagKeyPrefix), backupExpiryRule(location, expirationDays, noncurrentDays))
+	if !lifecycleManaged(location.spec) {
+		plan.LifecycleRules = nil
+		plan.LifecycleUnmanaged = true
+	}
+	if !encryptionManaged(location.spec) {
+		plan.Encryption = s3.EncryptionPlan{}
+		plan.EncryptionUnmanaged = true
+	}
+	if !tagsManaged(location.spec) {
+		plan.Tags = nil
+		plan.TagsUnmanaged = true
+	}
+	if !publicAccessBlockManaged(location.spec) || !publicAccessBlockImplemented(instance) {
+		plan.BlockPublicAccess = false
+	}
+	return plan
+}
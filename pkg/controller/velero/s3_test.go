@@ -0,0 +1,2670 @@
+package velero
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// mockS3Client implements the s3.Client interface, and keeps the state of
+// a single bucket in memory.
+type mockS3Client struct {
+	bucketName        string
+	encryption        *awss3.ServerSideEncryptionConfiguration
+	publicAccessBlock *awss3.PublicAccessBlockConfiguration
+	lifecycle         *awss3.BucketLifecycleConfiguration
+	tags              []*awss3.Tag
+	objects           map[string]bool
+	policy            *string
+	versioning        *string
+	logging           *awss3.LoggingEnabled
+	objectLock        *awss3.ObjectLockConfiguration
+	ownershipControls *awss3.OwnershipControls
+	replication       *awss3.ReplicationConfiguration
+	corsRules         []*awss3.CORSRule
+	notification      *awss3.NotificationConfiguration
+	creationDate      *time.Time
+
+	// writtenKeys records the key of every object written.
+	writtenKeys []string
+	// immutableEncryption simulates a backend which only sets the encryption
+	// of the bucket when it is created.
+	immutableEncryption bool
+	// deleteBucketOn simulates the bucket being deleted out-of-band just
+	// before the named call, which then fails with NoSuchBucket.
+	deleteBucketOn string
+
+	// mutations records the name of every call which changes the bucket.
+	mutations []string
+}
+
+func newMockS3Client(bucketName string) *mockS3Client {
+	return &mockS3Client{bucketName: bucketName, objects: make(map[string]bool)}
+}
+
+// CreateBucket implements the CreateBucket method for mockS3Client.
+func (c *mockS3Client) CreateBucket(ctx context.Context, input *awss3.CreateBucketInput) (*awss3.CreateBucketOutput, error) {
+	c.mutations = append(c.mutations, "CreateBucket")
+	c.bucketName = *input.Bucket
+	return &awss3.CreateBucketOutput{}, nil
+}
+
+// DeleteBucket implements the DeleteBucket method for mockS3Client.
+func (c *mockS3Client) DeleteBucket(ctx context.Context, input *awss3.DeleteBucketInput) (*awss3.DeleteBucketOutput, error) {
+	c.mutations = append(c.mutations, "DeleteBucket")
+	if len(c.objects) > 0 {
+		return nil, awserr.New("BucketNotEmpty", "The bucket you tried to delete is not empty", nil)
+	}
+	*c = mockS3Client{
+		immutableEncryption: c.immutableEncryption,
+		objects:             c.objects,
+		writtenKeys:         c.writtenKeys,
+		mutations:           c.mutations,
+	}
+	return &awss3.DeleteBucketOutput{}, nil
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for mockS3Client.
+func (c *mockS3Client) DeleteBucketPolicy(ctx context.Context, input *awss3.DeleteBucketPolicyInput) (*awss3.DeleteBucketPolicyOutput, error) {
+	c.mutations = append(c.mutations, "DeleteBucketPolicy")
+	c.policy = nil
+	return &awss3.DeleteBucketPolicyOutput{}, nil
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for mockS3Client.
+func (c *mockS3Client) DeleteBucketTagging(ctx context.Context, input *awss3.DeleteBucketTaggingInput) (*awss3.DeleteBucketTaggingOutput, error) {
+	c.mutations = append(c.mutations, "DeleteBucketTagging")
+	c.tags = nil
+	return &awss3.DeleteBucketTaggingOutput{}, nil
+}
+
+// DeleteObject implements the DeleteObject method for mockS3Client.
+func (c *mockS3Client) DeleteObject(ctx context.Context, input *awss3.DeleteObjectInput) (*awss3.DeleteObjectOutput, error) {
+	c.mutations = append(c.mutations, "DeleteObject")
+	delete(c.objects, *input.Key)
+	return &awss3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects implements the DeleteObjects method for mockS3Client.
+func (c *mockS3Client) DeleteObjects(ctx context.Context, input *awss3.DeleteObjectsInput) (*awss3.DeleteObjectsOutput, error) {
+	c.mutations = append(c.mutations, "DeleteObjects")
+	for _, object := range input.Delete.Objects {
+		delete(c.objects, *object.Key)
+	}
+	return &awss3.DeleteObjectsOutput{}, nil
+}
+
+// HeadBucket implements the HeadBucket method for mockS3Client.
+func (c *mockS3Client) HeadBucket(ctx context.Context, input *awss3.HeadBucketInput) (*awss3.HeadBucketOutput, error) {
+	if *input.Bucket != c.bucketName {
+		return nil, awserr.New("NotFound", "Not Found", nil)
+	}
+	return &awss3.HeadBucketOutput{}, nil
+}
+
+// ForRegion implements the ForRegion method for mockS3Client, which holds a
+// single bucket in every region.
+func (c *mockS3Client) ForRegion(region string) (s3.Client, error) {
+	return c, nil
+}
+
+// GetAWSClientConfig returns a copy of the AWS Client Config for the mockS3Client.
+func (c *mockS3Client) GetAWSClientConfig() *aws.Config {
+	return &aws.Config{Region: aws.String(testRegion)}
+}
+
+// GetBucketCors implements the GetBucketCors method for mockS3Client.
+func (c *mockS3Client) GetBucketCors(ctx context.Context, input *awss3.GetBucketCorsInput) (*awss3.GetBucketCorsOutput, error) {
+	if c.corsRules == nil {
+		return nil, awserr.New("NoSuchCORSConfiguration", "The CORS configuration does not exist", nil)
+	}
+	return &awss3.GetBucketCorsOutput{CORSRules: c.corsRules}, nil
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for mockS3Client.
+func (c *mockS3Client) GetBucketEncryption(ctx context.Context, input *awss3.GetBucketEncryptionInput) (*awss3.GetBucketEncryptionOutput, error) {
+	if c.encryption == nil {
+		return nil, awserr.New("ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found", nil)
+	}
+	return &awss3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: c.encryption}, nil
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for mockS3Client.
+func (c *mockS3Client) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *awss3.GetBucketLifecycleConfigurationInput) (*awss3.GetBucketLifecycleConfigurationOutput, error) {
+	if c.lifecycle == nil {
+		return nil, awserr.New("NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist", nil)
+	}
+	return &awss3.GetBucketLifecycleConfigurationOutput{Rules: c.lifecycle.Rules}, nil
+}
+
+// GetBucketLocation implements the GetBucketLocation method for mockS3Client.
+func (c *mockS3Client) GetBucketLocation(ctx context.Context, input *awss3.GetBucketLocationInput) (*awss3.GetBucketLocationOutput, error) {
+	return &awss3.GetBucketLocationOutput{LocationConstraint: aws.String(testRegion)}, nil
+}
+
+// GetBucketLogging implements the GetBucketLogging method for mockS3Client.
+func (c *mockS3Client) GetBucketLogging(ctx context.Context, input *awss3.GetBucketLoggingInput) (*awss3.GetBucketLoggingOutput, error) {
+	return &awss3.GetBucketLoggingOutput{LoggingEnabled: c.logging}, nil
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for mockS3Client.
+func (c *mockS3Client) GetBucketNotificationConfiguration(
+	ctx context.Context, input *awss3.GetBucketNotificationConfigurationRequest) (*awss3.NotificationConfiguration, error) {
+	if c.notification == nil {
+		return &awss3.NotificationConfiguration{}, nil
+	}
+	return c.notification, nil
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for mockS3Client.
+func (c *mockS3Client) GetBucketOwnershipControls(
+	ctx context.Context, input *awss3.GetBucketOwnershipControlsInput) (*awss3.GetBucketOwnershipControlsOutput, error) {
+	if c.ownershipControls == nil {
+		return nil, awserr.New("OwnershipControlsNotFoundError", "The bucket ownership controls were not found", nil)
+	}
+	return &awss3.GetBucketOwnershipControlsOutput{OwnershipControls: c.ownershipControls}, nil
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for mockS3Client.
+func (c *mockS3Client) GetBucketPolicy(ctx context.Context, input *awss3.GetBucketPolicyInput) (*awss3.GetBucketPolicyOutput, error) {
+	if c.policy == nil {
+		return nil, awserr.New("NoSuchBucketPolicy", "The bucket policy does not exist", nil)
+	}
+	return &awss3.GetBucketPolicyOutput{Policy: c.policy}, nil
+}
+
+// GetBucketReplication implements the GetBucketReplication method for mockS3Client.
+func (c *mockS3Client) GetBucketReplication(ctx context.Context, input *awss3.GetBucketReplicationInput) (*awss3.GetBucketReplicationOutput, error) {
+	if c.replication == nil {
+		return nil, awserr.New("ReplicationConfigurationNotFoundError", "The replication configuration was not found", nil)
+	}
+	return &awss3.GetBucketReplicationOutput{ReplicationConfiguration: c.replication}, nil
+}
+
+// GetBucketTagging implements the GetBucketTagging method for mockS3Client.
+func (c *mockS3Client) GetBucketTagging(ctx context.Context, input *awss3.GetBucketTaggingInput) (*awss3.GetBucketTaggingOutput, error) {
+	if c.tags == nil {
+		return nil, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+	}
+	return &awss3.GetBucketTaggingOutput{TagSet: c.tags}, nil
+}
+
+// GetBucketVersioning implements the GetBucketVersioning method for mockS3Client.
+func (c *mockS3Client) GetBucketVersioning(ctx context.Context, input *awss3.GetBucketVersioningInput) (*awss3.GetBucketVersioningOutput, error) {
+	return &awss3.GetBucketVersioningOutput{Status: c.versioning}, nil
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for mockS3Client.
+func (c *mockS3Client) GetObjectLockConfiguration(
+	ctx context.Context, input *awss3.GetObjectLockConfigurationInput) (*awss3.GetObjectLockConfigurationOutput, error) {
+	if c.objectLock == nil {
+		return nil, awserr.New("ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", nil)
+	}
+	return &awss3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: c.objectLock}, nil
+}
+
+// GetPublicAccessBlock implements the GetPublicAccessBlock method for mockS3Client.
+func (c *mockS3Client) GetPublicAccessBlock(ctx context.Context, input *awss3.GetPublicAccessBlockInput) (*awss3.GetPublicAccessBlockOutput, error) {
+	if c.publicAccessBlock == nil {
+		return nil, awserr.New("NoSuchPublicAccessBlockConfiguration", "The public access block configuration was not found", nil)
+	}
+	return &awss3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: c.publicAccessBlock}, nil
+}
+
+// ListBuckets implements the ListBuckets method for mockS3Client.
+func (c *mockS3Client) ListBuckets(ctx context.Context, input *awss3.ListBucketsInput) (*awss3.ListBucketsOutput, error) {
+	if c.bucketName == "" {
+		return &awss3.ListBucketsOutput{}, nil
+	}
+	return &awss3.ListBucketsOutput{Buckets: []*awss3.Bucket{{Name: aws.String(c.bucketName), CreationDate: c.creationDate}}}, nil
+}
+
+// ListObjectVersions implements the ListObjectVersions method for mockS3Client.
+func (c *mockS3Client) ListObjectVersions(ctx context.Context, input *awss3.ListObjectVersionsInput) (*awss3.ListObjectVersionsOutput, error) {
+	output := &awss3.ListObjectVersionsOutput{}
+	for key := range c.objects {
+		output.Versions = append(output.Versions, &awss3.ObjectVersion{Key: aws.String(key)})
+	}
+	return output, nil
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for mockS3Client. Every
+// object is reported as 1 KiB large.
+func (c *mockS3Client) ListObjectsV2(ctx context.Context, input *awss3.ListObjectsV2Input) (*awss3.ListObjectsV2Output, error) {
+	output := &awss3.ListObjectsV2Output{}
+	for key := range c.objects {
+		output.Contents = append(output.Contents, &awss3.Object{Key: aws.String(key), Size: aws.Int64(1024)})
+	}
+	return output, nil
+}
+
+// PutBucketCors implements the PutBucketCors method for mockS3Client.
+func (c *mockS3Client) PutBucketCors(ctx context.Context, input *awss3.PutBucketCorsInput) (*awss3.PutBucketCorsOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketCors")
+	c.corsRules = input.CORSConfiguration.CORSRules
+	return &awss3.PutBucketCorsOutput{}, nil
+}
+
+// PutBucketEncryption implements the PutBucketEncryption method for mockS3Client.
+func (c *mockS3Client) PutBucketEncryption(ctx context.Context, input *awss3.PutBucketEncryptionInput) (*awss3.PutBucketEncryptionOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketEncryption")
+	if c.immutableEncryption && c.encryption != nil {
+		return &awss3.PutBucketEncryptionOutput{}, nil
+	}
+	c.encryption = input.ServerSideEncryptionConfiguration
+	return &awss3.PutBucketEncryptionOutput{}, nil
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for mockS3Client.
+func (c *mockS3Client) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *awss3.PutBucketLifecycleConfigurationInput) (*awss3.PutBucketLifecycleConfigurationOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketLifecycleConfiguration")
+	if c.deleteBucketOn == "PutBucketLifecycleConfiguration" {
+		c.deleteBucketOn = ""
+		c.bucketName = ""
+		return nil, awserr.New(awss3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil)
+	}
+	c.lifecycle = input.LifecycleConfiguration
+	return &awss3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+// PutBucketLogging implements the PutBucketLogging method for mockS3Client.
+func (c *mockS3Client) PutBucketLogging(ctx context.Context, input *awss3.PutBucketLoggingInput) (*awss3.PutBucketLoggingOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketLogging")
+	c.logging = input.BucketLoggingStatus.LoggingEnabled
+	return &awss3.PutBucketLoggingOutput{}, nil
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for mockS3Client.
+func (c *mockS3Client) PutBucketNotificationConfiguration(
+	ctx context.Context, input *awss3.PutBucketNotificationConfigurationInput) (*awss3.PutBucketNotificationConfigurationOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketNotificationConfiguration")
+	c.notification = input.NotificationConfiguration
+	return &awss3.PutBucketNotificationConfigurationOutput{}, nil
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for mockS3Client.
+func (c *mockS3Client) PutBucketOwnershipControls(
+	ctx context.Context, input *awss3.PutBucketOwnershipControlsInput) (*awss3.PutBucketOwnershipControlsOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketOwnershipControls")
+	c.ownershipControls = input.OwnershipControls
+	return &awss3.PutBucketOwnershipControlsOutput{}, nil
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for mockS3Client.
+func (c *mockS3Client) PutBucketPolicy(ctx context.Context, input *awss3.PutBucketPolicyInput) (*awss3.PutBucketPolicyOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketPolicy")
+	c.policy = input.Policy
+	return &awss3.PutBucketPolicyOutput{}, nil
+}
+
+// PutBucketReplication implements the PutBucketReplication method for mockS3Client.
+func (c *mockS3Client) PutBucketReplication(ctx context.Context, input *awss3.PutBucketReplicationInput) (*awss3.PutBucketReplicationOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketReplication")
+	c.replication = input.ReplicationConfiguration
+	return &awss3.PutBucketReplicationOutput{}, nil
+}
+
+// PutBucketTagging implements the PutBucketTagging method for mockS3Client.
+func (c *mockS3Client) PutBucketTagging(ctx context.Context, input *awss3.PutBucketTaggingInput) (*awss3.PutBucketTaggingOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketTagging")
+	c.tags = input.Tagging.TagSet
+	return &awss3.PutBucketTaggingOutput{}, nil
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for mockS3Client.
+func (c *mockS3Client) PutBucketAccelerateConfiguration(
+	ctx context.Context, input *awss3.PutBucketAccelerateConfigurationInput) (*awss3.PutBucketAccelerateConfigurationOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketAccelerateConfiguration")
+	return &awss3.PutBucketAccelerateConfigurationOutput{}, nil
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for mockS3Client.
+func (c *mockS3Client) PutBucketVersioning(ctx context.Context, input *awss3.PutBucketVersioningInput) (*awss3.PutBucketVersioningOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketVersioning")
+	c.versioning = input.VersioningConfiguration.Status
+	return &awss3.PutBucketVersioningOutput{}, nil
+}
+
+// PutObject implements the PutObject method for mockS3Client.
+func (c *mockS3Client) PutObject(ctx context.Context, input *awss3.PutObjectInput) (*awss3.PutObjectOutput, error) {
+	c.mutations = append(c.mutations, "PutObject")
+	c.objects[*input.Key] = true
+	c.writtenKeys = append(c.writtenKeys, *input.Key)
+	return &awss3.PutObjectOutput{}, nil
+}
+
+// PutPublicAccessBlock implements the PutPublicAccessBlock method for mockS3Client.
+func (c *mockS3Client) PutPublicAccessBlock(ctx context.Context, input *awss3.PutPublicAccessBlockInput) (*awss3.PutPublicAccessBlockOutput, error) {
+	c.mutations = append(c.mutations, "PutPublicAccessBlock")
+	c.publicAccessBlock = input.PublicAccessBlockConfiguration
+	return &awss3.PutPublicAccessBlockOutput{}, nil
+}
+
+// getTestInstance fetches the stored state of the test Velero instance.
+func getTestInstance(t *testing.T, r *ReconcileVelero) *veleroCR.Velero {
+	t.Helper()
+	instance := &veleroCR.Velero{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "cluster"}, instance); err != nil {
+		t.Fatalf("unable to get Velero instance: %v", err)
+	}
+	return instance
+}
+
+func TestProvisionS3SyncsBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	stored := getTestInstance(t, r)
+	if condition := stored.Status.GetCondition(veleroCR.BucketDrifted); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("BucketDrifted condition = %+v, want status %v", condition, corev1.ConditionFalse)
+	}
+	if stored.Status.S3Bucket.LastSyncTimestamp == nil {
+		t.Errorf("LastSyncTimestamp was not set")
+	}
+}
+
+func TestCheckFrozenBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			SLAClass: veleroCR.SLAClassGold,
+		},
+	})
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	instance.Status.S3Bucket.LastSyncTimestamp = &metav1.Time{}
+	r := newTestReconciler(t, instance)
+	// The bucket exists, but none of its configuration is in place
+	s3Client := newMockS3Client(testBucketName)
+
+	if !bucketFrozen(instance) {
+		t.Fatalf("bucketFrozen() = false with the %v annotation", bucketFrozenAnnotation)
+	}
+	if err := r.checkFrozenBucket(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("checkFrozenBucket() error = %v", err)
+	}
+
+	if len(s3Client.mutations) != 0 {
+		t.Errorf("checkFrozenBucket() changed the frozen bucket with %v", s3Client.mutations)
+	}
+	stored := getTestInstance(t, r)
+	condition := stored.Status.GetCondition(veleroCR.BucketDrifted)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "BucketFrozen" {
+		t.Errorf("BucketDrifted condition = %+v, want status %v with reason BucketFrozen", condition, corev1.ConditionTrue)
+	}
+	if stored.Status.S3Bucket.LastSyncTimestamp != nil {
+		t.Errorf("LastSyncTimestamp = %v on a frozen bucket, want it cleared", stored.Status.S3Bucket.LastSyncTimestamp)
+	}
+}
+
+func TestCheckFrozenBucketInSync(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	s3Client.mutations = nil
+
+	instance = getTestInstance(t, r)
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	if err := r.checkFrozenBucket(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("checkFrozenBucket() error = %v", err)
+	}
+	if len(s3Client.mutations) != 0 {
+		t.Errorf("checkFrozenBucket() changed the frozen bucket with %v", s3Client.mutations)
+	}
+	if condition := getTestInstance(t, r).Status.GetCondition(veleroCR.BucketDrifted); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("BucketDrifted condition = %+v, want status %v", condition, corev1.ConditionFalse)
+	}
+}
+
+func TestCheckFrozenBucketLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary", Region: "eu-west-1"},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	defaultClient := newMockS3Client(testBucketName)
+	if _, err := r.provisionS3(context.TODO(), log, defaultClient, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+
+	// The bucket of the secondary location exists, but none of its
+	// configuration is in place
+	instance = getTestInstance(t, r)
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket", Provisioned: true}},
+	}
+	defaultClient.mutations = nil
+	secondaryClient := newMockS3Client("secondary-bucket")
+	s3Client := &regionalMockS3Client{
+		mockS3Client: defaultClient,
+		regions:      map[string]*mockS3Client{"eu-west-1": secondaryClient},
+	}
+	if err := r.checkFrozenBucket(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("checkFrozenBucket() error = %v", err)
+	}
+	if len(defaultClient.mutations) != 0 || len(secondaryClient.mutations) != 0 {
+		t.Errorf("checkFrozenBucket() changed the frozen buckets with %v and %v", defaultClient.mutations, secondaryClient.mutations)
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.BucketDrifted)
+	if condition == nil || condition.Status != corev1.ConditionTrue || !strings.Contains(condition.Message, "of location secondary") ||
+		strings.Contains(condition.Message, ";") {
+		t.Errorf("BucketDrifted condition = %+v, want only the drift of the secondary location", condition)
+	}
+}
+
+func TestProvisionS3VerifyWritablePrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		wantPrefix string
+	}{
+		{
+			name:       "default prefix",
+			prefix:     "",
+			wantPrefix: ".managed-velero-operator/",
+		},
+		{
+			name:       "configured prefix",
+			prefix:     "velero/allowed/",
+			wantPrefix: "velero/allowed/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					VerifyWritablePrefix: tt.prefix,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+
+			if len(s3Client.writtenKeys) != 1 {
+				t.Fatalf("provisionS3() wrote %d probe objects, want 1", len(s3Client.writtenKeys))
+			}
+			if key := s3Client.writtenKeys[0]; !strings.HasPrefix(key, tt.wantPrefix) {
+				t.Errorf("probe object %v is not under %v", key, tt.wantPrefix)
+			}
+			if len(s3Client.objects) != 0 {
+				t.Errorf("probe objects %v were left in the bucket", s3Client.objects)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.BucketWritable)
+			if condition == nil || condition.Status != corev1.ConditionTrue {
+				t.Errorf("BucketWritable condition = %+v, want status %v", condition, corev1.ConditionTrue)
+			}
+		})
+	}
+}
+
+// tagValue returns the value of the bucket tag with the given key.
+func (c *mockS3Client) tagValue(key string) (string, bool) {
+	for _, tag := range c.tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value), true
+		}
+	}
+	return "", false
+}
+
+// tagMap returns the tags of the bucket as a map.
+func (c *mockS3Client) tagMap() map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range c.tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags
+}
+
+func TestProvisionS3ProvisionedByOperatorTag(t *testing.T) {
+	t.Run("created bucket", func(t *testing.T) {
+		instance := newTestInstance(veleroCR.VeleroSpec{})
+		instance.Status.S3Bucket = veleroCR.S3Bucket{}
+		r := newTestReconciler(t, instance)
+		s3Client := newMockS3Client("")
+
+		// The first pass proposes a name, and the second creates the bucket
+		for i := 0; i < 2; i++ {
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+		}
+		if !getTestInstance(t, r).Status.S3Bucket.Created {
+			t.Errorf("S3Bucket.Created = false for a created bucket")
+		}
+		if value, ok := s3Client.tagValue(provisionedByOperatorKey); !ok || value != "true" {
+			t.Errorf("bucket tag %v = %q, want %q", provisionedByOperatorKey, value, "true")
+		}
+	})
+
+	t.Run("adopted bucket", func(t *testing.T) {
+		instance := newTestInstance(veleroCR.VeleroSpec{})
+		instance.Status.S3Bucket = veleroCR.S3Bucket{}
+		r := newTestReconciler(t, instance)
+		s3Client := newMockS3Client(testBucketName)
+		s3Client.tags = []*awss3.Tag{
+			{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+			{Key: aws.String("velero.io/infrastructureName"), Value: aws.String(testInfraName)},
+		}
+
+		// The first pass adopts the bucket, and the second syncs it
+		for i := 0; i < 2; i++ {
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+		}
+		stored := getTestInstance(t, r)
+		if stored.Status.S3Bucket.Name != testBucketName || stored.Status.S3Bucket.Created {
+			t.Errorf("S3Bucket = %+v, want %v adopted", stored.Status.S3Bucket, testBucketName)
+		}
+		if _, ok := s3Client.tagValue(provisionedByOperatorKey); ok {
+			t.Errorf("bucket tag %v is set on an adopted bucket", provisionedByOperatorKey)
+		}
+	})
+}
+
+func TestProvisionS3MigratesTagKeyPrefix(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	r.options.tagKeyPrefix = "example.com/velero-"
+	// The bucket was tagged before the prefix was configured
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.tags = []*awss3.Tag{
+		{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+		{Key: aws.String("velero.io/infrastructureName"), Value: aws.String(testInfraName)},
+	}
+
+	// The first pass adopts the bucket, and the second tags it again
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+	}
+	if name := getTestInstance(t, r).Status.S3Bucket.Name; name != testBucketName {
+		t.Fatalf("S3Bucket.Name = %q, want %v adopted", name, testBucketName)
+	}
+	if value, ok := s3Client.tagValue("example.com/velero-infrastructureName"); !ok || value != testInfraName {
+		t.Errorf("bucket tag example.com/velero-infrastructureName = %q, want %q", value, testInfraName)
+	}
+	for _, tag := range s3Client.tags {
+		if strings.HasPrefix(aws.StringValue(tag.Key), s3.DefaultTagKeyPrefix) {
+			t.Errorf("bucket tag %v kept its old prefix, tags = %v", aws.StringValue(tag.Key), s3Client.tags)
+		}
+	}
+}
+
+func TestProvisionS3RecordsBucketOrigin(t *testing.T) {
+	createdAt := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		bucket string
+		tags   []*awss3.Tag
+	}{
+		{
+			name:   "bucket recovered",
+			bucket: testBucketName,
+			tags: []*awss3.Tag{
+				{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+				{Key: aws.String("velero.io/infrastructureName"), Value: aws.String(testInfraName)},
+			},
+		},
+		{
+			name: "bucket created",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			instance.Status.S3Bucket = veleroCR.S3Bucket{}
+			r := newTestReconciler(t, instance)
+			r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+			s3Client := newMockS3Client(tt.bucket)
+			s3Client.tags = tt.tags
+			s3Client.creationDate = &createdAt
+
+			// The first pass recovers the bucket, or proposes its name, and the second provisions it
+			for i := 0; i < 2; i++ {
+				if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+					t.Fatalf("provisionS3() error = %v", err)
+				}
+			}
+			status := getTestInstance(t, r).Status.S3Bucket
+			if status.CreatedAt == nil || !status.CreatedAt.Time.Equal(createdAt) {
+				t.Errorf("S3Bucket.CreatedAt = %v, want %v", status.CreatedAt, createdAt)
+			}
+			if status.OwnerAccount != "123456789012" {
+				t.Errorf("S3Bucket.OwnerAccount = %q, want the account of the caller identity", status.OwnerAccount)
+			}
+		})
+	}
+}
+
+func TestProvisionS3MigratesLegacyInfraName(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{LegacyInfraNames: []string{"legacy-infra"}})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.tags = []*awss3.Tag{
+		{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+		{Key: aws.String("velero.io/infrastructureName"), Value: aws.String("legacy-infra")},
+	}
+
+	// The first pass recovers the bucket, and the second tags it with the current name
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	status := getTestInstance(t, r).Status.S3Bucket
+	if status.Name != testBucketName || status.MigratedFromInfrastructureName != "legacy-infra" {
+		t.Fatalf("S3Bucket = %+v, want %v recovered from legacy-infra", status, testBucketName)
+	}
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	infraName := ""
+	for _, tag := range s3Client.tags {
+		if aws.StringValue(tag.Key) == "velero.io/infrastructureName" {
+			infraName = aws.StringValue(tag.Value)
+		}
+	}
+	if infraName != testInfraName {
+		t.Errorf("bucket tagged with infrastructure name %q, want %q", infraName, testInfraName)
+	}
+	status = getTestInstance(t, r).Status.S3Bucket
+	if status.InfrastructureName != testInfraName || status.MigratedFromInfrastructureName != "legacy-infra" {
+		t.Errorf("S3Bucket = %+v, want infrastructure name %v migrated from legacy-infra", status, testInfraName)
+	}
+	// Once tagged with the current name, the bucket is found without the legacy names
+	tags := map[string]*awss3.GetBucketTaggingOutput{testBucketName: {TagSet: s3Client.tags}}
+	if got := s3.FindMatchingTags(tags, "", defaultBackupStorageLocation, testInfraName); got != testBucketName {
+		t.Errorf("FindMatchingTags() = %q after the migration, want %v", got, testBucketName)
+	}
+}
+
+func TestProvisionS3TagsUnmanaged(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		tags   []*awss3.Tag
+	}{
+		{
+			name:   "tagged bucket recovered",
+			bucket: testBucketName,
+			tags: []*awss3.Tag{
+				{Key: aws.String("velero.io/backup-location"), Value: aws.String(defaultBackupStorageLocation)},
+				{Key: aws.String("velero.io/infrastructureName"), Value: aws.String(testInfraName)},
+			},
+		},
+		{
+			name: "bucket created",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManageTags: aws.Bool(false)},
+			})
+			instance.Status.S3Bucket = veleroCR.S3Bucket{}
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(tt.bucket)
+			s3Client.tags = tt.tags
+
+			// The first pass recovers the bucket, or proposes its name, and the
+			// next ones find it through the name in the status
+			for i := 0; i < 3; i++ {
+				if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+					t.Fatalf("provisionS3() error = %v", err)
+				}
+			}
+			for _, mutation := range s3Client.mutations {
+				if mutation == "PutBucketTagging" || mutation == "DeleteBucketTagging" {
+					t.Errorf("provisionS3() called %v with the tags unmanaged, calls = %v", mutation, s3Client.mutations)
+				}
+			}
+			status := getTestInstance(t, r).Status.S3Bucket
+			if status.Name == "" || status.Name != s3Client.bucketName || !status.Provisioned {
+				t.Errorf("S3Bucket = %+v, want bucket %q provisioned", status, s3Client.bucketName)
+			}
+			if tt.bucket != "" && status.Name != tt.bucket {
+				t.Errorf("S3Bucket.Name = %q, want the tagged bucket %v recovered", status.Name, tt.bucket)
+			}
+			if len(s3Client.tags) != len(tt.tags) {
+				t.Errorf("bucket tags = %v, want them left unchanged", s3Client.tags)
+			}
+		})
+	}
+}
+
+func TestProvisionS3RecreatesMissingBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	r.options.maxBucketRestarts = 1
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.deleteBucketOn = "PutBucketLifecycleConfiguration"
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	created := false
+	for _, mutation := range s3Client.mutations {
+		if mutation == "CreateBucket" {
+			created = true
+		}
+	}
+	if !created || s3Client.bucketName != testBucketName {
+		t.Errorf("provisionS3() did not recreate bucket %v, calls = %v", testBucketName, s3Client.mutations)
+	}
+	if !getTestInstance(t, r).Status.S3Bucket.Provisioned {
+		t.Errorf("S3Bucket.Provisioned = false after recovering")
+	}
+}
+
+func TestProvisionS3DisablesACLsBeforeTagging(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket.Provisioned = false
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client("")
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	order := make(map[string]int)
+	for i, mutation := range s3Client.mutations {
+		if _, ok := order[mutation]; !ok {
+			order[mutation] = i
+		}
+	}
+	created, createdOK := order["CreateBucket"]
+	ownership, ownershipOK := order["PutBucketOwnershipControls"]
+	tagged, taggedOK := order["PutBucketTagging"]
+	if !createdOK || !ownershipOK || !taggedOK || ownership < created || ownership > tagged {
+		t.Errorf("provisionS3() calls = %v, want the ownership controls put between creating and tagging the bucket", s3Client.mutations)
+	}
+	if s3Client.ownershipControls == nil ||
+		aws.StringValue(s3Client.ownershipControls.Rules[0].ObjectOwnership) != awss3.ObjectOwnershipBucketOwnerEnforced {
+		t.Errorf("ownership controls = %v, want %v", s3Client.ownershipControls, awss3.ObjectOwnershipBucketOwnerEnforced)
+	}
+}
+
+func TestProvisionS3InvalidBucketName(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{Name: "Invalid_Bucket"}
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client("")
+
+	// The invalid name isn't retried, and is reported in the status instead
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v, want the invalid name not to be requeued", err)
+	}
+	if len(s3Client.mutations) != 0 {
+		t.Errorf("provisionS3() changed the bucket, calls = %v", s3Client.mutations)
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.InvalidBucketName)
+	if condition == nil || condition.Status != corev1.ConditionTrue {
+		t.Fatalf("InvalidBucketName condition = %+v, want it to report the invalid name", condition)
+	}
+
+	// Correcting the name creates the bucket, and clears the condition
+	instance = getTestInstance(t, r)
+	instance.Status.S3Bucket.Name = testBucketName
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	condition = getTestInstance(t, r).Status.GetCondition(veleroCR.InvalidBucketName)
+	if condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("InvalidBucketName condition = %+v, want it cleared", condition)
+	}
+}
+
+func TestProvisionS3BoundsRestarts(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.deleteBucketOn = "PutBucketLifecycleConfiguration"
+
+	// Without restarts, the missing bucket fails the reconcile
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err == nil {
+		t.Errorf("provisionS3() error = nil, want an error for the missing bucket")
+	}
+}
+
+func TestProvisionS3TagPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		slaClass      veleroCR.SLAClass
+		wantViolation corev1.ConditionStatus
+	}{
+		{
+			name:          "compliant tags",
+			slaClass:      veleroCR.SLAClassGold,
+			wantViolation: corev1.ConditionFalse,
+		},
+		{
+			name:          "missing required key",
+			wantViolation: corev1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					SLAClass: tt.slaClass,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			r.options.tagPolicyRequiredKeys = []string{slaClassKey}
+			s3Client := newMockS3Client(testBucketName)
+
+			_, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName)
+			if (err != nil) != (tt.wantViolation == corev1.ConditionTrue) {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.TagPolicyViolation)
+			if condition == nil || condition.Status != tt.wantViolation {
+				t.Errorf("TagPolicyViolation condition = %+v, want status %v", condition, tt.wantViolation)
+			}
+			tagged := false
+			for _, mutation := range s3Client.mutations {
+				if mutation == "PutBucketTagging" {
+					tagged = true
+				}
+			}
+			if tagged == (tt.wantViolation == corev1.ConditionTrue) {
+				t.Errorf("bucket tagged = %v with TagPolicyViolation %v", tagged, tt.wantViolation)
+			}
+		})
+	}
+}
+
+func TestProvisionS3LifecycleRetentionCap(t *testing.T) {
+	tests := []struct {
+		name          string
+		lifecycleDays int64
+		policy        string
+		wantDays      int64
+		wantClamped   corev1.ConditionStatus
+		wantRejected  corev1.ConditionStatus
+	}{
+		{
+			name:          "within the cap",
+			lifecycleDays: 30,
+			policy:        lifecycleCapClamp,
+			wantDays:      30,
+			wantClamped:   corev1.ConditionFalse,
+			wantRejected:  corev1.ConditionFalse,
+		},
+		{
+			name:          "clamped to the cap",
+			lifecycleDays: 365,
+			policy:        lifecycleCapClamp,
+			wantDays:      60,
+			wantClamped:   corev1.ConditionTrue,
+			wantRejected:  corev1.ConditionFalse,
+		},
+		{
+			name:         "default retention clamped to the cap",
+			policy:       lifecycleCapClamp,
+			wantDays:     60,
+			wantClamped:  corev1.ConditionTrue,
+			wantRejected: corev1.ConditionFalse,
+		},
+		{
+			name:          "rejected above the cap",
+			lifecycleDays: 365,
+			policy:        lifecycleCapReject,
+			wantClamped:   corev1.ConditionFalse,
+			wantRejected:  corev1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					LifecycleDays: tt.lifecycleDays,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			r.options.maxLifecycleDays = 60
+			r.options.lifecycleCapPolicy = tt.policy
+			s3Client := newMockS3Client(testBucketName)
+
+			_, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName)
+			if (err != nil) != (tt.wantRejected == corev1.ConditionTrue) {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			status := getTestInstance(t, r).Status
+			if condition := status.GetCondition(veleroCR.LifecycleRetentionClamped); condition == nil || condition.Status != tt.wantClamped {
+				t.Errorf("LifecycleRetentionClamped condition = %+v, want status %v", condition, tt.wantClamped)
+			}
+			if condition := status.GetCondition(veleroCR.LifecycleRetentionRejected); condition == nil || condition.Status != tt.wantRejected {
+				t.Errorf("LifecycleRetentionRejected condition = %+v, want status %v", condition, tt.wantRejected)
+			}
+
+			if tt.wantRejected == corev1.ConditionTrue {
+				if s3Client.lifecycle != nil {
+					t.Errorf("lifecycle rules = %v, want none when the retention is rejected", s3Client.lifecycle.Rules)
+				}
+				return
+			}
+			if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+				t.Fatalf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+			}
+			if got := *s3Client.lifecycle.Rules[0].Expiration.Days; got != tt.wantDays {
+				t.Errorf("lifecycle expiration = %d days, want %d", got, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestProvisionS3VersionedBucketLifecycle(t *testing.T) {
+	tests := []struct {
+		name             string
+		versioning       *string
+		lifecycleDays    int64
+		wantDays         int64
+		wantDeleteMarker bool
+	}{
+		{
+			name:     "unversioned bucket",
+			wantDays: s3.DefaultBackupExpiryDays,
+		},
+		{
+			name:             "versioned bucket",
+			versioning:       aws.String(awss3.BucketVersioningStatusEnabled),
+			wantDeleteMarker: true,
+		},
+		{
+			name:          "versioned bucket with an explicit retention",
+			versioning:    aws.String(awss3.BucketVersioningStatusEnabled),
+			lifecycleDays: 30,
+			wantDays:      30,
+		},
+		{
+			name:       "suspended versioning",
+			versioning: aws.String(awss3.BucketVersioningStatusSuspended),
+			wantDays:   s3.DefaultBackupExpiryDays,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					LifecycleDays: tt.lifecycleDays,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.versioning = tt.versioning
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			if got, want := getTestInstance(t, r).Status.S3Bucket.Versioned, tt.versioning != nil && *tt.versioning == awss3.BucketVersioningStatusEnabled; got != want {
+				t.Errorf("status versioned = %v, want %v", got, want)
+			}
+			if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+				t.Fatalf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+			}
+			expiration := s3Client.lifecycle.Rules[0].Expiration
+			if got := aws.BoolValue(expiration.ExpiredObjectDeleteMarker); got != tt.wantDeleteMarker {
+				t.Errorf("ExpiredObjectDeleteMarker = %v, want %v", got, tt.wantDeleteMarker)
+			}
+			if got := aws.Int64Value(expiration.Days); got != tt.wantDays {
+				t.Errorf("lifecycle expiration = %d days, want %d", got, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Versioning(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning *string
+		wantPut    bool
+	}{
+		{
+			name:       "enable from suspended",
+			versioning: aws.String(awss3.BucketVersioningStatusSuspended),
+			wantPut:    true,
+		},
+		{
+			name:       "already enabled",
+			versioning: aws.String(awss3.BucketVersioningStatusEnabled),
+			wantPut:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Versioning: true,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.versioning = tt.versioning
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketVersioning"
+			}
+			if put != tt.wantPut {
+				t.Errorf("PutBucketVersioning issued = %v, want %v", put, tt.wantPut)
+			}
+			if !getTestInstance(t, r).Status.S3Bucket.Versioned {
+				t.Errorf("status versioned = false, want true")
+			}
+
+			// The noncurrent versions expire, rather than growing the bucket
+			if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+				t.Fatalf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+			}
+			noncurrent := s3Client.lifecycle.Rules[0].NoncurrentVersionExpiration
+			if noncurrent == nil || aws.Int64Value(noncurrent.NoncurrentDays) != s3.DefaultBackupExpiryDays {
+				t.Errorf("lifecycle noncurrent version expiration = %v, want %d days", noncurrent, s3.DefaultBackupExpiryDays)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Logging(t *testing.T) {
+	tests := []struct {
+		name    string
+		logging veleroCR.BucketLoggingSpec
+		current *awss3.LoggingEnabled
+		wantPut bool
+	}{
+		{
+			name:    "logging unset",
+			current: &awss3.LoggingEnabled{TargetBucket: aws.String("other-logs"), TargetPrefix: aws.String("")},
+			wantPut: false,
+		},
+		{
+			name:    "enable logging",
+			logging: veleroCR.BucketLoggingSpec{TargetBucket: "audit-logs", TargetPrefix: "velero/"},
+			wantPut: true,
+		},
+		{
+			name:    "target already matches",
+			logging: veleroCR.BucketLoggingSpec{TargetBucket: "audit-logs", TargetPrefix: "velero/"},
+			current: &awss3.LoggingEnabled{TargetBucket: aws.String("audit-logs"), TargetPrefix: aws.String("velero/")},
+			wantPut: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Logging: tt.logging,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.logging = tt.current
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketLogging"
+			}
+			if put != tt.wantPut {
+				t.Errorf("PutBucketLogging issued = %v, want %v", put, tt.wantPut)
+			}
+			if tt.logging.TargetBucket != "" {
+				if s3Client.logging == nil || aws.StringValue(s3Client.logging.TargetBucket) != tt.logging.TargetBucket ||
+					aws.StringValue(s3Client.logging.TargetPrefix) != tt.logging.TargetPrefix {
+					t.Errorf("logging = %v, want logs delivered to %v under %v", s3Client.logging, tt.logging.TargetBucket, tt.logging.TargetPrefix)
+				}
+			} else if s3Client.logging != tt.current {
+				t.Errorf("logging = %v, want it left unchanged", s3Client.logging)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Cors(t *testing.T) {
+	rules := []veleroCR.BucketCORSRule{{
+		AllowedOrigins: []string{"https://console.example.com"},
+		AllowedMethods: []string{"GET", "HEAD"},
+		MaxAgeSeconds:  3000,
+	}}
+	current := []*awss3.CORSRule{{
+		AllowedOrigins: aws.StringSlice([]string{"https://console.example.com"}),
+		AllowedMethods: aws.StringSlice([]string{"GET", "HEAD"}),
+		MaxAgeSeconds:  aws.Int64(3000),
+	}}
+	tests := []struct {
+		name    string
+		rules   []veleroCR.BucketCORSRule
+		current []*awss3.CORSRule
+		wantPut bool
+	}{
+		{
+			name:    "CORS rules unset",
+			current: []*awss3.CORSRule{{AllowedOrigins: aws.StringSlice([]string{"*"}), AllowedMethods: aws.StringSlice([]string{"PUT"})}},
+			wantPut: false,
+		},
+		{
+			name:    "set CORS rules",
+			rules:   rules,
+			wantPut: true,
+		},
+		{
+			name:    "rules already match",
+			rules:   rules,
+			current: current,
+			wantPut: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					CORSRules: tt.rules,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.corsRules = tt.current
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketCors"
+			}
+			if put != tt.wantPut {
+				t.Errorf("PutBucketCors issued = %v, want %v", put, tt.wantPut)
+			}
+			if len(tt.rules) > 0 {
+				if len(s3Client.corsRules) != 1 || aws.Int64Value(s3Client.corsRules[0].MaxAgeSeconds) != 3000 ||
+					len(s3Client.corsRules[0].AllowedMethods) != 2 {
+					t.Errorf("CORS rules = %v, want %v", s3Client.corsRules, tt.rules)
+				}
+			} else if len(s3Client.corsRules) != len(tt.current) || s3Client.corsRules[0] != tt.current[0] {
+				t.Errorf("CORS rules = %v, want them left unchanged", s3Client.corsRules)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Notifications(t *testing.T) {
+	notifications := veleroCR.BucketNotificationSpec{
+		Queues: []veleroCR.BucketNotificationTarget{{
+			ARN:    "arn:aws:sqs:us-east-1:123456789012:velero-backups",
+			Events: []string{"s3:ObjectCreated:*"},
+			Prefix: "backups/",
+		}},
+	}
+	current := &awss3.NotificationConfiguration{
+		QueueConfigurations: []*awss3.QueueConfiguration{{
+			Id:       aws.String("velero-backups"),
+			QueueArn: aws.String("arn:aws:sqs:us-east-1:123456789012:velero-backups"),
+			Events:   aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+			Filter: &awss3.NotificationConfigurationFilter{Key: &awss3.KeyFilter{FilterRules: []*awss3.FilterRule{
+				{Name: aws.String("Prefix"), Value: aws.String("backups/")},
+			}}},
+		}},
+	}
+	tests := []struct {
+		name          string
+		notifications veleroCR.BucketNotificationSpec
+		current       *awss3.NotificationConfiguration
+		wantPut       bool
+	}{
+		{
+			name:    "notifications unset",
+			current: &awss3.NotificationConfiguration{EventBridgeConfiguration: &awss3.EventBridgeConfiguration{}},
+			wantPut: false,
+		},
+		{
+			name:          "set notifications",
+			notifications: notifications,
+			wantPut:       true,
+		},
+		{
+			name:          "notifications already match",
+			notifications: notifications,
+			current:       current,
+			wantPut:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Notifications: tt.notifications,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.notification = tt.current
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			put := false
+			for _, mutation := range s3Client.mutations {
+				put = put || mutation == "PutBucketNotificationConfiguration"
+			}
+			if put != tt.wantPut {
+				t.Errorf("PutBucketNotificationConfiguration issued = %v, want %v", put, tt.wantPut)
+			}
+			if len(tt.notifications.Queues) > 0 {
+				queues := s3Client.notification.QueueConfigurations
+				if len(queues) != 1 || aws.StringValue(queues[0].QueueArn) != tt.notifications.Queues[0].ARN {
+					t.Errorf("notification configuration = %v, want %+v", s3Client.notification, tt.notifications)
+				}
+			} else if s3Client.notification != tt.current {
+				t.Errorf("notification configuration = %v, want it left unchanged", s3Client.notification)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Replication(t *testing.T) {
+	replication := veleroCR.BucketReplicationSpec{
+		DestinationBucketARN: "arn:aws:s3:::velero-dr",
+		RoleARN:              "arn:aws:iam::123456789012:role/velero-replication",
+	}
+	tests := []struct {
+		name       string
+		versioning bool
+		wantErr    bool
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "versioned bucket",
+			versioning: true,
+			wantStatus: corev1.ConditionTrue,
+			wantReason: "ReplicationEnabled",
+		},
+		{
+			name:       "versioning not enabled",
+			wantErr:    true,
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "VersioningRequired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Versioning:  tt.versioning,
+					Replication: replication,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); (err != nil) != tt.wantErr {
+				t.Fatalf("provisionS3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.ReplicationConfigured)
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("ReplicationConfigured condition = %+v, want status %v with reason %v", condition, tt.wantStatus, tt.wantReason)
+			}
+			if tt.wantErr {
+				if s3Client.replication != nil {
+					t.Errorf("replication = %v, want none on an unversioned bucket", s3Client.replication)
+				}
+				return
+			}
+			if s3Client.replication == nil || aws.StringValue(s3Client.replication.Role) != replication.RoleARN ||
+				len(s3Client.replication.Rules) != 1 ||
+				aws.StringValue(s3Client.replication.Rules[0].Destination.Bucket) != replication.DestinationBucketARN {
+				t.Errorf("replication = %v, want objects replicated to %v", s3Client.replication, replication.DestinationBucketARN)
+			}
+		})
+	}
+}
+
+func TestProvisionS3ObjectLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectLock *awss3.ObjectLockConfiguration
+		want       corev1.ConditionStatus
+	}{
+		{
+			name: "object lock disabled",
+			want: corev1.ConditionFalse,
+		},
+		{
+			name:       "object lock enabled",
+			objectLock: &awss3.ObjectLockConfiguration{ObjectLockEnabled: aws.String(awss3.ObjectLockEnabledEnabled)},
+			want:       corev1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.objectLock = tt.objectLock
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			condition := getTestInstance(t, r).Status.GetCondition(veleroCR.LifecycleObjectLockConflict)
+			if condition == nil || condition.Status != tt.want {
+				t.Errorf("LifecycleObjectLockConflict condition = %v, want status %v", condition, tt.want)
+			}
+			// The lifecycle rule still applies to the backups once their retention ends
+			if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+				t.Errorf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+			}
+		})
+	}
+}
+
+func TestProvisionS3ManageLifecycle(t *testing.T) {
+	userLifecycle := &awss3.BucketLifecycleConfiguration{
+		Rules: []*awss3.LifecycleRule{{
+			ID:         aws.String("governance"),
+			Status:     aws.String(awss3.ExpirationStatusEnabled),
+			Filter:     &awss3.LifecycleRuleFilter{Prefix: aws.String("")},
+			Expiration: &awss3.LifecycleExpiration{Days: aws.Int64(365)},
+		}},
+	}
+	lifecyclePuts := func(s3Client *mockS3Client) int {
+		puts := 0
+		for _, mutation := range s3Client.mutations {
+			if mutation == "PutBucketLifecycleConfiguration" {
+				puts++
+			}
+		}
+		return puts
+	}
+
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			ManageLifecycle: aws.Bool(false),
+			LifecycleDays:   30,
+		},
+	})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	s3Client.lifecycle = userLifecycle
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if puts := lifecyclePuts(s3Client); puts != 0 {
+		t.Errorf("provisionS3() issued %d PutBucketLifecycleConfiguration calls, want none", puts)
+	}
+	if s3Client.lifecycle != userLifecycle {
+		t.Errorf("lifecycle = %v, want the user's rules left unchanged", s3Client.lifecycle)
+	}
+	instance = getTestInstance(t, r)
+	if lifecycleChanged(instance) {
+		t.Errorf("lifecycleChanged() = true for unmanaged lifecycle rules")
+	}
+
+	// The user's rules aren't reported as drift of a frozen bucket either
+	instance.Annotations = map[string]string{bucketFrozenAnnotation: "true"}
+	if err := r.checkFrozenBucket(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("checkFrozenBucket() error = %v", err)
+	}
+	if puts := lifecyclePuts(s3Client); puts != 0 {
+		t.Errorf("checkFrozenBucket() issued %d PutBucketLifecycleConfiguration calls, want none", puts)
+	}
+	instance = getTestInstance(t, r)
+	if condition := instance.Status.GetCondition(veleroCR.BucketDrifted); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("BucketDrifted condition = %+v, want status %v", condition, corev1.ConditionFalse)
+	}
+
+	// Managing the lifecycle again re-asserts the operator's rules right away
+	instance.Annotations = nil
+	instance.Spec.BackupStorageLocation.ManageLifecycle = nil
+	if !lifecycleChanged(instance) {
+		t.Errorf("lifecycleChanged() = false once the lifecycle rules are managed again")
+	}
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if puts := lifecyclePuts(s3Client); puts != 1 {
+		t.Errorf("provisionS3() issued %d PutBucketLifecycleConfiguration calls, want 1", puts)
+	}
+	if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 || aws.Int64Value(s3Client.lifecycle.Rules[0].Expiration.Days) != 30 {
+		t.Errorf("lifecycle = %v, want a single rule expiring backups after 30 days", s3Client.lifecycle)
+	}
+}
+
+func TestProvisionS3LifecycleTransitions(t *testing.T) {
+	glacier := veleroCR.LifecycleTransition{Days: 30, StorageClass: veleroCR.StorageClassGlacier}
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Lifecycle: veleroCR.LifecycleSpec{
+				ExpirationDays: 365,
+				Transitions:    []veleroCR.LifecycleTransition{glacier},
+			},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+		t.Fatalf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+	}
+	transitions := s3Client.lifecycle.Rules[0].Transitions
+	if len(transitions) != 1 || aws.Int64Value(transitions[0].Days) != 30 || aws.StringValue(transitions[0].StorageClass) != "GLACIER" {
+		t.Errorf("lifecycle transitions = %v, want GLACIER after 30 days", transitions)
+	}
+	instance = getTestInstance(t, r)
+	if lifecycleChanged(instance) {
+		t.Errorf("lifecycleChanged() = true right after configuring the lifecycle rules")
+	}
+
+	// A transition no earlier than the expiration is rejected
+	instance.Spec.BackupStorageLocation.Lifecycle.Transitions[0].Days = 400
+	if !lifecycleChanged(instance) {
+		t.Fatalf("lifecycleChanged() = false after the transitions changed")
+	}
+	_, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName)
+	if err == nil || !strings.Contains(err.Error(), s3.ErrInvalidLifecycleTransition.Error()) {
+		t.Fatalf("provisionS3() error = %v, want %v", err, s3.ErrInvalidLifecycleTransition)
+	}
+	if got := aws.Int64Value(s3Client.lifecycle.Rules[0].Transitions[0].Days); got != 30 {
+		t.Errorf("lifecycle transition after %d days, want the valid rule left in place", got)
+	}
+}
+
+func TestProvisionS3LifecyclePrefix(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+
+	rulePrefix := func() string {
+		t.Helper()
+		if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 || s3Client.lifecycle.Rules[0].Filter == nil {
+			t.Fatalf("lifecycle = %v, want a single rule with a filter", s3Client.lifecycle)
+		}
+		return aws.StringValue(s3Client.lifecycle.Rules[0].Filter.Prefix)
+	}
+
+	// The rule applies to the backups unless another prefix is requested
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if got := rulePrefix(); got != "backups/" {
+		t.Errorf("lifecycle rule prefix = %q, want the default backups/", got)
+	}
+
+	instance = getTestInstance(t, r)
+	instance.Spec.BackupStorageLocation.Lifecycle.Prefix = "velero/backups/"
+	if !lifecycleChanged(instance) {
+		t.Fatalf("lifecycleChanged() = false after the prefix changed")
+	}
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if got := rulePrefix(); got != "velero/backups/" {
+		t.Errorf("lifecycle rule prefix = %q, want velero/backups/", got)
+	}
+	if lifecycleChanged(getTestInstance(t, r)) {
+		t.Errorf("lifecycleChanged() = true right after configuring the prefixed rule")
+	}
+
+	// The next sync leaves the prefixed rule in place
+	synced := len(s3Client.mutations)
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	for _, mutation := range s3Client.mutations[synced:] {
+		if mutation == "PutBucketLifecycleConfiguration" {
+			t.Errorf("provisionS3() put the lifecycle configuration again, want the matching rule left in place")
+		}
+	}
+}
+
+// noPublicAccessBlockS3Client is a mockS3Client for an S3 compatible backend
+// which doesn't implement the public access block.
+type noPublicAccessBlockS3Client struct {
+	*mockS3Client
+	code string
+}
+
+// GetPublicAccessBlock implements the GetPublicAccessBlock method for noPublicAccessBlockS3Client.
+func (c *noPublicAccessBlockS3Client) GetPublicAccessBlock(ctx context.Context, input *awss3.GetPublicAccessBlockInput) (*awss3.GetPublicAccessBlockOutput, error) {
+	return nil, awserr.New(c.code, "A header you provided implies functionality that is not implemented", nil)
+}
+
+// PutPublicAccessBlock implements the PutPublicAccessBlock method for noPublicAccessBlockS3Client.
+func (c *noPublicAccessBlockS3Client) PutPublicAccessBlock(ctx context.Context, input *awss3.PutPublicAccessBlockInput) (*awss3.PutPublicAccessBlockOutput, error) {
+	c.mutations = append(c.mutations, "PutPublicAccessBlock")
+	return nil, awserr.New(c.code, "A header you provided implies functionality that is not implemented", nil)
+}
+
+// noBucketPolicyS3Client is a mockS3Client for an S3 compatible backend which
+// doesn't implement bucket policies.
+type noBucketPolicyS3Client struct {
+	*mockS3Client
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for noBucketPolicyS3Client.
+func (c *noBucketPolicyS3Client) GetBucketPolicy(ctx context.Context, input *awss3.GetBucketPolicyInput) (*awss3.GetBucketPolicyOutput, error) {
+	return nil, awserr.New("NotImplemented", "A header you provided implies functionality that is not implemented", nil)
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for noBucketPolicyS3Client.
+func (c *noBucketPolicyS3Client) PutBucketPolicy(ctx context.Context, input *awss3.PutBucketPolicyInput) (*awss3.PutBucketPolicyOutput, error) {
+	c.mutations = append(c.mutations, "PutBucketPolicy")
+	return nil, awserr.New("NotImplemented", "A header you provided implies functionality that is not implemented", nil)
+}
+
+func TestSetSSECPolicyNotImplemented(t *testing.T) {
+	tests := []struct {
+		name     string
+		denySSEC bool
+		wantErr  bool
+	}{
+		{
+			name:     "not requested",
+			denySSEC: false,
+		},
+		{
+			name:     "requested",
+			denySSEC: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{DenySSEC: tt.denySSEC},
+			})
+			s3Client := &noBucketPolicyS3Client{mockS3Client: newMockS3Client(testBucketName)}
+
+			err := setSSECPolicy(context.TODO(), log, s3Client, defaultLocation(instance))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setSSECPolicy() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionS3WithoutBucketPolicies(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	mockClient := newMockS3Client(testBucketName)
+	s3Client := &noBucketPolicyS3Client{mockS3Client: mockClient}
+
+	// Neither SSE-C denial nor allowed roles are requested
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	for _, mutation := range mockClient.mutations {
+		if mutation == "PutBucketPolicy" {
+			t.Errorf("provisionS3() put the bucket policy, want none requested")
+		}
+	}
+	if status := getTestInstance(t, r).Status; status.S3Bucket.LastSyncTimestamp == nil {
+		t.Errorf("status bucket = %+v, want it synced", status.S3Bucket)
+	}
+
+	// Allowed roles can't be enforced without bucket policies
+	instance.Spec.BackupStorageLocation.AllowedRoleARNs = []string{"arn:aws:iam::123456789012:role/velero"}
+	if err := setRoleRestrictionPolicy(context.TODO(), log, s3Client, defaultLocation(instance)); err == nil {
+		t.Errorf("setRoleRestrictionPolicy() error = nil, want the allowed roles rejected")
+	}
+}
+
+func TestProvisionS3PublicAccessBlock(t *testing.T) {
+	tests := []struct {
+		name           string
+		managed        *bool
+		unimplemented  string
+		wantBlocked    bool
+		wantReason     string
+		wantPutRequest bool
+	}{
+		{
+			name:           "managed",
+			wantBlocked:    true,
+			wantReason:     "PublicAccessBlocked",
+			wantPutRequest: true,
+		},
+		{
+			name:       "opted out",
+			managed:    aws.Bool(false),
+			wantReason: "PublicAccessBlockUnmanaged",
+		},
+		{
+			name:          "not implemented",
+			unimplemented: "NotImplemented",
+			wantReason:    reasonPublicAccessBlockNotImplemented,
+		},
+		{
+			name:          "method not allowed",
+			unimplemented: "MethodNotAllowed",
+			wantReason:    reasonPublicAccessBlockNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManagePublicAccessBlock: tt.managed},
+			})
+			r := newTestReconciler(t, instance)
+			mockClient := newMockS3Client(testBucketName)
+			var s3Client s3.Client = mockClient
+			if tt.unimplemented != "" {
+				s3Client = &noPublicAccessBlockS3Client{mockS3Client: mockClient, code: tt.unimplemented}
+			}
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			putRequest := false
+			for _, mutation := range mockClient.mutations {
+				putRequest = putRequest || mutation == "PutPublicAccessBlock"
+			}
+			if putRequest != tt.wantPutRequest {
+				t.Errorf("provisionS3() made a PutPublicAccessBlock request = %v, want %v", putRequest, tt.wantPutRequest)
+			}
+			status := getTestInstance(t, r).Status
+			if !status.S3Bucket.Provisioned || status.S3Bucket.LastSyncTimestamp == nil {
+				t.Errorf("status bucket = %+v, want it synced", status.S3Bucket)
+			}
+			wantStatus := corev1.ConditionFalse
+			if tt.wantBlocked {
+				wantStatus = corev1.ConditionTrue
+			}
+			condition := status.GetCondition(veleroCR.PublicAccessBlocked)
+			if condition == nil || condition.Status != wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("PublicAccessBlocked condition = %+v, want status %v with reason %v", condition, wantStatus, tt.wantReason)
+			}
+			if plan := bucketPlan(getTestInstance(t, r), "us-east-1", testInfraName, options{}); plan.BlockPublicAccess != tt.wantBlocked {
+				t.Errorf("bucketPlan() BlockPublicAccess = %v, want %v", plan.BlockPublicAccess, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestProvisionS3Encryption(t *testing.T) {
+	tests := []struct {
+		name           string
+		managed        *bool
+		wantStatus     corev1.ConditionStatus
+		wantReason     string
+		wantPutRequest bool
+	}{
+		{
+			name:           "managed by default",
+			wantStatus:     corev1.ConditionTrue,
+			wantReason:     "EncryptionEnforced",
+			wantPutRequest: true,
+		},
+		{
+			name:           "managed",
+			managed:        aws.Bool(true),
+			wantStatus:     corev1.ConditionTrue,
+			wantReason:     "EncryptionEnforced",
+			wantPutRequest: true,
+		},
+		{
+			name:       "opted out",
+			managed:    aws.Bool(false),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "EncryptionUnmanaged",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{ManageEncryption: tt.managed},
+			})
+			r := newTestReconciler(t, instance)
+			s3Client := newMockS3Client(testBucketName)
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			instance = getTestInstance(t, r)
+			putRequest := false
+			for _, mutation := range s3Client.mutations {
+				putRequest = putRequest || mutation == "PutBucketEncryption"
+			}
+			if putRequest != tt.wantPutRequest {
+				t.Errorf("provisionS3() made a PutBucketEncryption request = %v, want %v", putRequest, tt.wantPutRequest)
+			}
+			if !tt.wantPutRequest && s3Client.encryption != nil {
+				t.Errorf("bucket encryption = %v, want it left unset", s3Client.encryption)
+			}
+			condition := instance.Status.GetCondition(veleroCR.EncryptionConfigured)
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("EncryptionConfigured condition = %+v, want status %v with reason %v", condition, tt.wantStatus, tt.wantReason)
+			}
+			if plan := bucketPlan(instance, "us-east-1", testInfraName, options{}); plan.EncryptionUnmanaged == tt.wantPutRequest {
+				t.Errorf("bucketPlan() EncryptionUnmanaged = %v, want %v", plan.EncryptionUnmanaged, !tt.wantPutRequest)
+			}
+		})
+	}
+}
+
+func TestProvisionS3LifecycleExpiration(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			LifecycleDays: 60,
+			Lifecycle: veleroCR.LifecycleSpec{
+				ExpirationDays:                  30,
+				NoncurrentVersionExpirationDays: 7,
+			},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+
+	assertRule := func(wantDays, wantNoncurrentDays int64) {
+		t.Helper()
+		if s3Client.lifecycle == nil || len(s3Client.lifecycle.Rules) != 1 {
+			t.Fatalf("lifecycle = %v, want a single rule", s3Client.lifecycle)
+		}
+		rule := s3Client.lifecycle.Rules[0]
+		if got := aws.Int64Value(rule.Expiration.Days); got != wantDays {
+			t.Errorf("lifecycle expiration = %d days, want %d", got, wantDays)
+		}
+		if rule.NoncurrentVersionExpiration == nil {
+			t.Fatalf("lifecycle rule has no noncurrent version expiration, want %d days", wantNoncurrentDays)
+		}
+		if got := aws.Int64Value(rule.NoncurrentVersionExpiration.NoncurrentDays); got != wantNoncurrentDays {
+			t.Errorf("lifecycle noncurrent version expiration = %d days, want %d", got, wantNoncurrentDays)
+		}
+	}
+
+	// The lifecycle expiration takes precedence over the lifecycle days
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	assertRule(30, 7)
+	instance = getTestInstance(t, r)
+	if lifecycleChanged(instance) {
+		t.Errorf("lifecycleChanged() = true right after configuring the lifecycle rules")
+	}
+
+	// Changing the days re-applies the rule, without waiting for the next sync
+	instance.Spec.BackupStorageLocation.Lifecycle.ExpirationDays = 45
+	instance.Spec.BackupStorageLocation.Lifecycle.NoncurrentVersionExpirationDays = 14
+	if instance.S3BucketReconcileRequired(s3ReconcilePeriod) {
+		t.Fatalf("S3BucketReconcileRequired() = true right after a sync")
+	}
+	if !lifecycleChanged(instance) {
+		t.Fatalf("lifecycleChanged() = false after the expiration days changed")
+	}
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	assertRule(45, 14)
+	if lifecycleChanged(getTestInstance(t, r)) {
+		t.Errorf("lifecycleChanged() = true after re-applying the lifecycle rules")
+	}
+}
+
+func TestProvisionS3RecreateOnImmutableChange(t *testing.T) {
+	tests := []struct {
+		name         string
+		objects      []string
+		force        bool
+		wantRecreate bool
+	}{
+		{
+			name:         "empty bucket",
+			wantRecreate: true,
+		},
+		{
+			name:    "non-empty bucket",
+			objects: []string{"backups/backup-1/velero-backup.json"},
+		},
+		{
+			name:         "non-empty bucket with force",
+			objects:      []string{"backups/backup-1/velero-backup.json"},
+			force:        true,
+			wantRecreate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					Encryption: veleroCR.EncryptionSpec{
+						Type:     veleroCR.EncryptionTypeKMS,
+						KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/a",
+					},
+					RecreateOnImmutableChange: true,
+					ForceRecreate:             tt.force,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+			s3Client := newMockS3Client(testBucketName)
+			s3Client.immutableEncryption = true
+			s3Client.encryption = &awss3.ServerSideEncryptionConfiguration{
+				Rules: []*awss3.ServerSideEncryptionRule{{
+					ApplyServerSideEncryptionByDefault: &awss3.ServerSideEncryptionByDefault{
+						SSEAlgorithm: aws.String(awss3.ServerSideEncryptionAes256),
+					},
+				}},
+			}
+			for _, key := range tt.objects {
+				s3Client.objects[key] = true
+			}
+
+			result, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName)
+			if !tt.wantRecreate {
+				if err == nil {
+					t.Fatalf("provisionS3() error = nil, want an error for the non-empty bucket")
+				}
+				if s3Client.bucketName != testBucketName {
+					t.Errorf("provisionS3() deleted the non-empty bucket")
+				}
+				return
+			}
+			if err != nil || !result.Requeue {
+				t.Fatalf("provisionS3() = %+v, %v, want a requeue", result, err)
+			}
+			if s3Client.bucketName != "" || getTestInstance(t, r).Status.S3Bucket.Provisioned {
+				t.Fatalf("provisionS3() did not delete bucket %v", testBucketName)
+			}
+
+			// The next pass creates the bucket with the requested encryption
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			if !getTestInstance(t, r).Status.S3Bucket.Provisioned || s3Client.bucketName != testBucketName {
+				t.Errorf("provisionS3() did not recreate bucket %v, calls = %v", testBucketName, s3Client.mutations)
+			}
+			algorithm := s3Client.encryption.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm
+			if aws.StringValue(algorithm) != awss3.ServerSideEncryptionAwsKms {
+				t.Errorf("recreated bucket encryption = %v, want %v", aws.StringValue(algorithm), awss3.ServerSideEncryptionAwsKms)
+			}
+		})
+	}
+}
+
+func TestSyncClusterVersionTags(t *testing.T) {
+	clusterVersion := &configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Spec:       configv1.ClusterVersionSpec{ClusterID: "6d7e4c3f-0b1a-4f5e-9c2d-8a7b6c5d4e3f"},
+		Status:     configv1.ClusterVersionStatus{Desired: configv1.Update{Version: "4.2.0"}},
+	}
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance, clusterVersion)
+	s3Client := newMockS3Client(testBucketName)
+
+	assertTags := func(wantVersion string) {
+		t.Helper()
+		instance := getTestInstance(t, r)
+		if err := r.syncClusterVersion(log, r.client, instance); err != nil {
+			t.Fatalf("syncClusterVersion() error = %v", err)
+		}
+		if !instance.S3BucketReconcileRequired(s3ReconcilePeriod) {
+			t.Fatalf("S3BucketReconcileRequired() = false after the cluster version changed")
+		}
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+		if value, _ := s3Client.tagValue(clusterIDKey); value != string(clusterVersion.Spec.ClusterID) {
+			t.Errorf("bucket tag %v = %q, want %q", clusterIDKey, value, clusterVersion.Spec.ClusterID)
+		}
+		if value, _ := s3Client.tagValue(clusterVersionKey); value != wantVersion {
+			t.Errorf("bucket tag %v = %q, want %q", clusterVersionKey, value, wantVersion)
+		}
+	}
+	assertTags("4.2.0")
+
+	// An unchanged cluster version doesn't require the bucket to be synced again
+	synced := getTestInstance(t, r)
+	if err := r.syncClusterVersion(log, r.client, synced); err != nil {
+		t.Fatalf("syncClusterVersion() error = %v", err)
+	}
+	if synced.S3BucketReconcileRequired(s3ReconcilePeriod) {
+		t.Errorf("S3BucketReconcileRequired() = true for an unchanged cluster version")
+	}
+
+	// Upgrading the cluster refreshes the tags
+	clusterVersion.Status.Desired.Version = "4.2.1"
+	if err := r.client.Update(context.TODO(), clusterVersion); err != nil {
+		t.Fatalf("unable to update ClusterVersion: %v", err)
+	}
+	assertTags("4.2.1")
+}
+
+func TestProvisionS3SanitizedInfraName(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+	infraName := "team/fake cluster"
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), infraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	infraNameKey := s3.TagKey("", s3.DefaultTagKeyPrefix+"infrastructureName")
+	if value, _ := s3Client.tagValue(infraNameKey); value != "team_fake_cluster" {
+		t.Errorf("bucket tag %v = %q, want %q", infraNameKey, value, "team_fake_cluster")
+	}
+	if got := getTestInstance(t, r).Status.S3Bucket.InfrastructureName; got != infraName {
+		t.Errorf("status infrastructure name = %q, want %q", got, infraName)
+	}
+}
+
+func TestProvisionS3TransferAcceleration(t *testing.T) {
+	tests := []struct {
+		name       string
+		accelerate bool
+	}{
+		{name: "not requested"},
+		{name: "requested", accelerate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			r := newTestReconciler(t, instance)
+			r.options.s3UseAccelerate = tt.accelerate
+			s3Client := newMockS3Client(testBucketName)
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			var enabled bool
+			for _, mutation := range s3Client.mutations {
+				if mutation == "PutBucketAccelerateConfiguration" {
+					enabled = true
+				}
+			}
+			if enabled != tt.accelerate {
+				t.Errorf("provisionS3() enabled transfer acceleration = %v, want %v", enabled, tt.accelerate)
+			}
+		})
+	}
+}
+
+func TestS3Endpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		location     veleroCR.BackupStorageLocationSpec
+		flagEndpoint string
+		accelerate   bool
+		dualStack    bool
+		want         s3.Endpoint
+	}{
+		{
+			name: "aws",
+		},
+		{
+			name:       "aws with transfer acceleration and dual-stack",
+			accelerate: true,
+			dualStack:  true,
+			want:       s3.Endpoint{Accelerate: true, DualStack: true},
+		},
+		{
+			name:         "flag endpoint isn't accelerated",
+			flagEndpoint: "https://minio.example.com:9000",
+			accelerate:   true,
+			dualStack:    true,
+			want:         s3.Endpoint{URL: "https://minio.example.com:9000", ForcePathStyle: true},
+		},
+		{
+			name:         "flag endpoint",
+			flagEndpoint: "https://minio.example.com:9000",
+			want:         s3.Endpoint{URL: "https://minio.example.com:9000", ForcePathStyle: true},
+		},
+		{
+			name:     "storage location endpoint",
+			location: veleroCR.BackupStorageLocationSpec{Endpoint: "https://rgw.example.com"},
+			want:     s3.Endpoint{URL: "https://rgw.example.com"},
+		},
+		{
+			name:         "storage location endpoint overrides the flag",
+			location:     veleroCR.BackupStorageLocationSpec{Endpoint: "https://minio.example.com:9000", S3ForcePathStyle: true},
+			flagEndpoint: "https://rgw.example.com",
+			want:         s3.Endpoint{URL: "https://minio.example.com:9000", ForcePathStyle: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{BackupStorageLocation: tt.location})
+			r := newTestReconciler(t, instance)
+			r.options.s3Endpoint = tt.flagEndpoint
+			r.options.s3UseAccelerate = tt.accelerate
+			r.options.s3UseDualStack = tt.dualStack
+			if got := r.s3Endpoint(instance); got != tt.want {
+				t.Errorf("s3Endpoint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRegion(t *testing.T) {
+	tests := []struct {
+		name        string
+		region      string
+		endpoint    string
+		wantErr     bool
+		wantInvalid corev1.ConditionStatus
+	}{
+		{
+			name:        "valid region",
+			region:      "us-east-1",
+			wantInvalid: corev1.ConditionFalse,
+		},
+		{
+			name:        "valid region in another partition",
+			region:      "cn-north-1",
+			wantInvalid: corev1.ConditionFalse,
+		},
+		{
+			name:        "mistyped region",
+			region:      "us-east-11",
+			wantErr:     true,
+			wantInvalid: corev1.ConditionTrue,
+		},
+		{
+			name:     "custom endpoint",
+			region:   "us-east-11",
+			endpoint: "https://minio.example.com:9000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			r := newTestReconciler(t, instance)
+			r.options.s3Endpoint = tt.endpoint
+
+			if err := r.checkRegion(log, instance, tt.region); (err != nil) != tt.wantErr {
+				t.Fatalf("checkRegion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			condition := instance.Status.GetCondition(veleroCR.InvalidRegion)
+			if tt.wantInvalid == "" {
+				if condition != nil {
+					t.Errorf("InvalidRegion condition = %+v, want none with a custom endpoint", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tt.wantInvalid {
+				t.Errorf("InvalidRegion condition = %+v, want status %v", condition, tt.wantInvalid)
+			}
+			if tt.wantErr {
+				if persisted := getTestInstance(t, r).Status.GetCondition(veleroCR.InvalidRegion); persisted == nil || persisted.Status != corev1.ConditionTrue {
+					t.Errorf("persisted InvalidRegion condition = %+v, want status %v", persisted, corev1.ConditionTrue)
+				}
+			}
+		})
+	}
+}
+
+// taggingDeniedS3Client is a mockS3Client whose credentials aren't allowed to
+// read the bucket tags.
+type taggingDeniedS3Client struct {
+	*mockS3Client
+}
+
+// GetBucketTagging implements the GetBucketTagging method for taggingDeniedS3Client.
+func (c *taggingDeniedS3Client) GetBucketTagging(ctx context.Context, input *awss3.GetBucketTaggingInput) (*awss3.GetBucketTaggingOutput, error) {
+	return nil, awserr.New("AccessDenied", "Access Denied", nil)
+}
+
+func TestCheckCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    s3.Client
+		wantErr   bool
+		wantValid corev1.ConditionStatus
+	}{
+		{
+			name:      "permissions granted",
+			client:    newMockS3Client(testBucketName),
+			wantValid: corev1.ConditionTrue,
+		},
+		{
+			name:      "tagging denied",
+			client:    &taggingDeniedS3Client{newMockS3Client(testBucketName)},
+			wantErr:   true,
+			wantValid: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			r := newTestReconciler(t, instance)
+
+			if err := r.checkCredentials(context.TODO(), log, tt.client, instance); (err != nil) != tt.wantErr {
+				t.Fatalf("checkCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			condition := instance.Status.GetCondition(veleroCR.CredentialsValid)
+			if condition == nil || condition.Status != tt.wantValid {
+				t.Fatalf("CredentialsValid condition = %+v, want status %v", condition, tt.wantValid)
+			}
+			if tt.wantErr {
+				if !strings.Contains(condition.Message, "s3:GetBucketTagging") {
+					t.Errorf("CredentialsValid message = %q, want it to list s3:GetBucketTagging", condition.Message)
+				}
+				if persisted := getTestInstance(t, r).Status.GetCondition(veleroCR.CredentialsValid); persisted == nil || persisted.Status != corev1.ConditionFalse {
+					t.Errorf("persisted CredentialsValid condition = %+v, want status %v", persisted, corev1.ConditionFalse)
+				}
+			}
+		})
+	}
+}
+
+// encryptionDeniedS3Client is a mockS3Client whose credentials aren't allowed
+// to encrypt the bucket.
+type encryptionDeniedS3Client struct {
+	*mockS3Client
+}
+
+// encryptionDeniedRequestID is the request ID of the denied PutBucketEncryption requests.
+const encryptionDeniedRequestID = "4442587FB7D0A2F9"
+
+// PutBucketEncryption implements the PutBucketEncryption method for encryptionDeniedS3Client.
+func (c *encryptionDeniedS3Client) PutBucketEncryption(ctx context.Context, input *awss3.PutBucketEncryptionInput) (*awss3.PutBucketEncryptionOutput, error) {
+	return nil, awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, encryptionDeniedRequestID)
+}
+
+func TestProvisionS3Conditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  s3.Client
+		wantErr bool
+		want    map[veleroCR.VeleroConditionType]string
+	}{
+		{
+			name:   "bucket configured",
+			client: newMockS3Client(testBucketName),
+			want: map[veleroCR.VeleroConditionType]string{
+				veleroCR.BucketReady:          "BucketSynced",
+				veleroCR.EncryptionConfigured: "EncryptionEnforced",
+				veleroCR.PublicAccessBlocked:  "PublicAccessBlocked",
+			},
+		},
+		{
+			name:    "encryption denied",
+			client:  &encryptionDeniedS3Client{newMockS3Client(testBucketName)},
+			wantErr: true,
+			want: map[veleroCR.VeleroConditionType]string{
+				veleroCR.EncryptionConfigured: "EncryptionFailed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{})
+			r := newTestReconciler(t, instance)
+
+			if _, err := r.provisionS3(context.TODO(), log, tt.client, instance, testInfraName); (err != nil) != tt.wantErr {
+				t.Fatalf("provisionS3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			status := getTestInstance(t, r).Status
+			for conditionType, reason := range tt.want {
+				wantStatus := corev1.ConditionTrue
+				if tt.wantErr {
+					wantStatus = corev1.ConditionFalse
+				}
+				condition := status.GetCondition(conditionType)
+				if condition == nil || condition.Status != wantStatus || condition.Reason != reason {
+					t.Errorf("%v condition = %+v, want status %v with reason %v", conditionType, condition, wantStatus, reason)
+				}
+				if tt.wantErr && !strings.Contains(condition.Message, "AccessDenied") {
+					t.Errorf("%v message = %q, want the error", conditionType, condition.Message)
+				}
+				if tt.wantErr && !strings.Contains(condition.Message, "request id: "+encryptionDeniedRequestID) {
+					t.Errorf("%v message = %q, want the request ID of the failed request", conditionType, condition.Message)
+				}
+			}
+			if tt.wantErr {
+				if condition := status.GetCondition(veleroCR.BucketReady); condition != nil && condition.Status == corev1.ConditionTrue {
+					t.Errorf("BucketReady condition = %+v after a failed sync, want it not True", condition)
+				}
+			}
+		})
+	}
+}
+
+// foreignBucketS3Client is a mockS3Client whose bucket belongs to another
+// account, which HeadBucket reports as forbidden.
+type foreignBucketS3Client struct {
+	*mockS3Client
+}
+
+// HeadBucket implements the HeadBucket method for foreignBucketS3Client.
+func (c *foreignBucketS3Client) HeadBucket(ctx context.Context, input *awss3.HeadBucketInput) (*awss3.HeadBucketOutput, error) {
+	return nil, awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "")
+}
+
+// relocatedBucketS3Client is a mockS3Client whose bucket lives in another
+// region than the client's, recording the regions it created clients for.
+type relocatedBucketS3Client struct {
+	*mockS3Client
+	bucketRegion string
+	regions      []string
+}
+
+// GetBucketLocation implements the GetBucketLocation method for relocatedBucketS3Client.
+func (c *relocatedBucketS3Client) GetBucketLocation(ctx context.Context, input *awss3.GetBucketLocationInput) (*awss3.GetBucketLocationOutput, error) {
+	return &awss3.GetBucketLocationOutput{LocationConstraint: aws.String(c.bucketRegion)}, nil
+}
+
+// ForRegion implements the ForRegion method for relocatedBucketS3Client.
+func (c *relocatedBucketS3Client) ForRegion(region string) (s3.Client, error) {
+	c.regions = append(c.regions, region)
+	return c, nil
+}
+
+func TestProvisionS3BucketInOtherRegion(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	s3Client := &relocatedBucketS3Client{mockS3Client: newMockS3Client(testBucketName), bucketRegion: "eu-west-1"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, getTestInstance(t, r), testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+	}
+	stored := getTestInstance(t, r)
+	if stored.Status.S3Bucket.Region != "eu-west-1" {
+		t.Errorf("status region = %q, want eu-west-1", stored.Status.S3Bucket.Region)
+	}
+	if len(s3Client.regions) == 0 {
+		t.Errorf("provisionS3() didn't create a client for the bucket's region")
+	}
+	for _, region := range s3Client.regions {
+		if region != "eu-west-1" {
+			t.Errorf("provisionS3() created clients for regions %v, want eu-west-1", s3Client.regions)
+			break
+		}
+	}
+	if got := bucketRegion(stored, testRegion); got != "eu-west-1" {
+		t.Errorf("bucketRegion() = %v, want the region the bucket lives in", got)
+	}
+	var events []string
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, eventBucketRegionMismatch) {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 1 {
+		t.Errorf("recorded %v events %q, want one", eventBucketRegionMismatch, events)
+	}
+}
+
+func TestProvisionS3UserBucket(t *testing.T) {
+	tests := []struct {
+		name            string
+		bucketName      string
+		foreign         bool
+		wantProvisioned bool
+		wantReason      string
+	}{
+		{
+			name:            "bucket exists",
+			bucketName:      testBucketName,
+			wantProvisioned: true,
+		},
+		{
+			name:       "bucket not found",
+			bucketName: "missing-bucket",
+			wantReason: "BucketNotFound",
+		},
+		{
+			name:       "bucket of another account",
+			bucketName: testBucketName,
+			foreign:    true,
+			wantReason: "BucketForbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					BucketName: tt.bucketName,
+				},
+			})
+			instance.Status.S3Bucket = veleroCR.S3Bucket{}
+			r := newTestReconciler(t, instance)
+			mockClient := newMockS3Client(testBucketName)
+			var s3Client s3.Client = mockClient
+			if tt.foreign {
+				s3Client = &foreignBucketS3Client{mockClient}
+			}
+
+			if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			for _, mutation := range mockClient.mutations {
+				if mutation == "CreateBucket" {
+					t.Errorf("provisionS3() created the bucket named in the spec")
+				}
+			}
+			status := getTestInstance(t, r).Status
+			if status.S3Bucket.Name != tt.bucketName || status.S3Bucket.Provisioned != tt.wantProvisioned || status.S3Bucket.Created {
+				t.Errorf("status bucket = %+v, want %v provisioned %v and not created", status.S3Bucket, tt.bucketName, tt.wantProvisioned)
+			}
+			condition := status.GetCondition(veleroCR.BucketUnavailable)
+			if tt.wantReason == "" {
+				if condition != nil && condition.Status == corev1.ConditionTrue {
+					t.Errorf("BucketUnavailable condition = %+v, want it not True", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != tt.wantReason {
+				t.Errorf("BucketUnavailable condition = %+v, want status True with reason %v", condition, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestProvisionS3ForbiddenBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	mockClient := newMockS3Client(testBucketName)
+
+	if _, err := r.provisionS3(context.TODO(), log, &foreignBucketS3Client{mockClient}, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if len(mockClient.mutations) != 0 {
+		t.Errorf("provisionS3() made mutations %v to a forbidden bucket, want none", mockClient.mutations)
+	}
+	status := getTestInstance(t, r).Status
+	if status.S3Bucket.Name != testBucketName || !status.S3Bucket.Provisioned {
+		t.Errorf("status bucket = %+v, want %v still provisioned", status.S3Bucket, testBucketName)
+	}
+	condition := status.GetCondition(veleroCR.BucketReady)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != "BucketForbidden" {
+		t.Errorf("BucketReady condition = %+v, want status False with reason BucketForbidden", condition)
+	}
+}
+
+// countingS3Client is a mockS3Client counting the calls discovering and
+// verifying the bucket.
+type countingS3Client struct {
+	*mockS3Client
+
+	listBucketsCalls int
+	headBucketCalls  int
+}
+
+// ListBuckets implements the ListBuckets method for countingS3Client.
+func (c *countingS3Client) ListBuckets(ctx context.Context, input *awss3.ListBucketsInput) (*awss3.ListBucketsOutput, error) {
+	c.listBucketsCalls++
+	return c.mockS3Client.ListBuckets(ctx, input)
+}
+
+// HeadBucket implements the HeadBucket method for countingS3Client.
+func (c *countingS3Client) HeadBucket(ctx context.Context, input *awss3.HeadBucketInput) (*awss3.HeadBucketOutput, error) {
+	c.headBucketCalls++
+	return c.mockS3Client.HeadBucket(ctx, input)
+}
+
+func TestProvisionS3TrustsStatusBucket(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	s3Client := &countingS3Client{mockS3Client: newMockS3Client(testBucketName)}
+
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if s3Client.listBucketsCalls != 0 {
+		t.Errorf("provisionS3() made %d ListBuckets calls, want the bucket in the status to be used", s3Client.listBucketsCalls)
+	}
+	if s3Client.headBucketCalls != 1 {
+		t.Errorf("provisionS3() made %d HeadBucket calls, want 1 verifying the bucket in the status", s3Client.headBucketCalls)
+	}
+	for _, mutation := range s3Client.mutations {
+		if mutation == "CreateBucket" {
+			t.Errorf("provisionS3() created a bucket, want the bucket in the status to be used")
+		}
+	}
+	if status := getTestInstance(t, r).Status.S3Bucket; status.Name != testBucketName || !status.Provisioned {
+		t.Errorf("status bucket = %+v, want %v provisioned", status, testBucketName)
+	}
+}
+
+func TestGeneratePrefixedBucketName(t *testing.T) {
+	random := "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name      string
+		prefix    string
+		infraName string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "short prefix",
+			prefix:    "team-sre",
+			infraName: "cluster-abc12",
+			want:      "team-sre-cluster-abc12-" + random,
+		},
+		{
+			name:      "long prefix truncates the suffix",
+			prefix:    "platform-engineering-backups",
+			infraName: "cluster-abc12",
+			want:      "platform-engineering-backups-cluster-abc12-0123456789abcdef0123",
+		},
+		{
+			name:      "prefix leaving no room for the suffix",
+			prefix:    "platform-engineering-disaster-recovery-backups",
+			infraName: "cluster-abc12",
+			wantErr:   true,
+		},
+		{
+			name:      "prefix with uppercase letters",
+			prefix:    "Team-SRE",
+			infraName: "cluster-abc12",
+			wantErr:   true,
+		},
+		{
+			name:      "prefix with underscores",
+			prefix:    "team_sre",
+			infraName: "cluster-abc12",
+			wantErr:   true,
+		},
+		{
+			name:      "prefix starting with a hyphen",
+			prefix:    "-team",
+			infraName: "cluster-abc12",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generatePrefixedBucketName(tt.prefix, tt.infraName, random, minBucketNameRandomLength)
+			if tt.wantErr {
+				if !errors.Is(err, s3.ErrInvalidBucketName) {
+					t.Errorf("generatePrefixedBucketName() error = %v, want ErrInvalidBucketName", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generatePrefixedBucketName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("generatePrefixedBucketName() = %q, want %q", got, tt.want)
+			}
+			if len(got) > maxBucketNameLength {
+				t.Errorf("generatePrefixedBucketName() = %q is %d characters long, want at most %d", got, len(got), maxBucketNameLength)
+			}
+		})
+	}
+}
+
+func TestProposedBucketNameSuffixLength(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		suffixLength int
+		want         *regexp.Regexp
+		wantErr      bool
+	}{
+		{
+			name: "UUID suffix",
+			want: regexp.MustCompile(`^managed-velero-backups-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+		},
+		{
+			name:         "configured suffix length",
+			suffixLength: 8,
+			want:         regexp.MustCompile(`^managed-velero-backups-[a-z0-9]{8}$`),
+		},
+		{
+			name:         "configured suffix length with a prefix",
+			prefix:       "team-sre",
+			suffixLength: 8,
+			want:         regexp.MustCompile(`^team-sre-cluster-abc12-[a-z0-9]{8}$`),
+		},
+		{
+			name:         "suffix exceeding the name length",
+			suffixLength: maxBucketNameLength - len(bucketPrefix) + 1,
+			wantErr:      true,
+		},
+		{
+			name:         "suffix exceeding the room left by the prefix",
+			prefix:       "platform-engineering-backups",
+			suffixLength: 24,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := storageLocation{spec: veleroCR.BackupStorageLocationSpec{BucketNamePrefix: tt.prefix}}
+			propose := func(seed int64) (string, error) {
+				r := &ReconcileVelero{
+					options: options{bucketNameSuffixLength: tt.suffixLength},
+					random:  rand.New(rand.NewSource(seed)),
+				}
+				return r.proposedBucketName(location, "cluster-abc12")
+			}
+
+			got, err := propose(1)
+			if tt.wantErr {
+				if !errors.Is(err, s3.ErrInvalidBucketName) {
+					t.Errorf("proposedBucketName() = %q, %v, want ErrInvalidBucketName", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("proposedBucketName() error = %v", err)
+			}
+			if !tt.want.MatchString(got) {
+				t.Errorf("proposedBucketName() = %q, want a name matching %v", got, tt.want)
+			}
+			// The name only depends on the entropy source
+			if again, _ := propose(1); again != got {
+				t.Errorf("proposedBucketName() = %q, then %q with the same seed, want a stable name", got, again)
+			}
+			if other, _ := propose(2); other == got {
+				t.Errorf("proposedBucketName() = %q with different seeds, want different names", got)
+			}
+		})
+	}
+}
+
+func TestProvisionS3BucketNameSuffixLengthTooLong(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Status.S3Bucket = veleroCR.S3Bucket{}
+	r := newTestReconciler(t, instance)
+	r.options.bucketNameSuffixLength = maxBucketNameLength
+
+	if _, err := r.provisionS3(context.TODO(), log, newMockS3Client(""), instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	status := getTestInstance(t, r).Status
+	if status.S3Bucket.Name != "" {
+		t.Errorf("status bucket name = %q, want none proposed", status.S3Bucket.Name)
+	}
+	condition := status.GetCondition(veleroCR.InvalidBucketName)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "InvalidBucketNameSuffixLength" {
+		t.Errorf("InvalidBucketName condition = %+v, want status True with reason InvalidBucketNameSuffixLength", condition)
+	}
+}
+
+func TestProvisionS3BucketNamePrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		wantPrefix  string
+		wantInvalid bool
+	}{
+		{
+			name:       "default prefix",
+			wantPrefix: bucketPrefix,
+		},
+		{
+			name:       "custom prefix",
+			prefix:     "team-sre",
+			wantPrefix: "team-sre-cluster-abc12-",
+		},
+		{
+			name:        "invalid prefix",
+			prefix:      "Team_SRE",
+			wantInvalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					BucketNamePrefix: tt.prefix,
+				},
+			})
+			instance.Status.S3Bucket = veleroCR.S3Bucket{}
+			r := newTestReconciler(t, instance)
+
+			// The infrastructure names of OpenShift clusters are lowercase
+			if _, err := r.provisionS3(context.TODO(), log, newMockS3Client(""), instance, "cluster-abc12"); err != nil {
+				t.Fatalf("provisionS3() error = %v", err)
+			}
+			status := getTestInstance(t, r).Status
+			if tt.wantInvalid {
+				if status.S3Bucket.Name != "" {
+					t.Errorf("status bucket name = %q, want none proposed", status.S3Bucket.Name)
+				}
+				if condition := status.GetCondition(veleroCR.InvalidBucketName); condition == nil || condition.Status != corev1.ConditionTrue {
+					t.Errorf("InvalidBucketName condition = %+v, want status True", condition)
+				}
+				return
+			}
+			if !strings.HasPrefix(status.S3Bucket.Name, tt.wantPrefix) {
+				t.Errorf("status bucket name = %q, want prefix %q", status.S3Bucket.Name, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
 
 	velerov1 "github.com/heptio/velero/pkg/apis/velero/v1"
 	veleroInstall "github.com/heptio/velero/pkg/install"
@@ -15,14 +16,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	endpoints "github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-	endpoints "github.com/aws/aws-sdk-go/aws/endpoints"
 )
 
 const (
@@ -33,46 +35,32 @@ const (
 	veleroImageTag               = "velero:v1.1.0"
 	credentialsRequestName       = "velero-iam-credentials"
 	defaultBackupStorageLocation = "default"
+	slaClassKey                  = "velero.io/sla-class"
+	provisionedByOperatorKey     = "velero.io/provisioned-by-operator"
+	clusterIDKey                 = "velero.io/cluster-id"
+	clusterVersionKey            = "velero.io/cluster-version"
+	bucketFrozenAnnotation       = "velero.io/bucket-frozen"
+	bslReadOnlyAnnotation        = "velero.io/bsl-readonly"
+	nodeAgentName                = "restic"
 )
 
 func (r *ReconcileVelero) provisionVelero(reqLogger logr.Logger, namespace string, platformStatus *configv1.PlatformStatus, instance *veleroCR.Velero) (reconcile.Result, error) {
 	var err error
 
-	locationConfig := make(map[string]string)
-	locationConfig["region"] = platformStatus.AWS.Region
+	// Volume snapshots are kept in the cluster's region
+	snapshotConfig := map[string]string{"region": platformStatus.AWS.Region}
 
-	// Install BackupStorageLocation
-	veleroImage := generateVeleroImage(locationConfig["region"])
-	foundBsl := &velerov1.BackupStorageLocation{}
-	bsl := veleroInstall.BackupStorageLocation(namespace, strings.ToLower(string(platformStatus.Type)), instance.Status.S3Bucket.Name, "", locationConfig)
-	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: defaultBackupStorageLocation}, foundBsl); err != nil {
-		if errors.IsNotFound(err) {
-			// Didn't find BackupStorageLocation
-			reqLogger.Info("Creating BackupStorageLocation")
-			if err := controllerutil.SetControllerReference(instance, bsl, r.scheme); err != nil {
-				return reconcile.Result{}, err
-			}
-			if err = r.client.Create(context.TODO(), bsl); err != nil {
-				return reconcile.Result{}, err
-			}
-		} else {
-			return reconcile.Result{}, err
-		}
-	} else {
-		// BackupStorageLocation exists, check if it's updated.
-		if !reflect.DeepEqual(foundBsl.Spec, bsl.Spec) {
-			// Specs aren't equal, update and fix.
-			reqLogger.Info("Updating BackupStorageLocation")
-			foundBsl.Spec = *bsl.Spec.DeepCopy()
-			if err = r.client.Update(context.TODO(), foundBsl); err != nil {
-				return reconcile.Result{}, err
-			}
-		}
+	// Install a BackupStorageLocation for every backup storage location
+	veleroImage := generateVeleroImage(platformStatus.AWS.Region)
+	locations := storageLocations(instance)
+	bslPhase, err := r.reconcileBackupStorageLocations(reqLogger, namespace, platformStatus, instance, locations)
+	if err != nil {
+		return reconcile.Result{}, err
 	}
 
 	// Install VolumeSnapshotLocation
 	foundVsl := &velerov1.VolumeSnapshotLocation{}
-	vsl := veleroInstall.VolumeSnapshotLocation(namespace, strings.ToLower(string(platformStatus.Type)), locationConfig)
+	vsl := veleroInstall.VolumeSnapshotLocation(namespace, strings.ToLower(string(platformStatus.Type)), snapshotConfig)
 	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "default"}, foundVsl); err != nil {
 		if errors.IsNotFound(err) {
 			// Didn't find VolumeSnapshotLocation
@@ -99,12 +87,24 @@ func (r *ReconcileVelero) provisionVelero(reqLogger logr.Logger, namespace strin
 	}
 
 	// Install CredentialsRequest
-	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), locationConfig["region"])
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), platformStatus.AWS.Region)
 	if !ok {
-		return reconcile.Result{}, fmt.Errorf("no partition found for region %q", locationConfig["region"])
+		return reconcile.Result{}, fmt.Errorf("no partition found for region %q", platformStatus.AWS.Region)
+	}
+	// Velero is granted the bucket, and KMS key, of every location
+	var bucketNames, outpostIDs, kmsKeyARNs []string
+	for _, location := range locations {
+		if onOutpost(location.spec) {
+			outpostIDs = append(outpostIDs, location.spec.OutpostID)
+		} else if location.bucket.Name != "" {
+			bucketNames = append(bucketNames, location.bucket.Name)
+		}
+		if keyARN := locationKMSKeyARN(location); keyARN != "" {
+			kmsKeyARNs = append(kmsKeyARNs, keyARN)
+		}
 	}
 	foundCr := &minterv1.CredentialsRequest{}
-	cr := credentialsRequest(namespace, credentialsRequestName, partition.ID(), instance.Status.S3Bucket.Name)
+	cr := credentialsRequest(namespace, credentialsRequestName, partition.ID(), bucketNames, outpostIDs, kmsKeyARNs)
 	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: credentialsRequestName}, foundCr); err != nil {
 		if errors.IsNotFound(err) {
 			// Didn't find CredentialsRequest
@@ -130,9 +130,14 @@ func (r *ReconcileVelero) provisionVelero(reqLogger logr.Logger, namespace strin
 		}
 	}
 
+	// Publish the IAM policy the operator needs
+	if result, err := r.reconcileIAMPolicy(reqLogger, namespace, partition.ID(), instance); err != nil || result.Requeue {
+		return result, err
+	}
+
 	// Install Deployment
 	foundDeployment := &appsv1.Deployment{}
-	deployment := veleroDeployment(namespace, veleroImage)
+	deployment := veleroDeployment(namespace, veleroImage, instance.Spec.Velero)
 	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "velero"}, foundDeployment); err != nil {
 		if errors.IsNotFound(err) {
 			// Didn't find Deployment
@@ -158,48 +163,304 @@ func (r *ReconcileVelero) provisionVelero(reqLogger logr.Logger, namespace strin
 		}
 	}
 
+	// Install node agent DaemonSet
+	if result, err := r.reconcileNodeAgent(reqLogger, namespace, veleroImage, instance); err != nil || result.Requeue {
+		return result, err
+	}
+
+	// Install metrics Service and ServiceMonitor
+	if result, err := r.reconcileMonitoring(reqLogger, namespace, instance); err != nil || result.Requeue {
+		return result, err
+	}
+
+	// Everything is in the desired state for this generation of the spec,
+	// which is recorded so that an unchanged spec can be told apart when
+	// reconciling on changes only
+	generationObserved := instance.Status.ObservedGeneration != instance.Generation
+	instance.Status.ObservedGeneration = instance.Generation
+
+	// Report the health of the BackupStorageLocation, as observed by Velero
+	installed := instance.Status.SetCondition(veleroCR.VeleroInstalled, corev1.ConditionTrue, "Installed", "")
+	if setBackupStorageLocationCondition(instance, bslPhase) || installed {
+		return reconcile.Result{}, r.statusUpdate(reqLogger, instance)
+	}
+	if generationObserved {
+		if err := r.statusUpdate(reqLogger, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Only check again after the reconcile interval. Failures are retried
+	// sooner, with a backoff.
+	return reconcile.Result{RequeueAfter: r.requeueInterval()}, nil
+}
+
+// reconcileNodeAgent keeps the node agent DaemonSet scheduled as configured by
+// spec.nodeAgent, removing it when the node agent isn't configured.
+func (r *ReconcileVelero) reconcileNodeAgent(reqLogger logr.Logger, namespace string, veleroImage string, instance *veleroCR.Velero) (reconcile.Result, error) {
+	var err error
+
+	foundDaemonSet := &appsv1.DaemonSet{}
+	if err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: nodeAgentName}, foundDaemonSet); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		foundDaemonSet = nil
+	}
+
+	if instance.Spec.NodeAgent == nil {
+		if foundDaemonSet != nil {
+			reqLogger.Info("Deleting DaemonSet")
+			if err = r.client.Delete(context.TODO(), foundDaemonSet); err != nil && !errors.IsNotFound(err) {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	daemonSet := nodeAgentDaemonSet(namespace, veleroImage, *instance.Spec.NodeAgent)
+	if foundDaemonSet == nil {
+		// Didn't find DaemonSet
+		reqLogger.Info("Creating DaemonSet")
+		if err := controllerutil.SetControllerReference(instance, daemonSet, r.scheme); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err = r.client.Create(context.TODO(), daemonSet); err != nil {
+			return reconcile.Result{}, err
+		}
+	} else if !reflect.DeepEqual(foundDaemonSet.Spec, daemonSet.Spec) {
+		// Specs aren't equal, update and fix.
+		reqLogger.Info("Updating DaemonSet", "foundDaemonSet.Spec", foundDaemonSet.Spec, "daemonSet.Spec", daemonSet.Spec)
+		foundDaemonSet.Spec = *daemonSet.Spec.DeepCopy()
+		if err = r.client.Update(context.TODO(), foundDaemonSet); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	return reconcile.Result{}, nil
 }
 
-func credentialsRequest(namespace, name, partitionID, bucketName string) *minterv1.CredentialsRequest {
+// reconcileBackupStorageLocations installs a BackupStorageLocation for the
+// default location, and for every other location once its bucket is
+// provisioned, each addressing its bucket in its region. The
+// BackupStorageLocations of removed locations are deleted. The phase of the
+// default BackupStorageLocation is returned.
+func (r *ReconcileVelero) reconcileBackupStorageLocations(reqLogger logr.Logger, namespace string, platformStatus *configv1.PlatformStatus,
+	instance *veleroCR.Velero, locations []storageLocation) (velerov1.BackupStorageLocationPhase, error) {
+	provider := strings.ToLower(string(platformStatus.Type))
+	defaultRegion := bucketRegion(instance, platformStatus.AWS.Region)
+	endpoint := r.s3Endpoint(instance)
+
+	var defaultPhase velerov1.BackupStorageLocationPhase
+	wanted := make(map[string]bool)
+	for _, location := range locations {
+		if location.name != defaultBackupStorageLocation && location.bucket.Name == "" {
+			continue
+		}
+		wanted[location.name] = true
+		bsl := backupStorageLocation(namespace, provider, instance, location, locationRegion(location, defaultRegion), endpoint)
+		phase, err := r.ensureBackupStorageLocation(reqLogger, instance, bsl)
+		if err != nil {
+			return "", err
+		}
+		if location.name == defaultBackupStorageLocation {
+			defaultPhase = phase
+		}
+	}
+
+	bsls := &velerov1.BackupStorageLocationList{}
+	if err := r.client.List(context.TODO(), bsls, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	for i := range bsls.Items {
+		bsl := &bsls.Items[i]
+		if wanted[bsl.Name] || !metav1.IsControlledBy(bsl, instance) {
+			continue
+		}
+		reqLogger.Info("Deleting BackupStorageLocation", "BackupStorageLocation.Name", bsl.Name)
+		if err := r.client.Delete(context.TODO(), bsl); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+	return defaultPhase, nil
+}
+
+// backupStorageLocation returns the BackupStorageLocation of the location,
+// addressing its bucket, or the access point of its bucket on an Outpost, in
+// the region, at the endpoint unless its URL is empty.
+func backupStorageLocation(namespace, provider string, instance *veleroCR.Velero, location storageLocation, region string, endpoint s3.Endpoint) *velerov1.BackupStorageLocation {
+	config := map[string]string{"region": region}
+	if endpoint.URL != "" {
+		config["s3Url"] = endpoint.URL
+		if endpoint.ForcePathStyle {
+			config["s3ForcePathStyle"] = "true"
+		}
+	}
+	// Velero reaches the objects on an Outpost through the access point of the bucket
+	bucket := location.bucket.Name
+	if location.bucket.OutpostAccessPointARN != "" {
+		bucket = location.bucket.OutpostAccessPointARN
+	}
+	bsl := veleroInstall.BackupStorageLocation(namespace, provider, bucket, "", config)
+	bsl.Name = location.name
+	if slaClass := location.spec.SLAClass; slaClass != "" {
+		bsl.Labels[slaClassKey] = string(slaClass)
+	}
+	if bslReadOnly(instance, location.spec) {
+		bsl.Spec.AccessMode = velerov1.BackupStorageLocationAccessModeReadOnly
+	}
+	return bsl
+}
+
+// ensureBackupStorageLocation creates the BackupStorageLocation, or updates
+// it when it differs, and returns its phase as reported by Velero.
+func (r *ReconcileVelero) ensureBackupStorageLocation(reqLogger logr.Logger, instance *veleroCR.Velero, bsl *velerov1.BackupStorageLocation) (velerov1.BackupStorageLocationPhase, error) {
+	bslLog := reqLogger.WithValues("BackupStorageLocation.Name", bsl.Name)
+	foundBsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: bsl.Namespace, Name: bsl.Name}, foundBsl); err != nil {
+		if !errors.IsNotFound(err) {
+			return "", err
+		}
+		// Didn't find BackupStorageLocation
+		bslLog.Info("Creating BackupStorageLocation")
+		if err := controllerutil.SetControllerReference(instance, bsl, r.scheme); err != nil {
+			return "", err
+		}
+		return "", r.client.Create(context.TODO(), bsl)
+	}
+
+	// BackupStorageLocation exists, check if it's updated.
+	if !reflect.DeepEqual(foundBsl.Spec, bsl.Spec) || !reflect.DeepEqual(foundBsl.Labels, bsl.Labels) {
+		// Specs aren't equal, update and fix.
+		bslLog.Info("Updating BackupStorageLocation")
+		foundBsl.Spec = *bsl.Spec.DeepCopy()
+		foundBsl.Labels = bsl.Labels
+		if err := r.client.Update(context.TODO(), foundBsl); err != nil {
+			return "", err
+		}
+	}
+	return foundBsl.Status.Phase, nil
+}
+
+// bslReadOnly checks whether the backup storage location must be read-only,
+// as requested by its access mode or, in an emergency, by annotating the
+// Velero instance.
+func bslReadOnly(instance *veleroCR.Velero, location veleroCR.BackupStorageLocationSpec) bool {
+	return instance.Annotations[bslReadOnlyAnnotation] == "true" ||
+		location.AccessMode == veleroCR.AccessModeReadOnly
+}
+
+// setBackupStorageLocationCondition reflects the phase of the BackupStorageLocation
+// in the BackupStorageLocationAvailable condition, and reports whether it changed.
+func setBackupStorageLocationCondition(instance *veleroCR.Velero, phase velerov1.BackupStorageLocationPhase) bool {
+	switch phase {
+	case velerov1.BackupStorageLocationPhaseAvailable:
+		return instance.Status.SetCondition(veleroCR.BackupStorageLocationAvailable, corev1.ConditionTrue,
+			string(phase), "")
+	case velerov1.BackupStorageLocationPhaseUnavailable:
+		return instance.Status.SetCondition(veleroCR.BackupStorageLocationAvailable, corev1.ConditionFalse,
+			string(phase), "Velero reports the BackupStorageLocation as unavailable")
+	default:
+		return instance.Status.SetCondition(veleroCR.BackupStorageLocationAvailable, corev1.ConditionUnknown,
+			"PhaseUnknown", "Velero has not reported the BackupStorageLocation phase")
+	}
+}
+
+// setStorageOnlyCondition reports in the VeleroInstalled condition that only
+// the storage for backups is managed on the platform, and Velero isn't
+// installed, and reports whether it changed.
+func setStorageOnlyCondition(instance *veleroCR.Velero, platform configv1.PlatformType) bool {
+	return instance.Status.SetCondition(veleroCR.VeleroInstalled, corev1.ConditionFalse, "PlatformUnsupported",
+		fmt.Sprintf("Velero isn't installed on %s, only the storage for backups is managed", platform))
+}
+
+// credentialsRequest returns the CredentialsRequest of the credentials Velero
+// uses, granting it the buckets of every backup storage location, the objects
+// on the Outposts of the locations kept there, and the KMS keys encrypting them.
+func credentialsRequest(namespace, name, partitionID string, bucketNames []string, outpostIDs []string, kmsKeyARNs []string) *minterv1.CredentialsRequest {
+	statementEntries := []minterv1.StatementEntry{
+		{
+			Effect: "Allow",
+			Action: []string{
+				"ec2:DescribeVolumes",
+				"ec2:DescribeSnapshots",
+				"ec2:CreateTags",
+				"ec2:CreateVolume",
+				"ec2:CreateSnapshot",
+				"ec2:DeleteSnapshot",
+			},
+			Resource: "*",
+		},
+	}
+	for _, bucketName := range bucketNames {
+		statementEntries = append(statementEntries,
+			minterv1.StatementEntry{
+				Effect: "Allow",
+				Action: []string{
+					"s3:GetObject",
+					"s3:DeleteObject",
+					"s3:PutObject",
+					"s3:AbortMultipartUpload",
+					"s3:ListMultipartUploadParts",
+				},
+				Resource: fmt.Sprintf("arn:%s:s3:::%s/*", partitionID, bucketName),
+			},
+			minterv1.StatementEntry{
+				Effect: "Allow",
+				Action: []string{
+					"s3:ListBucket",
+				},
+				Resource: fmt.Sprintf("arn:%s:s3:::%s", partitionID, bucketName),
+			},
+		)
+	}
+
+	// The objects on an Outpost are reached through the access point of their bucket
+	outposts := make(map[string]bool)
+	for _, outpostID := range outpostIDs {
+		if outposts[outpostID] {
+			continue
+		}
+		outposts[outpostID] = true
+		statementEntries = append(statementEntries, minterv1.StatementEntry{
+			Effect: "Allow",
+			Action: []string{
+				"s3-outposts:GetObject",
+				"s3-outposts:DeleteObject",
+				"s3-outposts:PutObject",
+				"s3-outposts:AbortMultipartUpload",
+				"s3-outposts:ListMultipartUploadParts",
+				"s3-outposts:ListBucket",
+			},
+			Resource: outpostResourceARN(partitionID, outpostID),
+		})
+	}
+
+	// Velero needs to be able to use the KMS keys that encrypt the bucket contents
+	granted := make(map[string]bool)
+	for _, kmsKeyARN := range kmsKeyARNs {
+		if granted[kmsKeyARN] {
+			continue
+		}
+		granted[kmsKeyARN] = true
+		statementEntries = append(statementEntries, minterv1.StatementEntry{
+			Effect: "Allow",
+			Action: []string{
+				"kms:Decrypt",
+				"kms:Encrypt",
+				"kms:GenerateDataKey",
+			},
+			Resource: kmsKeyARN,
+		})
+	}
+
 	codec, _ := minterv1.NewCodec()
 	awsProvSpec, _ := codec.EncodeProviderSpec(
 		&minterv1.AWSProviderSpec{
 			TypeMeta: metav1.TypeMeta{
 				Kind: "AWSProviderSpec",
 			},
-			StatementEntries: []minterv1.StatementEntry{
-				{
-					Effect: "Allow",
-					Action: []string{
-						"ec2:DescribeVolumes",
-						"ec2:DescribeSnapshots",
-						"ec2:CreateTags",
-						"ec2:CreateVolume",
-						"ec2:CreateSnapshot",
-						"ec2:DeleteSnapshot",
-					},
-					Resource: "*",
-				},
-				{
-					Effect: "Allow",
-					Action: []string{
-						"s3:GetObject",
-						"s3:DeleteObject",
-						"s3:PutObject",
-						"s3:AbortMultipartUpload",
-						"s3:ListMultipartUploadParts",
-					},
-					Resource: fmt.Sprintf("arn:%s:s3:::%s/*", partitionID, bucketName),
-				},
-				{
-					Effect: "Allow",
-					Action: []string{
-						"s3:ListBucket",
-					},
-					Resource: fmt.Sprintf("arn:%s:s3:::%s", partitionID, bucketName),
-				},
-			},
+			StatementEntries: statementEntries,
 		})
 
 	return &minterv1.CredentialsRequest{
@@ -221,7 +482,7 @@ func credentialsRequest(namespace, name, partitionID, bucketName string) *minter
 	}
 }
 
-func veleroDeployment(namespace string, veleroImage string) *appsv1.Deployment {
+func veleroDeployment(namespace string, veleroImage string, serverSpec veleroCR.VeleroServerSpec) *appsv1.Deployment {
 	deployment := veleroInstall.Deployment(namespace,
 		veleroInstall.WithEnvFromSecretKey(strings.ToUpper(awsCredsSecretIDKey), credentialsRequestName, awsCredsSecretIDKey),
 		veleroInstall.WithEnvFromSecretKey(strings.ToUpper(awsCredsSecretAccessKey), credentialsRequestName, awsCredsSecretAccessKey),
@@ -291,9 +552,55 @@ func veleroDeployment(namespace string, veleroImage string) *appsv1.Deployment {
 		},
 	}
 
+	// The backup sync period applies to every BackupStorageLocation, as the
+	// BackupStorageLocation can't configure it in this version of Velero
+	if serverSpec.BackupSyncPeriod != nil {
+		deployment.Spec.Template.Spec.Containers[0].Args = append(deployment.Spec.Template.Spec.Containers[0].Args,
+			fmt.Sprintf("--backup-sync-period=%v", serverSpec.BackupSyncPeriod.Duration))
+	}
+
+	// Backups created without a TTL, such as ad-hoc backups, use the default backup TTL
+	if serverSpec.DefaultBackupTTL != nil {
+		deployment.Spec.Template.Spec.Containers[0].Args = append(deployment.Spec.Template.Spec.Containers[0].Args,
+			fmt.Sprintf("--default-backup-ttl=%v", serverSpec.DefaultBackupTTL.Duration))
+	}
+
+	// Plugins are installed by copying them into the shared plugins volume
+	for _, plugin := range serverSpec.Plugins {
+		deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, corev1.Container{
+			Name:            pluginContainerName(plugin),
+			Image:           plugin,
+			ImagePullPolicy: corev1.PullAlways,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "plugins",
+					MountPath: "/target",
+				},
+			},
+			TerminationMessagePath:   "/dev/termination-log",
+			TerminationMessagePolicy: "File",
+		})
+	}
+
 	return deployment
 }
 
+// pluginContainerName returns the init container name for a plugin image,
+// which is the image name without its registry, tag or digest.
+func pluginContainerName(image string) string {
+	name := image
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.NewReplacer(".", "-", "_", "-").Replace(strings.ToLower(name))
+}
+
 func generateVeleroImage(region string) string {
 	cnRegion := []string{"cn-north-1", "cn-northwest-1"}
 
@@ -310,3 +617,17 @@ func generateVeleroImage(region string) string {
 
 	return veleroImage
 }
+
+func nodeAgentDaemonSet(namespace string, veleroImage string, nodeAgent veleroCR.NodeAgentSpec) *appsv1.DaemonSet {
+	daemonSet := veleroInstall.DaemonSet(namespace,
+		veleroInstall.WithEnvFromSecretKey(strings.ToUpper(awsCredsSecretIDKey), credentialsRequestName, awsCredsSecretIDKey),
+		veleroInstall.WithEnvFromSecretKey(strings.ToUpper(awsCredsSecretAccessKey), credentialsRequestName, awsCredsSecretAccessKey),
+		veleroInstall.WithImage(veleroImage),
+	)
+
+	scheduling := nodeAgent.DeepCopy()
+	daemonSet.Spec.Template.Spec.Tolerations = scheduling.Tolerations
+	daemonSet.Spec.Template.Spec.NodeSelector = scheduling.NodeSelector
+
+	return daemonSet
+}
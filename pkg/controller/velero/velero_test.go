@@ -0,0 +1,681 @@
+package velero
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/managed-velero-operator/pkg/apis"
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/kms"
+
+	"github.com/aws/aws-sdk-go/aws"
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	velerov1 "github.com/heptio/velero/pkg/apis/velero/v1"
+	configv1 "github.com/openshift/api/config/v1"
+	minterv1 "github.com/openshift/cloud-credential-operator/pkg/apis/cloudcredential/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	testNamespace  = "openshift-velero"
+	testBucketName = "managed-velero-backups-test"
+	testRegion     = "us-east-1"
+)
+
+var testPlatformStatus = &configv1.PlatformStatus{
+	Type: configv1.AWSPlatformType,
+	AWS: &configv1.AWSPlatformStatus{
+		Region: testRegion,
+	},
+}
+
+// newTestReconciler returns a ReconcileVelero backed by a fake client
+// that is pre-loaded with the given objects.
+func newTestReconciler(t *testing.T, objs ...runtime.Object) *ReconcileVelero {
+	s := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		apis.AddToScheme,
+		velerov1.SchemeBuilder.AddToScheme,
+		minterv1.AddToScheme,
+		appsv1.AddToScheme,
+		corev1.AddToScheme,
+		monitoringv1.AddToScheme,
+		configv1.Install,
+	} {
+		if err := addToScheme(s); err != nil {
+			t.Fatalf("unable to build scheme: %v", err)
+		}
+	}
+	return &ReconcileVelero{
+		client: fake.NewFakeClientWithScheme(s, objs...),
+		scheme: s,
+	}
+}
+
+// newTestInstance returns a Velero instance with a provisioned bucket.
+func newTestInstance(spec veleroCR.VeleroSpec) *veleroCR.Velero {
+	return &veleroCR.Velero{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster",
+			Namespace: testNamespace,
+		},
+		Spec: spec,
+		Status: veleroCR.VeleroStatus{
+			S3Bucket: veleroCR.S3Bucket{
+				Name:        testBucketName,
+				Provisioned: true,
+			},
+		},
+	}
+}
+
+func TestProvisionVeleroSLAClassLabel(t *testing.T) {
+	tests := []struct {
+		name      string
+		slaClass  veleroCR.SLAClass
+		wantLabel bool
+	}{
+		{
+			name:      "no sla class",
+			slaClass:  "",
+			wantLabel: false,
+		},
+		{
+			name:      "gold sla class",
+			slaClass:  veleroCR.SLAClassGold,
+			wantLabel: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestInstance(veleroCR.VeleroSpec{
+				BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+					SLAClass: tt.slaClass,
+				},
+			})
+			r := newTestReconciler(t, instance)
+			if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+				t.Fatalf("provisionVelero() error = %v", err)
+			}
+
+			bsl := &velerov1.BackupStorageLocation{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+				t.Fatalf("unable to get BackupStorageLocation: %v", err)
+			}
+			label, ok := bsl.Labels[slaClassKey]
+			if ok != tt.wantLabel || label != string(tt.slaClass) {
+				t.Errorf("BackupStorageLocation label %v = %q, want %q", slaClassKey, label, tt.slaClass)
+			}
+		})
+	}
+}
+
+func TestProvisionVeleroRequeuesAfterReconcileInterval(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	r.options.reconcileInterval = 10 * time.Minute
+
+	// The first pass installs Velero, and reports the BackupStorageLocation phase
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	result, err := r.provisionVelero(log, testNamespace, testPlatformStatus, getTestInstance(t, r))
+	if err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	if want := (reconcile.Result{RequeueAfter: 10 * time.Minute}); result != want {
+		t.Errorf("provisionVelero() = %+v in the desired state, want %+v", result, want)
+	}
+}
+
+func TestProvisionVeleroUpdatesSLAClassLabel(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			SLAClass: veleroCR.SLAClassBronze,
+		},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	instance.Spec.BackupStorageLocation.SLAClass = veleroCR.SLAClassSilver
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	bsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+		t.Fatalf("unable to get BackupStorageLocation: %v", err)
+	}
+	if got := bsl.Labels[slaClassKey]; got != string(veleroCR.SLAClassSilver) {
+		t.Errorf("BackupStorageLocation label %v = %q, want %q", slaClassKey, got, veleroCR.SLAClassSilver)
+	}
+}
+
+func TestProvisionVeleroStorageLocations(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{
+			{},
+			{Name: "secondary", Region: "eu-west-1"},
+		},
+	})
+	instance.Status.AdditionalS3Buckets = []veleroCR.LocationS3Bucket{
+		{Location: "secondary", S3Bucket: veleroCR.S3Bucket{Name: "secondary-bucket", Provisioned: true}},
+	}
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	for _, want := range []struct{ name, bucket, region string }{
+		{defaultBackupStorageLocation, testBucketName, testRegion},
+		{"secondary", "secondary-bucket", "eu-west-1"},
+	} {
+		bsl := &velerov1.BackupStorageLocation{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: want.name}, bsl); err != nil {
+			t.Fatalf("unable to get BackupStorageLocation %v: %v", want.name, err)
+		}
+		if bsl.Spec.ObjectStorage.Bucket != want.bucket || bsl.Spec.Config["region"] != want.region {
+			t.Errorf("BackupStorageLocation %v stores into %v in %v, want %v in %v", want.name,
+				bsl.Spec.ObjectStorage.Bucket, bsl.Spec.Config["region"], want.bucket, want.region)
+		}
+	}
+
+	cr := &minterv1.CredentialsRequest{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: credentialsRequestName}, cr); err != nil {
+		t.Fatalf("unable to get CredentialsRequest: %v", err)
+	}
+	codec, _ := minterv1.NewCodec()
+	spec := &minterv1.AWSProviderSpec{}
+	if err := codec.DecodeProviderSpec(cr.Spec.ProviderSpec, spec); err != nil {
+		t.Fatalf("unable to decode provider spec: %v", err)
+	}
+	granted := make(map[string]bool)
+	for _, statement := range spec.StatementEntries {
+		granted[statement.Resource] = true
+	}
+	for _, bucketARN := range []string{"arn:aws:s3:::" + testBucketName, "arn:aws:s3:::secondary-bucket"} {
+		if !granted[bucketARN] {
+			t.Errorf("CredentialsRequest doesn't grant %v", bucketARN)
+		}
+	}
+
+	// Removing the location deletes its BackupStorageLocation
+	instance = getTestInstance(t, r)
+	instance.Spec.BackupStorageLocations = instance.Spec.BackupStorageLocations[:1]
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	bsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "secondary"}, bsl); !errors.IsNotFound(err) {
+		t.Errorf("BackupStorageLocation of the removed location wasn't deleted: %v", err)
+	}
+}
+
+func TestBucketTags(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			SLAClass: veleroCR.SLAClassGold,
+		},
+	})
+	tags := bucketTags(instance, defaultLocation(instance), "")
+	if got := tags[slaClassKey]; got != string(veleroCR.SLAClassGold) {
+		t.Errorf("bucketTags()[%v] = %q, want %q", slaClassKey, got, veleroCR.SLAClassGold)
+	}
+
+	untagged := newTestInstance(veleroCR.VeleroSpec{})
+	if tags := bucketTags(untagged, defaultLocation(untagged), ""); len(tags) != 0 {
+		t.Errorf("bucketTags() = %v, want no tags", tags)
+	}
+
+	// The additional tags can't override the operator's tags
+	instance.Spec.BackupStorageLocation.AdditionalTags = map[string]string{
+		"cost-center": "1234",
+		slaClassKey:   "platinum",
+	}
+	tags = bucketTags(instance, defaultLocation(instance), "")
+	if got := tags["cost-center"]; got != "1234" {
+		t.Errorf("bucketTags()[cost-center] = %q, want %q", got, "1234")
+	}
+	if got := tags[slaClassKey]; got != string(veleroCR.SLAClassGold) {
+		t.Errorf("bucketTags()[%v] = %q, want %q", slaClassKey, got, veleroCR.SLAClassGold)
+	}
+}
+
+func TestProvisionVeleroPlugins(t *testing.T) {
+	plugins := []string{
+		"velero/velero-plugin-for-aws:v1.0.0",
+		"quay.io/example/velero-plugin-example@sha256:0123456789abcdef",
+	}
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		Velero: veleroCR.VeleroServerSpec{
+			Plugins: plugins,
+		},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	assertPlugins := func(want []string) {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "velero"}, deployment); err != nil {
+			t.Fatalf("unable to get Deployment: %v", err)
+		}
+		initContainers := deployment.Spec.Template.Spec.InitContainers
+		if len(initContainers) != len(want) {
+			t.Fatalf("Deployment has %d init containers, want %d", len(initContainers), len(want))
+		}
+		for i, image := range want {
+			if initContainers[i].Image != image {
+				t.Errorf("init container %d image = %v, want %v", i, initContainers[i].Image, image)
+			}
+		}
+	}
+	assertPlugins(plugins)
+
+	// Changing the plugins updates the Deployment
+	instance.Spec.Velero.Plugins = []string{"velero/velero-plugin-for-aws:v1.1.0"}
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	assertPlugins(instance.Spec.Velero.Plugins)
+}
+
+func TestPluginContainerName(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "velero/velero-plugin-for-aws:v1.0.0", want: "velero-plugin-for-aws"},
+		{image: "registry.example.com:5000/velero/velero_plugin.example:v1", want: "velero-plugin-example"},
+		{image: "quay.io/example/plugin@sha256:0123456789abcdef", want: "plugin"},
+		{image: "plugin", want: "plugin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := pluginContainerName(tt.image); got != tt.want {
+				t.Errorf("pluginContainerName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvisionVeleroBackupSyncPeriod(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		Velero: veleroCR.VeleroServerSpec{
+			BackupSyncPeriod: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+	})
+	r := newTestReconciler(t, instance)
+
+	assertSyncPeriodArg := func(want string) {
+		t.Helper()
+		if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "velero"}, deployment); err != nil {
+			t.Fatalf("unable to get Deployment: %v", err)
+		}
+		var got string
+		for _, arg := range deployment.Spec.Template.Spec.Containers[0].Args {
+			if strings.HasPrefix(arg, "--backup-sync-period=") {
+				got = arg
+			}
+		}
+		if got != want {
+			t.Errorf("Deployment backup sync period argument = %q, want %q", got, want)
+		}
+	}
+	assertSyncPeriodArg("--backup-sync-period=5m0s")
+
+	// Changing the period updates the Deployment
+	instance.Spec.Velero.BackupSyncPeriod = &metav1.Duration{Duration: time.Hour}
+	assertSyncPeriodArg("--backup-sync-period=1h0m0s")
+
+	// Without a period, Velero's default is used
+	instance.Spec.Velero.BackupSyncPeriod = nil
+	assertSyncPeriodArg("")
+}
+
+func TestProvisionVeleroBackupStorageLocationUnavailable(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	stored := getTestInstance(t, r)
+	if condition := stored.Status.GetCondition(veleroCR.BackupStorageLocationAvailable); condition == nil || condition.Status != corev1.ConditionUnknown {
+		t.Errorf("BackupStorageLocationAvailable condition = %+v, want status %v", condition, corev1.ConditionUnknown)
+	}
+	if condition := stored.Status.GetCondition(veleroCR.VeleroInstalled); condition == nil || condition.Status != corev1.ConditionTrue {
+		t.Errorf("VeleroInstalled condition = %+v, want status %v", condition, corev1.ConditionTrue)
+	}
+
+	// Velero marks the BackupStorageLocation as unavailable
+	bsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+		t.Fatalf("unable to get BackupStorageLocation: %v", err)
+	}
+	bsl.Status.Phase = velerov1.BackupStorageLocationPhaseUnavailable
+	if err := r.client.Update(context.TODO(), bsl); err != nil {
+		t.Fatalf("unable to update BackupStorageLocation: %v", err)
+	}
+
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, stored); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	condition := getTestInstance(t, r).Status.GetCondition(veleroCR.BackupStorageLocationAvailable)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != string(velerov1.BackupStorageLocationPhaseUnavailable) {
+		t.Errorf("BackupStorageLocationAvailable condition = %+v, want status %v with reason %v",
+			condition, corev1.ConditionFalse, velerov1.BackupStorageLocationPhaseUnavailable)
+	}
+}
+
+func TestProvisionVeleroDefaultBackupTTL(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		Velero: veleroCR.VeleroServerSpec{
+			DefaultBackupTTL: &metav1.Duration{Duration: 720 * time.Hour},
+		},
+	})
+	r := newTestReconciler(t, instance)
+
+	assertTTLArg := func(want string) {
+		t.Helper()
+		if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "velero"}, deployment); err != nil {
+			t.Fatalf("unable to get Deployment: %v", err)
+		}
+		var got string
+		for _, arg := range deployment.Spec.Template.Spec.Containers[0].Args {
+			if strings.HasPrefix(arg, "--default-backup-ttl=") {
+				got = arg
+			}
+		}
+		if got != want {
+			t.Errorf("Deployment default backup TTL argument = %q, want %q", got, want)
+		}
+	}
+	assertTTLArg("--default-backup-ttl=720h0m0s")
+
+	// Changing the TTL updates the Deployment
+	instance.Spec.Velero.DefaultBackupTTL = &metav1.Duration{Duration: 168 * time.Hour}
+	assertTTLArg("--default-backup-ttl=168h0m0s")
+
+	// Without a TTL, Velero's default is used
+	instance.Spec.Velero.DefaultBackupTTL = nil
+	assertTTLArg("")
+}
+
+func TestProvisionVeleroNodeAgentScheduling(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		NodeAgent: &veleroCR.NodeAgentSpec{
+			Tolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+			},
+			NodeSelector: map[string]string{"node-role.kubernetes.io/worker": ""},
+		},
+	})
+	r := newTestReconciler(t, instance)
+
+	assertScheduling := func(want veleroCR.NodeAgentSpec) {
+		t.Helper()
+		if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+		daemonSet := &appsv1.DaemonSet{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: nodeAgentName}, daemonSet); err != nil {
+			t.Fatalf("unable to get DaemonSet: %v", err)
+		}
+		podSpec := daemonSet.Spec.Template.Spec
+		if !reflect.DeepEqual(podSpec.Tolerations, want.Tolerations) {
+			t.Errorf("DaemonSet tolerations = %v, want %v", podSpec.Tolerations, want.Tolerations)
+		}
+		if !reflect.DeepEqual(podSpec.NodeSelector, want.NodeSelector) {
+			t.Errorf("DaemonSet node selector = %v, want %v", podSpec.NodeSelector, want.NodeSelector)
+		}
+	}
+	assertScheduling(*instance.Spec.NodeAgent)
+
+	// Changing the scheduling updates the DaemonSet
+	instance.Spec.NodeAgent = &veleroCR.NodeAgentSpec{
+		Tolerations: []corev1.Toleration{
+			{
+				Key:      "dedicated",
+				Operator: corev1.TolerationOpEqual,
+				Value:    "backup",
+				Effect:   corev1.TaintEffectNoExecute,
+			},
+		},
+		NodeSelector: map[string]string{"dedicated": "backup"},
+	}
+	assertScheduling(*instance.Spec.NodeAgent)
+
+	// Without a node agent spec, the DaemonSet is removed
+	instance.Spec.NodeAgent = nil
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: nodeAgentName}, &appsv1.DaemonSet{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("unable to confirm DaemonSet was removed: %v", err)
+	}
+}
+
+func TestProvisionVeleroMonitoring(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		Velero: veleroCR.VeleroServerSpec{
+			Monitoring: veleroCR.MonitoringSpec{Enabled: true},
+		},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	name := types.NamespacedName{Namespace: testNamespace, Name: veleroMetricsName}
+	service := &corev1.Service{}
+	if err := r.client.Get(context.TODO(), name, service); err != nil {
+		t.Fatalf("unable to get metrics Service: %v", err)
+	}
+	if len(service.Spec.Ports) != 1 || service.Spec.Ports[0].Name != veleroMetricsPortName || service.Spec.Ports[0].Port != veleroMetricsPort {
+		t.Errorf("metrics Service ports = %+v, want port %v on %d", service.Spec.Ports, veleroMetricsPortName, veleroMetricsPort)
+	}
+	serviceMonitor := &monitoringv1.ServiceMonitor{}
+	if err := r.client.Get(context.TODO(), name, serviceMonitor); err != nil {
+		t.Fatalf("unable to get ServiceMonitor: %v", err)
+	}
+	wantEndpoints := []monitoringv1.Endpoint{{Port: veleroMetricsPortName, Path: veleroMetricsPath}}
+	if !reflect.DeepEqual(serviceMonitor.Spec.Endpoints, wantEndpoints) {
+		t.Errorf("ServiceMonitor endpoints = %+v, want %+v", serviceMonitor.Spec.Endpoints, wantEndpoints)
+	}
+	if !reflect.DeepEqual(serviceMonitor.Spec.Selector.MatchLabels, service.Labels) {
+		t.Errorf("ServiceMonitor selector = %v, doesn't select the metrics Service labelled %v", serviceMonitor.Spec.Selector.MatchLabels, service.Labels)
+	}
+	for _, owners := range [][]metav1.OwnerReference{service.OwnerReferences, serviceMonitor.OwnerReferences} {
+		if len(owners) != 1 || owners[0].Name != instance.Name || owners[0].Controller == nil || !*owners[0].Controller {
+			t.Errorf("owner references = %+v, want the Velero instance as controller", owners)
+		}
+	}
+
+	// Disabling monitoring removes the ServiceMonitor and Service
+	instance.Spec.Velero.Monitoring.Enabled = false
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+	if err := r.client.Get(context.TODO(), name, &monitoringv1.ServiceMonitor{}); !errors.IsNotFound(err) {
+		t.Errorf("unable to confirm ServiceMonitor was removed: %v", err)
+	}
+	if err := r.client.Get(context.TODO(), name, &corev1.Service{}); !errors.IsNotFound(err) {
+		t.Errorf("unable to confirm metrics Service was removed: %v", err)
+	}
+}
+
+func TestLegacyStorageLocationReconcilesIdentically(t *testing.T) {
+	location := veleroCR.BackupStorageLocationSpec{
+		SLAClass: veleroCR.SLAClassGold,
+		Encryption: veleroCR.EncryptionSpec{
+			Type:     veleroCR.EncryptionTypeKMS,
+			KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/a",
+		},
+	}
+
+	reconcileSpec := func(spec veleroCR.VeleroSpec) (*mockS3Client, *velerov1.BackupStorageLocation) {
+		t.Helper()
+		instance := newTestInstance(spec)
+		r := newTestReconciler(t, instance)
+		r.newKMSClient = func(*aws.Config) (kms.Client, error) { return &mockKMSClient{}, nil }
+		s3Client := newMockS3Client(testBucketName)
+		if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+			t.Fatalf("provisionS3() error = %v", err)
+		}
+		if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, getTestInstance(t, r)); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+		bsl := &velerov1.BackupStorageLocation{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+			t.Fatalf("unable to get BackupStorageLocation: %v", err)
+		}
+		return s3Client, bsl
+	}
+
+	legacyClient, legacyBsl := reconcileSpec(veleroCR.VeleroSpec{BackupStorageLocation: location})
+	listClient, listBsl := reconcileSpec(veleroCR.VeleroSpec{BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{location}})
+
+	if !reflect.DeepEqual(legacyClient.encryption, listClient.encryption) {
+		t.Errorf("bucket encryption = %v for the singular form, want %v", legacyClient.encryption, listClient.encryption)
+	}
+	if !reflect.DeepEqual(legacyClient.tagMap(), listClient.tagMap()) {
+		t.Errorf("bucket tags = %v for the singular form, want %v", legacyClient.tagMap(), listClient.tagMap())
+	}
+	if !reflect.DeepEqual(legacyBsl.Labels, listBsl.Labels) || !reflect.DeepEqual(legacyBsl.Spec, listBsl.Spec) {
+		t.Errorf("BackupStorageLocation = %+v for the singular form, want %+v", legacyBsl, listBsl)
+	}
+}
+
+func TestBackupStorageLocationReadOnly(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{})
+	instance.Annotations = map[string]string{bslReadOnlyAnnotation: "true"}
+	r := newTestReconciler(t, instance)
+	s3Client := newMockS3Client(testBucketName)
+
+	assertAccessMode := func(want velerov1.BackupStorageLocationAccessMode) {
+		t.Helper()
+		if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+			t.Fatalf("provisionVelero() error = %v", err)
+		}
+		bsl := &velerov1.BackupStorageLocation{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+			t.Fatalf("unable to get BackupStorageLocation: %v", err)
+		}
+		if bsl.Spec.AccessMode != want {
+			t.Errorf("BackupStorageLocation access mode = %q, want %q", bsl.Spec.AccessMode, want)
+		}
+	}
+
+	// The annotation denies writes to the bucket, and makes the BSL read-only
+	if _, err := r.provisionS3(context.TODO(), log, s3Client, instance, testInfraName); err != nil {
+		t.Fatalf("provisionS3() error = %v", err)
+	}
+	if !strings.Contains(aws.StringValue(s3Client.policy), `"Sid":"DenyWritesReadOnly","Effect":"Deny","Principal":"*","Action":["s3:PutObject","s3:DeleteObject"]`) {
+		t.Errorf("bucket policy = %v, want it to deny writes", aws.StringValue(s3Client.policy))
+	}
+	if len(s3Client.writtenKeys) != 0 {
+		t.Errorf("provisionS3() wrote %v to the read-only bucket", s3Client.writtenKeys)
+	}
+	status := getTestInstance(t, r).Status
+	if !status.S3Bucket.ReadOnly {
+		t.Errorf("status readOnly = false, want true")
+	}
+	if condition := status.GetCondition(veleroCR.BucketWritable); condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != "ReadOnly" {
+		t.Errorf("BucketWritable condition = %+v, want it to report the read-only bucket", condition)
+	}
+	assertAccessMode(velerov1.BackupStorageLocationAccessModeReadOnly)
+
+	// The spec access mode has the same effect as the annotation
+	instance.Annotations = nil
+	instance.Spec.BackupStorageLocation.AccessMode = veleroCR.AccessModeReadOnly
+	assertAccessMode(velerov1.BackupStorageLocationAccessModeReadOnly)
+
+	// Making the BSL writable again removes the deny statement
+	instance.Spec.BackupStorageLocation.AccessMode = veleroCR.AccessModeReadWrite
+	if err := setReadOnlyPolicy(context.TODO(), log, s3Client, instance, defaultLocation(instance)); err != nil {
+		t.Fatalf("setReadOnlyPolicy() error = %v", err)
+	}
+	if s3Client.policy != nil {
+		t.Errorf("bucket policy = %v, want it to be removed", aws.StringValue(s3Client.policy))
+	}
+	if instance.Status.S3Bucket.ReadOnly {
+		t.Errorf("status readOnly = true, want false")
+	}
+	assertAccessMode("")
+}
+
+func TestProvisionVeleroEndpoint(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocation: veleroCR.BackupStorageLocationSpec{
+			Endpoint:         "https://minio.example.com:9000",
+			S3ForcePathStyle: true,
+		},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	bsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+		t.Fatalf("unable to get BackupStorageLocation: %v", err)
+	}
+	if got := bsl.Spec.Config["s3Url"]; got != "https://minio.example.com:9000" {
+		t.Errorf("BackupStorageLocation s3Url = %q, want %q", got, "https://minio.example.com:9000")
+	}
+	if got := bsl.Spec.Config["s3ForcePathStyle"]; got != "true" {
+		t.Errorf("BackupStorageLocation s3ForcePathStyle = %q, want %q", got, "true")
+	}
+}
+
+func TestProvisionVeleroBucketRegion(t *testing.T) {
+	instance := newTestInstance(veleroCR.VeleroSpec{
+		BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{{Region: "us-west-2"}},
+	})
+	r := newTestReconciler(t, instance)
+	if _, err := r.provisionVelero(log, testNamespace, testPlatformStatus, instance); err != nil {
+		t.Fatalf("provisionVelero() error = %v", err)
+	}
+
+	bsl := &velerov1.BackupStorageLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: defaultBackupStorageLocation}, bsl); err != nil {
+		t.Fatalf("unable to get BackupStorageLocation: %v", err)
+	}
+	if got := bsl.Spec.Config["region"]; got != "us-west-2" {
+		t.Errorf("BackupStorageLocation region = %q, want the bucket region us-west-2", got)
+	}
+	vsl := &velerov1.VolumeSnapshotLocation{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "default"}, vsl); err != nil {
+		t.Fatalf("unable to get VolumeSnapshotLocation: %v", err)
+	}
+	if got := vsl.Spec.Config["region"]; got != testPlatformStatus.AWS.Region {
+		t.Errorf("VolumeSnapshotLocation region = %q, want the cluster region %v", got, testPlatformStatus.AWS.Region)
+	}
+}
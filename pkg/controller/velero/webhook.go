@@ -0,0 +1,81 @@
+package velero
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// validatingWebhookPath is the path the validating admission webhook of the
+// Velero instances is served on.
+const validatingWebhookPath = "/validate-managed-openshift-io-v1alpha1-velero"
+
+// specValidator is an admission handler rejecting the Velero instances whose
+// spec can't be reconciled, so that the mistake is reported when the instance
+// is created or updated, rather than in the logs of the operator.
+type specValidator struct {
+	decoder *admission.Decoder
+	options options
+}
+
+// newValidatingWebhook returns the validating admission webhook of the Velero
+// instances.
+func newValidatingWebhook(opts options) *admission.Webhook {
+	return &admission.Webhook{Handler: &specValidator{options: opts}}
+}
+
+// InjectDecoder implements admission.DecoderInjector for specValidator.
+func (v *specValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler for specValidator. Deleting an instance
+// is always allowed, so that an invalid instance can be removed.
+func (v *specValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1beta1.Delete {
+		return admission.Allowed("")
+	}
+	instance := &veleroCR.Velero{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := v.validate(instance); err != nil {
+		return admission.Denied(fmt.Sprintf("invalid Velero spec: %v", err))
+	}
+	return admission.Allowed("")
+}
+
+// validate checks the spec like the reconcile does before acting on it, and
+// that the regions set on the backup storage locations are known AWS regions.
+// As in checkRegion, the regions aren't checked when a custom S3 endpoint is
+// used.
+func (v *specValidator) validate(instance *veleroCR.Velero) error {
+	if err := instance.Spec.Validate(); err != nil {
+		return err
+	}
+	if instance.Spec.DefaultStorageLocation().Endpoint != "" || v.options.s3Endpoint != "" {
+		return nil
+	}
+	for i, location := range instance.Spec.StorageLocations() {
+		if location.Region == "" {
+			continue
+		}
+		if err := s3.ValidateRegion(location.Region); err != nil {
+			return fmt.Errorf("invalid region of backup storage location %v: %v", veleroCR.StorageLocationName(i, location), err)
+		}
+	}
+	return nil
+}
+
+// ValidateSpec checks the spec of the Velero instance like the validating
+// webhook does, with the options set by the command line flags.
+func ValidateSpec(instance *veleroCR.Velero) error {
+	return (&specValidator{options: flagOptions}).validate(instance)
+}
@@ -0,0 +1,104 @@
+package velero
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	veleroCR "github.com/openshift/managed-velero-operator/pkg/apis/managed/v1alpha1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// newTestAdmissionRequest returns an admission request of the operation on the instance.
+func newTestAdmissionRequest(t *testing.T, operation admissionv1beta1.Operation, instance *veleroCR.Velero) admission.Request {
+	instance.APIVersion = veleroCR.SchemeGroupVersion.String()
+	instance.Kind = "Velero"
+	raw, err := json.Marshal(instance)
+	if err != nil {
+		t.Fatalf("unable to encode instance: %v", err)
+	}
+	return admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestSpecValidator(t *testing.T) {
+	tests := []struct {
+		name        string
+		location    veleroCR.BackupStorageLocationSpec
+		s3Endpoint  string
+		wantAllowed bool
+	}{
+		{
+			name: "valid spec",
+			location: veleroCR.BackupStorageLocationSpec{
+				Region:        "eu-west-1",
+				BucketName:    "reviewed-backups",
+				LifecycleDays: 30,
+				Encryption:    veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: "alias/velero"},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:     "unknown region",
+			location: veleroCR.BackupStorageLocationSpec{Region: "eu-middle-9"},
+		},
+		{
+			name:        "region of a custom endpoint",
+			location:    veleroCR.BackupStorageLocationSpec{Region: "minio"},
+			s3Endpoint:  "https://minio.example.com",
+			wantAllowed: true,
+		},
+		{
+			name:     "negative lifecycle days",
+			location: veleroCR.BackupStorageLocationSpec{LifecycleDays: -1},
+		},
+		{
+			name:     "invalid bucket name",
+			location: veleroCR.BackupStorageLocationSpec{BucketName: "Reviewed_Backups"},
+		},
+		{
+			name: "malformed KMS key",
+			location: veleroCR.BackupStorageLocationSpec{
+				Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: "velero"},
+			},
+		},
+		{
+			name: "KMS key and created key",
+			location: veleroCR.BackupStorageLocationSpec{
+				Encryption: veleroCR.EncryptionSpec{Type: veleroCR.EncryptionTypeKMS, KMSKeyID: "alias/velero", CreateKey: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, err := admission.NewDecoder(newTestReconciler(t).scheme)
+			if err != nil {
+				t.Fatalf("unable to create decoder: %v", err)
+			}
+			validator := &specValidator{options: options{s3Endpoint: tt.s3Endpoint}}
+			if err := validator.InjectDecoder(decoder); err != nil {
+				t.Fatalf("InjectDecoder() error = %v", err)
+			}
+
+			instance := newTestInstance(veleroCR.VeleroSpec{BackupStorageLocations: []veleroCR.BackupStorageLocationSpec{tt.location}})
+			for _, operation := range []admissionv1beta1.Operation{admissionv1beta1.Create, admissionv1beta1.Update} {
+				response := validator.Handle(context.TODO(), newTestAdmissionRequest(t, operation, instance))
+				if response.Allowed != tt.wantAllowed {
+					t.Errorf("Handle(%v) allowed = %v, want %v (result %+v)", operation, response.Allowed, tt.wantAllowed, response.Result)
+				}
+				if !tt.wantAllowed && (response.Result == nil || response.Result.Reason == "") {
+					t.Errorf("Handle(%v) result = %+v, want the reason of the denial", operation, response.Result)
+				}
+			}
+			// An invalid instance can always be deleted
+			if response := validator.Handle(context.TODO(), newTestAdmissionRequest(t, admissionv1beta1.Delete, instance)); !response.Allowed {
+				t.Errorf("Handle(%v) allowed = false, want true", admissionv1beta1.Delete)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package gcs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// GCS label keys can't hold the / and . of the S3 tag keys
+	bucketLabelBackupLocation = "velero-backup-location"
+	bucketLabelInfraName      = "velero-infrastructure-name"
+
+	maxLabelLength = 63
+)
+
+// IsBucketConflict checks whether the error reports that the bucket name is
+// already taken, by this project or another one.
+func IsBucketConflict(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusConflict
+}
+
+// CreateBucket creates a new GCS bucket in the location. Access to the bucket
+// is only granted through IAM, as uniform bucket-level access is enabled.
+func CreateBucket(gcsClient Client, bucketName string, location string) error {
+	return gcsClient.CreateBucket(bucketName, &storage.BucketAttrs{
+		Location: location,
+		BucketPolicyOnly: storage.BucketPolicyOnly{
+			Enabled: true,
+		},
+	})
+}
+
+// DoesBucketExist checks that the bucket exists, and that we have access to it.
+func DoesBucketExist(gcsClient Client, bucketName string) (bool, error) {
+	_, err := gcsClient.GetBucketAttrs(bucketName)
+	if err == storage.ErrBucketNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read %v bucket: %w", bucketName, err)
+	}
+	return true, nil
+}
+
+// ListBucketLabels returns the labels of every bucket in the project, by bucket name.
+func ListBucketLabels(gcsClient Client) (map[string]map[string]string, error) {
+	buckets, err := gcsClient.ListBuckets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list buckets: %w", err)
+	}
+	labels := make(map[string]map[string]string)
+	for _, bucket := range buckets {
+		labels[bucket.Name] = bucket.Labels
+	}
+	return labels, nil
+}
+
+// LabelBucket adds the labels identifying the bucket as the backup location
+// of the cluster. Other labels on the bucket are kept.
+func LabelBucket(gcsClient Client, bucketName string, backUpLocation string, infraName string) error {
+	if err := gcsClient.SetBucketLabels(bucketName, bucketLabels(backUpLocation, infraName)); err != nil {
+		return fmt.Errorf("unable to label %v bucket: %w", bucketName, err)
+	}
+	return nil
+}
+
+// bucketLabels returns the labels identifying the bucket.
+func bucketLabels(backUpLocation string, infraName string) map[string]string {
+	return map[string]string{
+		bucketLabelBackupLocation: labelValue(backUpLocation),
+		bucketLabelInfraName:      labelValue(infraName),
+	}
+}
+
+// labelValue converts the value into a valid GCS label value, which only holds
+// up to 63 lowercase letters, digits, underscores and dashes.
+func labelValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, value)
+	if len(value) > maxLabelLength {
+		value = value[:maxLabelLength]
+	}
+	return value
+}
+
+// FindMatchingLabels looks through the labels of all GCS buckets and determines
+// if any of the buckets are labeled as the backup location of the cluster.
+// If a matching bucket is found, the bucket name is returned.
+func FindMatchingLabels(buckets map[string]map[string]string, infraName string) string {
+	for bucket, labels := range buckets {
+		if _, ok := labels[bucketLabelBackupLocation]; !ok {
+			continue
+		}
+		if labels[bucketLabelInfraName] == labelValue(infraName) {
+			return bucket
+		}
+	}
+
+	// No matching buckets found.
+	return ""
+}
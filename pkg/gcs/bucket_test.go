@@ -0,0 +1,155 @@
+package gcs
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// mockGCSClient is a Client holding the buckets of a single project in memory.
+type mockGCSClient struct {
+	// buckets holds the attributes of every bucket, by bucket name.
+	buckets map[string]*storage.BucketAttrs
+}
+
+// CreateBucket implements the CreateBucket method for mockGCSClient.
+func (c *mockGCSClient) CreateBucket(bucketName string, attrs *storage.BucketAttrs) error {
+	if _, ok := c.buckets[bucketName]; ok {
+		return &googleapi.Error{Code: http.StatusConflict, Message: "You already own this bucket."}
+	}
+	created := *attrs
+	created.Name = bucketName
+	c.buckets[bucketName] = &created
+	return nil
+}
+
+// GetBucketAttrs implements the GetBucketAttrs method for mockGCSClient.
+func (c *mockGCSClient) GetBucketAttrs(bucketName string) (*storage.BucketAttrs, error) {
+	attrs, ok := c.buckets[bucketName]
+	if !ok {
+		return nil, storage.ErrBucketNotExist
+	}
+	return attrs, nil
+}
+
+// GetProjectID implements the GetProjectID method for mockGCSClient.
+func (c *mockGCSClient) GetProjectID() string {
+	return "test-project"
+}
+
+// ListBuckets implements the ListBuckets method for mockGCSClient.
+func (c *mockGCSClient) ListBuckets() ([]*storage.BucketAttrs, error) {
+	var buckets []*storage.BucketAttrs
+	for _, attrs := range c.buckets {
+		buckets = append(buckets, attrs)
+	}
+	return buckets, nil
+}
+
+// SetBucketLabels implements the SetBucketLabels method for mockGCSClient.
+func (c *mockGCSClient) SetBucketLabels(bucketName string, labels map[string]string) error {
+	attrs, ok := c.buckets[bucketName]
+	if !ok {
+		return storage.ErrBucketNotExist
+	}
+	if attrs.Labels == nil {
+		attrs.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		attrs.Labels[key] = value
+	}
+	return nil
+}
+
+func TestCreateBucket(t *testing.T) {
+	client := &mockGCSClient{buckets: map[string]*storage.BucketAttrs{}}
+	if err := CreateBucket(client, "testBucket", "us-central1"); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	attrs := client.buckets["testBucket"]
+	if attrs == nil || attrs.Location != "us-central1" || !attrs.BucketPolicyOnly.Enabled {
+		t.Errorf("CreateBucket() created %+v, want a bucket in us-central1 with uniform bucket-level access", attrs)
+	}
+
+	if err := CreateBucket(client, "testBucket", "us-central1"); !IsBucketConflict(err) {
+		t.Errorf("CreateBucket() error = %v, want a conflict", err)
+	}
+}
+
+func TestDoesBucketExist(t *testing.T) {
+	client := &mockGCSClient{buckets: map[string]*storage.BucketAttrs{
+		"testBucket": {Name: "testBucket"},
+	}}
+	for bucket, want := range map[string]bool{"testBucket": true, "otherBucket": false} {
+		got, err := DoesBucketExist(client, bucket)
+		if err != nil {
+			t.Fatalf("DoesBucketExist() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("DoesBucketExist(%v) = %v, want %v", bucket, got, want)
+		}
+	}
+}
+
+func TestLabelBucket(t *testing.T) {
+	client := &mockGCSClient{buckets: map[string]*storage.BucketAttrs{
+		"testBucket": {Name: "testBucket", Labels: map[string]string{"team": "sre"}},
+	}}
+	if err := LabelBucket(client, "testBucket", "default", "MyCluster.abc12"); err != nil {
+		t.Fatalf("LabelBucket() error = %v", err)
+	}
+	want := map[string]string{
+		"team":                    "sre",
+		bucketLabelBackupLocation: "default",
+		bucketLabelInfraName:      "mycluster-abc12",
+	}
+	if got := client.buckets["testBucket"].Labels; !reflect.DeepEqual(got, want) {
+		t.Errorf("bucket labels = %v, want %v", got, want)
+	}
+}
+
+func TestFindMatchingLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets map[string]map[string]string
+		want    string
+	}{
+		{
+			name: "matching bucket",
+			buckets: map[string]map[string]string{
+				"other":  {"team": "sre"},
+				"backup": bucketLabels("default", "cluster-abc12"),
+			},
+			want: "backup",
+		},
+		{
+			name: "other cluster",
+			buckets: map[string]map[string]string{
+				"backup": bucketLabels("default", "cluster-xyz89"),
+			},
+		},
+		{
+			name: "labels split across buckets",
+			buckets: map[string]map[string]string{
+				"location": {bucketLabelBackupLocation: "default"},
+				"cluster":  {bucketLabelInfraName: "cluster-abc12"},
+			},
+		},
+		{
+			name: "unlabeled buckets",
+			buckets: map[string]map[string]string{
+				"backup": nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindMatchingLabels(tt.buckets, "cluster-abc12"); got != tt.want {
+				t.Errorf("FindMatchingLabels() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
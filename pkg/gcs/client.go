@@ -0,0 +1,119 @@
+package gcs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/managed-velero-operator/version"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	gcpCredsSecretKey = "service_account.json" // #nosec G101
+)
+
+var (
+	gcpCredsSecretName = version.OperatorName + "-iam-credentials"
+)
+
+// gcsClient implements the Client interface.
+type gcsClient struct {
+	storageClient *storage.Client
+	projectID     string
+}
+
+// Client is a wrapper object for the actual GCS SDK client to allow for easier testing.
+type Client interface {
+	CreateBucket(bucketName string, attrs *storage.BucketAttrs) error
+	GetBucketAttrs(bucketName string) (*storage.BucketAttrs, error)
+	GetProjectID() string
+	ListBuckets() ([]*storage.BucketAttrs, error)
+	SetBucketLabels(bucketName string, labels map[string]string) error
+}
+
+// When all of the above Client methods are implemented for gcsClient, gcsClient becomes a kind of Client.
+
+// CreateBucket creates the bucket in the project of the gcsClient.
+func (c *gcsClient) CreateBucket(bucketName string, attrs *storage.BucketAttrs) error {
+	return c.storageClient.Bucket(bucketName).Create(context.TODO(), c.projectID, attrs)
+}
+
+// GetBucketAttrs returns the attributes of the bucket.
+func (c *gcsClient) GetBucketAttrs(bucketName string) (*storage.BucketAttrs, error) {
+	return c.storageClient.Bucket(bucketName).Attrs(context.TODO())
+}
+
+// GetProjectID returns the project the gcsClient manages buckets in.
+func (c *gcsClient) GetProjectID() string {
+	return c.projectID
+}
+
+// ListBuckets returns the attributes of every bucket in the project of the gcsClient.
+func (c *gcsClient) ListBuckets() ([]*storage.BucketAttrs, error) {
+	var buckets []*storage.BucketAttrs
+	it := c.storageClient.Buckets(context.TODO(), c.projectID)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return buckets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, attrs)
+	}
+}
+
+// SetBucketLabels sets the labels on the bucket, keeping its other labels.
+func (c *gcsClient) SetBucketLabels(bucketName string, labels map[string]string) error {
+	var attrs storage.BucketAttrsToUpdate
+	for key, value := range labels {
+		attrs.SetLabel(key, value)
+	}
+	_, err := c.storageClient.Bucket(bucketName).Update(context.TODO(), attrs)
+	return err
+}
+
+// NewGCSClient reads the service account key from the operator's credentials
+// secret, and returns a GCS client managing buckets in the project.
+func NewGCSClient(kubeClient client.Client, projectID string) (Client, error) {
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operator namespace: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	err = kubeClient.Get(context.TODO(),
+		types.NamespacedName{
+			Name:      gcpCredsSecretName,
+			Namespace: namespace,
+		},
+		secret)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount, ok := secret.Data[gcpCredsSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("GCP credentials secret %v did not contain key %v",
+			gcpCredsSecretName, gcpCredsSecretKey)
+	}
+
+	storageClient, err := storage.NewClient(context.TODO(), option.WithCredentialsJSON(serviceAccount))
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the actual GCS client into the gcsClient interface.
+	return &gcsClient{
+		storageClient: storageClient,
+		projectID:     projectID,
+	}, nil
+}
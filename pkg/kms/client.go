@@ -0,0 +1,69 @@
+package kms
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// awsClient implements the Client interface.
+type awsClient struct {
+	kmsClient kmsiface.KMSAPI
+	stsClient stsiface.STSAPI
+	Config    *aws.Config
+}
+
+// Client is a wrapper object for the actual AWS SDK clients to allow for easier testing.
+type Client interface {
+	CreateKey(*kms.CreateKeyInput) (*kms.CreateKeyOutput, error)
+	DescribeKey(*kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error)
+	GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+	GetAWSClientConfig() *aws.Config
+	GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+// When all of the above Client methods are implemented for awsClient, awsClient becomes a kind of Client.
+
+// CreateKey implements the CreateKey method for awsClient.
+func (c *awsClient) CreateKey(input *kms.CreateKeyInput) (*kms.CreateKeyOutput, error) {
+	return c.kmsClient.CreateKey(input)
+}
+
+// DescribeKey implements the DescribeKey method for awsClient.
+func (c *awsClient) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	return c.kmsClient.DescribeKey(input)
+}
+
+// GenerateDataKey implements the GenerateDataKey method for awsClient.
+func (c *awsClient) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	return c.kmsClient.GenerateDataKey(input)
+}
+
+// GetAWSClientConfig returns a copy of the AWS Client Config for the awsClient.
+func (c *awsClient) GetAWSClientConfig() *aws.Config {
+	return c.Config
+}
+
+// GetCallerIdentity implements the GetCallerIdentity method for awsClient.
+func (c *awsClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return c.stsClient.GetCallerIdentity(input)
+}
+
+// NewKMSClient creates a new client for accessing the KMS API, using the
+// region and credentials from an existing AWS client config.
+func NewKMSClient(awsConfig *aws.Config) (Client, error) {
+	s, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the actual AWS clients into the awsClient interface.
+	return &awsClient{
+		kmsClient: kms.New(s),
+		stsClient: sts.New(s),
+		Config:    awsConfig,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package kms
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	keyTagBackupLocation = "velero.io/backup-location"
+	keyTagInfraName      = "velero.io/infrastructureName"
+)
+
+// keyPolicy is the policy document attached to the KMS keys created by the operator.
+type keyPolicy struct {
+	Version   string               `json:"Version"`
+	Statement []keyPolicyStatement `json:"Statement"`
+}
+
+type keyPolicyStatement struct {
+	Sid       string            `json:"Sid"`
+	Effect    string            `json:"Effect"`
+	Principal map[string]string `json:"Principal"`
+	Action    []string          `json:"Action"`
+	Resource  string            `json:"Resource"`
+}
+
+// KeyPolicy returns the key policy for a KMS key created by the operator.
+// Access to the key is delegated to IAM policies in the account, which is how
+// Velero's credentials are granted use of the key, and the operator itself is
+// allowed to manage the key.
+func KeyPolicy(accountID, operatorARN string) (string, error) {
+	// The partition is the second field of the operator's ARN, eg. arn:aws:iam::123456789012:user/name
+	arnParts := strings.Split(operatorARN, ":")
+	if len(arnParts) < 6 || arnParts[0] != "arn" {
+		return "", fmt.Errorf("unable to parse operator ARN %v", operatorARN)
+	}
+	partition := arnParts[1]
+
+	policy := keyPolicy{
+		Version: "2012-10-17",
+		Statement: []keyPolicyStatement{
+			{
+				Sid:       "EnableIAMUserPermissions",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": fmt.Sprintf("arn:%s:iam::%s:root", partition, accountID)},
+				Action:    []string{"kms:*"},
+				Resource:  "*",
+			},
+			{
+				Sid:       "AllowOperatorKeyManagement",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": operatorARN},
+				Action: []string{
+					"kms:Describe*",
+					"kms:List*",
+					"kms:Get*",
+					"kms:Put*",
+					"kms:TagResource",
+					"kms:UntagResource",
+				},
+				Resource: "*",
+			},
+		},
+	}
+
+	document, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("unable to render key policy: %v", err)
+	}
+	return string(document), nil
+}
+
+// IsAlias checks whether the key ID names an alias of a KMS key, either as
+// alias/<name> or as the ARN of the alias.
+func IsAlias(keyID string) bool {
+	if strings.HasPrefix(keyID, "arn:") {
+		arnParts := strings.SplitN(keyID, ":", 6)
+		return len(arnParts) == 6 && strings.HasPrefix(arnParts[5], "alias/")
+	}
+	return strings.HasPrefix(keyID, "alias/")
+}
+
+// ResolveKeyARN returns the ARN of the KMS key the key ID currently names,
+// which for an alias is the key the alias points to.
+func ResolveKeyARN(kmsClient Client, keyID string) (string, error) {
+	input := &kms.DescribeKeyInput{KeyId: aws.String(keyID)}
+	if err := input.Validate(); err != nil {
+		return "", fmt.Errorf("unable to validate key description request: %v", err)
+	}
+
+	output, err := kmsClient.DescribeKey(input)
+	if err != nil {
+		return "", fmt.Errorf("unable to describe KMS key %v: %v", keyID, err)
+	}
+	if output.KeyMetadata == nil || output.KeyMetadata.Arn == nil {
+		return "", fmt.Errorf("KMS key %v has no ARN", keyID)
+	}
+
+	return *output.KeyMetadata.Arn, nil
+}
+
+// CallerAccount returns the ID of the AWS account of the credentials.
+func CallerAccount(kmsClient Client) (string, error) {
+	identity, err := kmsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("unable to determine caller identity: %v", err)
+	}
+	return aws.StringValue(identity.Account), nil
+}
+
+// CreateKey creates a new symmetric KMS key to encrypt the backup bucket with.
+// The tags are used to indicate that the key belongs to velero backups, and to
+// identify the associated cluster. The ARN of the new key is returned.
+func CreateKey(kmsClient Client, backupLocation string, infraName string) (string, error) {
+	identity, err := kmsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("unable to determine caller identity: %v", err)
+	}
+
+	policy, err := KeyPolicy(aws.StringValue(identity.Account), aws.StringValue(identity.Arn))
+	if err != nil {
+		return "", err
+	}
+
+	input := &kms.CreateKeyInput{
+		Description: aws.String(fmt.Sprintf("Velero backup encryption key for cluster %v", infraName)),
+		KeyUsage:    aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		Origin:      aws.String(kms.OriginTypeAwsKms),
+		Policy:      aws.String(policy),
+		Tags: []*kms.Tag{
+			{
+				TagKey:   aws.String(keyTagBackupLocation),
+				TagValue: aws.String(backupLocation),
+			},
+			{
+				TagKey:   aws.String(keyTagInfraName),
+				TagValue: aws.String(infraName),
+			},
+		},
+	}
+	if err := input.Validate(); err != nil {
+		return "", fmt.Errorf("unable to validate key creation configuration: %v", err)
+	}
+
+	output, err := kmsClient.CreateKey(input)
+	if err != nil {
+		return "", err
+	}
+	if output.KeyMetadata == nil || output.KeyMetadata.Arn == nil {
+		return "", fmt.Errorf("created key has no ARN")
+	}
+
+	return *output.KeyMetadata.Arn, nil
+}
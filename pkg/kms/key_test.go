@@ -0,0 +1,196 @@
+package kms
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	clusterInfraName             = "fakeCluster"
+	region                       = "us-east-1"
+	defaultBackupStorageLocation = "default"
+	accountID                    = "123456789012"
+	operatorARN                  = "arn:aws:iam::123456789012:user/managed-velero-operator"
+	keyARN                       = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+)
+
+var awsConfig = &aws.Config{Region: aws.String(region)}
+
+// mockAWSClient implements the Client interface.
+type mockAWSClient struct {
+	Config *aws.Config
+
+	// createKeyErr is returned by CreateKey when set.
+	createKeyErr error
+	// createKeyInputs records every CreateKey request.
+	createKeyInputs []*kms.CreateKeyInput
+	// aliases maps the aliases to the ARNs of their keys.
+	aliases map[string]string
+}
+
+// CreateKey implements the CreateKey method for mockAWSClient.
+func (c *mockAWSClient) CreateKey(input *kms.CreateKeyInput) (*kms.CreateKeyOutput, error) {
+	c.createKeyInputs = append(c.createKeyInputs, input)
+	if c.createKeyErr != nil {
+		return &kms.CreateKeyOutput{}, c.createKeyErr
+	}
+	return &kms.CreateKeyOutput{
+		KeyMetadata: &kms.KeyMetadata{
+			Arn: aws.String(keyARN),
+		},
+	}, nil
+}
+
+// DescribeKey implements the DescribeKey method for mockAWSClient.
+func (c *mockAWSClient) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	arn, ok := c.aliases[*input.KeyId]
+	if !ok {
+		return nil, awserr.New(kms.ErrCodeNotFoundException, "Alias is not found", nil)
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: &kms.KeyMetadata{Arn: aws.String(arn)}}, nil
+}
+
+// GenerateDataKey implements the GenerateDataKey method for mockAWSClient.
+func (c *mockAWSClient) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	return &kms.GenerateDataKeyOutput{KeyId: input.KeyId, Plaintext: make([]byte, 32), CiphertextBlob: []byte("ciphertext")}, nil
+}
+
+// GetAWSClientConfig returns a copy of the AWS Client Config for the mockAWSClient.
+func (c *mockAWSClient) GetAWSClientConfig() *aws.Config {
+	return c.Config
+}
+
+// GetCallerIdentity implements the GetCallerIdentity method for mockAWSClient.
+func (c *mockAWSClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(accountID),
+		Arn:     aws.String(operatorARN),
+	}, nil
+}
+
+func TestKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		operatorARN string
+		wantRoot    string
+		wantErr     bool
+	}{
+		{
+			name:        "Standard partition",
+			operatorARN: operatorARN,
+			wantRoot:    "arn:aws:iam::123456789012:root",
+		},
+		{
+			name:        "GovCloud partition",
+			operatorARN: "arn:aws-us-gov:iam::123456789012:user/managed-velero-operator",
+			wantRoot:    "arn:aws-us-gov:iam::123456789012:root",
+		},
+		{
+			name:        "Invalid operator ARN",
+			operatorARN: "managed-velero-operator",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := KeyPolicy(accountID, tt.operatorARN)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KeyPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			policy := keyPolicy{}
+			if err := json.Unmarshal([]byte(document), &policy); err != nil {
+				t.Fatalf("KeyPolicy() returned invalid JSON: %v", err)
+			}
+			principals := make(map[string]bool)
+			for _, statement := range policy.Statement {
+				principals[statement.Principal["AWS"]] = true
+			}
+			if !principals[tt.wantRoot] {
+				t.Errorf("KeyPolicy() doesn't grant the account root %v", tt.wantRoot)
+			}
+			if !principals[tt.operatorARN] {
+				t.Errorf("KeyPolicy() doesn't grant the operator %v", tt.operatorARN)
+			}
+		})
+	}
+}
+
+func TestCreateKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		createKeyErr error
+		want         string
+		wantErr      bool
+	}{
+		{
+			name: "Create a key",
+			want: keyARN,
+		},
+		{
+			name:         "Key creation fails",
+			createKeyErr: awserr.New("AccessDeniedException", "Access Denied", nil),
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, createKeyErr: tt.createKeyErr}
+			got, err := CreateKey(client, defaultBackupStorageLocation, clusterInfraName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CreateKey() = %v, want %v", got, tt.want)
+			}
+			if len(client.createKeyInputs) != 1 {
+				t.Fatalf("CreateKey() issued %d CreateKey calls, want 1", len(client.createKeyInputs))
+			}
+			tags := make(map[string]string)
+			for _, tag := range client.createKeyInputs[0].Tags {
+				tags[*tag.TagKey] = *tag.TagValue
+			}
+			if tags[keyTagInfraName] != clusterInfraName || tags[keyTagBackupLocation] != defaultBackupStorageLocation {
+				t.Errorf("CreateKey() tags = %v", tags)
+			}
+		})
+	}
+}
+
+func TestIsAlias(t *testing.T) {
+	tests := []struct {
+		keyID string
+		want  bool
+	}{
+		{keyID: "alias/velero", want: true},
+		{keyID: "arn:aws:kms:us-east-1:123456789012:alias/velero", want: true},
+		{keyID: "1234abcd-12ab-34cd-56ef-1234567890ab", want: false},
+		{keyID: keyARN, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsAlias(tt.keyID); got != tt.want {
+			t.Errorf("IsAlias(%v) = %v, want %v", tt.keyID, got, tt.want)
+		}
+	}
+}
+
+func TestResolveKeyARN(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig, aliases: map[string]string{"alias/velero": keyARN}}
+	got, err := ResolveKeyARN(client, "alias/velero")
+	if err != nil {
+		t.Fatalf("ResolveKeyARN() error = %v", err)
+	}
+	if got != keyARN {
+		t.Errorf("ResolveKeyARN() = %v, want %v", got, keyARN)
+	}
+	if _, err := ResolveKeyARN(client, "alias/missing"); err == nil {
+		t.Errorf("ResolveKeyARN() error = nil for a missing alias")
+	}
+}
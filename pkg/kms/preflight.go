@@ -0,0 +1,77 @@
+package kms
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// errCodeAccessDenied is the error code of the KMS requests the credentials
+// aren't allowed to make, which the SDK has no constant for.
+const errCodeAccessDenied = "AccessDeniedException"
+
+// KeyUnusableError reports why the backups can't be encrypted with a KMS key.
+type KeyUnusableError struct {
+	// KeyID is the checked key.
+	KeyID string
+	// Reason describes why the key can't be used.
+	Reason string
+}
+
+func (e *KeyUnusableError) Error() string {
+	return fmt.Sprintf("KMS key %v can't be used to encrypt the backups: %v", e.KeyID, e.Reason)
+}
+
+// PreflightKeyCheck verifies that the credentials can use the KMS key to
+// encrypt the objects of a bucket, which S3 doesn't check when the bucket
+// encryption is set, but only as every object is written. The key is
+// described, to check that it is enabled for encryption, and a data key is
+// generated with it, as S3 does on every upload, and thrown away. A
+// KeyUnusableError is returned when the key can't be used.
+func PreflightKeyCheck(kmsClient Client, keyID string) error {
+	describeInput := &kms.DescribeKeyInput{KeyId: aws.String(keyID)}
+	if err := describeInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate key description request: %v", err)
+	}
+	output, err := kmsClient.DescribeKey(describeInput)
+	if err != nil {
+		return keyCheckError(keyID, "kms:DescribeKey", err)
+	}
+	if metadata := output.KeyMetadata; metadata != nil {
+		if state := aws.StringValue(metadata.KeyState); state != "" && state != kms.KeyStateEnabled {
+			return &KeyUnusableError{KeyID: keyID, Reason: fmt.Sprintf("the key state is %v", state)}
+		}
+		if usage := aws.StringValue(metadata.KeyUsage); usage != "" && usage != kms.KeyUsageTypeEncryptDecrypt {
+			return &KeyUnusableError{KeyID: keyID, Reason: fmt.Sprintf("the key usage is %v", usage)}
+		}
+	}
+
+	generateInput := &kms.GenerateDataKeyInput{KeyId: aws.String(keyID), KeySpec: aws.String(kms.DataKeySpecAes256)}
+	if err := generateInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate data key generation request: %v", err)
+	}
+	if _, err := kmsClient.GenerateDataKey(generateInput); err != nil {
+		return keyCheckError(keyID, "kms:GenerateDataKey", err)
+	}
+	return nil
+}
+
+// keyCheckError returns a KeyUnusableError when a request checking the key
+// failed because of the key, or of the permissions on it, and otherwise the
+// error of the request, which may succeed when retried.
+func keyCheckError(keyID, action string, err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case errCodeAccessDenied:
+			return &KeyUnusableError{KeyID: keyID, Reason: fmt.Sprintf("the credentials are denied %v", action)}
+		case kms.ErrCodeNotFoundException, kms.ErrCodeDisabledException,
+			kms.ErrCodeInvalidStateException, kms.ErrCodeInvalidKeyUsageException:
+			return &KeyUnusableError{KeyID: keyID, Reason: aerr.Message()}
+		}
+	}
+	return fmt.Errorf("unable to check KMS key %v: %v", keyID, err)
+}
@@ -0,0 +1,129 @@
+package kms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// keyMockClient is a mockAWSClient describing a single key, whose requests
+// fail with the configured errors.
+type keyMockClient struct {
+	mockAWSClient
+
+	// metadata is the description of the key.
+	metadata *kms.KeyMetadata
+	// describeErr is returned by DescribeKey when set.
+	describeErr error
+	// generateErr is returned by GenerateDataKey when set.
+	generateErr error
+	// generateInputs records every GenerateDataKey request.
+	generateInputs []*kms.GenerateDataKeyInput
+}
+
+// DescribeKey implements the DescribeKey method for keyMockClient.
+func (c *keyMockClient) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	if c.describeErr != nil {
+		return nil, c.describeErr
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: c.metadata}, nil
+}
+
+// GenerateDataKey implements the GenerateDataKey method for keyMockClient.
+func (c *keyMockClient) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	c.generateInputs = append(c.generateInputs, input)
+	if c.generateErr != nil {
+		return nil, c.generateErr
+	}
+	return c.mockAWSClient.GenerateDataKey(input)
+}
+
+func TestPreflightKeyCheck(t *testing.T) {
+	enabled := &kms.KeyMetadata{
+		Arn:      aws.String(keyARN),
+		KeyState: aws.String(kms.KeyStateEnabled),
+		KeyUsage: aws.String(kms.KeyUsageTypeEncryptDecrypt),
+	}
+	tests := []struct {
+		name         string
+		client       *keyMockClient
+		wantUnusable bool
+		wantErr      bool
+		wantGenerate bool
+	}{
+		{
+			name:         "key permitted",
+			client:       &keyMockClient{metadata: enabled},
+			wantGenerate: true,
+		},
+		{
+			name: "description denied",
+			client: &keyMockClient{
+				describeErr: awserr.NewRequestFailure(awserr.New(errCodeAccessDenied, "User is not authorized to perform: kms:DescribeKey", nil), 400, ""),
+			},
+			wantUnusable: true,
+			wantErr:      true,
+		},
+		{
+			name: "data key generation denied",
+			client: &keyMockClient{
+				metadata:    enabled,
+				generateErr: awserr.NewRequestFailure(awserr.New(errCodeAccessDenied, "User is not authorized to perform: kms:GenerateDataKey", nil), 400, ""),
+			},
+			wantUnusable: true,
+			wantErr:      true,
+			wantGenerate: true,
+		},
+		{
+			name: "key pending deletion",
+			client: &keyMockClient{metadata: &kms.KeyMetadata{
+				Arn:      aws.String(keyARN),
+				KeyState: aws.String(kms.KeyStatePendingDeletion),
+				KeyUsage: aws.String(kms.KeyUsageTypeEncryptDecrypt),
+			}},
+			wantUnusable: true,
+			wantErr:      true,
+		},
+		{
+			name: "key not found",
+			client: &keyMockClient{
+				describeErr: awserr.NewRequestFailure(awserr.New(kms.ErrCodeNotFoundException, "Key does not exist", nil), 400, ""),
+			},
+			wantUnusable: true,
+			wantErr:      true,
+		},
+		{
+			name: "KMS unavailable",
+			client: &keyMockClient{
+				metadata:    enabled,
+				generateErr: awserr.NewRequestFailure(awserr.New("KMSInternalException", "Internal error", nil), 500, ""),
+			},
+			wantErr:      true,
+			wantGenerate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.client.Config = awsConfig
+			err := PreflightKeyCheck(tt.client, keyARN)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PreflightKeyCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var keyErr *KeyUnusableError
+			if errors.As(err, &keyErr) != tt.wantUnusable {
+				t.Errorf("PreflightKeyCheck() error = %v, want a KeyUnusableError %v", err, tt.wantUnusable)
+			}
+			if generated := len(tt.client.generateInputs) > 0; generated != tt.wantGenerate {
+				t.Errorf("PreflightKeyCheck() generated a data key = %v, want %v", generated, tt.wantGenerate)
+			}
+			for _, input := range tt.client.generateInputs {
+				if aws.StringValue(input.KeyId) != keyARN || aws.StringValue(input.KeySpec) != kms.DataKeySpecAes256 {
+					t.Errorf("GenerateDataKey() input = %+v, want an AES_256 data key of %v", input, keyARN)
+				}
+			}
+		})
+	}
+}
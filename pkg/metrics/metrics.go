@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "velero_operator"
+
+var (
+	// BucketCreateTotal counts the attempts to create a bucket.
+	BucketCreateTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bucket_create_total",
+		Help:      "Number of attempts to create a bucket",
+	})
+
+	// BucketCreateErrorsTotal counts the attempts to create a bucket which failed.
+	BucketCreateErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bucket_create_errors_total",
+		Help:      "Number of attempts to create a bucket which failed",
+	})
+
+	// S3RequestErrorsTotal counts the failed requests to the S3 API, by operation.
+	S3RequestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "s3_request_errors_total",
+		Help:      "Number of requests to the S3 API which failed, by operation",
+	}, []string{"operation"})
+
+	// S3RequestDuration observes the duration of the requests to the S3 API, by operation.
+	S3RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "s3_request_duration_seconds",
+		Help:      "Duration of the requests to the S3 API, by operation",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// LastSuccessTimestamp holds when each Velero instance was last
+	// reconciled successfully, by name and namespace.
+	LastSuccessTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time the Velero instance was last reconciled successfully",
+	}, []string{"name", "namespace"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		BucketCreateTotal,
+		BucketCreateErrorsTotal,
+		S3RequestErrorsTotal,
+		S3RequestDuration,
+		LastSuccessTimestamp,
+	)
+}
+
+// S3Observer records the calls to the S3 API in the operator's metrics, as
+// an s3.RequestObserver.
+type S3Observer struct{}
+
+// ObserveS3Request implements the ObserveS3Request method for S3Observer.
+func (S3Observer) ObserveS3Request(operation string, duration time.Duration, err error) {
+	ObserveS3Request(operation, duration, err)
+}
+
+// ObserveBucketCreate implements the ObserveBucketCreate method for S3Observer.
+func (S3Observer) ObserveBucketCreate(err error) {
+	ObserveBucketCreate(err)
+}
+
+// ObserveS3Request records the duration and the outcome of a request to the S3 API.
+func ObserveS3Request(operation string, duration time.Duration, err error) {
+	S3RequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		S3RequestErrorsTotal.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveBucketCreate records the outcome of an attempt to create a bucket.
+func ObserveBucketCreate(err error) {
+	BucketCreateTotal.Inc()
+	if err != nil {
+		BucketCreateErrorsTotal.Inc()
+	}
+}
+
+// ObserveReconcileSuccess records that the Velero instance was reconciled
+// successfully at the time.
+func ObserveReconcileSuccess(name, namespace string, at time.Time) {
+	LastSuccessTimestamp.WithLabelValues(name, namespace).Set(float64(at.Unix()))
+}
+
+// ForgetVelero removes the series of a deleted Velero instance.
+func ForgetVelero(name, namespace string) {
+	LastSuccessTimestamp.DeleteLabelValues(name, namespace)
+}
@@ -0,0 +1,566 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// DefaultCircuitBreakerThreshold is how many consecutive failed calls
+	// open the circuit by default.
+	DefaultCircuitBreakerThreshold = 10
+	// DefaultCircuitBreakerWindow is how close together the failed calls must
+	// be to open the circuit by default.
+	DefaultCircuitBreakerWindow = time.Minute
+	// DefaultCircuitBreakerOpenDuration is how long an open circuit fails the
+	// calls by default, before letting a call probe S3 again.
+	DefaultCircuitBreakerOpenDuration = 5 * time.Minute
+)
+
+// ErrCircuitOpen is returned instead of making a call to the S3 API while
+// the circuit of the CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("S3 circuit breaker is open after repeated failures")
+
+// CircuitBreaker stops the calls to the S3 API for a while once they keep
+// failing, such as during a regional outage, so that retrying the reconciles
+// doesn't add up to a storm of requests. The circuit opens after the threshold
+// of consecutive calls failed with a transient error or timed out within the
+// window, and the calls then fail with ErrCircuitOpen. Once the open duration
+// passed, the circuit is half-open: a single call is let through to probe S3,
+// and closes the circuit when it succeeds, or opens it again when it fails. It
+// is safe for concurrent use. A nil CircuitBreaker never opens.
+type CircuitBreaker struct {
+	mu           sync.Mutex
+	threshold    int
+	window       time.Duration
+	openDuration time.Duration
+
+	// failures counts the consecutive failed calls since firstFailure
+	failures     int
+	firstFailure time.Time
+	// openedAt is when the circuit opened, and is zero while it is closed
+	openedAt time.Time
+	// probing is true while the call probing S3 in the half-open circuit is
+	// in flight
+	probing bool
+
+	// now returns the current time, and defaults to time.Now
+	now func() time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker opening after the
+// threshold of consecutive failed calls within the window, for the open
+// duration. The circuit never opens when the threshold is 0.
+func NewCircuitBreaker(threshold int, window, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, window: window, openDuration: openDuration, now: time.Now}
+}
+
+// enabled checks whether the circuit may open at all.
+func (b *CircuitBreaker) enabled() bool {
+	return b != nil && b.threshold > 0
+}
+
+// RetryAfter returns how long the circuit stays open, or 0 once it is closed
+// or half-open.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	if !b.enabled() {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return 0
+	}
+	if wait := b.openedAt.Add(b.openDuration).Sub(b.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// allow returns ErrCircuitOpen when the call must not be made, and whether the
+// call otherwise probes S3 in the half-open circuit.
+func (b *CircuitBreaker) allow() (bool, error) {
+	if !b.enabled() {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return false, nil
+	case b.probing || b.now().Before(b.openedAt.Add(b.openDuration)):
+		return false, ErrCircuitOpen
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record records the outcome of a call made with the context. Only the
+// transient errors and the timeouts, which are how an outage shows, count as
+// failures: any other error is an answer of S3. The outcome of a cancelled
+// call tells nothing about S3, and is ignored.
+func (b *CircuitBreaker) record(ctx context.Context, probe bool, err error) {
+	if !b.enabled() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch {
+	case err != nil && (isRetryableError(err) || ctx.Err() == context.DeadlineExceeded):
+		if probe {
+			b.probing = false
+			b.openedAt = now
+			return
+		}
+		if !b.openedAt.IsZero() {
+			// The call was made before the circuit opened
+			return
+		}
+		if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+			b.failures = 0
+			b.firstFailure = now
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.failures = 0
+			b.openedAt = now
+		}
+	case err != nil && ctx.Err() != nil:
+		if probe {
+			// The next call probes S3 instead
+			b.probing = false
+		}
+	case probe || b.openedAt.IsZero():
+		b.probing = false
+		b.openedAt = time.Time{}
+		b.failures = 0
+	}
+}
+
+// breakerClient is a Client whose calls are stopped by a CircuitBreaker, and
+// whose outcomes are recorded by it.
+type breakerClient struct {
+	Client
+	breaker *CircuitBreaker
+}
+
+// Client returns a Client making the calls with s3Client, unless the circuit
+// is open. s3Client is returned as is when the breaker is nil.
+func (b *CircuitBreaker) Client(s3Client Client) Client {
+	if b == nil {
+		return s3Client
+	}
+	return &breakerClient{Client: s3Client, breaker: b}
+}
+
+// ForRegion returns a breakerClient for the region, which is stopped by the same breaker.
+func (c *breakerClient) ForRegion(region string) (Client, error) {
+	regional, err := c.Client.ForRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return c.breaker.Client(regional), nil
+}
+
+// CreateBucket implements the CreateBucket method for breakerClient.
+func (c *breakerClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.CreateBucket(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// DeleteBucket implements the DeleteBucket method for breakerClient.
+func (c *breakerClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.DeleteBucket(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for breakerClient.
+func (c *breakerClient) DeleteBucketPolicy(ctx context.Context, input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.DeleteBucketPolicy(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for breakerClient.
+func (c *breakerClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.DeleteBucketTagging(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// DeleteObject implements the DeleteObject method for breakerClient.
+func (c *breakerClient) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.DeleteObject(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// DeleteObjects implements the DeleteObjects method for breakerClient.
+func (c *breakerClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.DeleteObjects(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// HeadBucket implements the HeadBucket method for breakerClient.
+func (c *breakerClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.HeadBucket(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketCors implements the GetBucketCors method for breakerClient.
+func (c *breakerClient) GetBucketCors(ctx context.Context, input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketCors(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for breakerClient.
+func (c *breakerClient) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketEncryption(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for breakerClient.
+func (c *breakerClient) GetBucketLifecycleConfiguration(ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketLifecycleConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketLocation implements the GetBucketLocation method for breakerClient.
+func (c *breakerClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketLocation(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketLogging implements the GetBucketLogging method for breakerClient.
+func (c *breakerClient) GetBucketLogging(ctx context.Context, input *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketLogging(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for breakerClient.
+func (c *breakerClient) GetBucketNotificationConfiguration(
+	ctx context.Context, input *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketNotificationConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for breakerClient.
+func (c *breakerClient) GetBucketOwnershipControls(ctx context.Context, input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketOwnershipControls(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for breakerClient.
+func (c *breakerClient) GetBucketPolicy(ctx context.Context, input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketPolicy(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketReplication implements the GetBucketReplication method for breakerClient.
+func (c *breakerClient) GetBucketReplication(ctx context.Context, input *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketReplication(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketTagging implements the GetBucketTagging method for breakerClient.
+func (c *breakerClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketTagging(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetBucketVersioning implements the GetBucketVersioning method for breakerClient.
+func (c *breakerClient) GetBucketVersioning(ctx context.Context, input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetBucketVersioning(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for breakerClient.
+func (c *breakerClient) GetObjectLockConfiguration(ctx context.Context, input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetObjectLockConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// GetPublicAccessBlock implements the GetPublicAccessBlock method for breakerClient.
+func (c *breakerClient) GetPublicAccessBlock(ctx context.Context, input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.GetPublicAccessBlock(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// ListBuckets implements the ListBuckets method for breakerClient.
+func (c *breakerClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.ListBuckets(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// ListObjectVersions implements the ListObjectVersions method for breakerClient.
+func (c *breakerClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.ListObjectVersions(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for breakerClient.
+func (c *breakerClient) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.ListObjectsV2(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for breakerClient.
+func (c *breakerClient) PutBucketAccelerateConfiguration(ctx context.Context, input *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketAccelerateConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketCors implements the PutBucketCors method for breakerClient.
+func (c *breakerClient) PutBucketCors(ctx context.Context, input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketCors(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketEncryption implements the PutBucketEncryption method for breakerClient.
+func (c *breakerClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketEncryption(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for breakerClient.
+func (c *breakerClient) PutBucketLifecycleConfiguration(ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketLifecycleConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketLogging implements the PutBucketLogging method for breakerClient.
+func (c *breakerClient) PutBucketLogging(ctx context.Context, input *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketLogging(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for breakerClient.
+func (c *breakerClient) PutBucketNotificationConfiguration(
+	ctx context.Context, input *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketNotificationConfiguration(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for breakerClient.
+func (c *breakerClient) PutBucketOwnershipControls(ctx context.Context, input *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketOwnershipControls(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for breakerClient.
+func (c *breakerClient) PutBucketPolicy(ctx context.Context, input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketPolicy(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketReplication implements the PutBucketReplication method for breakerClient.
+func (c *breakerClient) PutBucketReplication(ctx context.Context, input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketReplication(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketTagging implements the PutBucketTagging method for breakerClient.
+func (c *breakerClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketTagging(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for breakerClient.
+func (c *breakerClient) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutBucketVersioning(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutObject implements the PutObject method for breakerClient.
+func (c *breakerClient) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutObject(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
+
+// PutPublicAccessBlock implements the PutPublicAccessBlock method for breakerClient.
+func (c *breakerClient) PutPublicAccessBlock(ctx context.Context, input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.Client.PutPublicAccessBlock(ctx, input)
+	c.breaker.record(ctx, probe, err)
+	return output, err
+}
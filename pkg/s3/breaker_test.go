@@ -0,0 +1,133 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// unavailable is the error of a call to S3 during an outage.
+var unavailable = awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "Service Unavailable", nil), 503, "")
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	ctx := WithRetryPolicy(context.TODO(), RetryPolicy{MaxAttempts: 3})
+	client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{unavailable, unavailable, unavailable}}
+	breaker := NewCircuitBreaker(3, time.Minute, time.Minute)
+	breaker.now = func() time.Time { return now }
+	s3Client := breaker.Client(client)
+
+	// The retries of a single call use up the threshold
+	if _, err := DoesBucketExist(ctx, s3Client, "testBucket"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("DoesBucketExist() error = %v, want the outage", err)
+	}
+	if wait := breaker.RetryAfter(); wait != time.Minute {
+		t.Errorf("RetryAfter() = %v after %d failed calls, want the circuit open for %v", wait, client.headBucketCalls, time.Minute)
+	}
+	if _, err := DoesBucketExist(ctx, s3Client, "testBucket"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("DoesBucketExist() error = %v with the circuit open, want ErrCircuitOpen", err)
+	}
+	if client.headBucketCalls != 3 {
+		t.Errorf("HeadBucket called %d times, want no call with the circuit open", client.headBucketCalls)
+	}
+
+	// Once the open duration passed, a call probes S3 and closes the circuit
+	now = now.Add(time.Minute)
+	if wait := breaker.RetryAfter(); wait != 0 {
+		t.Errorf("RetryAfter() = %v with the circuit half-open, want 0", wait)
+	}
+	for i := 0; i < 2; i++ {
+		if exists, err := DoesBucketExist(ctx, s3Client, "testBucket"); err != nil || !exists {
+			t.Fatalf("DoesBucketExist() = %v, %v after S3 recovered, want the bucket found", exists, err)
+		}
+	}
+	if client.headBucketCalls != 5 {
+		t.Errorf("HeadBucket called %d times, want 5 with the circuit closed again", client.headBucketCalls)
+	}
+}
+
+func TestCircuitBreakerProbeFails(t *testing.T) {
+	now := time.Now()
+	client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{unavailable, unavailable}}
+	breaker := NewCircuitBreaker(1, time.Minute, time.Minute)
+	breaker.now = func() time.Time { return now }
+	s3Client := breaker.Client(client)
+
+	if _, err := s3Client.HeadBucket(context.TODO(), nil); err != unavailable {
+		t.Fatalf("HeadBucket() error = %v, want the outage", err)
+	}
+	now = now.Add(time.Minute)
+	// A call made while the probe is in flight is stopped
+	probe, err := breaker.allow()
+	if !probe || err != nil {
+		t.Fatalf("allow() = %v, %v with the circuit half-open, want a probe", probe, err)
+	}
+	if _, err := s3Client.HeadBucket(context.TODO(), nil); err != ErrCircuitOpen {
+		t.Errorf("HeadBucket() error = %v while probing, want ErrCircuitOpen", err)
+	}
+	breaker.record(context.TODO(), probe, unavailable)
+	if wait := breaker.RetryAfter(); wait != time.Minute {
+		t.Errorf("RetryAfter() = %v after the probe failed, want the circuit open again for %v", wait, time.Minute)
+	}
+}
+
+func TestCircuitBreakerStaysClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+		// sincePrevious is how long after the previous call each call is made
+		sincePrevious time.Duration
+	}{
+		{
+			name: "S3 answers with errors",
+			errs: []error{
+				awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, ""),
+				awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, ""),
+				awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, ""),
+			},
+		},
+		{
+			name:          "failures spread out",
+			errs:          []error{unavailable, unavailable, unavailable},
+			sincePrevious: 45 * time.Second,
+		},
+		{
+			name: "failures interrupted by a success",
+			errs: []error{unavailable, unavailable, nil, unavailable},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			breaker := NewCircuitBreaker(3, time.Minute, time.Minute)
+			breaker.now = func() time.Time { return now }
+			for _, err := range tt.errs {
+				now = now.Add(tt.sincePrevious)
+				probe, allowErr := breaker.allow()
+				if allowErr != nil {
+					t.Fatalf("allow() error = %v, want the circuit closed", allowErr)
+				}
+				breaker.record(context.TODO(), probe, err)
+			}
+			if wait := breaker.RetryAfter(); wait != 0 {
+				t.Errorf("RetryAfter() = %v, want the circuit closed", wait)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	for _, breaker := range []*CircuitBreaker{nil, NewCircuitBreaker(0, time.Minute, time.Minute)} {
+		client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{unavailable, unavailable, unavailable}}
+		s3Client := breaker.Client(client)
+		for i := 0; i < 4; i++ {
+			_, _ = s3Client.HeadBucket(context.TODO(), nil)
+		}
+		if client.headBucketCalls != 4 || breaker.RetryAfter() != 0 {
+			t.Errorf("HeadBucket called %d times, RetryAfter() = %v, want every call made", client.headBucketCalls, breaker.RetryAfter())
+		}
+	}
+}
@@ -1,78 +1,560 @@
 package s3
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
-	bucketTagBackupLocation = "velero.io/backup-location"
-	bucketTagInfraName      = "velero.io/infrastructureName"
+	bucketTagBackupLocation = DefaultTagKeyPrefix + "backup-location"
+	bucketTagInfraName      = DefaultTagKeyPrefix + "infrastructureName"
+
+	// DefaultWritableProbePrefix is the reserved key prefix the writable probe object is written under.
+	DefaultWritableProbePrefix = ".managed-velero-operator/"
+	writableProbeName          = "writable-probe"
 )
 
-// CreateBucket creates a new S3 bucket.
-func CreateBucket(s3Client Client, bucketName string) error {
+// ErrEncryptionImmutable is returned by EncryptBucket when the backend accepted
+// the encryption configuration, but kept the configuration the bucket was
+// created with.
+var ErrEncryptionImmutable = errors.New("bucket encryption cannot be changed")
+
+// ErrBucketNotEncrypted is returned by ReadBucketEncryption when the bucket has
+// no encryption configuration.
+var ErrBucketNotEncrypted = errors.New("bucket has no encryption configured")
+
+// ErrKMSKeyRotated is returned by EnsureBucketKMSEncryption when the bucket is
+// encrypted with another KMS key than the configured one it was last encrypted
+// with, such as after the key was rotated, which is left in place rather than
+// replaced.
+var ErrKMSKeyRotated = errors.New("bucket is encrypted with another KMS key")
+
+// ErrInvalidLifecycleTransition is returned when a lifecycle rule transitions
+// the objects to another storage class no earlier than they expire.
+var ErrInvalidLifecycleTransition = errors.New("lifecycle transition must happen before the expiration")
+
+// ErrBucketForbidden is returned by DoesBucketExist when access to the bucket
+// is denied, such as when another account owns it: the bucket exists, so it
+// can't be created either.
+var ErrBucketForbidden = errors.New("access to bucket is forbidden")
+
+// ErrBucketNameTaken is returned by CreateBucket when another account owns a
+// bucket of the same name, as bucket names are shared by all accounts.
+var ErrBucketNameTaken = errors.New("bucket name is already taken")
+
+// ErrInvalidBucketName is returned by CreateBucket when the bucket name breaks
+// the S3 bucket naming rules, which retrying can't fix.
+var ErrInvalidBucketName = errors.New("invalid bucket name")
+
+// bucketNamePattern matches the characters a bucket name may hold, and those
+// it may begin and end with.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+
+// IsNoSuchBucket checks whether the error reports that the bucket doesn't exist.
+func IsNoSuchBucket(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchBucket
+}
+
+// ValidateBucketName checks the bucket name against the S3 bucket naming rules.
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html
+func ValidateBucketName(bucketName string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w %q: %v", ErrInvalidBucketName, bucketName, reason)
+	}
+	switch {
+	case len(bucketName) < 3 || len(bucketName) > 63:
+		return invalid("must be between 3 and 63 characters long")
+	case !bucketNamePattern.MatchString(bucketName):
+		return invalid("must only hold lowercase letters, digits, dots and hyphens, and begin and end with a letter or digit")
+	case strings.Contains(bucketName, ".."):
+		return invalid("must not hold consecutive dots")
+	case net.ParseIP(bucketName) != nil:
+		return invalid("must not be formatted as an IP address")
+	}
+	return nil
+}
+
+// CreateBucket creates a new S3 bucket in the region of the s3Client. An
+// invalid bucket name is rejected with ErrInvalidBucketName before any
+// request is made. A bucket we already own, such as one created by an earlier
+// attempt whose response was lost, is created successfully, so that creating
+// a bucket can be retried. CreateBucket fails with ErrBucketNameTaken when
+// another account owns the bucket.
+func CreateBucket(ctx context.Context, s3Client Client, bucketName string) error {
+	if err := ValidateBucketName(bucketName); err != nil {
+		return err
+	}
 	createBucketInput := &s3.CreateBucketInput{
 		ACL:    aws.String(s3.BucketCannedACLPrivate),
 		Bucket: aws.String(bucketName),
 	}
-	// Only set a location constraint if the cluster isn't in us-east-1
-	// https://github.com/boto/boto3/issues/125
 	config := s3Client.GetAWSClientConfig()
-
-	if *config.Region != "us-east-1" {
-		createBucketConfiguation := &s3.CreateBucketConfiguration{
-			LocationConstraint: config.Region,
-		}
-		createBucketInput.SetCreateBucketConfiguration(createBucketConfiguation)
+	if createBucketConfiguration := bucketLocationConstraint(aws.StringValue(config.Region)); createBucketConfiguration != nil {
+		createBucketInput.SetCreateBucketConfiguration(createBucketConfiguration)
 	}
 	if err := createBucketInput.Validate(); err != nil {
 		return fmt.Errorf("unable to validate %v bucket creation configuration: %v", bucketName, err)
 	}
 
-	_, err := s3Client.CreateBucket(createBucketInput)
-
+	err := withRetry(ctx, "CreateBucket", func(ctx context.Context) error {
+		_, err := s3Client.CreateBucket(ctx, createBucketInput)
+		return err
+	})
+	switch {
+	case isErrorCode(err, s3.ErrCodeBucketAlreadyOwnedByYou):
+		err = nil
+	case isErrorCode(err, s3.ErrCodeBucketAlreadyExists):
+		err = &bucketError{kind: ErrBucketNameTaken, bucketName: bucketName, err: err}
+	}
+	if observer := requestObserverFrom(ctx); observer != nil {
+		observer.ObserveBucketCreate(err)
+	}
 	return err
 }
 
+// bucketLocationConstraint returns the configuration creating a bucket in the
+// region. No location constraint is set for us-east-1, where S3 rejects it
+// and creates the buckets which don't set one.
+// https://github.com/boto/boto3/issues/125
+func bucketLocationConstraint(region string) *s3.CreateBucketConfiguration {
+	if region == "" || region == "us-east-1" {
+		return nil
+	}
+	return &s3.CreateBucketConfiguration{
+		LocationConstraint: aws.String(region),
+	}
+}
+
 // DoesBucketExist checks that the bucket exists, and that we have access to it.
-func DoesBucketExist(s3Client Client, bucketName string) (bool, error) {
+// A bucket living in another region than the s3Client's is looked up in its
+// own region. A 404 response reports that the bucket doesn't exist, while a
+// 403 response fails with ErrBucketForbidden.
+func DoesBucketExist(ctx context.Context, s3Client Client, bucketName string) (bool, error) {
 	input := &s3.HeadBucketInput{
 		Bucket: aws.String(bucketName),
 	}
 
-	_, err := s3Client.HeadBucket(input)
+	err := withRetry(ctx, "HeadBucket", func(ctx context.Context) error {
+		_, err := s3Client.HeadBucket(ctx, input)
+		return err
+	})
+	if isWrongRegionError(err) {
+		err = headBucketInRegion(ctx, s3Client, input)
+	}
+	switch {
+	case err == nil:
+		return true, nil
+	// This is supposed to say "NoSuchBucket", but actually emits "NotFound"
+	// https://github.com/aws/aws-sdk-go/issues/2593
+	case isErrorCode(err, s3.ErrCodeNoSuchBucket), isErrorCode(err, "NotFound"), statusCode(err) == http.StatusNotFound:
+		return false, nil
+	case IsAccessDenied(err), statusCode(err) == http.StatusForbidden:
+		return false, &bucketError{kind: ErrBucketForbidden, bucketName: bucketName, err: err}
+	default:
+		return false, fmt.Errorf("unable to determine bucket %v status: %w", bucketName, err)
+	}
+}
+
+// headBucketInRegion issues the HeadBucket request with a client for the
+// region the bucket lives in.
+func headBucketInRegion(ctx context.Context, s3Client Client, input *s3.HeadBucketInput) error {
+	region, err := BucketRegion(ctx, s3Client, aws.StringValue(input.Bucket))
+	if err != nil {
+		return err
+	}
+	regionalClient, err := s3Client.ForRegion(region)
+	if err != nil {
+		return fmt.Errorf("unable to create S3 client for region %v: %w", region, err)
+	}
+	return withRetry(ctx, "HeadBucket", func(ctx context.Context) error {
+		_, err := regionalClient.HeadBucket(ctx, input)
+		return err
+	})
+}
+
+// BucketRegion returns the region the bucket lives in.
+func BucketRegion(ctx context.Context, s3Client Client, bucketName string) (string, error) {
+	var output *s3.GetBucketLocationOutput
+	err := withRetry(ctx, "GetBucketLocation", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to read %v bucket location: %w", bucketName, err)
+	}
+	return s3.NormalizeBucketLocation(aws.StringValue(output.LocationConstraint)), nil
+}
+
+// IsBucketVersioned checks whether versioning is enabled on the bucket.
+func IsBucketVersioned(ctx context.Context, s3Client Client, bucketName string) (bool, error) {
+	var output *s3.GetBucketVersioningOutput
+	err := withRetry(ctx, "GetBucketVersioning", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to read %v bucket versioning: %w", bucketName, err)
+	}
+	return aws.StringValue(output.Status) == s3.BucketVersioningStatusEnabled, nil
+}
+
+// EnsureBucketVersioning enables versioning on the bucket, unless it is
+// already enabled. A bucket with suspended versioning is enabled again.
+func EnsureBucketVersioning(ctx context.Context, s3Client Client, bucketName string) error {
+	versioned, err := IsBucketVersioned(ctx, s3Client, bucketName)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			// This is supposed to say "NoSuchBucket", but actually emits "NotFound"
-			// https://github.com/aws/aws-sdk-go/issues/2593
-			case s3.ErrCodeNoSuchBucket, "NotFound":
-				return false, nil
-			default:
-				return false, fmt.Errorf("unable to determine bucket %v status: %v", bucketName, aerr.Error())
+		return err
+	}
+	if versioned {
+		return nil
+	}
+
+	bucketVersioningInput := &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(s3.BucketVersioningStatusEnabled),
+		},
+	}
+	if err := bucketVersioningInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket versioning configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketVersioning", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketVersioning(ctx, bucketVersioningInput)
+		return err
+	})
+}
+
+// EnableBucketAcceleration enables S3 Transfer Acceleration on the bucket,
+// which the clients addressing the accelerate endpoint require.
+func EnableBucketAcceleration(ctx context.Context, s3Client Client, bucketName string) error {
+	input := &s3.PutBucketAccelerateConfigurationInput{
+		Bucket: aws.String(bucketName),
+		AccelerateConfiguration: &s3.AccelerateConfiguration{
+			Status: aws.String(s3.BucketAccelerateStatusEnabled),
+		},
+	}
+	if err := input.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket accelerate configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketAccelerateConfiguration", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketAccelerateConfiguration(ctx, input)
+		return err
+	})
+}
+
+// IsBucketObjectLocked checks whether object lock is enabled on the bucket,
+// which keeps the lifecycle expiration from deleting objects still under
+// retention. A bucket without an object lock configuration isn't locked.
+func IsBucketObjectLocked(ctx context.Context, s3Client Client, bucketName string) (bool, error) {
+	var output *s3.GetObjectLockConfigurationOutput
+	err := withRetry(ctx, "GetObjectLockConfiguration", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if isErrorCode(err, "ObjectLockConfigurationNotFoundError") {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read %v bucket object lock configuration: %w", bucketName, err)
+	}
+	return output.ObjectLockConfiguration != nil &&
+		aws.StringValue(output.ObjectLockConfiguration.ObjectLockEnabled) == s3.ObjectLockEnabledEnabled, nil
+}
+
+// LogDelivery selects how the S3 log delivery is allowed to write the server
+// access logs to the target bucket.
+type LogDelivery int
+
+const (
+	// LogDeliveryAuto grants the log delivery in the bucket policy of the
+	// target bucket when its ACLs are disabled, and leaves it to its ACL
+	// otherwise.
+	LogDeliveryAuto LogDelivery = iota
+	// LogDeliveryBucketPolicy grants the log delivery in the bucket policy of
+	// the target bucket.
+	LogDeliveryBucketPolicy
+	// LogDeliveryACL leaves the log delivery to the ACL of the target bucket.
+	LogDeliveryACL
+)
+
+// EnsureBucketLogging delivers the server access logs of the bucket to the
+// target bucket, under the target prefix, unless they are already delivered
+// there. The grants of an existing logging configuration are kept, unless the
+// ACLs of the target bucket are disabled by the BucketOwnerEnforced object
+// ownership, as S3 then rejects them. The S3 log delivery is allowed to write
+// to the target bucket as selected by delivery.
+func EnsureBucketLogging(
+	ctx context.Context, s3Client Client, bucketName string, targetBucket string, targetPrefix string, delivery LogDelivery) error {
+	aclsDisabled := false
+	if delivery != LogDeliveryACL {
+		var ownership *s3.GetBucketOwnershipControlsOutput
+		err := withRetry(ctx, "GetBucketOwnershipControls", func(ctx context.Context) (err error) {
+			ownership, err = s3Client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{
+				Bucket: aws.String(targetBucket),
+			})
+			return err
+		})
+		if err != nil && !isErrorCode(err, "OwnershipControlsNotFoundError") {
+			return fmt.Errorf("unable to read %v log target bucket ownership controls: %w", targetBucket, err)
+		}
+		aclsDisabled = err == nil && ownershipEnforced(ownership.OwnershipControls)
+	}
+	if delivery == LogDeliveryBucketPolicy || (delivery == LogDeliveryAuto && aclsDisabled) {
+		statement := logDeliveryStatement(clientPartitionID(s3Client), bucketName, targetBucket, targetPrefix)
+		if err := setBucketPolicyStatement(ctx, s3Client, targetBucket, statement, true); err != nil {
+			return err
+		}
+	}
+
+	var output *s3.GetBucketLoggingOutput
+	err := withRetry(ctx, "GetBucketLogging", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read %v bucket logging: %w", bucketName, err)
+	}
+	loggingEnabled := &s3.LoggingEnabled{}
+	if output.LoggingEnabled != nil {
+		if aws.StringValue(output.LoggingEnabled.TargetBucket) == targetBucket &&
+			aws.StringValue(output.LoggingEnabled.TargetPrefix) == targetPrefix &&
+			(!aclsDisabled || len(output.LoggingEnabled.TargetGrants) == 0) {
+			return nil
+		}
+		if !aclsDisabled {
+			loggingEnabled.TargetGrants = output.LoggingEnabled.TargetGrants
+		}
+	}
+	loggingEnabled.TargetBucket = aws.String(targetBucket)
+	loggingEnabled.TargetPrefix = aws.String(targetPrefix)
+
+	bucketLoggingInput := &s3.PutBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+		BucketLoggingStatus: &s3.BucketLoggingStatus{
+			LoggingEnabled: loggingEnabled,
+		},
+	}
+	if err := bucketLoggingInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket logging configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketLogging", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketLogging(ctx, bucketLoggingInput)
+		return err
+	})
+}
+
+// IsBucketEmpty checks whether the bucket holds no objects, including
+// noncurrent object versions and delete markers.
+func IsBucketEmpty(ctx context.Context, s3Client Client, bucketName string) (bool, error) {
+	var output *s3.ListObjectVersionsOutput
+	err := withRetry(ctx, "ListObjectVersions", func(ctx context.Context) (err error) {
+		output, err = s3Client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:  aws.String(bucketName),
+			MaxKeys: aws.Int64(1),
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to list %v bucket objects: %w", bucketName, err)
+	}
+	return len(output.Versions) == 0 && len(output.DeleteMarkers) == 0, nil
+}
+
+// DeleteBucket deletes the bucket. When force is set, every object version
+// and delete marker in the bucket is deleted first, otherwise the bucket must
+// already be empty.
+func DeleteBucket(ctx context.Context, s3Client Client, bucketName string, force bool) error {
+	if force {
+		if err := emptyBucket(ctx, s3Client, bucketName); err != nil {
+			return err
+		}
+	}
+	err := withRetry(ctx, "DeleteBucket", func(ctx context.Context) error {
+		_, err := s3Client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucketName)})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete %v bucket: %w", bucketName, err)
+	}
+	return nil
+}
+
+// emptyBucket deletes every object version and delete marker in the bucket.
+func emptyBucket(ctx context.Context, s3Client Client, bucketName string) error {
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucketName)}
+	for {
+		var output *s3.ListObjectVersionsOutput
+		err := withRetry(ctx, "ListObjectVersions", func(ctx context.Context) (err error) {
+			output, err = s3Client.ListObjectVersions(ctx, input)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list %v bucket objects: %w", bucketName, err)
+		}
+
+		var objects []*s3.ObjectIdentifier
+		for _, version := range output.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range output.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) > 0 {
+			var deleted *s3.DeleteObjectsOutput
+			err = withRetry(ctx, "DeleteObjects", func(ctx context.Context) (err error) {
+				deleted, err = s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+					Bucket: aws.String(bucketName),
+					Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+				})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("unable to delete %v bucket objects: %w", bucketName, err)
+			}
+			if len(deleted.Errors) > 0 {
+				return fmt.Errorf("unable to delete %v bucket object %v: %v", bucketName,
+					aws.StringValue(deleted.Errors[0].Key), aws.StringValue(deleted.Errors[0].Message))
+			}
+		}
+
+		if !aws.BoolValue(output.IsTruncated) {
+			return nil
+		}
+		input.KeyMarker = output.NextKeyMarker
+		input.VersionIdMarker = output.NextVersionIdMarker
+	}
+}
+
+// ReadBucketEncryption returns the encryption configuration of the bucket, or
+// ErrBucketNotEncrypted when it has none.
+func ReadBucketEncryption(ctx context.Context, s3Client Client, bucketName string) (*s3.ServerSideEncryptionConfiguration, error) {
+	var output *s3.GetBucketEncryptionOutput
+	err := withRetry(ctx, "GetBucketEncryption", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		if isErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+			return nil, fmt.Errorf("unable to read %v bucket encryption configuration: %w", bucketName, ErrBucketNotEncrypted)
+		}
+		return nil, fmt.Errorf("unable to read %v bucket encryption configuration: %w", bucketName, err)
+	}
+	return output.ServerSideEncryptionConfiguration, nil
+}
+
+// EnsureBucketEncryption compares the encryption configuration of the bucket
+// with the requested one, and only encrypts the bucket with EncryptBucket when
+// it differs, such as when the encryption was removed or its key was changed.
+func EnsureBucketEncryption(ctx context.Context, s3Client Client, bucketName string, sseAlgorithm string, kmsKeyID string) error {
+	expected := defaultEncryptionRule(sseAlgorithm, kmsKeyID)
+	current, err := ReadBucketEncryption(ctx, s3Client, bucketName)
+	if err != nil && !errors.Is(err, ErrBucketNotEncrypted) {
+		return err
+	}
+	if err == nil && encryptionMatches(current, EncryptionPlan{
+		Algorithm: aws.StringValue(expected.SSEAlgorithm),
+		KMSKeyID:  aws.StringValue(expected.KMSMasterKeyID),
+	}) {
+		return nil
+	}
+	return EncryptBucket(ctx, s3Client, bucketName, sseAlgorithm, kmsKeyID)
+}
+
+// KMSKey is the KMS key a bucket is encrypted with.
+type KMSKey struct {
+	// ID is the configured key ID, key ARN, alias or alias ARN.
+	ID string
+	// ResolvedARN is the ARN of the key the alias currently points to, when
+	// the ID is an alias.
+	ResolvedARN string
+	// AppliedID is the configured ID the bucket was last encrypted with, or
+	// empty when unknown.
+	AppliedID string
+}
+
+// EnsureBucketKMSEncryption behaves like EnsureBucketEncryption with the
+// aws:kms algorithm, but accepts the key an alias currently points to, so that
+// rotating the key behind the alias doesn't cause the bucket to be encrypted
+// again. When the bucket is encrypted with another key than a key ID which is
+// unchanged since it was applied, ErrKMSKeyRotated is returned and the bucket
+// is left unchanged. A changed key ID is always applied.
+func EnsureBucketKMSEncryption(ctx context.Context, s3Client Client, bucketName string, key KMSKey) error {
+	current, err := ReadBucketEncryption(ctx, s3Client, bucketName)
+	if err != nil && !errors.Is(err, ErrBucketNotEncrypted) {
+		return err
+	}
+	if err == nil && len(current.Rules) == 1 && current.Rules[0].ApplyServerSideEncryptionByDefault != nil {
+		actual := current.Rules[0].ApplyServerSideEncryptionByDefault
+		actualKeyID := aws.StringValue(actual.KMSMasterKeyID)
+		if aws.StringValue(actual.SSEAlgorithm) == s3.ServerSideEncryptionAwsKms && actualKeyID != "" {
+			switch {
+			case sameKMSKey(actualKeyID, key.ID):
+				return nil
+			case key.ResolvedARN != "" && sameKMSKey(actualKeyID, key.ResolvedARN):
+				return nil
+			case key.ResolvedARN == "" && key.AppliedID != "" && sameKMSKey(key.AppliedID, key.ID):
+				return fmt.Errorf("bucket %v is encrypted with KMS key %v rather than %v: %w", bucketName, actualKeyID, key.ID, ErrKMSKeyRotated)
 			}
-		} else {
-			return false, fmt.Errorf("unable to determine bucket %v status: %v", bucketName, aerr.Error())
 		}
 	}
+	return EncryptBucket(ctx, s3Client, bucketName, s3.ServerSideEncryptionAwsKms, key.ID)
+}
 
-	return true, nil
+// defaultEncryptionRule returns the default encryption rule for the algorithm,
+// which defaults to AES256. The kmsKeyID is only used with the aws:kms
+// algorithm, and when empty the AWS managed aws/s3 key is used instead.
+func defaultEncryptionRule(sseAlgorithm string, kmsKeyID string) *s3.ServerSideEncryptionByDefault {
+	if sseAlgorithm == "" {
+		sseAlgorithm = s3.ServerSideEncryptionAes256
+	}
+	rule := &s3.ServerSideEncryptionByDefault{
+		SSEAlgorithm: aws.String(sseAlgorithm),
+	}
+	if sseAlgorithm == s3.ServerSideEncryptionAwsKms && kmsKeyID != "" {
+		rule.KMSMasterKeyID = aws.String(kmsKeyID)
+	}
+	return rule
 }
 
 // EncryptBucket sets the encryption configuration for the bucket.
-func EncryptBucket(s3Client Client, bucketName string) error {
+// The sseAlgorithm defaults to AES256. The kmsKeyID is only used with the
+// aws:kms algorithm, and when empty the AWS managed aws/s3 key is used instead.
+// The configuration always holds a single rule, replacing any previous rules,
+// and is read back afterwards to confirm that it was applied.
+func EncryptBucket(ctx context.Context, s3Client Client, bucketName string, sseAlgorithm string, kmsKeyID string) error {
+	encryptionByDefault := defaultEncryptionRule(sseAlgorithm, kmsKeyID)
 	bucketEncryptionInput := &s3.PutBucketEncryptionInput{
 		Bucket: aws.String(bucketName),
 		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
 			Rules: []*s3.ServerSideEncryptionRule{
 				{
-					ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
-						SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256),
-					},
+					ApplyServerSideEncryptionByDefault: encryptionByDefault,
 				},
 			},
 		},
@@ -82,13 +564,43 @@ func EncryptBucket(s3Client Client, bucketName string) error {
 		return fmt.Errorf("unable to validate %v bucket encryption configuration: %v", bucketName, err)
 	}
 
-	_, err := s3Client.PutBucketEncryption(bucketEncryptionInput)
+	err := withRetry(ctx, "PutBucketEncryption", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketEncryption(ctx, bucketEncryptionInput)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyBucketEncryption(ctx, s3Client, bucketName, encryptionByDefault)
+}
 
-	return err
+// verifyBucketEncryption reads back the encryption configuration for the bucket
+// and checks that it consists of a single rule matching the expected one.
+func verifyBucketEncryption(ctx context.Context, s3Client Client, bucketName string, expected *s3.ServerSideEncryptionByDefault) error {
+	var output *s3.GetBucketEncryptionOutput
+	err := withRetry(ctx, "GetBucketEncryption", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read back %v bucket encryption configuration: %w", bucketName, err)
+	}
+
+	if !encryptionMatches(output.ServerSideEncryptionConfiguration, EncryptionPlan{
+		Algorithm: aws.StringValue(expected.SSEAlgorithm),
+		KMSKeyID:  aws.StringValue(expected.KMSMasterKeyID),
+	}) {
+		return fmt.Errorf("bucket %v encryption configuration does not match the requested configuration: %w", bucketName, ErrEncryptionImmutable)
+	}
+
+	return nil
 }
 
 // BlockBucketPublicAccess blocks public access to the bucket's contents.
-func BlockBucketPublicAccess(s3Client Client, bucketName string) error {
+func BlockBucketPublicAccess(ctx context.Context, s3Client Client, bucketName string) error {
 	publicAccessBlockInput := &s3.PutPublicAccessBlockInput{
 		Bucket: aws.String(bucketName),
 		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
@@ -103,38 +615,163 @@ func BlockBucketPublicAccess(s3Client Client, bucketName string) error {
 		return fmt.Errorf("unable to validate %v bucket public access configuration: %v", bucketName, err)
 	}
 
-	_, err := s3Client.PutPublicAccessBlock(publicAccessBlockInput)
+	return withRetry(ctx, "PutPublicAccessBlock", func(ctx context.Context) error {
+		_, err := s3Client.PutPublicAccessBlock(ctx, publicAccessBlockInput)
+		return err
+	})
+}
 
-	return err
+// EnsurePublicAccessBlock compares the public access block of the bucket with
+// the blocked state, and only blocks public access with BlockBucketPublicAccess
+// when any of its flags differ, such as when another tool re-enabled public
+// access, or when the public access block is missing.
+func EnsurePublicAccessBlock(ctx context.Context, s3Client Client, bucketName string) error {
+	var output *s3.GetPublicAccessBlockOutput
+	err := withRetry(ctx, "GetPublicAccessBlock", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil && !isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return fmt.Errorf("unable to read %v bucket public access configuration: %w", bucketName, err)
+	}
+	if err == nil && publicAccessBlocked(output.PublicAccessBlockConfiguration) {
+		return nil
+	}
+	return BlockBucketPublicAccess(ctx, s3Client, bucketName)
 }
 
-// SetBucketLifecycle sets a lifecycle on the specified bucket.
-func SetBucketLifecycle(s3Client Client, bucketName string) error {
-	bucketLifecycleConfigurationInput := &s3.PutBucketLifecycleConfigurationInput{
+// EnsureOwnershipControls makes the bucket owner own every object of the
+// bucket, disabling its ACLs, unless the ownership controls of the bucket
+// already enforce this.
+func EnsureOwnershipControls(ctx context.Context, s3Client Client, bucketName string) error {
+	var output *s3.GetBucketOwnershipControlsOutput
+	err := withRetry(ctx, "GetBucketOwnershipControls", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil && !isErrorCode(err, "OwnershipControlsNotFoundError") {
+		return fmt.Errorf("unable to read %v bucket ownership controls: %w", bucketName, err)
+	}
+	if err == nil && ownershipEnforced(output.OwnershipControls) {
+		return nil
+	}
+
+	ownershipControlsInput := &s3.PutBucketOwnershipControlsInput{
 		Bucket: aws.String(bucketName),
-		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
-			Rules: []*s3.LifecycleRule{
-				{
-					ID:     aws.String("Backup Expiry"),
-					Status: aws.String("Enabled"),
-					Filter: &s3.LifecycleRuleFilter{
-						Prefix: aws.String("backups/"),
-					},
-					Expiration: &s3.LifecycleExpiration{
-						Days: aws.Int64(90),
-					},
-				},
+		OwnershipControls: &s3.OwnershipControls{
+			Rules: []*s3.OwnershipControlsRule{
+				{ObjectOwnership: aws.String(s3.ObjectOwnershipBucketOwnerEnforced)},
 			},
 		},
 	}
+	if err := ownershipControlsInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket ownership controls: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketOwnershipControls", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketOwnershipControls(ctx, ownershipControlsInput)
+		return err
+	})
+}
+
+// VerifyBucketWritable checks that objects can be written to the bucket, by
+// writing and then removing a probe object under the given key prefix.
+func VerifyBucketWritable(ctx context.Context, s3Client Client, bucketName string, prefix string) error {
+	key := aws.String(prefix + writableProbeName)
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    key,
+		Body:   bytes.NewReader([]byte{}),
+	}
+	if err := putObjectInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket probe object: %v", bucketName, err)
+	}
+	err := withRetry(ctx, "PutObject", func(ctx context.Context) error {
+		_, err := s3Client.PutObject(ctx, putObjectInput)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write probe object %v to bucket %v: %w", *key, bucketName, err)
+	}
+
+	deleteObjectInput := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    key,
+	}
+	err = withRetry(ctx, "DeleteObject", func(ctx context.Context) error {
+		_, err := s3Client.DeleteObject(ctx, deleteObjectInput)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove probe object %v from bucket %v: %w", *key, bucketName, err)
+	}
+
+	return nil
+}
+
+// SetBucketLifecycle sets a lifecycle on the specified bucket, consisting of
+// the backup expiry rule.
+func SetBucketLifecycle(ctx context.Context, s3Client Client, bucketName string, backupExpiry LifecycleRulePlan) error {
+	return withBucketRegion(ctx, s3Client, bucketName, func(s3Client Client) error {
+		return putBucketLifecycle(ctx, s3Client, bucketName, []LifecycleRulePlan{backupExpiry})
+	})
+}
+
+// EnsureBucketLifecycle sets the lifecycle rules of the bucket to the planned
+// rules, unless they already match. The rules are compared regardless of
+// their order, which S3 needn't keep. A bucket living in another region than
+// the s3Client's is configured in its own region.
+func EnsureBucketLifecycle(ctx context.Context, s3Client Client, bucketName string, rules []LifecycleRulePlan) error {
+	return withBucketRegion(ctx, s3Client, bucketName, func(s3Client Client) error {
+		return ensureBucketLifecycle(ctx, s3Client, bucketName, rules)
+	})
+}
+
+// ensureBucketLifecycle implements EnsureBucketLifecycle with a client for
+// the region of the bucket.
+func ensureBucketLifecycle(ctx context.Context, s3Client Client, bucketName string, rules []LifecycleRulePlan) error {
+	var output *s3.GetBucketLifecycleConfigurationOutput
+	err := withRetry(ctx, "GetBucketLifecycleConfiguration", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	switch {
+	case isErrorCode(err, "NoSuchLifecycleConfiguration"):
+	case err != nil:
+		return fmt.Errorf("unable to read %v bucket lifecycle configuration: %w", bucketName, err)
+	case lifecycleMatches(output.Rules, rules):
+		return nil
+	}
+	return putBucketLifecycle(ctx, s3Client, bucketName, rules)
+}
+
+// putBucketLifecycle replaces the lifecycle configuration of the bucket with the rules.
+func putBucketLifecycle(ctx context.Context, s3Client Client, bucketName string, rules []LifecycleRulePlan) error {
+	bucketLifecycleConfigurationInput := &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucketName),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{},
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid %v bucket lifecycle configuration: %w", bucketName, err)
+		}
+		bucketLifecycleConfigurationInput.LifecycleConfiguration.Rules = append(
+			bucketLifecycleConfigurationInput.LifecycleConfiguration.Rules, rule.lifecycleRule())
+	}
 
 	if err := bucketLifecycleConfigurationInput.Validate(); err != nil {
 		return fmt.Errorf("unable to validate %v bucket lifecycle configuration: %v", bucketName, err)
 	}
 
-	_, err := s3Client.PutBucketLifecycleConfiguration(bucketLifecycleConfigurationInput)
-
-	return err
+	return withRetry(ctx, "PutBucketLifecycleConfiguration", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketLifecycleConfiguration(ctx, bucketLifecycleConfigurationInput)
+		return err
+	})
 }
 
 // CreateBucketTaggingInput creates an S3 PutBucketTaggingInput object,
@@ -158,24 +795,33 @@ func CreateBucketTaggingInput(bucketname string, tags map[string]string) *s3.Put
 
 // ClearBucketTags wipes all existing tags from a bucket so that velero-specific
 // tags can be applied to the bucket instead.
-func ClearBucketTags(s3Client Client, bucketName string) (err error) {
+func ClearBucketTags(ctx context.Context, s3Client Client, bucketName string) (err error) {
 	deleteInput := &s3.DeleteBucketTaggingInput{Bucket: aws.String(bucketName)}
-	_, err = s3Client.DeleteBucketTagging(deleteInput)
-	return err
+	return withRetry(ctx, "DeleteBucketTagging", func(ctx context.Context) error {
+		_, err := s3Client.DeleteBucketTagging(ctx, deleteInput)
+		return err
+	})
 }
 
 // TagBucket adds tags to an S3 bucket. The tags are used to indicate that velero backups
 // are stored in the bucket, and to identify the associated cluster.
-func TagBucket(s3Client Client, bucketName string, backUpLocation string, infraName string) error {
-	err := ClearBucketTags(s3Client, bucketName)
-	if err != nil {
-		return fmt.Errorf("unable to clear %v bucket tags: %v", bucketName, err)
-	}
-	input := CreateBucketTaggingInput(bucketName, map[string]string{
-		bucketTagBackupLocation: backUpLocation,
-		bucketTagInfraName:      infraName,
+// Any extraTags are applied alongside these, but never replace the tags used to
+// identify the bucket. The existing tags are cleared first, which migrates the
+// tags keyed under an earlier tag key prefix to tagKeyPrefix, as passed to
+// TagKey. The infrastructure name is tagged as sanitized by SanitizeTagValue.
+// A bucket living in another region than the s3Client's is tagged in its own region.
+func TagBucket(ctx context.Context, s3Client Client, bucketName string, tagKeyPrefix string, backUpLocation string, infraName string, extraTags map[string]string) error {
+	input := CreateBucketTaggingInput(bucketName, bucketTagSet(tagKeyPrefix, backUpLocation, infraName, extraTags))
+	err := withBucketRegion(ctx, s3Client, bucketName, func(s3Client Client) error {
+		err := ClearBucketTags(ctx, s3Client, bucketName)
+		if err != nil {
+			return fmt.Errorf("unable to clear %v bucket tags: %w", bucketName, err)
+		}
+		return withRetry(ctx, "PutBucketTagging", func(ctx context.Context) error {
+			_, err := s3Client.PutBucketTagging(ctx, input)
+			return err
+		})
 	})
-	_, err = s3Client.PutBucketTagging(input)
 	if err != nil {
 		fmt.Println(err.Error())
 		return err
@@ -183,10 +829,31 @@ func TagBucket(s3Client Client, bucketName string, backUpLocation string, infraN
 	return nil
 }
 
-// ListBuckets lists all buckets in the AWS account.
-func ListBuckets(s3Client Client) (*s3.ListBucketsOutput, error) {
+// bucketTagSet merges the extraTags with the tags used to identify the bucket,
+// which are keyed under the tag key prefix.
+func bucketTagSet(tagKeyPrefix string, backUpLocation string, infraName string, extraTags map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for key, value := range extraTags {
+		tags[key] = value
+	}
+	tags[TagKey(tagKeyPrefix, bucketTagBackupLocation)] = backUpLocation
+	tags[TagKey(tagKeyPrefix, bucketTagInfraName)] = SanitizeTagValue(infraName)
+	return tags
+}
+
+// ListBuckets lists all buckets in the AWS account. The ListBuckets API of
+// this SDK version isn't paginated: S3 returns every bucket of the account in
+// a single response, and there is no continuation token to follow. An
+// S3-compatible endpoint truncating the listing would hide the missing buckets
+// from ScanBucketTags, so that an existing bucket isn't recovered and a
+// duplicate bucket is created instead.
+func ListBuckets(ctx context.Context, s3Client Client) (*s3.ListBucketsOutput, error) {
 	input := &s3.ListBucketsInput{}
-	result, err := s3Client.ListBuckets(input)
+	var result *s3.ListBucketsOutput
+	err := withRetry(ctx, "ListBuckets", func(ctx context.Context) (err error) {
+		result, err = s3Client.ListBuckets(ctx, input)
+		return err
+	})
 	if err != nil {
 		fmt.Println(err.Error())
 		return result, err
@@ -194,61 +861,270 @@ func ListBuckets(s3Client Client) (*s3.ListBucketsOutput, error) {
 	return result, nil
 }
 
+// CreationDate returns the creation date of the bucket in the listing, or nil
+// when the bucket isn't listed or the listing reports no date.
+func CreationDate(buckets *s3.ListBucketsOutput, bucketName string) *time.Time {
+	if buckets == nil {
+		return nil
+	}
+	for _, bucket := range buckets.Buckets {
+		if aws.StringValue(bucket.Name) == bucketName {
+			return bucket.CreationDate
+		}
+	}
+	return nil
+}
+
+// BucketCreationDate lists the buckets, and returns the creation date of the
+// bucket, or nil when the bucket isn't listed as it belongs to another account.
+func BucketCreationDate(ctx context.Context, s3Client Client, bucketName string) (*time.Time, error) {
+	buckets, err := ListBuckets(ctx, s3Client)
+	if err != nil {
+		return nil, err
+	}
+	return CreationDate(buckets, bucketName), nil
+}
+
 // ListBucketTags returns a list of s3.GetBucketTagging objects, one for each bucket.
 // If the bucket is not readable, or has no tags, the bucket name is omitted from the taglist.
 // So taglist only contains the list of buckets that have tags.
-func ListBucketTags(s3Client Client, bucketlist *s3.ListBucketsOutput) (map[string]*s3.GetBucketTaggingOutput, error) {
+func ListBucketTags(ctx context.Context, s3Client Client, bucketlist *s3.ListBucketsOutput) (map[string]*s3.GetBucketTaggingOutput, error) {
+	return ScanBucketTags(ctx, s3Client, bucketlist, ScanOptions{})
+}
+
+// DefaultScanConcurrency is how many buckets ScanBucketTags reads the tags of
+// at once, unless configured otherwise.
+const DefaultScanConcurrency = 10
+
+// ScanOptions configures how ScanBucketTags searches the buckets.
+type ScanOptions struct {
+	// RegionalClients are tried in turn when the tags of a bucket can't be
+	// read from the s3Client's region, because the bucket lives elsewhere.
+	RegionalClients []Client
+
+	// Exclude lists the bucket name globs whose tags are never read.
+	Exclude []string
+
+	// Concurrency bounds how many buckets have their tags read at once,
+	// defaulting to DefaultScanConcurrency.
+	Concurrency int
+}
+
+// ScanBucketTags behaves like ListBucketTags, but searches the buckets as
+// configured by the options. The tags of up to Concurrency buckets are read at
+// once, each retrying its throttled reads. The first error which isn't
+// retryable cancels the rest of the scan.
+func ScanBucketTags(ctx context.Context, s3Client Client, bucketlist *s3.ListBucketsOutput, options ScanOptions) (map[string]*s3.GetBucketTaggingOutput, error) {
 	taglist := make(map[string]*s3.GetBucketTaggingOutput)
+	var bucketNames []string
 	for _, bucket := range bucketlist.Buckets {
-		request := &s3.GetBucketTaggingInput{
-			Bucket: aws.String(*bucket.Name),
-		}
-		response, err := s3Client.GetBucketTagging(request)
+		excluded, err := matchesAny(*bucket.Name, options.Exclude)
 		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case "NoSuchTagSet":
-					// There are no tags on this bucket, continue.
-					continue
-				case "NoSuchBucket":
-					// The bucket specified no longer exists (can be due to delays in AWS API), continue.
-					continue
-				default:
-					return taglist, err
-				}
-			} else {
-				return taglist, err
+			return taglist, err
+		}
+		if !excluded {
+			bucketNames = append(bucketNames, *bucket.Name)
+		}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultScanConcurrency
+	}
+	if concurrency > len(bucketNames) {
+		concurrency = len(bucketNames)
+	}
+
+	clients := append([]Client{s3Client}, options.RegionalClients...)
+	group, scanCtx := errgroup.WithContext(ctx)
+	// slots holds a token for every bucket whose tags are being read
+	slots := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+scan:
+	for _, bucketName := range bucketNames {
+		select {
+		case slots <- struct{}{}:
+		case <-scanCtx.Done():
+			break scan
+		}
+		bucketName := bucketName
+		group.Go(func() error {
+			defer func() { <-slots }()
+			response, err := getBucketTagging(scanCtx, clients, &s3.GetBucketTaggingInput{
+				Bucket: aws.String(bucketName),
+			})
+			// There are no tags on the bucket, or it no longer exists (can be due to delays in AWS API)
+			if isErrorCode(err, "NoSuchTagSet") || isErrorCode(err, s3.ErrCodeNoSuchBucket) {
+				return nil
 			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			taglist[bucketName] = response
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return taglist, err
+	}
+	// The scan is incomplete when the context was cancelled
+	return taglist, ctx.Err()
+}
+
+// matchesAny checks whether the bucket name matches any of the globs.
+func matchesAny(bucketName string, globs []string) (bool, error) {
+	for _, glob := range globs {
+		matched, err := path.Match(glob, bucketName)
+		if err != nil {
+			return false, fmt.Errorf("invalid bucket name glob %q: %v", glob, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getBucketTagging reads the tags of a bucket with the first of the clients
+// that is in the bucket's region.
+func getBucketTagging(ctx context.Context, clients []Client, request *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	var response *s3.GetBucketTaggingOutput
+	var err error
+	for _, client := range clients {
+		err = withRegionHint(ctx, client, func(client Client) error {
+			return withRetry(ctx, "GetBucketTagging", func(ctx context.Context) error {
+				response, err = client.GetBucketTagging(ctx, request)
+				return err
+			})
+		})
+		if !isWrongRegionError(err) {
+			break
+		}
+	}
+	return response, err
+}
+
+// regionHintPattern matches the region S3 expects a request to be signed for
+// in the message of an AuthorizationHeaderMalformed error.
+var regionHintPattern = regexp.MustCompile(`expecting '([a-z0-9-]+)'`)
+
+// regionHint extracts the bucket's region from an AuthorizationHeaderMalformed
+// error, returned when the bucket was addressed through another region.
+func regionHint(err error) (string, bool) {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != "AuthorizationHeaderMalformed" {
+		return "", false
+	}
+	match := regionHintPattern.FindStringSubmatch(aerr.Message())
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// withRegionHint runs call with the s3Client, retrying it once with a client
+// for the bucket's region when S3 reports the bucket lives elsewhere.
+func withRegionHint(ctx context.Context, s3Client Client, call func(Client) error) error {
+	err := call(s3Client)
+	region, ok := regionHint(err)
+	if !ok {
+		return err
+	}
+	regionalClient, clientErr := s3Client.ForRegion(region)
+	if clientErr != nil {
+		return fmt.Errorf("unable to create S3 client for region %v: %w", region, clientErr)
+	}
+	return call(regionalClient)
+}
+
+// withBucketRegion behaves like withRegionHint, but looks the region of the
+// bucket up with GetBucketLocation when S3 redirects the request without
+// telling the region, such as with a PermanentRedirect, before retrying the
+// call once in that region.
+func withBucketRegion(ctx context.Context, s3Client Client, bucketName string, call func(Client) error) error {
+	err := withRegionHint(ctx, s3Client, call)
+	if !isWrongRegionError(err) {
+		return err
+	}
+	region, lookupErr := BucketRegion(ctx, s3Client, bucketName)
+	if lookupErr != nil || region == aws.StringValue(s3Client.GetAWSClientConfig().Region) {
+		return err
+	}
+	regionalClient, clientErr := s3Client.ForRegion(region)
+	if clientErr != nil {
+		return fmt.Errorf("unable to create S3 client for region %v: %w", region, clientErr)
+	}
+	return call(regionalClient)
+}
+
+// isWrongRegionError checks whether the error was caused by addressing a bucket
+// through a client for a different region than its own.
+func isWrongRegionError(err error) bool {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		// HEAD responses have no body, so their error code is the 301 status text
+		case "PermanentRedirect", "MovedPermanently", "AuthorizationHeaderMalformed", "IllegalLocationConstraintException":
+			return true
 		}
-		taglist[*bucket.Name] = response
 	}
-	return taglist, nil
+	return false
 }
 
 // FindMatchingTags looks through the TagSets for all AWS buckets and determines if
-// any of the buckets are tagged for velero updates for the cluster.
-// If matching tags are found, the bucket name is returned.
-func FindMatchingTags(buckets map[string]*s3.GetBucketTaggingOutput, infraName string) string {
-	var tagMatchesCluster, tagMatchesVelero bool
-	var possiblematch string
+// any of the buckets are tagged for the velero backup location of the cluster.
+// If matching tags are found, the bucket name is returned, which is the first
+// in sorted order when several buckets match. The tags are matched under the
+// tag key prefix, or under DefaultTagKeyPrefix for the buckets tagged before
+// the prefix was configured. The infrastructure name is matched
+// as sanitized by SanitizeTagValue, as TagBucket tags it, or as it is, for
+// the buckets tagged before it was sanitized.
+func FindMatchingTags(buckets map[string]*s3.GetBucketTaggingOutput, tagKeyPrefix string, backupLocation string, infraName string) string {
+	matches := FindAllMatchingTags(buckets, tagKeyPrefix, backupLocation, infraName)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// FindMatchingLegacyTags looks through the TagSets for all AWS buckets and
+// determines if any of the buckets are tagged for the velero backup location
+// of the cluster under one of its legacy infrastructure names, such as from
+// before the cluster was renamed. The legacy names are tried in order, and the
+// name of the bucket is returned with the legacy name it is tagged with, as
+// FindMatchingTags matches them.
+func FindMatchingLegacyTags(buckets map[string]*s3.GetBucketTaggingOutput, tagKeyPrefix string, backupLocation string, legacyInfraNames []string) (string, string) {
+	for _, legacyInfraName := range legacyInfraNames {
+		if bucket := FindMatchingTags(buckets, tagKeyPrefix, backupLocation, legacyInfraName); bucket != "" {
+			return bucket, legacyInfraName
+		}
+	}
+	return "", ""
+}
+
+// FindAllMatchingTags behaves like FindMatchingTags, but returns the sorted
+// names of every matching bucket. More than one match means the tags of a
+// bucket were copied, such as by cloning it, and the bucket to use is ambiguous.
+func FindAllMatchingTags(buckets map[string]*s3.GetBucketTaggingOutput, tagKeyPrefix string, backupLocation string, infraName string) []string {
+	var matches []string
 	for bucket, tags := range buckets {
+		var tagMatchesCluster, tagMatchesVelero bool
 		for _, tag := range tags.TagSet {
-			if *tag.Key == bucketTagInfraName && *tag.Value == infraName {
+			if isTagKey(tagKeyPrefix, *tag.Key, bucketTagInfraName) && (*tag.Value == infraName || *tag.Value == SanitizeTagValue(infraName)) {
 				tagMatchesCluster = true
-				possiblematch = bucket
 			}
-			if *tag.Key == bucketTagBackupLocation {
+			if isTagKey(tagKeyPrefix, *tag.Key, bucketTagBackupLocation) && *tag.Value == backupLocation {
 				tagMatchesVelero = true
-				possiblematch = bucket
 			}
 		}
-	}
 
-	// If these two conditions are true, the match is confirmed.
-	if tagMatchesCluster && tagMatchesVelero {
-		return possiblematch
+		// If these two conditions are true for the same bucket, the match is confirmed.
+		if tagMatchesCluster && tagMatchesVelero {
+			matches = append(matches, bucket)
+		}
 	}
-
-	// No matching buckets found.
-	return ""
+	sort.Strings(matches)
+	return matches
 }
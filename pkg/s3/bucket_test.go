@@ -1,14 +1,24 @@
 package s3
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/openshift/managed-velero-operator/pkg/metrics"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 const (
@@ -31,18 +41,103 @@ var fakeClient = mockAWSClient{
 type mockAWSClient struct {
 	s3Client s3iface.S3API
 	Config   *aws.Config
+
+	// encryptionConfiguration holds the last applied encryption configuration,
+	// and is returned by GetBucketEncryption.
+	encryptionConfiguration *s3.ServerSideEncryptionConfiguration
+	// lifecycleConfiguration holds the last applied lifecycle configuration,
+	// and is returned by GetBucketLifecycleConfiguration.
+	lifecycleConfiguration *s3.BucketLifecycleConfiguration
+	// putBucketLifecycleConfigurationInputs records every PutBucketLifecycleConfiguration request.
+	putBucketLifecycleConfigurationInputs []*s3.PutBucketLifecycleConfigurationInput
+	// publicAccessBlockConfiguration holds the last applied public access block,
+	// and is returned by GetPublicAccessBlock.
+	publicAccessBlockConfiguration *s3.PublicAccessBlockConfiguration
+	// putPublicAccessBlockInputs records every PutPublicAccessBlock request.
+	putPublicAccessBlockInputs []*s3.PutPublicAccessBlockInput
+	// putBucketEncryptionInputs records every PutBucketEncryption request.
+	putBucketEncryptionInputs []*s3.PutBucketEncryptionInput
+	// putBucketTaggingInputs records every PutBucketTagging request.
+	putBucketTaggingInputs []*s3.PutBucketTaggingInput
+	// putObjectInputs records every PutObject request.
+	putObjectInputs []*s3.PutObjectInput
+	// deleteObjectInputs records every DeleteObject request.
+	deleteObjectInputs []*s3.DeleteObjectInput
+	// bucketPolicy holds the last applied bucket policy, and is returned by GetBucketPolicy.
+	bucketPolicy *string
+	// putBucketPolicyInputs records every PutBucketPolicy request.
+	putBucketPolicyInputs []*s3.PutBucketPolicyInput
+	// versioningStatus is returned by GetBucketVersioning.
+	versioningStatus *string
+	// putBucketVersioningInputs records every PutBucketVersioning request.
+	putBucketVersioningInputs []*s3.PutBucketVersioningInput
+	// loggingEnabled holds the last applied logging configuration, and is
+	// returned by GetBucketLogging.
+	loggingEnabled *s3.LoggingEnabled
+	// putBucketLoggingInputs records every PutBucketLogging request.
+	putBucketLoggingInputs []*s3.PutBucketLoggingInput
+	// objectLockConfiguration is returned by GetObjectLockConfiguration,
+	// which fails as S3 does when it is unset.
+	objectLockConfiguration *s3.ObjectLockConfiguration
+	// replicationConfiguration holds the last applied replication
+	// configuration, and is returned by GetBucketReplication.
+	replicationConfiguration *s3.ReplicationConfiguration
+	// putBucketReplicationInputs records every PutBucketReplication request.
+	putBucketReplicationInputs []*s3.PutBucketReplicationInput
+	// ownershipControls holds the last applied ownership controls, and is
+	// returned by GetBucketOwnershipControls.
+	ownershipControls *s3.OwnershipControls
+	// putBucketOwnershipControlsInputs records every PutBucketOwnershipControls request.
+	putBucketOwnershipControlsInputs []*s3.PutBucketOwnershipControlsInput
+	// corsRules holds the last applied CORS rules, and is returned by
+	// GetBucketCors.
+	corsRules []*s3.CORSRule
+	// putBucketCorsInputs records every PutBucketCors request.
+	putBucketCorsInputs []*s3.PutBucketCorsInput
+	// notification holds the last applied notification configuration, and is
+	// returned by GetBucketNotificationConfiguration.
+	notification *s3.NotificationConfiguration
+	// putBucketNotificationInputs records every PutBucketNotificationConfiguration request.
+	putBucketNotificationInputs []*s3.PutBucketNotificationConfigurationInput
 }
 
 // CreateBucket implements the CreateBucket method for mockAWSClient.
-func (c *mockAWSClient) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+func (c *mockAWSClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
 	return &s3.CreateBucketOutput{
 		Location: aws.String(region),
 	}, nil
 }
 
+// DeleteBucket implements the DeleteBucket method for mockAWSClient.
+func (c *mockAWSClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for mockAWSClient.
+func (c *mockAWSClient) DeleteBucketPolicy(ctx context.Context, input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	c.bucketPolicy = nil
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
 // DeleteBucketTagging implements the DeleteBucketTagging method for mockAWSClient.
-func (c *mockAWSClient) DeleteBucketTagging(input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
-	return c.s3Client.DeleteBucketTagging(input)
+func (c *mockAWSClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	return &s3.DeleteBucketTaggingOutput{}, nil
+}
+
+// DeleteObject implements the DeleteObject method for mockAWSClient.
+func (c *mockAWSClient) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	c.deleteObjectInputs = append(c.deleteObjectInputs, input)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects implements the DeleteObjects method for mockAWSClient.
+func (c *mockAWSClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+// ForRegion implements the ForRegion method for mockAWSClient.
+func (c *mockAWSClient) ForRegion(region string) (Client, error) {
+	return &mockAWSClient{Config: c.Config.Copy().WithRegion(region)}, nil
 }
 
 // GetAWSClientConfig returns a copy of the AWS Client Config for the mockAWSClient.
@@ -52,15 +147,91 @@ func (c *mockAWSClient) GetAWSClientConfig() *aws.Config {
 
 // HeadBucket implements the HeadBucket method for mockAWSClient.
 // This mocks the AWS API response of having access to a single bucket named "testBucket".
-func (c *mockAWSClient) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+func (c *mockAWSClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
 	if *input.Bucket == "testBucket" {
 		return &s3.HeadBucketOutput{}, nil
 	}
 	return &s3.HeadBucketOutput{}, awserr.New("NotFound", "Not Found", nil)
 }
 
+// GetBucketCors implements the GetBucketCors method for mockAWSClient.
+func (c *mockAWSClient) GetBucketCors(ctx context.Context, input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	if c.corsRules == nil {
+		return nil, awserr.New("NoSuchCORSConfiguration", "The CORS configuration does not exist", nil)
+	}
+	return &s3.GetBucketCorsOutput{CORSRules: c.corsRules}, nil
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for mockAWSClient.
+func (c *mockAWSClient) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	if c.encryptionConfiguration == nil {
+		return nil, awserr.New("ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found", nil)
+	}
+	return &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: c.encryptionConfiguration,
+	}, nil
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for mockAWSClient.
+func (c *mockAWSClient) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if c.lifecycleConfiguration == nil {
+		return nil, awserr.New("NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist", nil)
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{
+		Rules: c.lifecycleConfiguration.Rules,
+	}, nil
+}
+
+// GetBucketLocation implements the GetBucketLocation method for mockAWSClient.
+// This mocks every bucket living in the region of the mockAWSClient.
+func (c *mockAWSClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{
+		LocationConstraint: c.Config.Region,
+	}, nil
+}
+
+// GetBucketLogging implements the GetBucketLogging method for mockAWSClient.
+func (c *mockAWSClient) GetBucketLogging(ctx context.Context, input *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error) {
+	return &s3.GetBucketLoggingOutput{LoggingEnabled: c.loggingEnabled}, nil
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for mockAWSClient.
+func (c *mockAWSClient) GetBucketNotificationConfiguration(
+	ctx context.Context, input *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error) {
+	if c.notification == nil {
+		return &s3.NotificationConfiguration{}, nil
+	}
+	return c.notification, nil
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for mockAWSClient.
+func (c *mockAWSClient) GetBucketOwnershipControls(
+	ctx context.Context, input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if c.ownershipControls == nil {
+		return nil, awserr.New("OwnershipControlsNotFoundError", "The bucket ownership controls were not found", nil)
+	}
+	return &s3.GetBucketOwnershipControlsOutput{OwnershipControls: c.ownershipControls}, nil
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for mockAWSClient.
+func (c *mockAWSClient) GetBucketPolicy(ctx context.Context, input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	if c.bucketPolicy == nil {
+		return nil, awserr.New("NoSuchBucketPolicy", "The bucket policy does not exist", nil)
+	}
+	return &s3.GetBucketPolicyOutput{Policy: c.bucketPolicy}, nil
+}
+
+// GetBucketReplication implements the GetBucketReplication method for mockAWSClient.
+func (c *mockAWSClient) GetBucketReplication(ctx context.Context, input *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	if c.replicationConfiguration == nil {
+		return nil, awserr.New("ReplicationConfigurationNotFoundError", "The replication configuration was not found", nil)
+	}
+	return &s3.GetBucketReplicationOutput{ReplicationConfiguration: c.replicationConfiguration}, nil
+}
+
 // GetBucketTagging implements the GetBucketTagging method for mockAWSClient.
-func (c *mockAWSClient) GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+func (c *mockAWSClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
 	if *input.Bucket == "testBucket" {
 		return &s3.GetBucketTaggingOutput{
 			TagSet: []*s3.Tag{
@@ -80,51 +251,153 @@ func (c *mockAWSClient) GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.G
 	}, nil
 }
 
+// GetBucketVersioning implements the GetBucketVersioning method for mockAWSClient.
+func (c *mockAWSClient) GetBucketVersioning(ctx context.Context, input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	return &s3.GetBucketVersioningOutput{Status: c.versioningStatus}, nil
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for mockAWSClient.
+func (c *mockAWSClient) GetObjectLockConfiguration(
+	ctx context.Context, input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	if c.objectLockConfiguration == nil {
+		return nil, awserr.New("ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", nil)
+	}
+	return &s3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: c.objectLockConfiguration}, nil
+}
+
 // GetPublicAccessBlock implements the GetPublicAccessBlock method for mockAWSClient.
-func (c *mockAWSClient) GetPublicAccessBlock(input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
-	return c.s3Client.GetPublicAccessBlock(input)
+func (c *mockAWSClient) GetPublicAccessBlock(ctx context.Context, input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	if c.publicAccessBlockConfiguration == nil {
+		return nil, awserr.New("NoSuchPublicAccessBlockConfiguration", "The public access block configuration was not found", nil)
+	}
+	return &s3.GetPublicAccessBlockOutput{
+		PublicAccessBlockConfiguration: c.publicAccessBlockConfiguration,
+	}, nil
 }
 
 // ListBuckets implements the ListBuckets method for mockAWSClient.
-func (c *mockAWSClient) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-	return c.s3Client.ListBuckets(input)
+func (c *mockAWSClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	return c.s3Client.ListBucketsWithContext(ctx, input)
+}
+
+// ListObjectVersions implements the ListObjectVersions method for mockAWSClient.
+func (c *mockAWSClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{}, nil
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for mockAWSClient.
+func (c *mockAWSClient) PutBucketAccelerateConfiguration(
+	ctx context.Context, input *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error) {
+	return &s3.PutBucketAccelerateConfigurationOutput{}, nil
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for mockAWSClient.
+func (c *mockAWSClient) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+// PutBucketCors implements the PutBucketCors method for mockAWSClient.
+func (c *mockAWSClient) PutBucketCors(ctx context.Context, input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	c.putBucketCorsInputs = append(c.putBucketCorsInputs, input)
+	c.corsRules = input.CORSConfiguration.CORSRules
+	return &s3.PutBucketCorsOutput{}, nil
 }
 
 // PutBucketEncryption implements the PutBucketEncryption method for mockAWSClient.
-func (c *mockAWSClient) PutBucketEncryption(input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
-	return c.s3Client.PutBucketEncryption(input)
+func (c *mockAWSClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	c.putBucketEncryptionInputs = append(c.putBucketEncryptionInputs, input)
+	// PutBucketEncryption replaces the whole configuration
+	c.encryptionConfiguration = input.ServerSideEncryptionConfiguration
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+// PutBucketLogging implements the PutBucketLogging method for mockAWSClient.
+func (c *mockAWSClient) PutBucketLogging(ctx context.Context, input *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error) {
+	c.putBucketLoggingInputs = append(c.putBucketLoggingInputs, input)
+	c.loggingEnabled = input.BucketLoggingStatus.LoggingEnabled
+	return &s3.PutBucketLoggingOutput{}, nil
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for mockAWSClient.
+func (c *mockAWSClient) PutBucketNotificationConfiguration(
+	ctx context.Context, input *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	c.putBucketNotificationInputs = append(c.putBucketNotificationInputs, input)
+	c.notification = input.NotificationConfiguration
+	return &s3.PutBucketNotificationConfigurationOutput{}, nil
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for mockAWSClient.
+func (c *mockAWSClient) PutBucketOwnershipControls(
+	ctx context.Context, input *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error) {
+	c.putBucketOwnershipControlsInputs = append(c.putBucketOwnershipControlsInputs, input)
+	c.ownershipControls = input.OwnershipControls
+	return &s3.PutBucketOwnershipControlsOutput{}, nil
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for mockAWSClient.
+func (c *mockAWSClient) PutBucketPolicy(ctx context.Context, input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	c.putBucketPolicyInputs = append(c.putBucketPolicyInputs, input)
+	c.bucketPolicy = input.Policy
+	return &s3.PutBucketPolicyOutput{}, nil
 }
 
 // PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for mockAWSClient.
 func (c *mockAWSClient) PutBucketLifecycleConfiguration(
-	input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
-	return c.s3Client.PutBucketLifecycleConfiguration(input)
+	ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	c.putBucketLifecycleConfigurationInputs = append(c.putBucketLifecycleConfigurationInputs, input)
+	c.lifecycleConfiguration = input.LifecycleConfiguration
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+// PutBucketReplication implements the PutBucketReplication method for mockAWSClient.
+func (c *mockAWSClient) PutBucketReplication(ctx context.Context, input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	c.putBucketReplicationInputs = append(c.putBucketReplicationInputs, input)
+	c.replicationConfiguration = input.ReplicationConfiguration
+	return &s3.PutBucketReplicationOutput{}, nil
 }
 
 // PutBucketTagging implements the PutBucketTagging method for mockAWSClient.
-func (c *mockAWSClient) PutBucketTagging(input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
-	return c.s3Client.PutBucketTagging(input)
+func (c *mockAWSClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	c.putBucketTaggingInputs = append(c.putBucketTaggingInputs, input)
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for mockAWSClient.
+func (c *mockAWSClient) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	c.putBucketVersioningInputs = append(c.putBucketVersioningInputs, input)
+	c.versioningStatus = input.VersioningConfiguration.Status
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+// PutObject implements the PutObject method for mockAWSClient.
+func (c *mockAWSClient) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	c.putObjectInputs = append(c.putObjectInputs, input)
+	return &s3.PutObjectOutput{}, nil
 }
 
 // PutPublicAccessBlock implements the PutPublicAccessBlock method for mockAWSClient.
-func (c *mockAWSClient) PutPublicAccessBlock(input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
-	return c.s3Client.PutPublicAccessBlock(input)
+func (c *mockAWSClient) PutPublicAccessBlock(ctx context.Context, input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	c.putPublicAccessBlockInputs = append(c.putPublicAccessBlockInputs, input)
+	c.publicAccessBlockConfiguration = input.PublicAccessBlockConfiguration
+	return &s3.PutPublicAccessBlockOutput{}, nil
 }
 
 func TestFindMatchingTags(t *testing.T) {
 
 	tests := []struct {
-		name       string
-		bucketinfo map[string]*s3.GetBucketTaggingOutput
-		infraName  string
-		want       string
+		name           string
+		bucketinfo     map[string]*s3.GetBucketTaggingOutput
+		backupLocation string
+		infraName      string
+		want           string
 	}{
 		// This tests the case of having buckets that don't match our cluster's name.
 		// Since this bucket belongs to a different cluster, we want the function to return "",
 		// indicating that there is no matching bucket name.
 		{
-			name:      "Bucket infraName doesn't match tag.",
-			infraName: "wrongClusterName",
+			name:           "Bucket infraName doesn't match tag.",
+			backupLocation: defaultBackupStorageLocation,
+			infraName:      "wrongClusterName",
 			bucketinfo: map[string]*s3.GetBucketTaggingOutput{
 				"bucket1": {
 					TagSet: []*s3.Tag{
@@ -144,8 +417,9 @@ func TestFindMatchingTags(t *testing.T) {
 		// This tests the case of having a bucket with a matching infraName, indicating that
 		// the bucket belongs to our cluster. We expect the name of the bucket returned.
 		{
-			name:      "Bucket infraName matches tag.",
-			infraName: clusterInfraName,
+			name:           "Bucket infraName matches tag.",
+			backupLocation: defaultBackupStorageLocation,
+			infraName:      clusterInfraName,
 			bucketinfo: map[string]*s3.GetBucketTaggingOutput{
 				"bucket1": {
 					TagSet: []*s3.Tag{
@@ -165,8 +439,9 @@ func TestFindMatchingTags(t *testing.T) {
 		// This tests the case of two buckets. The first bucket should not match.
 		// The name of the second bucket should be returned.
 		{
-			name:      "Two buckets; second bucket should match.",
-			infraName: clusterInfraName,
+			name:           "Two buckets; second bucket should match.",
+			backupLocation: defaultBackupStorageLocation,
+			infraName:      clusterInfraName,
 			bucketinfo: map[string]*s3.GetBucketTaggingOutput{
 				"bucket1": {
 					TagSet: []*s3.Tag{
@@ -195,11 +470,68 @@ func TestFindMatchingTags(t *testing.T) {
 			},
 			want: "bucket2",
 		},
+		// This tests the case of two buckets of the same cluster, tagged for
+		// different backup locations. Only the bucket of the requested backup
+		// location should be returned.
+		{
+			name:           "Two backup locations; bucket of the requested location should match.",
+			backupLocation: "secondary",
+			infraName:      clusterInfraName,
+			bucketinfo: map[string]*s3.GetBucketTaggingOutput{
+				"bucket1": {
+					TagSet: []*s3.Tag{
+						{
+							Key:   aws.String(bucketTagBackupLocation),
+							Value: aws.String(defaultBackupStorageLocation),
+						},
+						{
+							Key:   aws.String(bucketTagInfraName),
+							Value: aws.String(clusterInfraName),
+						},
+					},
+				},
+				"bucket2": {
+					TagSet: []*s3.Tag{
+						{
+							Key:   aws.String(bucketTagBackupLocation),
+							Value: aws.String("secondary"),
+						},
+						{
+							Key:   aws.String(bucketTagInfraName),
+							Value: aws.String(clusterInfraName),
+						},
+					},
+				},
+			},
+			want: "bucket2",
+		},
+		// This tests the case of a bucket of the cluster which is tagged for
+		// another backup location. We want the function to return "".
+		{
+			name:           "Bucket backup location doesn't match tag.",
+			backupLocation: "secondary",
+			infraName:      clusterInfraName,
+			bucketinfo: map[string]*s3.GetBucketTaggingOutput{
+				"bucket1": {
+					TagSet: []*s3.Tag{
+						{
+							Key:   aws.String(bucketTagBackupLocation),
+							Value: aws.String(defaultBackupStorageLocation),
+						},
+						{
+							Key:   aws.String(bucketTagInfraName),
+							Value: aws.String(clusterInfraName),
+						},
+					},
+				},
+			},
+			want: "",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := FindMatchingTags(tt.bucketinfo, tt.infraName)
+			got := FindMatchingTags(tt.bucketinfo, "", tt.backupLocation, tt.infraName)
 			if got != tt.want {
 				t.Errorf("FindMatchingTags() = %v, want %v", got, tt.want)
 			}
@@ -213,15 +545,16 @@ func TestCreateBucket(t *testing.T) {
 		bucketName string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name        string
+		args        args
+		wantErr     bool
+		wantInvalid bool
 	}{
 		{
-			name: "Create a bucket named 'testBucket'",
+			name: "Create a bucket named 'test-bucket'",
 			args: args{
 				s3Client:   &fakeClient,
-				bucketName: "testBucket",
+				bucketName: "test-bucket",
 			},
 			wantErr: false,
 		},
@@ -231,18 +564,173 @@ func TestCreateBucket(t *testing.T) {
 				s3Client:   &fakeClient,
 				bucketName: "",
 			},
-			wantErr: true,
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket with uppercase letters",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: "testBucket",
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket with an underscore",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: "test_bucket",
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket with a trailing dot",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: "test.bucket.",
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket with consecutive dots",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: "test..bucket",
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket named like an IP address",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: "192.168.5.4",
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Create a bucket with a name longer than 63 characters",
+			args: args{
+				s3Client:   &fakeClient,
+				bucketName: strings.Repeat("a", 64),
+			},
+			wantErr:     true,
+			wantInvalid: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := CreateBucket(tt.args.s3Client, tt.args.bucketName); (err != nil) != tt.wantErr {
+			err := CreateBucket(context.TODO(), tt.args.s3Client, tt.args.bucketName)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateBucket() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if errors.Is(err, ErrInvalidBucketName) != tt.wantInvalid {
+				t.Errorf("CreateBucket() error = %v, want ErrInvalidBucketName %v", err, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+// deniedMockClient is a mockAWSClient which isn't allowed to create buckets.
+type deniedMockClient struct {
+	mockAWSClient
+}
+
+// CreateBucket implements the CreateBucket method for deniedMockClient.
+func (c *deniedMockClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	return nil, awserr.New("AccessDenied", "Access Denied", nil)
+}
+
+// existingBucketMockClient is a mockAWSClient whose CreateBucket calls fail
+// with the error code of a bucket which already exists.
+type existingBucketMockClient struct {
+	mockAWSClient
+	code string
+}
+
+// CreateBucket implements the CreateBucket method for existingBucketMockClient.
+func (c *existingBucketMockClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	return nil, awserr.NewRequestFailure(awserr.New(c.code, "The requested bucket name is not available.", nil), 409, "4442587FB7D0A2F9")
+}
+
+func TestCreateBucketExisting(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		wantErr   bool
+		wantTaken bool
+	}{
+		{
+			name: "bucket owned by us",
+			code: s3.ErrCodeBucketAlreadyOwnedByYou,
+		},
+		{
+			name:      "bucket owned by another account",
+			code:      s3.ErrCodeBucketAlreadyExists,
+			wantErr:   true,
+			wantTaken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &existingBucketMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, code: tt.code}
+			err := CreateBucket(context.TODO(), client, "test-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrBucketNameTaken) != tt.wantTaken {
+				t.Errorf("CreateBucket() error = %v, want ErrBucketNameTaken %v", err, tt.wantTaken)
+			}
+			if tt.wantErr && !isErrorCode(err, tt.code) {
+				t.Errorf("CreateBucket() error = %v, want the %v request error", err, tt.code)
+			}
 		})
 	}
 }
 
+func TestCreateBucketMetrics(t *testing.T) {
+	creates := testutil.ToFloat64(metrics.BucketCreateTotal)
+	createErrors := testutil.ToFloat64(metrics.BucketCreateErrorsTotal)
+	requestErrors := testutil.ToFloat64(metrics.S3RequestErrorsTotal.WithLabelValues("CreateBucket"))
+
+	ctx := WithRequestObserver(context.TODO(), metrics.S3Observer{})
+	if err := CreateBucket(ctx, &deniedMockClient{mockAWSClient{Config: awsConfig}}, "test-bucket"); err == nil {
+		t.Fatalf("CreateBucket() error = nil, want access denied")
+	}
+	if got := testutil.ToFloat64(metrics.BucketCreateTotal); got != creates+1 {
+		t.Errorf("%v = %v, want %v", "bucket_create_total", got, creates+1)
+	}
+	if got := testutil.ToFloat64(metrics.BucketCreateErrorsTotal); got != createErrors+1 {
+		t.Errorf("%v = %v, want %v", "bucket_create_errors_total", got, createErrors+1)
+	}
+	if got := testutil.ToFloat64(metrics.S3RequestErrorsTotal.WithLabelValues("CreateBucket")); got != requestErrors+1 {
+		t.Errorf("%v = %v, want %v", "s3_request_errors_total", got, requestErrors+1)
+	}
+}
+
+func TestBucketLocationConstraint(t *testing.T) {
+	tests := []struct {
+		region string
+		want   *s3.CreateBucketConfiguration
+	}{
+		{region: "us-east-1"},
+		{region: ""},
+		{
+			region: "us-west-2",
+			want:   &s3.CreateBucketConfiguration{LocationConstraint: aws.String("us-west-2")},
+		},
+	}
+	for _, tt := range tests {
+		if got := bucketLocationConstraint(tt.region); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bucketLocationConstraint(%q) = %v, want %v", tt.region, got, tt.want)
+		}
+	}
+}
+
 func TestDoesBucketExist(t *testing.T) {
 	type args struct {
 		s3Client   Client
@@ -275,7 +763,7 @@ func TestDoesBucketExist(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := DoesBucketExist(tt.args.s3Client, tt.args.bucketName)
+			got, err := DoesBucketExist(context.TODO(), tt.args.s3Client, tt.args.bucketName)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DoesBucketExist() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -287,6 +775,23 @@ func TestDoesBucketExist(t *testing.T) {
 	}
 }
 
+func TestCreationDate(t *testing.T) {
+	createdAt := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+	buckets := &s3.ListBucketsOutput{Buckets: []*s3.Bucket{
+		{Name: aws.String("otherBucket")},
+		{Name: aws.String("testBucket"), CreationDate: &createdAt},
+	}}
+	if got := CreationDate(buckets, "testBucket"); got == nil || !got.Equal(createdAt) {
+		t.Errorf("CreationDate() = %v, want %v", got, createdAt)
+	}
+	if got := CreationDate(buckets, "missingBucket"); got != nil {
+		t.Errorf("CreationDate() = %v for an unlisted bucket, want nil", got)
+	}
+	if got := CreationDate(nil, "testBucket"); got != nil {
+		t.Errorf("CreationDate() = %v without a listing, want nil", got)
+	}
+}
+
 func TestListBucketTags(t *testing.T) {
 	type args struct {
 		s3Client   Client
@@ -348,7 +853,7 @@ func TestListBucketTags(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ListBucketTags(tt.args.s3Client, tt.args.bucketlist)
+			got, err := ListBucketTags(context.TODO(), tt.args.s3Client, tt.args.bucketlist)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListBucketTags() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -359,3 +864,1736 @@ func TestListBucketTags(t *testing.T) {
 		})
 	}
 }
+
+// listingMockClient is a mockAWSClient listing several buckets, each with its own tags.
+type listingMockClient struct {
+	mockAWSClient
+
+	// tags holds the tags of every listed bucket, by bucket name. Buckets
+	// without tags have none set.
+	tags map[string][]*s3.Tag
+}
+
+// ListBuckets implements the ListBuckets method for listingMockClient.
+func (c *listingMockClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	output := &s3.ListBucketsOutput{}
+	for name := range c.tags {
+		output.Buckets = append(output.Buckets, &s3.Bucket{Name: aws.String(name)})
+	}
+	return output, nil
+}
+
+// GetBucketTagging implements the GetBucketTagging method for listingMockClient.
+func (c *listingMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	tags := c.tags[*input.Bucket]
+	if tags == nil {
+		return nil, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+	}
+	return &s3.GetBucketTaggingOutput{TagSet: tags}, nil
+}
+
+func TestFindMatchingTagsOnOneBucket(t *testing.T) {
+	tagged := func(infraName string) []*s3.Tag {
+		return []*s3.Tag{
+			{Key: aws.String(bucketTagBackupLocation), Value: aws.String(defaultBackupStorageLocation)},
+			{Key: aws.String(bucketTagInfraName), Value: aws.String(infraName)},
+		}
+	}
+	client := &listingMockClient{
+		mockAWSClient: mockAWSClient{Config: awsConfig},
+		tags: map[string][]*s3.Tag{
+			"otherBucket":      tagged("otherCluster"),
+			"untaggedBucket":   nil,
+			"teamBucket":       {{Key: aws.String("team"), Value: aws.String("sre")}},
+			"testBucket":       tagged(clusterInfraName),
+			"yetAnotherBucket": tagged("yetAnotherCluster"),
+		},
+	}
+
+	bucketlist, err := ListBuckets(context.TODO(), client)
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+	taglist, err := ListBucketTags(context.TODO(), client, bucketlist)
+	if err != nil {
+		t.Fatalf("ListBucketTags() error = %v", err)
+	}
+	for name, tags := range client.tags {
+		_, ok := taglist[name]
+		if ok != (tags != nil) {
+			t.Errorf("ListBucketTags() included %v = %v, want %v", name, ok, tags != nil)
+		}
+	}
+	if got := FindMatchingTags(taglist, "", defaultBackupStorageLocation, clusterInfraName); got != "testBucket" {
+		t.Errorf("FindMatchingTags() = %q, want %q", got, "testBucket")
+	}
+}
+
+// concurrentMockClient is a listingMockClient recording how many GetBucketTagging
+// calls are in flight at once, and throttling the first call for some buckets.
+type concurrentMockClient struct {
+	listingMockClient
+
+	mu sync.Mutex
+	// throttled holds the buckets whose next GetBucketTagging call fails with SlowDown.
+	throttled map[string]bool
+	// inFlight and maxInFlight count the concurrent GetBucketTagging calls.
+	inFlight    int
+	maxInFlight int
+}
+
+// GetBucketTagging implements the GetBucketTagging method for concurrentMockClient.
+func (c *concurrentMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	throttled := c.throttled[*input.Bucket]
+	delete(c.throttled, *input.Bucket)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	time.Sleep(time.Millisecond)
+	if throttled {
+		return nil, awserr.New("SlowDown", "Please reduce your request rate.", nil)
+	}
+	return c.listingMockClient.GetBucketTagging(ctx, input)
+}
+
+func TestScanBucketTagsConcurrency(t *testing.T) {
+	ctx := WithRetryPolicy(context.TODO(), RetryPolicy{MaxAttempts: 3})
+
+	client := &concurrentMockClient{
+		listingMockClient: listingMockClient{
+			mockAWSClient: mockAWSClient{Config: awsConfig},
+			tags:          make(map[string][]*s3.Tag),
+		},
+		throttled: make(map[string]bool),
+	}
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("bucket-%02d", i)
+		client.tags[name] = []*s3.Tag{{Key: aws.String("index"), Value: aws.String(fmt.Sprint(i))}}
+		if i%10 == 0 {
+			client.throttled[name] = true
+		}
+	}
+	bucketlist, err := ListBuckets(context.TODO(), client)
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+
+	taglist, err := ScanBucketTags(ctx, client, bucketlist, ScanOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("ScanBucketTags() error = %v", err)
+	}
+	if len(taglist) != len(client.tags) {
+		t.Errorf("ScanBucketTags() collected the tags of %d buckets, want %d", len(taglist), len(client.tags))
+	}
+	for name, tags := range client.tags {
+		if got := taglist[name]; got == nil || !reflect.DeepEqual(got.TagSet, tags) {
+			t.Errorf("ScanBucketTags() tags of %v = %v, want %v", name, got, tags)
+		}
+	}
+	if client.maxInFlight > 4 {
+		t.Errorf("ScanBucketTags() made %d concurrent GetBucketTagging calls, want at most 4", client.maxInFlight)
+	}
+}
+
+// deniedTaggingMockClient is a listingMockClient denied reading the tags of any bucket.
+type deniedTaggingMockClient struct {
+	listingMockClient
+}
+
+// GetBucketTagging implements the GetBucketTagging method for deniedTaggingMockClient.
+func (c *deniedTaggingMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	return nil, awserr.New("AccessDenied", "Access Denied", nil)
+}
+
+func TestScanBucketTagsError(t *testing.T) {
+	client := &deniedTaggingMockClient{listingMockClient{
+		mockAWSClient: mockAWSClient{Config: awsConfig},
+		tags:          make(map[string][]*s3.Tag),
+	}}
+	for i := 0; i < 50; i++ {
+		client.tags[fmt.Sprintf("bucket-%02d", i)] = []*s3.Tag{}
+	}
+	bucketlist, err := ListBuckets(context.TODO(), client)
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+
+	if _, err := ScanBucketTags(context.TODO(), client, bucketlist, ScanOptions{}); !isErrorCode(err, "AccessDenied") {
+		t.Errorf("ScanBucketTags() error = %v, want AccessDenied", err)
+	}
+}
+
+// cancelledTaggingMockClient is a listingMockClient denied reading the tags of
+// the first bucket, whose other reads wait for their context to be cancelled.
+type cancelledTaggingMockClient struct {
+	listingMockClient
+
+	mu    sync.Mutex
+	reads int
+}
+
+// GetBucketTagging implements the GetBucketTagging method for cancelledTaggingMockClient.
+func (c *cancelledTaggingMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	c.mu.Lock()
+	c.reads++
+	c.mu.Unlock()
+	if aws.StringValue(input.Bucket) == "bucket-00" {
+		return nil, awserr.New("AccessDenied", "Access Denied", nil)
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestScanBucketTagsCancelsOnError(t *testing.T) {
+	client := &cancelledTaggingMockClient{listingMockClient: listingMockClient{
+		mockAWSClient: mockAWSClient{Config: awsConfig},
+	}}
+	bucketlist := &s3.ListBucketsOutput{}
+	for i := 0; i < 50; i++ {
+		bucketlist.Buckets = append(bucketlist.Buckets, &s3.Bucket{Name: aws.String(fmt.Sprintf("bucket-%02d", i))})
+	}
+
+	// The reads in flight are cancelled, and no other bucket is read
+	if _, err := ScanBucketTags(context.TODO(), client, bucketlist, ScanOptions{Concurrency: 4}); !isErrorCode(err, "AccessDenied") {
+		t.Errorf("ScanBucketTags() error = %v, want AccessDenied", err)
+	}
+	if client.reads >= len(bucketlist.Buckets) {
+		t.Errorf("ScanBucketTags() read the tags of %d buckets, want the scan cancelled", client.reads)
+	}
+}
+
+func TestTagBucket(t *testing.T) {
+	type args struct {
+		bucketName     string
+		backUpLocation string
+		infraName      string
+		extraTags      map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string]string
+	}{
+		{
+			name: "Tag a bucket without extra tags",
+			args: args{
+				bucketName:     "testBucket",
+				backUpLocation: defaultBackupStorageLocation,
+				infraName:      clusterInfraName,
+			},
+			want: map[string]string{
+				bucketTagBackupLocation: defaultBackupStorageLocation,
+				bucketTagInfraName:      clusterInfraName,
+			},
+		},
+		{
+			name: "Tag a bucket with an SLA class tag",
+			args: args{
+				bucketName:     "testBucket",
+				backUpLocation: defaultBackupStorageLocation,
+				infraName:      clusterInfraName,
+				extraTags: map[string]string{
+					"velero.io/sla-class": "gold",
+				},
+			},
+			want: map[string]string{
+				bucketTagBackupLocation: defaultBackupStorageLocation,
+				bucketTagInfraName:      clusterInfraName,
+				"velero.io/sla-class":   "gold",
+			},
+		},
+		{
+			name: "Extra tags can't override the identifying tags",
+			args: args{
+				bucketName:     "testBucket",
+				backUpLocation: defaultBackupStorageLocation,
+				infraName:      clusterInfraName,
+				extraTags: map[string]string{
+					bucketTagInfraName: "wrongClusterName",
+				},
+			},
+			want: map[string]string{
+				bucketTagBackupLocation: defaultBackupStorageLocation,
+				bucketTagInfraName:      clusterInfraName,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if err := TagBucket(context.TODO(), client, tt.args.bucketName, "", tt.args.backUpLocation, tt.args.infraName, tt.args.extraTags); err != nil {
+				t.Fatalf("TagBucket() error = %v", err)
+			}
+			if len(client.putBucketTaggingInputs) != 1 {
+				t.Fatalf("TagBucket() issued %d PutBucketTagging calls, want 1", len(client.putBucketTaggingInputs))
+			}
+			got := make(map[string]string)
+			for _, tag := range client.putBucketTaggingInputs[0].Tagging.TagSet {
+				got[*tag.Key] = *tag.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TagBucket() tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMatchingTagsIgnoresExtraTags(t *testing.T) {
+	bucketinfo := map[string]*s3.GetBucketTaggingOutput{
+		"bucket1": {
+			TagSet: []*s3.Tag{
+				{
+					Key:   aws.String(bucketTagBackupLocation),
+					Value: aws.String(defaultBackupStorageLocation),
+				},
+				{
+					Key:   aws.String(bucketTagInfraName),
+					Value: aws.String(clusterInfraName),
+				},
+				{
+					Key:   aws.String("velero.io/sla-class"),
+					Value: aws.String("bronze"),
+				},
+				{
+					Key:   aws.String("velero.io/provisioned-by-operator"),
+					Value: aws.String("true"),
+				},
+			},
+		},
+	}
+	if got := FindMatchingTags(bucketinfo, "", defaultBackupStorageLocation, clusterInfraName); got != "bucket1" {
+		t.Errorf("FindMatchingTags() = %v, want %v", got, "bucket1")
+	}
+}
+
+func TestFindAllMatchingTagsDuplicateInfraName(t *testing.T) {
+	tagging := func(backupLocation string) *s3.GetBucketTaggingOutput {
+		return &s3.GetBucketTaggingOutput{
+			TagSet: []*s3.Tag{
+				{Key: aws.String(bucketTagBackupLocation), Value: aws.String(backupLocation)},
+				{Key: aws.String(bucketTagInfraName), Value: aws.String(clusterInfraName)},
+			},
+		}
+	}
+	// A clone of the bucket carries the same tags
+	bucketinfo := map[string]*s3.GetBucketTaggingOutput{
+		"bucket3": tagging(defaultBackupStorageLocation),
+		"bucket1": tagging(defaultBackupStorageLocation),
+		"bucket2": tagging("secondary"),
+	}
+	want := []string{"bucket1", "bucket3"}
+	// Repeated, as the map iteration order changes between runs
+	for i := 0; i < 20; i++ {
+		if got := FindAllMatchingTags(bucketinfo, "", defaultBackupStorageLocation, clusterInfraName); !reflect.DeepEqual(got, want) {
+			t.Fatalf("FindAllMatchingTags() = %v, want %v", got, want)
+		}
+		if got := FindMatchingTags(bucketinfo, "", defaultBackupStorageLocation, clusterInfraName); got != want[0] {
+			t.Fatalf("FindMatchingTags() = %v, want %v", got, want[0])
+		}
+	}
+	if got := FindAllMatchingTags(bucketinfo, "", defaultBackupStorageLocation, "other-infra"); len(got) != 0 {
+		t.Errorf("FindAllMatchingTags() = %v, want no match", got)
+	}
+}
+
+func TestFindMatchingLegacyTags(t *testing.T) {
+	tagging := func(infraName string) *s3.GetBucketTaggingOutput {
+		return &s3.GetBucketTaggingOutput{
+			TagSet: []*s3.Tag{
+				{Key: aws.String(bucketTagBackupLocation), Value: aws.String(defaultBackupStorageLocation)},
+				{Key: aws.String(bucketTagInfraName), Value: aws.String(infraName)},
+			},
+		}
+	}
+	bucketinfo := map[string]*s3.GetBucketTaggingOutput{
+		"bucket1": tagging("legacy-infra-1"),
+		"bucket2": tagging("legacy-infra-2"),
+	}
+	tests := []struct {
+		name             string
+		legacyInfraNames []string
+		wantBucket       string
+		wantInfraName    string
+	}{
+		{
+			name: "no legacy infrastructure names",
+		},
+		{
+			name:             "bucket tagged with a legacy name",
+			legacyInfraNames: []string{"legacy-infra-0", "legacy-infra-2"},
+			wantBucket:       "bucket2",
+			wantInfraName:    "legacy-infra-2",
+		},
+		{
+			name:             "first legacy name matching",
+			legacyInfraNames: []string{"legacy-infra-2", "legacy-infra-1"},
+			wantBucket:       "bucket2",
+			wantInfraName:    "legacy-infra-2",
+		},
+		{
+			name:             "no bucket tagged with the legacy names",
+			legacyInfraNames: []string{"legacy-infra-0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, infraName := FindMatchingLegacyTags(bucketinfo, "", defaultBackupStorageLocation, tt.legacyInfraNames)
+			if bucket != tt.wantBucket || infraName != tt.wantInfraName {
+				t.Errorf("FindMatchingLegacyTags() = %q, %q, want %q, %q", bucket, infraName, tt.wantBucket, tt.wantInfraName)
+			}
+		})
+	}
+}
+
+func TestEncryptBucket(t *testing.T) {
+	type args struct {
+		sseAlgorithm string
+		kmsKeyID     string
+	}
+	tests := []struct {
+		name          string
+		args          args
+		wantAlgorithm string
+		wantKMSKeyID  *string
+	}{
+		{
+			name:          "Default to AES256",
+			args:          args{},
+			wantAlgorithm: s3.ServerSideEncryptionAes256,
+		},
+		{
+			name: "KMS with a customer managed key",
+			args: args{
+				sseAlgorithm: s3.ServerSideEncryptionAwsKms,
+				kmsKeyID:     "arn:aws:kms:us-east-1:123456789012:key/test",
+			},
+			wantAlgorithm: s3.ServerSideEncryptionAwsKms,
+			wantKMSKeyID:  aws.String("arn:aws:kms:us-east-1:123456789012:key/test"),
+		},
+		{
+			name: "KMS with the AWS managed key",
+			args: args{
+				sseAlgorithm: s3.ServerSideEncryptionAwsKms,
+			},
+			wantAlgorithm: s3.ServerSideEncryptionAwsKms,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if err := EncryptBucket(context.TODO(), client, "testBucket", tt.args.sseAlgorithm, tt.args.kmsKeyID); err != nil {
+				t.Fatalf("EncryptBucket() error = %v", err)
+			}
+			if len(client.putBucketEncryptionInputs) != 1 {
+				t.Fatalf("EncryptBucket() issued %d PutBucketEncryption calls, want 1", len(client.putBucketEncryptionInputs))
+			}
+			rules := client.putBucketEncryptionInputs[0].ServerSideEncryptionConfiguration.Rules
+			if len(rules) != 1 {
+				t.Fatalf("EncryptBucket() applied %d rules, want 1", len(rules))
+			}
+			got := rules[0].ApplyServerSideEncryptionByDefault
+			if aws.StringValue(got.SSEAlgorithm) != tt.wantAlgorithm {
+				t.Errorf("EncryptBucket() algorithm = %v, want %v", aws.StringValue(got.SSEAlgorithm), tt.wantAlgorithm)
+			}
+			if !reflect.DeepEqual(got.KMSMasterKeyID, tt.wantKMSKeyID) {
+				t.Errorf("EncryptBucket() KMS key = %v, want %v", aws.StringValue(got.KMSMasterKeyID), aws.StringValue(tt.wantKMSKeyID))
+			}
+		})
+	}
+}
+
+func TestEncryptBucketReplacesKMSKey(t *testing.T) {
+	const (
+		keyA = "arn:aws:kms:us-east-1:123456789012:key/a"
+		keyB = "arn:aws:kms:us-east-1:123456789012:key/b"
+	)
+	client := &mockAWSClient{Config: awsConfig}
+	if err := EncryptBucket(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAwsKms, keyA); err != nil {
+		t.Fatalf("EncryptBucket() error = %v", err)
+	}
+	if err := EncryptBucket(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAwsKms, keyB); err != nil {
+		t.Fatalf("EncryptBucket() error = %v", err)
+	}
+
+	output, err := client.GetBucketEncryption(context.TODO(), &s3.GetBucketEncryptionInput{Bucket: aws.String("testBucket")})
+	if err != nil {
+		t.Fatalf("GetBucketEncryption() error = %v", err)
+	}
+	rules := output.ServerSideEncryptionConfiguration.Rules
+	if len(rules) != 1 {
+		t.Fatalf("bucket has %d encryption rules, want 1", len(rules))
+	}
+	if got := aws.StringValue(rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID); got != keyB {
+		t.Errorf("bucket KMS key = %v, want %v", got, keyB)
+	}
+}
+
+func TestEnsureBucketEncryption(t *testing.T) {
+	const key = "arn:aws:kms:us-east-1:123456789012:key/a"
+	kmsConfiguration := func(keyID string) *s3.ServerSideEncryptionConfiguration {
+		return &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+						KMSMasterKeyID: aws.String(keyID),
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name          string
+		configuration *s3.ServerSideEncryptionConfiguration
+		wantPuts      int
+	}{
+		{
+			name:          "already matching",
+			configuration: kmsConfiguration(key),
+			wantPuts:      0,
+		},
+		{
+			name:          "drifted key",
+			configuration: kmsConfiguration("arn:aws:kms:us-east-1:123456789012:key/other"),
+			wantPuts:      1,
+		},
+		{
+			name:     "encryption removed",
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, encryptionConfiguration: tt.configuration}
+			if err := EnsureBucketEncryption(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAwsKms, key); err != nil {
+				t.Fatalf("EnsureBucketEncryption() error = %v", err)
+			}
+			if len(client.putBucketEncryptionInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketEncryption() issued %d PutBucketEncryption calls, want %d", len(client.putBucketEncryptionInputs), tt.wantPuts)
+			}
+			current, err := ReadBucketEncryption(context.TODO(), client, "testBucket")
+			if err != nil {
+				t.Fatalf("ReadBucketEncryption() error = %v", err)
+			}
+			if got := aws.StringValue(current.Rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID); got != key {
+				t.Errorf("bucket KMS key = %v, want %v", got, key)
+			}
+		})
+	}
+}
+
+func TestEnsureBucketKMSEncryption(t *testing.T) {
+	const (
+		aliasARN = "arn:aws:kms:us-east-1:123456789012:alias/velero"
+		keyA     = "arn:aws:kms:us-east-1:123456789012:key/a"
+		keyB     = "arn:aws:kms:us-east-1:123456789012:key/b"
+	)
+	kmsConfiguration := func(keyID string) *s3.ServerSideEncryptionConfiguration {
+		return &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+						KMSMasterKeyID: aws.String(keyID),
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name          string
+		configuration *s3.ServerSideEncryptionConfiguration
+		key           KMSKey
+		wantPuts      int
+		wantErr       error
+	}{
+		{
+			name:          "encrypted with the key behind the alias",
+			configuration: kmsConfiguration(keyB),
+			key:           KMSKey{ID: "alias/velero", ResolvedARN: keyB},
+			wantPuts:      0,
+		},
+		{
+			name:          "encrypted with the alias",
+			configuration: kmsConfiguration(aliasARN),
+			key:           KMSKey{ID: "alias/velero", ResolvedARN: keyB},
+			wantPuts:      0,
+		},
+		{
+			name:          "encrypted with a key no longer behind the alias",
+			configuration: kmsConfiguration(keyA),
+			key:           KMSKey{ID: "alias/velero", ResolvedARN: keyB},
+			wantPuts:      1,
+		},
+		{
+			name:          "encrypted with the key by ID",
+			configuration: kmsConfiguration(keyA),
+			key:           KMSKey{ID: "a"},
+			wantPuts:      0,
+		},
+		{
+			name:          "encrypted with a rotated key",
+			configuration: kmsConfiguration(keyB),
+			key:           KMSKey{ID: keyA, AppliedID: keyA},
+			wantPuts:      0,
+			wantErr:       ErrKMSKeyRotated,
+		},
+		{
+			name:          "configured key changed",
+			configuration: kmsConfiguration(keyA),
+			key:           KMSKey{ID: keyB, AppliedID: keyA},
+			wantPuts:      1,
+		},
+		{
+			name:          "encrypted with another key before the key was applied",
+			configuration: kmsConfiguration(keyB),
+			key:           KMSKey{ID: keyA},
+			wantPuts:      1,
+		},
+		{
+			name:     "not encrypted",
+			key:      KMSKey{ID: keyA},
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, encryptionConfiguration: tt.configuration}
+			err := EnsureBucketKMSEncryption(context.TODO(), client, "testBucket", tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EnsureBucketKMSEncryption() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(client.putBucketEncryptionInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketKMSEncryption() issued %d PutBucketEncryption calls, want %d", len(client.putBucketEncryptionInputs), tt.wantPuts)
+			}
+		})
+	}
+}
+
+func TestEnsurePublicAccessBlock(t *testing.T) {
+	blocked := func() *s3.PublicAccessBlockConfiguration {
+		return &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		}
+	}
+	tests := []struct {
+		name          string
+		configuration func() *s3.PublicAccessBlockConfiguration
+		wantPuts      int
+	}{
+		{
+			name:          "already blocked",
+			configuration: blocked,
+			wantPuts:      0,
+		},
+		{
+			name: "drifted public acls",
+			configuration: func() *s3.PublicAccessBlockConfiguration {
+				config := blocked()
+				config.BlockPublicAcls = aws.Bool(false)
+				return config
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "drifted public policy",
+			configuration: func() *s3.PublicAccessBlockConfiguration {
+				config := blocked()
+				config.BlockPublicPolicy = aws.Bool(false)
+				return config
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "drifted ignored public acls",
+			configuration: func() *s3.PublicAccessBlockConfiguration {
+				config := blocked()
+				config.IgnorePublicAcls = aws.Bool(false)
+				return config
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "drifted restricted public buckets",
+			configuration: func() *s3.PublicAccessBlockConfiguration {
+				config := blocked()
+				config.RestrictPublicBuckets = nil
+				return config
+			},
+			wantPuts: 1,
+		},
+		{
+			name:          "public access block removed",
+			configuration: func() *s3.PublicAccessBlockConfiguration { return nil },
+			wantPuts:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, publicAccessBlockConfiguration: tt.configuration()}
+			if err := EnsurePublicAccessBlock(context.TODO(), client, "testBucket"); err != nil {
+				t.Fatalf("EnsurePublicAccessBlock() error = %v", err)
+			}
+			if len(client.putPublicAccessBlockInputs) != tt.wantPuts {
+				t.Errorf("EnsurePublicAccessBlock() issued %d PutPublicAccessBlock calls, want %d", len(client.putPublicAccessBlockInputs), tt.wantPuts)
+			}
+			if !publicAccessBlocked(client.publicAccessBlockConfiguration) {
+				t.Errorf("public access block = %v, want all public access blocked", client.publicAccessBlockConfiguration)
+			}
+		})
+	}
+}
+
+func TestEnsureOwnershipControls(t *testing.T) {
+	ownership := func(objectOwnership string) *s3.OwnershipControls {
+		return &s3.OwnershipControls{
+			Rules: []*s3.OwnershipControlsRule{{ObjectOwnership: aws.String(objectOwnership)}},
+		}
+	}
+	tests := []struct {
+		name     string
+		controls *s3.OwnershipControls
+		wantPuts int
+	}{
+		{
+			name:     "ownership controls absent",
+			wantPuts: 1,
+		},
+		{
+			name:     "already enforced",
+			controls: ownership(s3.ObjectOwnershipBucketOwnerEnforced),
+			wantPuts: 0,
+		},
+		{
+			name:     "object writer owns its objects",
+			controls: ownership(s3.ObjectOwnershipObjectWriter),
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, ownershipControls: tt.controls}
+			if err := EnsureOwnershipControls(context.TODO(), client, "testBucket"); err != nil {
+				t.Fatalf("EnsureOwnershipControls() error = %v", err)
+			}
+			if len(client.putBucketOwnershipControlsInputs) != tt.wantPuts {
+				t.Errorf("EnsureOwnershipControls() issued %d PutBucketOwnershipControls calls, want %d",
+					len(client.putBucketOwnershipControlsInputs), tt.wantPuts)
+			}
+			if !ownershipEnforced(client.ownershipControls) {
+				t.Errorf("ownership controls = %v, want %v", client.ownershipControls, s3.ObjectOwnershipBucketOwnerEnforced)
+			}
+		})
+	}
+}
+
+func TestReadBucketEncryptionNotEncrypted(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	if _, err := ReadBucketEncryption(context.TODO(), client, "testBucket"); !errors.Is(err, ErrBucketNotEncrypted) {
+		t.Errorf("ReadBucketEncryption() error = %v, want %v", err, ErrBucketNotEncrypted)
+	}
+}
+
+// mismatchedEncryptionClient is a mockAWSClient whose encryption configuration
+// is never updated by PutBucketEncryption.
+type mismatchedEncryptionClient struct {
+	mockAWSClient
+}
+
+// PutBucketEncryption implements the PutBucketEncryption method for mismatchedEncryptionClient.
+func (c *mismatchedEncryptionClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+func TestEncryptBucketReadBackMismatch(t *testing.T) {
+	client := &mismatchedEncryptionClient{mockAWSClient{
+		Config: awsConfig,
+		encryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+						KMSMasterKeyID: aws.String("arn:aws:kms:us-east-1:123456789012:key/a"),
+					},
+				},
+			},
+		},
+	}}
+	err := EncryptBucket(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAwsKms, "arn:aws:kms:us-east-1:123456789012:key/b")
+	if !errors.Is(err, ErrEncryptionImmutable) {
+		t.Errorf("EncryptBucket() error = %v, want %v", err, ErrEncryptionImmutable)
+	}
+}
+
+// regionalMockClient is a mockAWSClient which can only read the tags of the
+// buckets in its own region.
+type regionalMockClient struct {
+	mockAWSClient
+
+	// bucketRegions maps the name of each bucket to its region.
+	bucketRegions map[string]string
+	// bucketTags maps the name of each bucket to its tags.
+	bucketTags map[string][]*s3.Tag
+}
+
+// GetBucketTagging implements the GetBucketTagging method for regionalMockClient.
+func (c *regionalMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	if c.bucketRegions[*input.Bucket] != *c.Config.Region {
+		return nil, awserr.New("PermanentRedirect", "The bucket you are attempting to access must be addressed using the specified endpoint.", nil)
+	}
+	tags, ok := c.bucketTags[*input.Bucket]
+	if !ok {
+		return nil, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+	}
+	return &s3.GetBucketTaggingOutput{TagSet: tags}, nil
+}
+
+// relocatedMockClient is a mockAWSClient which only finds the buckets in its
+// own region, and redirects requests for the buckets in other regions.
+type relocatedMockClient struct {
+	mockAWSClient
+
+	// bucketRegions maps the name of each bucket to its region.
+	bucketRegions map[string]string
+}
+
+// ForRegion implements the ForRegion method for relocatedMockClient.
+func (c *relocatedMockClient) ForRegion(region string) (Client, error) {
+	return &relocatedMockClient{
+		mockAWSClient: mockAWSClient{Config: c.Config.Copy().WithRegion(region)},
+		bucketRegions: c.bucketRegions,
+	}, nil
+}
+
+// GetBucketLocation implements the GetBucketLocation method for relocatedMockClient.
+func (c *relocatedMockClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	bucketRegion, ok := c.bucketRegions[*input.Bucket]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil)
+	}
+	// Buckets in us-east-1 have no location constraint
+	if bucketRegion == "us-east-1" {
+		return &s3.GetBucketLocationOutput{}, nil
+	}
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String(bucketRegion)}, nil
+}
+
+// HeadBucket implements the HeadBucket method for relocatedMockClient.
+func (c *relocatedMockClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	bucketRegion, ok := c.bucketRegions[*input.Bucket]
+	if !ok {
+		return nil, awserr.New("NotFound", "Not Found", nil)
+	}
+	if bucketRegion != *c.Config.Region {
+		return nil, awserr.New("MovedPermanently", "Moved Permanently", nil)
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func TestDoesBucketExistStatusCodes(t *testing.T) {
+	ctx := WithRetryPolicy(context.TODO(), RetryPolicy{MaxAttempts: 3})
+	notFound := awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")
+	forbidden := awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "")
+	internal := awserr.NewRequestFailure(awserr.New("InternalError", "We encountered an internal error.", nil), 500, "")
+	tests := []struct {
+		name          string
+		errs          []error
+		wantForbidden bool
+		wantErr       bool
+		wantCalls     int
+	}{
+		{
+			name:      "404 not found",
+			errs:      []error{notFound},
+			wantCalls: 1,
+		},
+		{
+			name:          "403 forbidden",
+			errs:          []error{forbidden},
+			wantForbidden: true,
+			wantErr:       true,
+			wantCalls:     1,
+		},
+		{
+			name:      "500 internal error",
+			errs:      []error{internal, internal, internal},
+			wantErr:   true,
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: tt.errs}
+			exists, err := DoesBucketExist(ctx, client, "testBucket")
+			if exists {
+				t.Errorf("DoesBucketExist() = true, want false")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DoesBucketExist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrBucketForbidden) != tt.wantForbidden {
+				t.Errorf("DoesBucketExist() error = %v, want ErrBucketForbidden %v", err, tt.wantForbidden)
+			}
+			if tt.wantForbidden && statusCode(err) != 403 {
+				t.Errorf("DoesBucketExist() error = %v, want the 403 request failure unwrapped", err)
+			}
+			if client.headBucketCalls != tt.wantCalls {
+				t.Errorf("DoesBucketExist() made %d HeadBucket calls, want %d", client.headBucketCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoesBucketExistInOtherRegion(t *testing.T) {
+	client := &relocatedMockClient{
+		mockAWSClient: mockAWSClient{Config: &aws.Config{Region: aws.String("us-west-2")}},
+		bucketRegions: map[string]string{
+			"localBucket":    "us-west-2",
+			"virginiaBucket": "us-east-1",
+			"irelandBucket":  "eu-west-1",
+		},
+	}
+	for bucket, want := range map[string]bool{
+		"localBucket":    true,
+		"virginiaBucket": true,
+		"irelandBucket":  true,
+		"missingBucket":  false,
+	} {
+		got, err := DoesBucketExist(context.TODO(), client, bucket)
+		if err != nil {
+			t.Fatalf("DoesBucketExist(%v) error = %v", bucket, err)
+		}
+		if got != want {
+			t.Errorf("DoesBucketExist(%v) = %v, want %v", bucket, got, want)
+		}
+	}
+}
+
+// hintingMockClient is a mockAWSClient which rejects tagging requests for the
+// buckets in other regions, hinting at the bucket's region like S3 does.
+type hintingMockClient struct {
+	mockAWSClient
+
+	// bucketRegions maps the name of each bucket to its region.
+	bucketRegions map[string]string
+	// regionalClients holds the clients created by ForRegion, by region.
+	regionalClients map[string]*hintingMockClient
+}
+
+// wrongRegion returns the error S3 responds with when the bucket isn't in the client's region.
+func (c *hintingMockClient) wrongRegion(bucket *string) error {
+	if bucketRegion := c.bucketRegions[*bucket]; bucketRegion != *c.Config.Region {
+		return awserr.New("AuthorizationHeaderMalformed", fmt.Sprintf(
+			"The authorization header is malformed; the region '%s' is wrong; expecting '%s'", *c.Config.Region, bucketRegion), nil)
+	}
+	return nil
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for hintingMockClient.
+func (c *hintingMockClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.DeleteBucketTagging(ctx, input)
+}
+
+// ForRegion implements the ForRegion method for hintingMockClient.
+func (c *hintingMockClient) ForRegion(region string) (Client, error) {
+	client := &hintingMockClient{
+		mockAWSClient:   mockAWSClient{Config: c.Config.Copy().WithRegion(region)},
+		bucketRegions:   c.bucketRegions,
+		regionalClients: c.regionalClients,
+	}
+	c.regionalClients[region] = client
+	return client, nil
+}
+
+// GetBucketTagging implements the GetBucketTagging method for hintingMockClient.
+func (c *hintingMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.GetBucketTagging(ctx, input)
+}
+
+// PutBucketTagging implements the PutBucketTagging method for hintingMockClient.
+func (c *hintingMockClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.PutBucketTagging(ctx, input)
+}
+
+// redirectingMockClient is a mockAWSClient which redirects the lifecycle and
+// tagging requests for the buckets in other regions with a PermanentRedirect,
+// which doesn't tell the bucket's region.
+type redirectingMockClient struct {
+	mockAWSClient
+
+	// bucketRegions maps the name of each bucket to its region.
+	bucketRegions map[string]string
+	// regionalClients holds the clients created by ForRegion, by region.
+	regionalClients map[string]*redirectingMockClient
+}
+
+// wrongRegion returns the error S3 responds with when the bucket isn't in the client's region.
+func (c *redirectingMockClient) wrongRegion(bucket *string) error {
+	if c.bucketRegions[*bucket] != *c.Config.Region {
+		return awserr.NewRequestFailure(awserr.New("PermanentRedirect",
+			"The bucket you are attempting to access must be addressed using the specified endpoint.", nil), 301, "4442587FB7D0A2F9")
+	}
+	return nil
+}
+
+// ForRegion implements the ForRegion method for redirectingMockClient.
+func (c *redirectingMockClient) ForRegion(region string) (Client, error) {
+	client := &redirectingMockClient{
+		mockAWSClient:   mockAWSClient{Config: c.Config.Copy().WithRegion(region)},
+		bucketRegions:   c.bucketRegions,
+		regionalClients: c.regionalClients,
+	}
+	c.regionalClients[region] = client
+	return client, nil
+}
+
+// GetBucketLocation implements the GetBucketLocation method for redirectingMockClient.
+func (c *redirectingMockClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String(c.bucketRegions[*input.Bucket])}, nil
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for redirectingMockClient.
+func (c *redirectingMockClient) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.GetBucketLifecycleConfiguration(ctx, input)
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for redirectingMockClient.
+func (c *redirectingMockClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.PutBucketLifecycleConfiguration(ctx, input)
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for redirectingMockClient.
+func (c *redirectingMockClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.DeleteBucketTagging(ctx, input)
+}
+
+// PutBucketTagging implements the PutBucketTagging method for redirectingMockClient.
+func (c *redirectingMockClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	if err := c.wrongRegion(input.Bucket); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.PutBucketTagging(ctx, input)
+}
+
+func TestPermanentRedirectCorrectsRegion(t *testing.T) {
+	newClient := func() *redirectingMockClient {
+		return &redirectingMockClient{
+			mockAWSClient:   mockAWSClient{Config: awsConfig},
+			bucketRegions:   map[string]string{"testBucket": "eu-west-1"},
+			regionalClients: make(map[string]*redirectingMockClient),
+		}
+	}
+
+	client := newClient()
+	if err := EnsureBucketLifecycle(context.TODO(), client, "testBucket", []LifecycleRulePlan{BackupExpiryRule(30, true)}); err != nil {
+		t.Fatalf("EnsureBucketLifecycle() error = %v", err)
+	}
+	regionalClient, ok := client.regionalClients["eu-west-1"]
+	if !ok {
+		t.Fatalf("EnsureBucketLifecycle() didn't retry in the bucket's region, created clients for %v", client.regionalClients)
+	}
+	if len(regionalClient.putBucketLifecycleConfigurationInputs) != 1 {
+		t.Errorf("EnsureBucketLifecycle() issued %d PutBucketLifecycleConfiguration calls in the bucket's region, want 1",
+			len(regionalClient.putBucketLifecycleConfigurationInputs))
+	}
+
+	client = newClient()
+	if err := TagBucket(context.TODO(), client, "testBucket", "", defaultBackupStorageLocation, clusterInfraName, nil); err != nil {
+		t.Fatalf("TagBucket() error = %v", err)
+	}
+	if regionalClient, ok := client.regionalClients["eu-west-1"]; !ok || len(regionalClient.putBucketTaggingInputs) != 1 {
+		t.Errorf("TagBucket() didn't tag the bucket in its region, created clients for %v", client.regionalClients)
+	}
+
+	// A bucket in the client's region isn't redirected, so nothing is retried
+	client = newClient()
+	client.bucketRegions["testBucket"] = region
+	if err := EnsureBucketLifecycle(context.TODO(), client, "testBucket", []LifecycleRulePlan{BackupExpiryRule(30, true)}); err != nil {
+		t.Fatalf("EnsureBucketLifecycle() error = %v", err)
+	}
+	if len(client.regionalClients) != 0 {
+		t.Errorf("EnsureBucketLifecycle() created clients for %v, want none", client.regionalClients)
+	}
+}
+
+func TestTaggingRetriesRegionHint(t *testing.T) {
+	newClient := func() *hintingMockClient {
+		return &hintingMockClient{
+			mockAWSClient:   mockAWSClient{Config: awsConfig},
+			bucketRegions:   map[string]string{"testBucket": "eu-west-1"},
+			regionalClients: make(map[string]*hintingMockClient),
+		}
+	}
+
+	client := newClient()
+	if err := TagBucket(context.TODO(), client, "testBucket", "", defaultBackupStorageLocation, clusterInfraName, nil); err != nil {
+		t.Fatalf("TagBucket() error = %v", err)
+	}
+	if len(client.putBucketTaggingInputs) != 0 {
+		t.Errorf("TagBucket() tagged the bucket through region %v", region)
+	}
+	regionalClient, ok := client.regionalClients["eu-west-1"]
+	if !ok {
+		t.Fatalf("TagBucket() didn't retry in the hinted region, created clients for %v", client.regionalClients)
+	}
+	if len(regionalClient.putBucketTaggingInputs) != 1 {
+		t.Errorf("TagBucket() issued %d PutBucketTagging calls in the hinted region, want 1", len(regionalClient.putBucketTaggingInputs))
+	}
+
+	client = newClient()
+	bucketlist := &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{
+			{Name: aws.String("testBucket")},
+		},
+	}
+	taglist, err := ListBucketTags(context.TODO(), client, bucketlist)
+	if err != nil {
+		t.Fatalf("ListBucketTags() error = %v", err)
+	}
+	if got := FindMatchingTags(taglist, "", defaultBackupStorageLocation, clusterInfraName); got != "testBucket" {
+		t.Errorf("FindMatchingTags() = %v, want %v", got, "testBucket")
+	}
+	if _, ok := client.regionalClients["eu-west-1"]; !ok {
+		t.Errorf("ListBucketTags() didn't retry in the hinted region, created clients for %v", client.regionalClients)
+	}
+}
+
+func TestScanBucketTagsRegionalClients(t *testing.T) {
+	bucketRegions := map[string]string{
+		"localBucket":   region,
+		"adoptedBucket": "us-west-2",
+	}
+	bucketTags := map[string][]*s3.Tag{
+		"localBucket": {
+			{
+				Key:   aws.String("owner"),
+				Value: aws.String("sre"),
+			},
+		},
+		"adoptedBucket": {
+			{
+				Key:   aws.String(bucketTagBackupLocation),
+				Value: aws.String(defaultBackupStorageLocation),
+			},
+			{
+				Key:   aws.String(bucketTagInfraName),
+				Value: aws.String(clusterInfraName),
+			},
+		},
+	}
+	newClient := func(clientRegion string) *regionalMockClient {
+		return &regionalMockClient{
+			mockAWSClient: mockAWSClient{Config: &aws.Config{Region: aws.String(clientRegion)}},
+			bucketRegions: bucketRegions,
+			bucketTags:    bucketTags,
+		}
+	}
+	bucketlist := &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{
+			{Name: aws.String("localBucket")},
+			{Name: aws.String("adoptedBucket")},
+		},
+	}
+
+	// Without the secondary region, the bucket can't be read
+	if _, err := ListBucketTags(context.TODO(), newClient(region), bucketlist); err == nil {
+		t.Errorf("ListBucketTags() error = nil, want a wrong region error")
+	}
+
+	regionalClients := []Client{newClient("eu-west-1"), newClient("us-west-2")}
+	taglist, err := ScanBucketTags(context.TODO(), newClient(region), bucketlist, ScanOptions{RegionalClients: regionalClients})
+	if err != nil {
+		t.Fatalf("ScanBucketTags() error = %v", err)
+	}
+	if len(taglist) != 2 {
+		t.Errorf("ScanBucketTags() returned tags for %d buckets, want 2", len(taglist))
+	}
+	if got := FindMatchingTags(taglist, "", defaultBackupStorageLocation, clusterInfraName); got != "adoptedBucket" {
+		t.Errorf("FindMatchingTags() = %v, want %v", got, "adoptedBucket")
+	}
+}
+
+// versionedMockClient is a mockAWSClient holding the object versions and
+// delete markers of a versioned bucket, which are listed a page at a time.
+type versionedMockClient struct {
+	mockAWSClient
+
+	// versions and deleteMarkers hold the keys of the object versions and
+	// delete markers in the bucket, by version ID.
+	versions      map[string]string
+	deleteMarkers map[string]string
+	// pageSize bounds the entries listed by every ListObjectVersions call.
+	pageSize int
+	// deleted is set once the bucket was deleted.
+	deleted bool
+}
+
+// ListObjectVersions implements the ListObjectVersions method for versionedMockClient.
+func (c *versionedMockClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	pageSize := c.pageSize
+	if input.MaxKeys != nil && int(*input.MaxKeys) < pageSize {
+		pageSize = int(*input.MaxKeys)
+	}
+	output := &s3.ListObjectVersionsOutput{}
+	for versionID, key := range c.versions {
+		if len(output.Versions) == pageSize {
+			output.IsTruncated = aws.Bool(true)
+			return output, nil
+		}
+		output.Versions = append(output.Versions, &s3.ObjectVersion{Key: aws.String(key), VersionId: aws.String(versionID)})
+	}
+	for versionID, key := range c.deleteMarkers {
+		if len(output.Versions)+len(output.DeleteMarkers) == pageSize {
+			output.IsTruncated = aws.Bool(true)
+			return output, nil
+		}
+		output.DeleteMarkers = append(output.DeleteMarkers, &s3.DeleteMarkerEntry{Key: aws.String(key), VersionId: aws.String(versionID)})
+	}
+	return output, nil
+}
+
+// DeleteObjects implements the DeleteObjects method for versionedMockClient.
+func (c *versionedMockClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	for _, object := range input.Delete.Objects {
+		delete(c.versions, *object.VersionId)
+		delete(c.deleteMarkers, *object.VersionId)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+// DeleteBucket implements the DeleteBucket method for versionedMockClient.
+func (c *versionedMockClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	if len(c.versions)+len(c.deleteMarkers) > 0 {
+		return nil, awserr.New("BucketNotEmpty", "The bucket you tried to delete is not empty", nil)
+	}
+	c.deleted = true
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func TestDeleteBucket(t *testing.T) {
+	tests := []struct {
+		name          string
+		versions      map[string]string
+		deleteMarkers map[string]string
+		force         bool
+		wantErr       bool
+	}{
+		{
+			name: "empty bucket",
+		},
+		{
+			name:     "non-empty bucket",
+			versions: map[string]string{"v1": "backups/a/velero-backup.json"},
+			wantErr:  true,
+		},
+		{
+			name: "emptied versioned bucket",
+			versions: map[string]string{
+				"v1": "backups/a/velero-backup.json",
+				"v2": "backups/a/velero-backup.json",
+				"v3": "backups/b/velero-backup.json",
+			},
+			deleteMarkers: map[string]string{
+				"m1": "backups/a/velero-backup.json",
+				"m2": "backups/c/velero-backup.json",
+			},
+			force: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &versionedMockClient{
+				mockAWSClient: mockAWSClient{Config: awsConfig},
+				versions:      map[string]string{},
+				deleteMarkers: map[string]string{},
+				pageSize:      2,
+			}
+			for versionID, key := range tt.versions {
+				client.versions[versionID] = key
+			}
+			for versionID, key := range tt.deleteMarkers {
+				client.deleteMarkers[versionID] = key
+			}
+
+			err := DeleteBucket(context.TODO(), client, "testBucket", tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if client.deleted == tt.wantErr {
+				t.Errorf("bucket deleted = %v, want %v", client.deleted, !tt.wantErr)
+			}
+			if tt.force && len(client.versions)+len(client.deleteMarkers) > 0 {
+				t.Errorf("bucket left versions %v and delete markers %v, want none", client.versions, client.deleteMarkers)
+			}
+		})
+	}
+}
+
+func TestEnsureBucketVersioning(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   *string
+		wantPuts int
+	}{
+		{
+			name:     "never versioned",
+			wantPuts: 1,
+		},
+		{
+			name:     "suspended versioning",
+			status:   aws.String(s3.BucketVersioningStatusSuspended),
+			wantPuts: 1,
+		},
+		{
+			name:     "already enabled",
+			status:   aws.String(s3.BucketVersioningStatusEnabled),
+			wantPuts: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, versioningStatus: tt.status}
+			if err := EnsureBucketVersioning(context.TODO(), client, "testBucket"); err != nil {
+				t.Fatalf("EnsureBucketVersioning() error = %v", err)
+			}
+			if len(client.putBucketVersioningInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketVersioning() issued %d PutBucketVersioning calls, want %d", len(client.putBucketVersioningInputs), tt.wantPuts)
+			}
+			versioned, err := IsBucketVersioned(context.TODO(), client, "testBucket")
+			if err != nil {
+				t.Fatalf("IsBucketVersioned() error = %v", err)
+			}
+			if !versioned {
+				t.Errorf("IsBucketVersioned() = false, want versioning enabled")
+			}
+		})
+	}
+}
+
+func TestIsBucketObjectLocked(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *s3.ObjectLockConfiguration
+		want   bool
+	}{
+		{
+			name: "no object lock configuration",
+			want: false,
+		},
+		{
+			name:   "object lock enabled",
+			config: &s3.ObjectLockConfiguration{ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled)},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, objectLockConfiguration: tt.config}
+			got, err := IsBucketObjectLocked(context.TODO(), client, "testBucket")
+			if err != nil {
+				t.Fatalf("IsBucketObjectLocked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBucketObjectLocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureBucketLogging(t *testing.T) {
+	grants := []*s3.TargetGrant{{Permission: aws.String("READ")}}
+	tests := []struct {
+		name           string
+		loggingEnabled *s3.LoggingEnabled
+		wantPuts       int
+	}{
+		{
+			name:     "logging disabled",
+			wantPuts: 1,
+		},
+		{
+			name: "other target prefix",
+			loggingEnabled: &s3.LoggingEnabled{
+				TargetBucket: aws.String("logBucket"),
+				TargetPrefix: aws.String("other/"),
+				TargetGrants: grants,
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "target already matches",
+			loggingEnabled: &s3.LoggingEnabled{
+				TargetBucket: aws.String("logBucket"),
+				TargetPrefix: aws.String("velero/"),
+				TargetGrants: grants,
+			},
+			wantPuts: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, loggingEnabled: tt.loggingEnabled}
+			if err := EnsureBucketLogging(context.TODO(), client, "testBucket", "logBucket", "velero/", LogDeliveryAuto); err != nil {
+				t.Fatalf("EnsureBucketLogging() error = %v", err)
+			}
+			if len(client.putBucketLoggingInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketLogging() issued %d PutBucketLogging calls, want %d", len(client.putBucketLoggingInputs), tt.wantPuts)
+			}
+			got := client.loggingEnabled
+			if got == nil || aws.StringValue(got.TargetBucket) != "logBucket" || aws.StringValue(got.TargetPrefix) != "velero/" {
+				t.Fatalf("logging = %v, want logs delivered to logBucket under velero/", got)
+			}
+			// The grants of the existing configuration are kept
+			if tt.loggingEnabled != nil && !reflect.DeepEqual(got.TargetGrants, grants) {
+				t.Errorf("logging grants = %v, want %v", got.TargetGrants, grants)
+			}
+		})
+	}
+}
+
+func TestEnsureBucketLoggingDeliveryPermission(t *testing.T) {
+	enforced := &s3.OwnershipControls{Rules: []*s3.OwnershipControlsRule{
+		{ObjectOwnership: aws.String(s3.ObjectOwnershipBucketOwnerEnforced)},
+	}}
+	statement, err := json.Marshal(logDeliveryStatement("aws", "testBucket", "logBucket", "velero/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[%s]}`, statement)
+	tests := []struct {
+		name           string
+		delivery       LogDelivery
+		ownership      *s3.OwnershipControls
+		policy         *string
+		wantPolicyPuts int
+		wantGrants     bool
+	}{
+		{
+			name:       "ACLs enabled on the target bucket",
+			delivery:   LogDeliveryAuto,
+			wantGrants: true,
+		},
+		{
+			name:           "ACLs disabled on the target bucket",
+			delivery:       LogDeliveryAuto,
+			ownership:      enforced,
+			wantPolicyPuts: 1,
+		},
+		{
+			name:      "log delivery already granted",
+			delivery:  LogDeliveryAuto,
+			ownership: enforced,
+			policy:    aws.String(granted),
+		},
+		{
+			name:           "bucket policy requested",
+			delivery:       LogDeliveryBucketPolicy,
+			wantPolicyPuts: 1,
+			wantGrants:     true,
+		},
+		{
+			name:       "ACL requested",
+			delivery:   LogDeliveryACL,
+			ownership:  enforced,
+			wantGrants: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{
+				Config:            awsConfig,
+				ownershipControls: tt.ownership,
+				bucketPolicy:      tt.policy,
+				loggingEnabled: &s3.LoggingEnabled{
+					TargetBucket: aws.String("logBucket"),
+					TargetPrefix: aws.String("other/"),
+					TargetGrants: []*s3.TargetGrant{{Permission: aws.String("READ")}},
+				},
+			}
+			if err := EnsureBucketLogging(context.TODO(), client, "testBucket", "logBucket", "velero/", tt.delivery); err != nil {
+				t.Fatalf("EnsureBucketLogging() error = %v", err)
+			}
+			if len(client.putBucketPolicyInputs) != tt.wantPolicyPuts {
+				t.Errorf("EnsureBucketLogging() issued %d PutBucketPolicy calls, want %d", len(client.putBucketPolicyInputs), tt.wantPolicyPuts)
+			}
+			if tt.wantPolicyPuts > 0 {
+				var document struct {
+					Statement []struct {
+						Effect    string
+						Principal map[string]string
+						Action    string
+						Resource  string
+						Condition map[string]map[string]string
+					}
+				}
+				if err := json.Unmarshal([]byte(aws.StringValue(client.bucketPolicy)), &document); err != nil {
+					t.Fatalf("unable to parse bucket policy: %v", err)
+				}
+				if len(document.Statement) != 1 {
+					t.Fatalf("bucket policy = %v, want a statement granting the log delivery", aws.StringValue(client.bucketPolicy))
+				}
+				got := document.Statement[0]
+				if got.Effect != "Allow" || got.Principal["Service"] != "logging.s3.amazonaws.com" || got.Action != "s3:PutObject" ||
+					got.Resource != "arn:aws:s3:::logBucket/velero/*" || got.Condition["ArnLike"]["aws:SourceArn"] != "arn:aws:s3:::testBucket" {
+					t.Errorf("bucket policy statement = %+v, want logging.s3.amazonaws.com allowed to write the logs of testBucket", got)
+				}
+			}
+			if grants := client.loggingEnabled.TargetGrants; (len(grants) > 0) != tt.wantGrants {
+				t.Errorf("logging grants = %v, want grants kept %v", grants, tt.wantGrants)
+			}
+		})
+	}
+}
+
+func TestEnsureBucketLifecycle(t *testing.T) {
+	backupExpiry := BackupExpiryRule(30, true)
+	orphanExpiry := LifecycleRulePlan{ID: "Orphan Expiry", Prefix: "restores/", ExpirationDays: 7}
+	rules := []LifecycleRulePlan{backupExpiry, orphanExpiry}
+	tests := []struct {
+		name      string
+		lifecycle *s3.BucketLifecycleConfiguration
+		wantPuts  int
+	}{
+		{
+			name:     "no lifecycle configuration",
+			wantPuts: 1,
+		},
+		{
+			name: "rules match in another order",
+			lifecycle: &s3.BucketLifecycleConfiguration{
+				Rules: []*s3.LifecycleRule{orphanExpiry.lifecycleRule(), backupExpiry.lifecycleRule()},
+			},
+			wantPuts: 0,
+		},
+		{
+			name: "expiration days changed",
+			lifecycle: &s3.BucketLifecycleConfiguration{
+				Rules: []*s3.LifecycleRule{BackupExpiryRule(90, true).lifecycleRule(), orphanExpiry.lifecycleRule()},
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "rule missing",
+			lifecycle: &s3.BucketLifecycleConfiguration{
+				Rules: []*s3.LifecycleRule{backupExpiry.lifecycleRule()},
+			},
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, lifecycleConfiguration: tt.lifecycle}
+			if err := EnsureBucketLifecycle(context.TODO(), client, "testBucket", rules); err != nil {
+				t.Fatalf("EnsureBucketLifecycle() error = %v", err)
+			}
+			if len(client.putBucketLifecycleConfigurationInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketLifecycle() issued %d PutBucketLifecycleConfiguration calls, want %d",
+					len(client.putBucketLifecycleConfigurationInputs), tt.wantPuts)
+			}
+			if !lifecycleMatches(client.lifecycleConfiguration.Rules, rules) {
+				t.Errorf("lifecycle rules = %v, want %v", client.lifecycleConfiguration.Rules, rules)
+			}
+		})
+	}
+}
+
+func TestVerifyBucketWritable(t *testing.T) {
+	for _, prefix := range []string{DefaultWritableProbePrefix, "allowed/velero/"} {
+		t.Run(prefix, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if err := VerifyBucketWritable(context.TODO(), client, "testBucket", prefix); err != nil {
+				t.Fatalf("VerifyBucketWritable() error = %v", err)
+			}
+			if len(client.putObjectInputs) != 1 {
+				t.Fatalf("VerifyBucketWritable() issued %d PutObject calls, want 1", len(client.putObjectInputs))
+			}
+			key := aws.StringValue(client.putObjectInputs[0].Key)
+			if !strings.HasPrefix(key, prefix) {
+				t.Errorf("VerifyBucketWritable() wrote probe object %v, want it under %v", key, prefix)
+			}
+			if len(client.deleteObjectInputs) != 1 || aws.StringValue(client.deleteObjectInputs[0].Key) != key {
+				t.Errorf("VerifyBucketWritable() did not remove probe object %v", key)
+			}
+		})
+	}
+}
+
+func TestSetBucketLifecycle(t *testing.T) {
+	tests := []struct {
+		name               string
+		expireCurrent      bool
+		wantDays           int64
+		wantNoncurrentDays int64
+		wantDeleteMarker   bool
+	}{
+		{
+			name:          "current versions expire",
+			expireCurrent: true,
+			wantDays:      30,
+		},
+		{
+			name:               "expired delete markers are removed",
+			wantNoncurrentDays: 30,
+			wantDeleteMarker:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if err := SetBucketLifecycle(context.TODO(), client, "testBucket", BackupExpiryRule(30, tt.expireCurrent)); err != nil {
+				t.Fatalf("SetBucketLifecycle() error = %v", err)
+			}
+			if client.lifecycleConfiguration == nil || len(client.lifecycleConfiguration.Rules) != 1 {
+				t.Fatalf("lifecycle = %v, want a single rule", client.lifecycleConfiguration)
+			}
+			rule := client.lifecycleConfiguration.Rules[0]
+			if rule.Expiration == nil {
+				t.Fatalf("lifecycle rule %v has no expiration", rule)
+			}
+			// S3 rejects an ExpiredObjectDeleteMarker combined with Days or Date
+			if tt.wantDeleteMarker && (rule.Expiration.Days != nil || rule.Expiration.Date != nil) {
+				t.Errorf("expiration = %v, want the delete marker without days or date", rule.Expiration)
+			}
+			if got := aws.BoolValue(rule.Expiration.ExpiredObjectDeleteMarker); got != tt.wantDeleteMarker {
+				t.Errorf("ExpiredObjectDeleteMarker = %v, want %v", got, tt.wantDeleteMarker)
+			}
+			if got := aws.Int64Value(rule.Expiration.Days); got != tt.wantDays {
+				t.Errorf("expiration = %d days, want %d", got, tt.wantDays)
+			}
+			var noncurrentDays int64
+			if rule.NoncurrentVersionExpiration != nil {
+				noncurrentDays = aws.Int64Value(rule.NoncurrentVersionExpiration.NoncurrentDays)
+			}
+			if noncurrentDays != tt.wantNoncurrentDays {
+				t.Errorf("noncurrent version expiration = %d days, want %d", noncurrentDays, tt.wantNoncurrentDays)
+			}
+		})
+	}
+}
+
+func TestSetBucketLifecycleTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		transitions []TransitionPlan
+		wantErr     error
+	}{
+		{
+			name:        "GLACIER transition before the expiration",
+			transitions: []TransitionPlan{{Days: 30, StorageClass: s3.TransitionStorageClassGlacier}},
+		},
+		{
+			name:        "transition after the expiration",
+			transitions: []TransitionPlan{{Days: 400, StorageClass: s3.TransitionStorageClassGlacier}},
+			wantErr:     ErrInvalidLifecycleTransition,
+		},
+		{
+			name:        "transition at the expiration",
+			transitions: []TransitionPlan{{Days: 365, StorageClass: s3.TransitionStorageClassIntelligentTiering}},
+			wantErr:     ErrInvalidLifecycleTransition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			rule := BackupExpiryRule(365, true)
+			rule.Transitions = tt.transitions
+			err := SetBucketLifecycle(context.TODO(), client, "testBucket", rule)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetBucketLifecycle() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(client.putBucketLifecycleConfigurationInputs) != 0 {
+					t.Errorf("SetBucketLifecycle() issued %d PutBucketLifecycleConfiguration calls, want 0", len(client.putBucketLifecycleConfigurationInputs))
+				}
+				return
+			}
+			transitions := client.lifecycleConfiguration.Rules[0].Transitions
+			if len(transitions) != 1 || aws.Int64Value(transitions[0].Days) != 30 ||
+				aws.StringValue(transitions[0].StorageClass) != s3.TransitionStorageClassGlacier {
+				t.Errorf("lifecycle transitions = %v, want GLACIER after 30 days", transitions)
+			}
+			if got := aws.Int64Value(client.lifecycleConfiguration.Rules[0].Expiration.Days); got != 365 {
+				t.Errorf("expiration = %d days, want 365", got)
+			}
+			if !lifecycleMatches(client.lifecycleConfiguration.Rules, []LifecycleRulePlan{rule}) {
+				t.Errorf("lifecycle rules = %v, want them to match %v", client.lifecycleConfiguration.Rules, rule)
+			}
+			if lifecycleMatches(client.lifecycleConfiguration.Rules, []LifecycleRulePlan{BackupExpiryRule(365, true)}) {
+				t.Errorf("lifecycle rules with transitions match a rule without them")
+			}
+		})
+	}
+}
+
+// recordingMockClient is a mockAWSClient which records the buckets whose tags are read.
+type recordingMockClient struct {
+	mockAWSClient
+
+	// taggingBuckets records the bucket of every GetBucketTagging request.
+	taggingBuckets []string
+}
+
+// GetBucketTagging implements the GetBucketTagging method for recordingMockClient.
+func (c *recordingMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	c.taggingBuckets = append(c.taggingBuckets, *input.Bucket)
+	return c.mockAWSClient.GetBucketTagging(ctx, input)
+}
+
+func TestScanBucketTagsExclude(t *testing.T) {
+	client := &recordingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+	bucketlist := &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{
+			{Name: aws.String("testBucket")},
+			{Name: aws.String("quirky-bucket")},
+			{Name: aws.String("legacy-logs-1")},
+			{Name: aws.String("legacy-logs-2")},
+		},
+	}
+
+	taglist, err := ScanBucketTags(context.TODO(), client, bucketlist, ScanOptions{Exclude: []string{"quirky-bucket", "legacy-logs-*"}})
+	if err != nil {
+		t.Fatalf("ScanBucketTags() error = %v", err)
+	}
+	if !reflect.DeepEqual(client.taggingBuckets, []string{"testBucket"}) {
+		t.Errorf("ScanBucketTags() read the tags of %v, want only testBucket", client.taggingBuckets)
+	}
+	if got := FindMatchingTags(taglist, "", defaultBackupStorageLocation, clusterInfraName); got != "testBucket" {
+		t.Errorf("FindMatchingTags() = %v, want %v", got, "testBucket")
+	}
+
+	if _, err := ScanBucketTags(context.TODO(), client, bucketlist, ScanOptions{Exclude: []string{"["}}); err == nil {
+		t.Errorf("ScanBucketTags() error = nil with an invalid glob")
+	}
+}
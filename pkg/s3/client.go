@@ -2,17 +2,14 @@ package s3
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/openshift/managed-velero-operator/version"
-	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -34,112 +31,308 @@ type awsClient struct {
 }
 
 // Client is a wrapper object for the actual AWS SDK client to allow for easier testing.
+// It is the client taken by all the helpers of the package: create one with
+// NewClient, or implement it to stub the S3 API out.
 type Client interface {
-	CreateBucket(*s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
-	DeleteBucketTagging(*s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error)
-	HeadBucket(*s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
+	CreateBucket(context.Context, *s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
+	DeleteBucket(context.Context, *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error)
+	DeleteBucketPolicy(context.Context, *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error)
+	DeleteBucketTagging(context.Context, *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error)
+	DeleteObject(context.Context, *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(context.Context, *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error)
+	ForRegion(region string) (Client, error)
+	HeadBucket(context.Context, *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
 	GetAWSClientConfig() *aws.Config
-	GetBucketTagging(*s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error)
-	GetPublicAccessBlock(*s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error)
-	ListBuckets(*s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
-	PutBucketEncryption(*s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error)
-	PutBucketLifecycleConfiguration(*s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error)
-	PutBucketTagging(*s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error)
-	PutPublicAccessBlock(*s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error)
+	GetBucketCors(context.Context, *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error)
+	GetBucketEncryption(context.Context, *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error)
+	GetBucketLifecycleConfiguration(context.Context, *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	GetBucketLocation(context.Context, *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error)
+	GetBucketLogging(context.Context, *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error)
+	GetBucketNotificationConfiguration(context.Context, *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error)
+	GetBucketOwnershipControls(context.Context, *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error)
+	GetBucketPolicy(context.Context, *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error)
+	GetBucketReplication(context.Context, *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error)
+	GetBucketTagging(context.Context, *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error)
+	GetBucketVersioning(context.Context, *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error)
+	GetObjectLockConfiguration(context.Context, *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error)
+	GetPublicAccessBlock(context.Context, *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error)
+	ListBuckets(context.Context, *s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
+	ListObjectVersions(context.Context, *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error)
+	ListObjectsV2(context.Context, *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	PutBucketAccelerateConfiguration(context.Context, *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error)
+	PutBucketCors(context.Context, *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error)
+	PutBucketEncryption(context.Context, *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error)
+	PutBucketLifecycleConfiguration(context.Context, *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error)
+	PutBucketLogging(context.Context, *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error)
+	PutBucketNotificationConfiguration(context.Context, *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error)
+	PutBucketOwnershipControls(context.Context, *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error)
+	PutBucketPolicy(context.Context, *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error)
+	PutBucketReplication(context.Context, *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error)
+	PutBucketTagging(context.Context, *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error)
+	PutBucketVersioning(context.Context, *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error)
+	PutObject(context.Context, *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	PutPublicAccessBlock(context.Context, *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error)
 }
 
 // When all of the above Client methods are implemented for awsClient, awsClient becomes a kind of Client.
 
 // CreateBucket implements the CreateBucket method for awsClient.
-func (c *awsClient) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
-	return c.s3Client.CreateBucket(input)
+func (c *awsClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	return c.s3Client.CreateBucketWithContext(ctx, input)
+}
+
+// DeleteBucket implements the DeleteBucket method for awsClient.
+func (c *awsClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	return c.s3Client.DeleteBucketWithContext(ctx, input)
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for awsClient.
+func (c *awsClient) DeleteBucketPolicy(ctx context.Context, input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	return c.s3Client.DeleteBucketPolicyWithContext(ctx, input)
 }
 
 // DeleteBucketTagging implements the DeleteBucketTagging method for awsClient.
-func (c *awsClient) DeleteBucketTagging(input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
-	return c.s3Client.DeleteBucketTagging(input)
+func (c *awsClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	return c.s3Client.DeleteBucketTaggingWithContext(ctx, input)
+}
+
+// DeleteObject implements the DeleteObject method for awsClient.
+func (c *awsClient) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	return c.s3Client.DeleteObjectWithContext(ctx, input)
 }
 
-// GetAWSClientConfig returns a copy of the AWS Client Config for the awsClient.
+// DeleteObjects implements the DeleteObjects method for awsClient.
+func (c *awsClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	return c.s3Client.DeleteObjectsWithContext(ctx, input)
+}
+
+// ForRegion returns a client with the same credentials, addressing the S3
+// endpoint of another region.
+func (c *awsClient) ForRegion(region string) (Client, error) {
+	awsConfig := c.Config.Copy().WithRegion(region)
+	s, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &awsClient{
+		s3Client: s3.New(s),
+		Config:   awsConfig,
+	}, nil
+}
+
+// GetAWSClientConfig returns the AWS Client Config for the awsClient.
 func (c *awsClient) GetAWSClientConfig() *aws.Config {
 	return c.Config
 }
 
 // HeadBucket implements the HeadBucket method for awsClient.
-func (c *awsClient) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
-	return c.s3Client.HeadBucket(input)
+func (c *awsClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return c.s3Client.HeadBucketWithContext(ctx, input)
+}
+
+// GetBucketCors implements the GetBucketCors method for awsClient.
+func (c *awsClient) GetBucketCors(ctx context.Context, input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	return c.s3Client.GetBucketCorsWithContext(ctx, input)
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for awsClient.
+func (c *awsClient) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	return c.s3Client.GetBucketEncryptionWithContext(ctx, input)
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for awsClient.
+func (c *awsClient) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	return c.s3Client.GetBucketLifecycleConfigurationWithContext(ctx, input)
+}
+
+// GetBucketLocation implements the GetBucketLocation method for awsClient.
+func (c *awsClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	return c.s3Client.GetBucketLocationWithContext(ctx, input)
+}
+
+// GetBucketLogging implements the GetBucketLogging method for awsClient.
+func (c *awsClient) GetBucketLogging(ctx context.Context, input *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error) {
+	return c.s3Client.GetBucketLoggingWithContext(ctx, input)
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for awsClient.
+func (c *awsClient) GetBucketNotificationConfiguration(
+	ctx context.Context, input *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error) {
+	return c.s3Client.GetBucketNotificationConfigurationWithContext(ctx, input)
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for awsClient.
+func (c *awsClient) GetBucketOwnershipControls(
+	ctx context.Context, input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	return c.s3Client.GetBucketOwnershipControlsWithContext(ctx, input)
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for awsClient.
+func (c *awsClient) GetBucketPolicy(ctx context.Context, input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	return c.s3Client.GetBucketPolicyWithContext(ctx, input)
+}
+
+// GetBucketReplication implements the GetBucketReplication method for awsClient.
+func (c *awsClient) GetBucketReplication(ctx context.Context, input *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	return c.s3Client.GetBucketReplicationWithContext(ctx, input)
 }
 
 // GetBucketTagging implements the GetBucketTagging method for awsClient.
-func (c *awsClient) GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
-	return c.s3Client.GetBucketTagging(input)
+func (c *awsClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	return c.s3Client.GetBucketTaggingWithContext(ctx, input)
+}
+
+// GetBucketVersioning implements the GetBucketVersioning method for awsClient.
+func (c *awsClient) GetBucketVersioning(ctx context.Context, input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	return c.s3Client.GetBucketVersioningWithContext(ctx, input)
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for awsClient.
+func (c *awsClient) GetObjectLockConfiguration(
+	ctx context.Context, input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	return c.s3Client.GetObjectLockConfigurationWithContext(ctx, input)
 }
 
 // GetPublicAccessBlock implements the GetPublicAccessBlock method for awsClient.
-func (c *awsClient) GetPublicAccessBlock(input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
-	return c.s3Client.GetPublicAccessBlock(input)
+func (c *awsClient) GetPublicAccessBlock(ctx context.Context, input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	return c.s3Client.GetPublicAccessBlockWithContext(ctx, input)
 }
 
 // ListBuckets implements the ListBuckets method for awsClient.
-func (c *awsClient) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-	return c.s3Client.ListBuckets(input)
+func (c *awsClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	return c.s3Client.ListBucketsWithContext(ctx, input)
+}
+
+// ListObjectVersions implements the ListObjectVersions method for awsClient.
+func (c *awsClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	return c.s3Client.ListObjectVersionsWithContext(ctx, input)
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for awsClient.
+func (c *awsClient) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	return c.s3Client.ListObjectsV2WithContext(ctx, input)
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for awsClient.
+func (c *awsClient) PutBucketAccelerateConfiguration(
+	ctx context.Context, input *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error) {
+	return c.s3Client.PutBucketAccelerateConfigurationWithContext(ctx, input)
+}
+
+// PutBucketCors implements the PutBucketCors method for awsClient.
+func (c *awsClient) PutBucketCors(ctx context.Context, input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	return c.s3Client.PutBucketCorsWithContext(ctx, input)
 }
 
 // PutBucketEncryption implements the PutBucketEncryption method for awsClient.
-func (c *awsClient) PutBucketEncryption(input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
-	return c.s3Client.PutBucketEncryption(input)
+func (c *awsClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	return c.s3Client.PutBucketEncryptionWithContext(ctx, input)
 }
 
 // PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for awsClient.
 func (c *awsClient) PutBucketLifecycleConfiguration(
-	input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
-	return c.s3Client.PutBucketLifecycleConfiguration(input)
+	ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	return c.s3Client.PutBucketLifecycleConfigurationWithContext(ctx, input)
+}
+
+// PutBucketLogging implements the PutBucketLogging method for awsClient.
+func (c *awsClient) PutBucketLogging(ctx context.Context, input *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error) {
+	return c.s3Client.PutBucketLoggingWithContext(ctx, input)
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for awsClient.
+func (c *awsClient) PutBucketNotificationConfiguration(
+	ctx context.Context, input *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	return c.s3Client.PutBucketNotificationConfigurationWithContext(ctx, input)
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for awsClient.
+func (c *awsClient) PutBucketOwnershipControls(
+	ctx context.Context, input *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error) {
+	return c.s3Client.PutBucketOwnershipControlsWithContext(ctx, input)
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for awsClient.
+func (c *awsClient) PutBucketPolicy(ctx context.Context, input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	return c.s3Client.PutBucketPolicyWithContext(ctx, input)
+}
+
+// PutBucketReplication implements the PutBucketReplication method for awsClient.
+func (c *awsClient) PutBucketReplication(ctx context.Context, input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	return c.s3Client.PutBucketReplicationWithContext(ctx, input)
 }
 
 // PutBucketTagging implements the PutBucketTagging method for awsClient.
-func (c *awsClient) PutBucketTagging(input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
-	return c.s3Client.PutBucketTagging(input)
+func (c *awsClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	return c.s3Client.PutBucketTaggingWithContext(ctx, input)
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for awsClient.
+func (c *awsClient) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	return c.s3Client.PutBucketVersioningWithContext(ctx, input)
+}
+
+// PutObject implements the PutObject method for awsClient.
+func (c *awsClient) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	return c.s3Client.PutObjectWithContext(ctx, input)
 }
 
 // PutPublicAccessBlock implements the PutPublicAccessBlock method for awsClient.
-func (c *awsClient) PutPublicAccessBlock(input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
-	return c.s3Client.PutPublicAccessBlock(input)
+func (c *awsClient) PutPublicAccessBlock(ctx context.Context, input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	return c.s3Client.PutPublicAccessBlockWithContext(ctx, input)
 }
 
-// NewS3Client reads the aws secrets in the operator's namespace and uses
-// them to create a new client for accessing the S3 API.
-func NewS3Client(kubeClient client.Client, region string) (Client, error) {
-	var err error
+// Endpoint is a custom S3 endpoint addressed instead of the AWS endpoint of
+// the region, such as an S3-compatible object store like MinIO or Ceph RGW.
+type Endpoint struct {
+	// URL is the URL of the endpoint. AWS is addressed when empty.
+	URL string
 
-	awsConfig := &aws.Config{Region: aws.String(region)}
-	namespace, err := k8sutil.GetOperatorNamespace()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get operator namespace: %v", err)
+	// ForcePathStyle addresses the buckets in the path of the URL, rather
+	// than as a subdomain of its host, which MinIO requires.
+	ForcePathStyle bool
+
+	// Accelerate addresses the buckets at the S3 Transfer Acceleration
+	// endpoint, which requires acceleration to be enabled on the bucket with
+	// EnableBucketAcceleration.
+	Accelerate bool
+
+	// DualStack addresses the dual-stack endpoint of the region, which is
+	// reachable over IPv6 as well as IPv4.
+	DualStack bool
+}
+
+// NewS3Client reads the aws secrets in the operator's namespace, or assumes
+// the role of the web identity, as selected by the credentials options, and
+// uses them to create a new client for accessing the S3 API.
+func NewS3Client(kubeClient client.Client, region string, opts CredentialsOptions) (Client, error) {
+	return NewS3ClientForEndpoint(kubeClient, region, Endpoint{}, opts)
+}
+
+// NewClient returns a client for accessing the S3 API with the configuration,
+// such as its region and credentials. Unlike NewS3Client, it reads nothing
+// from the cluster, so that the package can be used outside of the operator.
+// It panics like session.Must when no session can be created from cfg.
+func NewClient(cfg aws.Config) Client {
+	awsConfig := cfg.Copy()
+	return &awsClient{
+		s3Client: s3.New(session.Must(session.NewSession(awsConfig))),
+		Config:   awsConfig,
 	}
+}
+
+// NewS3ClientForEndpoint behaves like NewS3Client, but addresses the S3 API at
+// a custom endpoint, unless its URL is empty.
+func NewS3ClientForEndpoint(kubeClient client.Client, region string, endpoint Endpoint, opts CredentialsOptions) (Client, error) {
+	awsConfig := newAWSConfig(region, endpoint)
+	awsConfig.HTTPClient = newHTTPClient(proxyConfigFromEnvironment())
 
-	secret := &corev1.Secret{}
-	err = kubeClient.Get(context.TODO(),
-		types.NamespacedName{
-			Name:      awsCredsSecretName,
-			Namespace: namespace,
-		},
-		secret)
+	provider, err := newCredentialsProvider(kubeClient, awsConfig, opts)
 	if err != nil {
 		return nil, err
 	}
-	accessKeyID, ok := secret.Data[awsCredsSecretIDKey]
-	if !ok {
-		return nil, fmt.Errorf("AWS credentials secret %v did not contain key %v",
-			awsCredsSecretName, awsCredsSecretIDKey)
-	}
-	secretAccessKey, ok := secret.Data[awsCredsSecretAccessKey]
-	if !ok {
-		return nil, fmt.Errorf("AWS credentials secret %v did not contain key %v",
-			awsCredsSecretName, awsCredsSecretAccessKey)
-	}
-
-	awsConfig.Credentials = credentials.NewStaticCredentials(
-		string(accessKeyID), string(secretAccessKey), "")
+	awsConfig.Credentials = credentials.NewCredentials(provider)
 
 	s, err := session.NewSession(awsConfig)
 	if err != nil {
@@ -152,3 +345,20 @@ func NewS3Client(kubeClient client.Client, region string) (Client, error) {
 		Config:   awsConfig,
 	}, nil
 }
+
+// newAWSConfig returns the configuration of a client addressing the S3 API
+// of the region, at the endpoint unless its URL is empty. Otherwise the
+// endpoint is resolved within the AWS partition of the region.
+func newAWSConfig(region string, endpoint Endpoint) *aws.Config {
+	awsConfig := &aws.Config{Region: aws.String(region), EndpointResolver: endpoints.ResolverFunc(resolveEndpoint)}
+	if endpoint.URL != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint.URL).WithS3ForcePathStyle(endpoint.ForcePathStyle)
+	}
+	if endpoint.Accelerate {
+		awsConfig = awsConfig.WithS3UseAccelerate(true)
+	}
+	if endpoint.DualStack {
+		awsConfig = awsConfig.WithUseDualStack(true)
+	}
+	return awsConfig
+}
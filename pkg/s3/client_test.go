@@ -0,0 +1,119 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestNewAWSConfigEndpoint(t *testing.T) {
+	tests := []struct {
+		name               string
+		endpoint           Endpoint
+		wantEndpoint       string
+		wantForcePathStyle bool
+		wantAccelerate     bool
+		wantDualStack      bool
+	}{
+		{
+			name: "aws",
+		},
+		{
+			name:           "aws with transfer acceleration",
+			endpoint:       Endpoint{Accelerate: true},
+			wantAccelerate: true,
+		},
+		{
+			name:          "aws dual-stack",
+			endpoint:      Endpoint{DualStack: true},
+			wantDualStack: true,
+		},
+		{
+			name:               "minio with path-style addressing",
+			endpoint:           Endpoint{URL: "https://minio.example.com:9000", ForcePathStyle: true},
+			wantEndpoint:       "https://minio.example.com:9000",
+			wantForcePathStyle: true,
+		},
+		{
+			name:         "ceph rgw with virtual-hosted addressing",
+			endpoint:     Endpoint{URL: "https://rgw.example.com"},
+			wantEndpoint: "https://rgw.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := session.NewSession(newAWSConfig(region, tt.endpoint))
+			if err != nil {
+				t.Fatalf("NewSession() error = %v", err)
+			}
+			if got := aws.StringValue(s.Config.Endpoint); got != tt.wantEndpoint {
+				t.Errorf("session endpoint = %q, want %q", got, tt.wantEndpoint)
+			}
+			if got := aws.BoolValue(s.Config.S3ForcePathStyle); got != tt.wantForcePathStyle {
+				t.Errorf("session S3ForcePathStyle = %v, want %v", got, tt.wantForcePathStyle)
+			}
+			if got := aws.BoolValue(s.Config.S3UseAccelerate); got != tt.wantAccelerate {
+				t.Errorf("session S3UseAccelerate = %v, want %v", got, tt.wantAccelerate)
+			}
+			if got := aws.BoolValue(s.Config.UseDualStack); got != tt.wantDualStack {
+				t.Errorf("session UseDualStack = %v, want %v", got, tt.wantDualStack)
+			}
+			if got := aws.StringValue(s.Config.Region); got != region {
+				t.Errorf("session region = %q, want %q", got, region)
+			}
+
+			// Clients for other regions keep addressing the endpoint
+			regional, err := (&awsClient{Config: newAWSConfig(region, tt.endpoint)}).ForRegion("eu-west-1")
+			if err != nil {
+				t.Fatalf("ForRegion() error = %v", err)
+			}
+			if got := aws.StringValue(regional.GetAWSClientConfig().Endpoint); got != tt.wantEndpoint {
+				t.Errorf("regional client endpoint = %q, want %q", got, tt.wantEndpoint)
+			}
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	cfg := aws.Config{Region: aws.String("eu-west-1")}
+	client := NewClient(cfg)
+	cfg.Region = aws.String("us-east-1")
+
+	if got := aws.StringValue(client.GetAWSClientConfig().Region); got != "eu-west-1" {
+		t.Errorf("NewClient() region = %v, want eu-west-1 regardless of later changes to the configuration", got)
+	}
+	regional, err := client.ForRegion("us-west-2")
+	if err != nil {
+		t.Fatalf("ForRegion() error = %v", err)
+	}
+	if got := aws.StringValue(regional.GetAWSClientConfig().Region); got != "us-west-2" {
+		t.Errorf("ForRegion() region = %v, want us-west-2", got)
+	}
+}
+
+func TestNewAWSConfigPartitionEndpoint(t *testing.T) {
+	tests := []struct {
+		region        string
+		wantURL       string
+		wantPartition string
+	}{
+		{region: "us-west-2", wantURL: "https://s3.us-west-2.amazonaws.com", wantPartition: "aws"},
+		{region: "us-gov-west-1", wantURL: "https://s3.us-gov-west-1.amazonaws.com", wantPartition: "aws-us-gov"},
+		{region: "cn-north-1", wantURL: "https://s3.cn-north-1.amazonaws.com.cn", wantPartition: "aws-cn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			resolved, err := newAWSConfig(tt.region, Endpoint{}).EndpointResolver.EndpointFor("s3", tt.region)
+			if err != nil {
+				t.Fatalf("EndpointFor() error = %v", err)
+			}
+			if resolved.URL != tt.wantURL || resolved.PartitionID != tt.wantPartition {
+				t.Errorf("EndpointFor() = %v in partition %v, want %v in partition %v", resolved.URL, resolved.PartitionID, tt.wantURL, tt.wantPartition)
+			}
+			if got := PartitionID(tt.region); got != tt.wantPartition {
+				t.Errorf("PartitionID() = %v, want %v", got, tt.wantPartition)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// CORSRulePlan is an intended cross-origin resource sharing rule of the
+// bucket.
+type CORSRulePlan struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposeHeaders  []string
+	MaxAgeSeconds  int64
+}
+
+// EnsureBucketCors sets the CORS rules of the bucket to the planned rules,
+// unless the bucket already holds them, in order. Any other rules of the
+// bucket are replaced.
+func EnsureBucketCors(ctx context.Context, s3Client Client, bucketName string, rules []CORSRulePlan) error {
+	var output *s3.GetBucketCorsOutput
+	err := withRetry(ctx, "GetBucketCors", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil && !isErrorCode(err, "NoSuchCORSConfiguration") {
+		return fmt.Errorf("unable to read %v bucket CORS configuration: %w", bucketName, err)
+	}
+	if err == nil && corsRulesMatch(output.CORSRules, rules) {
+		return nil
+	}
+
+	bucketCorsInput := &s3.PutBucketCorsInput{
+		Bucket:            aws.String(bucketName),
+		CORSConfiguration: &s3.CORSConfiguration{},
+	}
+	for _, rule := range rules {
+		bucketCorsInput.CORSConfiguration.CORSRules = append(bucketCorsInput.CORSConfiguration.CORSRules, rule.corsRule())
+	}
+	if err := bucketCorsInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket CORS configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketCors", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketCors(ctx, bucketCorsInput)
+		return err
+	})
+}
+
+// corsRule returns the S3 CORS rule described by the plan.
+func (p CORSRulePlan) corsRule() *s3.CORSRule {
+	rule := &s3.CORSRule{
+		AllowedOrigins: aws.StringSlice(p.AllowedOrigins),
+		AllowedMethods: aws.StringSlice(p.AllowedMethods),
+	}
+	if len(p.AllowedHeaders) > 0 {
+		rule.AllowedHeaders = aws.StringSlice(p.AllowedHeaders)
+	}
+	if len(p.ExposeHeaders) > 0 {
+		rule.ExposeHeaders = aws.StringSlice(p.ExposeHeaders)
+	}
+	if p.MaxAgeSeconds > 0 {
+		rule.MaxAgeSeconds = aws.Int64(p.MaxAgeSeconds)
+	}
+	return rule
+}
+
+// corsRulesMatch checks whether the CORS rules of the bucket are the planned
+// rules, in the same order, as S3 applies the first rule matching a request.
+func corsRulesMatch(current []*s3.CORSRule, rules []CORSRulePlan) bool {
+	if len(current) != len(rules) {
+		return false
+	}
+	for i, rule := range rules {
+		if !stringsMatch(current[i].AllowedOrigins, rule.AllowedOrigins) ||
+			!stringsMatch(current[i].AllowedMethods, rule.AllowedMethods) ||
+			!stringsMatch(current[i].AllowedHeaders, rule.AllowedHeaders) ||
+			!stringsMatch(current[i].ExposeHeaders, rule.ExposeHeaders) ||
+			aws.Int64Value(current[i].MaxAgeSeconds) != rule.MaxAgeSeconds {
+			return false
+		}
+	}
+	return true
+}
+
+// stringsMatch checks whether the S3 strings are the planned strings, in the
+// same order.
+func stringsMatch(current []*string, planned []string) bool {
+	if len(current) != len(planned) {
+		return false
+	}
+	for i := range planned {
+		if aws.StringValue(current[i]) != planned[i] {
+			return false
+		}
+	}
+	return true
+}
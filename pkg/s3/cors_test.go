@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestEnsureBucketCors(t *testing.T) {
+	rules := []CORSRulePlan{
+		{
+			AllowedOrigins: []string{"https://console.example.com"},
+			AllowedMethods: []string{"GET", "HEAD"},
+			ExposeHeaders:  []string{"ETag"},
+			MaxAgeSeconds:  3000,
+		},
+		{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET"},
+		},
+	}
+	matching := []*s3.CORSRule{
+		{
+			AllowedOrigins: aws.StringSlice([]string{"https://console.example.com"}),
+			AllowedMethods: aws.StringSlice([]string{"GET", "HEAD"}),
+			ExposeHeaders:  aws.StringSlice([]string{"ETag"}),
+			MaxAgeSeconds:  aws.Int64(3000),
+		},
+		{
+			AllowedOrigins: aws.StringSlice([]string{"*"}),
+			AllowedMethods: aws.StringSlice([]string{"GET"}),
+		},
+	}
+	tests := []struct {
+		name      string
+		corsRules []*s3.CORSRule
+		wantPuts  int
+	}{
+		{
+			name:     "no CORS configuration",
+			wantPuts: 1,
+		},
+		{
+			name:      "rules already match",
+			corsRules: matching,
+			wantPuts:  0,
+		},
+		{
+			name:      "rules in another order",
+			corsRules: []*s3.CORSRule{matching[1], matching[0]},
+			wantPuts:  1,
+		},
+		{
+			name: "other max age",
+			corsRules: []*s3.CORSRule{
+				{
+					AllowedOrigins: matching[0].AllowedOrigins,
+					AllowedMethods: matching[0].AllowedMethods,
+					ExposeHeaders:  matching[0].ExposeHeaders,
+					MaxAgeSeconds:  aws.Int64(60),
+				},
+				matching[1],
+			},
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, corsRules: tt.corsRules}
+			if err := EnsureBucketCors(context.TODO(), client, "testBucket", rules); err != nil {
+				t.Fatalf("EnsureBucketCors() error = %v", err)
+			}
+			if len(client.putBucketCorsInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketCors() issued %d PutBucketCors calls, want %d", len(client.putBucketCorsInputs), tt.wantPuts)
+			}
+			if !corsRulesMatch(client.corsRules, rules) {
+				t.Errorf("CORS rules = %v, want %v", client.corsRules, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+const (
+	// CredentialsModeSecret reads static access keys from the operator's
+	// credentials secret.
+	CredentialsModeSecret = "secret"
+	// CredentialsModeWebIdentity assumes the role of the service account
+	// with its projected web identity token, as with IAM Roles for Service
+	// Accounts or OpenShift STS.
+	CredentialsModeWebIdentity = "webIdentity"
+
+	// The environment variables configuring web identity credentials, as
+	// injected by the EKS pod identity webhook
+	webIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	webIdentityRoleARNEnv   = "AWS_ROLE_ARN"
+	webIdentitySessionEnv   = "AWS_ROLE_SESSION_NAME"
+
+	// WebIdentityProviderName is the ProviderName of web identity credentials.
+	WebIdentityProviderName = "WebIdentityProvider"
+
+	// webIdentityExpiryWindow renews the assumed-role credentials before
+	// they expire
+	webIdentityExpiryWindow = time.Minute
+)
+
+// CredentialsOptions selects how the S3 clients created by NewS3Client are
+// authenticated. The zero value reads the static access keys of the
+// operator's credentials secret in the namespace of the operator.
+type CredentialsOptions struct {
+	// Mode is either CredentialsModeSecret, the default when empty, or
+	// CredentialsModeWebIdentity.
+	Mode string
+
+	// Namespace holds the operator's credentials secret, and defaults to the
+	// namespace of the operator.
+	Namespace string
+}
+
+// webIdentityProvider retrieves the credentials of a role assumed with a web
+// identity token, which is read again on every renewal as it is rotated.
+type webIdentityProvider struct {
+	credentials.Expiry
+
+	stsClient       stsiface.STSAPI
+	roleARN         string
+	roleSessionName string
+	tokenFile       string
+}
+
+// Retrieve implements the Retrieve method of credentials.Provider for webIdentityProvider.
+func (p *webIdentityProvider) Retrieve() (credentials.Value, error) {
+	token, err := ioutil.ReadFile(p.tokenFile)
+	if err != nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName}, fmt.Errorf("unable to read web identity token file %v: %v", p.tokenFile, err)
+	}
+	output, err := p.stsClient.AssumeRoleWithWebIdentity(&sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(p.roleARN),
+		RoleSessionName:  aws.String(p.roleSessionName),
+		WebIdentityToken: aws.String(string(token)),
+	})
+	if err != nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName}, fmt.Errorf("unable to assume role %v with web identity: %w", p.roleARN, err)
+	}
+	if output.Credentials == nil {
+		return credentials.Value{ProviderName: WebIdentityProviderName}, fmt.Errorf("assuming role %v with web identity returned no credentials", p.roleARN)
+	}
+
+	p.SetExpiration(aws.TimeValue(output.Credentials.Expiration), webIdentityExpiryWindow)
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(output.Credentials.SessionToken),
+		ProviderName:    WebIdentityProviderName,
+	}, nil
+}
+
+// newCredentialsProvider returns the provider of the S3 clients' credentials
+// for the credentials options. The web identity role and token are taken from
+// the environment, and the access keys from the operator's secret otherwise.
+func newCredentialsProvider(kubeClient client.Client, awsConfig *aws.Config, opts CredentialsOptions) (credentials.Provider, error) {
+	switch opts.Mode {
+	case CredentialsModeWebIdentity:
+		return newWebIdentityProvider(awsConfig)
+	case CredentialsModeSecret, "":
+		return newSecretProvider(kubeClient, opts.Namespace)
+	default:
+		return nil, fmt.Errorf("invalid AWS credentials mode %q: must be one of %v or %v", opts.Mode, CredentialsModeSecret, CredentialsModeWebIdentity)
+	}
+}
+
+func newWebIdentityProvider(awsConfig *aws.Config) (credentials.Provider, error) {
+	roleARN := os.Getenv(webIdentityRoleARNEnv)
+	tokenFile := os.Getenv(webIdentityTokenFileEnv)
+	if roleARN == "" || tokenFile == "" {
+		return nil, fmt.Errorf("web identity credentials require the %v and %v environment variables", webIdentityRoleARNEnv, webIdentityTokenFileEnv)
+	}
+	roleSessionName := os.Getenv(webIdentitySessionEnv)
+	if roleSessionName == "" {
+		roleSessionName = fmt.Sprintf("managed-velero-operator-%d", time.Now().UnixNano())
+	}
+
+	// STS is always addressed at AWS, rather than at a custom S3 endpoint
+	s, err := session.NewSession(&aws.Config{
+		Region:     awsConfig.Region,
+		HTTPClient: awsConfig.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &webIdentityProvider{
+		stsClient:       sts.New(s),
+		roleARN:         roleARN,
+		roleSessionName: roleSessionName,
+		tokenFile:       tokenFile,
+	}, nil
+}
+
+func newSecretProvider(kubeClient client.Client, namespace string) (credentials.Provider, error) {
+	if namespace == "" {
+		var err error
+		namespace, err = k8sutil.GetOperatorNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get operator namespace: %v", err)
+		}
+	}
+
+	secret := &corev1.Secret{}
+	err := kubeClient.Get(context.TODO(),
+		types.NamespacedName{
+			Name:      awsCredsSecretName,
+			Namespace: namespace,
+		},
+		secret)
+	if err != nil {
+		return nil, err
+	}
+	accessKeyID, ok := secret.Data[awsCredsSecretIDKey]
+	if !ok {
+		return nil, fmt.Errorf("AWS credentials secret %v did not contain key %v",
+			awsCredsSecretName, awsCredsSecretIDKey)
+	}
+	secretAccessKey, ok := secret.Data[awsCredsSecretAccessKey]
+	if !ok {
+		return nil, fmt.Errorf("AWS credentials secret %v did not contain key %v",
+			awsCredsSecretName, awsCredsSecretAccessKey)
+	}
+
+	return &credentials.StaticProvider{Value: credentials.Value{
+		AccessKeyID:     string(accessKeyID),
+		SecretAccessKey: string(secretAccessKey),
+	}}, nil
+}
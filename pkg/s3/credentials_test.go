@@ -0,0 +1,160 @@
+package s3
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// mockSTSClient returns fixed credentials for the role assumed with a web identity.
+type mockSTSClient struct {
+	stsiface.STSAPI
+
+	// input records the last AssumeRoleWithWebIdentity request.
+	input *sts.AssumeRoleWithWebIdentityInput
+}
+
+// AssumeRoleWithWebIdentity implements the AssumeRoleWithWebIdentity method for mockSTSClient.
+func (c *mockSTSClient) AssumeRoleWithWebIdentity(input *sts.AssumeRoleWithWebIdentityInput) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	c.input = input
+	return &sts.AssumeRoleWithWebIdentityOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("ASIAEXAMPLE"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("session"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func TestNewCredentialsProvider(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: awsCredsSecretName, Namespace: "openshift-velero"},
+		Data: map[string][]byte{
+			awsCredsSecretIDKey:     []byte("AKIAEXAMPLE"),
+			awsCredsSecretAccessKey: []byte("secret"),
+		},
+	}
+	defer setEnv(map[string]string{
+		webIdentityRoleARNEnv:   "arn:aws:iam::123456789012:role/velero",
+		webIdentityTokenFileEnv: "/var/run/secrets/openshift/serviceaccount/token",
+	})()
+
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr bool
+		check   func(t *testing.T, provider credentials.Provider)
+	}{
+		{
+			name: "web identity",
+			mode: CredentialsModeWebIdentity,
+			check: func(t *testing.T, provider credentials.Provider) {
+				webIdentity, ok := provider.(*webIdentityProvider)
+				if !ok {
+					t.Fatalf("provider = %T, want *webIdentityProvider", provider)
+				}
+				if webIdentity.roleARN != "arn:aws:iam::123456789012:role/velero" {
+					t.Errorf("role ARN = %q, want the role of the environment", webIdentity.roleARN)
+				}
+				if webIdentity.tokenFile != "/var/run/secrets/openshift/serviceaccount/token" {
+					t.Errorf("token file = %q, want the token file of the environment", webIdentity.tokenFile)
+				}
+			},
+		},
+		{
+			name: "secret",
+			mode: CredentialsModeSecret,
+			check: func(t *testing.T, provider credentials.Provider) {
+				value, err := provider.Retrieve()
+				if err != nil {
+					t.Fatalf("Retrieve() error = %v", err)
+				}
+				if value.AccessKeyID != "AKIAEXAMPLE" || value.ProviderName != credentials.StaticProviderName {
+					t.Errorf("Retrieve() = %+v, want the static keys of the secret", value)
+				}
+			},
+		},
+		{
+			name: "default to the secret",
+			check: func(t *testing.T, provider credentials.Provider) {
+				if _, ok := provider.(*credentials.StaticProvider); !ok {
+					t.Errorf("provider = %T, want *credentials.StaticProvider", provider)
+				}
+			},
+		},
+		{
+			name:    "unknown mode",
+			mode:    "instanceProfile",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := newCredentialsProvider(fake.NewFakeClient(secret), awsConfig, CredentialsOptions{Mode: tt.mode, Namespace: "openshift-velero"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCredentialsProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, provider)
+			}
+		})
+	}
+}
+
+func TestNewCredentialsProviderWebIdentityEnvironment(t *testing.T) {
+	defer setEnv(map[string]string{webIdentityRoleARNEnv: "", webIdentityTokenFileEnv: ""})()
+	if _, err := newCredentialsProvider(fake.NewFakeClient(), awsConfig, CredentialsOptions{Mode: CredentialsModeWebIdentity}); err == nil {
+		t.Errorf("newCredentialsProvider() succeeded without a role and token file, want an error")
+	}
+}
+
+func TestWebIdentityProviderRetrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "web-identity")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err = ioutil.WriteFile(tokenFile, []byte("projected-token"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	stsClient := &mockSTSClient{}
+	provider := &webIdentityProvider{
+		stsClient:       stsClient,
+		roleARN:         "arn:aws:iam::123456789012:role/velero",
+		roleSessionName: "velero",
+		tokenFile:       tokenFile,
+	}
+	value, err := credentials.NewCredentials(provider).Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := credentials.Value{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		ProviderName:    WebIdentityProviderName,
+	}
+	if value != want {
+		t.Errorf("Get() = %+v, want %+v", value, want)
+	}
+	if got := aws.StringValue(stsClient.input.WebIdentityToken); got != "projected-token" {
+		t.Errorf("AssumeRoleWithWebIdentity() token = %q, want the token file contents", got)
+	}
+	if provider.IsExpired() {
+		t.Errorf("IsExpired() = true, want the assumed-role credentials valid until they expire")
+	}
+}
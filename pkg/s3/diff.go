@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"sigs.k8s.io/yaml"
+)
+
+// ActualBucketState holds the configuration a bucket currently has, as read by
+// ReadBucketState. Configurations which aren't set on the bucket are nil.
+type ActualBucketState struct {
+	Encryption        *s3.ServerSideEncryptionConfiguration
+	PublicAccessBlock *s3.PublicAccessBlockConfiguration
+	LifecycleRules    []*s3.LifecycleRule
+	Tags              map[string]string
+}
+
+// ChangeSet describes the changes needed to bring a bucket in line with its
+// plan. Each field is only set when that aspect of the bucket differs, and
+// then holds the planned configuration.
+type ChangeSet struct {
+	Encryption        *EncryptionPlan     `json:"encryption,omitempty"`
+	BlockPublicAccess bool                `json:"blockPublicAccess,omitempty"`
+	LifecycleRules    []LifecycleRulePlan `json:"lifecycleRules,omitempty"`
+	// TagsAdded holds the planned tags which are missing, or have a different value.
+	TagsAdded map[string]string `json:"tagsAdded,omitempty"`
+	// TagsRemoved lists the tags which aren't planned.
+	TagsRemoved []string `json:"tagsRemoved,omitempty"`
+}
+
+// ReadBucketState reads the configuration of the bucket. Only read calls are
+// made, so the bucket is never changed.
+func ReadBucketState(ctx context.Context, s3Client Client, bucketName string) (ActualBucketState, error) {
+	var state ActualBucketState
+	bucket := aws.String(bucketName)
+
+	encryption, err := ReadBucketEncryption(ctx, s3Client, bucketName)
+	if err != nil && !errors.Is(err, ErrBucketNotEncrypted) {
+		return state, err
+	}
+	state.Encryption = encryption
+
+	// Backends without public access blocks are read as having none
+	publicAccessBlock, err := s3Client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") && !IsNotImplemented(err) {
+		return state, fmt.Errorf("unable to read %v bucket public access configuration: %v", bucketName, err)
+	}
+	if err == nil {
+		state.PublicAccessBlock = publicAccessBlock.PublicAccessBlockConfiguration
+	}
+
+	lifecycle, err := s3Client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, "NoSuchLifecycleConfiguration") {
+		return state, fmt.Errorf("unable to read %v bucket lifecycle configuration: %v", bucketName, err)
+	}
+	if err == nil {
+		state.LifecycleRules = lifecycle.Rules
+	}
+
+	tagging, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, "NoSuchTagSet") {
+		return state, fmt.Errorf("unable to read %v bucket tags: %v", bucketName, err)
+	}
+	if err == nil {
+		state.Tags = make(map[string]string)
+		for _, tag := range tagging.TagSet {
+			state.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+
+	return state, nil
+}
+
+// DiffBucketState compares the current state of the bucket with the plan.
+func DiffBucketState(desired BucketPlan, current ActualBucketState) ChangeSet {
+	var changes ChangeSet
+
+	if !desired.EncryptionUnmanaged && !encryptionMatches(current.Encryption, desired.Encryption) {
+		encryption := desired.Encryption
+		changes.Encryption = &encryption
+	}
+	if desired.BlockPublicAccess && !publicAccessBlocked(current.PublicAccessBlock) {
+		changes.BlockPublicAccess = true
+	}
+	if !desired.LifecycleUnmanaged && !lifecycleMatches(current.LifecycleRules, desired.LifecycleRules) {
+		// Never nil, so that removing every rule is still reported
+		changes.LifecycleRules = append([]LifecycleRulePlan{}, desired.LifecycleRules...)
+	}
+
+	if desired.TagsUnmanaged {
+		return changes
+	}
+	for key, value := range desired.Tags {
+		if actual, ok := current.Tags[key]; !ok || actual != value {
+			if changes.TagsAdded == nil {
+				changes.TagsAdded = make(map[string]string)
+			}
+			changes.TagsAdded[key] = value
+		}
+	}
+	for key := range current.Tags {
+		if _, ok := desired.Tags[key]; !ok {
+			changes.TagsRemoved = append(changes.TagsRemoved, key)
+		}
+	}
+	sort.Strings(changes.TagsRemoved)
+
+	return changes
+}
+
+// Empty checks whether the bucket already matches its plan.
+func (c ChangeSet) Empty() bool {
+	return len(c.Aspects()) == 0
+}
+
+// Aspects returns the aspects of the bucket configuration which change.
+func (c ChangeSet) Aspects() []string {
+	var aspects []string
+	if c.Encryption != nil {
+		aspects = append(aspects, DriftEncryption)
+	}
+	if c.BlockPublicAccess {
+		aspects = append(aspects, DriftPublicAccessBlock)
+	}
+	if c.LifecycleRules != nil {
+		aspects = append(aspects, DriftLifecycle)
+	}
+	if len(c.TagsAdded) > 0 || len(c.TagsRemoved) > 0 {
+		aspects = append(aspects, DriftTags)
+	}
+	return aspects
+}
+
+// MarshalYAML renders the change set as YAML, in the same form as BucketPlan.
+func (c ChangeSet) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
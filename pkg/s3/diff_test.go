@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// plannedBucketState returns the state of a bucket configured exactly as planned.
+func plannedBucketState(plan BucketPlan) ActualBucketState {
+	state := ActualBucketState{
+		Encryption: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+					SSEAlgorithm: aws.String(plan.Encryption.Algorithm),
+				},
+			}},
+		},
+		PublicAccessBlock: &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+		Tags: make(map[string]string),
+	}
+	for _, rule := range plan.LifecycleRules {
+		state.LifecycleRules = append(state.LifecycleRules, &s3.LifecycleRule{
+			ID:         aws.String(rule.ID),
+			Status:     aws.String("Enabled"),
+			Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(rule.ExpirationDays)},
+		})
+	}
+	for key, value := range plan.Tags {
+		state.Tags[key] = value
+	}
+	return state
+}
+
+func TestDiffBucketState(t *testing.T) {
+	plan := NewBucketPlan("testBucket", region, s3.ServerSideEncryptionAes256, "",
+		"", defaultBackupStorageLocation, clusterInfraName, map[string]string{"velero.io/sla-class": "gold"}, BackupExpiryRule(DefaultBackupExpiryDays, true))
+
+	tests := []struct {
+		name   string
+		modify func(state *ActualBucketState)
+		want   ChangeSet
+	}{
+		{
+			name:   "no change",
+			modify: func(state *ActualBucketState) {},
+			want:   ChangeSet{},
+		},
+		{
+			name: "tags added and removed",
+			modify: func(state *ActualBucketState) {
+				state.Tags["velero.io/sla-class"] = "bronze"
+				state.Tags["team"] = "backup"
+			},
+			want: ChangeSet{
+				TagsAdded:   map[string]string{"velero.io/sla-class": "gold"},
+				TagsRemoved: []string{"team"},
+			},
+		},
+		{
+			name: "encryption change",
+			modify: func(state *ActualBucketState) {
+				state.Encryption.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm = aws.String(s3.ServerSideEncryptionAwsKms)
+			},
+			want: ChangeSet{Encryption: &plan.Encryption},
+		},
+		{
+			name: "lifecycle and public access change",
+			modify: func(state *ActualBucketState) {
+				state.LifecycleRules = nil
+				state.PublicAccessBlock.RestrictPublicBuckets = aws.Bool(false)
+			},
+			want: ChangeSet{
+				BlockPublicAccess: true,
+				LifecycleRules:    plan.LifecycleRules,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := plannedBucketState(plan)
+			tt.modify(&state)
+			got := DiffBucketState(plan, state)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffBucketState() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != reflect.DeepEqual(tt.want, ChangeSet{}) {
+				t.Errorf("ChangeSet.Empty() = %v for %+v", got.Empty(), got)
+			}
+		})
+	}
+}
+
+func TestDiffBucketStateEncryptionUnmanaged(t *testing.T) {
+	plan := NewBucketPlan("testBucket", region, s3.ServerSideEncryptionAes256, "",
+		"", defaultBackupStorageLocation, clusterInfraName, nil, BackupExpiryRule(DefaultBackupExpiryDays, true))
+	state := plannedBucketState(plan)
+	plan.Encryption = EncryptionPlan{}
+	plan.EncryptionUnmanaged = true
+
+	// Whatever encryption the bucket has, or none, is left alone
+	for _, encryption := range []*s3.ServerSideEncryptionConfiguration{state.Encryption, nil} {
+		state.Encryption = encryption
+		if got := DiffBucketState(plan, state); !got.Empty() {
+			t.Errorf("DiffBucketState() = %+v, want no change with the encryption unmanaged", got)
+		}
+	}
+}
+
+func TestDiffBucketStateTagsUnmanaged(t *testing.T) {
+	plan := NewBucketPlan("testBucket", region, s3.ServerSideEncryptionAes256, "",
+		"", defaultBackupStorageLocation, clusterInfraName, nil, BackupExpiryRule(DefaultBackupExpiryDays, true))
+	state := plannedBucketState(plan)
+	plan.Tags = nil
+	plan.TagsUnmanaged = true
+
+	// Whatever tags the bucket has, or none, are left alone
+	for _, tags := range []map[string]string{state.Tags, {"team": "backup"}, nil} {
+		state.Tags = tags
+		if got := DiffBucketState(plan, state); !got.Empty() {
+			t.Errorf("DiffBucketState() = %+v, want no change with the tags unmanaged", got)
+		}
+	}
+}
+
+func TestChangeSetAspects(t *testing.T) {
+	changes := ChangeSet{
+		Encryption:     &EncryptionPlan{Algorithm: s3.ServerSideEncryptionAes256},
+		LifecycleRules: []LifecycleRulePlan{},
+		TagsRemoved:    []string{"team"},
+	}
+	want := []string{DriftEncryption, DriftLifecycle, DriftTags}
+	if got := changes.Aspects(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangeSet.Aspects() = %v, want %v", got, want)
+	}
+}
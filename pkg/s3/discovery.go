@@ -0,0 +1,159 @@
+package s3
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DefaultDiscoveryCacheTTL is how long the buckets and their tags listed to
+// find an existing bucket are reused by default.
+const DefaultDiscoveryCacheTTL = 60 * time.Second
+
+// Discovery is the result of listing the buckets of the account, and reading
+// their tags, when searching for an existing bucket to adopt.
+type Discovery struct {
+	// Buckets are the buckets of the account, as returned by ListBuckets.
+	Buckets *s3.ListBucketsOutput
+	// Tags holds the tags of each bucket, as returned by ScanBucketTags.
+	Tags map[string]*s3.GetBucketTaggingOutput
+}
+
+// DiscoveryCache reuses the bucket discoveries across reconciles for a short
+// while, so that reconciling many Velero instances doesn't list all buckets,
+// and read all their tags, on every reconcile. It is safe for concurrent use.
+// A nil DiscoveryCache caches nothing.
+type DiscoveryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]discoveryEntry
+	// generation is incremented on every invalidation, so that a discovery
+	// started before the invalidation isn't cached.
+	generation uint64
+
+	// now returns the current time, and defaults to time.Now
+	now func() time.Time
+}
+
+// discoveryEntry is a cached discovery.
+type discoveryEntry struct {
+	discovery Discovery
+	expires   time.Time
+}
+
+// NewDiscoveryCache returns a DiscoveryCache reusing each discovery for the
+// TTL, or caching nothing when the TTL is 0.
+func NewDiscoveryCache(ttl time.Duration) *DiscoveryCache {
+	return &DiscoveryCache{ttl: ttl, entries: make(map[string]discoveryEntry), now: time.Now}
+}
+
+// Discover returns the buckets listed with s3Client, and their tags as scanned
+// with the options, reusing the discovery cached under the key until it
+// expires. The key identifies the account, region and endpoint of s3Client.
+// The returned discovery is shared, and must not be modified.
+func (c *DiscoveryCache) Discover(ctx context.Context, key string, s3Client Client, options ScanOptions) (Discovery, error) {
+	if c == nil || c.ttl <= 0 {
+		return discover(ctx, s3Client, options)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.discovery, nil
+	}
+
+	// The lock isn't held while scanning, which takes a while with many
+	// buckets, so concurrent reconciles may both scan on a miss
+	discovery, err := discover(ctx, s3Client, options)
+	if err != nil {
+		return Discovery{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.entries[key] = discoveryEntry{discovery: discovery, expires: c.now().Add(c.ttl)}
+	}
+	return discovery, nil
+}
+
+// Invalidate drops all cached discoveries, such as after a bucket was
+// created or tagged.
+func (c *DiscoveryCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]discoveryEntry)
+	c.generation++
+}
+
+// discover lists the buckets and scans their tags.
+func discover(ctx context.Context, s3Client Client, options ScanOptions) (Discovery, error) {
+	buckets, err := ListBuckets(ctx, s3Client)
+	if err != nil {
+		return Discovery{}, err
+	}
+	tags, err := ScanBucketTags(ctx, s3Client, buckets, options)
+	if err != nil {
+		return Discovery{}, err
+	}
+	return Discovery{Buckets: buckets, Tags: tags}, nil
+}
+
+// invalidatingClient is a Client which invalidates a DiscoveryCache whenever
+// it creates, deletes or tags a bucket, as the cached discoveries may no
+// longer find the bucket they should.
+type invalidatingClient struct {
+	Client
+	cache *DiscoveryCache
+}
+
+// InvalidatingClient returns a Client making the requests with s3Client, and
+// invalidating the cache on every request changing which buckets exist or
+// how they are tagged. s3Client is returned as is when the cache is nil.
+func (c *DiscoveryCache) InvalidatingClient(s3Client Client) Client {
+	if c == nil {
+		return s3Client
+	}
+	return &invalidatingClient{Client: s3Client, cache: c}
+}
+
+// ForRegion returns an invalidatingClient for the region, which invalidates the same cache.
+func (c *invalidatingClient) ForRegion(region string) (Client, error) {
+	regional, err := c.Client.ForRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return c.cache.InvalidatingClient(regional), nil
+}
+
+// CreateBucket implements the CreateBucket method for invalidatingClient.
+// The cache is invalidated even when the request failed, as the bucket may
+// have been created anyway, such as when the response timed out.
+func (c *invalidatingClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	defer c.cache.Invalidate()
+	return c.Client.CreateBucket(ctx, input)
+}
+
+// DeleteBucket implements the DeleteBucket method for invalidatingClient.
+func (c *invalidatingClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	defer c.cache.Invalidate()
+	return c.Client.DeleteBucket(ctx, input)
+}
+
+// PutBucketTagging implements the PutBucketTagging method for invalidatingClient.
+func (c *invalidatingClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	defer c.cache.Invalidate()
+	return c.Client.PutBucketTagging(ctx, input)
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for invalidatingClient.
+func (c *invalidatingClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	defer c.cache.Invalidate()
+	return c.Client.DeleteBucketTagging(ctx, input)
+}
@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// countingListMockClient is a mockAWSClient listing a single bucket, and
+// counting the ListBuckets calls.
+type countingListMockClient struct {
+	mockAWSClient
+
+	mu               sync.Mutex
+	listBucketsCalls int
+}
+
+// ListBuckets implements the ListBuckets method for countingListMockClient.
+func (c *countingListMockClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listBucketsCalls++
+	return &s3.ListBucketsOutput{Buckets: []*s3.Bucket{{Name: aws.String("testBucket")}}}, nil
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	now := time.Now()
+	client := &countingListMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+	cache := NewDiscoveryCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	discover := func() {
+		discovery, err := cache.Discover(context.TODO(), region, client, ScanOptions{})
+		if err != nil {
+			t.Fatalf("Discover() error = %v", err)
+		}
+		if got := FindMatchingTags(discovery.Tags, "", defaultBackupStorageLocation, clusterInfraName); got != "testBucket" {
+			t.Fatalf("Discover() found bucket %q, want testBucket", got)
+		}
+	}
+	discover()
+	now = now.Add(time.Minute / 2)
+	discover()
+	if client.listBucketsCalls != 1 {
+		t.Errorf("Discover() issued %d ListBuckets calls within the TTL, want 1", client.listBucketsCalls)
+	}
+
+	// Another region is discovered on its own
+	if _, err := cache.Discover(context.TODO(), "eu-west-1", client, ScanOptions{}); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if client.listBucketsCalls != 2 {
+		t.Errorf("Discover() issued %d ListBuckets calls for 2 regions, want 2", client.listBucketsCalls)
+	}
+
+	now = now.Add(time.Minute)
+	discover()
+	if client.listBucketsCalls != 3 {
+		t.Errorf("Discover() issued %d ListBuckets calls after the TTL, want 3", client.listBucketsCalls)
+	}
+}
+
+func TestDiscoveryCacheInvalidatingClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(Client) error
+	}{
+		{
+			name: "bucket created",
+			mutate: func(s3Client Client) error {
+				return CreateBucket(context.TODO(), s3Client, "test-bucket")
+			},
+		},
+		{
+			name: "bucket tagged",
+			mutate: func(s3Client Client) error {
+				return TagBucket(context.TODO(), s3Client, "testBucket", "", defaultBackupStorageLocation, clusterInfraName, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &countingListMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+			cache := NewDiscoveryCache(time.Minute)
+			s3Client := cache.InvalidatingClient(client)
+
+			if _, err := cache.Discover(context.TODO(), region, s3Client, ScanOptions{}); err != nil {
+				t.Fatalf("Discover() error = %v", err)
+			}
+			if err := tt.mutate(s3Client); err != nil {
+				t.Fatalf("mutation error = %v", err)
+			}
+			if _, err := cache.Discover(context.TODO(), region, s3Client, ScanOptions{}); err != nil {
+				t.Fatalf("Discover() error = %v", err)
+			}
+			if client.listBucketsCalls != 2 {
+				t.Errorf("Discover() issued %d ListBuckets calls around the mutation, want 2", client.listBucketsCalls)
+			}
+		})
+	}
+}
+
+func TestDiscoveryCacheConcurrent(t *testing.T) {
+	client := &countingListMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+	cache := NewDiscoveryCache(time.Minute)
+	if _, err := cache.Discover(context.TODO(), region, client, ScanOptions{}); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Discover(context.TODO(), region, client, ScanOptions{}); err != nil {
+				t.Errorf("Discover() error = %v", err)
+			}
+			cache.Invalidate()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDiscoveryCacheDisabled(t *testing.T) {
+	client := &countingListMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+	for _, cache := range []*DiscoveryCache{nil, NewDiscoveryCache(0)} {
+		client.listBucketsCalls = 0
+		for i := 0; i < 2; i++ {
+			if _, err := cache.Discover(context.TODO(), region, client, ScanOptions{}); err != nil {
+				t.Fatalf("Discover() error = %v", err)
+			}
+		}
+		if client.listBucketsCalls != 2 {
+			t.Errorf("Discover() issued %d ListBuckets calls without caching, want 2", client.listBucketsCalls)
+		}
+	}
+}
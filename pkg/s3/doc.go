@@ -0,0 +1,16 @@
+// Package s3 provisions and inspects the S3 buckets storing Velero backups.
+//
+// All the helpers, such as CreateBucket, DoesBucketExist, TagBucket or
+// EnsureBucketEncryption, take a Client: NewClient returns one addressing the
+// S3 API with an aws.Config, while NewS3Client reads the operator's
+// credentials from the cluster. The helpers retry the throttled and failed
+// requests following the RetryPolicy set on their context with
+// WithRetryPolicy, or DefaultRetryPolicy, and report their requests to the
+// RequestObserver set with WithRequestObserver, if any.
+//
+// The tag key prefix and the credentials are passed explicitly, so that
+// clients configured differently may be used at once: the helpers tagging
+// buckets or matching their tags, such as TagBucket and FindMatchingTags, take
+// the prefix of the tag keys, which is DefaultTagKeyPrefix when empty, and
+// NewS3Client takes the CredentialsOptions authenticating its requests.
+package s3
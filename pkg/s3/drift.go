@@ -0,0 +1,185 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The aspects of the bucket configuration which are checked for drift.
+const (
+	DriftEncryption        = "encryption"
+	DriftPublicAccessBlock = "publicAccessBlock"
+	DriftLifecycle         = "lifecycle"
+	DriftTags              = "tags"
+)
+
+// BucketDrift compares the configuration of the bucket with the plan, and returns
+// the aspects of the configuration which differ. Only read calls are made, so
+// the bucket is never changed.
+func BucketDrift(ctx context.Context, s3Client Client, plan BucketPlan) ([]string, error) {
+	current, err := ReadBucketState(ctx, s3Client, plan.Name)
+	if err != nil {
+		return nil, err
+	}
+	return DiffBucketState(plan, current).Aspects(), nil
+}
+
+// isErrorCode checks whether err is an AWS error with the given code.
+func isErrorCode(err error, code string) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == code
+}
+
+// encryptionMatches checks that the encryption configuration consists of a
+// single rule matching the plan.
+func encryptionMatches(config *s3.ServerSideEncryptionConfiguration, plan EncryptionPlan) bool {
+	if config == nil || len(config.Rules) != 1 {
+		return false
+	}
+	actual := config.Rules[0].ApplyServerSideEncryptionByDefault
+	return actual != nil &&
+		aws.StringValue(actual.SSEAlgorithm) == plan.Algorithm &&
+		aws.StringValue(actual.KMSMasterKeyID) == plan.KMSKeyID
+}
+
+// sameKMSKey checks whether the key IDs name the same KMS key, or the same
+// alias, when one is an ARN and the other is not.
+func sameKMSKey(a string, b string) bool {
+	return kmsKeyResource(a) == kmsKeyResource(b)
+}
+
+// kmsKeyResource returns the resource of the KMS key ID, such as key/<id> or
+// alias/<name>, without the ARN prefix.
+func kmsKeyResource(keyID string) string {
+	if strings.HasPrefix(keyID, "arn:") {
+		if arnParts := strings.SplitN(keyID, ":", 6); len(arnParts) == 6 {
+			return arnParts[5]
+		}
+		return keyID
+	}
+	if strings.HasPrefix(keyID, "alias/") {
+		return keyID
+	}
+	return "key/" + keyID
+}
+
+// publicAccessBlocked checks that all public access to the bucket is blocked.
+func publicAccessBlocked(config *s3.PublicAccessBlockConfiguration) bool {
+	return config != nil &&
+		aws.BoolValue(config.BlockPublicAcls) &&
+		aws.BoolValue(config.BlockPublicPolicy) &&
+		aws.BoolValue(config.IgnorePublicAcls) &&
+		aws.BoolValue(config.RestrictPublicBuckets)
+}
+
+// ownershipEnforced checks that the bucket owner owns every object of the
+// bucket, which disables its ACLs.
+func ownershipEnforced(controls *s3.OwnershipControls) bool {
+	return controls != nil &&
+		len(controls.Rules) == 1 &&
+		aws.StringValue(controls.Rules[0].ObjectOwnership) == s3.ObjectOwnershipBucketOwnerEnforced
+}
+
+// replicationMatches checks that the replication configuration consists of
+// the operator's enabled rule, replicating to the destination bucket of the
+// plan with its role.
+func replicationMatches(config *s3.ReplicationConfiguration, plan ReplicationPlan) bool {
+	if config == nil || aws.StringValue(config.Role) != plan.RoleARN || len(config.Rules) != 1 {
+		return false
+	}
+	rule := config.Rules[0]
+	return aws.StringValue(rule.ID) == replicationRuleID &&
+		aws.StringValue(rule.Status) == s3.ReplicationRuleStatusEnabled &&
+		rule.Destination != nil &&
+		aws.StringValue(rule.Destination.Bucket) == plan.DestinationBucketARN
+}
+
+// lifecycleMatches checks that the lifecycle rules are exactly the planned
+// rules, in any order. Rules are told apart by their ID.
+func lifecycleMatches(rules []*s3.LifecycleRule, plan []LifecycleRulePlan) bool {
+	if len(rules) != len(plan) {
+		return false
+	}
+	planned := make(map[string]LifecycleRulePlan, len(plan))
+	for _, rule := range plan {
+		planned[rule.ID] = rule
+	}
+	for _, rule := range rules {
+		want, ok := planned[aws.StringValue(rule.ID)]
+		if !ok || !lifecycleRuleMatches(rule, want) {
+			return false
+		}
+		// A rule may only match a single planned rule
+		delete(planned, want.ID)
+	}
+	return true
+}
+
+// lifecycleRuleMatches checks that the lifecycle rule is the planned rule,
+// including the objects its filter applies to.
+func lifecycleRuleMatches(rule *s3.LifecycleRule, plan LifecycleRulePlan) bool {
+	prefix, prefixOnly := lifecycleRulePrefix(rule)
+	if !prefixOnly {
+		return false
+	}
+	var days int64
+	var expiredObjectDeleteMarker bool
+	if rule.Expiration != nil {
+		days = aws.Int64Value(rule.Expiration.Days)
+		expiredObjectDeleteMarker = aws.BoolValue(rule.Expiration.ExpiredObjectDeleteMarker)
+	}
+	var noncurrentDays int64
+	if rule.NoncurrentVersionExpiration != nil {
+		noncurrentDays = aws.Int64Value(rule.NoncurrentVersionExpiration.NoncurrentDays)
+	}
+	return aws.StringValue(rule.ID) == plan.ID &&
+		aws.StringValue(rule.Status) == "Enabled" &&
+		prefix == plan.Prefix &&
+		days == plan.ExpirationDays &&
+		noncurrentDays == plan.NoncurrentExpirationDays &&
+		expiredObjectDeleteMarker == plan.ExpiredObjectDeleteMarker &&
+		transitionsMatch(rule.Transitions, plan.Transitions)
+}
+
+// lifecycleRulePrefix returns the key prefix the lifecycle rule applies to,
+// and whether the rule selects its objects by that prefix only. Rules written
+// before S3 introduced filters carry the deprecated top-level prefix instead,
+// and a filter also selecting objects by tags makes a different rule.
+func lifecycleRulePrefix(rule *s3.LifecycleRule) (string, bool) {
+	filter := rule.Filter
+	switch {
+	case filter == nil:
+		return aws.StringValue(rule.Prefix), true
+	case filter.Tag != nil:
+		return "", false
+	case filter.And != nil:
+		return aws.StringValue(filter.And.Prefix), len(filter.And.Tags) == 0
+	}
+	return aws.StringValue(filter.Prefix), true
+}
+
+// transitionsMatch checks that the transitions are the planned transitions,
+// regardless of their order.
+func transitionsMatch(transitions []*s3.Transition, plans []TransitionPlan) bool {
+	if len(transitions) != len(plans) {
+		return false
+	}
+	for _, plan := range plans {
+		found := false
+		for _, transition := range transitions {
+			if aws.Int64Value(transition.Days) == plan.Days && aws.StringValue(transition.StorageClass) == plan.StorageClass {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestBucketDrift(t *testing.T) {
+	plan := NewBucketPlan("testBucket", region, s3.ServerSideEncryptionAes256, "",
+		"", defaultBackupStorageLocation, clusterInfraName, nil, BackupExpiryRule(DefaultBackupExpiryDays, true))
+
+	tests := []struct {
+		name      string
+		configure func(client *mockAWSClient) error
+		want      []string
+	}{
+		{
+			name:      "Unconfigured bucket",
+			configure: func(client *mockAWSClient) error { return nil },
+			want:      []string{DriftEncryption, DriftPublicAccessBlock, DriftLifecycle},
+		},
+		{
+			name: "Configured bucket",
+			configure: func(client *mockAWSClient) error {
+				if err := EncryptBucket(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAes256, ""); err != nil {
+					return err
+				}
+				if err := BlockBucketPublicAccess(context.TODO(), client, "testBucket"); err != nil {
+					return err
+				}
+				return SetBucketLifecycle(context.TODO(), client, "testBucket", BackupExpiryRule(DefaultBackupExpiryDays, true))
+			},
+			want: nil,
+		},
+		{
+			name: "Bucket encrypted with a different algorithm",
+			configure: func(client *mockAWSClient) error {
+				if err := EncryptBucket(context.TODO(), client, "testBucket", s3.ServerSideEncryptionAwsKms, ""); err != nil {
+					return err
+				}
+				if err := BlockBucketPublicAccess(context.TODO(), client, "testBucket"); err != nil {
+					return err
+				}
+				return SetBucketLifecycle(context.TODO(), client, "testBucket", BackupExpiryRule(DefaultBackupExpiryDays, true))
+			},
+			want: []string{DriftEncryption},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if err := tt.configure(client); err != nil {
+				t.Fatalf("unable to configure bucket: %v", err)
+			}
+			// Checking for drift must not change the bucket
+			puts := len(client.putBucketEncryptionInputs) + len(client.putBucketTaggingInputs)
+			got, err := BucketDrift(context.TODO(), client, plan)
+			if err != nil {
+				t.Fatalf("BucketDrift() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BucketDrift() = %v, want %v", got, tt.want)
+			}
+			if len(client.putBucketEncryptionInputs)+len(client.putBucketTaggingInputs) != puts {
+				t.Errorf("BucketDrift() changed the bucket")
+			}
+		})
+	}
+}
+
+func TestBucketDriftTags(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	plan := NewBucketPlan("testBucket", region, "", "",
+		"", defaultBackupStorageLocation, clusterInfraName, map[string]string{"velero.io/sla-class": "gold"}, BackupExpiryRule(DefaultBackupExpiryDays, true))
+	got, err := BucketDrift(context.TODO(), client, plan)
+	if err != nil {
+		t.Fatalf("BucketDrift() error = %v", err)
+	}
+	found := false
+	for _, aspect := range got {
+		if aspect == DriftTags {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("BucketDrift() = %v, want %v to be reported", got, DriftTags)
+	}
+}
+
+func TestLifecycleMatchesFilter(t *testing.T) {
+	plan := BackupExpiryRule(30, true)
+	plan.Prefix = "velero/"
+	withFilter := func(filter *s3.LifecycleRuleFilter) *s3.LifecycleRule {
+		rule := plan.lifecycleRule()
+		rule.Filter = filter
+		return rule
+	}
+	tests := []struct {
+		name string
+		rule *s3.LifecycleRule
+		want bool
+	}{
+		{
+			name: "prefix filter",
+			rule: plan.lifecycleRule(),
+			want: true,
+		},
+		{
+			name: "another prefix",
+			rule: withFilter(&s3.LifecycleRuleFilter{Prefix: aws.String(backupExpiryPrefix)}),
+			want: false,
+		},
+		{
+			name: "whole bucket",
+			rule: withFilter(&s3.LifecycleRuleFilter{}),
+			want: false,
+		},
+		{
+			name: "legacy top-level prefix",
+			rule: func() *s3.LifecycleRule {
+				rule := withFilter(nil)
+				rule.Prefix = aws.String("velero/")
+				return rule
+			}(),
+			want: true,
+		},
+		{
+			name: "prefix and tags",
+			rule: withFilter(&s3.LifecycleRuleFilter{And: &s3.LifecycleRuleAndOperator{
+				Prefix: aws.String("velero/"),
+				Tags:   []*s3.Tag{{Key: aws.String("expire"), Value: aws.String("true")}},
+			}}),
+			want: false,
+		},
+		{
+			name: "tag filter",
+			rule: withFilter(&s3.LifecycleRuleFilter{Tag: &s3.Tag{Key: aws.String("expire"), Value: aws.String("true")}}),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lifecycleMatches([]*s3.LifecycleRule{tt.rule}, []LifecycleRulePlan{plan}); got != tt.want {
+				t.Errorf("lifecycleMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DryRunClient is a Client which records the calls changing S3 rather than
+// making them, so that the changes the operator intends can be reviewed. The
+// calls reading S3 are still made, so that the recorded changes are the ones
+// the operator would make, except on the buckets it would have created, which
+// read as new, empty buckets.
+type DryRunClient struct {
+	Client
+	*dryRunState
+}
+
+// dryRunState is shared by a DryRunClient and its regional clients.
+type dryRunState struct {
+	mu sync.Mutex
+	// actions are the skipped calls, in order.
+	actions []string
+	// created holds the buckets which would have been created.
+	created map[string]bool
+	// encryption holds the encryption configuration which would have been
+	// applied to a bucket, which is read back once applied.
+	encryption map[string]*s3.ServerSideEncryptionConfiguration
+	// versioning holds the versioning configuration which would have been
+	// applied to a bucket, which is read back once applied.
+	versioning map[string]*s3.VersioningConfiguration
+}
+
+// NewDryRunClient returns a DryRunClient reading S3 through s3Client.
+func NewDryRunClient(s3Client Client) *DryRunClient {
+	return &DryRunClient{
+		Client: s3Client,
+		dryRunState: &dryRunState{
+			created:    make(map[string]bool),
+			encryption: make(map[string]*s3.ServerSideEncryptionConfiguration),
+			versioning: make(map[string]*s3.VersioningConfiguration),
+		},
+	}
+}
+
+// Actions returns the calls changing S3 which were skipped, in order, such as
+// "CreateBucket mybucket".
+func (s *dryRunState) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.actions...)
+}
+
+// record adds the skipped call to the actions.
+func (s *dryRunState) record(operation string, bucket *string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, fmt.Sprintf("%v %v", operation, aws.StringValue(bucket)))
+}
+
+// isCreated checks whether the bucket would have been created.
+func (s *dryRunState) isCreated(bucket *string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.created[aws.StringValue(bucket)]
+}
+
+// ForRegion implements the ForRegion method for DryRunClient. The regional
+// client records its calls alongside those of c.
+func (c *DryRunClient) ForRegion(region string) (Client, error) {
+	regionalClient, err := c.Client.ForRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunClient{Client: regionalClient, dryRunState: c.dryRunState}, nil
+}
+
+// CreateBucket implements the CreateBucket method for DryRunClient.
+func (c *DryRunClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	c.record("CreateBucket", input.Bucket)
+	c.mu.Lock()
+	c.created[aws.StringValue(input.Bucket)] = true
+	c.mu.Unlock()
+	return &s3.CreateBucketOutput{}, nil
+}
+
+// DeleteBucket implements the DeleteBucket method for DryRunClient.
+func (c *DryRunClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	c.record("DeleteBucket", input.Bucket)
+	c.mu.Lock()
+	delete(c.created, aws.StringValue(input.Bucket))
+	delete(c.encryption, aws.StringValue(input.Bucket))
+	c.mu.Unlock()
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for DryRunClient.
+func (c *DryRunClient) DeleteBucketPolicy(ctx context.Context, input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	c.record("DeleteBucketPolicy", input.Bucket)
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for DryRunClient.
+func (c *DryRunClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	c.record("DeleteBucketTagging", input.Bucket)
+	return &s3.DeleteBucketTaggingOutput{}, nil
+}
+
+// DeleteObject implements the DeleteObject method for DryRunClient.
+func (c *DryRunClient) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	c.record("DeleteObject", input.Bucket)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects implements the DeleteObjects method for DryRunClient.
+func (c *DryRunClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	c.record("DeleteObjects", input.Bucket)
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+// HeadBucket implements the HeadBucket method for DryRunClient.
+func (c *DryRunClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return &s3.HeadBucketOutput{}, nil
+	}
+	return c.Client.HeadBucket(ctx, input)
+}
+
+// GetBucketCors implements the GetBucketCors method for DryRunClient.
+func (c *DryRunClient) GetBucketCors(ctx context.Context, input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("NoSuchCORSConfiguration", "The CORS configuration does not exist", nil)
+	}
+	return c.Client.GetBucketCors(ctx, input)
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for DryRunClient.
+func (c *DryRunClient) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	c.mu.Lock()
+	config, ok := c.encryption[aws.StringValue(input.Bucket)]
+	c.mu.Unlock()
+	if ok {
+		return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: config}, nil
+	}
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found", nil)
+	}
+	return c.Client.GetBucketEncryption(ctx, input)
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for DryRunClient.
+func (c *DryRunClient) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist", nil)
+	}
+	return c.Client.GetBucketLifecycleConfiguration(ctx, input)
+}
+
+// GetBucketLocation implements the GetBucketLocation method for DryRunClient.
+func (c *DryRunClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return &s3.GetBucketLocationOutput{LocationConstraint: c.GetAWSClientConfig().Region}, nil
+	}
+	return c.Client.GetBucketLocation(ctx, input)
+}
+
+// GetBucketLogging implements the GetBucketLogging method for DryRunClient.
+func (c *DryRunClient) GetBucketLogging(ctx context.Context, input *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return &s3.GetBucketLoggingOutput{}, nil
+	}
+	return c.Client.GetBucketLogging(ctx, input)
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for DryRunClient.
+func (c *DryRunClient) GetBucketNotificationConfiguration(
+	ctx context.Context, input *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error) {
+	if c.isCreated(input.Bucket) {
+		return &s3.NotificationConfiguration{}, nil
+	}
+	return c.Client.GetBucketNotificationConfiguration(ctx, input)
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for DryRunClient.
+func (c *DryRunClient) GetBucketOwnershipControls(
+	ctx context.Context, input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("OwnershipControlsNotFoundError", "The bucket ownership controls were not found", nil)
+	}
+	return c.Client.GetBucketOwnershipControls(ctx, input)
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for DryRunClient.
+func (c *DryRunClient) GetBucketPolicy(ctx context.Context, input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("NoSuchBucketPolicy", "The bucket policy does not exist", nil)
+	}
+	return c.Client.GetBucketPolicy(ctx, input)
+}
+
+// GetBucketReplication implements the GetBucketReplication method for DryRunClient.
+func (c *DryRunClient) GetBucketReplication(ctx context.Context, input *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("ReplicationConfigurationNotFoundError", "The replication configuration was not found", nil)
+	}
+	return c.Client.GetBucketReplication(ctx, input)
+}
+
+// GetBucketTagging implements the GetBucketTagging method for DryRunClient.
+func (c *DryRunClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+	}
+	return c.Client.GetBucketTagging(ctx, input)
+}
+
+// GetBucketVersioning implements the GetBucketVersioning method for DryRunClient.
+func (c *DryRunClient) GetBucketVersioning(ctx context.Context, input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	c.mu.Lock()
+	config, ok := c.versioning[aws.StringValue(input.Bucket)]
+	c.mu.Unlock()
+	if ok {
+		return &s3.GetBucketVersioningOutput{Status: config.Status}, nil
+	}
+	if c.isCreated(input.Bucket) {
+		return &s3.GetBucketVersioningOutput{}, nil
+	}
+	return c.Client.GetBucketVersioning(ctx, input)
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for DryRunClient.
+func (c *DryRunClient) GetObjectLockConfiguration(
+	ctx context.Context, input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", nil)
+	}
+	return c.Client.GetObjectLockConfiguration(ctx, input)
+}
+
+// GetPublicAccessBlock implements the GetPublicAccessBlock method for DryRunClient.
+func (c *DryRunClient) GetPublicAccessBlock(ctx context.Context, input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return nil, awserr.New("NoSuchPublicAccessBlockConfiguration", "The public access block configuration was not found", nil)
+	}
+	return c.Client.GetPublicAccessBlock(ctx, input)
+}
+
+// ListObjectVersions implements the ListObjectVersions method for DryRunClient.
+func (c *DryRunClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	if c.isCreated(input.Bucket) {
+		return &s3.ListObjectVersionsOutput{}, nil
+	}
+	return c.Client.ListObjectVersions(ctx, input)
+}
+
+// PutBucketCors implements the PutBucketCors method for DryRunClient.
+func (c *DryRunClient) PutBucketCors(ctx context.Context, input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	c.record("PutBucketCors", input.Bucket)
+	return &s3.PutBucketCorsOutput{}, nil
+}
+
+// PutBucketEncryption implements the PutBucketEncryption method for DryRunClient.
+func (c *DryRunClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	c.record("PutBucketEncryption", input.Bucket)
+	c.mu.Lock()
+	c.encryption[aws.StringValue(input.Bucket)] = input.ServerSideEncryptionConfiguration
+	c.mu.Unlock()
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for DryRunClient.
+func (c *DryRunClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	c.record("PutBucketLifecycleConfiguration", input.Bucket)
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+// PutBucketLogging implements the PutBucketLogging method for DryRunClient.
+func (c *DryRunClient) PutBucketLogging(ctx context.Context, input *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error) {
+	c.record("PutBucketLogging", input.Bucket)
+	return &s3.PutBucketLoggingOutput{}, nil
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for DryRunClient.
+func (c *DryRunClient) PutBucketNotificationConfiguration(
+	ctx context.Context, input *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	c.record("PutBucketNotificationConfiguration", input.Bucket)
+	return &s3.PutBucketNotificationConfigurationOutput{}, nil
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for DryRunClient.
+func (c *DryRunClient) PutBucketOwnershipControls(
+	ctx context.Context, input *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error) {
+	c.record("PutBucketOwnershipControls", input.Bucket)
+	return &s3.PutBucketOwnershipControlsOutput{}, nil
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for DryRunClient.
+func (c *DryRunClient) PutBucketPolicy(ctx context.Context, input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	c.record("PutBucketPolicy", input.Bucket)
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+
+// PutBucketReplication implements the PutBucketReplication method for DryRunClient.
+func (c *DryRunClient) PutBucketReplication(ctx context.Context, input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	c.record("PutBucketReplication", input.Bucket)
+	return &s3.PutBucketReplicationOutput{}, nil
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for DryRunClient.
+func (c *DryRunClient) PutBucketAccelerateConfiguration(
+	ctx context.Context, input *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error) {
+	c.record("PutBucketAccelerateConfiguration", input.Bucket)
+	return &s3.PutBucketAccelerateConfigurationOutput{}, nil
+}
+
+// PutBucketTagging implements the PutBucketTagging method for DryRunClient.
+func (c *DryRunClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	c.record("PutBucketTagging", input.Bucket)
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for DryRunClient.
+func (c *DryRunClient) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	c.record("PutBucketVersioning", input.Bucket)
+	c.mu.Lock()
+	c.versioning[aws.StringValue(input.Bucket)] = input.VersioningConfiguration
+	c.mu.Unlock()
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+// PutObject implements the PutObject method for DryRunClient.
+func (c *DryRunClient) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	c.record("PutObject", input.Bucket)
+	return &s3.PutObjectOutput{}, nil
+}
+
+// PutPublicAccessBlock implements the PutPublicAccessBlock method for DryRunClient.
+func (c *DryRunClient) PutPublicAccessBlock(ctx context.Context, input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	c.record("PutPublicAccessBlock", input.Bucket)
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestDryRunClient(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	dryRunClient := NewDryRunClient(client)
+
+	if err := CreateBucket(context.TODO(), dryRunClient, "testbucket"); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	// The bucket which would have been created reads as a new bucket
+	exists, err := DoesBucketExist(context.TODO(), dryRunClient, "testbucket")
+	if err != nil || !exists {
+		t.Fatalf("DoesBucketExist() = %v, %v, want the created bucket", exists, err)
+	}
+	if err := EnsureBucketEncryption(context.TODO(), dryRunClient, "testbucket", "", ""); err != nil {
+		t.Fatalf("EnsureBucketEncryption() error = %v", err)
+	}
+	if err := EnsurePublicAccessBlock(context.TODO(), dryRunClient, "testbucket"); err != nil {
+		t.Fatalf("EnsurePublicAccessBlock() error = %v", err)
+	}
+	if err := TagBucket(context.TODO(), dryRunClient, "testbucket", "", "default", "cluster-abc12", nil); err != nil {
+		t.Fatalf("TagBucket() error = %v", err)
+	}
+
+	want := []string{
+		"CreateBucket testbucket",
+		"PutBucketEncryption testbucket",
+		"PutPublicAccessBlock testbucket",
+		"DeleteBucketTagging testbucket",
+		"PutBucketTagging testbucket",
+	}
+	if got := dryRunClient.Actions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Actions() = %q, want %q", got, want)
+	}
+	if len(client.putBucketEncryptionInputs) > 0 || len(client.putPublicAccessBlockInputs) > 0 || len(client.putBucketTaggingInputs) > 0 {
+		t.Errorf("dry run changed the bucket: encryption %v, public access block %v, tagging %v",
+			client.putBucketEncryptionInputs, client.putPublicAccessBlockInputs, client.putBucketTaggingInputs)
+	}
+}
+
+func TestDryRunClientExistingBucket(t *testing.T) {
+	// The read calls still reach S3, so a configured bucket plans no changes
+	client := &mockAWSClient{
+		Config: awsConfig,
+		encryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+					SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256),
+				},
+			}},
+		},
+	}
+	dryRunClient := NewDryRunClient(client)
+	if err := EnsureBucketEncryption(context.TODO(), dryRunClient, "testBucket", "", ""); err != nil {
+		t.Fatalf("EnsureBucketEncryption() error = %v", err)
+	}
+	if got := dryRunClient.Actions(); len(got) > 0 {
+		t.Errorf("Actions() = %q, want none", got)
+	}
+}
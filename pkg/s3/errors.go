@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// RequestError reports a failed S3 request, along with the details of the
+// response AWS support asks for when looking into the failure. The
+// awserr.RequestFailure of the request is still found with errors.As.
+type RequestError struct {
+	// Operation is the S3 operation of the request, such as PutBucketTagging.
+	Operation string
+	// Code is the error code of the response.
+	Code string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// RequestID is the x-amz-request-id of the response.
+	RequestID string
+
+	err awserr.RequestFailure
+}
+
+func (e *RequestError) Error() string {
+	message := e.Code
+	if e.err.Message() != "" {
+		message = fmt.Sprintf("%v: %v", message, e.err.Message())
+	}
+	return fmt.Sprintf("%v failed: %v (status code: %d, request id: %v)", e.Operation, message, e.StatusCode, e.RequestID)
+}
+
+// Unwrap returns the awserr.RequestFailure of the request.
+func (e *RequestError) Unwrap() error {
+	return e.err
+}
+
+// OperationTimeoutError reports an S3 request which didn't complete within
+// the operation timeout of the retry policy. It is retried like throttling,
+// and matches context.DeadlineExceeded with errors.Is.
+type OperationTimeoutError struct {
+	// Operation is the S3 operation of the request, such as CreateBucket.
+	Operation string
+	// Timeout is the operation timeout the request ran out of.
+	Timeout time.Duration
+}
+
+func (e *OperationTimeoutError) Error() string {
+	return fmt.Sprintf("%v timed out after %v", e.Operation, e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *OperationTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// bucketError reports a failed request on a bucket with one of the sentinel
+// errors, such as ErrBucketForbidden. It matches the sentinel with errors.Is,
+// while the request error is still found with errors.As.
+type bucketError struct {
+	kind       error
+	bucketName string
+	err        error
+}
+
+func (e *bucketError) Error() string {
+	return fmt.Sprintf("%v %v: %v", e.kind, e.bucketName, e.err)
+}
+
+// Is reports whether the target is the sentinel error of the failure.
+func (e *bucketError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Unwrap returns the error of the request.
+func (e *bucketError) Unwrap() error {
+	return e.err
+}
+
+// statusCode returns the HTTP status code of the failed request, or 0 when
+// the error doesn't report a request failure.
+func statusCode(err error) int {
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		return 0
+	}
+	return reqErr.StatusCode()
+}
+
+// wrapRequestError wraps the error in a RequestError when a request of the
+// operation failed, and returns any other error as it is.
+func wrapRequestError(operation string, err error) error {
+	var requestErr *RequestError
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &requestErr) || !errors.As(err, &reqErr) {
+		return err
+	}
+	return &RequestError{
+		Operation:  operation,
+		Code:       reqErr.Code(),
+		StatusCode: reqErr.StatusCode(),
+		RequestID:  reqErr.RequestID(),
+		err:        reqErr,
+	}
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestRequestErrorKeepsRequestID(t *testing.T) {
+	denied := awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "4442587FB7D0A2F9")
+	client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{denied}}
+
+	_, err := DoesBucketExist(context.TODO(), client, "testBucket")
+	if err == nil {
+		t.Fatalf("DoesBucketExist() error = nil, want the request failure")
+	}
+	for _, detail := range []string{"HeadBucket", "AccessDenied", "status code: 403", "request id: 4442587FB7D0A2F9"} {
+		if !strings.Contains(err.Error(), detail) {
+			t.Errorf("DoesBucketExist() error = %q, want it to mention %q", err, detail)
+		}
+	}
+	if strings.Contains(err.Error(), "\n") {
+		t.Errorf("DoesBucketExist() error = %q, want a single line", err)
+	}
+
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("DoesBucketExist() error = %v, want a RequestError", err)
+	}
+	if requestErr.Operation != "HeadBucket" || requestErr.Code != "AccessDenied" || requestErr.StatusCode != 403 || requestErr.RequestID != "4442587FB7D0A2F9" {
+		t.Errorf("RequestError = %+v, want the details of the HeadBucket response", *requestErr)
+	}
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) || reqErr.RequestID() != "4442587FB7D0A2F9" {
+		t.Errorf("DoesBucketExist() error = %v, want the awserr.RequestFailure unwrapped", err)
+	}
+	if !IsAccessDenied(err) {
+		t.Errorf("IsAccessDenied() = false for the wrapped request failure")
+	}
+}
+
+func TestWrapRequestErrorPassesOtherErrors(t *testing.T) {
+	for _, err := range []error{nil, context.Canceled, awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)} {
+		if got := wrapRequestError("GetBucketTagging", err); got != err {
+			t.Errorf("wrapRequestError(%v) = %v, want the error as it is", err, got)
+		}
+	}
+}
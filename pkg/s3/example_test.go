@@ -0,0 +1,33 @@
+package s3_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/openshift/managed-velero-operator/pkg/s3"
+)
+
+func ExampleCreateBucket() {
+	client := s3.NewClient(aws.Config{Region: aws.String("us-east-1")})
+	ctx := s3.WithRetryPolicy(context.Background(), s3.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	})
+
+	exists, err := s3.DoesBucketExist(ctx, client, "my-velero-backups")
+	if err != nil {
+		fmt.Println("unable to look the bucket up:", err)
+		return
+	}
+	if !exists {
+		if err := s3.CreateBucket(ctx, client, "my-velero-backups"); err != nil {
+			fmt.Println("unable to create the bucket:", err)
+			return
+		}
+	}
+	fmt.Println("bucket ready")
+}
@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// BucketInventory is the number and total size of the objects in a bucket.
+type BucketInventory struct {
+	// ObjectCount is the number of objects in the bucket.
+	ObjectCount int64
+	// TotalBytes is the total size of the objects in the bucket.
+	TotalBytes int64
+}
+
+// InventoryBucket lists every object in the bucket, and adds up their number
+// and size. Only the current versions are listed, so the noncurrent versions
+// of a versioned bucket aren't counted. A page of up to 1000 objects is listed
+// per request, so inventorying a large bucket takes a while.
+func InventoryBucket(ctx context.Context, s3Client Client, bucketName string) (BucketInventory, error) {
+	var inventory BucketInventory
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)}
+	for {
+		var output *s3.ListObjectsV2Output
+		err := withRetry(ctx, "ListObjectsV2", func(ctx context.Context) (err error) {
+			output, err = s3Client.ListObjectsV2(ctx, input)
+			return err
+		})
+		if err != nil {
+			return BucketInventory{}, fmt.Errorf("unable to list %v bucket objects: %w", bucketName, err)
+		}
+		for _, object := range output.Contents {
+			inventory.ObjectCount++
+			inventory.TotalBytes += aws.Int64Value(object.Size)
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			return inventory, nil
+		}
+		input.ContinuationToken = output.NextContinuationToken
+	}
+}
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// pagingMockClient is a mockAWSClient listing its pages of object sizes one
+// page per ListObjectsV2 call.
+type pagingMockClient struct {
+	mockAWSClient
+
+	pages [][]int64
+	calls int
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for pagingMockClient.
+func (c *pagingMockClient) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	c.calls++
+	page := 0
+	if input.ContinuationToken != nil {
+		page, _ = strconv.Atoi(*input.ContinuationToken)
+	}
+	output := &s3.ListObjectsV2Output{}
+	for i, size := range c.pages[page] {
+		output.Contents = append(output.Contents, &s3.Object{Key: aws.String(strconv.Itoa(page) + "/" + strconv.Itoa(i)), Size: aws.Int64(size)})
+	}
+	if page+1 < len(c.pages) {
+		output.IsTruncated = aws.Bool(true)
+		output.NextContinuationToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
+func TestInventoryBucket(t *testing.T) {
+	tests := []struct {
+		name      string
+		pages     [][]int64
+		want      BucketInventory
+		wantCalls int
+	}{
+		{
+			name:      "empty bucket",
+			pages:     [][]int64{{}},
+			want:      BucketInventory{},
+			wantCalls: 1,
+		},
+		{
+			name:      "single page",
+			pages:     [][]int64{{100, 200}},
+			want:      BucketInventory{ObjectCount: 2, TotalBytes: 300},
+			wantCalls: 1,
+		},
+		{
+			name:      "several pages",
+			pages:     [][]int64{{100, 200}, {300}, {400, 500, 600}},
+			want:      BucketInventory{ObjectCount: 6, TotalBytes: 2100},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &pagingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, pages: tt.pages}
+			got, err := InventoryBucket(context.TODO(), client, "testBucket")
+			if err != nil {
+				t.Fatalf("InventoryBucket() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("InventoryBucket() = %+v, want %+v", got, tt.want)
+			}
+			if client.calls != tt.wantCalls {
+				t.Errorf("InventoryBucket() issued %d ListObjectsV2 calls, want %d", client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
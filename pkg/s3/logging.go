@@ -0,0 +1,321 @@
+package s3
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-logr/logr"
+)
+
+// loggingClient is a Client which logs every request made to S3 at V(1),
+// with the operation, the bucket and the region of the request.
+type loggingClient struct {
+	Client
+	logger logr.Logger
+}
+
+// NewLoggingClient returns a Client making the requests with s3Client, and
+// logging them with the logger, such as the logger of the reconcile request.
+// A failed CreateBucket request is logged as an error along with its AWS error
+// code, as the bucket can't be provisioned without it.
+func NewLoggingClient(s3Client Client, logger logr.Logger) Client {
+	return &loggingClient{Client: s3Client, logger: logger}
+}
+
+// ForRegion returns a loggingClient for the region, which logs with the same logger.
+func (c *loggingClient) ForRegion(region string) (Client, error) {
+	regional, err := c.Client.ForRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoggingClient(regional, c.logger), nil
+}
+
+// logRequest logs the request of the operation on the bucket, which is nil
+// for the operations on the account, such as ListBuckets.
+func (c *loggingClient) logRequest(operation string, bucket *string, err error) {
+	keysAndValues := []interface{}{"Operation", operation}
+	if bucket != nil {
+		keysAndValues = append(keysAndValues, "S3Bucket.Name", aws.StringValue(bucket))
+	}
+	if config := c.Client.GetAWSClientConfig(); config != nil {
+		keysAndValues = append(keysAndValues, "S3Bucket.Region", aws.StringValue(config.Region))
+	}
+	if err == nil {
+		c.logger.V(1).Info("S3 request succeeded", keysAndValues...)
+		return
+	}
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		keysAndValues = append(keysAndValues, "Code", aerr.Code())
+	}
+	// A bucket already owned by us is created by an earlier attempt
+	if operation == "CreateBucket" && !isErrorCode(err, s3.ErrCodeBucketAlreadyOwnedByYou) {
+		c.logger.Error(err, "S3 request failed", keysAndValues...)
+		return
+	}
+	c.logger.V(1).Info("S3 request failed", append(keysAndValues, "error", err.Error())...)
+}
+
+// CreateBucket implements the CreateBucket method for loggingClient.
+func (c *loggingClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	output, err := c.Client.CreateBucket(ctx, input)
+	c.logRequest("CreateBucket", input.Bucket, err)
+	return output, err
+}
+
+// DeleteBucket implements the DeleteBucket method for loggingClient.
+func (c *loggingClient) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	output, err := c.Client.DeleteBucket(ctx, input)
+	c.logRequest("DeleteBucket", input.Bucket, err)
+	return output, err
+}
+
+// DeleteBucketPolicy implements the DeleteBucketPolicy method for loggingClient.
+func (c *loggingClient) DeleteBucketPolicy(ctx context.Context, input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	output, err := c.Client.DeleteBucketPolicy(ctx, input)
+	c.logRequest("DeleteBucketPolicy", input.Bucket, err)
+	return output, err
+}
+
+// DeleteBucketTagging implements the DeleteBucketTagging method for loggingClient.
+func (c *loggingClient) DeleteBucketTagging(ctx context.Context, input *s3.DeleteBucketTaggingInput) (*s3.DeleteBucketTaggingOutput, error) {
+	output, err := c.Client.DeleteBucketTagging(ctx, input)
+	c.logRequest("DeleteBucketTagging", input.Bucket, err)
+	return output, err
+}
+
+// DeleteObject implements the DeleteObject method for loggingClient.
+func (c *loggingClient) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	output, err := c.Client.DeleteObject(ctx, input)
+	c.logRequest("DeleteObject", input.Bucket, err)
+	return output, err
+}
+
+// DeleteObjects implements the DeleteObjects method for loggingClient.
+func (c *loggingClient) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	output, err := c.Client.DeleteObjects(ctx, input)
+	c.logRequest("DeleteObjects", input.Bucket, err)
+	return output, err
+}
+
+// HeadBucket implements the HeadBucket method for loggingClient.
+func (c *loggingClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	output, err := c.Client.HeadBucket(ctx, input)
+	c.logRequest("HeadBucket", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketCors implements the GetBucketCors method for loggingClient.
+func (c *loggingClient) GetBucketCors(ctx context.Context, input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	output, err := c.Client.GetBucketCors(ctx, input)
+	c.logRequest("GetBucketCors", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketEncryption implements the GetBucketEncryption method for loggingClient.
+func (c *loggingClient) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	output, err := c.Client.GetBucketEncryption(ctx, input)
+	c.logRequest("GetBucketEncryption", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketLifecycleConfiguration implements the GetBucketLifecycleConfiguration method for loggingClient.
+func (c *loggingClient) GetBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	output, err := c.Client.GetBucketLifecycleConfiguration(ctx, input)
+	c.logRequest("GetBucketLifecycleConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketLocation implements the GetBucketLocation method for loggingClient.
+func (c *loggingClient) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	output, err := c.Client.GetBucketLocation(ctx, input)
+	c.logRequest("GetBucketLocation", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketLogging implements the GetBucketLogging method for loggingClient.
+func (c *loggingClient) GetBucketLogging(ctx context.Context, input *s3.GetBucketLoggingInput) (*s3.GetBucketLoggingOutput, error) {
+	output, err := c.Client.GetBucketLogging(ctx, input)
+	c.logRequest("GetBucketLogging", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketNotificationConfiguration implements the GetBucketNotificationConfiguration method for loggingClient.
+func (c *loggingClient) GetBucketNotificationConfiguration(
+	ctx context.Context, input *s3.GetBucketNotificationConfigurationRequest) (*s3.NotificationConfiguration, error) {
+	output, err := c.Client.GetBucketNotificationConfiguration(ctx, input)
+	c.logRequest("GetBucketNotificationConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketOwnershipControls implements the GetBucketOwnershipControls method for loggingClient.
+func (c *loggingClient) GetBucketOwnershipControls(
+	ctx context.Context, input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	output, err := c.Client.GetBucketOwnershipControls(ctx, input)
+	c.logRequest("GetBucketOwnershipControls", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketPolicy implements the GetBucketPolicy method for loggingClient.
+func (c *loggingClient) GetBucketPolicy(ctx context.Context, input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	output, err := c.Client.GetBucketPolicy(ctx, input)
+	c.logRequest("GetBucketPolicy", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketReplication implements the GetBucketReplication method for loggingClient.
+func (c *loggingClient) GetBucketReplication(ctx context.Context, input *s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	output, err := c.Client.GetBucketReplication(ctx, input)
+	c.logRequest("GetBucketReplication", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketTagging implements the GetBucketTagging method for loggingClient.
+func (c *loggingClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	output, err := c.Client.GetBucketTagging(ctx, input)
+	c.logRequest("GetBucketTagging", input.Bucket, err)
+	return output, err
+}
+
+// GetBucketVersioning implements the GetBucketVersioning method for loggingClient.
+func (c *loggingClient) GetBucketVersioning(ctx context.Context, input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	output, err := c.Client.GetBucketVersioning(ctx, input)
+	c.logRequest("GetBucketVersioning", input.Bucket, err)
+	return output, err
+}
+
+// GetObjectLockConfiguration implements the GetObjectLockConfiguration method for loggingClient.
+func (c *loggingClient) GetObjectLockConfiguration(
+	ctx context.Context, input *s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	output, err := c.Client.GetObjectLockConfiguration(ctx, input)
+	c.logRequest("GetObjectLockConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// GetPublicAccessBlock implements the GetPublicAccessBlock method for loggingClient.
+func (c *loggingClient) GetPublicAccessBlock(ctx context.Context, input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	output, err := c.Client.GetPublicAccessBlock(ctx, input)
+	c.logRequest("GetPublicAccessBlock", input.Bucket, err)
+	return output, err
+}
+
+// ListBuckets implements the ListBuckets method for loggingClient.
+func (c *loggingClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	output, err := c.Client.ListBuckets(ctx, input)
+	c.logRequest("ListBuckets", nil, err)
+	return output, err
+}
+
+// ListObjectVersions implements the ListObjectVersions method for loggingClient.
+func (c *loggingClient) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	output, err := c.Client.ListObjectVersions(ctx, input)
+	c.logRequest("ListObjectVersions", input.Bucket, err)
+	return output, err
+}
+
+// ListObjectsV2 implements the ListObjectsV2 method for loggingClient.
+func (c *loggingClient) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	output, err := c.Client.ListObjectsV2(ctx, input)
+	c.logRequest("ListObjectsV2", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketCors implements the PutBucketCors method for loggingClient.
+func (c *loggingClient) PutBucketCors(ctx context.Context, input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	output, err := c.Client.PutBucketCors(ctx, input)
+	c.logRequest("PutBucketCors", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketEncryption implements the PutBucketEncryption method for loggingClient.
+func (c *loggingClient) PutBucketEncryption(ctx context.Context, input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	output, err := c.Client.PutBucketEncryption(ctx, input)
+	c.logRequest("PutBucketEncryption", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for loggingClient.
+func (c *loggingClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	output, err := c.Client.PutBucketLifecycleConfiguration(ctx, input)
+	c.logRequest("PutBucketLifecycleConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketLogging implements the PutBucketLogging method for loggingClient.
+func (c *loggingClient) PutBucketLogging(ctx context.Context, input *s3.PutBucketLoggingInput) (*s3.PutBucketLoggingOutput, error) {
+	output, err := c.Client.PutBucketLogging(ctx, input)
+	c.logRequest("PutBucketLogging", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketNotificationConfiguration implements the PutBucketNotificationConfiguration method for loggingClient.
+func (c *loggingClient) PutBucketNotificationConfiguration(
+	ctx context.Context, input *s3.PutBucketNotificationConfigurationInput) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	output, err := c.Client.PutBucketNotificationConfiguration(ctx, input)
+	c.logRequest("PutBucketNotificationConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketOwnershipControls implements the PutBucketOwnershipControls method for loggingClient.
+func (c *loggingClient) PutBucketOwnershipControls(
+	ctx context.Context, input *s3.PutBucketOwnershipControlsInput) (*s3.PutBucketOwnershipControlsOutput, error) {
+	output, err := c.Client.PutBucketOwnershipControls(ctx, input)
+	c.logRequest("PutBucketOwnershipControls", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketPolicy implements the PutBucketPolicy method for loggingClient.
+func (c *loggingClient) PutBucketPolicy(ctx context.Context, input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	output, err := c.Client.PutBucketPolicy(ctx, input)
+	c.logRequest("PutBucketPolicy", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketReplication implements the PutBucketReplication method for loggingClient.
+func (c *loggingClient) PutBucketReplication(ctx context.Context, input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	output, err := c.Client.PutBucketReplication(ctx, input)
+	c.logRequest("PutBucketReplication", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketAccelerateConfiguration implements the PutBucketAccelerateConfiguration method for loggingClient.
+func (c *loggingClient) PutBucketAccelerateConfiguration(
+	ctx context.Context, input *s3.PutBucketAccelerateConfigurationInput) (*s3.PutBucketAccelerateConfigurationOutput, error) {
+	output, err := c.Client.PutBucketAccelerateConfiguration(ctx, input)
+	c.logRequest("PutBucketAccelerateConfiguration", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketTagging implements the PutBucketTagging method for loggingClient.
+func (c *loggingClient) PutBucketTagging(ctx context.Context, input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	output, err := c.Client.PutBucketTagging(ctx, input)
+	c.logRequest("PutBucketTagging", input.Bucket, err)
+	return output, err
+}
+
+// PutBucketVersioning implements the PutBucketVersioning method for loggingClient.
+func (c *loggingClient) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	output, err := c.Client.PutBucketVersioning(ctx, input)
+	c.logRequest("PutBucketVersioning", input.Bucket, err)
+	return output, err
+}
+
+// PutObject implements the PutObject method for loggingClient.
+func (c *loggingClient) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	output, err := c.Client.PutObject(ctx, input)
+	c.logRequest("PutObject", input.Bucket, err)
+	return output, err
+}
+
+// PutPublicAccessBlock implements the PutPublicAccessBlock method for loggingClient.
+func (c *loggingClient) PutPublicAccessBlock(ctx context.Context, input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	output, err := c.Client.PutPublicAccessBlock(ctx, input)
+	c.logRequest("PutPublicAccessBlock", input.Bucket, err)
+	return output, err
+}
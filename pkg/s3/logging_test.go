@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-logr/logr"
+)
+
+// logLine is a line logged by a recordingLogger.
+type logLine struct {
+	level  int
+	err    error
+	msg    string
+	values map[string]interface{}
+}
+
+// recordingLogger is a logr.Logger recording the lines it logs.
+type recordingLogger struct {
+	lines  *[]logLine
+	level  int
+	values []interface{}
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{lines: &[]logLine{}}
+}
+
+func (l *recordingLogger) log(err error, msg string, keysAndValues []interface{}) {
+	values := make(map[string]interface{})
+	all := append(append([]interface{}{}, l.values...), keysAndValues...)
+	for i := 0; i+1 < len(all); i += 2 {
+		values[all[i].(string)] = all[i+1]
+	}
+	*l.lines = append(*l.lines, logLine{level: l.level, err: err, msg: msg, values: values})
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(nil, msg, keysAndValues)
+}
+
+func (l *recordingLogger) Enabled() bool {
+	return true
+}
+
+func (l *recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.log(err, msg, keysAndValues)
+}
+
+func (l *recordingLogger) V(level int) logr.InfoLogger {
+	return &recordingLogger{lines: l.lines, level: level, values: l.values}
+}
+
+func (l *recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &recordingLogger{lines: l.lines, level: l.level, values: append(append([]interface{}{}, l.values...), keysAndValues...)}
+}
+
+func (l *recordingLogger) WithName(name string) logr.Logger {
+	return l
+}
+
+// createFailingMockClient is a mockAWSClient whose CreateBucket calls fail.
+type createFailingMockClient struct {
+	mockAWSClient
+}
+
+// CreateBucket implements the CreateBucket method for createFailingMockClient.
+func (c *createFailingMockClient) CreateBucket(ctx context.Context, input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	return nil, awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "4442587FB7D0A2F9")
+}
+
+func TestLoggingClientCreateBucket(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    Client
+		wantLevel int
+		wantError bool
+	}{
+		{
+			name:      "bucket created",
+			client:    &mockAWSClient{Config: awsConfig},
+			wantLevel: 1,
+		},
+		{
+			name:      "creation denied",
+			client:    &createFailingMockClient{mockAWSClient{Config: awsConfig}},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newRecordingLogger()
+			err := CreateBucket(context.TODO(), NewLoggingClient(tt.client, logger), "test-bucket")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("CreateBucket() error = %v, wantError %v", err, tt.wantError)
+			}
+
+			var line *logLine
+			for i := range *logger.lines {
+				if (*logger.lines)[i].values["Operation"] == "CreateBucket" {
+					line = &(*logger.lines)[i]
+				}
+			}
+			if line == nil {
+				t.Fatalf("logged lines %+v, want one about the CreateBucket request", *logger.lines)
+			}
+			if line.values["S3Bucket.Name"] != "test-bucket" {
+				t.Errorf("CreateBucket log line values = %v, want S3Bucket.Name test-bucket", line.values)
+			}
+			if line.values["S3Bucket.Region"] != *awsConfig.Region {
+				t.Errorf("CreateBucket log line values = %v, want S3Bucket.Region %v", line.values, *awsConfig.Region)
+			}
+			if line.level != tt.wantLevel {
+				t.Errorf("CreateBucket log line at V(%d), want V(%d)", line.level, tt.wantLevel)
+			}
+			if tt.wantError && (line.err == nil || line.values["Code"] != "AccessDenied") {
+				t.Errorf("CreateBucket log line = %+v, want an error with the AccessDenied code", *line)
+			}
+		})
+	}
+}
+
+func TestLoggingClientForRegion(t *testing.T) {
+	logger := newRecordingLogger()
+	regional, err := NewLoggingClient(&mockAWSClient{Config: awsConfig}, logger).ForRegion("eu-west-1")
+	if err != nil {
+		t.Fatalf("ForRegion() error = %v", err)
+	}
+	if _, ok := regional.(*loggingClient); !ok {
+		t.Errorf("ForRegion() = %T, want a loggingClient", regional)
+	}
+	if _, err := regional.HeadBucket(context.TODO(), &s3.HeadBucketInput{Bucket: aws.String("testBucket")}); err != nil {
+		t.Fatalf("HeadBucket() error = %v", err)
+	}
+	if lines := *logger.lines; len(lines) != 1 || lines[0].values["S3Bucket.Region"] != "eu-west-1" {
+		t.Errorf("logged lines %+v, want the HeadBucket request logged in region eu-west-1", lines)
+	}
+}
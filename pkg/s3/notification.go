@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// NotificationPlan is the intended notification configuration of the bucket.
+type NotificationPlan struct {
+	Topics      []NotificationTargetPlan
+	Queues      []NotificationTargetPlan
+	EventBridge bool
+}
+
+// NotificationTargetPlan is an intended SNS topic or SQS queue notified of
+// the events of the bucket, restricted to the objects whose keys have the
+// prefix and suffix, unless empty.
+type NotificationTargetPlan struct {
+	ARN    string
+	Events []string
+	Prefix string
+	Suffix string
+}
+
+// EnsureBucketNotification sets the notification configuration of the bucket
+// to the plan, unless the bucket already sends the planned events, in any
+// order. Any other notifications of the bucket, such as to Lambda functions,
+// are removed.
+func EnsureBucketNotification(ctx context.Context, s3Client Client, bucketName string, plan NotificationPlan) error {
+	var current *s3.NotificationConfiguration
+	err := withRetry(ctx, "GetBucketNotificationConfiguration", func(ctx context.Context) (err error) {
+		current, err = s3Client.GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationRequest{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read %v bucket notification configuration: %w", bucketName, err)
+	}
+	if notificationMatches(current, plan) {
+		return nil
+	}
+
+	input := &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucketName),
+		NotificationConfiguration: plan.notificationConfiguration(),
+	}
+	if err := input.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket notification configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketNotificationConfiguration", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketNotificationConfiguration(ctx, input)
+		return err
+	})
+}
+
+// notificationConfiguration returns the S3 notification configuration
+// described by the plan.
+func (p NotificationPlan) notificationConfiguration() *s3.NotificationConfiguration {
+	configuration := &s3.NotificationConfiguration{}
+	for _, topic := range p.Topics {
+		configuration.TopicConfigurations = append(configuration.TopicConfigurations, &s3.TopicConfiguration{
+			TopicArn: aws.String(topic.ARN),
+			Events:   aws.StringSlice(topic.Events),
+			Filter:   topic.filter(),
+		})
+	}
+	for _, queue := range p.Queues {
+		configuration.QueueConfigurations = append(configuration.QueueConfigurations, &s3.QueueConfiguration{
+			QueueArn: aws.String(queue.ARN),
+			Events:   aws.StringSlice(queue.Events),
+			Filter:   queue.filter(),
+		})
+	}
+	if p.EventBridge {
+		configuration.EventBridgeConfiguration = &s3.EventBridgeConfiguration{}
+	}
+	return configuration
+}
+
+// filter returns the S3 key filter of the target, or nil when the events of
+// every object are sent.
+func (p NotificationTargetPlan) filter() *s3.NotificationConfigurationFilter {
+	var rules []*s3.FilterRule
+	if p.Prefix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String("prefix"), Value: aws.String(p.Prefix)})
+	}
+	if p.Suffix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String("suffix"), Value: aws.String(p.Suffix)})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{FilterRules: rules}}
+}
+
+// key identifies the target, its events and its filter, regardless of the
+// order of the events.
+func (p NotificationTargetPlan) key() string {
+	events := append([]string{}, p.Events...)
+	sort.Strings(events)
+	return strings.Join([]string{p.ARN, strings.Join(events, ","), p.Prefix, p.Suffix}, "|")
+}
+
+// currentTarget returns the plan of a notification target of the bucket. S3
+// reports the names of the filter rules capitalized, as Prefix and Suffix.
+func currentTarget(arn *string, events []*string, filter *s3.NotificationConfigurationFilter) NotificationTargetPlan {
+	target := NotificationTargetPlan{ARN: aws.StringValue(arn), Events: aws.StringValueSlice(events)}
+	if filter == nil || filter.Key == nil {
+		return target
+	}
+	for _, rule := range filter.Key.FilterRules {
+		switch strings.ToLower(aws.StringValue(rule.Name)) {
+		case "prefix":
+			target.Prefix = aws.StringValue(rule.Value)
+		case "suffix":
+			target.Suffix = aws.StringValue(rule.Value)
+		}
+	}
+	return target
+}
+
+// notificationMatches checks whether the notification configuration of the
+// bucket sends the planned events, and no others. The notification IDs S3
+// generates are ignored.
+func notificationMatches(current *s3.NotificationConfiguration, plan NotificationPlan) bool {
+	if current == nil {
+		current = &s3.NotificationConfiguration{}
+	}
+	if len(current.LambdaFunctionConfigurations) > 0 || (current.EventBridgeConfiguration != nil) != plan.EventBridge {
+		return false
+	}
+	var topics, queues []NotificationTargetPlan
+	for _, topic := range current.TopicConfigurations {
+		topics = append(topics, currentTarget(topic.TopicArn, topic.Events, topic.Filter))
+	}
+	for _, queue := range current.QueueConfigurations {
+		queues = append(queues, currentTarget(queue.QueueArn, queue.Events, queue.Filter))
+	}
+	return targetsMatch(topics, plan.Topics) && targetsMatch(queues, plan.Queues)
+}
+
+// targetsMatch checks whether the notification targets of the bucket are the
+// planned targets, in any order.
+func targetsMatch(current []NotificationTargetPlan, planned []NotificationTargetPlan) bool {
+	if len(current) != len(planned) {
+		return false
+	}
+	keys := make(map[string]int)
+	for _, target := range current {
+		keys[target.key()]++
+	}
+	for _, target := range planned {
+		if keys[target.key()] == 0 {
+			return false
+		}
+		keys[target.key()]--
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestEnsureBucketNotification(t *testing.T) {
+	plan := NotificationPlan{
+		Topics: []NotificationTargetPlan{{
+			ARN:    "arn:aws:sns:us-east-1:123456789012:velero-backups",
+			Events: []string{"s3:ObjectCreated:*"},
+			Prefix: "backups/",
+			Suffix: "velero-backup.json",
+		}},
+		Queues: []NotificationTargetPlan{
+			{ARN: "arn:aws:sqs:us-east-1:123456789012:velero-created", Events: []string{"s3:ObjectCreated:Put", "s3:ObjectCreated:CompleteMultipartUpload"}},
+			{ARN: "arn:aws:sqs:us-east-1:123456789012:velero-removed", Events: []string{"s3:ObjectRemoved:*"}},
+		},
+		EventBridge: true,
+	}
+	// S3 reports the configuration with generated IDs and capitalized filter rule names
+	matching := func() *s3.NotificationConfiguration {
+		return &s3.NotificationConfiguration{
+			TopicConfigurations: []*s3.TopicConfiguration{{
+				Id:       aws.String("NjQ3YzE2OTQtYmE2Zi00ZTQyLWI4YzEtYjY4ZDhkNmE0ZTQ2"),
+				TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:velero-backups"),
+				Events:   aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				Filter: &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{FilterRules: []*s3.FilterRule{
+					{Name: aws.String("Suffix"), Value: aws.String("velero-backup.json")},
+					{Name: aws.String("Prefix"), Value: aws.String("backups/")},
+				}}},
+			}},
+			QueueConfigurations: []*s3.QueueConfiguration{
+				{QueueArn: aws.String("arn:aws:sqs:us-east-1:123456789012:velero-removed"), Events: aws.StringSlice([]string{"s3:ObjectRemoved:*"})},
+				{
+					QueueArn: aws.String("arn:aws:sqs:us-east-1:123456789012:velero-created"),
+					Events:   aws.StringSlice([]string{"s3:ObjectCreated:CompleteMultipartUpload", "s3:ObjectCreated:Put"}),
+				},
+			},
+			EventBridgeConfiguration: &s3.EventBridgeConfiguration{},
+		}
+	}
+	tests := []struct {
+		name     string
+		current  func() *s3.NotificationConfiguration
+		wantPuts int
+	}{
+		{
+			name:     "no notification configuration",
+			wantPuts: 1,
+		},
+		{
+			name:     "configuration already matches",
+			current:  matching,
+			wantPuts: 0,
+		},
+		{
+			name: "other topic filter",
+			current: func() *s3.NotificationConfiguration {
+				configuration := matching()
+				configuration.TopicConfigurations[0].Filter = nil
+				return configuration
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "EventBridge disabled",
+			current: func() *s3.NotificationConfiguration {
+				configuration := matching()
+				configuration.EventBridgeConfiguration = nil
+				return configuration
+			},
+			wantPuts: 1,
+		},
+		{
+			name: "additional Lambda function",
+			current: func() *s3.NotificationConfiguration {
+				configuration := matching()
+				configuration.LambdaFunctionConfigurations = []*s3.LambdaFunctionConfiguration{{
+					LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:index"),
+					Events:            aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				}}
+				return configuration
+			},
+			wantPuts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig}
+			if tt.current != nil {
+				client.notification = tt.current()
+			}
+			if err := EnsureBucketNotification(context.TODO(), client, "testBucket", plan); err != nil {
+				t.Fatalf("EnsureBucketNotification() error = %v", err)
+			}
+			if len(client.putBucketNotificationInputs) != tt.wantPuts {
+				t.Errorf("EnsureBucketNotification() issued %d PutBucketNotificationConfiguration calls, want %d",
+					len(client.putBucketNotificationInputs), tt.wantPuts)
+			}
+			if !notificationMatches(client.notification, plan) {
+				t.Errorf("notification configuration = %v, want %+v", client.notification, plan)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3control"
+	"github.com/aws/aws-sdk-go/service/s3control/s3controliface"
+)
+
+const (
+	// errCodeNoSuchAccessPoint is the error code of an access point which
+	// doesn't exist
+	errCodeNoSuchAccessPoint = "NoSuchAccessPoint"
+	// maxAccessPointNameLength is the longest access point name S3 accepts
+	maxAccessPointNameLength = 50
+)
+
+// awsOutpostsClient implements the OutpostsClient interface.
+type awsOutpostsClient struct {
+	s3ControlClient s3controliface.S3ControlAPI
+	Config          *aws.Config
+}
+
+// OutpostsClient is a wrapper object for the actual AWS SDK S3 Control client,
+// which manages the buckets on S3 on Outposts resources, to allow for easier
+// testing. Create one with NewOutpostsClient.
+type OutpostsClient interface {
+	CreateAccessPoint(context.Context, *s3control.CreateAccessPointInput) (*s3control.CreateAccessPointOutput, error)
+	CreateBucket(context.Context, *s3control.CreateBucketInput) (*s3control.CreateBucketOutput, error)
+	GetAccessPoint(context.Context, *s3control.GetAccessPointInput) (*s3control.GetAccessPointOutput, error)
+	GetAWSClientConfig() *aws.Config
+	PutBucketLifecycleConfiguration(context.Context, *s3control.PutBucketLifecycleConfigurationInput) (*s3control.PutBucketLifecycleConfigurationOutput, error)
+	PutBucketTagging(context.Context, *s3control.PutBucketTaggingInput) (*s3control.PutBucketTaggingOutput, error)
+}
+
+// When all of the above OutpostsClient methods are implemented for awsOutpostsClient, awsOutpostsClient becomes a kind of OutpostsClient.
+
+// CreateAccessPoint implements the CreateAccessPoint method for awsOutpostsClient.
+func (c *awsOutpostsClient) CreateAccessPoint(ctx context.Context, input *s3control.CreateAccessPointInput) (*s3control.CreateAccessPointOutput, error) {
+	return c.s3ControlClient.CreateAccessPointWithContext(ctx, input)
+}
+
+// CreateBucket implements the CreateBucket method for awsOutpostsClient.
+func (c *awsOutpostsClient) CreateBucket(ctx context.Context, input *s3control.CreateBucketInput) (*s3control.CreateBucketOutput, error) {
+	return c.s3ControlClient.CreateBucketWithContext(ctx, input)
+}
+
+// GetAccessPoint implements the GetAccessPoint method for awsOutpostsClient.
+func (c *awsOutpostsClient) GetAccessPoint(ctx context.Context, input *s3control.GetAccessPointInput) (*s3control.GetAccessPointOutput, error) {
+	return c.s3ControlClient.GetAccessPointWithContext(ctx, input)
+}
+
+// GetAWSClientConfig returns the AWS Client Config for the awsOutpostsClient.
+func (c *awsOutpostsClient) GetAWSClientConfig() *aws.Config {
+	return c.Config
+}
+
+// PutBucketLifecycleConfiguration implements the PutBucketLifecycleConfiguration method for awsOutpostsClient.
+func (c *awsOutpostsClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3control.PutBucketLifecycleConfigurationInput) (*s3control.PutBucketLifecycleConfigurationOutput, error) {
+	return c.s3ControlClient.PutBucketLifecycleConfigurationWithContext(ctx, input)
+}
+
+// PutBucketTagging implements the PutBucketTagging method for awsOutpostsClient.
+func (c *awsOutpostsClient) PutBucketTagging(ctx context.Context, input *s3control.PutBucketTaggingInput) (*s3control.PutBucketTaggingOutput, error) {
+	return c.s3ControlClient.PutBucketTaggingWithContext(ctx, input)
+}
+
+// NewOutpostsClient creates a new client for managing the buckets on S3 on
+// Outposts, using the region and credentials from an existing AWS client
+// config. The S3 Control API addresses S3 on Outposts when given an Outpost
+// ARN, and doesn't support transfer acceleration, so it is left disabled.
+func NewOutpostsClient(awsConfig *aws.Config) (OutpostsClient, error) {
+	outpostsConfig := awsConfig.Copy().WithS3UseAccelerate(false)
+	s, err := session.NewSession(outpostsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the actual AWS client into the awsOutpostsClient interface.
+	return &awsOutpostsClient{
+		s3ControlClient: s3control.New(s),
+		Config:          outpostsConfig,
+	}, nil
+}
+
+// OutpostBucketARN returns the ARN which addresses a bucket on an S3 on
+// Outposts resource, in place of the bucket name. The ARN is in the AWS
+// partition of the region.
+func OutpostBucketARN(region string, accountID string, outpostID string, bucketName string) string {
+	return fmt.Sprintf("arn:%s:s3-outposts:%s:%s:outpost/%s/bucket/%s", PartitionID(region), region, accountID, outpostID, bucketName)
+}
+
+// OutpostAccessPointARN returns the ARN of the access point on an S3 on
+// Outposts resource, which the objects of its bucket are addressed through.
+func OutpostAccessPointARN(region string, accountID string, outpostID string, accessPointName string) string {
+	return fmt.Sprintf("arn:%s:s3-outposts:%s:%s:outpost/%s/accesspoint/%s", PartitionID(region), region, accountID, outpostID, accessPointName)
+}
+
+// OutpostAccessPointName returns the name of the access point of the bucket on
+// an Outpost: the bucket name, truncated to the longest access point name.
+func OutpostAccessPointName(bucketName string) string {
+	if len(bucketName) > maxAccessPointNameLength {
+		bucketName = strings.TrimRight(bucketName[:maxAccessPointNameLength], "-.")
+	}
+	return bucketName
+}
+
+// CreateOutpostBucket creates the bucket on the Outpost. A bucket the account
+// already owns is left as is.
+func CreateOutpostBucket(ctx context.Context, outpostsClient OutpostsClient, outpostID string, bucketName string) error {
+	if err := ValidateBucketName(bucketName); err != nil {
+		return err
+	}
+	err := withRetry(ctx, "CreateBucket", func(ctx context.Context) error {
+		_, err := outpostsClient.CreateBucket(ctx, &s3control.CreateBucketInput{
+			Bucket:    aws.String(bucketName),
+			OutpostId: aws.String(outpostID),
+		})
+		return err
+	})
+	switch {
+	case isErrorCode(err, s3control.ErrCodeBucketAlreadyOwnedByYou):
+		err = nil
+	case isErrorCode(err, s3control.ErrCodeBucketAlreadyExists):
+		err = &bucketError{kind: ErrBucketNameTaken, bucketName: bucketName, err: err}
+	}
+	return err
+}
+
+// EnsureOutpostAccessPoint creates the access point of the bucket on the
+// Outpost, which only accepts requests from the VPC, unless it exists. The ARN
+// of the access point is returned.
+func EnsureOutpostAccessPoint(ctx context.Context, outpostsClient OutpostsClient, accountID string, outpostID string, bucketName string, vpcID string) (string, error) {
+	region := aws.StringValue(outpostsClient.GetAWSClientConfig().Region)
+	name := OutpostAccessPointName(bucketName)
+	accessPointARN := OutpostAccessPointARN(region, accountID, outpostID, name)
+
+	err := withRetry(ctx, "GetAccessPoint", func(ctx context.Context) error {
+		_, err := outpostsClient.GetAccessPoint(ctx, &s3control.GetAccessPointInput{
+			AccountId: aws.String(accountID),
+			Name:      aws.String(accessPointARN),
+		})
+		return err
+	})
+	if err == nil {
+		return accessPointARN, nil
+	}
+	if !isErrorCode(err, errCodeNoSuchAccessPoint) {
+		return "", fmt.Errorf("unable to read access point %v: %w", accessPointARN, err)
+	}
+
+	err = withRetry(ctx, "CreateAccessPoint", func(ctx context.Context) error {
+		_, err := outpostsClient.CreateAccessPoint(ctx, &s3control.CreateAccessPointInput{
+			AccountId:        aws.String(accountID),
+			Bucket:           aws.String(OutpostBucketARN(region, accountID, outpostID, bucketName)),
+			Name:             aws.String(name),
+			VpcConfiguration: &s3control.VpcConfiguration{VpcId: aws.String(vpcID)},
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create access point %v: %w", accessPointARN, err)
+	}
+	return accessPointARN, nil
+}
+
+// TagOutpostBucket replaces the tags of the bucket on the Outpost with the
+// extraTags, alongside the tags used to identify the bucket, keyed under the
+// tag key prefix.
+func TagOutpostBucket(ctx context.Context, outpostsClient OutpostsClient, accountID string, outpostID string, bucketName string,
+	tagKeyPrefix string, backUpLocation string, infraName string, extraTags map[string]string) error {
+	tags := bucketTagSet(tagKeyPrefix, backUpLocation, infraName, extraTags)
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tagging := &s3control.Tagging{}
+	for _, key := range keys {
+		tagging.TagSet = append(tagging.TagSet, &s3control.S3Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+
+	region := aws.StringValue(outpostsClient.GetAWSClientConfig().Region)
+	return withRetry(ctx, "PutBucketTagging", func(ctx context.Context) error {
+		_, err := outpostsClient.PutBucketTagging(ctx, &s3control.PutBucketTaggingInput{
+			AccountId: aws.String(accountID),
+			Bucket:    aws.String(OutpostBucketARN(region, accountID, outpostID, bucketName)),
+			Tagging:   tagging,
+		})
+		return err
+	})
+}
+
+// SetOutpostBucketLifecycle replaces the lifecycle rules of the bucket on the
+// Outpost with the backup expiry rule. S3 on Outposts keeps every object in a
+// single storage class, so the rule mustn't transition the objects.
+func SetOutpostBucketLifecycle(ctx context.Context, outpostsClient OutpostsClient, accountID string, outpostID string, bucketName string, backupExpiry LifecycleRulePlan) error {
+	if len(backupExpiry.Transitions) > 0 {
+		return fmt.Errorf("rule %v transitions the objects of bucket %v, which S3 on Outposts doesn't support: %w",
+			backupExpiry.ID, bucketName, ErrInvalidLifecycleTransition)
+	}
+	rule := &s3control.LifecycleRule{
+		ID:     aws.String(backupExpiry.ID),
+		Status: aws.String(s3control.ExpirationStatusEnabled),
+		Filter: &s3control.LifecycleRuleFilter{
+			Prefix: aws.String(backupExpiry.Prefix),
+		},
+	}
+	if backupExpiry.ExpirationDays > 0 {
+		rule.Expiration = &s3control.LifecycleExpiration{
+			Days: aws.Int64(backupExpiry.ExpirationDays),
+		}
+	} else if backupExpiry.ExpiredObjectDeleteMarker {
+		rule.Expiration = &s3control.LifecycleExpiration{
+			ExpiredObjectDeleteMarker: aws.Bool(true),
+		}
+	}
+	if backupExpiry.NoncurrentExpirationDays > 0 {
+		rule.NoncurrentVersionExpiration = &s3control.NoncurrentVersionExpiration{
+			NoncurrentDays: aws.Int64(backupExpiry.NoncurrentExpirationDays),
+		}
+	}
+
+	region := aws.StringValue(outpostsClient.GetAWSClientConfig().Region)
+	return withRetry(ctx, "PutBucketLifecycleConfiguration", func(ctx context.Context) error {
+		_, err := outpostsClient.PutBucketLifecycleConfiguration(ctx, &s3control.PutBucketLifecycleConfigurationInput{
+			AccountId:              aws.String(accountID),
+			Bucket:                 aws.String(OutpostBucketARN(region, accountID, outpostID, bucketName)),
+			LifecycleConfiguration: &s3control.LifecycleConfiguration{Rules: []*s3control.LifecycleRule{rule}},
+		})
+		return err
+	})
+}
@@ -0,0 +1,212 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3control"
+)
+
+const (
+	testAccountID = "123456789012"
+	testOutpostID = "op-01ac5d28a6a232904"
+)
+
+// mockOutpostsClient implements the OutpostsClient interface.
+type mockOutpostsClient struct {
+	Config *aws.Config
+
+	// createBucketErr is returned by CreateBucket.
+	createBucketErr error
+	// accessPoints holds the ARNs of the existing access points.
+	accessPoints map[string]bool
+	// createAccessPointInputs records every CreateAccessPoint request.
+	createAccessPointInputs []*s3control.CreateAccessPointInput
+	// putBucketTaggingInputs records every PutBucketTagging request.
+	putBucketTaggingInputs []*s3control.PutBucketTaggingInput
+	// putBucketLifecycleConfigurationInputs records every PutBucketLifecycleConfiguration request.
+	putBucketLifecycleConfigurationInputs []*s3control.PutBucketLifecycleConfigurationInput
+}
+
+func (c *mockOutpostsClient) CreateAccessPoint(ctx context.Context, input *s3control.CreateAccessPointInput) (*s3control.CreateAccessPointOutput, error) {
+	c.createAccessPointInputs = append(c.createAccessPointInputs, input)
+	return &s3control.CreateAccessPointOutput{}, nil
+}
+
+func (c *mockOutpostsClient) CreateBucket(ctx context.Context, input *s3control.CreateBucketInput) (*s3control.CreateBucketOutput, error) {
+	return &s3control.CreateBucketOutput{}, c.createBucketErr
+}
+
+func (c *mockOutpostsClient) GetAccessPoint(ctx context.Context, input *s3control.GetAccessPointInput) (*s3control.GetAccessPointOutput, error) {
+	if !c.accessPoints[*input.Name] {
+		return nil, awserr.New(errCodeNoSuchAccessPoint, "The specified accesspoint does not exist", nil)
+	}
+	return &s3control.GetAccessPointOutput{}, nil
+}
+
+func (c *mockOutpostsClient) GetAWSClientConfig() *aws.Config {
+	return c.Config
+}
+
+func (c *mockOutpostsClient) PutBucketLifecycleConfiguration(
+	ctx context.Context, input *s3control.PutBucketLifecycleConfigurationInput) (*s3control.PutBucketLifecycleConfigurationOutput, error) {
+	c.putBucketLifecycleConfigurationInputs = append(c.putBucketLifecycleConfigurationInputs, input)
+	return &s3control.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (c *mockOutpostsClient) PutBucketTagging(ctx context.Context, input *s3control.PutBucketTaggingInput) (*s3control.PutBucketTaggingOutput, error) {
+	c.putBucketTaggingInputs = append(c.putBucketTaggingInputs, input)
+	return &s3control.PutBucketTaggingOutput{}, nil
+}
+
+func TestOutpostBucketARN(t *testing.T) {
+	got := OutpostBucketARN(region, testAccountID, testOutpostID, "managed-velero-backups-test")
+	want := "arn:aws:s3-outposts:us-east-1:123456789012:outpost/op-01ac5d28a6a232904/bucket/managed-velero-backups-test"
+	if got != want {
+		t.Errorf("OutpostBucketARN() = %v, want %v", got, want)
+	}
+
+	got = OutpostBucketARN("us-gov-west-1", testAccountID, testOutpostID, "managed-velero-backups-test")
+	want = "arn:aws-us-gov:s3-outposts:us-gov-west-1:123456789012:outpost/op-01ac5d28a6a232904/bucket/managed-velero-backups-test"
+	if got != want {
+		t.Errorf("OutpostBucketARN() = %v in GovCloud, want %v", got, want)
+	}
+}
+
+func TestOutpostAccessPointARN(t *testing.T) {
+	name := OutpostAccessPointName("managed-velero-backups-0123456789abcdef0123456789abcdef0123")
+	if len(name) > maxAccessPointNameLength {
+		t.Errorf("OutpostAccessPointName() = %v, longer than %d characters", name, maxAccessPointNameLength)
+	}
+	if got := OutpostAccessPointName("managed-velero-backups-test"); got != "managed-velero-backups-test" {
+		t.Errorf("OutpostAccessPointName() = %v, want the bucket name", got)
+	}
+
+	got := OutpostAccessPointARN(region, testAccountID, testOutpostID, "managed-velero-backups-test")
+	want := "arn:aws:s3-outposts:us-east-1:123456789012:outpost/op-01ac5d28a6a232904/accesspoint/managed-velero-backups-test"
+	if got != want {
+		t.Errorf("OutpostAccessPointARN() = %v, want %v", got, want)
+	}
+}
+
+func TestNewOutpostsClient(t *testing.T) {
+	awsConfig := newAWSConfig("us-gov-west-1", Endpoint{Accelerate: true})
+	outpostsClient, err := NewOutpostsClient(awsConfig)
+	if err != nil {
+		t.Fatalf("NewOutpostsClient() error = %v", err)
+	}
+
+	config := outpostsClient.GetAWSClientConfig()
+	if got := aws.StringValue(config.Region); got != "us-gov-west-1" {
+		t.Errorf("NewOutpostsClient() region = %v, want us-gov-west-1", got)
+	}
+	if aws.BoolValue(config.S3UseAccelerate) {
+		t.Errorf("NewOutpostsClient() enables transfer acceleration, which S3 on Outposts doesn't support")
+	}
+	if !aws.BoolValue(awsConfig.S3UseAccelerate) {
+		t.Errorf("NewOutpostsClient() changed the configuration of the S3 client")
+	}
+	// The S3 Control client derives the S3 on Outposts endpoint from the
+	// Outpost ARN of each request, in the region of its S3 Control endpoint
+	resolved, err := config.EndpointResolver.EndpointFor("s3-control", "us-gov-west-1")
+	if err != nil {
+		t.Fatalf("EndpointFor() error = %v", err)
+	}
+	if want := "https://s3-control.us-gov-west-1.amazonaws.com"; resolved.URL != want {
+		t.Errorf("S3 Control endpoint = %v, want %v", resolved.URL, want)
+	}
+}
+
+func TestCreateOutpostBucket(t *testing.T) {
+	outpostsClient := &mockOutpostsClient{Config: awsConfig}
+	outpostsClient.createBucketErr = awserr.New(s3control.ErrCodeBucketAlreadyOwnedByYou, "Your previous request to create the named bucket succeeded", nil)
+	if err := CreateOutpostBucket(context.TODO(), outpostsClient, testOutpostID, "managed-velero-backups-test"); err != nil {
+		t.Errorf("CreateOutpostBucket() error = %v for a bucket the account owns", err)
+	}
+
+	outpostsClient.createBucketErr = awserr.New(s3control.ErrCodeBucketAlreadyExists, "The requested bucket name is not available", nil)
+	err := CreateOutpostBucket(context.TODO(), outpostsClient, testOutpostID, "managed-velero-backups-test")
+	if !errors.Is(err, ErrBucketNameTaken) {
+		t.Errorf("CreateOutpostBucket() error = %v, want %v", err, ErrBucketNameTaken)
+	}
+}
+
+func TestEnsureOutpostAccessPoint(t *testing.T) {
+	outpostsClient := &mockOutpostsClient{Config: awsConfig}
+	accessPointARN, err := EnsureOutpostAccessPoint(context.TODO(), outpostsClient, testAccountID, testOutpostID, "managed-velero-backups-test", "vpc-0123456789abcdef0")
+	if err != nil {
+		t.Fatalf("EnsureOutpostAccessPoint() error = %v", err)
+	}
+	if want := OutpostAccessPointARN(region, testAccountID, testOutpostID, "managed-velero-backups-test"); accessPointARN != want {
+		t.Errorf("EnsureOutpostAccessPoint() = %v, want %v", accessPointARN, want)
+	}
+	if len(outpostsClient.createAccessPointInputs) != 1 {
+		t.Fatalf("CreateAccessPoint() calls = %d, want 1", len(outpostsClient.createAccessPointInputs))
+	}
+	input := outpostsClient.createAccessPointInputs[0]
+	if want := OutpostBucketARN(region, testAccountID, testOutpostID, "managed-velero-backups-test"); aws.StringValue(input.Bucket) != want {
+		t.Errorf("CreateAccessPoint() bucket = %v, want %v", aws.StringValue(input.Bucket), want)
+	}
+	if input.VpcConfiguration == nil || aws.StringValue(input.VpcConfiguration.VpcId) != "vpc-0123456789abcdef0" {
+		t.Errorf("CreateAccessPoint() VPC configuration = %v, want vpc-0123456789abcdef0", input.VpcConfiguration)
+	}
+
+	// An existing access point is left as is
+	outpostsClient.accessPoints = map[string]bool{accessPointARN: true}
+	if _, err = EnsureOutpostAccessPoint(context.TODO(), outpostsClient, testAccountID, testOutpostID, "managed-velero-backups-test", "vpc-0123456789abcdef0"); err != nil {
+		t.Fatalf("EnsureOutpostAccessPoint() error = %v", err)
+	}
+	if len(outpostsClient.createAccessPointInputs) != 1 {
+		t.Errorf("EnsureOutpostAccessPoint() created the existing access point again")
+	}
+}
+
+func TestTagOutpostBucket(t *testing.T) {
+	outpostsClient := &mockOutpostsClient{Config: awsConfig}
+	if err := TagOutpostBucket(context.TODO(), outpostsClient, testAccountID, testOutpostID, "managed-velero-backups-test",
+		"", defaultBackupStorageLocation, clusterInfraName, map[string]string{"cost-center": "1234"}); err != nil {
+		t.Fatalf("TagOutpostBucket() error = %v", err)
+	}
+	if len(outpostsClient.putBucketTaggingInputs) != 1 {
+		t.Fatalf("PutBucketTagging() calls = %d, want 1", len(outpostsClient.putBucketTaggingInputs))
+	}
+	input := outpostsClient.putBucketTaggingInputs[0]
+	if want := OutpostBucketARN(region, testAccountID, testOutpostID, "managed-velero-backups-test"); aws.StringValue(input.Bucket) != want {
+		t.Errorf("PutBucketTagging() bucket = %v, want %v", aws.StringValue(input.Bucket), want)
+	}
+	tags := make(map[string]string)
+	for _, tag := range input.Tagging.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if tags[TagKey("", bucketTagBackupLocation)] != defaultBackupStorageLocation || tags[TagKey("", bucketTagInfraName)] != clusterInfraName || tags["cost-center"] != "1234" {
+		t.Errorf("PutBucketTagging() tags = %v, want the identifying and extra tags", tags)
+	}
+}
+
+func TestSetOutpostBucketLifecycle(t *testing.T) {
+	outpostsClient := &mockOutpostsClient{Config: awsConfig}
+	if err := SetOutpostBucketLifecycle(context.TODO(), outpostsClient, testAccountID, testOutpostID, "managed-velero-backups-test", BackupExpiryRule(90, true)); err != nil {
+		t.Fatalf("SetOutpostBucketLifecycle() error = %v", err)
+	}
+	if len(outpostsClient.putBucketLifecycleConfigurationInputs) != 1 {
+		t.Fatalf("PutBucketLifecycleConfiguration() calls = %d, want 1", len(outpostsClient.putBucketLifecycleConfigurationInputs))
+	}
+	input := outpostsClient.putBucketLifecycleConfigurationInputs[0]
+	if want := OutpostBucketARN(region, testAccountID, testOutpostID, "managed-velero-backups-test"); aws.StringValue(input.Bucket) != want {
+		t.Errorf("PutBucketLifecycleConfiguration() bucket = %v, want %v", aws.StringValue(input.Bucket), want)
+	}
+	rules := input.LifecycleConfiguration.Rules
+	if len(rules) != 1 || rules[0].Expiration == nil || aws.Int64Value(rules[0].Expiration.Days) != 90 {
+		t.Errorf("PutBucketLifecycleConfiguration() rules = %v, want the backups expiring after 90 days", rules)
+	}
+
+	rule := BackupExpiryRule(90, true)
+	rule.Transitions = []TransitionPlan{{Days: 30, StorageClass: "GLACIER"}}
+	err := SetOutpostBucketLifecycle(context.TODO(), outpostsClient, testAccountID, testOutpostID, "managed-velero-backups-test", rule)
+	if !errors.Is(err, ErrInvalidLifecycleTransition) {
+		t.Errorf("SetOutpostBucketLifecycle() error = %v with a transition, want %v", err, ErrInvalidLifecycleTransition)
+	}
+}
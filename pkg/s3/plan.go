@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	backupExpiryRuleID = "Backup Expiry"
+	backupExpiryPrefix = "backups/"
+
+	// DefaultBackupExpiryDays is how many days backups are kept when no
+	// other retention is configured.
+	DefaultBackupExpiryDays = 90
+)
+
+// BucketPlan describes the intended configuration of a bucket, as enforced by
+// the operator. It marshals to the same document for the same configuration,
+// so that rendered plans can be diffed across runs. The lifecycle rules of a
+// bucket whose LifecycleUnmanaged is set are left to the user, and so are the
+// encryption of a bucket whose EncryptionUnmanaged is set, and the tags of a
+// bucket whose TagsUnmanaged is set.
+type BucketPlan struct {
+	Name                string              `json:"name,omitempty"`
+	Region              string              `json:"region,omitempty"`
+	Encryption          EncryptionPlan      `json:"encryption"`
+	EncryptionUnmanaged bool                `json:"encryptionUnmanaged,omitempty"`
+	BlockPublicAccess   bool                `json:"blockPublicAccess"`
+	LifecycleRules      []LifecycleRulePlan `json:"lifecycleRules,omitempty"`
+	LifecycleUnmanaged  bool                `json:"lifecycleUnmanaged,omitempty"`
+	Tags                map[string]string   `json:"tags,omitempty"`
+	TagsUnmanaged       bool                `json:"tagsUnmanaged,omitempty"`
+}
+
+// EncryptionPlan describes the default encryption of a bucket.
+type EncryptionPlan struct {
+	Algorithm string `json:"algorithm"`
+	KMSKeyID  string `json:"kmsKeyId,omitempty"`
+}
+
+// LifecycleRulePlan describes an expiration rule for the objects in a bucket,
+// and the storage classes they move to before they expire.
+type LifecycleRulePlan struct {
+	ID                        string           `json:"id"`
+	Prefix                    string           `json:"prefix,omitempty"`
+	ExpirationDays            int64            `json:"expirationDays,omitempty"`
+	NoncurrentExpirationDays  int64            `json:"noncurrentExpirationDays,omitempty"`
+	ExpiredObjectDeleteMarker bool             `json:"expiredObjectDeleteMarker,omitempty"`
+	Transitions               []TransitionPlan `json:"transitions,omitempty"`
+}
+
+// TransitionPlan describes when the objects move to another storage class.
+type TransitionPlan struct {
+	Days         int64  `json:"days"`
+	StorageClass string `json:"storageClass"`
+}
+
+// validate checks that every transition happens before the objects expire,
+// as S3 would otherwise have them expire before they move.
+func (p LifecycleRulePlan) validate() error {
+	for _, transition := range p.Transitions {
+		if p.ExpirationDays > 0 && transition.Days >= p.ExpirationDays {
+			return fmt.Errorf("rule %v transitions to %v after %d days, but expires after %d days: %w",
+				p.ID, transition.StorageClass, transition.Days, p.ExpirationDays, ErrInvalidLifecycleTransition)
+		}
+	}
+	return nil
+}
+
+// BackupExpiryRule returns the rule expiring backups after the given days.
+// Without expireCurrent, which a versioned bucket may not need, the noncurrent
+// versions expire instead, and the delete markers left once every version of
+// an object expired are removed. S3 doesn't allow removing expired delete
+// markers in a rule which also expires the current versions.
+func BackupExpiryRule(expirationDays int64, expireCurrent bool) LifecycleRulePlan {
+	rule := LifecycleRulePlan{
+		ID:     backupExpiryRuleID,
+		Prefix: backupExpiryPrefix,
+	}
+	if expireCurrent {
+		rule.ExpirationDays = expirationDays
+	} else {
+		rule.NoncurrentExpirationDays = expirationDays
+		rule.ExpiredObjectDeleteMarker = true
+	}
+	return rule
+}
+
+// lifecycleRule returns the S3 lifecycle rule described by the plan.
+func (p LifecycleRulePlan) lifecycleRule() *s3.LifecycleRule {
+	rule := &s3.LifecycleRule{
+		ID:     aws.String(p.ID),
+		Status: aws.String("Enabled"),
+		Filter: &s3.LifecycleRuleFilter{
+			Prefix: aws.String(p.Prefix),
+		},
+	}
+	if p.ExpirationDays > 0 {
+		rule.Expiration = &s3.LifecycleExpiration{
+			Days: aws.Int64(p.ExpirationDays),
+		}
+	} else if p.ExpiredObjectDeleteMarker {
+		rule.Expiration = &s3.LifecycleExpiration{
+			ExpiredObjectDeleteMarker: aws.Bool(true),
+		}
+	}
+	if p.NoncurrentExpirationDays > 0 {
+		rule.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{
+			NoncurrentDays: aws.Int64(p.NoncurrentExpirationDays),
+		}
+	}
+	for _, transition := range p.Transitions {
+		rule.Transitions = append(rule.Transitions, &s3.Transition{
+			Days:         aws.Int64(transition.Days),
+			StorageClass: aws.String(transition.StorageClass),
+		})
+	}
+	return rule
+}
+
+// NewBucketPlan returns the plan for a bucket holding velero backups, with the
+// given encryption, tags and backup expiry rule, alongside the public access
+// block the operator always enforces.
+func NewBucketPlan(bucketName, region, sseAlgorithm, kmsKeyID, tagKeyPrefix, backUpLocation, infraName string, extraTags map[string]string, backupExpiry LifecycleRulePlan) BucketPlan {
+	if sseAlgorithm == "" {
+		sseAlgorithm = s3.ServerSideEncryptionAes256
+	}
+	if sseAlgorithm != s3.ServerSideEncryptionAwsKms {
+		kmsKeyID = ""
+	}
+	return BucketPlan{
+		Name:   bucketName,
+		Region: region,
+		Encryption: EncryptionPlan{
+			Algorithm: sseAlgorithm,
+			KMSKeyID:  kmsKeyID,
+		},
+		BlockPublicAccess: true,
+		LifecycleRules:    []LifecycleRulePlan{backupExpiry},
+		Tags:              bucketTagSet(tagKeyPrefix, backUpLocation, infraName, extraTags),
+	}
+}
+
+// MarshalYAML renders the plan as YAML. Fields and tags are emitted in sorted
+// order, and empty optional fields are omitted.
+func (p BucketPlan) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(p)
+}
@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestBucketPlanMarshalYAML(t *testing.T) {
+	newPlan := func() BucketPlan {
+		return NewBucketPlan("testBucket", region, s3.ServerSideEncryptionAwsKms, "arn:aws:kms:us-east-1:123456789012:key/test",
+			"", defaultBackupStorageLocation, clusterInfraName, map[string]string{
+				"velero.io/sla-class": "gold",
+				"owner":               "sre",
+				"cost-center":         "1234",
+			}, BackupExpiryRule(DefaultBackupExpiryDays, true))
+	}
+
+	want, err := newPlan().MarshalYAML()
+	if err != nil {
+		t.Fatalf("MarshalYAML() error = %v", err)
+	}
+	// Map iteration order is random, so render the plan several times
+	for i := 0; i < 10; i++ {
+		got, err := newPlan().MarshalYAML()
+		if err != nil {
+			t.Fatalf("MarshalYAML() error = %v", err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("MarshalYAML() is not deterministic:\n%s\nwant:\n%s", got, want)
+		}
+	}
+
+	// Fields and tags are emitted in sorted order
+	out := string(want)
+	order := []string{
+		"blockPublicAccess:",
+		"encryption:",
+		"lifecycleRules:",
+		"name:",
+		"region:",
+		"tags:",
+		"cost-center:",
+		"owner:",
+		"velero.io/backup-location:",
+		"velero.io/infrastructureName:",
+		"velero.io/sla-class:",
+	}
+	last := -1
+	for _, key := range order {
+		idx := strings.Index(out, key)
+		if idx < 0 {
+			t.Fatalf("MarshalYAML() output is missing %q:\n%s", key, out)
+		}
+		if idx < last {
+			t.Errorf("MarshalYAML() output has %q out of order:\n%s", key, out)
+		}
+		last = idx
+	}
+}
+
+func TestBucketPlanMarshalYAMLOmitEmpty(t *testing.T) {
+	out, err := BucketPlan{}.MarshalYAML()
+	if err != nil {
+		t.Fatalf("MarshalYAML() error = %v", err)
+	}
+	for _, key := range []string{"name:", "region:", "kmsKeyId:", "lifecycleRules:", "tags:"} {
+		if strings.Contains(string(out), key) {
+			t.Errorf("MarshalYAML() output contains empty field %q:\n%s", key, out)
+		}
+	}
+}
+
+func TestNewBucketPlan(t *testing.T) {
+	plan := NewBucketPlan("testBucket", region, "", "arn:aws:kms:us-east-1:123456789012:key/test",
+		"", defaultBackupStorageLocation, clusterInfraName, nil, BackupExpiryRule(DefaultBackupExpiryDays, true))
+	if plan.Encryption.Algorithm != s3.ServerSideEncryptionAes256 {
+		t.Errorf("NewBucketPlan() algorithm = %v, want %v", plan.Encryption.Algorithm, s3.ServerSideEncryptionAes256)
+	}
+	if plan.Encryption.KMSKeyID != "" {
+		t.Errorf("NewBucketPlan() KMS key = %v, want none with AES256", plan.Encryption.KMSKeyID)
+	}
+	if plan.Tags[bucketTagInfraName] != clusterInfraName || plan.Tags[bucketTagBackupLocation] != defaultBackupStorageLocation {
+		t.Errorf("NewBucketPlan() tags = %v, want the identifying tags", plan.Tags)
+	}
+}
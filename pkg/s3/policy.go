@@ -0,0 +1,245 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	policyVersion            = "2012-10-17"
+	denySSECStatementID      = "DenySSECUploads"
+	denyWritesStatementID    = "DenyWritesReadOnly"
+	logDeliveryStatementID   = "AllowLogDelivery"
+	restrictRolesStatementID = "DenyOtherPrincipals"
+	logDeliveryPrincipal     = "logging.s3.amazonaws.com"
+	sseCustomerAlgorithm     = "s3:x-amz-server-side-encryption-customer-algorithm"
+)
+
+// policyDocument is a bucket policy. Statements are kept as raw JSON, so that
+// statements the operator doesn't manage are preserved as they are.
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// policyStatement is a bucket policy statement managed by the operator.
+type policyStatement struct {
+	Sid    string `json:"Sid"`
+	Effect string `json:"Effect"`
+	// Principal is "*", or maps the principal type to the principal, such as
+	// Service to the principal of an AWS service
+	Principal interface{}                        `json:"Principal"`
+	Action    policyValues                       `json:"Action"`
+	Resource  policyValues                       `json:"Resource"`
+	Condition map[string]map[string]policyValues `json:"Condition,omitempty"`
+}
+
+// policyValues are the values of an element of a policy statement, such as
+// its actions, which are written as a single string when there is only one.
+type policyValues []string
+
+// MarshalJSON implements json.Marshaler for policyValues.
+func (a policyValues) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// ssecDenyStatement returns the statement denying uploads to the bucket which
+// use customer-provided encryption keys (SSE-C). The resources of the
+// statements are ARNs in the AWS partition of the bucket.
+func ssecDenyStatement(partitionID string, bucketName string) policyStatement {
+	return policyStatement{
+		Sid:       denySSECStatementID,
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    policyValues{"s3:PutObject"},
+		Resource:  policyValues{bucketARN(partitionID, bucketName) + "/*"},
+		Condition: map[string]map[string]policyValues{
+			"Null": {sseCustomerAlgorithm: {"false"}},
+		},
+	}
+}
+
+// writeDenyStatement returns the statement denying writes and deletes of the
+// objects in the bucket. Reads are still allowed, so that backups can be
+// restored.
+func writeDenyStatement(partitionID string, bucketName string) policyStatement {
+	return policyStatement{
+		Sid:       denyWritesStatementID,
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    policyValues{"s3:PutObject", "s3:DeleteObject"},
+		Resource:  policyValues{bucketARN(partitionID, bucketName) + "/*"},
+	}
+}
+
+// roleRestrictionStatement returns the statement denying every action on the
+// bucket and its objects to the principals other than the allowed IAM roles.
+func roleRestrictionStatement(partitionID string, bucketName string, allowedRoleARNs []string) policyStatement {
+	roles := append(policyValues{}, allowedRoleARNs...)
+	sort.Strings(roles)
+	return policyStatement{
+		Sid:       restrictRolesStatementID,
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    policyValues{"s3:*"},
+		Resource:  policyValues{bucketARN(partitionID, bucketName), bucketARN(partitionID, bucketName) + "/*"},
+		Condition: map[string]map[string]policyValues{
+			"ArnNotLike": {"aws:PrincipalArn": roles},
+		},
+	}
+}
+
+// logDeliveryStatement returns the statement of the target bucket policy
+// allowing the S3 log delivery to write the access logs of the bucket under
+// the target prefix. Every bucket logging to the target bucket has a
+// statement of its own, identified by the alphanumeric characters of its name.
+func logDeliveryStatement(partitionID string, bucketName string, targetBucket string, targetPrefix string) policyStatement {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, bucketName)
+	return policyStatement{
+		Sid:       logDeliveryStatementID + id,
+		Effect:    "Allow",
+		Principal: map[string]string{"Service": logDeliveryPrincipal},
+		Action:    policyValues{"s3:PutObject"},
+		Resource:  policyValues{fmt.Sprintf("%s/%s*", bucketARN(partitionID, targetBucket), targetPrefix)},
+		Condition: map[string]map[string]policyValues{
+			"ArnLike": {"aws:SourceArn": {bucketARN(partitionID, bucketName)}},
+		},
+	}
+}
+
+// SSECDenyPolicy returns a bucket policy consisting of the statement which
+// denies SSE-C uploads to the bucket, in the AWS partition.
+func SSECDenyPolicy(partitionID string, bucketName string) (string, error) {
+	statement, err := json.Marshal(ssecDenyStatement(partitionID, bucketName))
+	if err != nil {
+		return "", err
+	}
+	policy, err := json.Marshal(policyDocument{Version: policyVersion, Statement: []json.RawMessage{statement}})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
+
+// SetBucketSSECPolicy adds the statement denying SSE-C uploads to the bucket
+// policy when deny is set, and removes it otherwise. Other statements in the
+// bucket policy are kept, and the policy is only written when it changes.
+func SetBucketSSECPolicy(ctx context.Context, s3Client Client, bucketName string, deny bool) error {
+	return setBucketPolicyStatement(ctx, s3Client, bucketName, ssecDenyStatement(clientPartitionID(s3Client), bucketName), deny)
+}
+
+// SetBucketReadOnlyPolicy adds the statement denying writes and deletes of the
+// objects in the bucket to the bucket policy when readOnly is set, and removes
+// it otherwise. Other statements in the bucket policy are kept.
+func SetBucketReadOnlyPolicy(ctx context.Context, s3Client Client, bucketName string, readOnly bool) error {
+	return setBucketPolicyStatement(ctx, s3Client, bucketName, writeDenyStatement(clientPartitionID(s3Client), bucketName), readOnly)
+}
+
+// EnsureBucketPolicy adds the statement denying access to the bucket to every
+// principal other than the allowed IAM roles to the bucket policy, and removes
+// it when no role is allowed. The allowed roles have to include the roles of
+// Velero and of the operator, which would otherwise be locked out of the
+// bucket. Other statements in the bucket policy, such as those granting the
+// S3 log delivery, are kept.
+func EnsureBucketPolicy(ctx context.Context, s3Client Client, bucketName string, allowedRoleARNs []string) error {
+	statement := roleRestrictionStatement(clientPartitionID(s3Client), bucketName, allowedRoleARNs)
+	return setBucketPolicyStatement(ctx, s3Client, bucketName, statement, len(allowedRoleARNs) > 0)
+}
+
+// IsNotImplemented checks whether the error is returned by an S3 compatible
+// backend for a request it doesn't support, which some Ceph RGW versions
+// answer with MethodNotAllowed.
+func IsNotImplemented(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && (aerr.Code() == "NotImplemented" || aerr.Code() == "MethodNotAllowed")
+}
+
+// setBucketPolicyStatement adds the statement to the bucket policy when
+// present is set, replacing a statement with the same Sid, and removes it
+// otherwise. The policy is only written when it changes.
+func setBucketPolicyStatement(ctx context.Context, s3Client Client, bucketName string, statement policyStatement, present bool) error {
+	document := policyDocument{Version: policyVersion}
+	output, err := s3Client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{Bucket: aws.String(bucketName)})
+	if err != nil && !isErrorCode(err, "NoSuchBucketPolicy") {
+		return fmt.Errorf("unable to read %v bucket policy: %w", bucketName, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(aws.StringValue(output.Policy)), &document); err != nil {
+			return fmt.Errorf("unable to parse %v bucket policy: %v", bucketName, err)
+		}
+	}
+
+	wanted, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	var statements []json.RawMessage
+	found := false
+	for _, existing := range document.Statement {
+		var id struct{ Sid string }
+		if err := json.Unmarshal(existing, &id); err != nil {
+			return fmt.Errorf("unable to parse %v bucket policy statement: %v", bucketName, err)
+		}
+		if id.Sid != statement.Sid {
+			statements = append(statements, existing)
+			continue
+		}
+		found = true
+		if present && jsonEqual(existing, wanted) {
+			// The policy already holds the statement
+			return nil
+		}
+	}
+	if !present && !found {
+		return nil
+	}
+	if present {
+		statements = append(statements, wanted)
+	}
+
+	if len(statements) == 0 {
+		if _, err := s3Client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{Bucket: aws.String(bucketName)}); err != nil {
+			return fmt.Errorf("unable to delete %v bucket policy: %w", bucketName, err)
+		}
+		return nil
+	}
+	document.Statement = statements
+	policy, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if _, err := s3Client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: aws.String(string(policy)),
+	}); err != nil {
+		return fmt.Errorf("unable to set %v bucket policy: %w", bucketName, err)
+	}
+	return nil
+}
+
+// jsonEqual checks whether two JSON documents hold the same values.
+func jsonEqual(a, b []byte) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,197 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestSSECDenyPolicy(t *testing.T) {
+	policy, err := SSECDenyPolicy("aws", "testBucket")
+	if err != nil {
+		t.Fatalf("SSECDenyPolicy() error = %v", err)
+	}
+	want := `{"Version":"2012-10-17","Statement":[{"Sid":"DenySSECUploads","Effect":"Deny","Principal":"*",` +
+		`"Action":"s3:PutObject","Resource":"arn:aws:s3:::testBucket/*",` +
+		`"Condition":{"Null":{"s3:x-amz-server-side-encryption-customer-algorithm":"false"}}}]}`
+	if policy != want {
+		t.Errorf("SSECDenyPolicy() = %v, want %v", policy, want)
+	}
+}
+
+func TestSetBucketSSECPolicy(t *testing.T) {
+	otherStatement := `{"Sid":"AllowBackupRole","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/backup"},` +
+		`"Action":"s3:GetObject","Resource":"arn:aws:s3:::testBucket/*"}`
+	client := &mockAWSClient{
+		Config:       awsConfig,
+		bucketPolicy: aws.String(`{"Version":"2012-10-17","Statement":[` + otherStatement + `]}`),
+	}
+
+	// Applying the policy twice only writes it once
+	for i := 0; i < 2; i++ {
+		if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", true); err != nil {
+			t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+		}
+	}
+	if len(client.putBucketPolicyInputs) != 1 {
+		t.Errorf("SetBucketSSECPolicy() issued %d PutBucketPolicy calls, want 1", len(client.putBucketPolicyInputs))
+	}
+	var document policyDocument
+	if err := json.Unmarshal([]byte(aws.StringValue(client.bucketPolicy)), &document); err != nil {
+		t.Fatalf("unable to parse bucket policy: %v", err)
+	}
+	if len(document.Statement) != 2 || !jsonEqual(document.Statement[0], []byte(otherStatement)) {
+		t.Errorf("bucket policy = %v, want the existing statement to be kept", aws.StringValue(client.bucketPolicy))
+	}
+
+	// Disabling the policy only removes the SSE-C statement
+	if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", false); err != nil {
+		t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+	}
+	if !jsonEqual([]byte(aws.StringValue(client.bucketPolicy)), []byte(`{"Version":"2012-10-17","Statement":[`+otherStatement+`]}`)) {
+		t.Errorf("bucket policy = %v, want only the existing statement", aws.StringValue(client.bucketPolicy))
+	}
+}
+
+func TestSetBucketSSECPolicyUnset(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", false); err != nil {
+		t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+	}
+	if len(client.putBucketPolicyInputs) != 0 || client.bucketPolicy != nil {
+		t.Errorf("SetBucketSSECPolicy() wrote a policy, but none was requested")
+	}
+
+	// Removing the only statement deletes the policy
+	if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", true); err != nil {
+		t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+	}
+	if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", false); err != nil {
+		t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+	}
+	if client.bucketPolicy != nil {
+		t.Errorf("bucket policy = %v, want it to be deleted", aws.StringValue(client.bucketPolicy))
+	}
+}
+
+func TestSetBucketReadOnlyPolicy(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	if err := SetBucketSSECPolicy(context.TODO(), client, "testBucket", true); err != nil {
+		t.Fatalf("SetBucketSSECPolicy() error = %v", err)
+	}
+	if err := SetBucketReadOnlyPolicy(context.TODO(), client, "testBucket", true); err != nil {
+		t.Fatalf("SetBucketReadOnlyPolicy() error = %v", err)
+	}
+	var document policyDocument
+	if err := json.Unmarshal([]byte(aws.StringValue(client.bucketPolicy)), &document); err != nil {
+		t.Fatalf("unable to parse bucket policy: %v", err)
+	}
+	want := `{"Sid":"DenyWritesReadOnly","Effect":"Deny","Principal":"*",` +
+		`"Action":["s3:PutObject","s3:DeleteObject"],"Resource":"arn:aws:s3:::testBucket/*"}`
+	if len(document.Statement) != 2 || !jsonEqual(document.Statement[1], []byte(want)) {
+		t.Errorf("bucket policy = %v, want the SSE-C and read-only statements", aws.StringValue(client.bucketPolicy))
+	}
+
+	// Making the bucket writable again keeps the SSE-C statement
+	if err := SetBucketReadOnlyPolicy(context.TODO(), client, "testBucket", false); err != nil {
+		t.Fatalf("SetBucketReadOnlyPolicy() error = %v", err)
+	}
+	ssecPolicy, err := SSECDenyPolicy("aws", "testBucket")
+	if err != nil {
+		t.Fatalf("SSECDenyPolicy() error = %v", err)
+	}
+	if !jsonEqual([]byte(aws.StringValue(client.bucketPolicy)), []byte(ssecPolicy)) {
+		t.Errorf("bucket policy = %v, want only the SSE-C statement", aws.StringValue(client.bucketPolicy))
+	}
+}
+
+func TestEnsureBucketPolicy(t *testing.T) {
+	roles := []string{"arn:aws:iam::123456789012:role/velero", "arn:aws:iam::123456789012:role/managed-velero-operator"}
+	logDelivery, err := json.Marshal(logDeliveryStatement("aws", "sourceBucket", "testBucket", "logs/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mockAWSClient{
+		Config:       awsConfig,
+		bucketPolicy: aws.String(`{"Version":"2012-10-17","Statement":[` + string(logDelivery) + `]}`),
+	}
+	restriction := func() json.RawMessage {
+		t.Helper()
+		var document policyDocument
+		if err := json.Unmarshal([]byte(aws.StringValue(client.bucketPolicy)), &document); err != nil {
+			t.Fatalf("unable to parse bucket policy: %v", err)
+		}
+		if len(document.Statement) == 0 || !jsonEqual(document.Statement[0], logDelivery) {
+			t.Errorf("bucket policy = %v, want the log delivery statement to be kept", aws.StringValue(client.bucketPolicy))
+		}
+		if len(document.Statement) < 2 {
+			return nil
+		}
+		return document.Statement[1]
+	}
+
+	// The statement is applied once, with the roles in sorted order
+	for i := 0; i < 2; i++ {
+		if err := EnsureBucketPolicy(context.TODO(), client, "testBucket", roles); err != nil {
+			t.Fatalf("EnsureBucketPolicy() error = %v", err)
+		}
+	}
+	if len(client.putBucketPolicyInputs) != 1 {
+		t.Errorf("EnsureBucketPolicy() issued %d PutBucketPolicy calls, want 1", len(client.putBucketPolicyInputs))
+	}
+	want := `{"Sid":"DenyOtherPrincipals","Effect":"Deny","Principal":"*","Action":"s3:*",` +
+		`"Resource":["arn:aws:s3:::testBucket","arn:aws:s3:::testBucket/*"],` +
+		`"Condition":{"ArnNotLike":{"aws:PrincipalArn":["arn:aws:iam::123456789012:role/managed-velero-operator","arn:aws:iam::123456789012:role/velero"]}}}`
+	if got := restriction(); !jsonEqual(got, []byte(want)) {
+		t.Errorf("role restriction statement = %s, want %s", got, want)
+	}
+
+	// A statement changed outside of the operator is corrected
+	drifted := strings.Replace(aws.StringValue(client.bucketPolicy), "role/velero", "role/other", 1)
+	client.bucketPolicy = aws.String(drifted)
+	if err := EnsureBucketPolicy(context.TODO(), client, "testBucket", roles); err != nil {
+		t.Fatalf("EnsureBucketPolicy() error = %v", err)
+	}
+	if len(client.putBucketPolicyInputs) != 2 {
+		t.Errorf("EnsureBucketPolicy() issued %d PutBucketPolicy calls after the drift, want 2", len(client.putBucketPolicyInputs))
+	}
+	if got := restriction(); !jsonEqual(got, []byte(want)) {
+		t.Errorf("role restriction statement = %s after the drift, want %s", got, want)
+	}
+
+	// Allowing no role removes the statement
+	if err := EnsureBucketPolicy(context.TODO(), client, "testBucket", nil); err != nil {
+		t.Fatalf("EnsureBucketPolicy() error = %v", err)
+	}
+	if got := restriction(); got != nil {
+		t.Errorf("role restriction statement = %s, want it removed", got)
+	}
+}
+
+func TestBucketPolicyPartition(t *testing.T) {
+	tests := []struct {
+		region        string
+		wantPartition string
+	}{
+		{region: "us-gov-west-1", wantPartition: "aws-us-gov"},
+		{region: "cn-northwest-1", wantPartition: "aws-cn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			client := &mockAWSClient{Config: &aws.Config{Region: aws.String(tt.region)}}
+			if err := SetBucketReadOnlyPolicy(context.TODO(), client, "testBucket", true); err != nil {
+				t.Fatalf("SetBucketReadOnlyPolicy() error = %v", err)
+			}
+			if err := EnsureBucketPolicy(context.TODO(), client, "testBucket", []string{"arn:" + tt.wantPartition + ":iam::123456789012:role/velero"}); err != nil {
+				t.Fatalf("EnsureBucketPolicy() error = %v", err)
+			}
+			policy := aws.StringValue(client.bucketPolicy)
+			if !strings.Contains(policy, `"arn:`+tt.wantPartition+`:s3:::testBucket/*"`) || strings.Contains(policy, "arn:aws:s3:::") {
+				t.Errorf("bucket policy = %v, want the bucket ARNs in the %v partition", policy, tt.wantPartition)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// PermissionError reports the S3 permissions the credentials were denied.
+type PermissionError struct {
+	// Missing lists the IAM actions which were denied.
+	Missing []string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("credentials are missing S3 permissions: %v", strings.Join(e.Missing, ", "))
+}
+
+// IsAccessDenied checks whether the error reports that the credentials aren't
+// allowed to make the request. HEAD responses have no body, so their error
+// code is the 403 status text.
+func IsAccessDenied(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && (aerr.Code() == "AccessDenied" || aerr.Code() == "Forbidden")
+}
+
+// PreflightPermissionCheck makes the read-only requests the reconcile starts
+// with, and returns a PermissionError listing every permission which was
+// denied, rather than failing on the first one. The bucket is only checked
+// once its name is known. Permissions to change the bucket, such as
+// s3:CreateBucket, can't be checked without changing it, and fail on use.
+func PreflightPermissionCheck(ctx context.Context, s3Client Client, bucketName string) error {
+	var missing []string
+	check := func(action string, err error) error {
+		if IsAccessDenied(err) {
+			missing = append(missing, action)
+			return nil
+		}
+		return err
+	}
+
+	if err := check("s3:ListAllMyBuckets", withRetry(ctx, "ListBuckets", func(ctx context.Context) error {
+		_, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+		return err
+	})); err != nil {
+		return fmt.Errorf("unable to list buckets: %w", err)
+	}
+
+	if bucketName != "" {
+		// HeadBucket is allowed by s3:ListBucket
+		err := withRetry(ctx, "HeadBucket", func(ctx context.Context) error {
+			_, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+			return err
+		})
+		if err = check("s3:ListBucket", err); err != nil && !isNotFound(err) {
+			return fmt.Errorf("unable to read %v bucket: %w", bucketName, err)
+		}
+
+		err = withRetry(ctx, "GetBucketTagging", func(ctx context.Context) error {
+			_, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucketName)})
+			return err
+		})
+		if err = check("s3:GetBucketTagging", err); err != nil && !isNotFound(err) && !isErrorCode(err, "NoSuchTagSet") {
+			return fmt.Errorf("unable to read %v bucket tags: %w", bucketName, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &PermissionError{Missing: missing}
+	}
+	return nil
+}
+
+// isNotFound checks whether the error reports that the bucket doesn't exist,
+// which leaves the permissions to be checked once it is created.
+func isNotFound(err error) bool {
+	return IsNoSuchBucket(err) || isErrorCode(err, "NotFound")
+}
@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// permissionMockClient is a mockAWSClient whose calls fail with AccessDenied
+// for the denied operations.
+type permissionMockClient struct {
+	mockAWSClient
+
+	// denied holds the operations the credentials aren't allowed to call.
+	denied map[string]bool
+	// err is returned by ListBuckets, unless the operation is denied.
+	err error
+}
+
+// accessDenied returns the error of a denied operation.
+func (c *permissionMockClient) accessDenied(operation string) error {
+	if !c.denied[operation] {
+		return nil
+	}
+	if operation == "HeadBucket" {
+		// HEAD responses carry no error code, only the status
+		return awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "")
+	}
+	return awserr.New("AccessDenied", "Access Denied", nil)
+}
+
+// HeadBucket implements the HeadBucket method for permissionMockClient.
+func (c *permissionMockClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if err := c.accessDenied("HeadBucket"); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.HeadBucket(ctx, input)
+}
+
+// GetBucketTagging implements the GetBucketTagging method for permissionMockClient.
+func (c *permissionMockClient) GetBucketTagging(ctx context.Context, input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	if err := c.accessDenied("GetBucketTagging"); err != nil {
+		return nil, err
+	}
+	return c.mockAWSClient.GetBucketTagging(ctx, input)
+}
+
+// ListBuckets implements the ListBuckets method for permissionMockClient.
+func (c *permissionMockClient) ListBuckets(ctx context.Context, input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	if err := c.accessDenied("ListBuckets"); err != nil {
+		return nil, err
+	}
+	return &s3.ListBucketsOutput{}, c.err
+}
+
+func TestPreflightPermissionCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		bucketName  string
+		denied      map[string]bool
+		err         error
+		wantMissing []string
+		wantErr     bool
+	}{
+		{
+			name:       "all permissions granted",
+			bucketName: "testBucket",
+		},
+		{
+			name:       "bucket not created yet",
+			bucketName: "otherBucket",
+		},
+		{
+			name:        "listing denied",
+			bucketName:  "testBucket",
+			denied:      map[string]bool{"ListBuckets": true},
+			wantMissing: []string{"s3:ListAllMyBuckets"},
+		},
+		{
+			name:        "every permission denied",
+			bucketName:  "testBucket",
+			denied:      map[string]bool{"ListBuckets": true, "HeadBucket": true, "GetBucketTagging": true},
+			wantMissing: []string{"s3:ListAllMyBuckets", "s3:ListBucket", "s3:GetBucketTagging"},
+		},
+		{
+			name:        "bucket permissions unchecked without a bucket",
+			denied:      map[string]bool{"HeadBucket": true, "GetBucketTagging": true},
+			wantMissing: nil,
+		},
+		{
+			name:       "other errors are returned",
+			bucketName: "testBucket",
+			err:        awserr.New("InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.", nil),
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &permissionMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, denied: tt.denied, err: tt.err}
+			err := PreflightPermissionCheck(context.TODO(), client, tt.bucketName)
+
+			var permErr *PermissionError
+			if errors.As(err, &permErr) {
+				if !reflect.DeepEqual(permErr.Missing, tt.wantMissing) {
+					t.Errorf("PreflightPermissionCheck() missing = %v, want %v", permErr.Missing, tt.wantMissing)
+				}
+				return
+			}
+			if tt.wantMissing != nil {
+				t.Fatalf("PreflightPermissionCheck() error = %v, want missing %v", err, tt.wantMissing)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PreflightPermissionCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPermissionErrorMessage(t *testing.T) {
+	err := &PermissionError{Missing: []string{"s3:ListAllMyBuckets", "s3:GetBucketTagging"}}
+	want := "credentials are missing S3 permissions: s3:ListAllMyBuckets, s3:GetBucketTagging"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
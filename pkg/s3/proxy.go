@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxyConfig holds the proxy settings honored by the requests to AWS.
+type proxyConfig struct {
+	httpProxy  string
+	httpsProxy string
+	noProxy    string
+}
+
+// proxyConfigFromEnvironment reads the proxy settings from the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables, or their lowercase forms.
+// On OpenShift these are set on the operator from the cluster-wide Proxy.
+func proxyConfigFromEnvironment() proxyConfig {
+	return proxyConfig{
+		httpProxy:  getEnvAny("HTTP_PROXY", "http_proxy"),
+		httpsProxy: getEnvAny("HTTPS_PROXY", "https_proxy"),
+		noProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+	}
+}
+
+// getEnvAny returns the value of the first of the environment variables which is set.
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// newHTTPClient returns the HTTP client used for the requests to AWS, which
+// sends them through the configured proxy.
+func newHTTPClient(proxy proxyConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy.proxyFunc()
+	return &http.Client{Transport: transport}
+}
+
+// proxyFunc returns the function selecting the proxy for a request, as used by
+// http.Transport. Requests to the hosts excluded by noProxy aren't proxied.
+func (c proxyConfig) proxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		var proxy string
+		switch req.URL.Scheme {
+		case "https":
+			proxy = c.httpsProxy
+		case "http":
+			proxy = c.httpProxy
+		}
+		if proxy == "" || c.bypassProxy(req.URL) {
+			return nil, nil
+		}
+		return parseProxyURL(proxy)
+	}
+}
+
+// parseProxyURL parses the address of a proxy, which is commonly given
+// without a scheme.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		if proxyURL, err := url.Parse("http://" + proxy); err == nil {
+			return proxyURL, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
+	}
+	return proxyURL, nil
+}
+
+// bypassProxy checks whether requests to the URL are sent directly. These are
+// requests to the loopback interface, and to the hosts matching noProxy, which
+// lists hosts, domains (matching their subdomains), IP addresses and CIDRs,
+// optionally with a port, or "*" to match all hosts.
+func (c proxyConfig) bypassProxy(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range strings.Split(c.noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+)
+
+// setEnv sets the environment variables, unsetting those with an empty value,
+// and returns a function restoring their previous values.
+func setEnv(env map[string]string) func() {
+	previous := make(map[string]*string)
+	for name, value := range env {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+	}
+	return func() {
+		for name, value := range previous {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
+	}
+}
+
+func TestHTTPClientProxyFromEnvironment(t *testing.T) {
+	defer setEnv(map[string]string{
+		"HTTP_PROXY":  "http://proxy.example.com:3128",
+		"HTTPS_PROXY": "proxy.example.com:3129",
+		"NO_PROXY":    ".cluster.local,10.0.0.0/16,s3.internal.example.com",
+		"http_proxy":  "",
+		"https_proxy": "",
+		"no_proxy":    "",
+	})()
+
+	transport, ok := newHTTPClient(proxyConfigFromEnvironment()).Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatalf("HTTP client transport = %#v, want a proxy function", transport)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://s3.us-east-1.amazonaws.com/bucket", want: "http://proxy.example.com:3129"},
+		{url: "http://s3.us-east-1.amazonaws.com/bucket", want: "http://proxy.example.com:3128"},
+		{url: "https://s3.internal.example.com/bucket"},
+		{url: "https://bucket.s3.internal.example.com"},
+		{url: "https://minio.velero.svc.cluster.local:9000"},
+		{url: "https://10.0.12.7/bucket"},
+		{url: "https://10.1.12.7/bucket", want: "http://proxy.example.com:3129"},
+		{url: "http://localhost:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			reqURL, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("invalid URL %v: %v", tt.url, err)
+			}
+			proxyURL, err := transport.Proxy(&http.Request{URL: reqURL})
+			if err != nil {
+				t.Fatalf("Proxy() error = %v", err)
+			}
+			var got string
+			if proxyURL != nil {
+				got = proxyURL.String()
+			}
+			if got != tt.want {
+				t.Errorf("Proxy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		noProxy string
+		url     string
+		want    bool
+	}{
+		{noProxy: "", url: "https://s3.amazonaws.com", want: false},
+		{noProxy: "*", url: "https://s3.amazonaws.com", want: true},
+		{noProxy: "amazonaws.com", url: "https://s3.amazonaws.com", want: true},
+		{noProxy: "amazonaws.com", url: "https://notamazonaws.com", want: false},
+		{noProxy: "*.example.com", url: "https://s3.example.com", want: true},
+		{noProxy: "example.com:9000", url: "https://example.com:9000", want: true},
+		{noProxy: "example.com:9000", url: "https://example.com", want: false},
+		{noProxy: " 192.168.1.10 ", url: "https://192.168.1.10", want: true},
+		{noProxy: "192.168.0.0/16", url: "https://192.168.1.10", want: true},
+		{noProxy: "192.168.0.0/16", url: "https://example.com", want: false},
+		{noProxy: "EXAMPLE.com", url: "https://S3.Example.COM", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.noProxy+" "+tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("invalid URL %v: %v", tt.url, err)
+			}
+			if got := (proxyConfig{noProxy: tt.noProxy}).bypassProxy(u); got != tt.want {
+				t.Errorf("bypassProxy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// readinessCacheTTL is how long the result of a readiness check is
+	// reported before S3 is checked again.
+	readinessCacheTTL = 30 * time.Second
+	// readinessTimeout bounds how long a readiness check waits on S3.
+	readinessTimeout = 5 * time.Second
+)
+
+// ReadinessProbe is an http.Handler reporting whether S3 can be reached with
+// the operator's credentials. It answers 503 Service Unavailable when the
+// credentials of any target are rejected or its endpoint is unreachable, and
+// 200 OK otherwise. The result of each target is cached for a short while, so
+// that frequent probes don't add up to S3 requests.
+type ReadinessProbe struct {
+	mu sync.Mutex
+	// targets holds the targets checked by the probe, by key
+	targets map[string]*readinessTarget
+
+	// now returns the current time, and defaults to time.Now
+	now func() time.Time
+}
+
+// readinessTarget is a bucket checked by the ReadinessProbe, with the result
+// of its last check.
+type readinessTarget struct {
+	client Client
+	bucket string
+
+	checked time.Time
+	err     error
+}
+
+// NewReadinessProbe returns a ReadinessProbe without targets, which reports
+// ready until SetTarget gives it a client to check.
+func NewReadinessProbe() *ReadinessProbe {
+	return &ReadinessProbe{targets: make(map[string]*readinessTarget), now: time.Now}
+}
+
+// SetTarget has the probe check the bucket with the client under the key, or
+// list the buckets when the bucket name is empty, replacing the target
+// previously set under the key. The cached result of the key is dropped.
+func (p *ReadinessProbe) SetTarget(key string, client Client, bucket string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets[key] = &readinessTarget{client: client, bucket: bucket}
+}
+
+// RemoveTarget stops the probe from checking the target set under the key.
+func (p *ReadinessProbe) RemoveTarget(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.targets, key)
+}
+
+// Check returns the error of the last readiness check of the first failing
+// target, in the order of their keys, checking S3 again for the targets whose
+// cached result expired.
+func (p *ReadinessProbe) Check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]string, 0, len(p.targets))
+	for key := range p.targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var err error
+	now := p.now()
+	for _, key := range keys {
+		target := p.targets[key]
+		if target.checked.IsZero() || now.Sub(target.checked) >= readinessCacheTTL {
+			target.err = target.check(ctx)
+			target.checked = now
+		}
+		if target.err != nil && err == nil {
+			err = fmt.Errorf("%v: %w", key, target.err)
+		}
+	}
+	return err
+}
+
+// check makes a single request to S3, without retrying, as the probe is
+// repeated anyway.
+func (t *readinessTarget) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	if t.bucket == "" {
+		_, err := t.client.ListBuckets(ctx, &s3.ListBucketsInput{})
+		return wrapRequestError("ListBuckets", err)
+	}
+	_, err := t.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(t.bucket)})
+	if isErrorCode(err, "NotFound") {
+		// S3 was reached, the bucket just doesn't exist (yet)
+		return nil
+	}
+	return wrapRequestError("HeadBucket", err)
+}
+
+// ServeHTTP implements http.Handler for ReadinessProbe.
+func (p *ReadinessProbe) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := p.Check(req.Context()); err != nil {
+		http.Error(w, fmt.Sprintf("unable to reach S3: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
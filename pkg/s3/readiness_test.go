@@ -0,0 +1,116 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestReadinessProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucket     string
+		errs       []error
+		wantStatus int
+	}{
+		{
+			name:       "bucket reachable",
+			bucket:     "testBucket",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "credentials denied",
+			bucket:     "testBucket",
+			errs:       []error{awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "4442587FB7D0A2F9")},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "endpoint unreachable",
+			bucket:     "testBucket",
+			errs:       []error{awserr.New("RequestError", "send request failed", nil)},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "bucket not created yet",
+			bucket:     "testBucket",
+			errs:       []error{awserr.New("NotFound", "Not Found", nil)},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: tt.errs}
+			probe := NewReadinessProbe()
+			probe.SetTarget("test", client, tt.bucket)
+
+			rec := httptest.NewRecorder()
+			probe.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("readiness status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK && len(tt.errs) > 0 && !strings.Contains(rec.Body.String(), tt.errs[0].(awserr.Error).Code()) {
+				t.Errorf("readiness body = %q, want the error", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadinessProbeCachesResult(t *testing.T) {
+	now := time.Now()
+	denied := awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "")
+	client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{denied}}
+	probe := NewReadinessProbe()
+	probe.now = func() time.Time { return now }
+	probe.SetTarget("test", client, "testBucket")
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		probe.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	// The failure is reported until the cached result expires
+	now = now.Add(readinessCacheTTL / 2)
+	if code := serve(); code != http.StatusServiceUnavailable || client.headBucketCalls != 1 {
+		t.Errorf("readiness status = %d after %d HeadBucket calls, want the cached %d after 1 call",
+			code, client.headBucketCalls, http.StatusServiceUnavailable)
+	}
+	now = now.Add(readinessCacheTTL)
+	if code := serve(); code != http.StatusOK || client.headBucketCalls != 2 {
+		t.Errorf("readiness status = %d after %d HeadBucket calls, want %d after 2 calls",
+			code, client.headBucketCalls, http.StatusOK)
+	}
+}
+
+func TestReadinessProbeWithoutTarget(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewReadinessProbe().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("readiness status = %d, want %d before the first reconcile", rec.Code, http.StatusOK)
+	}
+}
+
+func TestReadinessProbeTargets(t *testing.T) {
+	denied := awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "")
+	reachable := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}}
+	unreachable := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: []error{denied}}
+	probe := NewReadinessProbe()
+	probe.SetTarget("openshift-velero/denied", unreachable, "testBucket")
+	probe.SetTarget("openshift-velero/cluster", reachable, "testBucket")
+
+	// A target reaching S3 doesn't hide another one failing
+	if err := probe.Check(context.TODO()); err == nil || !strings.Contains(err.Error(), "openshift-velero/denied") {
+		t.Errorf("Check() = %v, want the error of openshift-velero/denied", err)
+	}
+	probe.RemoveTarget("openshift-velero/denied")
+	if err := probe.Check(context.TODO()); err != nil {
+		t.Errorf("Check() = %v once the failing target is removed, want nil", err)
+	}
+}
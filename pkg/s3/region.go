@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// ValidateRegion checks that the region is known to the SDK for the partition
+// it belongs to, so that a mistyped region fails before any request is made.
+func ValidateRegion(region string) error {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return fmt.Errorf("region %q does not belong to any known AWS partition", region)
+	}
+	if _, ok := partition.Regions()[region]; !ok {
+		return fmt.Errorf("region %q is not a known region of the %v partition", region, partition.ID())
+	}
+	return nil
+}
+
+// PartitionID returns the ID of the AWS partition the region belongs to, such
+// as aws-us-gov for the GovCloud regions or aws-cn for the China regions. An
+// unknown region is assumed to be in the standard aws partition.
+func PartitionID(region string) string {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return endpoints.AwsPartitionID
+	}
+	return partition.ID()
+}
+
+// clientPartitionID returns the ID of the AWS partition of the region the
+// client addresses.
+func clientPartitionID(s3Client Client) string {
+	var region string
+	if config := s3Client.GetAWSClientConfig(); config != nil {
+		region = aws.StringValue(config.Region)
+	}
+	return PartitionID(region)
+}
+
+// resolveEndpoint resolves the endpoint of the service in the region within
+// the AWS partition the region belongs to, so that the GovCloud and China
+// regions address the endpoints of their own partition.
+func resolveEndpoint(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+	}
+	return partition.EndpointFor(service, region, opts...)
+}
+
+// bucketARN returns the ARN of the bucket in the AWS partition.
+func bucketARN(partitionID string, bucketName string) string {
+	return fmt.Sprintf("arn:%s:s3:::%s", partitionID, bucketName)
+}
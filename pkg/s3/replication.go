@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// replicationRuleID identifies the replication rule of the bucket managed by
+// the operator.
+const replicationRuleID = "managed-velero-replication"
+
+// ErrVersioningRequired is returned by EnsureReplication when versioning isn't
+// enabled on the bucket, which S3 requires to replicate it.
+var ErrVersioningRequired = errors.New("replication requires versioning to be enabled on the bucket")
+
+// ReplicationPlan is the intended replication of the bucket.
+type ReplicationPlan struct {
+	// DestinationBucketARN is the ARN of the bucket the objects are replicated to.
+	DestinationBucketARN string
+	// RoleARN is the ARN of the IAM role S3 assumes to replicate the objects.
+	RoleARN string
+}
+
+// EnsureReplication replicates every object of the bucket to the destination
+// bucket of the plan, unless the replication configuration of the bucket
+// already matches the plan. Any other replication rules of the bucket are
+// replaced. ErrVersioningRequired is returned when versioning isn't enabled on
+// the bucket.
+func EnsureReplication(ctx context.Context, s3Client Client, bucketName string, plan ReplicationPlan) error {
+	versioned, err := IsBucketVersioned(ctx, s3Client, bucketName)
+	if err != nil {
+		return err
+	}
+	if !versioned {
+		return fmt.Errorf("unable to replicate bucket %v to %v: %w", bucketName, plan.DestinationBucketARN, ErrVersioningRequired)
+	}
+
+	var output *s3.GetBucketReplicationOutput
+	err = withRetry(ctx, "GetBucketReplication", func(ctx context.Context) (err error) {
+		output, err = s3Client.GetBucketReplication(ctx, &s3.GetBucketReplicationInput{
+			Bucket: aws.String(bucketName),
+		})
+		return err
+	})
+	if err != nil && !isErrorCode(err, "ReplicationConfigurationNotFoundError") {
+		return fmt.Errorf("unable to read %v bucket replication configuration: %w", bucketName, err)
+	}
+	if err == nil && replicationMatches(output.ReplicationConfiguration, plan) {
+		return nil
+	}
+
+	bucketReplicationInput := &s3.PutBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+		ReplicationConfiguration: &s3.ReplicationConfiguration{
+			Role: aws.String(plan.RoleARN),
+			Rules: []*s3.ReplicationRule{{
+				ID:       aws.String(replicationRuleID),
+				Status:   aws.String(s3.ReplicationRuleStatusEnabled),
+				Priority: aws.Int64(1),
+				// An empty filter selects every object of the bucket
+				Filter: &s3.ReplicationRuleFilter{Prefix: aws.String("")},
+				DeleteMarkerReplication: &s3.DeleteMarkerReplication{
+					Status: aws.String(s3.DeleteMarkerReplicationStatusDisabled),
+				},
+				Destination: &s3.Destination{
+					Bucket: aws.String(plan.DestinationBucketARN),
+				},
+			}},
+		},
+	}
+	if err := bucketReplicationInput.Validate(); err != nil {
+		return fmt.Errorf("unable to validate %v bucket replication configuration: %v", bucketName, err)
+	}
+	return withRetry(ctx, "PutBucketReplication", func(ctx context.Context) error {
+		_, err := s3Client.PutBucketReplication(ctx, bucketReplicationInput)
+		return err
+	})
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestEnsureReplication(t *testing.T) {
+	plan := ReplicationPlan{
+		DestinationBucketARN: "arn:aws:s3:::managed-velero-backups-dr",
+		RoleARN:              "arn:aws:iam::123456789012:role/velero-replication",
+	}
+	replicating := func(roleARN string) *s3.ReplicationConfiguration {
+		return &s3.ReplicationConfiguration{
+			Role: aws.String(roleARN),
+			Rules: []*s3.ReplicationRule{{
+				ID:          aws.String(replicationRuleID),
+				Status:      aws.String(s3.ReplicationRuleStatusEnabled),
+				Destination: &s3.Destination{Bucket: aws.String(plan.DestinationBucketARN)},
+			}},
+		}
+	}
+	tests := []struct {
+		name          string
+		versioning    *string
+		configuration *s3.ReplicationConfiguration
+		wantPuts      int
+		wantErr       error
+	}{
+		{
+			name:       "replication absent",
+			versioning: aws.String(s3.BucketVersioningStatusEnabled),
+			wantPuts:   1,
+		},
+		{
+			name:          "already replicating",
+			versioning:    aws.String(s3.BucketVersioningStatusEnabled),
+			configuration: replicating(plan.RoleARN),
+			wantPuts:      0,
+		},
+		{
+			name:          "replicating with another role",
+			versioning:    aws.String(s3.BucketVersioningStatusEnabled),
+			configuration: replicating("arn:aws:iam::123456789012:role/other"),
+			wantPuts:      1,
+		},
+		{
+			name:     "versioning not enabled",
+			wantPuts: 0,
+			wantErr:  ErrVersioningRequired,
+		},
+		{
+			name:       "versioning suspended",
+			versioning: aws.String(s3.BucketVersioningStatusSuspended),
+			wantPuts:   0,
+			wantErr:    ErrVersioningRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockAWSClient{Config: awsConfig, versioningStatus: tt.versioning, replicationConfiguration: tt.configuration}
+			err := EnsureReplication(context.TODO(), client, "testBucket", plan)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EnsureReplication() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(client.putBucketReplicationInputs) != tt.wantPuts {
+				t.Errorf("EnsureReplication() issued %d PutBucketReplication calls, want %d", len(client.putBucketReplicationInputs), tt.wantPuts)
+			}
+			if tt.wantErr == nil && !replicationMatches(client.replicationConfiguration, plan) {
+				t.Errorf("replication configuration = %v, want replication to %v", client.replicationConfiguration, plan.DestinationBucketARN)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// RetryPolicy configures how the calls to the S3 API are retried when they
+// fail with a transient error, such as throttling.
+type RetryPolicy struct {
+	// MaxAttempts bounds how often a call is made, including the first attempt.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, which doubles with every
+	// further retry. The actual delay is randomly jittered below it.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts, unless 0.
+	MaxDelay time.Duration
+
+	// OperationTimeout bounds how long each attempt may take, unless 0, so
+	// that a hung request is retried rather than holding up the reconcile
+	// until its own deadline.
+	OperationTimeout time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used unless the context was given
+// another one by WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      5,
+	BaseDelay:        200 * time.Millisecond,
+	MaxDelay:         10 * time.Second,
+	OperationTimeout: 30 * time.Second,
+}
+
+// retryPolicyKey is the context key of the retry policy.
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a copy of the context with which the calls to the
+// S3 API are retried as configured by the policy, rather than by
+// DefaultRetryPolicy.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicyFrom returns the retry policy of the context.
+func retryPolicyFrom(ctx context.Context) RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// RequestObserver is notified of the calls to the S3 API, such as to record
+// them in metrics.
+type RequestObserver interface {
+	// ObserveS3Request is called after every attempt of a call to the
+	// operation, with its duration and outcome.
+	ObserveS3Request(operation string, duration time.Duration, err error)
+	// ObserveBucketCreate is called once CreateBucket is done creating a
+	// bucket, with its outcome after the retries.
+	ObserveBucketCreate(err error)
+}
+
+// requestObserverKey is the context key of the request observer.
+type requestObserverKey struct{}
+
+// WithRequestObserver returns a copy of the context with which the calls to
+// the S3 API are reported to the observer. No calls are reported otherwise.
+func WithRequestObserver(ctx context.Context, observer RequestObserver) context.Context {
+	return context.WithValue(ctx, requestObserverKey{}, observer)
+}
+
+// requestObserverFrom returns the request observer of the context, or nil.
+func requestObserverFrom(ctx context.Context) RequestObserver {
+	observer, _ := ctx.Value(requestObserverKey{}).(RequestObserver)
+	return observer
+}
+
+// retryableErrorCodes are the error codes of the transient failures worth
+// retrying. Any other error, such as AccessDenied, fails right away.
+var retryableErrorCodes = map[string]bool{
+	"InternalError":           true,
+	"RequestError":            true,
+	"RequestLimitExceeded":    true,
+	"RequestThrottled":        true,
+	"RequestTimeout":          true,
+	"RequestTimeoutException": true,
+	"ServiceUnavailable":      true,
+	"SlowDown":                true,
+	"Throttling":              true,
+	"ThrottlingException":     true,
+}
+
+// isRetryableError checks whether the error is a transient failure of the S3
+// API, even when wrapped, such as in a RequestError.
+func isRetryableError(err error) bool {
+	var timeoutErr *OperationTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	if retryableErrorCodes[aerr.Code()] {
+		return true
+	}
+	// Server errors are transient, even when the response carries no code
+	var reqErr awserr.RequestFailure
+	return errors.As(err, &reqErr) && reqErr.StatusCode() >= http.StatusInternalServerError
+}
+
+// delay returns the jittered delay before the retry following the attempt,
+// counting from 0.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	backoff := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay == 0 || backoff < p.MaxDelay); i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff))) // #nosec G404
+}
+
+// withRetry runs call until it succeeds, fails with an error which isn't
+// retryable, the attempts of the retry policy are used up, or the context is
+// done. Each attempt is given a context which is done once the operation
+// timeout of the policy passes, and an attempt cut short by it fails with an
+// OperationTimeoutError. Every attempt is reported to the request observer of
+// the context. A failed request is returned as a RequestError.
+func withRetry(ctx context.Context, operation string, call func(ctx context.Context) error) error {
+	policy := retryPolicyFrom(ctx)
+	observer := requestObserverFrom(ctx)
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
+		err := callWithTimeout(ctx, operation, policy.OperationTimeout, call)
+		if observer != nil {
+			observer.ObserveS3Request(operation, time.Since(start), err)
+		}
+		if err == nil || !isRetryableError(err) || attempt+1 >= policy.MaxAttempts {
+			return wrapRequestError(operation, err)
+		}
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// callWithTimeout runs a single attempt of call, with a context which is done
+// once the timeout passes, unless it is 0. The error of an attempt cut short
+// by the timeout, rather than by the context of the call, is returned as an
+// OperationTimeoutError.
+func callWithTimeout(ctx context.Context, operation string, timeout time.Duration, call func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return call(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := call(attemptCtx)
+	if err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return &OperationTimeoutError{Operation: operation, Timeout: timeout}
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// throttlingMockClient is a mockAWSClient whose HeadBucket calls fail with the
+// queued errors, before succeeding.
+type throttlingMockClient struct {
+	mockAWSClient
+
+	// errs are returned by the next HeadBucket calls, in order.
+	errs []error
+	// headBucketCalls counts the HeadBucket calls.
+	headBucketCalls int
+}
+
+// HeadBucket implements the HeadBucket method for throttlingMockClient.
+func (c *throttlingMockClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	c.headBucketCalls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func TestRetry(t *testing.T) {
+	ctx := WithRetryPolicy(context.TODO(), RetryPolicy{MaxAttempts: 3})
+
+	slowDown := awserr.New("SlowDown", "Please reduce your request rate.", nil)
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "retry after throttling",
+			errs:      []error{slowDown},
+			wantCalls: 2,
+		},
+		{
+			name:      "retry server errors",
+			errs:      []error{awserr.NewRequestFailure(awserr.New("", "", nil), 503, "")},
+			wantCalls: 2,
+		},
+		{
+			name:      "fail fast on access denied",
+			errs:      []error{awserr.New("AccessDenied", "Access Denied", nil)},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "give up after max attempts",
+			errs:      []error{slowDown, slowDown, slowDown, slowDown},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &throttlingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, errs: tt.errs}
+			_, err := DoesBucketExist(ctx, client, "testBucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DoesBucketExist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if client.headBucketCalls != tt.wantCalls {
+				t.Errorf("DoesBucketExist() made %d HeadBucket calls, want %d", client.headBucketCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, bound := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if delay := policy.delay(attempt); delay < 0 || delay >= bound {
+			t.Errorf("delay(%d) = %v, want it jittered below %v", attempt, delay, bound)
+		}
+	}
+	if delay := (RetryPolicy{MaxAttempts: 3}).delay(2); delay != 0 {
+		t.Errorf("delay() = %v without a base delay, want 0", delay)
+	}
+}
+
+// cancellingMockClient is a mockAWSClient whose HeadBucket calls are throttled,
+// and cancel the context of the call.
+type cancellingMockClient struct {
+	mockAWSClient
+
+	cancel context.CancelFunc
+	// headBucketCalls counts the HeadBucket calls.
+	headBucketCalls int
+}
+
+// HeadBucket implements the HeadBucket method for cancellingMockClient.
+func (c *cancellingMockClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	c.headBucketCalls++
+	c.cancel()
+	return nil, awserr.New("SlowDown", "Please reduce your request rate.", nil)
+}
+
+func TestRetryCancelled(t *testing.T) {
+	// The backoff outlasts the test, unless the cancellation cuts it short
+	ctx, cancel := context.WithCancel(WithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+	defer cancel()
+	client := &cancellingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, cancel: cancel}
+	_, err := DoesBucketExist(ctx, client, "testBucket")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DoesBucketExist() error = %v, want %v", err, context.Canceled)
+	}
+	if client.headBucketCalls != 1 {
+		t.Errorf("DoesBucketExist() made %d HeadBucket calls, want 1", client.headBucketCalls)
+	}
+}
+
+// hangingMockClient is a mockAWSClient whose HeadBucket calls take longer than
+// the operation timeout, unless their context is done first.
+type hangingMockClient struct {
+	mockAWSClient
+
+	delay time.Duration
+	// headBucketCalls counts the HeadBucket calls.
+	headBucketCalls int
+}
+
+// HeadBucket implements the HeadBucket method for hangingMockClient.
+func (c *hangingMockClient) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	c.headBucketCalls++
+	select {
+	case <-time.After(c.delay):
+		return &s3.HeadBucketOutput{}, nil
+	case <-ctx.Done():
+		return nil, awserr.New("RequestCanceled", "request context canceled", ctx.Err())
+	}
+}
+
+func TestRetryOperationTimeout(t *testing.T) {
+	ctx := WithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 2, OperationTimeout: 10 * time.Millisecond})
+	client := &hangingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, delay: time.Minute}
+	_, err := DoesBucketExist(ctx, client, "testBucket")
+
+	var timeoutErr *OperationTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Operation != "HeadBucket" {
+		t.Fatalf("DoesBucketExist() error = %v, want a HeadBucket OperationTimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoesBucketExist() error = %v, want it to match %v", err, context.DeadlineExceeded)
+	}
+	// The timed out attempt is retried
+	if client.headBucketCalls != 2 {
+		t.Errorf("DoesBucketExist() made %d HeadBucket calls, want 2", client.headBucketCalls)
+	}
+}
+
+func TestRetryWithinOperationTimeout(t *testing.T) {
+	ctx := WithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 2, OperationTimeout: time.Minute})
+	client := &hangingMockClient{mockAWSClient: mockAWSClient{Config: awsConfig}, delay: time.Millisecond}
+	if _, err := DoesBucketExist(ctx, client, "testBucket"); err != nil {
+		t.Errorf("DoesBucketExist() error = %v", err)
+	}
+	if client.headBucketCalls != 1 {
+		t.Errorf("DoesBucketExist() made %d HeadBucket calls, want 1", client.headBucketCalls)
+	}
+}
+
+func TestIsRetryableErrorWrapped(t *testing.T) {
+	throttled := awserr.NewRequestFailure(awserr.New("SlowDown", "Please reduce your request rate", nil), 503, "")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "wrapped throttling",
+			err:  fmt.Errorf("unable to tag bucket: %w", throttled),
+			want: true,
+		},
+		{
+			name: "throttling request error",
+			err:  wrapRequestError("PutBucketTagging", throttled),
+			want: true,
+		},
+		{
+			name: "wrapped server error without a code",
+			err:  fmt.Errorf("unable to tag bucket: %w", awserr.NewRequestFailure(awserr.New("", "", nil), 500, "")),
+			want: true,
+		},
+		{
+			name: "wrapped operation timeout",
+			err:  fmt.Errorf("unable to tag bucket: %w", &OperationTimeoutError{Operation: "PutBucketTagging", Timeout: time.Second}),
+			want: true,
+		},
+		{
+			name: "wrapped access denied",
+			err:  fmt.Errorf("unable to tag bucket: %w", awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TagPolicy describes the tags an organization requires on its buckets.
+type TagPolicy struct {
+	// RequiredKeys lists the tag keys which must be present.
+	RequiredKeys []string
+	// AllowedValues maps a tag key to the pattern its value must match.
+	AllowedValues map[string]*regexp.Regexp
+}
+
+// ParseTagPolicy builds a TagPolicy from the required keys and a list of
+// key=pattern allowed values. Patterns must match the whole tag value.
+func ParseTagPolicy(requiredKeys []string, allowedValues []string) (TagPolicy, error) {
+	policy := TagPolicy{RequiredKeys: requiredKeys}
+	for _, allowed := range allowedValues {
+		parts := strings.SplitN(allowed, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return TagPolicy{}, fmt.Errorf("allowed tag value %q is not of the form key=pattern", allowed)
+		}
+		pattern, err := regexp.Compile("^(?:" + parts[1] + ")$")
+		if err != nil {
+			return TagPolicy{}, fmt.Errorf("allowed tag value pattern for key %v is invalid: %v", parts[0], err)
+		}
+		if policy.AllowedValues == nil {
+			policy.AllowedValues = make(map[string]*regexp.Regexp)
+		}
+		policy.AllowedValues[parts[0]] = pattern
+	}
+	return policy, nil
+}
+
+// Violations returns a description of each way the tags fail the policy.
+func (p TagPolicy) Violations(tags map[string]string) []string {
+	var violations []string
+	for _, key := range p.RequiredKeys {
+		if _, ok := tags[key]; !ok {
+			violations = append(violations, fmt.Sprintf("required tag %v is missing", key))
+		}
+	}
+
+	keys := make([]string, 0, len(p.AllowedValues))
+	for key := range p.AllowedValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := tags[key]
+		if ok && !p.AllowedValues[key].MatchString(value) {
+			violations = append(violations, fmt.Sprintf("tag %v value %q is not allowed", key, value))
+		}
+	}
+	return violations
+}
+
+// BucketTagViolations returns the ways the tags TagBucket would apply fail the policy.
+func (p TagPolicy) BucketTagViolations(tagKeyPrefix string, backUpLocation string, infraName string, extraTags map[string]string) []string {
+	return p.Violations(bucketTagSet(tagKeyPrefix, backUpLocation, infraName, extraTags))
+}
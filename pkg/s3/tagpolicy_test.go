@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagPolicyViolations(t *testing.T) {
+	policy, err := ParseTagPolicy([]string{"cost-center", bucketTagInfraName}, []string{"cost-center=[0-9]{4}"})
+	if err != nil {
+		t.Fatalf("ParseTagPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		extraTags map[string]string
+		want      []string
+	}{
+		{
+			name:      "compliant tags",
+			extraTags: map[string]string{"cost-center": "1234"},
+			want:      nil,
+		},
+		{
+			name:      "missing required key",
+			extraTags: map[string]string{"team": "backup"},
+			want:      []string{"required tag cost-center is missing"},
+		},
+		{
+			name:      "value not allowed",
+			extraTags: map[string]string{"cost-center": "12345"},
+			want:      []string{`tag cost-center value "12345" is not allowed`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.BucketTagViolations("", "default", "fakeCluster", tt.extraTags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BucketTagViolations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagPolicyInvalid(t *testing.T) {
+	for _, allowed := range []string{"cost-center", "=[0-9]+", "cost-center=[0-9"} {
+		if _, err := ParseTagPolicy(nil, []string{allowed}); err == nil {
+			t.Errorf("ParseTagPolicy(%q) error = nil, want an error", allowed)
+		}
+	}
+}
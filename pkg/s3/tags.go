@@ -0,0 +1,47 @@
+package s3
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultTagKeyPrefix prefixes the keys of the operator's bucket tags, unless
+// the tag helpers are given another prefix.
+const DefaultTagKeyPrefix = "velero.io/"
+
+// maxTagValueLength is the longest tag value AWS accepts.
+const maxTagValueLength = 256
+
+// tagValueDisallowed matches the characters which may not be used in the values
+// of cost allocation tags. Spaces and slashes are allowed in some tag values,
+// but not by all the AWS services and tooling reading them.
+var tagValueDisallowed = regexp.MustCompile(`[^\p{L}\p{N}+\-=._:@]`)
+
+// TagKey returns the key of the operator's bucket tag under the prefix, which
+// replaces DefaultTagKeyPrefix, such as velero.io/ in velero.io/backup-location,
+// so that the tags don't collide with the tags of other tooling. The key is
+// given under DefaultTagKeyPrefix, which is kept when the prefix is empty.
+func TagKey(prefix string, key string) string {
+	if prefix == "" {
+		prefix = DefaultTagKeyPrefix
+	}
+	return prefix + strings.TrimPrefix(key, DefaultTagKeyPrefix)
+}
+
+// SanitizeTagValue replaces the characters the operator's bucket tag value may
+// not hold with underscores, and truncates it to the longest value AWS
+// accepts. A value which is valid is returned as is.
+func SanitizeTagValue(value string) string {
+	value = tagValueDisallowed.ReplaceAllString(value, "_")
+	if len(value) > maxTagValueLength {
+		value = value[:maxTagValueLength]
+	}
+	return value
+}
+
+// isTagKey checks whether the tag key is the key of the operator's bucket
+// tag, under either the prefix or DefaultTagKeyPrefix. Buckets tagged under
+// DefaultTagKeyPrefix are still found, and are tagged again under the prefix.
+func isTagKey(prefix string, tagKey string, key string) bool {
+	return tagKey == TagKey(prefix, key) || tagKey == key
+}
@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const testTagKeyPrefix = "example.com/velero-"
+
+func TestFindMatchingTagsTagKeyPrefix(t *testing.T) {
+	tagging := func(backupLocationKey string, infraNameKey string) *s3.GetBucketTaggingOutput {
+		return &s3.GetBucketTaggingOutput{
+			TagSet: []*s3.Tag{
+				{Key: aws.String(backupLocationKey), Value: aws.String(defaultBackupStorageLocation)},
+				{Key: aws.String(infraNameKey), Value: aws.String(clusterInfraName)},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		tagging *s3.GetBucketTaggingOutput
+		want    string
+	}{
+		{
+			name:    "tagged under the prefix",
+			tagging: tagging("example.com/velero-backup-location", "example.com/velero-infrastructureName"),
+			want:    "bucket1",
+		},
+		{
+			name:    "tagged before the prefix was configured",
+			tagging: tagging(bucketTagBackupLocation, bucketTagInfraName),
+			want:    "bucket1",
+		},
+		{
+			name:    "tagged by other tooling",
+			tagging: tagging("backup-location", "infrastructureName"),
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets := map[string]*s3.GetBucketTaggingOutput{"bucket1": tt.tagging}
+			if got := FindMatchingTags(buckets, testTagKeyPrefix, defaultBackupStorageLocation, clusterInfraName); got != tt.want {
+				t.Errorf("FindMatchingTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagBucketTagKeyPrefix(t *testing.T) {
+	client := &mockAWSClient{Config: awsConfig}
+	extraTags := map[string]string{TagKey(testTagKeyPrefix, DefaultTagKeyPrefix+"cluster-id"): "abc", "cost-center": "1234"}
+	if err := TagBucket(context.TODO(), client, "testBucket", testTagKeyPrefix, defaultBackupStorageLocation, clusterInfraName, extraTags); err != nil {
+		t.Fatalf("TagBucket() error = %v", err)
+	}
+	if len(client.putBucketTaggingInputs) != 1 {
+		t.Fatalf("TagBucket() issued %d PutBucketTagging calls, want 1", len(client.putBucketTaggingInputs))
+	}
+	tags := make(map[string]string)
+	for _, tag := range client.putBucketTaggingInputs[0].Tagging.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	want := map[string]string{
+		"example.com/velero-backup-location":    defaultBackupStorageLocation,
+		"example.com/velero-infrastructureName": clusterInfraName,
+		"example.com/velero-cluster-id":         "abc",
+		"cost-center":                           "1234",
+	}
+	if len(tags) != len(want) {
+		t.Errorf("TagBucket() tags = %v, want %v", tags, want)
+	}
+	for key, value := range want {
+		if tags[key] != value {
+			t.Errorf("TagBucket() tag %v = %q, want %q", key, tags[key], value)
+		}
+	}
+}
+
+func TestSanitizeTagValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "cluster-abc12", want: "cluster-abc12"},
+		{value: "team/cluster-abc12", want: "team_cluster-abc12"},
+		{value: "my cluster", want: "my_cluster"},
+		{value: "cluster:1.2_a=b+c@d", want: "cluster:1.2_a=b+c@d"},
+		{value: "clüster", want: "clüster"},
+		{value: strings.Repeat("a", 300), want: strings.Repeat("a", 256)},
+	}
+	for _, tt := range tests {
+		if got := SanitizeTagValue(tt.value); got != tt.want {
+			t.Errorf("SanitizeTagValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFindMatchingTagsSanitizedInfraName(t *testing.T) {
+	for _, infraName := range []string{"team/cluster-abc12", "my cluster"} {
+		t.Run(infraName, func(t *testing.T) {
+			tagging := CreateBucketTaggingInput("bucket1", bucketTagSet("", defaultBackupStorageLocation, infraName, nil)).Tagging
+			for _, tag := range tagging.TagSet {
+				if *tag.Key == bucketTagInfraName && *tag.Value == infraName {
+					t.Fatalf("bucket tagged with the unsanitized infrastructure name %q", infraName)
+				}
+			}
+			buckets := map[string]*s3.GetBucketTaggingOutput{"bucket1": {TagSet: tagging.TagSet}}
+			if got := FindMatchingTags(buckets, "", defaultBackupStorageLocation, infraName); got != "bucket1" {
+				t.Errorf("FindMatchingTags() = %q, want the bucket tagged with the sanitized infrastructure name", got)
+			}
+
+			// Buckets tagged before the infrastructure name was sanitized are still found
+			buckets["bucket1"] = &s3.GetBucketTaggingOutput{TagSet: []*s3.Tag{
+				{Key: aws.String(bucketTagBackupLocation), Value: aws.String(defaultBackupStorageLocation)},
+				{Key: aws.String(bucketTagInfraName), Value: aws.String(infraName)},
+			}}
+			if got := FindMatchingTags(buckets, "", defaultBackupStorageLocation, infraName); got != "bucket1" {
+				t.Errorf("FindMatchingTags() = %q, want the bucket tagged with the unsanitized infrastructure name", got)
+			}
+		})
+	}
+}
@@ -53,6 +53,17 @@ func GetInfrastructureStatus(client client.Client) (*configv1.InfrastructureStat
 	return &infra.Status, nil
 }
 
+// GetClusterVersion fetches the ClusterVersion for the cluster.
+func GetClusterVersion(client client.Client) (*configv1.ClusterVersion, error) {
+	clusterVersion := &configv1.ClusterVersion{}
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterVersion, nil
+}
+
 // IsPlatformSupported checks if specified platform is in a slice of supported
 // platforms
 func IsPlatformSupported(platform configv1.PlatformType, supportedPlatforms []configv1.PlatformType) bool {